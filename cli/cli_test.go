@@ -348,7 +348,7 @@ func TestOutputDeterminism_StaleFilesRemoved(t *testing.T) {
 	}
 }
 
-func TestWriteFailure_ReadOnlyOutputDir_ReturnsExit3(t *testing.T) {
+func TestWriteFailure_ReadOnlyOutputDir_ReturnsWorkspaceExit(t *testing.T) {
 	workDir := t.TempDir()
 	graphPath := filepath.Join(workDir, "graph.json")
 	outDir := filepath.Join(workDir, "out")
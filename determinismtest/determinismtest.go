@@ -0,0 +1,351 @@
+// Package determinismtest verifies the ordering guarantees of the scriptweaver
+// engine: the graph hash, the task hashes, the invalidation map, the
+// incremental plan and the execution trace of a graph are canonical. They do
+// not depend on the order tasks and edges are declared in, on map iteration
+// order or on scheduling, and they are byte-for-byte stable across versions.
+//
+// Check recomputes a scenario's fingerprint under permuted declaration orders
+// and both schedulers; Golden compares it with a golden file, so a fork or
+// plugin that changes a canonical serialization, even consistently, is caught
+// as a diff. Tasks are scripted as in package plugintest: nothing executes.
+package determinismtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/incremental"
+	"scriptweaver/internal/pluginengine"
+)
+
+// Task is a graph node, as declared in graph files.
+type Task = core.Task
+
+// Edge is a dependency between two tasks.
+type Edge = dag.Edge
+
+// Plugin is the runtime plugin interface (see plugintest.Plugin).
+type Plugin = pluginengine.RuntimePlugin
+
+// UpdateEnv is the environment variable that makes Golden rewrite golden
+// files instead of comparing against them. It is the same as plugintest's.
+const UpdateEnv = "SCRIPTWEAVER_UPDATE_GOLDEN"
+
+// Scenario is a graph, optionally the graph it was changed from, and the
+// scripted outcome of its tasks.
+type Scenario struct {
+	Tasks []Task
+	Edges []Edge
+	// PreviousTasks and PreviousEdges are the graph the invalidation map is
+	// computed against; without them every task is new.
+	PreviousTasks []Task
+	PreviousEdges []Edge
+	// Cached names the tasks whose results the cache holds.
+	Cached []string
+	// ExitCodes are the exit codes tasks report when they run; tasks not
+	// listed succeed.
+	ExitCodes map[string]int
+	// Plugins, when set, returns fresh plugins to activate in every run.
+	Plugins func() []Plugin
+}
+
+// Fingerprint is the canonical output of the engine for a scenario.
+type Fingerprint struct {
+	GraphHash string
+	// TaskHashes maps task names to their task hash. Inputs are not
+	// resolved and the platform is left out, so fingerprints are portable.
+	TaskHashes map[string]string
+	// Invalidation is the SHA-256 of the binary invalidation map, and
+	// InvalidationReasons lists each task's canonical reasons.
+	Invalidation        string
+	InvalidationReasons map[string][]string
+	// PlanHash is the incremental plan's hash; Plan lists its decisions in
+	// plan order, as "<task> <decision>".
+	PlanHash string
+	Plan     []string
+	// Trace is the canonical execution trace of running the plan.
+	Trace []byte
+}
+
+// Compute returns the fingerprint of s, running the plan serially.
+func Compute(ctx context.Context, s Scenario) (*Fingerprint, error) {
+	return compute(ctx, s, 0)
+}
+
+// compute runs the plan serially when workers is 0, otherwise in parallel.
+func compute(ctx context.Context, s Scenario, workers int) (*Fingerprint, error) {
+	g, err := dag.NewTaskGraph(s.Tasks, s.Edges)
+	if err != nil {
+		return nil, err
+	}
+	snap := snapshot(g)
+	var prev *incremental.GraphSnapshot
+	if s.PreviousTasks != nil {
+		pg, err := dag.NewTaskGraph(s.PreviousTasks, s.PreviousEdges)
+		if err != nil {
+			return nil, fmt.Errorf("previous graph: %w", err)
+		}
+		prev = snapshot(pg)
+	}
+
+	cache := core.NewMemoryCache()
+	for _, name := range s.Cached {
+		n, ok := snap.Nodes[name]
+		if !ok {
+			return nil, fmt.Errorf("cached task %q is not in the graph", name)
+		}
+		if err := cache.Put(&core.CacheEntry{Hash: core.TaskHash(n.TaskHash)}); err != nil {
+			return nil, err
+		}
+	}
+	planning, err := incremental.PlanIncremental(prev, snap, cache)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := planning.Invalidation.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(inv)
+
+	fp := &Fingerprint{
+		GraphHash:           g.Hash().String(),
+		TaskHashes:          make(map[string]string, len(snap.Nodes)),
+		Invalidation:        hex.EncodeToString(sum[:]),
+		InvalidationReasons: make(map[string][]string, len(planning.Invalidation)),
+		PlanHash:            planning.Plan.Hash(),
+	}
+	for name, n := range snap.Nodes {
+		fp.TaskHashes[name] = n.TaskHash
+	}
+	for name, e := range planning.Invalidation {
+		var reasons []string
+		for _, r := range e.Reasons {
+			reasons = append(reasons, formatReason(r))
+		}
+		fp.InvalidationReasons[name] = reasons
+	}
+	for _, name := range planning.Plan.Order {
+		fp.Plan = append(fp.Plan, name+" "+string(planning.Plan.Decisions[name]))
+	}
+
+	exec, err := dag.NewExecutor(g, scriptedRunner{hashes: fp.TaskHashes, exitCodes: s.ExitCodes})
+	if err != nil {
+		return nil, err
+	}
+	exec.Plan = planning.Plan
+	if s.Plugins != nil {
+		hooks, err := pluginengine.NewHookEngine(s.Plugins(), nil)
+		if err != nil {
+			return nil, err
+		}
+		exec.Hooks = hooks
+	}
+	var gr *dag.GraphResult
+	if workers == 0 {
+		gr, err = exec.RunSerial(ctx)
+	} else {
+		gr, err = exec.RunParallel(ctx, workers)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fp.Trace = gr.TraceBytes
+	return fp, nil
+}
+
+// Check computes the fingerprint of s n times, declaring its tasks and edges
+// in a different order each time and alternating between the serial and the
+// parallel scheduler, and reports the first difference from the first
+// fingerprint. Permutations are seeded, so a failure reproduces.
+func Check(ctx context.Context, s Scenario, n int) error {
+	var first *Fingerprint
+	for i := 0; i < n; i++ {
+		workers := 0
+		if i%2 == 1 {
+			workers = 1 + i%4
+		}
+		fp, err := compute(ctx, permute(s, int64(i)), workers)
+		if err != nil {
+			return fmt.Errorf("run %d: %w", i, err)
+		}
+		if first == nil {
+			first = fp
+			continue
+		}
+		if diff := Diff(first, fp); diff != "" {
+			return fmt.Errorf("run %d (workers %d) differs from run 0: %s", i, workers, diff)
+		}
+	}
+	return nil
+}
+
+// Diff names the first part of a and b that differs, or returns "".
+func Diff(a, b *Fingerprint) string {
+	ga, gb := format(a), format(b)
+	if bytes.Equal(ga, gb) {
+		return ""
+	}
+	la, lb := strings.Split(string(ga), "\n"), strings.Split(string(gb), "\n")
+	section := ""
+	for i := 0; i < len(la) && i < len(lb); i++ {
+		if strings.HasPrefix(la[i], "# ") {
+			section = strings.TrimPrefix(la[i], "# ")
+		}
+		if la[i] != lb[i] {
+			return fmt.Sprintf("%s: %q vs %q", section, la[i], lb[i])
+		}
+	}
+	return fmt.Sprintf("%s: %d vs %d lines", section, len(la), len(lb))
+}
+
+// Golden compares fp with the golden file at path. When UpdateEnv is set to
+// 1 the file is written instead.
+func Golden(t testing.TB, path string, fp *Fingerprint) {
+	t.Helper()
+	got := format(fp)
+	if os.Getenv(UpdateEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden (set %s=1 to create it): %v", UpdateEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("golden mismatch for %s (set %s=1 to update)\n--- got ---\n%s\n--- want ---\n%s", path, UpdateEnv, got, want)
+	}
+}
+
+// snapshot describes the nodes of g for incremental planning.
+func snapshot(g *dag.TaskGraph) *incremental.GraphSnapshot {
+	upstream := make(map[string][]string)
+	orderUpstream := make(map[string][]string)
+	for _, e := range g.Edges() {
+		if e.IsOrder() {
+			orderUpstream[e.To] = append(orderUpstream[e.To], e.From)
+		} else {
+			upstream[e.To] = append(upstream[e.To], e.From)
+		}
+	}
+	hasher := core.NewTaskHasher()
+	snap := &incremental.GraphSnapshot{Nodes: make(map[string]incremental.NodeSnapshot)}
+	for _, n := range g.Nodes() {
+		t := n.Task
+		in := t.HashInput(&core.InputSet{}, "")
+		in.Platform = ""
+		snap.Nodes[t.Name] = incremental.NodeSnapshot{
+			Name:           t.Name,
+			TaskHash:       hasher.ComputeHash(in).String(),
+			DeclaredInputs: t.Inputs,
+			Env:            t.Env,
+			Command:        t.Run,
+			Outputs:        t.Outputs,
+			Upstream:       upstream[t.Name],
+			OrderUpstream:  orderUpstream[t.Name],
+		}
+	}
+	return snap
+}
+
+// permute returns s with its tasks, edges and cached tasks in a seeded
+// random order.
+func permute(s Scenario, seed int64) Scenario {
+	rng := rand.New(rand.NewSource(seed))
+	s.Tasks = shuffled(rng, s.Tasks)
+	s.Edges = shuffled(rng, s.Edges)
+	s.PreviousTasks = shuffled(rng, s.PreviousTasks)
+	s.PreviousEdges = shuffled(rng, s.PreviousEdges)
+	s.Cached = shuffled(rng, s.Cached)
+	return s
+}
+
+func shuffled[T any](rng *rand.Rand, in []T) []T {
+	if in == nil {
+		return nil
+	}
+	out := append([]T(nil), in...)
+	rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out
+}
+
+func formatReason(r incremental.InvalidationReason) string {
+	s := string(r.Type)
+	if r.SourceTaskID != "" {
+		s += " from " + r.SourceTaskID
+	}
+	for _, d := range r.Details {
+		s += " " + d.Key + "=" + d.Value
+	}
+	return s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func format(fp *Fingerprint) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# graph hash\n%s\n", fp.GraphHash)
+	b.WriteString("# task hashes\n")
+	for _, name := range sortedKeys(fp.TaskHashes) {
+		fmt.Fprintf(&b, "%s %s\n", name, fp.TaskHashes[name])
+	}
+	fmt.Fprintf(&b, "# invalidation\n%s\n", fp.Invalidation)
+	for _, name := range sortedKeys(fp.InvalidationReasons) {
+		reasons := "valid"
+		if r := fp.InvalidationReasons[name]; len(r) > 0 {
+			reasons = strings.Join(r, "; ")
+		}
+		fmt.Fprintf(&b, "%s: %s\n", name, reasons)
+	}
+	fmt.Fprintf(&b, "# plan\n%s\n", fp.PlanHash)
+	for _, line := range fp.Plan {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteString("# trace\n")
+	b.Write(fp.Trace)
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+// scriptedRunner reports the scripted outcome of each task without
+// executing anything. Planned cache reuse is restored successfully.
+type scriptedRunner struct {
+	hashes    map[string]string
+	exitCodes map[string]int
+}
+
+func (r scriptedRunner) Probe(context.Context, core.Task) (*dag.NodeResult, bool, error) {
+	return nil, false, nil
+}
+
+func (r scriptedRunner) Run(_ context.Context, task core.Task) (*dag.NodeResult, error) {
+	return &dag.NodeResult{Hash: core.TaskHash(r.hashes[task.Name]), ExitCode: r.exitCodes[task.Name]}, nil
+}
+
+func (r scriptedRunner) Restore(_ context.Context, task core.Task) (*dag.NodeResult, error) {
+	return &dag.NodeResult{Hash: core.TaskHash(r.hashes[task.Name]), FromCache: true}, nil
+}
@@ -0,0 +1,75 @@
+package determinismtest
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// diamond is a build graph changed since the previous run: compile's command
+// and lint's env changed, and package was added.
+func diamond() Scenario {
+	previous := []Task{
+		{Name: "fetch", Run: "fetch deps", Outputs: []string{"deps"}},
+		{Name: "compile", Inputs: []string{"src/**/*.go"}, Run: "go build ./...", Outputs: []string{"bin/app"}},
+		{Name: "lint", Run: "golangci-lint run", Env: map[string]string{"GOFLAGS": "-mod=mod"}},
+		{Name: "test", Run: "go test ./...", TimeoutSeconds: 600, Retries: 1},
+	}
+	tasks := []Task{
+		previous[0],
+		{Name: "compile", Inputs: []string{"src/**/*.go"}, Run: "go build -trimpath ./...", Outputs: []string{"bin/app"}},
+		{Name: "lint", Run: "golangci-lint run", Env: map[string]string{"GOFLAGS": "-mod=vendor"}},
+		previous[3],
+		{Name: "package", Run: "tar czf app.tgz bin", Outputs: []string{"app.tgz"}},
+	}
+	edges := []Edge{{From: "fetch", To: "compile"}, {From: "fetch", To: "lint"}, {From: "compile", To: "test"}, {From: "lint", To: "test"}}
+	return Scenario{
+		Tasks:         tasks,
+		Edges:         append(edges, Edge{From: "test", To: "package"}),
+		PreviousTasks: previous,
+		PreviousEdges: edges,
+		Cached:        []string{"fetch", "lint"},
+		ExitCodes:     map[string]int{"test": 1},
+	}
+}
+
+func TestCheck_Diamond(t *testing.T) {
+	if err := Check(context.Background(), diamond(), 8); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for name, s := range map[string]Scenario{
+		"diamond": diamond(),
+		"fresh":   {Tasks: []Task{{Name: "a", Run: "echo a"}, {Name: "b", Run: "echo b"}}, Edges: []Edge{{From: "a", To: "b"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fp, err := Compute(context.Background(), s)
+			if err != nil {
+				t.Fatalf("Compute: %v", err)
+			}
+			Golden(t, filepath.Join("testdata", name+".golden"), fp)
+		})
+	}
+}
+
+func TestDiff_NamesTheSection(t *testing.T) {
+	a, err := Compute(context.Background(), diamond())
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	changed := diamond()
+	changed.ExitCodes = nil
+	b, err := Compute(context.Background(), changed)
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	if d := Diff(a, b); !strings.HasPrefix(d, "trace: ") {
+		t.Fatalf("Diff = %q, want a trace difference", d)
+	}
+	if d := Diff(a, a); d != "" {
+		t.Fatalf("Diff of a fingerprint with itself = %q", d)
+	}
+}
@@ -0,0 +1,54 @@
+// Package exitcode is the canonical process exit-code table for scriptweaver.
+//
+// Every entry point (the sw CLI and the engine-level CLI invocation API)
+// reports outcomes with these values, so scripts and CI systems can rely on a
+// single contract.
+package exitcode
+
+const (
+	// Success means the command completed and every task succeeded.
+	Success = 0
+	// Validation means the graph or another declared input failed validation
+	// (schema, structure, cycles, audit chain).
+	Validation = 1
+	// Usage means the command line or invocation was invalid.
+	Usage = 2
+	// Execution means the graph ran and at least one task failed.
+	Execution = 3
+	// Plugin means plugin discovery or loading failed.
+	Plugin = 4
+	// Workspace means the workspace, configuration, cache or output directory
+	// could not be used.
+	Workspace = 5
+	// Cancelled means execution was interrupted before it completed.
+	Cancelled = 6
+	// Internal means an unexpected engine failure.
+	Internal = 7
+	// Offline means an offline run needed artifacts that are only available
+	// over the network.
+	Offline = 8
+	// Warnings means validation found no error but reported warnings, which
+	// --strict makes fatal.
+	Warnings = 9
+)
+
+var names = map[int]string{
+	Success:    "success",
+	Validation: "validation",
+	Usage:      "usage",
+	Execution:  "execution",
+	Plugin:     "plugin",
+	Workspace:  "workspace",
+	Cancelled:  "cancelled",
+	Internal:   "internal",
+	Offline:    "offline",
+	Warnings:   "warnings",
+}
+
+// Name returns the short name of code, or "unknown".
+func Name(code int) string {
+	if n, ok := names[code]; ok {
+		return n
+	}
+	return "unknown"
+}
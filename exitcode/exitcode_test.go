@@ -0,0 +1,20 @@
+package exitcode
+
+import "testing"
+
+func TestCodesAreDistinctAndNamed(t *testing.T) {
+	codes := []int{Success, Validation, Usage, Execution, Plugin, Workspace, Cancelled, Internal, Offline, Warnings}
+	seen := make(map[int]bool)
+	for _, c := range codes {
+		if seen[c] {
+			t.Fatalf("duplicate exit code %d", c)
+		}
+		seen[c] = true
+		if Name(c) == "unknown" {
+			t.Fatalf("exit code %d has no name", c)
+		}
+	}
+	if Name(99) != "unknown" {
+		t.Fatalf("unexpected name for 99")
+	}
+}
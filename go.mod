@@ -1,3 +1,10 @@
 module scriptweaver
 
 go 1.22
+
+require (
+	github.com/klauspost/compress v1.17.11
+	lukechampine.com/blake3 v1.4.1
+)
+
+require github.com/klauspost/cpuid/v2 v2.0.9 // indirect
@@ -0,0 +1,92 @@
+// Package graph is the public API for ScriptWeaver graph documents, so that
+// toolchains generating graphs in Go build them with Builder instead of
+// emitting JSON by string concatenation, and read them back with Parse.
+//
+// It re-exports the graph document types and functions of the engine: a
+// Document built here is the Document the engine parses, validates and
+// hashes.
+package graph
+
+import (
+	"io"
+
+	"scriptweaver/internal/graph"
+)
+
+// SupportedSchemaVersion is the schema_version of the documents Builder
+// builds.
+const SupportedSchemaVersion = graph.SupportedSchemaVersion
+
+// Document is a graph document: its schema version, graph and metadata.
+type Document = graph.Document
+
+// Graph holds the nodes and edges of a document.
+type Graph = graph.Graph
+
+// Node is a single execution unit of a graph.
+type Node = graph.Node
+
+// Edge is a dependency between two nodes: To depends on From.
+type Edge = graph.Edge
+
+// Metadata describes a document; it does not affect the graph hash.
+type Metadata = graph.Metadata
+
+// Builder constructs a Document, enforcing structural invariants as each
+// call is made (see NewBuilder).
+type Builder = graph.Builder
+
+// NewBuilder returns an empty Builder. Its methods return the builder so
+// calls can be chained, and its first error is reported by Err and Build:
+//
+//	doc, err := graph.NewBuilder().
+//		AddNode("build", "exec", map[string]any{"cmd": "go build ./..."}, "bin/app").
+//		AddNode("test", "exec", map[string]any{"cmd": "go test ./..."}).
+//		AddEdge("build", "test").
+//		Build()
+func NewBuilder() *Builder {
+	return graph.NewBuilder()
+}
+
+// Parse decodes a graph document from JSON and validates its schema.
+func Parse(r io.Reader) (*Document, error) {
+	return graph.Parse(r)
+}
+
+// Validate checks the structure of g: unique node IDs, edges between
+// existing nodes and no cycles.
+func Validate(g *Graph) error {
+	return graph.Validate(g)
+}
+
+// ComputeHash returns the hex-encoded graph hash of g, which only depends on
+// its normalized nodes and edges.
+func ComputeHash(g *Graph) (string, error) {
+	return graph.ComputeHash(g)
+}
+
+// Errors returned by Builder, Parse and Validate, by kind. Use errors.Is to
+// tell the kinds apart and errors.As for the details.
+var (
+	ErrParse      = graph.ErrParse
+	ErrSchema     = graph.ErrSchema
+	ErrStructural = graph.ErrStructural
+	ErrSemantic   = graph.ErrSemantic
+)
+
+// ParseError, SchemaError, StructuralError and SemanticError carry the
+// details of an error of each kind.
+type (
+	ParseError      = graph.ParseError
+	SchemaError     = graph.SchemaError
+	StructuralError = graph.StructuralError
+	SemanticError   = graph.SemanticError
+)
+
+// Hint is a suggested fix attached to an error.
+type Hint = graph.Hint
+
+// HintsOf returns the hints attached to err, if any.
+func HintsOf(err error) []Hint {
+	return graph.HintsOf(err)
+}
@@ -0,0 +1,68 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"scriptweaver/graph"
+)
+
+func ExampleNewBuilder() {
+	doc, err := graph.NewBuilder().
+		AddNode("test", "exec", map[string]any{"cmd": "go test ./..."}).
+		AddNode("build", "exec", map[string]any{"cmd": "go build ./..."}, "bin/app").
+		AddEdge("build", "test").
+		Build()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, n := range doc.Graph.Nodes {
+		fmt.Println(n.ID, n.Inputs["cmd"], n.Outputs)
+	}
+	fmt.Println(doc.Graph.Edges)
+	// Output:
+	// build go build ./... [bin/app]
+	// test go test ./... []
+	// [{build test}]
+}
+
+func TestBuilder_DocumentRoundTripsThroughParse(t *testing.T) {
+	doc, err := graph.NewBuilder().
+		AddNode("a", "exec", map[string]any{"cmd": "true"}).
+		AddNode("b", "exec", map[string]any{"cmd": "true"}).
+		AddEdge("a", "b").
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := graph.Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want, _ := graph.ComputeHash(&doc.Graph)
+	got, _ := graph.ComputeHash(&parsed.Graph)
+	if got != want {
+		t.Fatalf("hash after round trip = %s, want %s", got, want)
+	}
+}
+
+func TestBuilder_RejectsCycle(t *testing.T) {
+	_, err := graph.NewBuilder().
+		AddNode("a", "exec", map[string]any{"cmd": "true"}).
+		AddNode("b", "exec", map[string]any{"cmd": "true"}).
+		AddEdge("a", "b").
+		AddEdge("b", "a").
+		Build()
+	var structural *graph.StructuralError
+	if !errors.Is(err, graph.ErrStructural) || !errors.As(err, &structural) || structural.Kind != "cycle" {
+		t.Fatalf("err = %v, want a cycle StructuralError", err)
+	}
+}
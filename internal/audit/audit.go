@@ -0,0 +1,264 @@
+// Package audit maintains a tamper-evident log of workspace mutations.
+//
+// The log lives at <projectRoot>/.scriptweaver/logs/audit.jsonl and holds one
+// JSON entry per line. Each entry carries the hash of its predecessor, so
+// editing, reordering or deleting any entry breaks the chain and is reported by
+// Verify.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Kind classifies a recorded mutation.
+type Kind string
+
+const (
+	KindCacheWrite  Kind = "cache_write"
+	KindCacheEvict  Kind = "cache_evict"
+	KindOutputClear Kind = "output_clear"
+	KindStateWrite  Kind = "state_write"
+	KindPluginHook  Kind = "plugin_hook"
+	KindRunPurge    Kind = "run_purge"
+)
+
+// FileName is the audit log file name inside the workspace logs directory.
+const FileName = "audit.jsonl"
+
+// genesisHash is the Prev value of the first entry.
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// Entry is a single audit record.
+type Entry struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	RunID   string    `json:"run_id"`
+	Kind    Kind      `json:"kind"`
+	Subject string    `json:"subject"`
+	Prev    string    `json:"prev"`
+	Hash    string    `json:"hash"`
+}
+
+// computeHash hashes every field except Hash itself.
+func (e Entry) computeHash() string {
+	h := sha256.New()
+	writeField := func(data []byte) {
+		length := uint64(len(data))
+		lengthBytes := []byte{
+			byte(length >> 56),
+			byte(length >> 48),
+			byte(length >> 40),
+			byte(length >> 32),
+			byte(length >> 24),
+			byte(length >> 16),
+			byte(length >> 8),
+			byte(length),
+		}
+		h.Write(lengthBytes)
+		h.Write(data)
+	}
+	writeField([]byte(strconv.FormatUint(e.Seq, 10)))
+	writeField([]byte(e.Time.UTC().Format(time.RFC3339Nano)))
+	writeField([]byte(e.RunID))
+	writeField([]byte(e.Kind))
+	writeField([]byte(e.Subject))
+	writeField([]byte(e.Prev))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Path returns the audit log path for a project root.
+func Path(projectRoot string) string {
+	return filepath.Join(projectRoot, ".scriptweaver", "logs", FileName)
+}
+
+// Log appends entries for a single run.
+//
+// A nil *Log is valid and records nothing, so callers can treat auditing as
+// best-effort without nil checks. Appends are serialized within the process
+// by a mutex and across processes by an exclusive file lock.
+type Log struct {
+	path  string
+	runID string
+	now   func() time.Time
+
+	mu       sync.Mutex
+	lastSize int64
+	lastSeq  uint64
+	lastHash string
+}
+
+// Open prepares the audit log under projectRoot for run runID.
+func Open(projectRoot, runID string) (*Log, error) {
+	if strings.TrimSpace(projectRoot) == "" {
+		return nil, errors.New("project root is required")
+	}
+	p := Path(projectRoot)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, fmt.Errorf("create audit log dir: %w", err)
+	}
+	return &Log{path: p, runID: runID, now: time.Now, lastSize: -1}, nil
+}
+
+// Record appends one entry describing a mutation of subject.
+func (l *Log) Record(kind Kind, subject string) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("lock audit log: %w", err)
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat audit log: %w", err)
+	}
+	// Another writer may have appended since our last record; rescan the tail.
+	if info.Size() != l.lastSize {
+		seq, hash, err := lastEntry(f)
+		if err != nil {
+			return err
+		}
+		l.lastSeq, l.lastHash = seq, hash
+	}
+
+	e := Entry{
+		Seq:     l.lastSeq + 1,
+		Time:    l.now().UTC(),
+		RunID:   l.runID,
+		Kind:    kind,
+		Subject: subject,
+		Prev:    l.lastHash,
+	}
+	e.Hash = e.computeHash()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("seek audit log: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("append audit log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync audit log: %w", err)
+	}
+
+	l.lastSeq, l.lastHash = e.Seq, e.Hash
+	l.lastSize = info.Size() + int64(len(line))
+	return nil
+}
+
+// lastEntry returns the sequence number and hash of the final entry, or the
+// genesis values for an empty log.
+func lastEntry(r io.ReadSeeker) (uint64, string, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, "", fmt.Errorf("seek audit log: %w", err)
+	}
+	var last []byte
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		last = append(last[:0], sc.Bytes()...)
+	}
+	if err := sc.Err(); err != nil {
+		return 0, "", fmt.Errorf("read audit log: %w", err)
+	}
+	if last == nil {
+		return 0, genesisHash, nil
+	}
+	var e Entry
+	if err := json.Unmarshal(last, &e); err != nil {
+		return 0, "", fmt.Errorf("parse last audit entry: %w", err)
+	}
+	return e.Seq, e.Hash, nil
+}
+
+// VerifyError reports the first entry that breaks the hash chain.
+type VerifyError struct {
+	Line int
+	Msg  string
+}
+
+func (e *VerifyError) Error() string {
+	if e == nil {
+		return ""
+	}
+	return fmt.Sprintf("audit log line %d: %s", e.Line, e.Msg)
+}
+
+// Verify checks the audit log under projectRoot and returns the number of
+// entries. A missing log is valid and holds zero entries.
+func Verify(projectRoot string) (int, error) {
+	f, err := os.Open(Path(projectRoot))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	prev := genesisHash
+	var seq uint64
+	line := 0
+	count := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		line++
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(sc.Bytes()))
+		dec.DisallowUnknownFields()
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			return count, &VerifyError{Line: line, Msg: fmt.Sprintf("invalid entry: %v", err)}
+		}
+		if e.Seq != seq+1 {
+			return count, &VerifyError{Line: line, Msg: fmt.Sprintf("expected seq %d, got %d", seq+1, e.Seq)}
+		}
+		if e.Prev != prev {
+			return count, &VerifyError{Line: line, Msg: "prev hash does not match preceding entry"}
+		}
+		if e.computeHash() != e.Hash {
+			return count, &VerifyError{Line: line, Msg: "entry hash mismatch"}
+		}
+		prev = e.Hash
+		seq = e.Seq
+		count++
+	}
+	if err := sc.Err(); err != nil {
+		return count, fmt.Errorf("read audit log: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestLog_RecordsHashChainedEntries(t *testing.T) {
+	root := t.TempDir()
+	l, err := Open(root, "run-1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, k := range []Kind{KindOutputClear, KindCacheWrite, KindStateWrite} {
+		if err := l.Record(k, "subject"); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	// A second writer continues the same chain.
+	l2, err := Open(root, "run-2")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := l2.Record(KindPluginHook, "p/BeforeRun"); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := l.Record(KindCacheEvict, "abc"); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	n, err := Verify(root)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if n != 5 {
+		t.Fatalf("expected 5 entries, got %d", n)
+	}
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	root := t.TempDir()
+	l, err := Open(root, "run-1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	_ = l.Record(KindCacheWrite, "aaaa")
+	_ = l.Record(KindCacheWrite, "bbbb")
+
+	data, err := os.ReadFile(Path(root))
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	cases := map[string][]byte{
+		"edited":  bytes.Replace(data, []byte("aaaa"), []byte("cccc"), 1),
+		"deleted": data[bytes.IndexByte(data, '\n')+1:],
+	}
+	for name, tampered := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := os.WriteFile(Path(root), tampered, 0o644); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			_, err := Verify(root)
+			var ve *VerifyError
+			if !errors.As(err, &ve) || ve.Line != 1 {
+				t.Fatalf("expected verify error on line 1, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerify_MissingLogIsEmpty(t *testing.T) {
+	n, err := Verify(t.TempDir())
+	if err != nil || n != 0 {
+		t.Fatalf("expected empty valid log, got n=%d err=%v", n, err)
+	}
+}
+
+func TestLog_NilIsNoop(t *testing.T) {
+	var l *Log
+	if err := l.Record(KindCacheWrite, "x"); err != nil {
+		t.Fatalf("nil log must be a no-op, got %v", err)
+	}
+}
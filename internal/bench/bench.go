@@ -0,0 +1,182 @@
+// Package bench summarizes repeated graph executions.
+//
+// A Report holds mean/median/p95 durations for the whole graph and for every
+// node. Reports can be stored as baseline files and compared against later
+// runs to spot regressions.
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"scriptweaver/internal/core"
+)
+
+// Stats summarizes a set of duration samples.
+type Stats struct {
+	Samples int           `json:"samples"`
+	Mean    time.Duration `json:"mean_ns"`
+	Median  time.Duration `json:"median_ns"`
+	P95     time.Duration `json:"p95_ns"`
+	Min     time.Duration `json:"min_ns"`
+	Max     time.Duration `json:"max_ns"`
+}
+
+// Summarize computes Stats over samples. P95 uses the nearest-rank method.
+func Summarize(samples []time.Duration) Stats {
+	n := len(samples)
+	if n == 0 {
+		return Stats{}
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	median := sorted[n/2]
+	if n%2 == 0 {
+		median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	rank := int(math.Ceil(0.95*float64(n))) - 1
+	return Stats{
+		Samples: n,
+		Mean:    sum / time.Duration(n),
+		Median:  median,
+		P95:     sorted[rank],
+		Min:     sorted[0],
+		Max:     sorted[n-1],
+	}
+}
+
+// Report is the summary of a benchmark.
+type Report struct {
+	GraphHash  string           `json:"graph_hash"`
+	Mode       string           `json:"mode"`
+	Iterations int              `json:"iterations"`
+	Total      Stats            `json:"total"`
+	Nodes      map[string]Stats `json:"nodes"`
+}
+
+// Recorder accumulates per-iteration samples.
+type Recorder struct {
+	total []time.Duration
+	nodes map[string][]time.Duration
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{nodes: make(map[string][]time.Duration)}
+}
+
+// Add records one iteration.
+func (r *Recorder) Add(total time.Duration, nodes map[string]time.Duration) {
+	r.total = append(r.total, total)
+	for name, d := range nodes {
+		r.nodes[name] = append(r.nodes[name], d)
+	}
+}
+
+// Report summarizes the recorded iterations.
+func (r *Recorder) Report(graphHash, mode string) *Report {
+	rep := &Report{
+		GraphHash:  graphHash,
+		Mode:       mode,
+		Iterations: len(r.total),
+		Total:      Summarize(r.total),
+		Nodes:      make(map[string]Stats, len(r.nodes)),
+	}
+	for name, samples := range r.nodes {
+		rep.Nodes[name] = Summarize(samples)
+	}
+	return rep
+}
+
+// NodeNames returns the report's node names in lexical order.
+func (r *Report) NodeNames() []string {
+	names := make([]string, 0, len(r.Nodes))
+	for name := range r.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the report as an indented JSON baseline file.
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode baseline: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write baseline: %w", err)
+	}
+	return nil
+}
+
+// Load reads a baseline file written by Save, rejecting unknown fields.
+func Load(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read baseline: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var r Report
+	if err := dec.Decode(&r); err != nil {
+		return nil, fmt.Errorf("parse baseline: %w", err)
+	}
+	if r.Nodes == nil {
+		return nil, errors.New("parse baseline: missing nodes")
+	}
+	return &r, nil
+}
+
+// Delta compares the mean duration of one measurement with its baseline.
+type Delta struct {
+	Name     string
+	Baseline time.Duration
+	Current  time.Duration
+}
+
+// Change returns the relative change of Current over Baseline
+// (0.1 means 10% slower). It is zero when the baseline is zero.
+func (d Delta) Change() float64 {
+	if d.Baseline == 0 {
+		return 0
+	}
+	return float64(d.Current-d.Baseline) / float64(d.Baseline)
+}
+
+// Comparison is the result of comparing a report with a baseline.
+type Comparison struct {
+	// SameGraph is false when the baseline was recorded for a different graph.
+	SameGraph bool
+	Total     Delta
+	// Nodes covers nodes present in both reports, sorted by name.
+	Nodes []Delta
+}
+
+// Compare compares current against baseline using mean durations.
+func Compare(baseline, current *Report) Comparison {
+	c := Comparison{
+		SameGraph: core.SameHash(baseline.GraphHash, current.GraphHash),
+		Total:     Delta{Name: "total", Baseline: baseline.Total.Mean, Current: current.Total.Mean},
+	}
+	for _, name := range current.NodeNames() {
+		b, ok := baseline.Nodes[name]
+		if !ok {
+			continue
+		}
+		c.Nodes = append(c.Nodes, Delta{Name: name, Baseline: b.Mean, Current: current.Nodes[name].Mean})
+	}
+	return c
+}
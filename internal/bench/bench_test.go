@@ -0,0 +1,61 @@
+package bench
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func ms(n int) time.Duration { return time.Duration(n) * time.Millisecond }
+
+func TestSummarize(t *testing.T) {
+	samples := []time.Duration{}
+	for i := 20; i >= 1; i-- {
+		samples = append(samples, ms(i))
+	}
+	s := Summarize(samples)
+	if s.Samples != 20 || s.Min != ms(1) || s.Max != ms(20) {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if s.Mean != 10500*time.Microsecond || s.Median != 10500*time.Microsecond {
+		t.Fatalf("mean/median = %v/%v", s.Mean, s.Median)
+	}
+	if s.P95 != ms(19) {
+		t.Fatalf("p95 = %v", s.P95)
+	}
+	if got := Summarize([]time.Duration{ms(3), ms(1), ms(2)}); got.Median != ms(2) || got.P95 != ms(3) {
+		t.Fatalf("odd sample stats: %+v", got)
+	}
+	if got := Summarize(nil); got != (Stats{}) {
+		t.Fatalf("empty stats: %+v", got)
+	}
+}
+
+func TestReport_SaveLoadCompare(t *testing.T) {
+	r := NewRecorder()
+	r.Add(ms(10), map[string]time.Duration{"a": ms(4), "b": ms(6)})
+	r.Add(ms(20), map[string]time.Duration{"a": ms(6), "b": ms(14)})
+	base := r.Report("g1", "clean")
+	if base.Iterations != 2 || base.Total.Mean != ms(15) || base.Nodes["b"].Mean != ms(10) {
+		t.Fatalf("unexpected report: %+v", base)
+	}
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := base.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	cur := NewRecorder()
+	cur.Add(ms(30), map[string]time.Duration{"a": ms(5), "c": ms(1)})
+	c := Compare(loaded, cur.Report("g1", "clean"))
+	if !c.SameGraph || c.Total.Change() != 1 {
+		t.Fatalf("unexpected total comparison: %+v change=%v", c, c.Total.Change())
+	}
+	if len(c.Nodes) != 1 || c.Nodes[0].Name != "a" || c.Nodes[0].Change() != 0 {
+		t.Fatalf("unexpected node comparison: %+v", c.Nodes)
+	}
+}
@@ -0,0 +1,303 @@
+// Package chaos injects faults into a run to exercise its recovery paths:
+// failure classification, retries, checkpointing and resume, and the
+// isolation of plugin panics.
+//
+// Every decision is derived from the seed and from what is being decided on
+// (the task, the cache entry, the plugin hook), not from the order in which
+// decisions are made, so the same seed injects the same faults into the
+// same graph however its nodes are scheduled.
+package chaos
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/platform"
+)
+
+// Kinds of injected faults.
+const (
+	// TaskFailure makes a task that ran fail with an exit code chosen among
+	// those of the failure kinds state.ClassifyExit tells apart.
+	TaskFailure = "task-failure"
+	// CacheReadError makes a cache lookup fail with an I/O error.
+	CacheReadError = "cache-read-error"
+	// PluginPanic makes a plugin lifecycle hook panic.
+	PluginPanic = "plugin-panic"
+	// RestoreDelay delays the restoration of a node from its checkpoint.
+	RestoreDelay = "restore-delay"
+)
+
+// DefaultDelay is how long a delayed restore waits when Config.Delay is zero.
+const DefaultDelay = time.Second
+
+// failureExits are the exit codes of injected task failures: a plain
+// failure, a network outage, a timeout and an OOM kill.
+var failureExits = []int{1, 75, 124, 137}
+
+// Config is the fault injection of a run. Rates are probabilities between 0
+// and 1; a zero Config injects nothing.
+type Config struct {
+	Seed           int64   `json:"seed"`
+	TaskFailure    float64 `json:"task_failure,omitempty"`
+	CacheReadError float64 `json:"cache_read_error,omitempty"`
+	PluginPanic    float64 `json:"plugin_panic,omitempty"`
+	RestoreDelay   float64 `json:"restore_delay,omitempty"`
+	// Delay is how long a delayed restore waits; zero means DefaultDelay.
+	Delay time.Duration `json:"delay,omitempty"`
+}
+
+// ParseConfig parses a comma-separated list of key=value settings such as
+// "task-failure=0.1,cache-read-error=0.05,seed=42". The keys are the fault
+// kinds, whose values are rates, plus "delay" (a duration) and "seed".
+func ParseConfig(spec string) (Config, error) {
+	var c Config
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("chaos: %q is not key=value", item)
+		}
+		var err error
+		switch key {
+		case TaskFailure:
+			c.TaskFailure, err = parseRate(value)
+		case CacheReadError:
+			c.CacheReadError, err = parseRate(value)
+		case PluginPanic:
+			c.PluginPanic, err = parseRate(value)
+		case RestoreDelay:
+			c.RestoreDelay, err = parseRate(value)
+		case "delay":
+			c.Delay, err = time.ParseDuration(value)
+			if err == nil && c.Delay < 0 {
+				err = errors.New("must not be negative")
+			}
+		case "seed":
+			c.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return Config{}, fmt.Errorf("chaos: unknown setting %q", key)
+		}
+		if err != nil {
+			return Config{}, fmt.Errorf("chaos: invalid %s %q: %v", key, value, err)
+		}
+	}
+	return c, nil
+}
+
+func parseRate(s string) (float64, error) {
+	r, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if r < 0 || r > 1 {
+		return 0, errors.New("must be between 0 and 1")
+	}
+	return r, nil
+}
+
+// Fault is a fault that was injected.
+type Fault struct {
+	Kind string `json:"kind"`
+	// Target is the task, cache entry or plugin hook the fault hit.
+	Target string `json:"target"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Injector decides which faults to inject and records those it injected. It
+// is safe for concurrent use.
+type Injector struct {
+	Config Config
+	// Clock paces delayed restores; nil means the system clock.
+	Clock platform.Clock
+
+	mu     sync.Mutex
+	seen   map[string]int
+	faults []Fault
+}
+
+// New returns an Injector for c.
+func New(c Config) *Injector {
+	return &Injector{Config: c}
+}
+
+// roll reports whether a fault of kind hits key, with the given rate. Each
+// call for the same kind and key draws anew, so that a repeated cache read,
+// for example, does not always fail once it has failed. It also returns a
+// second draw the fault may use to choose its details.
+func (in *Injector) roll(kind, key string, rate float64) (bool, uint64) {
+	if rate <= 0 {
+		return false, 0
+	}
+	in.mu.Lock()
+	id := kind + "\x00" + key
+	n := in.seen[id]
+	if in.seen == nil {
+		in.seen = make(map[string]int)
+	}
+	in.seen[id] = n + 1
+	in.mu.Unlock()
+
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], uint64(in.Config.Seed))
+	h.Write(buf[:])
+	fmt.Fprintf(h, "%s\x00%d", id, n)
+	sum := h.Sum64()
+	// The top 53 bits are the draw; the rest choose the details.
+	return float64(sum>>11)/(1<<53) < rate, sum & 0x7ff
+}
+
+func (in *Injector) record(f Fault) {
+	in.mu.Lock()
+	in.faults = append(in.faults, f)
+	in.mu.Unlock()
+}
+
+// Faults returns the faults injected so far, ordered by kind and target.
+func (in *Injector) Faults() []Fault {
+	in.mu.Lock()
+	out := append([]Fault(nil), in.faults...)
+	in.mu.Unlock()
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Target < out[j].Target
+	})
+	return out
+}
+
+// PluginPanic reports whether the hook of a plugin panics; taskID is empty
+// for the run hooks. It fits pluginengine.HookEngine.SetPanicInjector.
+func (in *Injector) PluginPanic(pluginID, hook, taskID string) bool {
+	target := pluginID + "/" + hook
+	if taskID != "" {
+		target += "/" + taskID
+	}
+	if hit, _ := in.roll(PluginPanic, target, in.Config.PluginPanic); !hit {
+		return false
+	}
+	in.record(Fault{Kind: PluginPanic, Target: target})
+	return true
+}
+
+// Cache wraps c so that lookups fail at the configured rate.
+func (in *Injector) Cache(c core.Cache) core.Cache {
+	return faultyCache{Cache: c, in: in}
+}
+
+type faultyCache struct {
+	core.Cache
+	in *Injector
+}
+
+func (c faultyCache) readError(op string, hash core.TaskHash) error {
+	if hit, _ := c.in.roll(CacheReadError, hash.String(), c.in.Config.CacheReadError); !hit {
+		return nil
+	}
+	c.in.record(Fault{Kind: CacheReadError, Target: hash.String(), Detail: op})
+	return fmt.Errorf("chaos: injected cache read error: %w", &fs.PathError{Op: op, Path: hash.String(), Err: syscall.EIO})
+}
+
+func (c faultyCache) Has(hash core.TaskHash) (bool, error) {
+	if err := c.readError("has", hash); err != nil {
+		return false, err
+	}
+	return c.Cache.Has(hash)
+}
+
+func (c faultyCache) Get(hash core.TaskHash) (*core.CacheEntry, error) {
+	if err := c.readError("get", hash); err != nil {
+		return nil, err
+	}
+	return c.Cache.Get(hash)
+}
+
+// Evict forwards to the wrapped cache, so that wrapping keeps its optional
+// capability.
+func (c faultyCache) Evict(hash core.TaskHash) error {
+	ev, ok := c.Cache.(core.CacheEvicter)
+	if !ok {
+		return nil
+	}
+	return ev.Evict(hash)
+}
+
+// Runner wraps r so that tasks fail and restores are delayed at the
+// configured rates.
+func (in *Injector) Runner(r dag.TaskRunner) dag.TaskRunner {
+	return &faultyRunner{inner: r, in: in}
+}
+
+type faultyRunner struct {
+	inner dag.TaskRunner
+	in    *Injector
+}
+
+func (r *faultyRunner) Probe(ctx context.Context, task core.Task) (*dag.NodeResult, bool, error) {
+	return r.inner.Probe(ctx, task)
+}
+
+// ProbeBatch forwards to the wrapped runner when it batches probes.
+func (r *faultyRunner) ProbeBatch(ctx context.Context, tasks []core.Task) error {
+	if bp, ok := r.inner.(dag.BatchProber); ok {
+		return bp.ProbeBatch(ctx, tasks)
+	}
+	return nil
+}
+
+// Run runs task and then, when a failure is injected, reports it as failed:
+// the command has had its effects, as with a crash after the fact.
+func (r *faultyRunner) Run(ctx context.Context, task core.Task) (*dag.NodeResult, error) {
+	res, err := r.inner.Run(ctx, task)
+	if err != nil || res == nil || res.ExitCode != 0 {
+		return res, err
+	}
+	hit, detail := r.in.roll(TaskFailure, task.Name, r.in.Config.TaskFailure)
+	if !hit {
+		return res, nil
+	}
+	code := failureExits[detail%uint64(len(failureExits))]
+	r.in.record(Fault{Kind: TaskFailure, Target: task.Name, Detail: "exit " + strconv.Itoa(code)})
+	failed := *res
+	failed.ExitCode = code
+	failed.Stderr = append(append([]byte(nil), res.Stderr...), fmt.Sprintf("chaos: injected failure (exit %d)\n", code)...)
+	return &failed, nil
+}
+
+// Restore restores task, first waiting Delay when a delay is injected.
+func (r *faultyRunner) Restore(ctx context.Context, task core.Task) (*dag.NodeResult, error) {
+	restorer, ok := r.inner.(interface {
+		Restore(ctx context.Context, task core.Task) (*dag.NodeResult, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("runner does not support Restore for incremental plan execution")
+	}
+	if hit, _ := r.in.roll(RestoreDelay, task.Name, r.in.Config.RestoreDelay); hit {
+		d := r.in.Config.Delay
+		if d == 0 {
+			d = DefaultDelay
+		}
+		r.in.record(Fault{Kind: RestoreDelay, Target: task.Name, Detail: d.String()})
+		if err := platform.ClockOr(r.in.Clock).Sleep(ctx, d); err != nil {
+			return nil, err
+		}
+	}
+	return restorer.Restore(ctx, task)
+}
@@ -0,0 +1,121 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/platform"
+)
+
+func TestParseConfig(t *testing.T) {
+	c, err := ParseConfig("task-failure=0.25, cache-read-error=1,plugin-panic=0,restore-delay=0.5,delay=2s,seed=-7")
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	want := Config{Seed: -7, TaskFailure: 0.25, CacheReadError: 1, RestoreDelay: 0.5, Delay: 2 * time.Second}
+	if c != want {
+		t.Fatalf("config = %+v, want %+v", c, want)
+	}
+	for _, spec := range []string{"task-failure", "task-failure=1.5", "cache-read-error=-0.1", "delay=-1s", "seed=x", "disk-full=0.1"} {
+		if _, err := ParseConfig(spec); err == nil {
+			t.Errorf("ParseConfig(%q) succeeded", spec)
+		}
+	}
+}
+
+type okRunner struct{}
+
+func (okRunner) Probe(context.Context, core.Task) (*dag.NodeResult, bool, error) {
+	return nil, false, nil
+}
+
+func (okRunner) Run(_ context.Context, task core.Task) (*dag.NodeResult, error) {
+	return &dag.NodeResult{Stdout: []byte(task.Name)}, nil
+}
+
+func (okRunner) Restore(_ context.Context, task core.Task) (*dag.NodeResult, error) {
+	return &dag.NodeResult{Stdout: []byte(task.Name), FromCache: true}, nil
+}
+
+func TestInjector_DecisionsDependOnSeedAndTargetOnly(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	failed := func(seed int64, order []string) map[string]int {
+		r := New(Config{Seed: seed, TaskFailure: 0.5}).Runner(okRunner{})
+		out := make(map[string]int)
+		for _, name := range order {
+			res, err := r.Run(context.Background(), core.Task{Name: name})
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			out[name] = res.ExitCode
+		}
+		return out
+	}
+	reversed := make([]string, len(names))
+	for i, n := range names {
+		reversed[len(names)-1-i] = n
+	}
+	first := failed(1, names)
+	if !reflect.DeepEqual(first, failed(1, reversed)) {
+		t.Fatalf("decisions depend on the order of the tasks")
+	}
+	var hits int
+	for _, code := range first {
+		if code != 0 {
+			hits++
+			if code != 1 && code != 75 && code != 124 && code != 137 {
+				t.Fatalf("injected exit code %d", code)
+			}
+		}
+	}
+	if hits == 0 || hits == len(names) {
+		t.Fatalf("a 0.5 rate failed %d of %d tasks", hits, len(names))
+	}
+	if reflect.DeepEqual(first, failed(2, names)) && reflect.DeepEqual(first, failed(3, names)) {
+		t.Fatalf("decisions do not depend on the seed")
+	}
+}
+
+func TestInjector_InjectsEachKindOfFault(t *testing.T) {
+	clock := platform.NewFakeClock(time.Unix(0, 0))
+	in := New(Config{TaskFailure: 1, CacheReadError: 1, PluginPanic: 1, RestoreDelay: 1, Delay: 3 * time.Second})
+	in.Clock = clock
+	r := in.Runner(okRunner{})
+
+	res, err := r.Run(context.Background(), core.Task{Name: "build"})
+	if err != nil || res.ExitCode == 0 || len(res.Stderr) == 0 {
+		t.Fatalf("Run = %+v, %v", res, err)
+	}
+	restorer := r.(interface {
+		Restore(context.Context, core.Task) (*dag.NodeResult, error)
+	})
+	if res, err := restorer.Restore(context.Background(), core.Task{Name: "lint"}); err != nil || !res.FromCache {
+		t.Fatalf("Restore = %+v, %v", res, err)
+	}
+	if got := clock.Sleeps(); !reflect.DeepEqual(got, []time.Duration{3 * time.Second}) {
+		t.Fatalf("sleeps = %v", got)
+	}
+
+	cache := in.Cache(core.NewFileCache(t.TempDir()))
+	hash := core.TaskHash("abc123")
+	if _, err := cache.Get(hash); !errors.Is(err, syscall.EIO) {
+		t.Fatalf("Get = %v", err)
+	}
+	if !in.PluginPanic("lint-plugin", "BeforeNode", "build") {
+		t.Fatalf("plugin hook did not panic")
+	}
+
+	var kinds []string
+	for _, f := range in.Faults() {
+		kinds = append(kinds, f.Kind)
+	}
+	if want := []string{CacheReadError, PluginPanic, RestoreDelay, TaskFailure}; !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("faults = %v, want kinds %v", in.Faults(), want)
+	}
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"sort"
+	"time"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+// buildAttempts returns the attempts at each node of g after run runID: the
+// attempts recorded by the previous run, if runID retries or resumes one,
+// followed by the attempt of this run at every node that ran, was restored
+// or failed. Skipped, deduplicated and unreached nodes were not attempted.
+func buildAttempts(runID string, g *dag.TaskGraph, gr *dag.GraphResult, durations map[string]time.Duration, previous map[string][]state.NodeAttempt) map[string][]state.NodeAttempt {
+	out := make(map[string][]state.NodeAttempt)
+	for _, name := range g.TopologicalOrder() {
+		attempts := append([]state.NodeAttempt(nil), previous[name]...)
+		if _, dedup := gr.Deduplicated[name]; !dedup {
+			switch st := gr.FinalState[name]; st {
+			case dag.TaskCompleted, dag.TaskFailed, dag.TaskCached:
+				exit, _ := gr.ExitCodeOf(name)
+				attempts = append(attempts, state.NodeAttempt{
+					Attempt:   len(attempts) + 1,
+					RunID:     runID,
+					ExitCode:  exit,
+					Duration:  durations[name],
+					FromCache: st == dag.TaskCached,
+				})
+			}
+		}
+		if len(attempts) > 0 {
+			out[name] = attempts
+		}
+	}
+	return out
+}
+
+// saveAttempts records the attempts at the nodes of the run, best-effort.
+func (rc *RunContext) saveAttempts(gr *dag.GraphResult) {
+	var previous map[string][]state.NodeAttempt
+	if rc.previousRunID != nil {
+		previous, _ = rc.Store.LoadAttempts(*rc.previousRunID)
+	}
+	_ = rc.Store.SaveAttempts(rc.RunID, buildAttempts(rc.RunID, rc.Graph, gr, rc.timed.durations(), previous))
+}
+
+// FlakyNodes returns the nodes, sorted, that failed an attempt and passed a
+// later one, as when a retried run re-executes a node that failed
+// transiently.
+func FlakyNodes(attempts map[string][]state.NodeAttempt) []string {
+	var out []string
+	for name, list := range attempts {
+		failed := false
+		for _, a := range list {
+			if a.ExitCode != 0 {
+				failed = true
+			} else if failed {
+				out = append(out, name)
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
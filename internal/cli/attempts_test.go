@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestExecute_RecordsAttemptsAcrossRetries(t *testing.T) {
+	stubRetrySleep(t, func(context.Context, time.Duration) error { return nil })
+	// The first attempt fails transiently; the retry passes.
+	inv := retryInvocation(t, "if [ -f marker ]; then echo ok; else touch marker; echo 'curl: (7) Connection refused' >&2; exit 7; fi")
+	inv.Retries = 1
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess || len(res.Retries) != 1 {
+		t.Fatalf("exit=%d err=%v retries=%+v", res.ExitCode, err, res.Retries)
+	}
+	st, err := state.NewStore(inv.WorkDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	attempts, err := st.LoadAttempts(res.RunID)
+	if err != nil {
+		t.Fatalf("LoadAttempts: %v", err)
+	}
+	got := attempts["t1"]
+	if len(got) != 2 {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+	first, second := got[0], got[1]
+	if first.Attempt != 1 || first.RunID != res.Retries[0].RunID || first.ExitCode != 7 || first.FromCache {
+		t.Fatalf("first attempt = %+v", first)
+	}
+	if second.Attempt != 2 || second.RunID != res.RunID || second.ExitCode != 0 || second.FromCache || second.Duration <= 0 {
+		t.Fatalf("second attempt = %+v", second)
+	}
+	if flaky := FlakyNodes(attempts); !reflect.DeepEqual(flaky, []string{"t1"}) {
+		t.Fatalf("flaky = %v", flaky)
+	}
+
+	// A rerun restores t1 from the cache: a third attempt, in a new chain.
+	inv.Retries = 0
+	again, err := Execute(context.Background(), inv)
+	if err != nil || again.ExitCode != ExitSuccess {
+		t.Fatalf("rerun: exit=%d err=%v", again.ExitCode, err)
+	}
+	attempts, _ = st.LoadAttempts(again.RunID)
+	if got := attempts["t1"]; len(got) != 1 || !got[0].FromCache || got[0].Attempt != 1 {
+		t.Fatalf("rerun attempts = %+v", attempts)
+	}
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+// CacheDecisionsFileName is the run file that records the cache decision of
+// each node of a run as JSON Lines, one CacheDecision per line in topological
+// order, for cache-efficiency tooling. Which tier answered depends on the
+// machine, so it is kept out of the deterministic trace.
+const CacheDecisionsFileName = "cache-decisions.jsonl"
+
+// Cache decisions.
+const (
+	// CacheHit: the result was read from the cache instead of executing.
+	CacheHit = "hit"
+	// CacheMiss: no entry existed and the node executed.
+	CacheMiss = "miss"
+	// CacheRestored: the result was restored from a checkpoint of the run
+	// being resumed.
+	CacheRestored = "restored"
+	// CacheDeduplicated: the node shared the result of a byte-identical node.
+	CacheDeduplicated = "deduplicated"
+	// CacheSkipped: the node was skipped and never looked up.
+	CacheSkipped = "skipped"
+	// CacheNotRun: the run stopped before reaching the node.
+	CacheNotRun = "not-run"
+)
+
+// LocalCacheTier is the name of the first cache tier in a CacheDecision.
+const LocalCacheTier = "local"
+
+// CacheDecision is the cache decision taken for one node of a run.
+type CacheDecision struct {
+	Node     string `json:"node"`
+	Decision string `json:"decision"`
+	// Key is the task hash the cache was looked up by; empty for nodes that
+	// were never hashed.
+	Key string `json:"key,omitempty"`
+	// Tier is the tier a hit was read from: LocalCacheTier or the tier as
+	// configured in the workspace cache settings.
+	Tier string `json:"tier,omitempty"`
+	// BytesRestored is the size of the stdout, stderr and artifacts of a hit.
+	BytesRestored int64 `json:"bytes_restored"`
+}
+
+// buildCacheDecisions derives the cache decision of every node of g from the
+// outcome of the run and the lookups stats observed. tiers names the cache
+// tiers after the local one, in order.
+func buildCacheDecisions(g *dag.TaskGraph, gr *dag.GraphResult, stats *core.StatsCache, tiers []string) []CacheDecision {
+	var out []CacheDecision
+	for _, name := range g.TopologicalOrder() {
+		d := CacheDecision{Node: name, Key: gr.TaskHashOf(name).String()}
+		l, looked := stats.Lookup(gr.TaskHashOf(name))
+		_, dedup := gr.Deduplicated[name]
+		switch st := gr.FinalState[name]; {
+		case dedup:
+			d.Decision = CacheDeduplicated
+		case st == dag.TaskSkipped:
+			d.Decision = CacheSkipped
+		case looked && l.Hit:
+			d.Decision = CacheHit
+			d.Tier = LocalCacheTier
+			if l.Tier > 0 && l.Tier <= len(tiers) {
+				d.Tier = tiers[l.Tier-1]
+			}
+			d.BytesRestored = l.Bytes
+		case st == dag.TaskCached:
+			d.Decision = CacheRestored
+		case st == dag.TaskCompleted || st == dag.TaskFailed:
+			d.Decision = CacheMiss
+		default:
+			d.Decision = CacheNotRun
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// saveCacheDecisions records the cache decisions of runID, best-effort.
+func saveCacheDecisions(st *state.Store, runID string, decisions []CacheDecision) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, d := range decisions {
+		if enc.Encode(d) != nil {
+			return
+		}
+	}
+	_ = st.SaveRunFile(runID, CacheDecisionsFileName, buf.Bytes())
+}
+
+// LoadCacheDecisions returns the cache decisions recorded for run runID. Runs
+// that did not use the cache, such as clean runs, recorded none: the error
+// then wraps fs.ErrNotExist.
+func LoadCacheDecisions(st *state.Store, runID string) ([]CacheDecision, error) {
+	data, err := st.LoadRunFile(runID, CacheDecisionsFileName)
+	if err != nil {
+		return nil, err
+	}
+	var out []CacheDecision
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var d CacheDecision
+		if err := dec.Decode(&d); err != nil {
+			return nil, fmt.Errorf("parse %s of run %s: %w", CacheDecisionsFileName, runID, err)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
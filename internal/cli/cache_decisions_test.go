@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestExecute_RecordsCacheDecisions(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	cfg := `{"cache":{"tiers":["../team-cache"],"write":"write-all"}}`
+	if err := os.WriteFile(filepath.Join(workDir, ".scriptweaver", "config.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: "echo a > a.txt", Outputs: []string{"a.txt"}},
+		{Name: "b", Run: "echo b"},
+	}, nil)
+	run := func(localCache string, skip ...string) CLIResult {
+		t.Helper()
+		res, err := Execute(context.Background(), CLIInvocation{
+			WorkDir:       workDir,
+			GraphPath:     graphPath,
+			CacheDir:      filepath.Join(workDir, localCache),
+			OutputDir:     filepath.Join(workDir, "out"),
+			ExecutionMode: ExecutionModeIncremental,
+			Skip:          skip,
+		})
+		if err != nil || res.ExitCode != ExitSuccess {
+			t.Fatalf("run: exit %d, %v", res.ExitCode, err)
+		}
+		return res
+	}
+	decisions := func(res CLIResult) map[string]CacheDecision {
+		out := make(map[string]CacheDecision)
+		for _, d := range res.CacheDecisions {
+			out[d.Node] = d
+		}
+		return out
+	}
+
+	first := decisions(run("cache-a"))
+	if first["a"].Decision != CacheMiss || first["a"].Key == "" || first["a"].Tier != "" {
+		t.Fatalf("first run: %+v", first)
+	}
+
+	// A second machine reads a from the shared tier and then locally.
+	res := run("cache-b", "b")
+	second := decisions(res)
+	if d := second["a"]; d.Decision != CacheHit || d.Tier != "../team-cache" || d.Key != first["a"].Key || d.BytesRestored != 2 {
+		t.Fatalf("second run: a = %+v", d)
+	}
+	if d := second["b"]; d.Decision != CacheSkipped {
+		t.Fatalf("second run: b = %+v", d)
+	}
+	if d := decisions(run("cache-b"))["a"]; d.Decision != CacheHit || d.Tier != LocalCacheTier {
+		t.Fatalf("third run: a = %+v", d)
+	}
+
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	recorded, err := LoadCacheDecisions(st, res.RunID)
+	if err != nil || !reflect.DeepEqual(recorded, res.CacheDecisions) {
+		t.Fatalf("recorded = %+v, %v; want %+v", recorded, err, res.CacheDecisions)
+	}
+}
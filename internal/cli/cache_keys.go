@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/projectintegration/engine/workspace"
+)
+
+// loadCacheKeys reads the keys the local cache of workDir is encrypted with,
+// as configured by ce. It returns nil keys when ce is nil.
+func loadCacheKeys(workDir string, ce *config.CacheEncryptionConfig) (*core.CacheKeys, error) {
+	if ce == nil {
+		return nil, nil
+	}
+	var data []byte
+	if ce.KeyFile != "" {
+		path := ce.KeyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workDir, path)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("cache key file: %w", err)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+			return nil, fmt.Errorf("cache key file %s is accessible by other users; restrict it with chmod 600", path)
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("cache key file: %w", err)
+		}
+	} else {
+		var err error
+		if data, err = keychainLookup(ce.Keychain); err != nil {
+			return nil, fmt.Errorf("cache keys from keychain item %q: %w", ce.Keychain, err)
+		}
+	}
+	return core.ParseCacheKeys(data)
+}
+
+// keychainLookup returns the secret of the OS keychain item of service:
+// the login keychain on macOS, the Secret Service (GNOME Keyring, KWallet)
+// elsewhere. It is replaced in tests.
+var keychainLookup = func(service string) ([]byte, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-w")
+	case "windows":
+		return nil, fmt.Errorf("keychain items are not supported on windows; use key_file")
+	default:
+		cmd = exec.Command("secret-tool", "lookup", "service", service)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", cmd.Path, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return out, nil
+}
+
+// encryptedFileCache is the file cache of dir, encrypted with keys when they
+// are set.
+func encryptedFileCache(dir string, keys *core.CacheKeys) *core.FileCache {
+	c := core.NewFileCache(dir)
+	c.Keys = keys
+	return c
+}
+
+// ErrCacheNotEncrypted is returned by RekeyCache when the workspace config
+// has no cache_encryption settings.
+var ErrCacheNotEncrypted = errors.New("cache encryption is not configured")
+
+// RekeyCache re-encrypts the entries of the local cache of workDir, or of
+// cacheDir when set, with the current key of the workspace's
+// cache_encryption settings, and returns how many it rewrote.
+func RekeyCache(workDir, cacheDir string) (int, error) {
+	ws, err := workspace.EnsureWorkspace(workDir)
+	if err != nil {
+		return 0, err
+	}
+	if cacheDir == "" {
+		cacheDir = ws.CacheDir
+	}
+	cfg, _, err := config.LoadOptional(workDir)
+	if err != nil {
+		return 0, err
+	}
+	if cfg.CacheEncryption == nil {
+		return 0, ErrCacheNotEncrypted
+	}
+	keys, err := loadCacheKeys(workDir, cfg.CacheEncryption)
+	if err != nil {
+		return 0, err
+	}
+	return encryptedFileCache(cacheDir, keys).Rekey()
+}
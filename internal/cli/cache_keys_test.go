@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+)
+
+func TestExecute_EncryptsLocalCache(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeConfig := func(cfg string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(workDir, ".scriptweaver", "config.json"), []byte(cfg), 0o644); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	writeKeys := func(perm os.FileMode, keys ...byte) {
+		t.Helper()
+		var lines []string
+		for _, b := range keys {
+			lines = append(lines, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, core.CacheKeySize)))
+		}
+		path := filepath.Join(workDir, "cache.keys")
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), perm); err != nil {
+			t.Fatalf("write keys: %v", err)
+		}
+		if err := os.Chmod(path, perm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(`{"cache_encryption":{"key_file":"cache.keys"}}`)
+	writeKeys(0o600, 1)
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Run: "echo classified > a.txt", Outputs: []string{"a.txt"}}}, nil)
+	cacheDir := filepath.Join(workDir, "cache")
+	run := func() CLIResult {
+		t.Helper()
+		res, err := Execute(context.Background(), CLIInvocation{
+			WorkDir:       workDir,
+			GraphPath:     graphPath,
+			CacheDir:      cacheDir,
+			OutputDir:     filepath.Join(workDir, "out"),
+			ExecutionMode: ExecutionModeIncremental,
+		})
+		if err != nil || res.ExitCode != ExitSuccess {
+			t.Fatalf("run: exit %d, %v", res.ExitCode, err)
+		}
+		return res
+	}
+
+	run()
+	err := filepath.WalkDir(cacheDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if bytes.Contains(data, []byte("classified")) {
+			t.Errorf("%s holds plaintext", path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := run(); res.CacheStats == nil || res.CacheStats.Hits != 1 {
+		t.Fatalf("second run: %+v", res.CacheStats)
+	}
+
+	// Rotate: the new key comes first, the old one still decrypts until the
+	// cache is rekeyed.
+	writeKeys(0o600, 2, 1)
+	if n, err := RekeyCache(workDir, cacheDir); err != nil || n != 1 {
+		t.Fatalf("RekeyCache = %d, %v", n, err)
+	}
+	writeKeys(0o600, 2)
+	if res := run(); res.CacheStats.Hits != 1 {
+		t.Fatalf("run after rekey: %+v", res.CacheStats)
+	}
+
+	if _, err := ExportRun(workDir, run().RunID, io.Discard, ExportOptions{IncludeCache: true}); !errors.Is(err, ErrEncryptedCacheExport) {
+		t.Fatalf("export with cache: %v", err)
+	}
+
+	// A key file other users can read is refused.
+	writeKeys(0o644, 2)
+	res, _ := Execute(context.Background(), CLIInvocation{WorkDir: workDir, GraphPath: graphPath, CacheDir: cacheDir, ExecutionMode: ExecutionModeIncremental})
+	if res.ExitCode != ExitConfigError {
+		t.Fatalf("world-readable key file: exit %d", res.ExitCode)
+	}
+
+	// Keys can come from the OS keychain instead.
+	writeConfig(`{"cache_encryption":{"keychain":"sw-test"}}`)
+	defer func(orig func(string) ([]byte, error)) { keychainLookup = orig }(keychainLookup)
+	keychainLookup = func(service string) ([]byte, error) {
+		if service != "sw-test" {
+			return nil, errors.New("no such item")
+		}
+		return []byte(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, core.CacheKeySize)) + "\n"), nil
+	}
+	if res := run(); res.CacheStats.Hits != 1 {
+		t.Fatalf("run with keychain keys: %+v", res.CacheStats)
+	}
+}
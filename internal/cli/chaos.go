@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"encoding/json"
+
+	"scriptweaver/internal/chaos"
+	"scriptweaver/internal/recovery/state"
+)
+
+// ChaosFileName is the run file recording the faults injected into a run.
+const ChaosFileName = "chaos.json"
+
+// ChaosReport lists the faults injected into a run and the configuration,
+// including the seed, that reproduces them.
+type ChaosReport struct {
+	Config chaos.Config  `json:"config"`
+	Faults []chaos.Fault `json:"faults"`
+}
+
+// saveChaosReport records the injected faults of runID, best-effort.
+func saveChaosReport(st *state.Store, runID string, r *ChaosReport) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = st.SaveRunFile(runID, ChaosFileName, append(data, '\n'))
+}
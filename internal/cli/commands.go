@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"sort"
+	"strings"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+// CommandPreview is what running one node executes: the command, with its
+// working directory and its whole environment.
+type CommandPreview struct {
+	Node string
+	// Dir is the directory the command runs in.
+	Dir string
+	// Env is the complete environment of the command; the host environment
+	// is never passed through.
+	Env map[string]string
+	// Command is the shell command, run with sh -c. It is empty for nodes
+	// the engine runs itself: fetches and nodes of a plugin runner.
+	Command string
+	// Fetch is the URL a fetch node downloads.
+	Fetch string
+	// Runner is the plugin runner that executes the node.
+	Runner    string
+	NoNetwork bool
+}
+
+// ShellLine returns the command as a line that runs it from any shell as the
+// engine would, in its directory and with only its environment. It is empty
+// when the node has no shell command.
+func (p CommandPreview) ShellLine() string {
+	if p.Command == "" {
+		return ""
+	}
+	keys := make([]string, 0, len(p.Env))
+	for k := range p.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("(cd ")
+	b.WriteString(shellQuote(p.Dir))
+	b.WriteString(" && env -i")
+	for _, k := range keys {
+		b.WriteByte(' ')
+		b.WriteString(shellQuote(k + "=" + p.Env[k]))
+	}
+	b.WriteString(" sh -c ")
+	b.WriteString(shellQuote(p.Command))
+	b.WriteByte(')')
+	return b.String()
+}
+
+// PreviewCommands returns, in topological order, the commands a run of inv
+// would execute, without running anything: the graph is resolved as the run
+// resolves it, with namespaced outputs and pinned fetches, and the nodes the
+// run would not execute because of --skip or --node are left out. Cached
+// results are not considered, so in incremental mode a node may be restored
+// instead of executed.
+func PreviewCommands(inv CLIInvocation) ([]CommandPreview, error) {
+	g, err := resolveGraph(inv)
+	if err != nil {
+		return nil, err
+	}
+	run, skipped, err := runScope(g, inv)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []CommandPreview
+	for _, name := range g.TopologicalOrder() {
+		if !run[name] || skipped[name] {
+			continue
+		}
+		n, _ := g.Node(name)
+		p := CommandPreview{Node: name, Dir: inv.WorkDir, Env: core.EffectiveEnv(n.Task.Env), Runner: n.Task.Runner, NoNetwork: !n.Task.NetworkAllowed()}
+		switch {
+		case n.Task.Fetch != nil:
+			p.Fetch = n.Task.Fetch.URL
+		case n.Task.Runner == "":
+			p.Command = n.Task.Run
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// runScope returns the nodes of g a run of inv considers, leaving out the
+// isolated dependencies of --node, which are restored, and the nodes that do
+// not execute because --skip or --node skips them or a node they depend on.
+func runScope(g *dag.TaskGraph, inv CLIInvocation) (run, skipped map[string]bool, err error) {
+	skip, err := selectNodes(g, inv.Skip)
+	if err != nil {
+		return nil, nil, err
+	}
+	run = make(map[string]bool)
+	for _, name := range g.TopologicalOrder() {
+		run[name] = true
+	}
+	if inv.Node != "" || inv.Isolated {
+		upstream, others, err := nodeScope(g, inv.Node)
+		if err != nil {
+			return nil, nil, err
+		}
+		skip = append(skip, others...)
+		if inv.Isolated {
+			// Isolated dependencies are restored, not executed.
+			for _, name := range upstream {
+				delete(run, name)
+			}
+		}
+	}
+	// Whatever the skip policy, nothing depending on a skipped node executes.
+	skipped = make(map[string]bool)
+	for _, name := range skip {
+		skipped[name] = true
+	}
+	for name := range closure(skipped, newQueryGraph(g).rdeps) {
+		skipped[name] = true
+	}
+	return run, skipped, nil
+}
+
+// resolveGraph loads the graph of inv as a run of inv executes it: with its
+// outputs namespaced when requested and its fetches pinned by the lockfile.
+func resolveGraph(inv CLIInvocation) (*dag.TaskGraph, error) {
+	g, _, _, err := loadGraphAndHash(inv, newPhaseTimer(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if inv.NamespaceOutputs {
+		outputRel, err := outputDirRel(inv)
+		if err != nil {
+			return nil, err
+		}
+		if g, err = namespaceOutputs(g, outputRel); err != nil {
+			return nil, err
+		}
+	}
+	lockfile, err := LoadLockfile(inv.WorkDir)
+	if err != nil {
+		return nil, err
+	}
+	return pinFetches(g, lockfile)
+}
+
+// shellQuote quotes s as a single word for sh.
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_-.,:/@%+=", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
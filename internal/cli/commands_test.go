@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"":            "''",
+		"a.txt":       "a.txt",
+		"LANG=C":      "LANG=C",
+		"two words":   "'two words'",
+		"it's":        `'it'\''s'`,
+		"$HOME; rm x": "'$HOME; rm x'",
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestPreviewCommands_LinesRunAsTheEngineRunsThem(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Env: map[string]string{"MSG": "it's $fine"}, Run: `printf '%s|%s' "$MSG" "$HOME" > a.txt`, Outputs: []string{"a.txt"}},
+		{Name: "b", Inputs: []string{"a.txt"}, Run: "cat a.txt > b.txt", Outputs: []string{"b.txt"}},
+		{Name: "c", Run: "true"},
+	}, []dag.Edge{{From: "a", To: "b"}})
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out")}
+
+	previews, err := PreviewCommands(inv)
+	if err != nil {
+		t.Fatalf("PreviewCommands: %v", err)
+	}
+	var nodes []string
+	for _, p := range previews {
+		nodes = append(nodes, p.Node)
+	}
+	if !reflect.DeepEqual(nodes, []string{"a", "b", "c"}) {
+		t.Fatalf("nodes = %v", nodes)
+	}
+	if want := map[string]string{"MSG": "it's $fine", "LANG": "C", "LC_ALL": "C", "TZ": "UTC"}; !reflect.DeepEqual(previews[0].Env, want) {
+		t.Fatalf("env = %v", previews[0].Env)
+	}
+
+	// The host environment does not leak into the pasted line either.
+	if out, err := exec.Command("sh", "-c", previews[0].ShellLine()).CombinedOutput(); err != nil {
+		t.Fatalf("run %s: %v: %s", previews[0].ShellLine(), err, out)
+	}
+	if got, _ := os.ReadFile(filepath.Join(workDir, "a.txt")); string(got) != "it's $fine|" {
+		t.Fatalf("a.txt = %q", got)
+	}
+
+	inv.Skip = []string{"a"}
+	if previews, err = PreviewCommands(inv); err != nil || len(previews) != 1 || previews[0].Node != "c" {
+		t.Fatalf("with --skip a: %v %v", previews, err)
+	}
+	inv.Skip, inv.Node, inv.Isolated = nil, "b", true
+	if previews, err = PreviewCommands(inv); err != nil || len(previews) != 1 || previews[0].Node != "b" {
+		t.Fatalf("isolated b: %v %v", previews, err)
+	}
+}
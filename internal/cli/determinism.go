@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"scriptweaver/internal/dag"
+)
+
+// Aspects of a node that a determinism audit compares.
+const (
+	AuditState    = "state"
+	AuditTaskHash = "task_hash"
+	AuditOutput   = "output"
+)
+
+// Divergence is a difference between the serial and the parallel run of a
+// determinism audit. Values missing from one run are empty.
+type Divergence struct {
+	Node   string `json:"node"`
+	Aspect string `json:"aspect"`
+	// Path is the output file that differs, for AuditOutput.
+	Path     string `json:"path,omitempty"`
+	Serial   string `json:"serial"`
+	Parallel string `json:"parallel"`
+}
+
+// DeterminismAudit is the outcome of running a graph serially and then in
+// parallel.
+type DeterminismAudit struct {
+	SerialRunID   string       `json:"serial_run_id"`
+	ParallelRunID string       `json:"parallel_run_id"`
+	Workers       int          `json:"workers"`
+	Nodes         int          `json:"nodes"`
+	Divergences   []Divergence `json:"divergences"`
+}
+
+// Deterministic reports whether both runs agreed on every node.
+func (a DeterminismAudit) Deterministic() bool {
+	return len(a.Divergences) == 0
+}
+
+// AuditDeterminism runs inv twice in clean mode, serially and then in
+// parallel on workers workers, and compares the final state, the task hash
+// and the hashes of the produced outputs of every node. Both runs must agree
+// whatever the scheduler does, so any divergence is either a nondeterministic
+// task or a scheduler bug.
+//
+// The declared outputs of every node are removed before each run, so that
+// neither run observes what an earlier one left. A run that fails is still
+// compared; only an invocation that cannot run at all is an error.
+func AuditDeterminism(ctx context.Context, inv CLIInvocation, workers int) (DeterminismAudit, error) {
+	report := DeterminismAudit{Workers: workers}
+	g, err := resolveGraph(inv)
+	if err != nil {
+		return report, err
+	}
+	report.Nodes = len(g.Nodes())
+	inv.ExecutionMode = ExecutionModeClean
+	inv.ResumeRunID, inv.Retries, inv.VerifyDeterminism = "", 0, 0
+
+	var runs [2]CLIResult
+	for i, n := range []int{0, workers} {
+		if err := removeDeclaredOutputs(inv.WorkDir, g); err != nil {
+			return report, err
+		}
+		inv.Workers = n
+		res, err := Execute(ctx, inv)
+		if err != nil {
+			return report, err
+		}
+		runs[i] = res
+	}
+	report.SerialRunID, report.ParallelRunID = runs[0].RunID, runs[1].RunID
+	report.Divergences = diffRuns(g, runs[0], runs[1])
+	return report, nil
+}
+
+func removeDeclaredOutputs(workDir string, g *dag.TaskGraph) error {
+	for _, n := range g.Nodes() {
+		for _, output := range n.Task.Outputs {
+			if err := os.RemoveAll(filepath.Join(workDir, output)); err != nil {
+				return fmt.Errorf("removing %q: %w", output, err)
+			}
+		}
+	}
+	return nil
+}
+
+// diffRuns returns the divergences between the serial run a and the parallel
+// run b of g, by node in topological order.
+func diffRuns(g *dag.TaskGraph, a, b CLIResult) []Divergence {
+	outputsA, outputsB := outputHashes(a.Outputs), outputHashes(b.Outputs)
+	out := []Divergence{}
+	for _, name := range g.TopologicalOrder() {
+		if sa, sb := string(a.GraphResult.FinalState[name]), string(b.GraphResult.FinalState[name]); sa != sb {
+			out = append(out, Divergence{Node: name, Aspect: AuditState, Serial: sa, Parallel: sb})
+		}
+		if ha, hb := string(a.GraphResult.TaskHashOf(name)), string(b.GraphResult.TaskHashOf(name)); ha != hb {
+			out = append(out, Divergence{Node: name, Aspect: AuditTaskHash, Serial: ha, Parallel: hb})
+		}
+		paths := make(map[string]bool)
+		for path := range outputsA[name] {
+			paths[path] = true
+		}
+		for path := range outputsB[name] {
+			paths[path] = true
+		}
+		sorted := make([]string, 0, len(paths))
+		for path := range paths {
+			sorted = append(sorted, path)
+		}
+		sort.Strings(sorted)
+		for _, path := range sorted {
+			if ha, hb := outputsA[name][path], outputsB[name][path]; ha != hb {
+				out = append(out, Divergence{Node: name, Aspect: AuditOutput, Path: path, Serial: ha, Parallel: hb})
+			}
+		}
+	}
+	return out
+}
+
+// outputHashes indexes the sha256 of every output file by node and path.
+func outputHashes(outputs []NodeOutputs) map[string]map[string]string {
+	out := make(map[string]map[string]string, len(outputs))
+	for _, no := range outputs {
+		files := make(map[string]string, len(no.Files))
+		for _, f := range no.Files {
+			files[f.Path] = f.SHA256
+		}
+		out[no.Node] = files
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestAuditDeterminism_ReportsDivergingNodes(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: "printf a > a.txt", Outputs: []string{"a.txt"}},
+		{Name: "b", Run: "printf b > b.txt", Outputs: []string{"b.txt"}},
+		{Name: "c", Inputs: []string{"a.txt", "b.txt"}, Run: "cat a.txt b.txt > c.txt", Outputs: []string{"c.txt"}},
+	}, []dag.Edge{{From: "a", To: "c"}, {From: "b", To: "c"}})
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out")}
+
+	report, err := AuditDeterminism(context.Background(), inv, 2)
+	if err != nil {
+		t.Fatalf("AuditDeterminism: %v", err)
+	}
+	if !report.Deterministic() || report.Nodes != 3 || report.SerialRunID == "" || report.SerialRunID == report.ParallelRunID {
+		t.Fatalf("report = %+v", report)
+	}
+
+	// b counts the recorded runs, which the serial run changes.
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: "printf a > a.txt", Outputs: []string{"a.txt"}},
+		{Name: "b", Run: "ls .scriptweaver/runs | wc -l > b.txt", Outputs: []string{"b.txt"}},
+	}, nil)
+	if report, err = AuditDeterminism(context.Background(), inv, 2); err != nil {
+		t.Fatalf("AuditDeterminism: %v", err)
+	}
+	if len(report.Divergences) != 1 {
+		t.Fatalf("divergences = %+v", report.Divergences)
+	}
+	if d := report.Divergences[0]; d.Node != "b" || d.Aspect != AuditOutput || d.Path != "b.txt" || d.Serial == d.Parallel {
+		t.Fatalf("divergence = %+v", d)
+	}
+}
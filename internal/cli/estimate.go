@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/projectintegration/engine/workspace"
+	"scriptweaver/internal/recovery/state"
+)
+
+// EstimateHistory is the number of most recent runs whose timelines
+// EstimateRun takes node durations from.
+const EstimateHistory = 20
+
+// What a run is expected to do with a node.
+const (
+	EstimateExecute = "execute"
+	EstimateReuse   = "reuse"
+	EstimateSkip    = "skip"
+)
+
+// EstimatedNode is the expected outcome of one node of a run.
+type EstimatedNode struct {
+	Node     string `json:"node"`
+	Decision string `json:"decision"`
+	// Duration is how long the node took when it last executed, among the
+	// EstimateHistory most recent runs. Known is false when it did not
+	// execute in any of them; Duration is then zero.
+	Duration time.Duration `json:"duration_ns"`
+	Known    bool          `json:"known"`
+}
+
+// RunEstimate is the expected cost of a run, from the state of the cache and
+// the durations of earlier runs. Nodes are in topological order.
+type RunEstimate struct {
+	Nodes   []EstimatedNode `json:"nodes"`
+	Execute int             `json:"execute"`
+	Reuse   int             `json:"reuse"`
+	Skip    int             `json:"skip"`
+	// Unknown counts the executing nodes without a known duration.
+	Unknown int `json:"unknown"`
+	// Work is the sum of the durations of the executing nodes.
+	Work time.Duration `json:"work_ns"`
+	// Wall is the expected wall time: Work for a serial run, or the
+	// makespan of scheduling the executing nodes on the run's workers.
+	Wall time.Duration `json:"wall_ns"`
+	// Runs is the number of earlier runs durations were taken from.
+	Runs int `json:"runs"`
+}
+
+// EstimateRun estimates what a run of inv would cost without running
+// anything: which nodes it executes, reuses from the cache or skips, and how
+// long it takes.
+//
+// In incremental mode a node is expected to be reused when every node it
+// depends on is reused and the cache holds a successful entry for its task
+// hash, computed as WarmCache computes it. A node depending on an executing
+// node is expected to execute: its inputs are not known until then, even
+// though the run may still find it cached. Shared cache tiers are consulted
+// unless inv.Offline is set. In clean mode every node in scope executes.
+func EstimateRun(inv CLIInvocation) (RunEstimate, error) {
+	g, err := resolveGraph(inv)
+	if err != nil {
+		return RunEstimate{}, err
+	}
+	run, skipped, err := runScope(g, inv)
+	if err != nil {
+		return RunEstimate{}, err
+	}
+	ws, err := workspace.EnsureWorkspace(inv.WorkDir)
+	if err != nil {
+		return RunEstimate{}, err
+	}
+	cfg, _, err := config.LoadOptional(inv.WorkDir)
+	if err != nil {
+		return RunEstimate{}, err
+	}
+	var cache core.Cache = noCache{}
+	if inv.ExecutionMode != ExecutionModeClean {
+		cacheDir := inv.CacheDir
+		if cacheDir == "" {
+			cacheDir = ws.CacheDir
+		}
+		cache = core.NewFileCache(cacheDir)
+		if cc := cfg.Cache; cc != nil {
+			if inv.Offline {
+				cc = localTiers(cc)
+			}
+			if cache, err = withSharedTiers(cache, cc, inv.WorkDir, g.Hash().String()); err != nil {
+				return RunEstimate{}, err
+			}
+		}
+	}
+	st, err := state.NewStore(inv.WorkDir)
+	if err != nil {
+		return RunEstimate{}, err
+	}
+	durations, runs, err := recentDurations(st)
+	if err != nil {
+		return RunEstimate{}, err
+	}
+
+	upstream := upstreamByNode(g)
+	hasher := &core.TaskHasher{Algorithm: hashAlgorithm(cfg)}
+	resolver := core.NewInputResolver(inv.WorkDir)
+	restored := make(map[string][]byte)
+	est := RunEstimate{Runs: runs}
+	decision := make(map[string]string)
+	for _, name := range g.TopologicalOrder() {
+		node, _ := g.Node(name)
+		d := EstimateExecute
+		switch {
+		case skipped[name]:
+			d = EstimateSkip
+		case !run[name]:
+			// Isolated dependencies are restored from the cache.
+			d = EstimateReuse
+		case inv.Isolated && name == inv.Node:
+			// A node run in isolation executes even when cached.
+		default:
+			if reused, err := cachedResult(cache, hasher, resolver, node.Task, upstream[name], decision, restored, inv.WorkDir); err != nil {
+				return RunEstimate{}, fmt.Errorf("node %s: %w", name, err)
+			} else if reused {
+				d = EstimateReuse
+			}
+		}
+		decision[name] = d
+		n := EstimatedNode{Node: name, Decision: d}
+		switch d {
+		case EstimateExecute:
+			est.Execute++
+			n.Duration, n.Known = durations[name]
+			if !n.Known {
+				est.Unknown++
+			}
+			est.Work += n.Duration
+		case EstimateReuse:
+			est.Reuse++
+		case EstimateSkip:
+			est.Skip++
+		}
+		est.Nodes = append(est.Nodes, n)
+	}
+	est.Wall = makespan(est.Nodes, upstream, inv.Workers)
+	return est, nil
+}
+
+// cachedResult reports whether cache holds a successful entry for task, whose
+// dependencies deps have the given decisions. The artifacts of a reused entry
+// are added to restored, so that dependents are hashed over them.
+func cachedResult(cache core.Cache, hasher *core.TaskHasher, resolver *core.InputResolver, task core.Task, deps []string, decision map[string]string, restored map[string][]byte, workDir string) (bool, error) {
+	for _, d := range deps {
+		if decision[d] != EstimateReuse {
+			return false, nil
+		}
+	}
+	inputs, err := resolveWithRestored(resolver, task.Inputs, restored)
+	if err != nil {
+		// Inputs missing from disk: the run reports them when the node runs.
+		return false, nil
+	}
+	entry, err := cache.Get(hashTaskInputs(hasher, task, inputs, workDir))
+	if err != nil {
+		return false, err
+	}
+	if entry == nil || entry.ExitCode != 0 {
+		return false, nil
+	}
+	for _, a := range entry.Artifacts {
+		restored[filepath.ToSlash(filepath.Join(workDir, filepath.FromSlash(a.Path)))] = a.Content
+	}
+	return true, nil
+}
+
+// recentDurations returns how long each node took when it last executed,
+// among the EstimateHistory most recent runs that recorded a timeline, and
+// the number of those runs.
+func recentDurations(st *state.Store) (map[string]time.Duration, int, error) {
+	ids, err := st.ListRunIDs()
+	if err != nil {
+		return nil, 0, err
+	}
+	durations := make(map[string]time.Duration)
+	runs := 0
+	// Run IDs are time-ordered: the newest run comes last.
+	for i := len(ids) - 1; i >= 0 && runs < EstimateHistory; i-- {
+		data, err := st.LoadRunFile(ids[i], TimelineFileName)
+		if err != nil {
+			continue
+		}
+		var tl Timeline
+		if err := json.Unmarshal(data, &tl); err != nil {
+			continue
+		}
+		runs++
+		for _, n := range tl.Nodes {
+			if _, seen := durations[n.Name]; !seen && n.Status == TimelineExecuted {
+				durations[n.Name] = n.Duration()
+			}
+		}
+	}
+	return durations, runs, nil
+}
+
+// makespan is the wall time of running the executing nodes on workers, in
+// topological order, each as soon as its dependencies have finished and a
+// worker is free; zero workers run them one after another.
+func makespan(nodes []EstimatedNode, upstream map[string][]string, workers int) time.Duration {
+	if workers < 1 {
+		workers = 1
+	}
+	free := make([]time.Duration, workers)
+	finished := make(map[string]time.Duration, len(nodes))
+	var wall time.Duration
+	for _, n := range nodes {
+		var ready time.Duration
+		for _, d := range upstream[n.Node] {
+			ready = max(ready, finished[d])
+		}
+		if n.Decision != EstimateExecute {
+			finished[n.Node] = ready
+			continue
+		}
+		w := 0
+		for i := range free {
+			if free[i] < free[w] {
+				w = i
+			}
+		}
+		end := max(ready, free[w]) + n.Duration
+		free[w], finished[n.Node] = end, end
+		wall = max(wall, end)
+	}
+	return wall
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestEstimateRun_FromCacheStateAndPastDurations(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "src.txt"), []byte("v1"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Inputs: []string{"src.txt"}, Run: "cat src.txt > a.txt", Outputs: []string{"a.txt"}},
+		{Name: "b", Inputs: []string{"a.txt"}, Run: "cat a.txt > b.txt", Outputs: []string{"b.txt"}},
+		{Name: "c", Run: "echo c > c.txt", Outputs: []string{"c.txt"}},
+	}, []dag.Edge{{From: "a", To: "b"}})
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+
+	est, err := EstimateRun(inv)
+	if err != nil {
+		t.Fatalf("EstimateRun: %v", err)
+	}
+	if est.Execute != 3 || est.Reuse != 0 || est.Unknown != 3 || est.Runs != 0 || est.Wall != 0 {
+		t.Fatalf("before any run: %+v", est)
+	}
+
+	if res, err := Execute(context.Background(), inv); err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+	if est, err = EstimateRun(inv); err != nil {
+		t.Fatalf("EstimateRun: %v", err)
+	}
+	if est.Execute != 0 || est.Reuse != 3 || est.Runs != 1 || est.Wall != 0 {
+		t.Fatalf("after a run: %+v", est)
+	}
+
+	// a changed, so it and b, which depends on it, execute again.
+	if err := os.WriteFile(filepath.Join(workDir, "src.txt"), []byte("v2"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	inv.Skip = []string{"c"}
+	if est, err = EstimateRun(inv); err != nil {
+		t.Fatalf("EstimateRun: %v", err)
+	}
+	if est.Execute != 2 || est.Skip != 1 || est.Unknown != 0 || est.Wall != est.Work || est.Wall <= 0 {
+		t.Fatalf("after a change: %+v", est)
+	}
+	for _, n := range est.Nodes {
+		if want := map[string]string{"a": EstimateExecute, "b": EstimateExecute, "c": EstimateSkip}[n.Node]; n.Decision != want {
+			t.Fatalf("%s: %s, want %s", n.Node, n.Decision, want)
+		}
+	}
+}
+
+func TestMakespan_SchedulesExecutingNodesOnWorkers(t *testing.T) {
+	nodes := []EstimatedNode{
+		{Node: "a", Decision: EstimateExecute, Duration: 3 * time.Second},
+		{Node: "b", Decision: EstimateExecute, Duration: 2 * time.Second},
+		{Node: "r", Decision: EstimateReuse},
+		{Node: "c", Decision: EstimateExecute, Duration: time.Second},
+	}
+	upstream := map[string][]string{"c": {"b", "r"}}
+	for workers, want := range map[int]time.Duration{0: 6 * time.Second, 1: 6 * time.Second, 2: 3 * time.Second} {
+		if got := makespan(nodes, upstream, workers); got != want {
+			t.Fatalf("%d workers: %v, want %v", workers, got, want)
+		}
+	}
+}
@@ -2,21 +2,27 @@ package cli
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"scriptweaver/internal/audit"
 	"scriptweaver/internal/core"
 	"scriptweaver/internal/dag"
-	"scriptweaver/internal/graph"
 	"scriptweaver/internal/incremental"
+	"scriptweaver/internal/objectstore"
 	"scriptweaver/internal/pluginengine"
-	"scriptweaver/internal/projectintegration/engine/workspace"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/publish"
 	"scriptweaver/internal/recovery/state"
 	"scriptweaver/internal/trace"
 )
@@ -42,8 +48,83 @@ func (defaultGraphExecutor) Run(ctx context.Context, graph *dag.TaskGraph, runne
 }
 
 type cliGraphExecutor struct {
-	Plan     *incremental.IncrementalPlan
-	Observer dag.NodeObserver
+	Plan        *incremental.IncrementalPlan
+	Observer    dag.NodeObserver
+	Deduplicate bool
+	Results     *dag.ResultStore
+	// Hooks runs the allowlisted plugins' lifecycle hooks; nil when no
+	// plugins are active.
+	Hooks *pluginengine.HookEngine
+	// LabelLimits caps the tasks of a label running at once.
+	LabelLimits map[string]int
+	// Progress receives aggregate progress events; nil disables them.
+	Progress func(dag.Progress)
+	// Skip and SkipPolicy select the nodes not to run (see dag.Executor.Skip).
+	Skip       []string
+	SkipPolicy dag.SkipPolicy
+	// Workers, when positive, runs the graph with RunParallel.
+	Workers int
+	// Replan executes nodes whose planned restore fails instead of failing
+	// them (see dag.Executor.Replan). Isolated runs leave it off, as their
+	// dependencies must come from the cache.
+	Replan bool
+	// TraceSink receives the trace events as they are recorded; nil when the
+	// run's trace is not journaled.
+	TraceSink trace.Sink
+}
+
+// reportsDirName is the directory under the output directory where reporter
+// plugins write their artifacts, one subdirectory per plugin.
+const reportsDirName = "reports"
+
+// largeGraphNodes is the node count from which per-node outputs are kept in a
+// columnar dag.ResultStore instead of GraphResult maps.
+const largeGraphNodes = 10000
+
+func resultStoreFor(g *dag.TaskGraph) *dag.ResultStore {
+	if len(g.Nodes()) < largeGraphNodes {
+		return nil
+	}
+	return dag.NewResultStore()
+}
+
+// loadPlugins discovers the plugins under inv's plugins root, skips those
+// that fail signature verification against keys, and activates the
+// allowlisted ones. Discovery is deterministic and non-recursive; absence of
+// plugins is valid.
+func loadPlugins(inv CLIInvocation, keys []ed25519.PublicKey, log pluginengine.Logger) (*pluginengine.HookEngine, error) {
+	pluginsRoot := filepath.Join(inv.WorkDir, pluginengine.DefaultPluginsRoot)
+	if strings.TrimSpace(inv.PluginDir) != "" {
+		pluginsRoot = inv.PluginDir
+	}
+	reg, _ := discoverPlugins(pluginsRoot, log)
+	reg = reg.Verify(keys, inv.AllowUnsignedPlugins, log)
+	return activatePlugins(reg, inv.Plugins, log)
+}
+
+// pluginLogger returns l, or a logger writing to stderr when l is nil.
+func pluginLogger(l pluginengine.Logger) pluginengine.Logger {
+	if l == nil {
+		return log.New(os.Stderr, "", 0)
+	}
+	return l
+}
+
+// activatePlugins filters the discovered plugins by allowlist and wires their
+// runtimes into a HookEngine. It returns nil when the allowlist is empty.
+func activatePlugins(reg pluginengine.Registry, allow []string, log pluginengine.Logger) (*pluginengine.HookEngine, error) {
+	if len(allow) == 0 {
+		return nil, nil
+	}
+	active, err := reg.Filter(allow)
+	if err != nil {
+		return nil, err
+	}
+	plugins, err := pluginengine.Instantiate(active)
+	if err != nil {
+		return nil, err
+	}
+	return pluginengine.NewHookEngine(plugins, log)
 }
 
 func (c cliGraphExecutor) Run(ctx context.Context, graph *dag.TaskGraph, runner dag.TaskRunner) (*dag.GraphResult, error) {
@@ -53,12 +134,90 @@ func (c cliGraphExecutor) Run(ctx context.Context, graph *dag.TaskGraph, runner
 	}
 	exec.Plan = c.Plan
 	exec.Observer = c.Observer
+	exec.Deduplicate = c.Deduplicate
+	exec.Results = c.Results
+	exec.LabelLimits = c.LabelLimits
+	exec.Progress = c.Progress
+	exec.Skip = c.Skip
+	exec.SkipPolicy = c.SkipPolicy
+	exec.Replan = c.Replan
+	exec.TraceSink = c.TraceSink
+	if c.Hooks != nil {
+		exec.Hooks = c.Hooks
+	}
+	if c.Workers > 0 {
+		return exec.RunParallel(ctx, c.Workers)
+	}
 	return exec.RunSerial(ctx)
 }
 
 type CLIResult struct {
 	ExitCode    int
 	GraphResult *dag.GraphResult
+	// Nondeterministic lists sampled tasks whose repeated executions disagreed
+	// (only populated when CLIInvocation.VerifyDeterminism > 0).
+	Nondeterministic []core.DeterminismResult
+	// PublishedTo and Published describe the publish step, when one is
+	// configured and the run succeeded.
+	PublishedTo string
+	Published   []publish.File
+	// Duration is the wall-clock time spent executing the graph, and
+	// NodeDurations the time spent probing and running each node.
+	Duration      time.Duration
+	NodeDurations map[string]time.Duration
+	// Phases lists the engine phases that completed, in order, with their
+	// wall-clock durations.
+	Phases []PhaseTiming
+	// PluginStats summarizes the hook activity of each allowlisted plugin.
+	PluginStats []pluginengine.PluginStats
+	// Findings lists the validator plugins' findings when they rejected the
+	// graph.
+	Findings []pluginengine.Finding
+	// Resume describes which nodes were reused from a previous run's
+	// checkpoints; nil when the run did not resume.
+	Resume *ResumeReport
+	// RunID identifies the run in the recovery store; empty when no run
+	// record could be created.
+	RunID string
+	// Retries lists the runs that failed transiently and were retried, in
+	// order (only populated when CLIInvocation.Retries > 0).
+	Retries []RetryAttempt
+	// NodeFailures holds the post-mortem context of every failed node, ordered
+	// by node name; it is also recorded under the run's failures directory.
+	NodeFailures []state.NodeFailure
+	// Failure is the failure recorded for an unsuccessful run, or nil. Its
+	// Kind and Transient tell a task that merely exited nonzero from one that
+	// was OOM-killed, timed out or hit a network outage.
+	Failure *state.Failure
+	// StaleOutputs lists the files that earlier runs produced but that no node
+	// of the graph declares any more, found while planning. They are left in
+	// place; sw clean --stale-outputs removes them.
+	StaleOutputs []StaleOutput
+	// Outputs lists the files each successful node produced, in topological
+	// order, with sizes and hashes; it is also recorded as the run's
+	// OutputsFileName.
+	Outputs []NodeOutputs
+	// CacheDir is the artifact cache the run read and wrote: the invocation's
+	// CacheDir, or the workspace cache when it was empty. It is empty for
+	// runs that use no shared cache, such as clean runs.
+	CacheDir string
+	// CacheStats counts the cache hits and misses of the run; it is also
+	// recorded as the run's CacheStatsFileName. It is nil for clean runs.
+	CacheStats *core.CacheStats
+	// CacheDecisions is the cache decision of each node, in topological
+	// order. It is also recorded as the run's CacheDecisionsFileName. It is
+	// nil for clean runs.
+	CacheDecisions []CacheDecision
+	// Chaos lists the faults injected into the run; nil unless
+	// CLIInvocation.Chaos is set. It is also recorded as the run's
+	// ChaosFileName.
+	Chaos *ChaosReport
+	// Purged lists the old runs removed after the run per the workspace
+	// retention settings; nil when none were.
+	Purged *PurgeReport
+	// NotifyError is why the run's outcome could not be posted to the
+	// workspace's notify webhook; empty when it was, or needed not be.
+	NotifyError string
 }
 
 // Execute is the default entrypoint for running a canonical invocation.
@@ -74,220 +233,25 @@ func Execute(ctx context.Context, inv CLIInvocation) (CLIResult, error) {
 //   - Initialize trace output before execution and finalize after execution,
 //     even on panic/failure.
 //   - Translate engine outcomes to semantic exit codes.
-func ExecuteWithExecutor(ctx context.Context, inv CLIInvocation, executor GraphExecutor) (res CLIResult, execErr error) {
-	res.ExitCode = ExitInternalError
-	if executor == nil {
-		return res, fmt.Errorf("nil executor")
-	}
-
-	// Initialize recovery store as early as possible so failures can be recorded.
-	st, _ := state.NewStore(inv.WorkDir)
-	rec := &state.FailureRecorder{Store: st}
-	runID, _ := rec.NewRunID()
-
-	// Best-effort: validate/init .scriptweaver workspace; even if this fails,
-	// we still attempt to record a WorkspaceFailure.
-	_, wsErr := workspace.EnsureWorkspace(inv.WorkDir)
-	if wsErr != nil {
-		if runID != "" {
-			_ = rec.StartRun(state.Run{RunID: runID, GraphHash: "", StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: 0, Status: "failed", PreviousRunID: nil})
-			_ = rec.RecordFailure(runID, &state.WorkspaceFailureError{Code: "WorkspaceInvalid", Message: wsErr.Error(), Cause: wsErr})
-		}
-		res.ExitCode = ExitConfigError
-		return res, wsErr
-	}
-
-	// Plugin registration occurs at engine startup.
-	// Discovery is deterministic and non-recursive; absence of plugins is valid.
-	pluginsRoot := filepath.Join(inv.WorkDir, pluginengine.DefaultPluginsRoot)
-	pluginLog := log.New(os.Stderr, "", 0)
-	_, _ = discoverPlugins(pluginsRoot, pluginLog)
-
-	graphObj, graphHash, err := loadGraphAndHash(inv.GraphPath)
-	if err != nil {
-		if runID != "" {
-			_ = rec.StartRun(state.Run{RunID: runID, GraphHash: "", StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: 0, Status: "failed", PreviousRunID: nil})
-			var se *graph.SchemaError
-			var ste *graph.StructuralError
-			switch {
-			case errors.As(err, &se):
-				_ = rec.RecordFailure(runID, &state.GraphFailureError{Code: "SchemaViolation", Message: err.Error(), Cause: err})
-			case errors.As(err, &ste):
-				_ = rec.RecordFailure(runID, &state.GraphFailureError{Code: "StructuralInvalidity", Message: err.Error(), Cause: err})
-			default:
-				_ = rec.RecordFailure(runID, &state.GraphFailureError{Code: "GraphLoadError", Message: err.Error(), Cause: err})
-			}
-		}
-		res.ExitCode = ExitConfigError
-		return res, err
-	}
-
-	traceWriter, err := newTraceWriter(inv, graphHash)
-	if err != nil {
-		if runID != "" {
-			_ = rec.RecordFailure(runID, &state.SystemFailureError{Code: "TraceInit", Message: err.Error(), Cause: err})
-		}
-		res.ExitCode = ExitConfigError
-		return res, err
-	}
-	defer func() {
-		// Always finalize trace output deterministically.
-		_ = traceWriter.Finalize(res.GraphResult)
-	}()
-
-	if err := prepareOutputDir(inv.OutputDir); err != nil {
-		if runID != "" {
-			_ = rec.RecordFailure(runID, &state.WorkspaceFailureError{Code: "OutputDir", Message: err.Error(), Cause: err})
-		}
-		res.ExitCode = ExitConfigError
-		return res, err
-	}
-
-	cache, err := cacheForMode(inv.ExecutionMode, inv.CacheDir)
-	if err != nil {
-		if runID != "" {
-			_ = rec.RecordFailure(runID, &state.WorkspaceFailureError{Code: "CacheDir", Message: err.Error(), Cause: err})
-		}
-		res.ExitCode = ExitConfigError
-		return res, err
-	}
-
-	runner := core.NewRunner(inv.WorkDir, cache)
-	cacheRunner, err := dag.NewCacheAwareRunner(runner)
-	if err != nil {
-		res.ExitCode = ExitInternalError
-		return res, err
-	}
-
-	// Create a checkpoint observer. Checkpoints are only meaningful for incremental/resume-only.
-	var obs dag.NodeObserver
-	if runID != "" && (inv.ExecutionMode == ExecutionModeIncremental || inv.ExecutionMode == ExecutionModeResumeOnly) {
-		validator := &state.CheckpointValidator{Store: st, Cache: cache, Harvester: core.NewHarvester(inv.WorkDir)}
-		obs = checkpointObserver{RunID: runID, Validator: validator}
-	}
-
-	// Resume planning (incremental/resume-only): best-effort attempt to reuse prior work.
-	// Clean mode ignores all checkpoints.
-	var executorToUse GraphExecutor = executor
-	var previousRunID *string
-	retryCount := 0
-	var resumePlan *incremental.IncrementalPlan
-	if inv.ExecutionMode == ExecutionModeIncremental || inv.ExecutionMode == ExecutionModeResumeOnly {
-		prevID := ""
-		var perr error
-		if strings.TrimSpace(inv.ResumeRunID) != "" {
-			prevID = strings.TrimSpace(inv.ResumeRunID)
-		} else {
-			prevID, perr = detectPreviousRunID(st, graphHash)
-		}
-		if perr != nil {
-			if inv.ExecutionMode == ExecutionModeResumeOnly {
-				if runID != "" {
-					_ = rec.StartRun(state.Run{RunID: runID, GraphHash: graphHash, StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: 0, Status: "failed", PreviousRunID: nil})
-					_ = rec.RecordFailure(runID, &state.ExecutionFailureError{NodeID: "", Code: "ResumeIneligible", Message: perr.Error(), Cause: perr})
-				}
-				res.ExitCode = ExitConfigError
-				return res, perr
-			}
-		} else if prevID != "" {
-			prevRun, lerr := st.LoadRun(prevID)
-			if lerr == nil && prevRun.GraphHash == graphHash {
-				// Resume is only meaningful after a non-successful termination.
-				if _, ferr := st.LoadFailure(prevID); ferr == nil {
-					checkpoints, cerr := st.LoadAllCheckpoints(prevID)
-					if cerr == nil && len(checkpoints) > 0 {
-						plan, checkpointNode, snap, invMap, corruption := buildResumePlan(ctx, graphObj, runner, cacheRunner, cache, checkpoints)
-						if corruption != nil {
-							// Resume-only hard-fails; incremental falls back to scratch execution.
-							if inv.ExecutionMode == ExecutionModeResumeOnly {
-								if runID != "" {
-									_ = rec.StartRun(state.Run{RunID: runID, GraphHash: graphHash, StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: 0, Status: "failed", PreviousRunID: nil})
-									_ = rec.RecordFailure(runID, &state.WorkspaceFailureError{Code: "WorkspaceCorrupt", Message: corruption.Error(), Cause: corruption})
-								}
-								res.ExitCode = ExitConfigError
-								return res, corruption
-							}
-							// incremental: ignore resume plan
-						} else if plan != nil && checkpointNode != "" {
-							candidatePrevID := prevID
-							candidatePrevPtr := &candidatePrevID
-							candidateRetry := prevRun.RetryCount + 1
-							newRun := state.Run{RunID: runID, GraphHash: graphHash, StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: candidateRetry, Status: "running", PreviousRunID: candidatePrevPtr}
-							checker := &state.ResumeEligibilityChecker{Store: st, ProjectRoot: inv.WorkDir}
-							if err := checker.Check(state.ResumeEligibilityRequest{NewRun: newRun, ResumeFromNodeID: checkpointNode, Graph: snap, Invalidation: invMap}); err == nil {
-								resumePlan = plan
-								previousRunID = candidatePrevPtr
-								retryCount = candidateRetry
-								if _, ok := executor.(defaultGraphExecutor); ok {
-									executorToUse = cliGraphExecutor{Plan: resumePlan, Observer: obs}
-								}
-							} else if inv.ExecutionMode == ExecutionModeResumeOnly {
-								if runID != "" {
-									_ = rec.StartRun(state.Run{RunID: runID, GraphHash: graphHash, StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: 0, Status: "failed", PreviousRunID: nil})
-									_ = rec.RecordFailure(runID, &state.ExecutionFailureError{NodeID: "", Code: "ResumeIneligible", Message: err.Error(), Cause: err})
-								}
-								res.ExitCode = ExitConfigError
-								return res, err
-							}
-						}
-					}
-				}
-			}
-		}
-		if inv.ExecutionMode == ExecutionModeResumeOnly && resumePlan == nil {
-			err := fmt.Errorf("resume-only mode requires an eligible previous run with checkpoints")
-			if runID != "" {
-				_ = rec.StartRun(state.Run{RunID: runID, GraphHash: graphHash, StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: 0, Status: "failed", PreviousRunID: nil})
-				_ = rec.RecordFailure(runID, &state.ExecutionFailureError{NodeID: "", Code: "ResumeIneligible", Message: err.Error(), Cause: err})
-			}
-			res.ExitCode = ExitConfigError
-			return res, err
-		}
-	}
-
-	// Record the run metadata now that we know GraphHash and any run linkage.
-	if runID != "" {
-		_ = rec.StartRun(state.Run{RunID: runID, GraphHash: graphHash, StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: retryCount, Status: "running", PreviousRunID: previousRunID})
-	}
-
-	defer func() {
-		if r := recover(); r != nil {
-			res.ExitCode = ExitInternalError
-			res.GraphResult = nil
-			execErr = fmt.Errorf("panic: %v", r)
-			if runID != "" {
-				_ = rec.RecordFailure(runID, &state.SystemFailureError{Code: "Panic", Message: fmt.Sprintf("panic: %v", r), Cause: execErr})
-			}
-		}
-	}()
-
-	// If the caller provided the default executor, always run through the CLI-owned executor
-	// so we can attach checkpoint observer (even when resume is not possible).
-	if _, ok := executor.(defaultGraphExecutor); ok {
-		executorToUse = cliGraphExecutor{Plan: resumePlan, Observer: obs}
-	}
+func ExecuteWithExecutor(ctx context.Context, inv CLIInvocation, executor GraphExecutor) (CLIResult, error) {
+	return executeWith(ctx, inv, executor, nil, nil, nil)
+}
 
-	gr, err := executorToUse.Run(ctx, graphObj, cacheRunner)
-	if err != nil {
-		if runID != "" {
-			_ = rec.RecordFailure(runID, &state.SystemFailureError{Code: "EngineError", Message: err.Error(), Cause: err})
-		}
-		res.ExitCode = ExitInternalError
-		return res, err
-	}
-	res.GraphResult = gr
-	res.ExitCode = translateGraphResultToExitCode(gr)
-	if res.ExitCode == ExitGraphFailure && runID != "" {
-		// Deterministically choose a representative failed node.
-		failed := firstFailedNode(gr)
-		_ = rec.RecordFailure(runID, &state.ExecutionFailureError{NodeID: failed, Code: "NodeFailed", Message: fmt.Sprintf("node %s failed", failed)})
-	}
-	return res, nil
+// ExecuteWithObservers runs inv like Execute and additionally notifies each
+// observer whenever a node reaches a successful terminal state. Observers are
+// notified after the checkpoint observer, one event at a time, and each sees
+// every event; their errors are joined and fail the run like a checkpoint
+// error does.
+func ExecuteWithObservers(ctx context.Context, inv CLIInvocation, observers ...dag.NodeObserver) (CLIResult, error) {
+	return executeWith(ctx, inv, defaultGraphExecutor{}, nil, observers, nil)
 }
 
 type checkpointObserver struct {
 	RunID     string
 	Validator *state.CheckpointValidator
+	// Upstream maps each node to its direct dependencies, recorded so that a
+	// later run of a modified graph can tell whether the node's ancestry changed.
+	Upstream map[string][]string
 }
 
 func (o checkpointObserver) OnTaskTerminal(task core.Task, result *dag.NodeResult, traceEvents []trace.TraceEvent) error {
@@ -315,11 +279,69 @@ func (o checkpointObserver) OnTaskTerminal(task core.Task, result *dag.NodeResul
 		ExitCode:        result.ExitCode,
 		FromCache:       result.FromCache,
 		TraceEvents:     traceEvents,
+		Upstream:        o.upstream(task.Name),
 	})
 	return err
 }
 
-func detectPreviousRunID(st *state.Store, graphHash string) (string, error) {
+func (o checkpointObserver) upstream(name string) []string {
+	if o.Upstream == nil {
+		return nil
+	}
+	return append([]string{}, o.Upstream[name]...)
+}
+
+// upstreamByNode maps every node of g to its direct dependencies.
+func upstreamByNode(g *dag.TaskGraph) map[string][]string {
+	out := make(map[string][]string)
+	for _, e := range g.Edges() {
+		out[e.To] = append(out[e.To], e.From)
+	}
+	return out
+}
+
+// importRunCache copies the cache entries of checkpointed nodes from src, the
+// cache of a previous run, into cache, so a resumed run can restore their
+// artifacts. It does nothing when the cache directory does not exist;
+// entries already in cache are kept.
+func importRunCache(src *core.FileCache, cache core.Cache, checkpoints map[string]state.Checkpoint) error {
+	if _, err := os.Stat(src.CacheDir); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	nodes := make([]string, 0, len(checkpoints))
+	for node := range checkpoints {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		cp := checkpoints[node]
+		if !cp.Valid || len(cp.CacheKeys) == 0 {
+			continue
+		}
+		hash := core.TaskHash(cp.CacheKeys[0])
+		if ok, err := cache.Has(hash); err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+		entry, err := src.Get(hash)
+		if err != nil {
+			return fmt.Errorf("run cache entry for %q: %w", node, err)
+		}
+		if entry == nil {
+			continue
+		}
+		if err := cache.Put(entry); err != nil {
+			return fmt.Errorf("import run cache entry for %q: %w", node, err)
+		}
+	}
+	return nil
+}
+
+// detectPreviousRunID picks the run to resume from: the most recent failed run
+// with the same graph hash or, when there is none, the most recent failed run
+// of a modified graph that has a checkpoint for a node of g.
+func detectPreviousRunID(st *state.Store, graphHash string, g *dag.TaskGraph) (string, error) {
 	if st == nil {
 		return "", fmt.Errorf("nil store")
 	}
@@ -332,17 +354,24 @@ func detectPreviousRunID(st *state.Store, graphHash string) (string, error) {
 	}
 	// Resume is only meaningful after a non-successful termination.
 	// Prefer the most recent run with matching graph hash that has a persisted failure.
-	var bestID string
-	var bestTime time.Time
+	var bestID, changedID string
+	var bestTime, changedTime time.Time
 	for _, id := range ids {
 		r, err := st.LoadRun(id)
 		if err != nil {
 			continue
 		}
-		if r.GraphHash != graphHash {
+		if _, ferr := st.LoadFailure(id); ferr != nil {
 			continue
 		}
-		if _, ferr := st.LoadFailure(id); ferr != nil {
+		if !core.SameHash(r.GraphHash, graphHash) {
+			if !sharesCheckpointedNode(st, id, g) {
+				continue
+			}
+			if changedID == "" || r.StartTime.After(changedTime) || (r.StartTime.Equal(changedTime) && r.RunID < changedID) {
+				changedID = r.RunID
+				changedTime = r.StartTime
+			}
 			continue
 		}
 		if bestID == "" || r.StartTime.After(bestTime) || (r.StartTime.Equal(bestTime) && r.RunID < bestID) {
@@ -350,29 +379,65 @@ func detectPreviousRunID(st *state.Store, graphHash string) (string, error) {
 			bestTime = r.StartTime
 		}
 	}
+	if bestID == "" {
+		return changedID, nil
+	}
 	return bestID, nil
 }
 
+func sharesCheckpointedNode(st *state.Store, runID string, g *dag.TaskGraph) bool {
+	if g == nil {
+		return false
+	}
+	checkpoints, err := st.LoadAllCheckpoints(runID)
+	if err != nil {
+		return false
+	}
+	for name, cp := range checkpoints {
+		if _, ok := g.Node(name); ok && cp.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// buildResumePlan decides per node whether its checkpoint can be reused. A
+// node is invalidated when its task hash differs from the checkpoint's or,
+// when the checkpoint recorded them, its direct dependencies differ; legacy
+// checkpoints without dependencies are only trusted while the graph hash is
+// unchanged. The returned reasons explain every node that is not reused.
 func buildResumePlan(ctx context.Context, g *dag.TaskGraph, runner *core.Runner, restoreRunner interface {
 	Restore(ctx context.Context, task core.Task) (*dag.NodeResult, error)
-}, cache core.Cache, checkpoints map[string]state.Checkpoint) (*incremental.IncrementalPlan, string, *incremental.GraphSnapshot, incremental.InvalidationMap, error) {
+}, cache core.Cache, checkpoints map[string]state.Checkpoint, graphChanged bool) (*incremental.IncrementalPlan, string, *incremental.GraphSnapshot, incremental.InvalidationMap, map[string]string, error) {
 	if g == nil {
-		return nil, "", nil, nil, fmt.Errorf("nil graph")
+		return nil, "", nil, nil, nil, fmt.Errorf("nil graph")
 	}
 	if runner == nil {
-		return nil, "", nil, nil, fmt.Errorf("nil runner")
+		return nil, "", nil, nil, nil, fmt.Errorf("nil runner")
 	}
 	if cache == nil {
-		return nil, "", nil, nil, fmt.Errorf("nil cache")
+		return nil, "", nil, nil, nil, fmt.Errorf("nil cache")
 	}
 
 	order := g.TopologicalOrder()
+	// upstream holds every dependency, dataUpstream and orderUpstream those
+	// across data and order-only edges: an order-only dependency that
+	// executes does not stop a node's reuse.
 	upstream := make(map[string][]string, len(order))
+	dataUpstream := make(map[string][]string, len(order))
+	orderUpstream := make(map[string][]string, len(order))
 	for _, e := range g.Edges() {
 		upstream[e.To] = append(upstream[e.To], e.From)
+		if e.IsOrder() {
+			orderUpstream[e.To] = append(orderUpstream[e.To], e.From)
+		} else {
+			dataUpstream[e.To] = append(dataUpstream[e.To], e.From)
+		}
 	}
 	for k := range upstream {
 		sort.Strings(upstream[k])
+		sort.Strings(dataUpstream[k])
+		sort.Strings(orderUpstream[k])
 	}
 
 	invMap := make(incremental.InvalidationMap, len(order))
@@ -381,12 +446,13 @@ func buildResumePlan(ctx context.Context, g *dag.TaskGraph, runner *core.Runner,
 	computedHash := make(map[string]core.TaskHash, len(order))
 	canReuse := make(map[string]bool, len(order))
 	restored := make(map[string]bool, len(order))
+	reasons := make(map[string]string, len(order))
 
 	plan := &incremental.IncrementalPlan{Order: append([]string(nil), order...), Decisions: make(map[string]incremental.NodeExecutionDecision, len(order))}
 	for _, name := range order {
 		n, _ := g.Node(name)
 		// Populate snapshot for eligibility checks (only Upstream is used today).
-		snap.Nodes[name] = incremental.NodeSnapshot{Name: name, Upstream: append([]string(nil), upstream[name]...)}
+		snap.Nodes[name] = incremental.NodeSnapshot{Name: name, Upstream: append([]string(nil), dataUpstream[name]...), OrderUpstream: orderUpstream[name]}
 
 		// If we plan to reuse upstream tasks, restore their outputs before hashing this task's inputs.
 		for _, p := range upstream[name] {
@@ -397,22 +463,22 @@ func buildResumePlan(ctx context.Context, g *dag.TaskGraph, runner *core.Runner,
 				continue
 			}
 			if restoreRunner == nil {
-				return nil, "", nil, nil, fmt.Errorf("restore runner is required to build resume plan after output dir was cleared")
+				return nil, "", nil, nil, nil, fmt.Errorf("restore runner is required to build resume plan after output dir was cleared")
 			}
 			pn, _ := g.Node(p)
 			res, err := restoreRunner.Restore(ctx, pn.Task)
 			if err != nil {
-				return nil, "", nil, nil, err
+				return nil, "", nil, nil, nil, err
 			}
 			if res == nil || res.ExitCode != 0 {
-				return nil, "", nil, nil, fmt.Errorf("restoring %q for resume plan failed", p)
+				return nil, "", nil, nil, nil, fmt.Errorf("restoring %q for resume plan failed", p)
 			}
 			restored[p] = true
 		}
 
 		h, err := computeTaskHash(runner, n.Task)
 		if err != nil {
-			return nil, "", nil, nil, err
+			return nil, "", nil, nil, nil, err
 		}
 		computedHash[name] = h
 
@@ -421,16 +487,25 @@ func buildResumePlan(ctx context.Context, g *dag.TaskGraph, runner *core.Runner,
 			invMap[name] = incremental.InvalidationEntry{Invalidated: false, Reasons: nil}
 			canReuse[name] = false
 			plan.Decisions[name] = incremental.DecisionExecute
+			reasons[name] = ResumeReasonNoCheckpoint
 			continue
 		}
-		// Checkpoint invalidation marker: task hash mismatch.
+		// Checkpoint invalidation markers: task hash or dependency mismatch.
 		invalidated := false
 		if len(cp.CacheKeys) == 0 || cp.CacheKeys[0] == "" {
 			invalidated = true
 		} else if cp.CacheKeys[0] != h.String() {
 			invalidated = true
 		}
-		invMap[name] = incremental.InvalidationEntry{Invalidated: invalidated, Reasons: nil}
+		var invReasons incremental.InvalidationReasons
+		if invalidated {
+			reasons[name] = ResumeReasonTaskChanged
+		} else if (cp.Upstream == nil && graphChanged) || (cp.Upstream != nil && !slices.Equal(cp.Upstream, upstream[name])) {
+			invalidated = true
+			invReasons = incremental.InvalidationReasons{{Type: incremental.ReasonTypeGraphStructureChanged}}
+			reasons[name] = ResumeReasonDependenciesChanged
+		}
+		invMap[name] = incremental.InvalidationEntry{Invalidated: invalidated, Reasons: invReasons}
 		if invalidated {
 			canReuse[name] = false
 			plan.Decisions[name] = incremental.DecisionExecute
@@ -438,15 +513,15 @@ func buildResumePlan(ctx context.Context, g *dag.TaskGraph, runner *core.Runner,
 		}
 		exists, err := cache.Has(h)
 		if err != nil {
-			return nil, "", nil, nil, err
+			return nil, "", nil, nil, nil, err
 		}
 		if !exists {
-			return nil, "", nil, nil, fmt.Errorf("cache entry missing for checkpointed task %q", name)
+			return nil, "", nil, nil, nil, fmt.Errorf("cache entry missing for checkpointed task %q", name)
 		}
 		canReuse[name] = true
 
 		allUpstreamReuse := true
-		for _, p := range upstream[name] {
+		for _, p := range dataUpstream[name] {
 			if plan.Decisions[p] != incremental.DecisionReuseCache {
 				allUpstreamReuse = false
 				break
@@ -456,34 +531,35 @@ func buildResumePlan(ctx context.Context, g *dag.TaskGraph, runner *core.Runner,
 			plan.Decisions[name] = incremental.DecisionReuseCache
 			if !restored[name] {
 				if restoreRunner == nil {
-					return nil, "", nil, nil, fmt.Errorf("restore runner is required to build resume plan after output dir was cleared")
+					return nil, "", nil, nil, nil, fmt.Errorf("restore runner is required to build resume plan after output dir was cleared")
 				}
 				res, err := restoreRunner.Restore(ctx, n.Task)
 				if err != nil {
-					return nil, "", nil, nil, err
+					return nil, "", nil, nil, nil, err
 				}
 				if res == nil || res.ExitCode != 0 {
-					return nil, "", nil, nil, fmt.Errorf("restoring %q for resume plan failed", name)
+					return nil, "", nil, nil, nil, fmt.Errorf("restoring %q for resume plan failed", name)
 				}
 				restored[name] = true
 			}
 		} else {
 			plan.Decisions[name] = incremental.DecisionExecute
+			reasons[name] = ResumeReasonUpstreamNotReused
 		}
 	}
 
+	// Resume from the last reused node in topological order; every reused
+	// node already has a fully reused ancestry across data edges.
 	checkpointNode := ""
 	for _, name := range order {
 		if plan.Decisions[name] == incremental.DecisionReuseCache {
 			checkpointNode = name
-			continue
 		}
-		break
 	}
 	if checkpointNode == "" {
-		return nil, "", snap, invMap, nil
+		return nil, "", snap, invMap, reasons, nil
 	}
-	return plan, checkpointNode, snap, invMap, nil
+	return plan, checkpointNode, snap, invMap, reasons, nil
 }
 
 func computeTaskHash(r *core.Runner, task core.Task) (core.TaskHash, error) {
@@ -494,35 +570,117 @@ func computeTaskHash(r *core.Runner, task core.Task) (core.TaskHash, error) {
 	if err != nil {
 		return "", fmt.Errorf("resolving inputs: %w", err)
 	}
-	hashInput := core.HashInput{Inputs: inputSet, Command: task.Run, Env: task.Env, Outputs: task.Outputs, WorkingDir: r.WorkingDir}
+	hashInput := task.HashInput(inputSet, r.WorkingDir)
 	return r.Hasher.ComputeHash(hashInput), nil
 }
 
-func firstFailedNode(gr *dag.GraphResult) string {
-	if gr == nil || len(gr.FinalState) == 0 {
-		return ""
+// classifyEngineError returns the kind of an error that aborted execution,
+// recognizing plugin runner timeouts on top of state.ClassifyError.
+func classifyEngineError(err error) state.FailureKind {
+	if errors.Is(err, pluginengine.ErrHookTimeout) {
+		return state.FailureKindTimeout
+	}
+	return state.ClassifyError(err)
+}
+
+// skippedNodes lists the skipped nodes of gr with the failed node that
+// caused each skip, in lexical order.
+func skippedNodes(gr *dag.GraphResult) []state.SkippedNode {
+	names := gr.SkippedNodes()
+	out := make([]state.SkippedNode, 0, len(names))
+	for _, name := range names {
+		out = append(out, state.SkippedNode{NodeID: name, Cause: gr.SkipCause[name]})
+	}
+	return out
+}
+
+// validatePublishOutputs rejects publish selections that no task declares, so
+// a misconfigured publish step fails before anything executes.
+func validatePublishOutputs(g *dag.TaskGraph, outputs []string) error {
+	declared := make(map[string]bool)
+	for _, name := range g.TopologicalOrder() {
+		n, _ := g.Node(name)
+		for _, o := range n.Task.Outputs {
+			declared[filepath.Clean(o)] = true
+		}
+	}
+	for _, o := range outputs {
+		if !declared[filepath.Clean(o)] {
+			return fmt.Errorf("%w: publish output %q is not declared by any task", config.ErrInvalidConfig, o)
+		}
+	}
+	return nil
+}
+
+// determinismSample deterministically selects up to n successfully finished
+// tasks for re-execution. Ordering by task hash spreads the sample across the
+// graph while keeping it stable for an unchanged graph and inputs. The sample
+// is returned in topological order so each task observes its inputs.
+func determinismSample(g *dag.TaskGraph, gr *dag.GraphResult, n int) []string {
+	var eligible []string
+	for name, st := range gr.FinalState {
+		if !dag.IsSuccessful(st) {
+			continue
+		}
+		if _, dup := gr.Deduplicated[name]; dup {
+			continue
+		}
+		eligible = append(eligible, name)
+	}
+	sort.Slice(eligible, func(i, j int) bool {
+		hi, hj := gr.TaskHashOf(eligible[i]), gr.TaskHashOf(eligible[j])
+		if hi != hj {
+			return hi < hj
+		}
+		return eligible[i] < eligible[j]
+	})
+	if len(eligible) > n {
+		eligible = eligible[:n]
+	}
+
+	picked := make(map[string]bool, len(eligible))
+	for _, name := range eligible {
+		picked[name] = true
 	}
-	names := make([]string, 0, len(gr.FinalState))
-	for n := range gr.FinalState {
-		names = append(names, n)
+	sample := make([]string, 0, len(eligible))
+	for _, name := range g.TopologicalOrder() {
+		if picked[name] {
+			sample = append(sample, name)
+		}
 	}
-	sort.Strings(names)
-	for _, n := range names {
-		if gr.FinalState[n] == dag.TaskFailed {
-			return n
+	return sample
+}
+
+// verifyDeterminism re-executes a sample of n tasks twice each and returns
+// those whose executions disagreed, in sample order.
+func verifyDeterminism(ctx context.Context, g *dag.TaskGraph, runner *core.Runner, gr *dag.GraphResult, n int) ([]core.DeterminismResult, error) {
+	var nondet []core.DeterminismResult
+	for _, name := range determinismSample(g, gr, n) {
+		node, ok := g.Node(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown task %q", name)
+		}
+		task := node.Task
+		dr, err := runner.VerifyDeterminism(ctx, &task)
+		if err != nil {
+			return nil, fmt.Errorf("verifying determinism of %q: %w", name, err)
+		}
+		if !dr.Deterministic() {
+			nondet = append(nondet, *dr)
 		}
 	}
-	return ""
+	return nondet, nil
 }
 
-func translateGraphResultToExitCode(gr *dag.GraphResult) int {
+// translateGraphResultToExitCode returns the exit code of a run that ended
+// with gr. tolerated are the nodes whose failure the graph's success criteria
+// allow; nil fails the run on any failed node.
+func translateGraphResultToExitCode(gr *dag.GraphResult, tolerated map[string]bool) int {
 	if gr == nil {
 		return ExitInternalError
 	}
-	for _, st := range gr.FinalState {
-		if st == dag.TaskFailed {
-			return ExitGraphFailure
-		}
+	if !runSucceeded(gr, tolerated) {
+		return ExitGraphFailure
 	}
 	return ExitSuccess
 }
@@ -552,12 +710,134 @@ func cacheForMode(mode ExecutionMode, cacheDir string) (core.Cache, error) {
 	}
 }
 
+// hashAlgorithm is the algorithm task hashes are computed with under cfg,
+// which Parse has validated.
+func hashAlgorithm(cfg config.Config) core.HashAlgorithm {
+	if cfg.HashAlgorithm == "" {
+		return core.DefaultHashAlgorithm
+	}
+	return core.HashAlgorithm(cfg.HashAlgorithm)
+}
+
+// withSharedTiers reads through the shared cache tiers of cc after local.
+// Directories are relative to workDir unless absolute; object store tiers
+// of a graph namespace are prefixed with the digest of graphHash.
+func withSharedTiers(local core.Cache, cc *config.CacheConfig, workDir, graphHash string) (core.Cache, error) {
+	tiers := []core.Cache{local}
+	for _, tier := range cc.Tiers {
+		if objectstore.IsURL(tier) {
+			c, err := objectStoreCache(tier, cc, graphHash)
+			if err != nil {
+				return nil, err
+			}
+			tiers = append(tiers, c)
+			continue
+		}
+		if !filepath.IsAbs(tier) {
+			tier = filepath.Join(workDir, tier)
+		}
+		tiers = append(tiers, core.NewFileCache(filepath.Clean(tier)))
+	}
+	return core.NewTieredCache(core.CacheWritePolicy(cc.Write), tiers...)
+}
+
+// objectStoreCache is the cache of the object store tier rawURL.
+func objectStoreCache(rawURL string, cc *config.CacheConfig, graphHash string) (core.Cache, error) {
+	loc, err := objectstore.ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := objectstore.CredentialsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	region := cc.Region
+	if region == "" {
+		region = "us-east-1"
+		if loc.Scheme == "gs" {
+			region = "auto"
+		}
+	}
+	endpoint := cc.Endpoint
+	if endpoint == "" {
+		endpoint = loc.DefaultEndpoint(region)
+	}
+	prefix := loc.Prefix
+	if cc.Namespace == config.CacheNamespaceGraph {
+		_, digest := core.SplitHash(graphHash)
+		prefix = path.Join(prefix, digest)
+	}
+	client := objectstore.NewClient(endpoint, region, loc.Bucket, creds)
+	client.MaxConcurrent = cc.Concurrency
+	client.UploadRate = cc.UploadBytesPerSec
+	client.DownloadRate = cc.DownloadBytesPerSec
+	return objectstore.NewCache(client, prefix), nil
+}
+
 type noCache struct{}
 
 func (noCache) Has(core.TaskHash) (bool, error)             { return false, nil }
 func (noCache) Get(core.TaskHash) (*core.CacheEntry, error) { return nil, nil }
 func (noCache) Put(*core.CacheEntry) error                  { return nil }
 
+// auditCache records cache writes and evictions in the audit log.
+type auditCache struct {
+	core.Cache
+	log *audit.Log
+}
+
+func (c auditCache) Put(entry *core.CacheEntry) error {
+	if err := c.Cache.Put(entry); err != nil {
+		return err
+	}
+	_ = c.log.Record(audit.KindCacheWrite, entry.Hash.String())
+	return nil
+}
+
+func (c auditCache) GetTier(hash core.TaskHash) (*core.CacheEntry, int, error) {
+	if tg, ok := c.Cache.(core.TierGetter); ok {
+		return tg.GetTier(hash)
+	}
+	entry, err := c.Cache.Get(hash)
+	return entry, 0, err
+}
+
+func (c auditCache) PutSaving(entry *core.CacheEntry) (int64, error) {
+	sp, ok := c.Cache.(core.SavingPutter)
+	if !ok {
+		return 0, c.Put(entry)
+	}
+	saved, err := sp.PutSaving(entry)
+	if err != nil {
+		return 0, err
+	}
+	_ = c.log.Record(audit.KindCacheWrite, entry.Hash.String())
+	return saved, nil
+}
+
+func (c auditCache) TransferStats() core.TransferStats {
+	if tc, ok := c.Cache.(core.TransferCounter); ok {
+		return tc.TransferStats()
+	}
+	return core.TransferStats{}
+}
+
+func (c auditCache) HasBatch(hashes []core.TaskHash) (map[core.TaskHash]bool, error) {
+	return core.HasBatch(c.Cache, hashes)
+}
+
+func (c auditCache) Evict(hash core.TaskHash) error {
+	ev, ok := c.Cache.(core.CacheEvicter)
+	if !ok {
+		return nil
+	}
+	if err := ev.Evict(hash); err != nil {
+		return err
+	}
+	_ = c.log.Record(audit.KindCacheEvict, hash.String())
+	return nil
+}
+
 func prepareOutputDir(dir string) error {
 	if dir == "" {
 		return fmt.Errorf("output dir is empty")
@@ -589,18 +869,34 @@ func prepareOutputDir(dir string) error {
 	return nil
 }
 
-func loadGraphAndHash(path string) (*dag.TaskGraph, string, error) {
-	g, err := LoadGraphFromFile(path)
+// loadGraphAndHash returns the graph of inv, its hash and the nodes whose
+// failure its success criteria tolerate (see SuccessCriteria.tolerated).
+func loadGraphAndHash(inv CLIInvocation, phases *phaseTimer, session *Session) (*dag.TaskGraph, string, map[string]bool, error) {
+	if session != nil {
+		return session.loadGraph(inv.GraphPath, inv.Params, phases)
+	}
+	gf, _, err := readGraph(inv.GraphPath, inv.Params)
+	phases.mark(PhaseParse)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
-	return g, g.Hash().String(), nil
+	g, tolerated, err := buildGraph(gf)
+	phases.mark(PhaseValidate)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	hash := g.Hash().String()
+	phases.mark(PhaseHash)
+	return g, hash, tolerated, nil
 }
 
 type traceFileWriter struct {
 	enabled   bool
 	path      string
 	graphHash string
+	// journal records the events of the run as they happen; see openJournal.
+	journal     *trace.Journal
+	journalPath string
 }
 
 func newTraceWriter(inv CLIInvocation, graphHash string) (*traceFileWriter, error) {
@@ -619,16 +915,48 @@ func newTraceWriter(inv CLIInvocation, graphHash string) (*traceFileWriter, erro
 	return w, w.writeBytes(trace.ExecutionTrace{GraphHash: graphHash, Events: nil})
 }
 
+// openJournal journals the trace events of run runID to its run directory as
+// they are recorded, best-effort, so that the trace of a run whose process
+// crashed can be recovered with FinalizeTrace. It returns the sink to record
+// the events to, or nil.
+func (w *traceFileWriter) openJournal(st *state.Store, runID string) trace.Sink {
+	if w == nil || !w.enabled || runID == "" {
+		return nil
+	}
+	path, err := st.RunFilePath(runID, TraceJournalFileName)
+	if err != nil {
+		return nil
+	}
+	j, err := trace.CreateJournal(path)
+	if err != nil {
+		return nil
+	}
+	w.journal, w.journalPath = j, path
+	return j
+}
+
 func (w *traceFileWriter) Finalize(gr *dag.GraphResult) error {
 	if w == nil || !w.enabled {
 		return nil
 	}
+	_ = w.journal.Close()
 	if gr != nil && len(gr.TraceBytes) > 0 {
-		return writeFileAtomic(w.path, gr.TraceBytes, 0o644)
+		if err := writeFileAtomic(w.path, gr.TraceBytes, 0o644); err != nil {
+			return err
+		}
+		// The trace is complete: its journal is no longer needed.
+		if w.journalPath != "" {
+			_ = os.Remove(w.journalPath)
+		}
+		return nil
 	}
 	// If we don't have trace bytes (e.g., internal error or panic), still emit a valid
-	// empty trace for this graph.
-	return w.writeBytes(trace.ExecutionTrace{GraphHash: w.graphHash, Events: nil})
+	// trace for this graph, with the events journaled so far.
+	var events []trace.TraceEvent
+	if w.journalPath != "" {
+		events, _ = trace.ReadJournal(w.journalPath)
+	}
+	return w.writeBytes(trace.ExecutionTrace{GraphHash: w.graphHash, Events: events})
 }
 
 func (w *traceFileWriter) writeBytes(t trace.ExecutionTrace) error {
@@ -1,12 +1,16 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
 )
 
 // stubExecutor returns a GraphResult containing a deterministic node failure.
@@ -73,3 +77,92 @@ func TestFailureRecording_WritesFailureJSON_OnNodeFailure(t *testing.T) {
 		t.Fatalf("expected failure.json to exist in a run directory")
 	}
 }
+
+// multiFailureExecutor reports two failed nodes, one with more stderr than a
+// node failure keeps.
+type multiFailureExecutor struct{ stderr []byte }
+
+func (e multiFailureExecutor) Run(ctx context.Context, graph *dag.TaskGraph, runner dag.TaskRunner) (*dag.GraphResult, error) {
+	return &dag.GraphResult{
+		FinalState: map[string]dag.TaskState{"A": dag.TaskFailed, "B": dag.TaskFailed, "C": dag.TaskCompleted},
+		ExitCode:   map[string]int{"A": 2, "B": 127, "C": 0},
+		Stderr:     map[string][]byte{"A": e.stderr, "B": []byte("sh: protoc: not found\n")},
+	}, nil
+}
+
+func TestFailureRecording_WritesNodeFailureForEveryFailedNode(t *testing.T) {
+	work := t.TempDir()
+	inv := CLIInvocation{
+		GraphPath:     filepath.Join(work, "graph.json"),
+		WorkDir:       work,
+		CacheDir:      filepath.Join(work, "cache"),
+		OutputDir:     filepath.Join(work, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+	graphJSON := `{
+	  "tasks": [
+	    {"name": "A", "inputs": [], "run": "make test", "env": {"CI": "1"}},
+	    {"name": "B", "inputs": [], "run": "protoc api.proto"},
+	    {"name": "C", "inputs": [], "run": "true"}
+	  ],
+	  "edges": []
+	}`
+	if err := os.WriteFile(inv.GraphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("WriteFile graph: %v", err)
+	}
+	stderr := append(bytes.Repeat([]byte("x"), 5000), []byte("FAIL: TestParse\n")...)
+
+	res, err := ExecuteWithExecutor(context.Background(), inv, multiFailureExecutor{stderr: stderr})
+	if err != nil {
+		t.Fatalf("ExecuteWithExecutor: %v", err)
+	}
+	if res.ExitCode != ExitGraphFailure {
+		t.Fatalf("expected ExitGraphFailure got %d", res.ExitCode)
+	}
+
+	st, err := state.NewStore(work)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	failures, err := st.LoadNodeFailures(res.RunID)
+	if err != nil {
+		t.Fatalf("LoadNodeFailures: %v", err)
+	}
+	if !reflect.DeepEqual(failures, res.NodeFailures) {
+		t.Fatalf("recorded failures differ from result:\n%+v\n%+v", failures, res.NodeFailures)
+	}
+	if len(failures) != 2 || failures[0].NodeID != "A" || failures[1].NodeID != "B" {
+		t.Fatalf("expected failures for A and B, got %+v", failures)
+	}
+	a, b := failures[0], failures[1]
+	if a.Command != "make test" || a.ExitCode == nil || *a.ExitCode != 2 || a.Kind != state.FailureKindExit {
+		t.Fatalf("unexpected failure for A: %+v", a)
+	}
+	if len(a.StderrTail) != state.StderrTailBytes || !a.StderrTruncated || !strings.HasSuffix(a.StderrTail, "FAIL: TestParse\n") {
+		t.Fatalf("unexpected stderr tail for A: %d bytes, truncated=%v", len(a.StderrTail), a.StderrTruncated)
+	}
+	if a.EnvDigest == b.EnvDigest {
+		t.Fatalf("unexpected env digests: %s %s", a.EnvDigest, b.EnvDigest)
+	}
+	// A failure records the digest of the environment the command ran
+	// with, as the run's env.json does.
+	runEnv, err := LoadRunEnv(st, res.RunID)
+	if err != nil {
+		t.Fatalf("LoadRunEnv: %v", err)
+	}
+	for _, f := range failures {
+		if want := runEnv.Nodes[f.NodeID].Digest; f.EnvDigest != want {
+			t.Fatalf("env digest of %s = %s, env.json records %s", f.NodeID, f.EnvDigest, want)
+		}
+	}
+	if b.Kind != state.FailureKindMissingTool || b.StderrTruncated {
+		t.Fatalf("unexpected failure for B: %+v", b)
+	}
+	recorded, err := st.LoadFailure(res.RunID)
+	if err != nil {
+		t.Fatalf("LoadFailure: %v", err)
+	}
+	if *recorded.NodeID != "A" || !reflect.DeepEqual(recorded.FailedNodes, []string{"A", "B"}) || len(recorded.SkippedNodes) != 0 {
+		t.Fatalf("unexpected failure record: %+v", recorded)
+	}
+}
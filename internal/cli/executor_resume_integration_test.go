@@ -3,11 +3,16 @@ package cli
 import (
 	"context"
 	"encoding/json"
+	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"scriptweaver/internal/core"
 	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
 )
 
 func TestExecute_ResumeOnly_FailsWhenNoEligiblePreviousRun(t *testing.T) {
@@ -112,3 +117,283 @@ func TestExecute_Incremental_ReusesCheckpointedWorkAfterFailure(t *testing.T) {
 		t.Fatalf("expected TaskCached event for A")
 	}
 }
+
+func TestExecute_CleanCheckpoint_FailedRunCanBeResumed(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	flagPath := filepath.Join(workDir, "flag")
+	countPath := filepath.Join(workDir, "count")
+
+	tasks := []core.Task{
+		{
+			Name:    "A",
+			Inputs:  []string{},
+			Run:     "echo run >> count && mkdir -p out && echo hello > out/a.txt",
+			Outputs: []string{"out/a.txt"},
+		},
+		{
+			Name:   "B",
+			Inputs: []string{"out/a.txt"},
+			Run:    "test -f flag",
+		},
+	}
+	writeGraphJSON(t, graphPath, tasks, []dag.Edge{{From: "A", To: "B"}})
+
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "sw-out"),
+		ExecutionMode: ExecutionModeClean,
+		Checkpoint:    true,
+	}
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	res, err := Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitGraphFailure {
+		t.Fatalf("expected graph failure exit, got %d", res.ExitCode)
+	}
+	ids, _ := st.ListRunIDs()
+	if len(ids) != 1 {
+		t.Fatalf("runs = %v", ids)
+	}
+	cleanRun := ids[0]
+	if _, err := st.LoadCheckpoint(cleanRun, "A"); err != nil {
+		t.Fatalf("expected checkpoint for A: %v", err)
+	}
+	if _, err := os.Stat(inv.CacheDir); !os.IsNotExist(err) {
+		t.Fatalf("clean run must not populate the shared cache, stat err=%v", err)
+	}
+
+	// Fix B and resume the clean run: A is restored, not re-executed.
+	if err := os.WriteFile(flagPath, []byte("ok\n"), 0o644); err != nil {
+		t.Fatalf("write flag: %v", err)
+	}
+	if err := os.Remove(filepath.Join(workDir, "out", "a.txt")); err != nil {
+		t.Fatalf("remove output: %v", err)
+	}
+	resume := inv
+	resume.ExecutionMode = ExecutionModeIncremental
+	resume.Checkpoint = false
+	resume.ResumeRunID = cleanRun
+	res, err = Execute(context.Background(), resume)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitSuccess {
+		t.Fatalf("expected success, got %d", res.ExitCode)
+	}
+	count, err := os.ReadFile(countPath)
+	if err != nil {
+		t.Fatalf("read count: %v", err)
+	}
+	if string(count) != "run\n" {
+		t.Fatalf("A executed again on resume: %q", count)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "out", "a.txt")); err != nil {
+		t.Fatalf("expected A's output restored: %v", err)
+	}
+}
+
+func TestExecute_Clean_NoCheckpointsByDefault(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "A", Inputs: []string{}, Run: "true"}, {Name: "B", Inputs: []string{}, Run: "exit 1"}}, nil)
+
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+	}
+	if _, err := Execute(context.Background(), inv); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	ids, _ := st.ListRunIDs()
+	if len(ids) != 1 {
+		t.Fatalf("runs = %v", ids)
+	}
+	if cps, _ := st.LoadAllCheckpoints(ids[0]); len(cps) != 0 {
+		t.Fatalf("expected no checkpoints, got %v", cps)
+	}
+	if _, err := os.Stat(st.RunCacheDir(ids[0])); !os.IsNotExist(err) {
+		t.Fatalf("expected no run cache, stat err=%v", err)
+	}
+}
+
+func TestExecute_Incremental_ResumesModifiedGraphPerNode(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+
+	a := core.Task{Name: "A", Inputs: []string{}, Run: "echo A >> runs.log && mkdir -p out && echo a > out/a.txt", Outputs: []string{"out/a.txt"}}
+	c := core.Task{Name: "C", Inputs: []string{}, Run: "echo C >> runs.log"}
+	d := core.Task{Name: "D", Inputs: []string{}, Run: "echo D >> runs.log"}
+	e := core.Task{Name: "E", Inputs: []string{}, Run: "echo E >> runs.log"}
+	b := core.Task{Name: "B", Inputs: []string{"out/a.txt"}, Run: "exit 7"}
+	writeGraphJSON(t, graphPath, []core.Task{a, b, c, d, e}, []dag.Edge{{From: "A", To: "B"}, {From: "A", To: "D"}})
+
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "sw-out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+	res, err := Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitGraphFailure || res.Resume != nil {
+		t.Fatalf("first run: exit=%d resume=%+v", res.ExitCode, res.Resume)
+	}
+
+	// Fix B's command, edit E and move D under C: the graph hash changes, but
+	// A and C are unchanged and can be reused.
+	b.Run = "true"
+	e.Run = "echo E >> runs.log # edited"
+	writeGraphJSON(t, graphPath, []core.Task{a, b, c, d, e}, []dag.Edge{{From: "A", To: "B"}, {From: "C", To: "D"}})
+	res, err = Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitSuccess {
+		t.Fatalf("expected success, got %d", res.ExitCode)
+	}
+	if res.Resume == nil || !res.Resume.GraphChanged {
+		t.Fatalf("expected node-level resume of a changed graph, got %+v", res.Resume)
+	}
+	// Both lists are in topological order; compare them by name.
+	reused := append([]string(nil), res.Resume.Reused...)
+	sort.Strings(reused)
+	if want := []string{"A", "C"}; !reflect.DeepEqual(reused, want) {
+		t.Fatalf("reused = %v, want %v", reused, want)
+	}
+	rerun := append([]ResumeNode(nil), res.Resume.Rerun...)
+	sort.Slice(rerun, func(i, j int) bool { return rerun[i].Name < rerun[j].Name })
+	wantRerun := []ResumeNode{{Name: "B", Reason: ResumeReasonNoCheckpoint}, {Name: "D", Reason: ResumeReasonDependenciesChanged}, {Name: "E", Reason: ResumeReasonTaskChanged}}
+	if !reflect.DeepEqual(rerun, wantRerun) {
+		t.Fatalf("rerun = %+v, want %+v", rerun, wantRerun)
+	}
+
+	log, err := os.ReadFile(filepath.Join(workDir, "runs.log"))
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	counts := map[string]int{}
+	for _, line := range strings.Fields(string(log)) {
+		counts[line]++
+	}
+	// D is not resumed from its checkpoint, but its unchanged task may still
+	// be served by the cache.
+	if counts["A"] != 1 || counts["C"] != 1 || counts["E"] != 2 {
+		t.Fatalf("executions = %v", counts)
+	}
+
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	ids, _ := st.ListRunIDs()
+	found := false
+	for _, id := range ids {
+		if data, err := st.LoadRunFile(id, ResumeFileName); err == nil {
+			found = strings.Contains(string(data), `"graph_changed": true`)
+		}
+	}
+	if !found {
+		t.Fatalf("expected %s recorded for the resumed run", ResumeFileName)
+	}
+}
+
+func TestExecute_Resume_WithDifferentCacheAndOutputDirs(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	flagPath := filepath.Join(workDir, "flag")
+
+	tasks := []core.Task{
+		{Name: "A", Inputs: []string{}, Run: "echo A >> runs.log && mkdir -p out && echo a > out/a.txt", Outputs: []string{"out/a.txt"}},
+		{Name: "B", Inputs: []string{"out/a.txt"}, Run: "test -f flag"},
+	}
+	writeGraphJSON(t, graphPath, tasks, []dag.Edge{{From: "A", To: "B"}})
+
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache-1"),
+		OutputDir:     filepath.Join(workDir, "out-1"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	res, err := Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitGraphFailure {
+		t.Fatalf("expected graph failure exit, got %d", res.ExitCode)
+	}
+	ids, _ := st.ListRunIDs()
+	if len(ids) != 1 {
+		t.Fatalf("runs = %v", ids)
+	}
+
+	if err := os.WriteFile(flagPath, []byte("ok\n"), 0o644); err != nil {
+		t.Fatalf("write flag: %v", err)
+	}
+	resume := inv
+	resume.ResumeRunID = ids[0]
+	resume.CacheDir = filepath.Join(workDir, "cache-2")
+	resume.OutputDir = filepath.Join(workDir, "out-2")
+	resume.Trace = TraceConfig{Enabled: true, Path: filepath.Join(resume.OutputDir, "trace.json")}
+	res, err = Execute(context.Background(), resume)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitSuccess {
+		t.Fatalf("expected success, got %d", res.ExitCode)
+	}
+	if res.Resume == nil || !reflect.DeepEqual(res.Resume.Reused, []string{"A"}) {
+		t.Fatalf("expected A reused, got %+v", res.Resume)
+	}
+	log, err := os.ReadFile(filepath.Join(workDir, "runs.log"))
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if string(log) != "A\n" {
+		t.Fatalf("A executed again on resume: %q", log)
+	}
+	if _, err := os.Stat(resume.Trace.Path); err != nil {
+		t.Fatalf("expected trace in the new output dir: %v", err)
+	}
+	// The restored entry now lives in the new cache as well.
+	entries, err := os.ReadDir(resume.CacheDir)
+	if err != nil || len(entries) == 0 {
+		t.Fatalf("expected new cache populated, entries=%v err=%v", entries, err)
+	}
+}
+
+func TestResumeReport_ReplanMovesNodesToRerun(t *testing.T) {
+	g, err := dag.NewTaskGraph([]core.Task{{Name: "A", Run: "true"}, {Name: "B", Run: "true"}, {Name: "C", Run: "true"}}, []dag.Edge{{From: "A", To: "B"}, {From: "B", To: "C"}})
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	r := &ResumeReport{Reused: []string{"A", "B"}, Rerun: []ResumeNode{{Name: "C", Reason: ResumeReasonTaskChanged}}}
+	r.replan(g, map[string]string{"A": "cache corruption"})
+
+	want := &ResumeReport{Reused: []string{"B"}, Rerun: []ResumeNode{{Name: "A", Reason: "restore failed: cache corruption"}, {Name: "C", Reason: ResumeReasonTaskChanged}}}
+	if !reflect.DeepEqual(r, want) {
+		t.Fatalf("report = %+v, want %+v", r, want)
+	}
+}
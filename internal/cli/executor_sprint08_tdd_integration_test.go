@@ -334,7 +334,7 @@ func TestSprint08_WorkspaceCorruption_DeletedCacheEntryRejectsResumeOnly(t *test
 	if len(h) < 2 {
 		t.Fatalf("unexpected hash: %q", h)
 	}
-	meta := filepath.Join(inv1.CacheDir, h[:2], h, "metadata.json")
+	meta := filepath.Join(core.NewFileCache(inv1.CacheDir).EntryDir(core.TaskHash(h)), "metadata.json")
 	if err := os.Remove(meta); err != nil {
 		t.Fatalf("Remove metadata: %v", err)
 	}
@@ -1,14 +1,21 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	"scriptweaver/internal/core"
 	"scriptweaver/internal/dag"
+	"scriptweaver/internal/objectstore"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/recovery/state"
+	"scriptweaver/internal/trace"
 )
 
 type panicExecutor struct{}
@@ -74,6 +81,207 @@ func TestExecute_OverwritePolicy_RemovesStaleFiles(t *testing.T) {
 	}
 }
 
+func TestExecute_Incremental_DefaultsToWorkspaceCache(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "t1", Inputs: []string{}, Run: "echo built > out.txt", Outputs: []string{"out.txt"}}}, nil)
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("first run: exit %d, %v", res.ExitCode, err)
+	}
+	want := filepath.Join(workDir, ".scriptweaver", "cache")
+	if res.CacheDir != want {
+		t.Fatalf("CacheDir = %q, want %q", res.CacheDir, want)
+	}
+	res, err = Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("second run: exit %d, %v", res.ExitCode, err)
+	}
+	if s := res.GraphResult.FinalState["t1"]; s != dag.TaskCached {
+		t.Fatalf("second run: t1 is %s, want cached", s)
+	}
+}
+
+func TestExecute_Incremental_RecordsCacheStats(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "t1", Inputs: []string{}, Run: "echo built > out.txt", Outputs: []string{"out.txt"}}}, nil)
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.CacheStats == nil || *res.CacheStats != (core.CacheStats{Misses: 1}) {
+		t.Fatalf("first run: stats %+v, %v", res.CacheStats, err)
+	}
+	res, err = Execute(context.Background(), inv)
+	if err != nil || res.CacheStats == nil || *res.CacheStats != (core.CacheStats{Hits: 1, BytesRestored: int64(len("built\n"))}) {
+		t.Fatalf("second run: stats %+v, %v", res.CacheStats, err)
+	}
+
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := st.LoadRunFile(res.RunID, CacheStatsFileName)
+	if err != nil {
+		t.Fatalf("load %s: %v", CacheStatsFileName, err)
+	}
+	var saved core.CacheStats
+	if err := json.Unmarshal(data, &saved); err != nil || saved != *res.CacheStats {
+		t.Fatalf("saved stats = %+v, %v", saved, err)
+	}
+
+	inv.ExecutionMode = ExecutionModeClean
+	if res, _ := Execute(context.Background(), inv); res.CacheStats != nil {
+		t.Fatalf("clean run: stats %+v, want none", res.CacheStats)
+	}
+}
+
+func TestExecute_HashAlgorithmFromConfig(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, ".scriptweaver", "config.json"), []byte(`{"hash_algorithm":"blake3"}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "t1", Inputs: []string{}, Run: "echo built > out.txt", Outputs: []string{"out.txt"}}}, nil)
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+
+	if _, err := Execute(context.Background(), inv); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.CacheStats == nil || res.CacheStats.Hits != 1 {
+		t.Fatalf("second run: stats %+v, %v", res.CacheStats, err)
+	}
+	entries, _ := filepath.Glob(filepath.Join(res.CacheDir, "*", "blake3-*"))
+	if len(entries) != 1 {
+		t.Fatalf("expected one blake3 cache entry, got %v", entries)
+	}
+}
+
+func TestExecute_WritesProgressFile(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Inputs: []string{}, Run: "echo a > a.txt", Outputs: []string{"a.txt"}},
+		{Name: "b", Inputs: []string{"a.txt"}, Run: "echo b > b.txt", Outputs: []string{"b.txt"}},
+	}, []dag.Edge{{From: "a", To: "b"}})
+	progressPath := filepath.Join(workDir, "progress.jsonl")
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+		ProgressPath:  progressPath,
+	}
+
+	if _, err := Execute(context.Background(), inv); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	data, err := os.ReadFile(progressPath)
+	if err != nil {
+		t.Fatalf("read progress: %v", err)
+	}
+	var events []dag.Progress
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var p dag.Progress
+		if err := dec.Decode(&p); err != nil {
+			t.Fatalf("decode progress: %v", err)
+		}
+		events = append(events, p)
+	}
+	want := []dag.Progress{
+		{Done: 1, Total: 2, Stages: []dag.StageProgress{{Depth: 0, Done: 1, Total: 1}, {Depth: 1, Done: 0, Total: 1}}},
+		{Done: 2, Total: 2, Stages: []dag.StageProgress{{Depth: 0, Done: 1, Total: 1}, {Depth: 1, Done: 1, Total: 1}}},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("progress events = %+v, want %+v", events, want)
+	}
+}
+
+func TestExecute_SharedCacheTier_ReusedAcrossLocalCaches(t *testing.T) {
+	workDir := t.TempDir()
+	shared := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	cfg := `{"cache":{"tiers":["` + shared + `"],"write":"write-all"}}`
+	if err := os.WriteFile(filepath.Join(workDir, ".scriptweaver", "config.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "t1", Inputs: []string{}, Run: "echo built > out.txt", Outputs: []string{"out.txt"}}}, nil)
+	// Each run stands for a machine with its own local cache and the same
+	// checkout path.
+	run := func(localCache string) dag.TaskState {
+		t.Helper()
+		res, err := Execute(context.Background(), CLIInvocation{
+			WorkDir:       workDir,
+			GraphPath:     graphPath,
+			CacheDir:      filepath.Join(workDir, localCache),
+			OutputDir:     filepath.Join(workDir, "out"),
+			ExecutionMode: ExecutionModeIncremental,
+		})
+		if err != nil || res.ExitCode != ExitSuccess {
+			t.Fatalf("run: exit %d, %v", res.ExitCode, err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(workDir, "out.txt")); string(got) != "built\n" {
+			t.Fatalf("out.txt = %q", got)
+		}
+		return res.GraphResult.FinalState["t1"]
+	}
+
+	if s := run("cache-a"); s != dag.TaskCompleted {
+		t.Fatalf("first run: t1 is %s, want executed", s)
+	}
+	if s := run("cache-b"); s != dag.TaskCached {
+		t.Fatalf("second run: t1 is %s, want cached from the shared tier", s)
+	}
+	if entries, _ := filepath.Glob(filepath.Join(workDir, "cache-b", "*", "*", "metadata.json")); len(entries) != 1 {
+		t.Fatalf("entry not copied into the second local cache: %v", entries)
+	}
+}
+
+func TestWithSharedTiers_ObjectStoreNamespacedByGraph(t *testing.T) {
+	cc := &config.CacheConfig{Tiers: []string{"s3://team/sw"}, Write: config.CacheWriteLocal, Namespace: config.CacheNamespaceGraph}
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if _, err := withSharedTiers(core.NewFileCache(t.TempDir()), cc, t.TempDir(), "abc123"); err == nil {
+		t.Fatalf("expected an error without credentials")
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	c, err := withSharedTiers(core.NewFileCache(t.TempDir()), cc, t.TempDir(), "abc123")
+	if err != nil {
+		t.Fatalf("withSharedTiers: %v", err)
+	}
+	remote, ok := c.(*core.TieredCache).Tiers[1].(*objectstore.Cache)
+	if !ok || remote.Prefix != "sw/abc123" || remote.Client.Endpoint != "https://s3.us-east-1.amazonaws.com" || remote.Client.Bucket != "team" {
+		t.Fatalf("remote tier = %+v", c.(*core.TieredCache).Tiers[1])
+	}
+}
+
 func TestExecute_ExitCodeGraphFailure(t *testing.T) {
 	workDir := t.TempDir()
 	graphPath := filepath.Join(workDir, "graph.json")
@@ -108,6 +316,32 @@ func TestExecute_ExitCodeGraphFailure(t *testing.T) {
 	}
 }
 
+func TestExecute_CancelledContext_ExitCodeCancelled(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+
+	tasks := []core.Task{{Name: "t1", Run: "sleep 5"}}
+	writeGraphJSON(t, graphPath, tasks, nil)
+
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	res, err := Execute(ctx, inv)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if res.ExitCode != ExitCancelled {
+		t.Fatalf("expected exit %d got %d", ExitCancelled, res.ExitCode)
+	}
+}
+
 func TestExecute_ConfigError_WhenOutputDirIsFile(t *testing.T) {
 	workDir := t.TempDir()
 	graphPath := filepath.Join(workDir, "graph.json")
@@ -174,3 +408,58 @@ func TestExecute_Panic_ExitCodeInternalAndTraceFinalized(t *testing.T) {
 		t.Fatalf("expected graphHash in trace")
 	}
 }
+
+type nodeRecorder struct {
+	names []string
+}
+
+func (r *nodeRecorder) OnTaskTerminal(task core.Task, _ *dag.NodeResult, _ []trace.TraceEvent) error {
+	r.names = append(r.names, task.Name)
+	return nil
+}
+
+func TestExecuteWithObservers_NotifiedAlongsideCheckpoints(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	tasks := []core.Task{
+		{Name: "a", Inputs: []string{}, Run: "true"},
+		{Name: "b", Inputs: []string{}, Run: "true"},
+		{Name: "c", Inputs: []string{}, Run: "exit 3"},
+	}
+	writeGraphJSON(t, graphPath, tasks, []dag.Edge{{From: "a", To: "b"}})
+
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	metrics, tracing := &nodeRecorder{}, &nodeRecorder{}
+	res, err := ExecuteWithObservers(context.Background(), inv, metrics, nil, tracing)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitGraphFailure {
+		t.Fatalf("expected exit %d got %d", ExitGraphFailure, res.ExitCode)
+	}
+	for _, r := range []*nodeRecorder{metrics, tracing} {
+		if !reflect.DeepEqual(r.names, []string{"a", "b"}) {
+			t.Fatalf("observed %v, want [a b]", r.names)
+		}
+	}
+
+	ids, _ := st.ListRunIDs()
+	if len(ids) != 1 {
+		t.Fatalf("runs = %v", ids)
+	}
+	for _, n := range []string{"a", "b"} {
+		if _, err := st.LoadCheckpoint(ids[0], n); err != nil {
+			t.Fatalf("expected checkpoint for %s: %v", n, err)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/projectintegration/engine/config"
+)
+
+// ErrUnknownNode is returned when a node named on the command line is not in
+// the graph.
+var ErrUnknownNode = errors.New("unknown node")
+
+// ExplainTaskHash returns the task hash node of g has in workDir now, with the
+// components it is computed from: the files currently on disk, the hash
+// algorithm of the workspace config and the fetches pinned by the lockfile,
+// as an incremental run would use them once the node's dependencies have
+// completed.
+func ExplainTaskHash(workDir string, g *dag.TaskGraph, node string) (core.HashExplanation, error) {
+	cfg, _, err := config.LoadOptional(workDir)
+	if err != nil {
+		return core.HashExplanation{}, err
+	}
+	lockfile, err := LoadLockfile(workDir)
+	if err != nil {
+		return core.HashExplanation{}, err
+	}
+	if g, err = pinFetches(g, lockfile); err != nil {
+		return core.HashExplanation{}, err
+	}
+	n, ok := g.Node(node)
+	if !ok {
+		return core.HashExplanation{}, fmt.Errorf("%w: %q", ErrUnknownNode, node)
+	}
+
+	task := n.Task
+	inputs, err := core.NewInputResolver(workDir).Resolve(task.Inputs)
+	if err != nil {
+		return core.HashExplanation{}, fmt.Errorf("node %s: resolving inputs: %w", node, err)
+	}
+	hasher := &core.TaskHasher{Algorithm: hashAlgorithm(cfg)}
+	return hasher.Explain(task.HashInput(inputs, workDir)), nil
+}
@@ -0,0 +1,474 @@
+package cli
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/recovery/state"
+)
+
+// ExportManifestName is the file at the root of an export bundle that
+// describes it.
+const ExportManifestName = "scriptweaver-export.json"
+
+// exportFormat is the version of the bundle layout.
+const exportFormat = 1
+
+// redactedSettings are the workspace config settings left out of an export:
+// they name the storage and the chat webhook, a secret, of the workspace that
+// made it.
+var redactedSettings = []string{"cache", "cache_encryption", "notify", "publish"}
+
+// ErrInvalidBundle is returned by ImportRun for input that is not a bundle
+// written by ExportRun.
+var ErrInvalidBundle = errors.New("invalid export bundle")
+
+// ErrEncryptedCacheExport is returned by ExportRun for cache entries of a
+// workspace whose cache is encrypted.
+var ErrEncryptedCacheExport = errors.New("cache entries are encrypted and cannot be exported")
+
+// ErrGraphChanged is returned by ExportRun when the graph file of the run no
+// longer loads to the graph the run ran.
+var ErrGraphChanged = errors.New("graph changed since the run")
+
+// ErrImportConflict is returned by ImportRun when a file of the bundle
+// already exists in the target workspace.
+var ErrImportConflict = errors.New("already exists")
+
+// ExportOptions selects what ExportRun bundles besides the run's records.
+type ExportOptions struct {
+	// IncludeCache adds the cache entries of the run's checkpointed nodes
+	// and its run-scoped cache, so that the importing workspace can resume
+	// the run. They are left out by default: they hold every output.
+	IncludeCache bool
+}
+
+// ExportManifest describes an export bundle. Paths are slash-separated and
+// relative to the workdir the bundle is imported into.
+type ExportManifest struct {
+	Format    int    `json:"format"`
+	RunID     string `json:"run_id"`
+	GraphHash string `json:"graph_hash"`
+	Graph     string `json:"graph"`
+	// Includes lists the graph files the graph includes, directly or not.
+	Includes []string `json:"includes,omitempty"`
+	// Snapshot and Plan are the run files that record the graph the run ran
+	// and what it planned to do with each node; runs of earlier versions
+	// recorded neither.
+	Snapshot string `json:"snapshot,omitempty"`
+	Plan     string `json:"plan,omitempty"`
+	Trace    string `json:"trace,omitempty"`
+	// Redacted lists the workspace config settings that were left out.
+	Redacted []string `json:"redacted,omitempty"`
+	Cache    bool     `json:"cache"`
+	Files    []string `json:"files"`
+}
+
+// ExportRun writes a bundle of run runID of the workspace in workDir to w,
+// as a zstd-compressed tar, for reproducing the run elsewhere: the graph file
+// it ran and every file it includes, the workspace lockfile, the workspace
+// config without the settings in redactedSettings, the run's trace, and
+// everything recorded for the run (parameters, graph snapshot, plan,
+// checkpoints, failures, timeline and other run files) except its cache,
+// unless opts.IncludeCache is set. The bundle is deterministic: exporting the
+// same files twice yields the same bytes.
+//
+// The graph files are bundled as they are now, so ExportRun refuses, with
+// ErrGraphChanged, to export a run whose graph files no longer load, with
+// the run's parameters, to the graph of the run's hash.
+func ExportRun(workDir, runID string, w io.Writer, opts ExportOptions) (ExportManifest, error) {
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		return ExportManifest{}, err
+	}
+	run, err := st.LoadRun(runID)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ExportManifest{}, fmt.Errorf("run %s not found: %w", runID, err)
+	}
+	if err != nil {
+		return ExportManifest{}, err
+	}
+	params, err := LoadRunParams(st, runID)
+	if err != nil {
+		return ExportManifest{}, err
+	}
+	m := ExportManifest{Format: exportFormat, RunID: runID, GraphHash: run.GraphHash, Cache: opts.IncludeCache}
+	files := make(map[string][]byte)
+
+	if err := addGraphFiles(files, &m, workDir, params); err != nil {
+		return ExportManifest{}, err
+	}
+	if params.Trace != "" {
+		if m.Trace, err = addWorkspaceFile(files, workDir, params.Trace, "trace.json"); errors.Is(err, fs.ErrNotExist) {
+			m.Trace = ""
+		} else if err != nil {
+			return ExportManifest{}, fmt.Errorf("read trace: %w", err)
+		}
+	}
+	if _, err := addWorkspaceFile(files, workDir, LockFileName, LockFileName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return ExportManifest{}, err
+	}
+	if data, err := os.ReadFile(filepath.Join(workDir, ".scriptweaver", "config.json")); err == nil {
+		redacted, removed, err := redactConfig(data)
+		if err != nil {
+			return ExportManifest{}, err
+		}
+		files[".scriptweaver/config.json"] = redacted
+		m.Redacted = removed
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return ExportManifest{}, fmt.Errorf("read config: %w", err)
+	}
+
+	runDir, err := st.RunDir(runID)
+	if err != nil {
+		return ExportManifest{}, err
+	}
+	runPrefix := ".scriptweaver/runs/" + runID
+	err = addTree(files, runDir, runPrefix, func(rel string) bool {
+		return opts.IncludeCache || (rel != "cache" && !strings.HasPrefix(rel, "cache/"))
+	})
+	if err != nil {
+		return ExportManifest{}, err
+	}
+	if opts.IncludeCache {
+		// Encrypted entries would need the workspace's keys to be read, and
+		// are not decrypted into a bundle.
+		if cfg, _, err := config.LoadOptional(workDir); err != nil {
+			return ExportManifest{}, err
+		} else if cfg.CacheEncryption != nil {
+			return ExportManifest{}, ErrEncryptedCacheExport
+		}
+		if err := addCheckpointedEntries(files, st, runID, runPrefix+"/cache"); err != nil {
+			return ExportManifest{}, err
+		}
+	}
+
+	for name := range files {
+		m.Files = append(m.Files, name)
+	}
+	if _, ok := files[runPrefix+"/"+SnapshotFileName]; ok {
+		m.Snapshot = runPrefix + "/" + SnapshotFileName
+	}
+	if _, ok := files[runPrefix+"/"+PlanFileName]; ok {
+		m.Plan = runPrefix + "/" + PlanFileName
+	}
+	sort.Strings(m.Files)
+	return m, writeBundle(w, m, files)
+}
+
+// addGraphFiles adds the graph file of the run with parameters rp, and every
+// file it includes, to files and m, once they are checked to load to the
+// graph of m.GraphHash. Included files must lie inside workDir.
+func addGraphFiles(files map[string][]byte, m *ExportManifest, workDir string, rp RunParams) error {
+	path := filepath.FromSlash(rp.Graph)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+	g, sources, err := runGraph(path, rp)
+	if err != nil {
+		return fmt.Errorf("run %s: %w: %v", m.RunID, ErrGraphChanged, err)
+	}
+	// The run that first fetched a fetch task recorded its pin after it ran,
+	// so the graph may hash to the run's hash with or without the pins.
+	lf, err := LoadLockfile(workDir)
+	if err != nil {
+		return err
+	}
+	pinned, err := pinFetches(g, lf)
+	if err != nil {
+		return err
+	}
+	if hash := pinned.Hash().String(); hash != m.GraphHash && g.Hash().String() != m.GraphHash {
+		return fmt.Errorf("run %s: %w: it hashes to %s, the run to %s", m.RunID, ErrGraphChanged, hash, m.GraphHash)
+	}
+	for src, data := range sources {
+		if src == path {
+			continue
+		}
+		rel, err := filepath.Rel(workDir, src)
+		if err != nil || !filepath.IsLocal(rel) {
+			return fmt.Errorf("include %s lies outside the workdir and cannot be bundled", src)
+		}
+		files[filepath.ToSlash(rel)] = data
+		m.Includes = append(m.Includes, filepath.ToSlash(rel))
+	}
+	sort.Strings(m.Includes)
+	m.Graph = "graph.json"
+	if rel, err := filepath.Rel(workDir, path); err == nil && filepath.IsLocal(rel) {
+		m.Graph = filepath.ToSlash(rel)
+	}
+	files[m.Graph] = sources[path]
+	return nil
+}
+
+// runGraph loads the graph file at path the way the run with parameters rp
+// loaded it, but for lockfile pins, and returns the graph and the files it
+// read.
+func runGraph(path string, rp RunParams) (*dag.TaskGraph, map[string][]byte, error) {
+	gf, sources, err := readGraph(path, rp.Set)
+	if err != nil {
+		return nil, nil, err
+	}
+	g, _, err := buildGraph(gf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rp.NamespaceOutputs {
+		if g, err = namespaceOutputs(g, rp.OutputDir); err != nil {
+			return nil, nil, err
+		}
+	}
+	return g, sources, nil
+}
+
+// addWorkspaceFile adds the file at path, relative to workDir unless
+// absolute, to files. It keeps its path when it lies inside workDir and is
+// stored as fallback otherwise. It returns the path in the bundle.
+func addWorkspaceFile(files map[string][]byte, workDir, path, fallback string) (string, error) {
+	src := filepath.FromSlash(path)
+	if !filepath.IsAbs(src) {
+		src = filepath.Join(workDir, src)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+	name := fallback
+	if rel, err := filepath.Rel(workDir, src); err == nil && filepath.IsLocal(rel) {
+		name = filepath.ToSlash(rel)
+	}
+	files[name] = data
+	return name, nil
+}
+
+// redactConfig returns the workspace config data without redactedSettings,
+// and the settings it removed.
+func redactConfig(data []byte) ([]byte, []string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("parse config: %w", err)
+	}
+	var removed []string
+	for _, key := range redactedSettings {
+		if _, ok := raw[key]; ok {
+			delete(raw, key)
+			removed = append(removed, key)
+		}
+	}
+	out, err := json.MarshalIndent(raw, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(out, '\n'), removed, nil
+}
+
+// addTree adds the regular files under dir for which keep, given their
+// slash path relative to dir, is true, under prefix.
+func addTree(files map[string][]byte, dir, prefix string, keep func(rel string) bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if !keep(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[prefix+"/"+rel] = data
+		return nil
+	})
+}
+
+// addCheckpointedEntries adds the cache entries of the checkpointed nodes of
+// runID, from the caches the run could be resumed from, as a file cache
+// under prefix.
+func addCheckpointedEntries(files map[string][]byte, st *state.Store, runID, prefix string) error {
+	checkpoints, err := st.LoadAllCheckpoints(runID)
+	if err != nil {
+		return err
+	}
+	var sources []core.Cache
+	for _, dir := range previousCacheDirs(st, runID, "") {
+		sources = append(sources, core.NewFileCache(dir))
+	}
+	tmp, err := os.MkdirTemp("", "sw-export-cache-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	bundled := core.NewFileCache(tmp)
+	for _, cp := range checkpoints {
+		if !cp.Valid || len(cp.CacheKeys) == 0 || cp.CacheKeys[0] == "" {
+			continue
+		}
+		hash := core.TaskHash(cp.CacheKeys[0])
+		for _, src := range sources {
+			entry, err := src.Get(hash)
+			if err != nil {
+				return err
+			}
+			if entry != nil {
+				if err := bundled.Put(entry); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return addTree(files, tmp, prefix, func(string) bool { return true })
+}
+
+// writeBundle writes m and files to w as a zstd-compressed tar, manifest
+// first and then in path order, with fixed modes and times. The encoder runs
+// single-threaded, so the output does not depend on the machine.
+func writeBundle(w io.Writer, m ExportManifest, files map[string][]byte) error {
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	zw, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: time.Unix(0, 0), Typeflag: tar.TypeReg, Format: tar.FormatPAX}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := add(ExportManifestName, append(manifest, '\n')); err != nil {
+		return err
+	}
+	for _, name := range m.Files {
+		if err := add(name, files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ImportRun unpacks a bundle written by ExportRun into the workspace in
+// workDir, so that the run can be inspected with sw runs, replayed from its
+// trace or resumed. Bundles of earlier versions, compressed with gzip, are
+// read too. It refuses, with ErrImportConflict, to overwrite any existing
+// file, and writes nothing in that case.
+func ImportRun(workDir string, r io.Reader) (ExportManifest, error) {
+	zr, err := decompressBundle(r)
+	if err != nil {
+		return ExportManifest{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	files := make(map[string][]byte)
+	var manifest []byte
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ExportManifest{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !filepath.IsLocal(filepath.FromSlash(hdr.Name)) {
+			return ExportManifest{}, fmt.Errorf("%w: unexpected entry %q", ErrInvalidBundle, hdr.Name)
+		}
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, tr); err != nil {
+			return ExportManifest{}, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		if hdr.Name == ExportManifestName {
+			manifest = buf.Bytes()
+			continue
+		}
+		files[hdr.Name] = buf.Bytes()
+	}
+	if manifest == nil {
+		return ExportManifest{}, fmt.Errorf("%w: no %s", ErrInvalidBundle, ExportManifestName)
+	}
+	var m ExportManifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return ExportManifest{}, fmt.Errorf("%w: parse %s: %v", ErrInvalidBundle, ExportManifestName, err)
+	}
+	if m.Format != exportFormat {
+		return ExportManifest{}, fmt.Errorf("%w: unsupported format %d", ErrInvalidBundle, m.Format)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := os.Lstat(filepath.Join(workDir, filepath.FromSlash(name))); err == nil {
+			return ExportManifest{}, fmt.Errorf("import run %s: %s %w", m.RunID, name, ErrImportConflict)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return ExportManifest{}, err
+		}
+	}
+	for _, name := range names {
+		path := filepath.Join(workDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return ExportManifest{}, err
+		}
+		if err := os.WriteFile(path, files[name], 0o644); err != nil {
+			return ExportManifest{}, err
+		}
+	}
+	return m, nil
+}
+
+// gzipMagic starts the bundles of earlier versions.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompressBundle returns a reader of the tar in r, a bundle compressed
+// with zstd or, by earlier versions, gzip.
+func decompressBundle(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+		return gzip.NewReader(br)
+	}
+	zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return zr.IOReadCloser(), nil
+}
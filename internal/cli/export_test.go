@@ -0,0 +1,196 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestExportRun_ImportsIntoAnotherWorkspace(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	config := `{"label_limits":{"network":1},"cache":{"tiers":["../team-cache"]}}`
+	if err := os.WriteFile(filepath.Join(workDir, ".scriptweaver", "config.json"), []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graphs", "build.json")
+	if err := os.MkdirAll(filepath.Dir(graphPath), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: "echo a > a.txt", Outputs: []string{"a.txt"}},
+		{Name: "b", Run: "echo b; exit 3"},
+	}, nil)
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+		Trace:         TraceConfig{Enabled: true, Path: filepath.Join(workDir, "out", "trace.json")},
+	}
+	res, _ := Execute(context.Background(), inv)
+	if res.ExitCode != ExitGraphFailure {
+		t.Fatalf("exit=%d", res.ExitCode)
+	}
+
+	var bundle bytes.Buffer
+	m, err := ExportRun(workDir, res.RunID, &bundle, ExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportRun: %v", err)
+	}
+	if m.Graph != "graphs/build.json" || m.Trace != "out/trace.json" || len(m.Redacted) != 1 || m.Redacted[0] != "cache" {
+		t.Fatalf("manifest = %+v", m)
+	}
+	for _, name := range m.Files {
+		if strings.Contains(name, "/cache/") {
+			t.Fatalf("cache bundled by default: %s", name)
+		}
+	}
+	var again bytes.Buffer
+	if _, err := ExportRun(workDir, res.RunID, &again, ExportOptions{}); err != nil || !bytes.Equal(again.Bytes(), bundle.Bytes()) {
+		t.Fatalf("second export differs: %v", err)
+	}
+
+	target := t.TempDir()
+	if _, err := ImportRun(target, bytes.NewReader(bundle.Bytes())); err != nil {
+		t.Fatalf("ImportRun: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(target, ".scriptweaver", "config.json"))
+	if err != nil || strings.Contains(string(data), "team-cache") || !strings.Contains(string(data), "label_limits") {
+		t.Fatalf("imported config = %s, %v", data, err)
+	}
+	st, err := state.NewStore(target)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	rec, err := ShowRun(st, res.RunID)
+	if err != nil || rec.Failure == nil || rec.Params == nil || rec.Params.Graph != "graphs/build.json" {
+		t.Fatalf("imported run = %+v, %v", rec, err)
+	}
+	if _, err := os.Stat(filepath.Join(target, "out", "trace.json")); err != nil {
+		t.Fatalf("trace not imported: %v", err)
+	}
+
+	if _, err := ImportRun(target, bytes.NewReader(bundle.Bytes())); !errors.Is(err, ErrImportConflict) {
+		t.Fatalf("second import: %v", err)
+	}
+	if _, err := ImportRun(t.TempDir(), strings.NewReader("not a bundle")); !errors.Is(err, ErrInvalidBundle) {
+		t.Fatalf("invalid bundle: %v", err)
+	}
+
+	bundle.Reset()
+	if m, err = ExportRun(workDir, res.RunID, &bundle, ExportOptions{IncludeCache: true}); err != nil {
+		t.Fatalf("ExportRun with cache: %v", err)
+	}
+	cached := false
+	for _, name := range m.Files {
+		cached = cached || strings.HasSuffix(name, "/metadata.json")
+	}
+	if !cached {
+		t.Fatalf("no cache entry bundled: %v", m.Files)
+	}
+}
+
+func TestExportRun_RedactsNotifyWebhook(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	const webhook = "https://hooks.slack.com/services/T000/B000/secret-token"
+	config := `{"notify":{"format":"slack","webhook_url":"` + webhook + `","on":"failure"}}`
+	if err := os.WriteFile(filepath.Join(workDir, ".scriptweaver", "config.json"), []byte(config), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Run: "true"}}, nil)
+	res, err := Execute(context.Background(), CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean})
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+
+	var bundle bytes.Buffer
+	m, err := ExportRun(workDir, res.RunID, &bundle, ExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportRun: %v", err)
+	}
+	if len(m.Redacted) != 1 || m.Redacted[0] != "notify" {
+		t.Fatalf("redacted = %v", m.Redacted)
+	}
+	zr, err := decompressBundle(&bundle)
+	if err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("read bundle: %v", err)
+	}
+	if bytes.Contains(data, []byte("secret-token")) {
+		t.Fatalf("bundle holds the webhook URL")
+	}
+}
+
+func TestExportRun_BundlesIncludesSnapshotAndPlan(t *testing.T) {
+	workDir := t.TempDir()
+	writeFile(t, filepath.Join(workDir, "lint", "graph.json"), `{"tasks": [{"name": "check", "run": "true"}], "edges": []}`)
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeFile(t, graphPath, `{
+		"includes": [{"path": "lint/graph.json", "namespace": "lint"}],
+		"tasks": [{"name": "build", "run": "true"}],
+		"edges": [{"from": "lint/check", "to": "build"}]
+	}`)
+	res, err := Execute(context.Background(), CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean})
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+
+	var bundle bytes.Buffer
+	m, err := ExportRun(workDir, res.RunID, &bundle, ExportOptions{})
+	if err != nil {
+		t.Fatalf("ExportRun: %v", err)
+	}
+	if len(m.Includes) != 1 || m.Includes[0] != "lint/graph.json" || m.Snapshot == "" || m.Plan == "" {
+		t.Fatalf("manifest = %+v", m)
+	}
+	target := t.TempDir()
+	if _, err := ImportRun(target, &bundle); err != nil {
+		t.Fatalf("ImportRun: %v", err)
+	}
+	for _, name := range []string{m.Graph, m.Snapshot} {
+		g, err := LoadGraphFromFile(filepath.Join(target, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("load imported %s: %v", name, err)
+		}
+		if g.Hash().String() != m.GraphHash {
+			t.Fatalf("imported %s hashes to %s, want %s", name, g.Hash(), m.GraphHash)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(target, filepath.FromSlash(m.Plan)))
+	if err != nil || !strings.Contains(string(data), `"lint/check": "Execute"`) {
+		t.Fatalf("plan = %s, %v", data, err)
+	}
+}
+
+func TestExportRun_RefusesChangedGraph(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Run: "true"}}, nil)
+	res, err := Execute(context.Background(), CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean})
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Run: "false"}}, nil)
+
+	if _, err := ExportRun(workDir, res.RunID, io.Discard, ExportOptions{}); !errors.Is(err, ErrGraphChanged) {
+		t.Fatalf("ExportRun: %v, want ErrGraphChanged", err)
+	}
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+// nodeFailures collects the post-mortem context of every failed node of gr,
+// ordered by node name.
+func nodeFailures(g *dag.TaskGraph, gr *dag.GraphResult, durations map[string]time.Duration, retryCount int) []state.NodeFailure {
+	if gr == nil {
+		return nil
+	}
+	names := make([]string, 0)
+	for name, st := range gr.FinalState {
+		if st == dag.TaskFailed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	out := make([]state.NodeFailure, 0, len(names))
+	for _, name := range names {
+		node, ok := g.Node(name)
+		if !ok {
+			continue
+		}
+		nf := state.NodeFailure{
+			NodeID:     name,
+			Command:    node.Task.Run,
+			EnvDigest:  envDigest(core.EffectiveEnv(node.Task.Env)),
+			Duration:   durations[name],
+			RetryCount: retryCount,
+		}
+		if cause, ok := gr.FailedBySkip[name]; ok {
+			// The node never ran: it has no exit code and no output.
+			nf.Kind, nf.Cause = state.FailureKindSkipPolicy, cause
+		} else {
+			code, _ := gr.ExitCodeOf(name)
+			stderr, _ := gr.StderrOf(name)
+			nf.ExitCode, nf.Kind = &code, state.ClassifyExit(code, stderr)
+			nf.StderrTail, nf.StderrTruncated = stderrTail(stderr)
+		}
+		if md := node.Task.Metadata; md != nil {
+			nf.Owner, nf.Description, nf.DocsURL = md.Owner, md.Description, md.DocsURL
+		}
+		out = append(out, nf)
+	}
+	return out
+}
+
+// envDigest returns the sha256 hex of env as sorted KEY=VALUE lines.
+func envDigest(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{'='})
+		h.Write([]byte(env[k]))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// stderrTail returns the last state.StderrTailBytes of stderr, starting at a
+// rune boundary, and whether anything was dropped.
+func stderrTail(stderr []byte) (string, bool) {
+	if len(stderr) <= state.StderrTailBytes {
+		return string(stderr), false
+	}
+	tail := stderr[len(stderr)-state.StderrTailBytes:]
+	for i := 0; i < utf8.UTFMax && len(tail) > 0 && !utf8.RuneStart(tail[0]); i++ {
+		tail = tail[1:]
+	}
+	return string(tail), true
+}
+
+// annotation describes who owns the node md annotates and where its runbook
+// lives, as " [owner team-a, docs https://...]", or "" when md names neither.
+func annotation(md *core.TaskMetadata) string {
+	if md == nil {
+		return ""
+	}
+	var parts []string
+	if md.Owner != "" {
+		parts = append(parts, "owner "+md.Owner)
+	}
+	if md.DocsURL != "" {
+		parts = append(parts, "docs "+md.DocsURL)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [%s]", strings.Join(parts, ", "))
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+)
+
+// FormatGraph returns data, a graph file, in canonical form, as sw fmt writes
+// it. A file with a top-level schema_version is a graph document and is
+// formatted by graph.Format. Any other is a graph file of sw run, which is
+// decoded as LoadGraphFromFile decodes it and written with the keys in the
+// order of the graph types, tasks sorted by name, their inputs and outputs
+// sorted, edges sorted by From then To, and two-space indentation, ending in
+// a newline. Exactly the fields set in the source are written, and params,
+// includes, definitions and the extends of tasks are left as they are, so
+// formatting never changes the graph or its hash. Formatting a formatted
+// file returns it unchanged.
+func FormatGraph(data []byte) ([]byte, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err == nil {
+		if _, ok := probe["schema_version"]; ok {
+			return graph.Format(data)
+		}
+	}
+	if _, err := decodeGraph(data); err != nil {
+		return nil, err
+	}
+	var src formatGraphFile
+	if err := json.Unmarshal(data, &src); err != nil {
+		return nil, fmt.Errorf("parse graph json: %w", err)
+	}
+	if src.Tasks != nil {
+		tasks := *src.Tasks
+		sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+		for _, t := range tasks {
+			if t.Inputs != nil {
+				sort.Strings(*t.Inputs)
+			}
+			if t.Outputs != nil {
+				sort.Strings(*t.Outputs)
+			}
+		}
+	}
+	if src.Edges != nil {
+		edges := *src.Edges
+		sort.SliceStable(edges, func(i, j int) bool {
+			if edges[i].From != edges[j].From {
+				return edges[i].From < edges[j].From
+			}
+			return edges[i].To < edges[j].To
+		})
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Commands often hold HTML characters, such as redirections.
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(&src); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatGraphFile is a graph file as FormatGraph writes it. Its fields, and
+// those of its tasks, are pointers so that exactly the fields set in the
+// source are written.
+type formatGraphFile struct {
+	Params   *map[string]GraphParam `json:"params,omitempty"`
+	Includes *[]GraphInclude        `json:"includes,omitempty"`
+	// Definitions are written by name, each fragment as written.
+	Definitions *map[string]json.RawMessage `json:"definitions,omitempty"`
+	Tasks       *[]formatTask               `json:"tasks,omitempty"`
+	Edges       *[]dag.Edge                 `json:"edges,omitempty"`
+	Success     *SuccessCriteria            `json:"success,omitempty"`
+}
+
+type formatTask struct {
+	Name                string             `json:"name"`
+	Extends             json.RawMessage    `json:"extends,omitempty"`
+	Inputs              *[]string          `json:"inputs,omitempty"`
+	Run                 *string            `json:"run,omitempty"`
+	Env                 *map[string]string `json:"env,omitempty"`
+	Outputs             *[]string          `json:"outputs,omitempty"`
+	Runner              *string            `json:"runner,omitempty"`
+	Network             *bool              `json:"network,omitempty"`
+	Fetch               *core.Fetch        `json:"fetch,omitempty"`
+	PlatformIndependent *bool              `json:"platform_independent,omitempty"`
+	Mutex               *string            `json:"mutex,omitempty"`
+	Labels              *[]string          `json:"labels,omitempty"`
+	TimeoutSeconds      *int               `json:"timeout_seconds,omitempty"`
+	Retries             *int               `json:"retries,omitempty"`
+	Metadata            *core.TaskMetadata `json:"metadata,omitempty"`
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/graph"
+)
+
+func TestFormatGraph_WritesExactlyTheFieldsSet(t *testing.T) {
+	src := `{"success": {"require": "b"}, "includes": [{"path": "z.json", "namespace": "z"}, {"path": "a.json", "namespace": "a"}],
+		"params": {"v": {"default": "1"}, "mode": {}},
+		"tasks": [{"name": "b", "run": "echo {{mode}}", "inputs": [], "retries": 0, "labels": ["net", "db"]},
+		          {"name": "a", "fetch": {"sha256": "", "url": "https://example.com/x"}, "inputs": [], "outputs": ["x"]}]}`
+	got, err := FormatGraph([]byte(src))
+	if err != nil {
+		t.Fatalf("FormatGraph: %v", err)
+	}
+	want := `{
+  "params": {
+    "mode": {},
+    "v": {
+      "default": "1"
+    }
+  },
+  "includes": [
+    {
+      "path": "z.json",
+      "namespace": "z"
+    },
+    {
+      "path": "a.json",
+      "namespace": "a"
+    }
+  ],
+  "tasks": [
+    {
+      "name": "a",
+      "inputs": [],
+      "outputs": [
+        "x"
+      ],
+      "fetch": {
+        "url": "https://example.com/x"
+      }
+    },
+    {
+      "name": "b",
+      "inputs": [],
+      "run": "echo {{mode}}",
+      "labels": [
+        "net",
+        "db"
+      ],
+      "retries": 0
+    }
+  ],
+  "success": {
+    "require": "b"
+  }
+}
+`
+	if string(got) != want {
+		t.Fatalf("formatted:\n%s\nwant:\n%s", got, want)
+	}
+	again, err := FormatGraph(got)
+	if err != nil || !bytes.Equal(again, got) {
+		t.Fatalf("formatting is not idempotent (err=%v):\n%s", err, again)
+	}
+
+	if _, err := FormatGraph([]byte(`{"tasks": []} {}`)); err == nil || !strings.Contains(err.Error(), "trailing data") {
+		t.Fatalf("expected the loader's error for trailing data, got %v", err)
+	}
+}
+
+func TestFormatGraph_KeepsDefinitions(t *testing.T) {
+	src := `{"tasks": [{"run": "go test", "extends": ["go"], "name": "test"}], "definitions": {"go": {"retries": 1, "env": {"CGO_ENABLED": "0"}}}}`
+	got, err := FormatGraph([]byte(src))
+	if err != nil {
+		t.Fatalf("FormatGraph: %v", err)
+	}
+	want := `{
+  "definitions": {
+    "go": {
+      "retries": 1,
+      "env": {
+        "CGO_ENABLED": "0"
+      }
+    }
+  },
+  "tasks": [
+    {
+      "name": "test",
+      "extends": [
+        "go"
+      ],
+      "run": "go test"
+    }
+  ]
+}
+`
+	if string(got) != want {
+		t.Fatalf("formatted:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatGraph_FormatsGraphDocuments(t *testing.T) {
+	src := `{"schema_version": "2.0.0", "graph": {"nodes": [{"id": "a", "type": "exec", "inputs": {"cmd": "true"}, "outputs": []}], "edges": []}, "metadata": {}}`
+	got, err := FormatGraph([]byte(src))
+	if err != nil {
+		t.Fatalf("FormatGraph: %v", err)
+	}
+	want, _ := graph.Format([]byte(src))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("formatted:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -1,53 +1,246 @@
 package cli
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"scriptweaver/internal/core"
 	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+	"scriptweaver/internal/pluginengine"
+	"scriptweaver/internal/projectintegration/engine/config"
 )
 
 type graphFile struct {
-	Tasks []core.Task `json:"tasks"`
-	Edges []dag.Edge  `json:"edges"`
+	Params   map[string]GraphParam `json:"params,omitempty"`
+	Includes []GraphInclude        `json:"includes,omitempty"`
+	Tasks    []core.Task           `json:"tasks"`
+	Edges    []dag.Edge            `json:"edges"`
+	// Success applies to runs of this file; the criteria of included files
+	// are ignored.
+	Success *SuccessCriteria `json:"success,omitempty"`
 }
 
+// GraphInclude imports the tasks and edges of another graph file.
+//
+// Included task names are prefixed with "<namespace>/", in the included edges
+// too, so the including file refers to an included task as "<namespace>/<name>".
+// Includes nest: a task two includes deep is named "<outer>/<inner>/<name>".
+// Inputs, outputs and commands are not rewritten; they stay relative to the
+// working directory.
+type GraphInclude struct {
+	// Path is the included graph file, relative to the directory of the
+	// including file unless absolute.
+	Path      string `json:"path"`
+	Namespace string `json:"namespace"`
+}
+
+// includeNamespacePattern restricts namespaces to a single name segment, so
+// "/" only ever separates namespaces.
+var includeNamespacePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
 // LoadGraphFromFile reads and parses the graph definition at path.
 //
-// Current supported format: JSON.
+// Graph files are JSON, or JSON Lines when path ends in graph.LinesExt (see
+// decodeGraphLines).
 //
 // The loader is deterministic:
 //   - Disallows unknown fields (to avoid silent divergence).
 //   - Does not consult environment variables.
+//   - Resolves includes depth-first in declaration order (see GraphInclude).
+//   - Substitutes parameters (see GraphParam) before the graph is hashed.
 func LoadGraphFromFile(path string) (*dag.TaskGraph, error) {
+	return LoadGraphWithParams(path, nil)
+}
+
+// LoadGraphWithParams is LoadGraphFromFile with values for the parameters the
+// graph files declare. A "{{name}}" reference in a task's command, inputs,
+// outputs or env values is replaced by the value of parameter name, or its
+// default. Referencing an undeclared parameter, leaving a parameter without
+// default unset, or setting a parameter no file declares is a
+// *graph.SemanticError.
+func LoadGraphWithParams(path string, params map[string]string) (*dag.TaskGraph, error) {
+	gf, _, err := readGraph(path, params)
+	if err != nil {
+		return nil, err
+	}
+	g, _, err := buildGraph(gf)
+	return g, err
+}
+
+// buildGraph validates the graph of gf and resolves its success criteria.
+func buildGraph(gf *graphFile) (*dag.TaskGraph, map[string]bool, error) {
+	g, err := dag.NewTaskGraph(gf.Tasks, gf.Edges)
+	if err != nil {
+		return nil, nil, err
+	}
+	tolerated, err := gf.Success.tolerated(g)
+	if err != nil {
+		return nil, nil, err
+	}
+	return g, tolerated, nil
+}
+
+// readGraph reads and decodes the graph file, resolving its includes and
+// parameters, without validating its structure. It also returns the content
+// of every file it read, keyed by path.
+func readGraph(path string, values map[string]string) (*graphFile, map[string][]byte, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("read graph: %w", err)
+		return nil, nil, fmt.Errorf("read graph: %w", err)
 	}
-	var gf graphFile
-	dec := json.NewDecoder(bytes.NewReader(b))
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&gf); err != nil {
-		return nil, fmt.Errorf("parse graph json: %w", err)
+	sources := map[string][]byte{path: b}
+	gf, err := decodeGraphFile(path, b)
+	if err != nil {
+		return nil, nil, err
 	}
-	// Ensure there is no trailing garbage (including a second JSON value).
-	var trailing any
-	if err := dec.Decode(&trailing); err != io.EOF {
-		if err == nil {
-			return nil, fmt.Errorf("parse graph json: trailing data")
-		}
-		return nil, fmt.Errorf("parse graph json: %w", err)
+	params := newGraphParams(values)
+	if err := params.apply(gf, filepath.Base(path)); err != nil {
+		return nil, nil, err
+	}
+	if err := resolveIncludes(gf, path, params, sources, nil); err != nil {
+		return nil, nil, err
+	}
+	if err := params.checkUnused(); err != nil {
+		return nil, nil, err
 	}
 	if len(gf.Tasks) == 0 {
-		return nil, fmt.Errorf("parse graph json: no tasks")
+		return nil, nil, fmt.Errorf("parse graph json: no tasks")
 	}
-	g, err := dag.NewTaskGraph(gf.Tasks, gf.Edges)
+	return gf, sources, nil
+}
+
+// resolveIncludes replaces the includes of gf, decoded from the file at path,
+// with the namespaced tasks and edges of the included files. stack holds the
+// files being resolved, to reject include cycles.
+func resolveIncludes(gf *graphFile, path string, params *graphParams, sources map[string][]byte, stack []string) error {
+	if len(gf.Includes) == 0 {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("resolve graph path: %w", err)
+	}
+	stack = append(stack, abs)
+	namespaces := make(map[string]bool, len(gf.Includes))
+	for _, inc := range gf.Includes {
+		if !includeNamespacePattern.MatchString(inc.Namespace) {
+			return fmt.Errorf("parse graph json: include %q: invalid namespace %q", inc.Path, inc.Namespace)
+		}
+		if namespaces[inc.Namespace] {
+			return fmt.Errorf("parse graph json: duplicate include namespace %q", inc.Namespace)
+		}
+		namespaces[inc.Namespace] = true
+		if strings.TrimSpace(inc.Path) == "" {
+			return fmt.Errorf("parse graph json: include %q: empty path", inc.Namespace)
+		}
+	}
+	for _, inc := range gf.Includes {
+		incPath := inc.Path
+		if !filepath.IsAbs(incPath) {
+			incPath = filepath.Join(filepath.Dir(abs), incPath)
+		}
+		incPath = filepath.Clean(incPath)
+		for _, p := range stack {
+			if p == incPath {
+				return fmt.Errorf("parse graph json: include cycle through %s", incPath)
+			}
+		}
+		b, err := os.ReadFile(incPath)
+		if err != nil {
+			return &IncludeError{Path: inc.Path, Err: fmt.Errorf("read graph: %w", err)}
+		}
+		sources[incPath] = b
+		sub, err := decodeGraphFile(incPath, b)
+		if err != nil {
+			return &IncludeError{Path: inc.Path, Err: err}
+		}
+		if err := params.apply(sub, filepath.Base(incPath)); err != nil {
+			return &IncludeError{Path: inc.Path, Err: err}
+		}
+		if err := resolveIncludes(sub, incPath, params, sources, stack); err != nil {
+			return &IncludeError{Path: inc.Path, Err: err}
+		}
+
+		prefix := inc.Namespace + "/"
+		for _, t := range sub.Tasks {
+			t.Name = prefix + t.Name
+			gf.Tasks = append(gf.Tasks, t)
+		}
+		for _, e := range sub.Edges {
+			gf.Edges = append(gf.Edges, dag.Edge{From: prefix + e.From, To: prefix + e.To, Type: e.Type})
+		}
+	}
+	gf.Includes = nil
+	return nil
+}
+
+// IncludeError is an error in a graph file included by the file being
+// loaded, or in reading it. Source locations in Err are in the included file.
+type IncludeError struct {
+	// Path is the included file as the including file names it.
+	Path string
+	Err  error
+}
+
+func (e *IncludeError) Error() string { return fmt.Sprintf("include %q: %v", e.Path, e.Err) }
+
+func (e *IncludeError) Unwrap() error { return e.Err }
+
+// decodeGraph decodes a single graph file, leaving its includes unresolved.
+// The fragments under its definitions are merged into the tasks extending
+// them first (see graph.ExpandTaskDefinitions). Errors locate the offending
+// value by line, column and JSON path.
+func decodeGraph(b []byte) (*graphFile, error) {
+	expanded, err := graph.ExpandTaskDefinitions(b)
+	if err != nil {
+		return nil, fmt.Errorf("parse graph json: %w", err)
+	}
+	var gf graphFile
+	if err := graph.DecodeStrict(expanded, &gf); err != nil {
+		return nil, fmt.Errorf("parse graph json: %w", graph.LocateExpanded(b, expanded, err))
+	}
+	return &gf, nil
+}
+
+// PluginFindingsError reports the findings of validator plugins, which reject
+// the graph before it runs.
+type PluginFindingsError struct {
+	Findings []pluginengine.Finding
+}
+
+func (e *PluginFindingsError) Error() string {
+	lines := make([]string, 0, len(e.Findings)+1)
+	lines = append(lines, fmt.Sprintf("plugin validation failed (%d findings)", len(e.Findings)))
+	for _, f := range e.Findings {
+		lines = append(lines, "  "+f.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateWithPlugins runs the validator plugins among the plugins
+// allowlisted by inv.Plugins against g, discovering them and verifying their
+// signatures exactly as a run would. It returns a *PluginFindingsError when any
+// validator reports a finding, and an error wrapping
+// pluginengine.ErrUnknownRunner when a node selects a runner no allowlisted
+// plugin provides; plugins that are not allowlisted are never loaded.
+func ValidateWithPlugins(ctx context.Context, g *dag.TaskGraph, inv CLIInvocation, log pluginengine.Logger) error {
+	var hooks *pluginengine.HookEngine
+	if len(inv.Plugins) > 0 {
+		cfg, _, err := config.LoadOptional(inv.WorkDir)
+		if err != nil {
+			return err
+		}
+		if hooks, err = loadPlugins(inv, cfg.PluginKeys, log); err != nil {
+			return err
+		}
+	}
+	if findings := hooks.Validate(ctx, g); len(findings) > 0 {
+		return &PluginFindingsError{Findings: findings}
 	}
-	return g, nil
+	return hooks.CheckRunners(g)
 }
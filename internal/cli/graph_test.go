@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestLoadGraphFromFile_Includes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "teams", "web.json"), `{
+		"includes": [{"path": "../shared/lint.json", "namespace": "lint"}],
+		"tasks": [{"name": "build", "inputs": [], "run": "true"}, {"name": "test", "inputs": [], "run": "true"}],
+		"edges": [{"From": "build", "To": "test"}, {"From": "lint/check", "To": "test"}]
+	}`)
+	writeFile(t, filepath.Join(dir, "shared", "lint.json"), `{"tasks": [{"name": "check", "inputs": [], "run": "true"}], "edges": []}`)
+	writeFile(t, filepath.Join(dir, "graph.json"), `{
+		"includes": [{"path": "teams/web.json", "namespace": "web"}],
+		"tasks": [{"name": "deploy", "inputs": [], "run": "true"}],
+		"edges": [{"From": "web/test", "To": "deploy"}]
+	}`)
+
+	g, err := LoadGraphFromFile(filepath.Join(dir, "graph.json"))
+	if err != nil {
+		t.Fatalf("LoadGraphFromFile: %v", err)
+	}
+	var names []string
+	for _, n := range g.Nodes() {
+		names = append(names, n.Task.Name)
+	}
+	if got, want := strings.Join(names, ","), "deploy,web/build,web/lint/check,web/test"; got != want {
+		t.Fatalf("nodes = %s, want %s", got, want)
+	}
+	var edges []string
+	for _, e := range g.Edges() {
+		edges = append(edges, e.From+">"+e.To)
+	}
+	if got, want := strings.Join(edges, ","), "web/build>web/test,web/lint/check>web/test,web/test>deploy"; got != want {
+		t.Fatalf("edges = %s, want %s", got, want)
+	}
+
+	// The same graph written out in one file hashes identically.
+	writeFile(t, filepath.Join(dir, "flat.json"), `{"tasks": [
+		{"name": "web/lint/check", "inputs": [], "run": "true"}, {"name": "web/test", "inputs": [], "run": "true"},
+		{"name": "deploy", "inputs": [], "run": "true"}, {"name": "web/build", "inputs": [], "run": "true"}
+	], "edges": [{"From": "web/test", "To": "deploy"}, {"From": "web/lint/check", "To": "web/test"}, {"From": "web/build", "To": "web/test"}]}`)
+	flat, err := LoadGraphFromFile(filepath.Join(dir, "flat.json"))
+	if err != nil {
+		t.Fatalf("LoadGraphFromFile(flat): %v", err)
+	}
+	if flat.Hash() != g.Hash() {
+		t.Fatalf("included graph hash %s differs from flat graph hash %s", g.Hash(), flat.Hash())
+	}
+}
+
+func TestLoadGraphFromFile_IncludesOnly(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `{"tasks": [{"name": "x", "inputs": [], "run": "true"}], "edges": []}`)
+	writeFile(t, filepath.Join(dir, "graph.json"), `{"includes": [{"path": "a.json", "namespace": "a"}, {"path": "a.json", "namespace": "b"}], "tasks": [], "edges": []}`)
+	g, err := LoadGraphFromFile(filepath.Join(dir, "graph.json"))
+	if err != nil {
+		t.Fatalf("LoadGraphFromFile: %v", err)
+	}
+	if len(g.Nodes()) != 2 {
+		t.Fatalf("nodes = %d, want 2", len(g.Nodes()))
+	}
+}
+
+func TestLoadGraphFromFile_IncludeErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "cycle",
+			files: map[string]string{
+				"graph.json": `{"includes": [{"path": "a.json", "namespace": "a"}], "tasks": [], "edges": []}`,
+				"a.json":     `{"includes": [{"path": "graph.json", "namespace": "root"}], "tasks": [], "edges": []}`,
+			},
+			want: "include cycle",
+		},
+		{
+			name: "duplicate namespace",
+			files: map[string]string{
+				"graph.json": `{"includes": [{"path": "a.json", "namespace": "a"}, {"path": "b.json", "namespace": "a"}], "tasks": [], "edges": []}`,
+			},
+			want: `duplicate include namespace "a"`,
+		},
+		{
+			name: "namespace with separator",
+			files: map[string]string{
+				"graph.json": `{"includes": [{"path": "a.json", "namespace": "a/b"}], "tasks": [], "edges": []}`,
+			},
+			want: `invalid namespace "a/b"`,
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"graph.json": `{"includes": [{"path": "missing.json", "namespace": "m"}], "tasks": [], "edges": []}`,
+			},
+			want: `include "missing.json": read graph`,
+		},
+		{
+			name: "unknown field in included file",
+			files: map[string]string{
+				"graph.json": `{"includes": [{"path": "a.json", "namespace": "a"}], "tasks": [], "edges": []}`,
+				"a.json":     `{"tasks": [], "edges": [], "extra": 1}`,
+			},
+			want: `include "a.json": parse graph json`,
+		},
+		{
+			name: "no tasks anywhere",
+			files: map[string]string{
+				"graph.json": `{"includes": [{"path": "a.json", "namespace": "a"}], "tasks": [], "edges": []}`,
+				"a.json":     `{"tasks": [], "edges": []}`,
+			},
+			want: "no tasks",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+			_, err := LoadGraphFromFile(filepath.Join(dir, "graph.json"))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want it to contain %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadGraphFromFile_OrderEdges(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "db.json"), `{
+		"tasks": [{"name": "migrate", "inputs": [], "run": "true"}, {"name": "seed", "inputs": [], "run": "true"}],
+		"edges": [{"From": "migrate", "To": "seed", "type": "order"}]
+	}`)
+	writeFile(t, filepath.Join(dir, "graph.json"), `{
+		"includes": [{"path": "db.json", "namespace": "db"}],
+		"tasks": [{"name": "build", "inputs": [], "run": "true"}, {"name": "test", "inputs": [], "run": "true"}],
+		"edges": [{"From": "build", "To": "test", "type": "data"}, {"From": "db/seed", "To": "test", "type": "order"}]
+	}`)
+
+	g, err := LoadGraphFromFile(filepath.Join(dir, "graph.json"))
+	if err != nil {
+		t.Fatalf("LoadGraphFromFile: %v", err)
+	}
+	var edges []string
+	for _, e := range g.Edges() {
+		edges = append(edges, e.From+">"+e.To+":"+string(e.Type))
+	}
+	if got, want := strings.Join(edges, ","), "build>test:,db/migrate>db/seed:order,db/seed>test:order"; got != want {
+		t.Fatalf("edges = %s, want %s", got, want)
+	}
+
+	writeFile(t, filepath.Join(dir, "bad.json"), `{
+		"tasks": [{"name": "a", "inputs": [], "run": "true"}, {"name": "b", "inputs": [], "run": "true"}],
+		"edges": [{"From": "a", "To": "b", "type": "soft"}]
+	}`)
+	if _, err := LoadGraphFromFile(filepath.Join(dir, "bad.json")); err == nil || !strings.Contains(err.Error(), `unknown type "soft"`) {
+		t.Fatalf("expected an unknown edge type error, got %v", err)
+	}
+}
+
+func TestLoadGraphFromFile_Definitions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "defs.json"), `{
+		"definitions": {
+			"go": {"env": {"GOFLAGS": "-mod=vendor", "CGO_ENABLED": "0"}, "inputs": ["go.mod"]},
+			"ci": {"env": {"CI": "1"}, "retries": 2}
+		},
+		"tasks": [
+			{"name": "build", "extends": ["go", "ci"], "run": "go build ./...", "env": {"CGO_ENABLED": "1"}},
+			{"name": "test", "extends": "go", "run": "go test ./...", "inputs": []}
+		],
+		"edges": [{"from": "build", "to": "test"}]
+	}`)
+	writeFile(t, filepath.Join(dir, "full.json"), `{
+		"tasks": [
+			{"name": "build", "run": "go build ./...", "inputs": ["go.mod"], "env": {"GOFLAGS": "-mod=vendor", "CGO_ENABLED": "1", "CI": "1"}, "retries": 2},
+			{"name": "test", "run": "go test ./...", "inputs": [], "env": {"GOFLAGS": "-mod=vendor", "CGO_ENABLED": "0"}}
+		],
+		"edges": [{"from": "build", "to": "test"}]
+	}`)
+	defs, err := LoadGraphFromFile(filepath.Join(dir, "defs.json"))
+	if err != nil {
+		t.Fatalf("LoadGraphFromFile(defs): %v", err)
+	}
+	full, err := LoadGraphFromFile(filepath.Join(dir, "full.json"))
+	if err != nil {
+		t.Fatalf("LoadGraphFromFile(full): %v", err)
+	}
+	if defs.Hash() != full.Hash() {
+		t.Fatalf("graph with definitions hashes to %s, written out to %s", defs.Hash(), full.Hash())
+	}
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"unknown definition", `{"definitions": {"go": {}}, "tasks": [{"name": "a", "extends": "og", "run": "true"}]}`,
+			`tasks[0].extends: unknown definition "og" (line 1, column 64)`},
+		{"name in a definition", `{"definitions": {"go": {"name": "x"}}, "tasks": [{"name": "a", "run": "true"}]}`,
+			"definitions.go.name: not allowed in a definition"},
+		{"unknown field", "{\"definitions\": {\"go\": {}},\n \"tasks\": [{\"name\": \"a\", \"extends\": \"go\", \"bogus\": 1}]}",
+			`unknown field "bogus" (at tasks[0].bogus, line 2, column 52)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "graph.json")
+			writeFile(t, path, tt.content)
+			_, err := LoadGraphFromFile(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -7,14 +7,26 @@ import (
 	"io"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"scriptweaver/exitcode"
+	"scriptweaver/internal/chaos"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/pluginengine"
 )
 
+// Exit codes reported in CLIResult.ExitCode. They are the canonical values
+// from package exitcode.
 const (
-	ExitSuccess           = 0
-	ExitGraphFailure      = 1
-	ExitInvalidInvocation = 2
-	ExitConfigError       = 3
-	ExitInternalError     = 4
+	ExitSuccess           = exitcode.Success
+	ExitValidationError   = exitcode.Validation
+	ExitInvalidInvocation = exitcode.Usage
+	ExitGraphFailure      = exitcode.Execution
+	ExitPluginError       = exitcode.Plugin
+	ExitConfigError       = exitcode.Workspace
+	ExitCancelled         = exitcode.Cancelled
+	ExitInternalError     = exitcode.Internal
+	ExitOffline           = exitcode.Offline
 )
 
 type ExecutionMode string
@@ -38,8 +50,10 @@ type TraceConfig struct {
 // NOTE: WorkDir is required and must be absolute; this prevents any dependency
 // on the process current working directory.
 type CLIInvocation struct {
-	GraphPath     string
-	WorkDir       string
+	GraphPath string
+	WorkDir   string
+	// CacheDir is the artifact cache; empty selects the workspace cache,
+	// <WorkDir>/.scriptweaver/cache.
 	CacheDir      string
 	OutputDir     string
 	ExecutionMode ExecutionMode
@@ -51,6 +65,81 @@ type CLIInvocation struct {
 	OriginalCache  string
 	OriginalOutput string
 	OriginalTrace  string
+	// Deduplicate shares results between byte-identical nodes instead of
+	// executing each of them.
+	Deduplicate bool
+	// Checkpoint records checkpoints in clean mode too, keeping the artifacts
+	// of successfully executed nodes in a run-scoped cache so that a failed
+	// clean run can be resumed. Incremental runs always record checkpoints.
+	Checkpoint bool
+	// VerifyDeterminism is the number of successfully finished tasks to
+	// re-execute twice after the run to detect nondeterminism. Zero disables it.
+	VerifyDeterminism int
+	// Workers, when positive, runs independent nodes in parallel on that many
+	// workers; zero runs the graph serially.
+	Workers int
+	// PluginDir overrides the plugins root (default:
+	// <WorkDir>/.scriptweaver/plugins).
+	PluginDir string
+	// Plugins is the allowlist of plugin IDs whose hooks run during execution.
+	// Discovered plugins that are not listed stay inactive.
+	Plugins []string
+	// AllowUnsignedPlugins loads plugins whose signature is missing or does
+	// not verify against the workspace's plugin_keys instead of skipping them.
+	AllowUnsignedPlugins bool
+	// PluginLog receives the plugin engine's warnings, such as a skipped
+	// unsigned plugin or a failing hook; nil logs them to stderr. A daemon
+	// serving the run logs them itself.
+	PluginLog pluginengine.Logger `json:"-"`
+	// Retries is the number of times a run that fails transiently (see
+	// state.Failure.Transient) is automatically retried. Zero disables it.
+	Retries int
+	// RetryBackoff is the delay before the first retry; it doubles for every
+	// further retry. Zero means DefaultRetryBackoff.
+	RetryBackoff time.Duration
+	// NamespaceOutputs places each node's declared outputs under
+	// <OutputDir>/<node>/, rewriting the inputs of its consumers to match, so
+	// that nodes cannot overwrite each other's outputs and each node's outputs
+	// can be removed on their own. OutputDir must lie inside WorkDir.
+	NamespaceOutputs bool
+	// ProgressPath, when set, is the file to which aggregate progress events
+	// (see dag.Progress) are written as JSON Lines while the graph runs.
+	ProgressPath string
+	// Skip selects nodes that are not run: each item is a selector (see
+	// Selector), usually a node ID. SkipPolicy decides what happens to their
+	// dependents; empty means dag.SkipDependents.
+	Skip       []string
+	SkipPolicy dag.SkipPolicy
+	// Node, when set, runs only that node and its dependencies; every other
+	// node is skipped. With Isolated the dependencies are restored from the
+	// cache instead of running, and a dependency without a cached result
+	// fails the run with ErrUpstreamNotCached. Isolated requires incremental
+	// mode.
+	Node     string
+	Isolated bool
+	// Simulate, when set, is a scenario file (see dag.Scenario) played by a
+	// dag.SimulatedRunner instead of running the graph's commands. A simulated
+	// run is recorded and traced like any other, but reads and writes no
+	// cache, checkpoints or outputs.
+	Simulate string
+	// Chaos, when set, injects faults into the run (see package chaos) to
+	// exercise failure classification, retries, checkpoints and resume.
+	// Each automatic retry draws with the next seed.
+	Chaos *chaos.Config
+	// Offline forbids network access: object store cache tiers are not used
+	// and tasks that need the network (fetch tasks and tasks labelled
+	// NetworkLabel) must be restored from the local cache. A run that would
+	// have to run one fails with ExitOffline before any task runs.
+	Offline bool
+	// Params are values for the parameters the graph files declare (see
+	// GraphParam), substituted before the graph is hashed.
+	Params map[string]string
+	// Schedule is the workspace schedule (see config.ScheduleConfig) that
+	// started the run; empty for runs started otherwise.
+	Schedule string
+
+	// retryOf is the failed run that this invocation automatically retries.
+	retryOf string
 }
 
 type InvocationError struct {
@@ -88,7 +177,7 @@ func ParseInvocation(args []string) (CLIInvocation, error) {
 
 	fs.StringVar(&workDir, "workdir", "", "Absolute working directory. Required.")
 	fs.StringVar(&graphPath, "graph", "", "Graph source path. Required.")
-	fs.StringVar(&cacheDir, "cache-dir", "", "Cache directory. Required.")
+	fs.StringVar(&cacheDir, "cache-dir", "", "Cache directory. Defaults to the workspace cache.")
 	fs.StringVar(&outputDir, "output-dir", "", "Output directory. Required.")
 	fs.StringVar(&tracePath, "trace", "", "Trace output path (optional).")
 	fs.StringVar(&mode, "mode", string(ExecutionModeIncremental), "Execution mode: clean|incremental|resume-only")
@@ -113,9 +202,6 @@ func ParseInvocation(args []string) (CLIInvocation, error) {
 	if graphPath == "" {
 		return CLIInvocation{}, invalidInvocationf("--graph is required")
 	}
-	if cacheDir == "" {
-		return CLIInvocation{}, invalidInvocationf("--cache-dir is required")
-	}
 	if outputDir == "" {
 		return CLIInvocation{}, invalidInvocationf("--output-dir is required")
 	}
@@ -129,9 +215,11 @@ func ParseInvocation(args []string) (CLIInvocation, error) {
 	if err != nil {
 		return CLIInvocation{}, err
 	}
-	resolvedCache, err := resolveUnderWorkDir(workDir, cacheDir)
-	if err != nil {
-		return CLIInvocation{}, err
+	var resolvedCache string
+	if cacheDir != "" {
+		if resolvedCache, err = resolveUnderWorkDir(workDir, cacheDir); err != nil {
+			return CLIInvocation{}, err
+		}
 	}
 	resolvedOutput, err := resolveUnderWorkDir(workDir, outputDir)
 	if err != nil {
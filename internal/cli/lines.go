@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+)
+
+// graphRecord is one line of a graph file written as JSON Lines: the params
+// or the success criteria of the file, or one include, task or edge.
+type graphRecord struct {
+	Params  map[string]GraphParam `json:"params"`
+	Success *SuccessCriteria      `json:"success"`
+	Include *GraphInclude         `json:"include"`
+	Task    *core.Task            `json:"task"`
+	Edge    *dag.Edge             `json:"edge"`
+}
+
+// recordLists names the list of the graph file each repeatable record adds
+// to.
+var recordLists = map[string]string{"include": "includes", "task": "tasks", "edge": "edges"}
+
+// isGraphLines reports whether the graph file at path is written as JSON
+// Lines, by its extension.
+func isGraphLines(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), graph.LinesExt)
+}
+
+// decodeGraphFile decodes b, the graph file at path, as JSON Lines or JSON
+// by the extension of path, leaving its includes unresolved.
+func decodeGraphFile(path string, b []byte) (*graphFile, error) {
+	if isGraphLines(path) {
+		return decodeGraphLines(b)
+	}
+	return decodeGraph(b)
+}
+
+// decodeGraphLines decodes a graph file written as JSON Lines, one record per
+// line, so that generators can stream large graphs:
+//
+//	{"params": {"target": {"default": "linux"}}}
+//	{"task": {"name": "build", "run": "make {{target}}"}}
+//	{"edge": {"from": "build", "to": "test"}}
+//
+// Every record holds exactly one of params, success, include, task or edge,
+// in any order; params and success at most once. Blank lines are skipped.
+// The records are assembled into the graph file the same graph written as
+// JSON decodes into, so it loads, validates and hashes the same. Errors
+// locate the record by line; within a record they give the column too.
+func decodeGraphLines(b []byte) (*graphFile, error) {
+	gf := &graphFile{}
+	err := forEachRecord(b, func(line []byte, at graph.Position) error {
+		var rec graphRecord
+		if err := graph.DecodeRecord(line, at, &rec); err != nil {
+			return err
+		}
+		n := 0
+		for _, set := range []bool{rec.Params != nil, rec.Success != nil, rec.Include != nil, rec.Task != nil, rec.Edge != nil} {
+			if set {
+				n++
+			}
+		}
+		if n != 1 {
+			return &graph.ParseError{Msg: "a record must hold exactly one of params, success, include, task or edge", Pos: at}
+		}
+		switch {
+		case rec.Params != nil:
+			if gf.Params != nil {
+				return &graph.ParseError{Msg: "params are set by an earlier record", Path: "params", Pos: at}
+			}
+			gf.Params = rec.Params
+		case rec.Success != nil:
+			if gf.Success != nil {
+				return &graph.ParseError{Msg: "success is set by an earlier record", Path: "success", Pos: at}
+			}
+			gf.Success = rec.Success
+		case rec.Include != nil:
+			gf.Includes = append(gf.Includes, *rec.Include)
+		case rec.Task != nil:
+			gf.Tasks = append(gf.Tasks, *rec.Task)
+		default:
+			gf.Edges = append(gf.Edges, *rec.Edge)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("parse graph json: %w", err)
+	}
+	return gf, nil
+}
+
+// forEachRecord calls fn with every non-blank line of b, a JSON Lines file,
+// and where it starts, stopping at the first error.
+func forEachRecord(b []byte, fn func(line []byte, at graph.Position) error) error {
+	var offset int64
+	for i, line := range bytes.SplitAfter(b, []byte("\n")) {
+		at := graph.Position{Offset: offset, Line: i + 1, Column: 1}
+		offset += int64(len(line))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if err := fn(line, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deprecatedFields returns a warning for every use of a deprecated field in
+// data, the graph file at path. Fields of JSON Lines records are named as in
+// the same graph written as JSON.
+func deprecatedFields(path string, data []byte) []graph.DeprecationWarning {
+	if !isGraphLines(path) {
+		return graph.DeprecatedFields(data)
+	}
+	var out []graph.DeprecationWarning
+	counts := make(map[string]int)
+	_ = forEachRecord(data, func(line []byte, at graph.Position) error {
+		var rec map[string]json.RawMessage
+		if json.Unmarshal(line, &rec) != nil || len(rec) != 1 {
+			return nil
+		}
+		for key := range rec {
+			path := key
+			if list, ok := recordLists[key]; ok {
+				path = fmt.Sprintf("%s[%d]", list, counts[key])
+				counts[key]++
+			}
+			out = append(out, graph.DeprecatedRecordFields(line, at, key, path)...)
+		}
+		return nil
+	})
+	return out
+}
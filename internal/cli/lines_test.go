@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadGraphFromFile_JSONLines(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "lint.jsonl"), `{"task": {"name": "check", "run": "true"}}`+"\n")
+	writeFile(t, filepath.Join(dir, "graph.jsonl"), strings.Join([]string{
+		`{"params": {"target": {"default": "linux"}}}`,
+		`{"task": {"name": "build", "run": "make {{target}}", "outputs": ["bin"]}}`,
+		``,
+		`{"edge": {"from": "build", "to": "test"}}`,
+		`{"task": {"name": "test", "run": "true"}}`,
+		`{"include": {"path": "lint.jsonl", "namespace": "lint"}}`,
+		`{"edge": {"from": "lint/check", "to": "test"}}`,
+	}, "\n"))
+	writeFile(t, filepath.Join(dir, "graph.json"), `{
+		"params": {"target": {"default": "linux"}},
+		"tasks": [{"name": "build", "run": "make {{target}}", "outputs": ["bin"]}, {"name": "test", "run": "true"}, {"name": "lint/check", "run": "true"}],
+		"edges": [{"from": "build", "to": "test"}, {"from": "lint/check", "to": "test"}]
+	}`)
+
+	lines, err := LoadGraphWithParams(filepath.Join(dir, "graph.jsonl"), map[string]string{"target": "darwin"})
+	if err != nil {
+		t.Fatalf("LoadGraphWithParams(jsonl): %v", err)
+	}
+	whole, err := LoadGraphWithParams(filepath.Join(dir, "graph.json"), map[string]string{"target": "darwin"})
+	if err != nil {
+		t.Fatalf("LoadGraphWithParams(json): %v", err)
+	}
+	if lines.Hash() != whole.Hash() {
+		t.Fatalf("JSON Lines graph hash %s differs from JSON graph hash %s", lines.Hash(), whole.Hash())
+	}
+	if n, ok := lines.Node("build"); !ok || n.Task.Run != "make darwin" {
+		t.Fatalf("build = %+v", n)
+	}
+}
+
+func TestLoadGraphFromFile_JSONLinesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown field", `{"task": {"name": "a", "run": "true"}}` + "\n" + `{"task": {"name": "b", "bogus": 1}}`,
+			`unknown field "bogus" (at task.bogus, line 2, column 24)`},
+		{"two items", `{"task": {"name": "a"}, "edge": {"from": "a", "to": "b"}}`,
+			"a record must hold exactly one of params, success, include, task or edge (line 1, column 1)"},
+		{"params twice", `{"params": {}}` + "\n" + `{"params": {}}`,
+			"params are set by an earlier record (at params, line 2, column 1)"},
+		{"syntax error", `{"task": {"name": "a"}}` + "\n" + `{"task": {"name": }}`,
+			"line 2, column 19"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "graph.jsonl")
+			writeFile(t, path, tt.content)
+			_, err := LoadGraphFromFile(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
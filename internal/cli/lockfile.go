@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+// LockFileName is the lockfile, at the root of the workdir, that records the
+// content every fetch task last fetched. It is meant to be committed with the
+// graph: a fetch the graph does not pin is pinned by its lockfile entry, so
+// that later runs and other machines get the same artifact.
+const LockFileName = "scriptweaver.lock"
+
+// lockfileVersion is the version of the lockfile format.
+const lockfileVersion = 1
+
+// Lockfile pins the artifacts of fetch tasks, by task name.
+type Lockfile struct {
+	Version int                    `json:"version"`
+	Fetch   map[string]LockedFetch `json:"fetch"`
+}
+
+// LockedFetch is the artifact a fetch task fetched.
+type LockedFetch struct {
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// LoadLockfile reads the lockfile of workDir. A missing lockfile is empty.
+func LoadLockfile(workDir string) (*Lockfile, error) {
+	lf := &Lockfile{Version: lockfileVersion, Fetch: map[string]LockedFetch{}}
+	data, err := os.ReadFile(filepath.Join(workDir, LockFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return lf, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", LockFileName, err)
+	}
+	if err := json.Unmarshal(data, lf); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", LockFileName, err)
+	}
+	if lf.Version != lockfileVersion {
+		return nil, fmt.Errorf("parse %s: unsupported version %d", LockFileName, lf.Version)
+	}
+	if lf.Fetch == nil {
+		lf.Fetch = map[string]LockedFetch{}
+	}
+	return lf, nil
+}
+
+// SaveLockfile writes lf as the lockfile of workDir.
+func SaveLockfile(workDir string, lf *Lockfile) error {
+	data, err := json.MarshalIndent(lf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(workDir, LockFileName), append(data, '\n'), 0o644)
+}
+
+// pinnedSHA256 returns the digest lf pins for fetch task name fetching f, or
+// "" when the lockfile has no entry for that URL.
+func (lf *Lockfile) pinnedSHA256(name string, f *core.Fetch) string {
+	if e, ok := lf.Fetch[name]; ok && e.URL == f.URL {
+		return e.SHA256
+	}
+	return ""
+}
+
+// pinFetches returns g with every fetch task the graph leaves unpinned pinned
+// by its lockfile entry, or g itself when there is none.
+func pinFetches(g *dag.TaskGraph, lf *Lockfile) (*dag.TaskGraph, error) {
+	nodes := g.Nodes()
+	tasks := make([]core.Task, 0, len(nodes))
+	changed := false
+	for _, n := range nodes {
+		t := n.Task
+		if t.Fetch != nil && t.Fetch.SHA256 == "" {
+			if sum := lf.pinnedSHA256(t.Name, t.Fetch); sum != "" {
+				t.Fetch = &core.Fetch{URL: t.Fetch.URL, SHA256: sum}
+				changed = true
+			}
+		}
+		tasks = append(tasks, t)
+	}
+	if !changed {
+		return g, nil
+	}
+	return dag.NewTaskGraph(tasks, g.Edges())
+}
+
+// lockFetches records in lf the artifact of every fetch task of g listed in
+// outputs, and reports whether lf changed.
+func lockFetches(lf *Lockfile, g *dag.TaskGraph, outputs []NodeOutputs) bool {
+	changed := false
+	for _, no := range outputs {
+		n, ok := g.Node(no.Node)
+		if !ok || n.Task.Fetch == nil || len(no.Files) != 1 {
+			continue
+		}
+		e := LockedFetch{URL: n.Task.Fetch.URL, SHA256: no.Files[0].SHA256, Size: no.Files[0].Size}
+		if lf.Fetch[no.Node] != e {
+			lf.Fetch[no.Node] = e
+			changed = true
+		}
+	}
+	return changed
+}
+
+// FetchPinningIssues reports the fetch tasks of g whose content is not
+// pinned, ordered by task name: tasks pinned neither by the graph nor by lf,
+// and tasks whose graph pin disagrees with lf. A run records the fetched
+// content in the lockfile, which resolves both.
+func FetchPinningIssues(g *dag.TaskGraph, lf *Lockfile) []string {
+	var out []string
+	for _, n := range g.Nodes() {
+		t := n.Task
+		if t.Fetch == nil {
+			continue
+		}
+		locked := lf.pinnedSHA256(t.Name, t.Fetch)
+		switch {
+		case t.Fetch.SHA256 == "" && locked == "":
+			out = append(out, fmt.Sprintf("task %q fetches %s without a sha256 pin and has no %s entry", t.Name, t.Fetch.URL, LockFileName))
+		case t.Fetch.SHA256 != "" && locked != "" && t.Fetch.SHA256 != locked:
+			out = append(out, fmt.Sprintf("task %q pins sha256 %s but %s records %s", t.Name, t.Fetch.SHA256, LockFileName, locked))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestExecute_FetchRecordsLockfileAndEnforcesIt(t *testing.T) {
+	body := "dependency v1"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "dep", Fetch: &core.Fetch{URL: srv.URL + "/dep.tgz"}, Outputs: []string{"vendor/dep.tgz"}},
+		{Name: "use", Inputs: []string{"vendor/dep.tgz"}, Run: "cat vendor/dep.tgz > used.txt", Outputs: []string{"used.txt"}},
+	}, []dag.Edge{{From: "dep", To: "use"}})
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("first run: exit %d, %v", res.ExitCode, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(workDir, "used.txt")); string(got) != body {
+		t.Fatalf("used.txt = %q", got)
+	}
+	lf, err := LoadLockfile(workDir)
+	if err != nil {
+		t.Fatalf("LoadLockfile: %v", err)
+	}
+	sum := sha256.Sum256([]byte(body))
+	want := LockedFetch{URL: srv.URL + "/dep.tgz", SHA256: hex.EncodeToString(sum[:]), Size: int64(len(body))}
+	if lf.Fetch["dep"] != want {
+		t.Fatalf("lockfile entry = %+v, want %+v", lf.Fetch["dep"], want)
+	}
+
+	// Upstream changes: the lockfile pin rejects the new content.
+	body = "dependency v2"
+	res, err = Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if res.ExitCode != ExitGraphFailure {
+		t.Fatalf("second run: exit %d, want %d", res.ExitCode, ExitGraphFailure)
+	}
+	if lf, _ := LoadLockfile(workDir); lf.Fetch["dep"] != want {
+		t.Fatalf("lockfile changed by a failed fetch: %+v", lf.Fetch["dep"])
+	}
+}
+
+func TestFetchPinningIssues(t *testing.T) {
+	pin := strings.Repeat("a", 64)
+	g, err := dag.NewTaskGraph([]core.Task{
+		{Name: "pinned", Fetch: &core.Fetch{URL: "https://example.com/a", SHA256: pin}, Outputs: []string{"a"}},
+		{Name: "locked", Fetch: &core.Fetch{URL: "https://example.com/b"}, Outputs: []string{"b"}},
+		{Name: "moved", Fetch: &core.Fetch{URL: "https://example.com/c2"}, Outputs: []string{"c"}},
+		{Name: "stale", Fetch: &core.Fetch{URL: "https://example.com/d", SHA256: pin}, Outputs: []string{"d"}},
+		{Name: "build", Run: "true"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	lf := &Lockfile{Version: 1, Fetch: map[string]LockedFetch{
+		"locked": {URL: "https://example.com/b", SHA256: pin},
+		"moved":  {URL: "https://example.com/c1", SHA256: pin},
+		"stale":  {URL: "https://example.com/d", SHA256: strings.Repeat("b", 64)},
+	}}
+
+	issues := FetchPinningIssues(g, lf)
+	if len(issues) != 2 || !strings.Contains(issues[0], `"moved"`) || !strings.Contains(issues[1], `"stale"`) {
+		t.Fatalf("issues = %q", issues)
+	}
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+// NodeOutputDirEnv is set, when outputs are namespaced, to the directory a
+// node must write its declared outputs under, relative to the workdir.
+const NodeOutputDirEnv = "SW_NODE_OUTPUT_DIR"
+
+// nodeOutputDir is the namespaced output directory of node, relative to the
+// workdir, in slash form.
+func nodeOutputDir(outputRel, node string) string {
+	return path.Join(outputRel, node)
+}
+
+// outputDirRel returns inv.OutputDir relative to inv.WorkDir, in slash form.
+// Namespaced outputs are declared outputs, so they must lie in the workspace.
+func outputDirRel(inv CLIInvocation) (string, error) {
+	rel, err := filepath.Rel(inv.WorkDir, inv.OutputDir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", invalidInvocationf("namespaced outputs require an output dir inside the workdir, got %s", inv.OutputDir)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// namespaceOutputs returns g with each node's declared outputs moved under
+// <outputRel>/<node>/. Inputs that name an output of one of the node's
+// dependencies, or a path inside such an output, are rewritten the same way,
+// so consumers keep reading what their producers wrote. Each node is told its
+// directory through NodeOutputDirEnv.
+//
+// Producers may then declare the same output path without colliding, but an
+// input that names an output declared by two dependencies is ambiguous and
+// rejected.
+func namespaceOutputs(g *dag.TaskGraph, outputRel string) (*dag.TaskGraph, error) {
+	upstream := upstreamByNode(g)
+	nodes := g.Nodes()
+	tasks := make([]core.Task, 0, len(nodes))
+	for _, n := range nodes {
+		name := n.Task.Name
+		if name == "" || name == "." || name == ".." || name == reportsDirName || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("task %q: name cannot be used as an output directory", name)
+		}
+		task := n.Task
+		dir := nodeOutputDir(outputRel, name)
+
+		task.Env = make(map[string]string, len(n.Task.Env)+1)
+		for k, v := range n.Task.Env {
+			task.Env[k] = v
+		}
+		task.Env[NodeOutputDirEnv] = dir
+
+		task.Outputs = make([]string, len(n.Task.Outputs))
+		for i, o := range n.Task.Outputs {
+			task.Outputs[i] = path.Join(dir, o)
+		}
+
+		task.Inputs = make([]string, len(n.Task.Inputs))
+		for i, in := range n.Task.Inputs {
+			rewritten, err := namespaceInput(g, upstream[name], outputRel, in)
+			if err != nil {
+				return nil, fmt.Errorf("task %q: %w", name, err)
+			}
+			task.Inputs[i] = rewritten
+		}
+		tasks = append(tasks, task)
+	}
+	return dag.NewTaskGraph(tasks, g.Edges())
+}
+
+// namespaceInput rewrites input when it names an output of one of deps.
+func namespaceInput(g *dag.TaskGraph, deps []string, outputRel, input string) (string, error) {
+	clean := path.Clean(input)
+	producer, rewritten := "", input
+	for _, dep := range deps {
+		n, _ := g.Node(dep)
+		for _, o := range n.Task.Outputs {
+			o = path.Clean(o)
+			if clean != o && !strings.HasPrefix(clean, o+"/") {
+				continue
+			}
+			if producer != "" && producer != dep {
+				return "", fmt.Errorf("input %q is an output of both %q and %q", input, producer, dep)
+			}
+			producer = dep
+			rewritten = path.Join(nodeOutputDir(outputRel, dep), clean)
+		}
+	}
+	return rewritten, nil
+}
+
+// createNodeOutputDirs creates the namespaced output directory of every node
+// of g, so that commands can write into NodeOutputDirEnv directly.
+func createNodeOutputDirs(workDir, outputRel string, g *dag.TaskGraph) error {
+	for _, n := range g.Nodes() {
+		if err := os.MkdirAll(filepath.Join(workDir, filepath.FromSlash(nodeOutputDir(outputRel, n.Task.Name))), 0o755); err != nil {
+			return fmt.Errorf("create output dir of %q: %w", n.Task.Name, err)
+		}
+	}
+	return nil
+}
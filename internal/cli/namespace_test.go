@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestExecute_NamespaceOutputs_SeparatesNodesAndRewritesConsumers(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	// a and b both declare out.txt; c consumes a's.
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: `printf a > "$SW_NODE_OUTPUT_DIR/out.txt"`, Outputs: []string{"out.txt"}},
+		{Name: "b", Run: `printf b > "$SW_NODE_OUTPUT_DIR/out.txt"`, Outputs: []string{"out.txt"}},
+		{Name: "c", Inputs: []string{"out.txt"}, Run: `cat .sw/output/a/out.txt > "$SW_NODE_OUTPUT_DIR/copy.txt"`, Outputs: []string{"copy.txt"}},
+	}, []dag.Edge{{From: "a", To: "c"}})
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, CacheDir: filepath.Join(workDir, "cache"), OutputDir: filepath.Join(workDir, ".sw", "output"), ExecutionMode: ExecutionModeIncremental, NamespaceOutputs: true}
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitSuccess {
+		t.Fatalf("expected exit %d got %d", ExitSuccess, res.ExitCode)
+	}
+	for file, want := range map[string]string{"a/out.txt": "a", "b/out.txt": "b", "c/copy.txt": "a"} {
+		got, err := os.ReadFile(filepath.Join(inv.OutputDir, file))
+		if err != nil || string(got) != want {
+			t.Fatalf("%s = %q (%v), want %q", file, got, err, want)
+		}
+	}
+	var paths []string
+	for _, no := range res.Outputs {
+		for _, f := range no.Files {
+			paths = append(paths, f.Path)
+		}
+	}
+	sort.Strings(paths)
+	if want := []string{".sw/output/a/out.txt", ".sw/output/b/out.txt", ".sw/output/c/copy.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("outputs = %v, want %v", paths, want)
+	}
+
+	// A second run restores every namespaced output from the cache.
+	res, err = Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("second run: exit %d err %v", res.ExitCode, err)
+	}
+	for name, st := range res.GraphResult.FinalState {
+		if st != dag.TaskCached {
+			t.Fatalf("node %s: state %s, want cached", name, st)
+		}
+	}
+	if got, err := os.ReadFile(filepath.Join(inv.OutputDir, "c", "copy.txt")); err != nil || string(got) != "a" {
+		t.Fatalf("restored c/copy.txt = %q (%v)", got, err)
+	}
+}
+
+func TestNamespaceOutputs_RewritesOnlyDependencyOutputs(t *testing.T) {
+	g, err := dag.NewTaskGraph([]core.Task{
+		{Name: "gen", Run: "true", Outputs: []string{"build"}},
+		{Name: "other", Run: "true", Outputs: []string{"other.txt"}},
+		{Name: "use", Inputs: []string{"build/*.o", "./build", "other.txt", "src/main.c"}, Run: "true", Env: map[string]string{"K": "v"}},
+	}, []dag.Edge{{From: "gen", To: "use"}})
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	ng, err := namespaceOutputs(g, "out")
+	if err != nil {
+		t.Fatalf("namespaceOutputs: %v", err)
+	}
+	use, _ := ng.Node("use")
+	if want := []string{"out/gen/build/*.o", "out/gen/build", "other.txt", "src/main.c"}; !reflect.DeepEqual(use.Task.Inputs, want) {
+		t.Fatalf("inputs = %v, want %v", use.Task.Inputs, want)
+	}
+	if want := map[string]string{"K": "v", NodeOutputDirEnv: "out/use"}; !reflect.DeepEqual(use.Task.Env, want) {
+		t.Fatalf("env = %v, want %v", use.Task.Env, want)
+	}
+	gen, _ := ng.Node("gen")
+	if want := []string{"out/gen/build"}; !reflect.DeepEqual(gen.Task.Outputs, want) {
+		t.Fatalf("outputs = %v, want %v", gen.Task.Outputs, want)
+	}
+	if orig, _ := g.Node("use"); orig.Task.Env[NodeOutputDirEnv] != "" {
+		t.Fatalf("the original graph was modified")
+	}
+}
+
+func TestNamespaceOutputs_RejectsAmbiguousInput(t *testing.T) {
+	g, err := dag.NewTaskGraph([]core.Task{
+		{Name: "a", Run: "true", Outputs: []string{"out.txt"}},
+		{Name: "b", Run: "true", Outputs: []string{"out.txt"}},
+		{Name: "c", Inputs: []string{"out.txt"}, Run: "true"},
+	}, []dag.Edge{{From: "a", To: "c"}, {From: "b", To: "c"}})
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	if _, err := namespaceOutputs(g, "out"); err == nil || !strings.Contains(err.Error(), "output of both") {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+}
+
+func TestExecute_NamespaceOutputs_RequiresOutputDirInWorkdir(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Run: "true"}}, nil)
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, CacheDir: filepath.Join(workDir, "cache"), OutputDir: t.TempDir(), ExecutionMode: ExecutionModeClean, NamespaceOutputs: true}
+
+	res, err := Execute(context.Background(), inv)
+	if err == nil || res.ExitCode != ExitInvalidInvocation {
+		t.Fatalf("expected exit %d with error, got %d (%v)", ExitInvalidInvocation, res.ExitCode, err)
+	}
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"scriptweaver/internal/dag"
+)
+
+// networkTools are commands, as word sequences, that fetch from the network
+// and so fail in tasks declared with network: false.
+var networkTools = [][]string{
+	{"curl"}, {"wget"}, {"scp"}, {"rsync"},
+	{"git", "clone"}, {"git", "fetch"}, {"git", "pull"}, {"git", "submodule"},
+	{"npm", "install"}, {"npm", "ci"}, {"yarn", "install"}, {"pnpm", "install"},
+	{"pip", "install"}, {"pip3", "install"},
+	{"go", "get"}, {"go", "mod", "download"},
+	{"cargo", "fetch"}, {"bundle", "install"}, {"gem", "install"},
+	{"apt-get"}, {"apt"}, {"apk", "add"}, {"yum"}, {"dnf"},
+	{"docker", "pull"},
+}
+
+// NetworkWarnings warns about tasks declared with network: false whose
+// command runs a tool known to need the network, ordered by task name. They
+// are warnings, not errors: the tool may be used offline.
+func NetworkWarnings(g *dag.TaskGraph) []string {
+	var out []string
+	for _, n := range g.Nodes() {
+		if n.Task.NetworkAllowed() {
+			continue
+		}
+		if tool := networkTool(n.Task.Run); tool != "" {
+			out = append(out, fmt.Sprintf("task %q runs %s, which usually needs the network, but declares network: false", n.Task.Name, tool))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// networkTool returns the first known network tool that command runs, or "".
+func networkTool(command string) string {
+	words := strings.FieldsFunc(command, func(r rune) bool {
+		return strings.ContainsRune(" \t\n;|&()`'\"$", r)
+	})
+	for i := range words {
+		words[i] = path.Base(words[i])
+	}
+	for i := range words {
+		for _, tool := range networkTools {
+			if i+len(tool) > len(words) {
+				continue
+			}
+			match := true
+			for j, w := range tool {
+				if words[i+j] != w {
+					match = false
+					break
+				}
+			}
+			if match {
+				return strings.Join(tool, " ")
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestNetworkWarnings_FlagsNetworkToolsInNetworklessTasks(t *testing.T) {
+	off, on := false, true
+	g, err := dag.NewTaskGraph([]core.Task{
+		{Name: "fetch", Run: "/usr/bin/curl -fsSL https://example.com -o x", Network: &off},
+		{Name: "deps", Run: "cd web && npm ci", Network: &off},
+		{Name: "build", Run: "go build ./... && git rev-parse HEAD", Network: &off},
+		{Name: "online", Run: "curl https://example.com", Network: &on},
+		{Name: "default", Run: "go mod download"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	want := []string{
+		`task "deps" runs npm ci, which usually needs the network, but declares network: false`,
+		`task "fetch" runs curl, which usually needs the network, but declares network: false`,
+	}
+	if got := NetworkWarnings(g); !reflect.DeepEqual(got, want) {
+		t.Fatalf("warnings = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/incremental"
+)
+
+// ErrUpstreamNotCached is returned when a node run with --isolated has a
+// dependency whose result cannot be restored from the cache.
+var ErrUpstreamNotCached = errors.New("upstream result not cached")
+
+// nodeScope returns the dependencies of node in g, transitively, and the
+// nodes that are neither node nor one of them, which a run of node skips.
+func nodeScope(g *dag.TaskGraph, node string) (upstream, others []string, err error) {
+	if _, ok := g.Node(node); !ok {
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownNode, node)
+	}
+	deps := closure(map[string]bool{node: true}, newQueryGraph(g).deps)
+	for _, name := range g.TopologicalOrder() {
+		switch {
+		case deps[name]:
+			upstream = append(upstream, name)
+		case name != node:
+			others = append(others, name)
+		}
+	}
+	return upstream, others, nil
+}
+
+// isolatedPlan is the plan of running node on its own: its dependencies are
+// restored from the cache instead of running, and every other node is
+// skipped.
+func isolatedPlan(g *dag.TaskGraph, node string, upstream, others []string) *incremental.IncrementalPlan {
+	plan := &incremental.IncrementalPlan{Order: g.TopologicalOrder(), Decisions: make(map[string]incremental.NodeExecutionDecision)}
+	for _, name := range upstream {
+		plan.Decisions[name] = incremental.DecisionReuseCache
+	}
+	for _, name := range others {
+		plan.Decisions[name] = incremental.DecisionSkip
+	}
+	plan.Decisions[node] = incremental.DecisionExecute
+	return plan
+}
+
+// checkIsolatedUpstream reports the dependencies of an isolated node that
+// could not be restored, with the reason each restore failed.
+func checkIsolatedUpstream(gr *dag.GraphResult, node string) error {
+	var missing []string
+	for _, name := range gr.FailedNodes() {
+		if name == node {
+			continue
+		}
+		reason, _ := gr.StderrOf(name)
+		missing = append(missing, fmt.Sprintf("%s (%s)", name, strings.TrimSpace(string(reason))))
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s cannot run in isolation, restoring %s failed; run the graph without --isolated first", ErrUpstreamNotCached, node, strings.Join(missing, ", "))
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestExecute_NodeIsolated_RestoresUpstreamFromCache(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: "printf a > a.txt", Outputs: []string{"a.txt"}},
+		{Name: "b", Inputs: []string{"a.txt"}, Run: "cat a.txt > b.txt; echo b >> runs.log", Outputs: []string{"b.txt"}},
+		{Name: "c", Inputs: []string{"b.txt"}, Run: "cat b.txt > c.txt", Outputs: []string{"c.txt"}},
+	}, []dag.Edge{{From: "a", To: "b"}, {From: "b", To: "c"}})
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeIncremental, Node: "b", Isolated: true}
+	runs := func() int {
+		data, _ := os.ReadFile(filepath.Join(workDir, "runs.log"))
+		return strings.Count(string(data), "b\n")
+	}
+
+	// Nothing is cached yet: a cannot be restored.
+	res, err := Execute(context.Background(), inv)
+	if !errors.Is(err, ErrUpstreamNotCached) || res.ExitCode != ExitConfigError {
+		t.Fatalf("uncached: exit %d err %v", res.ExitCode, err)
+	}
+
+	full := inv
+	full.Node, full.Isolated = "", false
+	if res, err := Execute(context.Background(), full); err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("full run: exit %d err %v", res.ExitCode, err)
+	}
+	if err := os.Remove(filepath.Join(workDir, "a.txt")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	res, err = Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("isolated: exit %d err %v", res.ExitCode, err)
+	}
+	want := dag.ExecutionState{"a": dag.TaskCompleted, "b": dag.TaskCompleted, "c": dag.TaskSkipped}
+	if !reflect.DeepEqual(res.GraphResult.FinalState, want) {
+		t.Fatalf("final state = %v, want %v", res.GraphResult.FinalState, want)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "a.txt")); err != nil {
+		t.Fatalf("a.txt not restored: %v", err)
+	}
+
+	// b is cached by now, yet running it in isolation executes it again.
+	est, err := EstimateRun(inv)
+	if err != nil || est.Execute != 1 || est.Reuse != 1 {
+		t.Fatalf("estimate = %+v, err %v", est, err)
+	}
+	if res, err := Execute(context.Background(), inv); err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("isolated again: exit %d err %v", res.ExitCode, err)
+	}
+	if got := runs(); got != 3 {
+		t.Fatalf("b ran %d times, want 3", got)
+	}
+}
+
+func TestExecute_Node_RunsOnlyDependencies(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: "true"},
+		{Name: "b", Run: "true"},
+		{Name: "c", Run: "true"},
+	}, []dag.Edge{{From: "a", To: "b"}, {From: "b", To: "c"}})
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean, Node: "b"}
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit %d err %v", res.ExitCode, err)
+	}
+	if got := res.GraphResult.ExecutionOrder; !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("execution order = %v", got)
+	}
+
+	inv.Node = "z"
+	if res, err := Execute(context.Background(), inv); !errors.Is(err, ErrUnknownNode) || res.ExitCode != ExitInvalidInvocation {
+		t.Fatalf("unknown node: exit %d err %v", res.ExitCode, err)
+	}
+}
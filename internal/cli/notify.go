@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/notify"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/recovery/state"
+)
+
+// stageNotify posts the outcome of the run to the workspace's chat webhook,
+// per its notify-on policy, once the run has finished. Notifying is
+// best-effort: a failure is reported in CLIResult.NotifyError and never
+// changes the outcome of the run. Simulated runs are not notified.
+func stageNotify(ctx context.Context, rc *RunContext, next Next) error {
+	err := next(ctx)
+	if n := rc.Config.Notify; n != nil && rc.RunID != "" && rc.Invocation.Simulate == "" {
+		if nerr := notifyRun(context.WithoutCancel(ctx), rc, *n); nerr != nil {
+			rc.Result.NotifyError = nerr.Error()
+		}
+	}
+	return err
+}
+
+func notifyRun(ctx context.Context, rc *RunContext, n config.NotifyConfig) error {
+	graph := newRunParams(rc.Invocation).Graph
+	o := notify.Outcome{
+		RunID:          rc.RunID,
+		Graph:          graph,
+		ExitCode:       rc.Result.ExitCode,
+		Duration:       rc.Result.Duration,
+		PreviousFailed: previousRunFailed(rc.Store, rc.RunID, graph),
+	}
+	if gr := rc.Result.GraphResult; gr != nil {
+		for name, s := range gr.FinalState {
+			if s == dag.TaskFailed {
+				o.FailedNodes = append(o.FailedNodes, name)
+			}
+		}
+		sort.Strings(o.FailedNodes)
+	}
+	if !notify.ShouldNotify(n.On, o) {
+		return nil
+	}
+	webhook, err := webhookURL(rc.Invocation.WorkDir, n)
+	if err != nil {
+		return err
+	}
+	text, link := notify.Message(n, o)
+	payload, err := notify.Payload(n.Format, text, link)
+	if err != nil {
+		return err
+	}
+	return notify.Send(ctx, http.DefaultClient, webhook, payload)
+}
+
+// webhookURL returns the configured webhook, reading it from its file when
+// the configuration names one.
+func webhookURL(workDir string, n config.NotifyConfig) (string, error) {
+	if n.WebhookURLFile == "" {
+		return n.WebhookURL, nil
+	}
+	path := n.WebhookURLFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("notify: read webhook_url_file: %w", err)
+	}
+	u := strings.TrimSpace(string(b))
+	if err := config.ValidateWebhookURL(u); err != nil {
+		return "", fmt.Errorf("notify: webhook_url_file %s: %v", n.WebhookURLFile, err)
+	}
+	return u, nil
+}
+
+// previousRunFailed reports whether the latest run of graph before runID
+// recorded a failure.
+func previousRunFailed(st *state.Store, runID, graph string) bool {
+	if st == nil {
+		return false
+	}
+	ids, err := st.ListRunIDs()
+	if err != nil {
+		return false
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		if ids[i] >= runID {
+			continue
+		}
+		if rp, err := LoadRunParams(st, ids[i]); err != nil || rp.Graph != graph {
+			continue
+		}
+		_, ferr := st.LoadFailure(ids[i])
+		return ferr == nil
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+)
+
+func TestExecute_NotifiesPerPolicy(t *testing.T) {
+	var posted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Text string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		posted = append(posted, body.Text)
+	}))
+	defer srv.Close()
+
+	workDir := t.TempDir()
+	writeFile(t, filepath.Join(workDir, "hook.txt"), srv.URL+"/hook\n")
+	writeFile(t, filepath.Join(workDir, ".scriptweaver", "config.json"),
+		`{"notify":{"format":"slack","webhook_url_file":"hook.txt","on":"recovery","template":"{{status}} {{failed_nodes}}","link":"http://dash/#{{run_id}}"}}`)
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "check", Run: "test -f ok"}}, nil)
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean}
+
+	// A failure, and the first success after it, are notified; the next
+	// success is not.
+	var runIDs []string
+	for i, fixed := range []bool{false, true, true} {
+		if fixed {
+			writeFile(t, filepath.Join(workDir, "ok"), "")
+		}
+		res, _ := Execute(context.Background(), inv)
+		if (res.ExitCode == ExitSuccess) != fixed || res.NotifyError != "" {
+			t.Fatalf("run %d: exit=%d notify error=%q", i, res.ExitCode, res.NotifyError)
+		}
+		runIDs = append(runIDs, res.RunID)
+	}
+	want := []string{
+		"failed check\n<http://dash/#" + runIDs[0] + "|View report>",
+		"succeeded \n<http://dash/#" + runIDs[1] + "|View report>",
+	}
+	if strings.Join(posted, "|") != strings.Join(want, "|") {
+		t.Fatalf("posted = %q, want %q", posted, want)
+	}
+
+	// A failing webhook is reported without changing the outcome.
+	if err := os.Remove(filepath.Join(workDir, "ok")); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	res, _ := Execute(context.Background(), inv)
+	if res.ExitCode == ExitSuccess || !strings.HasPrefix(res.NotifyError, "notify:") || strings.Contains(res.NotifyError, "/hook") {
+		t.Fatalf("closed webhook: exit=%d notify error=%q", res.ExitCode, res.NotifyError)
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/incremental"
+	"scriptweaver/internal/objectstore"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/recovery/state"
+)
+
+// NetworkLabel is the label of tasks that call external services. In an
+// offline run they, like fetch tasks, must be restored from the local cache.
+const NetworkLabel = "network"
+
+// ErrOffline is wrapped by the errors of offline runs that need the network.
+var ErrOffline = errors.New("offline")
+
+// OfflineError reports the nodes of an offline run that need the network and
+// have no local cache entry.
+type OfflineError struct {
+	Nodes []string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("offline: %s need the network and are not in the local cache", strings.Join(e.Nodes, ", "))
+}
+
+func (e *OfflineError) Unwrap() error { return ErrOffline }
+
+// needsNetwork reports whether running task requires the network: it is a
+// fetch task or is labelled NetworkLabel.
+func needsNetwork(task core.Task) bool {
+	return task.Fetch != nil || slices.Contains(task.Labels, NetworkLabel)
+}
+
+// localTiers returns cc without its object store tiers.
+func localTiers(cc *config.CacheConfig) *config.CacheConfig {
+	local := *cc
+	local.Tiers = nil
+	for _, tier := range cc.Tiers {
+		if !objectstore.IsURL(tier) {
+			local.Tiers = append(local.Tiers, tier)
+		}
+	}
+	return &local
+}
+
+// stageOffline fails an offline run before anything runs when a node that
+// needs the network would have to run: a node the plan does not restore or
+// skip, whose result is not in the local cache. Nodes whose inputs do not
+// exist yet cannot be checked until their dependencies have run; the
+// offline runner stops the run if one of them has to run.
+func stageOffline(ctx context.Context, rc *RunContext, next Next) error {
+	if !rc.Invocation.Offline || rc.scenario != nil {
+		return next(ctx)
+	}
+	var missing []string
+	for _, name := range rc.Graph.TopologicalOrder() {
+		n, _ := rc.Graph.Node(name)
+		if !needsNetwork(n.Task) || slices.Contains(rc.skip, name) {
+			continue
+		}
+		if rc.resumePlan != nil {
+			if d, ok := rc.resumePlan.Decisions[name]; ok && d != incremental.DecisionExecute {
+				continue
+			}
+		}
+		hash, err := computeTaskHash(rc.Runner, n.Task)
+		if err != nil {
+			continue
+		}
+		if ok, _ := rc.Cache.Has(hash); !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		err := &OfflineError{Nodes: missing}
+		rc.abort(ExitOffline, &state.WorkspaceFailureError{Code: "OfflineUnavailable", Message: err.Error(), Cause: err})
+		return err
+	}
+	return next(ctx)
+}
+
+// offlineRunner refuses to run tasks that need the network.
+type offlineRunner struct {
+	inner dag.TaskRunner
+}
+
+func (r offlineRunner) Probe(ctx context.Context, task core.Task) (*dag.NodeResult, bool, error) {
+	return r.inner.Probe(ctx, task)
+}
+
+func (r offlineRunner) Run(ctx context.Context, task core.Task) (*dag.NodeResult, error) {
+	if needsNetwork(task) {
+		return nil, &OfflineError{Nodes: []string{task.Name}}
+	}
+	return r.inner.Run(ctx, task)
+}
+
+// ProbeBatch forwards to the inner runner when it batches probes.
+func (r offlineRunner) ProbeBatch(ctx context.Context, tasks []core.Task) error {
+	if bp, ok := r.inner.(dag.BatchProber); ok {
+		return bp.ProbeBatch(ctx, tasks)
+	}
+	return nil
+}
+
+func (r offlineRunner) Restore(ctx context.Context, task core.Task) (*dag.NodeResult, error) {
+	restorer, ok := r.inner.(interface {
+		Restore(ctx context.Context, task core.Task) (*dag.NodeResult, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("runner does not support Restore for incremental plan execution")
+	}
+	return restorer.Restore(ctx, task)
+}
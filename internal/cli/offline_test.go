@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestExecute_OfflineFailsFastOnUncachedNetworkTasks(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "build", Run: "echo built >> build.log", Outputs: []string{"build.log"}},
+		{Name: "deploy", Run: "echo deployed > deploy.log", Outputs: []string{"deploy.log"}, Labels: []string{NetworkLabel}},
+	}, []dag.Edge{{From: "build", To: "deploy"}})
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+		Offline:       true,
+	}
+
+	res, err := Execute(context.Background(), inv)
+	var oe *OfflineError
+	if !errors.As(err, &oe) || res.ExitCode != ExitOffline {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+	if len(oe.Nodes) != 1 || oe.Nodes[0] != "deploy" || res.Failure == nil || res.Failure.ErrorCode != "OfflineUnavailable" {
+		t.Fatalf("nodes=%v failure=%+v", oe.Nodes, res.Failure)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "build.log")); !os.IsNotExist(err) {
+		t.Fatalf("a task ran before the offline check: %v", err)
+	}
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if rp, err := LoadRunParams(st, res.RunID); err != nil || !rp.Offline {
+		t.Fatalf("params = %+v, %v", rp, err)
+	}
+
+	online := inv
+	online.Offline = false
+	if res, err := Execute(context.Background(), online); err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("online: exit=%d err=%v", res.ExitCode, err)
+	}
+	if err := os.Remove(filepath.Join(workDir, "deploy.log")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	// With every result cached, the network task is restored.
+	res, err = Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("cached: exit=%d err=%v", res.ExitCode, err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "deploy.log")); err != nil {
+		t.Fatalf("deploy output not restored: %v", err)
+	}
+}
+
+func TestOfflineRunner_RefusesNetworkTasks(t *testing.T) {
+	r, err := dag.NewCacheAwareRunner(core.NewRunner(t.TempDir(), noCache{}))
+	if err != nil {
+		t.Fatalf("NewCacheAwareRunner: %v", err)
+	}
+	off := offlineRunner{inner: r}
+	if _, err := off.Run(context.Background(), core.Task{Name: "get", Run: "true", Labels: []string{NetworkLabel}}); !errors.Is(err, ErrOffline) {
+		t.Fatalf("network task: %v", err)
+	}
+	if res, err := off.Run(context.Background(), core.Task{Name: "local", Run: "true"}); err != nil || res.ExitCode != 0 {
+		t.Fatalf("local task: %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+// OutputsFileName is the run file that lists the files each node produced.
+const OutputsFileName = "outputs.json"
+
+// NodeOutputs lists the files a successful node produced, in path order.
+type NodeOutputs struct {
+	Node  string       `json:"node"`
+	Files []OutputFile `json:"files"`
+}
+
+// OutputFile is a produced file. Path is relative to the workdir.
+type OutputFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// harvestOutputs harvests the declared outputs of every successful node of g,
+// in topological order, from the workspace as the run left it.
+func harvestOutputs(workDir string, g *dag.TaskGraph, gr *dag.GraphResult) ([]NodeOutputs, error) {
+	harvester := core.NewHarvester(workDir)
+	out := []NodeOutputs{}
+	for _, name := range g.TopologicalOrder() {
+		if !dag.IsSuccessful(gr.FinalState[name]) {
+			continue
+		}
+		n, _ := g.Node(name)
+		set, err := harvester.Harvest(n.Task.Outputs)
+		if err != nil {
+			return nil, fmt.Errorf("hashing outputs of %q: %w", name, err)
+		}
+		no := NodeOutputs{Node: name, Files: make([]OutputFile, 0, len(set.Artifacts))}
+		for _, a := range set.Artifacts {
+			sum := sha256.Sum256(a.Content)
+			no.Files = append(no.Files, OutputFile{Path: a.Path, Size: int64(len(a.Content)), SHA256: hex.EncodeToString(sum[:])})
+		}
+		out = append(out, no)
+	}
+	return out, nil
+}
+
+// MarshalOutputs encodes an output listing as written to OutputsFileName.
+func MarshalOutputs(outputs []NodeOutputs) ([]byte, error) {
+	if outputs == nil {
+		outputs = []NodeOutputs{}
+	}
+	b, err := json.MarshalIndent(outputs, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
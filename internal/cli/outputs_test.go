@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestExecute_ListsNodeOutputs(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: "mkdir -p out && printf hello > out/a.txt && printf x > out/b.txt", Outputs: []string{"out/b.txt", "out/a.txt"}},
+		{Name: "b", Run: "printf bye > b.txt", Outputs: []string{"b.txt"}},
+		{Name: "c", Run: "exit 1", Outputs: []string{"c.txt"}},
+	}, []dag.Edge{{From: "a", To: "b"}})
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, CacheDir: filepath.Join(workDir, "cache"), OutputDir: filepath.Join(workDir, "out-dir"), ExecutionMode: ExecutionModeClean}
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []NodeOutputs{
+		{Node: "a", Files: []OutputFile{
+			{Path: "out/a.txt", Size: 5, SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+			{Path: "out/b.txt", Size: 1, SHA256: "2d711642b726b04401627ca9fbac32f5c8530fb1903cc4db02258717921a4881"},
+		}},
+		{Node: "b", Files: []OutputFile{
+			{Path: "b.txt", Size: 3, SHA256: "b49f425a7e1f9cff3856329ada223f2f9d368f15a00cf48df16ca95986137fe8"},
+		}},
+	}
+	if !reflect.DeepEqual(res.Outputs, want) {
+		t.Fatalf("outputs = %#v, want %#v", res.Outputs, want)
+	}
+
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	data, err := st.LoadRunFile(res.RunID, OutputsFileName)
+	if err != nil {
+		t.Fatalf("LoadRunFile: %v", err)
+	}
+	var recorded []NodeOutputs
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		t.Fatalf("parse %s: %v", OutputsFileName, err)
+	}
+	if !reflect.DeepEqual(recorded, want) {
+		t.Fatalf("recorded outputs = %#v, want %#v", recorded, want)
+	}
+}
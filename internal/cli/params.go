@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"scriptweaver/internal/graph"
+)
+
+// GraphParam declares a parameter of a graph file. A parameter without a
+// default must be given a value with --set.
+type GraphParam struct {
+	Default     *string `json:"default,omitempty"`
+	Description string  `json:"description,omitempty"`
+}
+
+// paramNamePattern is the syntax of parameter names, and paramRefPattern that
+// of references to them: "{{name}}", optionally with spaces inside the braces.
+var (
+	paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	paramRefPattern  = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// graphParams substitutes parameter values into the graph files of one load.
+type graphParams struct {
+	values map[string]string
+	// declared records every parameter declared by a file of the load, to
+	// reject values for parameters no file declares.
+	declared map[string]bool
+}
+
+func newGraphParams(values map[string]string) *graphParams {
+	return &graphParams{values: values, declared: make(map[string]bool)}
+}
+
+// apply substitutes parameters into the commands, inputs, outputs and env
+// values of the tasks of gf, decoded from file. A file only sees the
+// parameters it declares.
+func (p *graphParams) apply(gf *graphFile, file string) error {
+	resolved := make(map[string]string, len(gf.Params))
+	var missing []string
+	for _, name := range sortedParamNames(gf.Params) {
+		if !paramNamePattern.MatchString(name) {
+			return &graph.SemanticError{Msg: fmt.Sprintf("%s: invalid parameter name %q", file, name)}
+		}
+		p.declared[name] = true
+		if v, ok := p.values[name]; ok {
+			resolved[name] = v
+		} else if d := gf.Params[name].Default; d != nil {
+			resolved[name] = *d
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return &graph.SemanticError{Msg: fmt.Sprintf("%s: missing value for parameter %s (set it with --set name=value)", file, strings.Join(missing, ", "))}
+	}
+
+	var err error
+	subst := func(s string) string {
+		return paramRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+			name := paramRefPattern.FindStringSubmatch(ref)[1]
+			v, ok := resolved[name]
+			if !ok && err == nil {
+				err = &graph.SemanticError{
+					Msg:   fmt.Sprintf("%s: undeclared parameter %q", file, name),
+					Hints: graph.DidYouMean(name, sortedParamNames(resolved)),
+				}
+			}
+			return v
+		})
+	}
+	for i := range gf.Tasks {
+		t := &gf.Tasks[i]
+		t.Run = subst(t.Run)
+		t.Inputs = substAll(t.Inputs, subst)
+		t.Outputs = substAll(t.Outputs, subst)
+		if len(t.Env) > 0 {
+			env := make(map[string]string, len(t.Env))
+			for k, v := range t.Env {
+				env[k] = subst(v)
+			}
+			t.Env = env
+		}
+		if err != nil {
+			return fmt.Errorf("task %q: %w", t.Name, err)
+		}
+	}
+	gf.Params = nil
+	return nil
+}
+
+// checkUnused rejects values for parameters that no file of the load
+// declares.
+func (p *graphParams) checkUnused() error {
+	var unknown []string
+	for name := range p.values {
+		if !p.declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return &graph.SemanticError{
+		Msg:   fmt.Sprintf("--set of undeclared parameter %s", strings.Join(unknown, ", ")),
+		Hints: graph.DidYouMean(unknown[0], sortedParamNames(p.declared)),
+	}
+}
+
+func sortedParamNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func substAll(in []string, subst func(string) string) []string {
+	if in == nil {
+		return nil
+	}
+	out := make([]string, len(in))
+	for i, s := range in {
+		out[i] = subst(s)
+	}
+	return out
+}
+
+// ParseParamAssignment parses a --set value of the form name=value.
+func ParseParamAssignment(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || !paramNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("invalid --set %q (expected name=value)", s)
+	}
+	return name, value, nil
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/graph"
+)
+
+func TestLoadGraphWithParams_Substitutes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "graph.json")
+	writeFile(t, path, `{
+		"params": {"target": {"default": "linux"}, "version": {"description": "release version"}},
+		"tasks": [{"name": "build", "inputs": ["src/{{target}}/*.go"], "run": "make VERSION={{ version }} ${HOME}", "outputs": ["bin/{{target}}/app"], "env": {"TARGET": "{{target}}"}}],
+		"edges": []
+	}`)
+
+	g, err := LoadGraphWithParams(path, map[string]string{"version": "1.2.0"})
+	if err != nil {
+		t.Fatalf("LoadGraphWithParams: %v", err)
+	}
+	task := g.Nodes()[0].Task
+	if task.Run != "make VERSION=1.2.0 ${HOME}" || task.Inputs[0] != "src/linux/*.go" || task.Outputs[0] != "bin/linux/app" || task.Env["TARGET"] != "linux" {
+		t.Fatalf("task = %+v", task)
+	}
+
+	other, err := LoadGraphWithParams(path, map[string]string{"version": "1.2.0", "target": "darwin"})
+	if err != nil {
+		t.Fatalf("LoadGraphWithParams: %v", err)
+	}
+	if other.Hash() == g.Hash() {
+		t.Fatal("a parameter value must change the graph hash")
+	}
+	same, err := LoadGraphWithParams(path, map[string]string{"version": "1.2.0", "target": "linux"})
+	if err != nil {
+		t.Fatalf("LoadGraphWithParams: %v", err)
+	}
+	if same.Hash() != g.Hash() {
+		t.Fatal("setting a parameter to its default must not change the graph hash")
+	}
+}
+
+func TestLoadGraphWithParams_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		run    string
+		params map[string]string
+		want   string
+	}{
+		{"missing", "make {{version}}", nil, "missing value for parameter version"},
+		{"undeclared reference", "make {{verison}}", map[string]string{"version": "1"}, `task "build": semantic error: graph.json: undeclared parameter "verison"`},
+		{"undeclared value", "make {{version}}", map[string]string{"version": "1", "verison": "1", "channel": "beta"}, "--set of undeclared parameter channel, verison"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "graph.json")
+			writeFile(t, path, `{"params": {"version": {}}, "tasks": [{"name": "build", "inputs": [], "run": "`+tc.run+`"}], "edges": []}`)
+			_, err := LoadGraphWithParams(path, tc.params)
+			if !errors.Is(err, graph.ErrSemantic) || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want a semantic error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadGraphWithParams_IncludesDeclareTheirOwn(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "team.json"), `{"params": {"env": {"default": "dev"}}, "tasks": [{"name": "deploy", "inputs": [], "run": "deploy {{env}}"}], "edges": []}`)
+	writeFile(t, filepath.Join(dir, "graph.json"), `{"includes": [{"path": "team.json", "namespace": "team"}], "tasks": [{"name": "check", "inputs": [], "run": "check {{env}}"}], "edges": []}`)
+
+	// The including file does not declare env, so it cannot reference it.
+	if _, err := LoadGraphWithParams(filepath.Join(dir, "graph.json"), nil); err == nil || !strings.Contains(err.Error(), `undeclared parameter "env"`) {
+		t.Fatalf("err = %v", err)
+	}
+	writeFile(t, filepath.Join(dir, "graph.json"), `{"includes": [{"path": "team.json", "namespace": "team"}], "tasks": [], "edges": []}`)
+	g, err := LoadGraphWithParams(filepath.Join(dir, "graph.json"), map[string]string{"env": "prod"})
+	if err != nil {
+		t.Fatalf("LoadGraphWithParams: %v", err)
+	}
+	if run := g.Nodes()[0].Task.Run; run != "deploy prod" {
+		t.Fatalf("run = %q", run)
+	}
+}
@@ -0,0 +1,891 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"scriptweaver/internal/audit"
+	"scriptweaver/internal/chaos"
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+	"scriptweaver/internal/incremental"
+	"scriptweaver/internal/pluginengine"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/projectintegration/engine/workspace"
+	"scriptweaver/internal/publish"
+	"scriptweaver/internal/recovery/state"
+)
+
+// Names of the stages of DefaultPipeline, in order.
+const (
+	StageRecovery  = "recovery"
+	StageWorkspace = "workspace"
+	StageRetention = "retention"
+	StageNotify    = "notify"
+	StagePlugins   = "plugins"
+	StageGraph     = "graph"
+	StageSelect    = "select"
+	StageTrace     = "trace"
+	StageOutputDir = "output-dir"
+	StageCache     = "cache"
+	StagePlan      = "plan"
+	StageOffline   = "offline"
+	StageStart     = "start"
+	StageExecute   = "execute"
+	StageOutputs   = "outputs"
+)
+
+// Next runs the remaining stages of a pipeline.
+type Next func(ctx context.Context) error
+
+// Stage is one step of a run. Run prepares its part of the RunContext and
+// calls next to continue the run, then may act on the outcome once next
+// returns, like an HTTP middleware. A stage that fails records the failure,
+// sets RunContext.Result.ExitCode and returns an error without calling next.
+type Stage struct {
+	Name string
+	Run  func(ctx context.Context, rc *RunContext, next Next) error
+}
+
+// Pipeline is the sequence of stages that executes a run. The first stage
+// runs outermost: it starts first and sees the outcome of all the others.
+//
+// Cross-cutting features, such as metrics or notifications, are stages
+// inserted into DefaultPipeline rather than changes to the stages
+// themselves.
+type Pipeline []Stage
+
+// DefaultPipeline returns the stages Execute runs.
+func DefaultPipeline() Pipeline {
+	return Pipeline{
+		{StageRecovery, stageRecovery},
+		{StageWorkspace, stageWorkspace},
+		{StageRetention, stageRetention},
+		{StageNotify, stageNotify},
+		{StagePlugins, stagePlugins},
+		{StageGraph, stageGraph},
+		{StageSelect, stageSelect},
+		{StageTrace, stageTrace},
+		{StageOutputDir, stageOutputDir},
+		{StageCache, stageCache},
+		{StagePlan, stagePlan},
+		{StageOffline, stageOffline},
+		{StageStart, stageStart},
+		{StageExecute, stageExecute},
+		{StageOutputs, stageOutputs},
+	}
+}
+
+// InsertBefore returns a copy of p with s inserted before the stage named
+// before, so that s runs around it and every later stage.
+func (p Pipeline) InsertBefore(before string, s Stage) (Pipeline, error) {
+	for i, st := range p {
+		if st.Name == before {
+			out := make(Pipeline, 0, len(p)+1)
+			out = append(out, p[:i]...)
+			out = append(out, s)
+			return append(out, p[i:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("pipeline has no stage %q", before)
+}
+
+// Execute runs inv through p like Execute, including automatic retries.
+func (p Pipeline) Execute(ctx context.Context, inv CLIInvocation) (CLIResult, error) {
+	return executeWith(ctx, inv, defaultGraphExecutor{}, nil, nil, p)
+}
+
+func (p Pipeline) run(ctx context.Context, rc *RunContext) error {
+	var next func(i int) Next
+	next = func(i int) Next {
+		return func(ctx context.Context) error {
+			if i == len(p) {
+				return nil
+			}
+			return p[i].Run(ctx, rc, next(i+1))
+		}
+	}
+	return next(0)(ctx)
+}
+
+// RunContext is the state of a run that the stages of a Pipeline build up.
+// Each field is set by the stage named in its comment and is the zero value
+// before it.
+type RunContext struct {
+	// Invocation is the run's invocation; StageWorkspace defaults its CacheDir.
+	Invocation CLIInvocation
+	// Result is the result the run returns.
+	Result *CLIResult
+
+	// Store, Recorder and RunID (StageRecovery) record the run. RunID is
+	// empty when no run record could be created.
+	Store    *state.Store
+	Recorder *state.FailureRecorder
+	RunID    string
+
+	// Workspace and Config (StageWorkspace) describe the project.
+	Workspace workspace.Workspace
+	Config    config.Config
+
+	// AuditLog and Hooks (StagePlugins) are the workspace audit log and the
+	// allowlisted plugins; Hooks is nil without plugins.
+	AuditLog *audit.Log
+	Hooks    *pluginengine.HookEngine
+
+	// Graph and GraphHash (StageGraph) are the graph as it runs, after
+	// output namespacing and fetch pinning.
+	Graph     *dag.TaskGraph
+	GraphHash string
+
+	// Cache and Runner (StageCache) execute the tasks.
+	Cache  core.Cache
+	Runner *core.Runner
+
+	// Executor (StageStart) runs the graph.
+	Executor GraphExecutor
+
+	executor  GraphExecutor
+	session   *Session
+	observers []dag.NodeObserver
+
+	phases   *phaseTimer
+	injector *chaos.Injector
+
+	outputRel string
+	lockfile  *Lockfile
+	// tolerated are the nodes whose failure the graph's success criteria
+	// allow (StageGraph).
+	tolerated map[string]bool
+
+	skip     []string
+	isolated *incremental.IncrementalPlan
+	scenario *dag.Scenario
+
+	stats            *core.StatsCache
+	cacheTiers       []string
+	cacheKeys        *core.CacheKeys
+	cacheRunner      *dag.CacheAwareRunner
+	cleanCheckpoints bool
+
+	observer      dag.NodeObserver
+	resumePlan    *incremental.IncrementalPlan
+	previousRunID *string
+	retryCount    int
+
+	traceWriter *traceFileWriter
+
+	timed     *timingRunner
+	startedAt time.Time
+	gr        *dag.GraphResult
+}
+
+// abort records the failure of a run that never started, and its exit code.
+func (rc *RunContext) abort(exitCode int, failure error) {
+	if rc.RunID != "" {
+		if err := rc.Recorder.StartRun(state.Run{RunID: rc.RunID, GraphHash: rc.GraphHash, StartTime: time.Now().UTC(), Mode: state.ExecutionMode(rc.Invocation.ExecutionMode), RetryCount: 0, Status: "failed", PreviousRunID: nil}); err == nil {
+			saveRunParams(rc.Store, rc.RunID, newRunParams(rc.Invocation))
+		}
+		_ = rc.Recorder.RecordFailure(rc.RunID, failure)
+	}
+	rc.Result.ExitCode = exitCode
+}
+
+// fail records the failure of the run, and its exit code.
+func (rc *RunContext) fail(exitCode int, failure error) {
+	if rc.RunID != "" {
+		_ = rc.Recorder.RecordFailure(rc.RunID, failure)
+	}
+	rc.Result.ExitCode = exitCode
+}
+
+func executeRun(ctx context.Context, inv CLIInvocation, executor GraphExecutor, session *Session, observers []dag.NodeObserver, p Pipeline) (CLIResult, error) {
+	res := CLIResult{ExitCode: ExitInternalError}
+	if executor == nil {
+		return res, fmt.Errorf("nil executor")
+	}
+	if p == nil {
+		p = DefaultPipeline()
+	}
+	rc := &RunContext{Invocation: inv, Result: &res, executor: executor, session: session, observers: observers}
+	err := p.run(ctx, rc)
+	return res, err
+}
+
+// stageRecovery initializes the recovery store as early as possible so
+// failures can be recorded.
+func stageRecovery(ctx context.Context, rc *RunContext, next Next) error {
+	rc.Store, _ = state.NewStore(rc.Invocation.WorkDir)
+	rc.Recorder = &state.FailureRecorder{Store: rc.Store}
+	rc.RunID, _ = rc.Recorder.NewRunID()
+	rc.Result.RunID = rc.RunID
+	return next(ctx)
+}
+
+// stageWorkspace validates or initializes the .scriptweaver workspace and
+// loads its configuration.
+func stageWorkspace(ctx context.Context, rc *RunContext, next Next) error {
+	ws, err := workspace.EnsureWorkspace(rc.Invocation.WorkDir)
+	if err != nil {
+		rc.abort(ExitConfigError, &state.WorkspaceFailureError{Code: "WorkspaceInvalid", Message: err.Error(), Cause: err})
+		return err
+	}
+	rc.Workspace = ws
+	// Without --cache-dir, runs share the workspace cache, as resume expects.
+	if rc.Invocation.CacheDir == "" {
+		rc.Invocation.CacheDir = ws.CacheDir
+	}
+
+	cfg, _, err := config.LoadOptional(rc.Invocation.WorkDir)
+	if err != nil {
+		rc.abort(ExitConfigError, &state.WorkspaceFailureError{Code: "ConfigInvalid", Message: err.Error(), Cause: err})
+		return err
+	}
+	rc.Config = cfg
+	return next(ctx)
+}
+
+// stagePlugins opens the audit log and registers the allowlisted plugins.
+func stagePlugins(ctx context.Context, rc *RunContext, next Next) error {
+	inv := rc.Invocation
+	// Best-effort: workspace mutations are appended to the hash-chained audit log.
+	rc.AuditLog, _ = audit.Open(inv.WorkDir, rc.RunID)
+	if rc.Store != nil {
+		rc.Store.SetAuditLog(rc.AuditLog)
+	}
+
+	hooks, err := loadPlugins(inv, rc.Config.PluginKeys, pluginLogger(inv.PluginLog))
+	if err != nil {
+		rc.abort(ExitPluginError, &state.SystemFailureError{Code: "PluginLoad", Message: err.Error(), Cause: err})
+		return err
+	}
+	if hooks != nil {
+		hooks.SetAuditLog(rc.AuditLog)
+	}
+	rc.Hooks = hooks
+	if inv.Chaos != nil {
+		rc.injector = chaos.New(*inv.Chaos)
+		if hooks != nil {
+			hooks.SetPanicInjector(rc.injector.PluginPanic)
+		}
+	}
+	return next(ctx)
+}
+
+// stageGraph loads the graph, namespaces its outputs, pins its fetches and
+// validates it.
+func stageGraph(ctx context.Context, rc *RunContext, next Next) error {
+	inv := rc.Invocation
+	rc.phases = newPhaseTimer()
+	defer func() { rc.Result.Phases = rc.phases.timings() }()
+
+	g, graphHash, tolerated, err := loadGraphAndHash(inv, rc.phases, rc.session)
+	if err != nil {
+		var se *graph.SchemaError
+		var ste *graph.StructuralError
+		var failure error
+		switch {
+		case errors.As(err, &se):
+			failure = &state.GraphFailureError{Code: "SchemaViolation", Message: err.Error(), Cause: err}
+		case errors.As(err, &ste):
+			failure = &state.GraphFailureError{Code: "StructuralInvalidity", Message: err.Error(), Cause: err}
+		default:
+			failure = &state.GraphFailureError{Code: "GraphLoadError", Message: err.Error(), Cause: err}
+		}
+		// An unreadable graph path is a bad invocation; anything else is an invalid graph.
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+			rc.abort(ExitInvalidInvocation, failure)
+		} else {
+			rc.abort(ExitValidationError, failure)
+		}
+		return err
+	}
+	rc.Graph, rc.GraphHash, rc.tolerated = g, graphHash, tolerated
+	if inv.NamespaceOutputs {
+		if rc.outputRel, err = outputDirRel(inv); err != nil {
+			rc.abort(ExitInvalidInvocation, &state.WorkspaceFailureError{Code: "OutputDir", Message: err.Error(), Cause: err})
+			return err
+		}
+		// The namespaced graph is a different graph: its outputs, inputs and
+		// env, and so its hash, differ from the file's.
+		if rc.Graph, err = namespaceOutputs(rc.Graph, rc.outputRel); err != nil {
+			rc.abort(ExitValidationError, &state.GraphFailureError{Code: "OutputNamespace", Message: err.Error(), Cause: err})
+			return err
+		}
+		rc.GraphHash = rc.Graph.Hash().String()
+	}
+	// Fetch tasks the graph leaves unpinned are pinned by the lockfile.
+	rc.lockfile, err = LoadLockfile(inv.WorkDir)
+	if err == nil {
+		rc.Graph, err = pinFetches(rc.Graph, rc.lockfile)
+	}
+	if err != nil {
+		rc.abort(ExitConfigError, &state.WorkspaceFailureError{Code: "LockfileInvalid", Message: err.Error(), Cause: err})
+		return err
+	}
+	rc.GraphHash = rc.Graph.Hash().String()
+	// Validator plugins contribute semantic rules; any finding rejects the graph.
+	if findings := rc.Hooks.Validate(ctx, rc.Graph); len(findings) > 0 {
+		err := &PluginFindingsError{Findings: findings}
+		rc.abort(ExitValidationError, &state.GraphFailureError{Code: "PluginValidation", Message: err.Error(), Cause: err})
+		rc.Result.Findings = findings
+		return err
+	}
+	// Nodes may only select runners that an allowlisted plugin provides.
+	if err := rc.Hooks.CheckRunners(rc.Graph); err != nil {
+		rc.abort(ExitValidationError, &state.GraphFailureError{Code: "UnknownRunner", Message: err.Error(), Cause: err})
+		return err
+	}
+	// The workspace policy is enforced again on the graph about to run.
+	violations, err := CheckPolicy(inv.WorkDir, rc.Graph)
+	if err != nil {
+		rc.abort(ExitConfigError, &state.WorkspaceFailureError{Code: "PolicyInvalid", Message: err.Error(), Cause: err})
+		return err
+	}
+	if err := policyError(violations); err != nil {
+		rc.abort(ExitValidationError, &state.GraphFailureError{Code: "PolicyViolation", Message: err.Error(), Cause: err})
+		return err
+	}
+	if rc.Config.Publish != nil {
+		if err := validatePublishOutputs(rc.Graph, rc.Config.Publish.Outputs); err != nil {
+			rc.abort(ExitConfigError, &state.WorkspaceFailureError{Code: "ConfigInvalid", Message: err.Error(), Cause: err})
+			return err
+		}
+	}
+	return next(ctx)
+}
+
+// stageSelect selects the nodes to skip or to run in isolation, and loads
+// the simulation scenario.
+func stageSelect(ctx context.Context, rc *RunContext, next Next) error {
+	inv := rc.Invocation
+	skip, err := selectNodes(rc.Graph, inv.Skip)
+	if err != nil {
+		rc.abort(ExitInvalidInvocation, &state.GraphFailureError{Code: "SkipSelection", Message: err.Error(), Cause: err})
+		return fmt.Errorf("--skip: %w", err)
+	}
+	// A node run on its own skips every node it does not depend on; in
+	// isolation its dependencies are restored rather than resumed or run.
+	if inv.Node != "" || inv.Isolated {
+		upstream, others, err := nodeScope(rc.Graph, inv.Node)
+		if err == nil && inv.Isolated && inv.ExecutionMode != ExecutionModeIncremental {
+			err = errors.New("--isolated requires incremental mode")
+		}
+		if err != nil {
+			rc.abort(ExitInvalidInvocation, &state.GraphFailureError{Code: "NodeSelection", Message: err.Error(), Cause: err})
+			return fmt.Errorf("--node: %w", err)
+		}
+		if inv.Isolated {
+			rc.isolated = isolatedPlan(rc.Graph, inv.Node, upstream, others)
+		} else {
+			skip = append(skip, others...)
+			sort.Strings(skip)
+		}
+	}
+	rc.skip = skip
+
+	if inv.Simulate != "" {
+		if rc.scenario, err = loadScenario(inv.Simulate, rc.Graph); err != nil {
+			rc.abort(ExitInvalidInvocation, &state.GraphFailureError{Code: "ScenarioInvalid", Message: err.Error(), Cause: err})
+			return fmt.Errorf("--simulate: %w", err)
+		}
+	}
+	return next(ctx)
+}
+
+// stageTrace initializes the trace output, and finalizes it and runs the
+// reporter plugins once the run is over, even on panic or failure.
+func stageTrace(ctx context.Context, rc *RunContext, next Next) error {
+	inv := rc.Invocation
+	traceWriter, err := newTraceWriter(inv, rc.GraphHash)
+	if err != nil {
+		rc.fail(ExitConfigError, &state.SystemFailureError{Code: "TraceInit", Message: err.Error(), Cause: err})
+		return err
+	}
+	rc.traceWriter = traceWriter
+	defer func() {
+		res := rc.Result
+		// Always finalize trace output deterministically.
+		_ = traceWriter.Finalize(res.GraphResult)
+		if res.GraphResult != nil {
+			rc.phases.mark(PhaseFinalize)
+			// Reporters see the finalized trace, even when the run was cancelled.
+			rc.Hooks.Report(context.WithoutCancel(ctx), filepath.Join(inv.OutputDir, reportsDirName), pluginengine.NewReport(rc.RunID, res.ExitCode, res.GraphResult))
+		}
+		if rc.Hooks != nil {
+			res.PluginStats = rc.Hooks.Stats()
+			if rc.RunID != "" {
+				// Best-effort: hook statistics are kept with the run record.
+				if data, serr := pluginengine.MarshalStats(res.PluginStats); serr == nil {
+					_ = rc.Store.SaveRunFile(rc.RunID, pluginengine.StatsFileName, data)
+				}
+			}
+		}
+	}()
+	return next(ctx)
+}
+
+// stageOutputDir clears the output directory per the overwrite policy.
+func stageOutputDir(ctx context.Context, rc *RunContext, next Next) error {
+	inv := rc.Invocation
+	if err := prepareOutputDir(inv.OutputDir); err != nil {
+		rc.fail(ExitConfigError, &state.WorkspaceFailureError{Code: "OutputDir", Message: err.Error(), Cause: err})
+		return err
+	}
+	_ = rc.AuditLog.Record(audit.KindOutputClear, inv.OutputDir)
+	if inv.NamespaceOutputs {
+		if err := createNodeOutputDirs(inv.WorkDir, rc.outputRel, rc.Graph); err != nil {
+			rc.fail(ExitConfigError, &state.WorkspaceFailureError{Code: "OutputDir", Message: err.Error(), Cause: err})
+			return err
+		}
+	}
+
+	if rc.Store != nil {
+		// Best-effort: stale outputs are only reported.
+		rc.Result.StaleOutputs, _ = findStaleOutputs(inv.WorkDir, rc.Store, rc.Graph)
+	}
+	return next(ctx)
+}
+
+// stageCache selects the cache for the execution mode and builds the task
+// runner on it.
+func stageCache(ctx context.Context, rc *RunContext, next Next) error {
+	inv := rc.Invocation
+	cache, err := cacheForMode(inv.ExecutionMode, inv.CacheDir)
+	if err != nil {
+		rc.fail(ExitConfigError, &state.WorkspaceFailureError{Code: "CacheDir", Message: err.Error(), Cause: err})
+		return err
+	}
+	if rc.cacheKeys, err = loadCacheKeys(inv.WorkDir, rc.Config.CacheEncryption); err != nil {
+		rc.fail(ExitConfigError, &state.WorkspaceFailureError{Code: "CacheKeys", Message: err.Error(), Cause: err})
+		return err
+	}
+	if fc, ok := cache.(*core.FileCache); ok {
+		fc.Keys = rc.cacheKeys
+	}
+	if inv.ExecutionMode != ExecutionModeClean && rc.scenario == nil {
+		rc.Result.CacheDir = inv.CacheDir
+		if cc := rc.Config.Cache; cc != nil {
+			if inv.Offline {
+				cc = localTiers(cc)
+			}
+			cache, err = withSharedTiers(cache, cc, inv.WorkDir, rc.GraphHash)
+			if err != nil {
+				rc.fail(ExitConfigError, &state.WorkspaceFailureError{Code: "CacheDir", Message: err.Error(), Cause: err})
+				return err
+			}
+			rc.cacheTiers = cc.Tiers
+		}
+	}
+
+	// Opt-in clean-mode checkpoints: artifacts go to a run-scoped cache that
+	// nothing reads during this run, so clean semantics are preserved.
+	rc.cleanCheckpoints = inv.ExecutionMode == ExecutionModeClean && inv.Checkpoint && rc.RunID != ""
+	if rc.cleanCheckpoints {
+		runCacheDir := rc.Store.RunCacheDir(rc.RunID)
+		if err := os.MkdirAll(runCacheDir, 0o755); err != nil {
+			rc.fail(ExitConfigError, &state.WorkspaceFailureError{Code: "CacheDir", Message: err.Error(), Cause: err})
+			return fmt.Errorf("create run cache dir: %w", err)
+		}
+		cache = encryptedFileCache(runCacheDir, rc.cacheKeys)
+	}
+
+	if inv.ExecutionMode != ExecutionModeClean || rc.cleanCheckpoints {
+		cache = auditCache{Cache: cache, log: rc.AuditLog}
+	}
+	rc.Cache = cache
+
+	// Only the runner's lookups count towards the run's cache statistics, not
+	// checkpoint imports or evictions.
+	runnerCache := cache
+	if inv.ExecutionMode != ExecutionModeClean && rc.scenario == nil {
+		rc.stats = core.NewStatsCache(cache)
+		runnerCache = rc.stats
+	}
+	if rc.injector != nil {
+		runnerCache = rc.injector.Cache(runnerCache)
+	}
+	runner := core.NewRunner(inv.WorkDir, runnerCache)
+	runner.Hasher = &core.TaskHasher{Algorithm: hashAlgorithm(rc.Config)}
+	runner.Runners = rc.Hooks.TaskExecutors(inv.WorkDir)
+	if rc.isolated != nil {
+		// The node run in isolation is the point of the run, cached or not.
+		runner.Rerun = map[string]bool{inv.Node: true}
+	}
+	if rc.session != nil {
+		runner.Resolver.Stat = rc.session.stats
+	}
+	rc.Runner = runner
+	if rc.cacheRunner, err = dag.NewCacheAwareRunner(runner); err != nil {
+		rc.Result.ExitCode = ExitInternalError
+		return err
+	}
+	return next(ctx)
+}
+
+// stagePlan attaches the checkpoint observer and plans the reuse of a
+// previous run's checkpoints.
+func stagePlan(ctx context.Context, rc *RunContext, next Next) error {
+	inv := rc.Invocation
+	// Create a checkpoint observer. Checkpoints are recorded for incremental/resume-only,
+	// and for clean mode when requested.
+	// Caller-provided observers are notified after it through the same fan-out.
+	multi := dag.NewMultiObserver()
+	if rc.RunID != "" && rc.scenario == nil && (inv.ExecutionMode == ExecutionModeIncremental || inv.ExecutionMode == ExecutionModeResumeOnly || rc.cleanCheckpoints) {
+		validator := &state.CheckpointValidator{Store: rc.Store, Cache: rc.Cache, Harvester: core.NewHarvester(inv.WorkDir)}
+		multi.Add(checkpointObserver{RunID: rc.RunID, Validator: validator, Upstream: upstreamByNode(rc.Graph)})
+	}
+	for _, o := range rc.observers {
+		multi.Add(o)
+	}
+	if multi.Len() > 0 {
+		rc.observer = multi
+	}
+
+	// Resume planning (incremental/resume-only): best-effort attempt to reuse prior work.
+	// Clean mode ignores all checkpoints, and simulations have none to reuse.
+	rc.resumePlan = rc.isolated
+	if rc.isolated == nil && rc.scenario == nil && (inv.ExecutionMode == ExecutionModeIncremental || inv.ExecutionMode == ExecutionModeResumeOnly) {
+		if err := rc.planResume(ctx); err != nil {
+			return err
+		}
+		if inv.ExecutionMode == ExecutionModeResumeOnly && rc.resumePlan == nil {
+			err := fmt.Errorf("resume-only mode requires an eligible previous run with checkpoints")
+			rc.abort(ExitConfigError, &state.ExecutionFailureError{NodeID: "", Code: "ResumeIneligible", Message: err.Error(), Cause: err})
+			return err
+		}
+	}
+	if rc.previousRunID == nil && inv.retryOf != "" {
+		// An automatic retry is linked to the run it retries even when none of
+		// that run's checkpoints could be reused.
+		if prevRun, lerr := rc.Store.LoadRun(inv.retryOf); lerr == nil {
+			prevID := prevRun.RunID
+			rc.previousRunID = &prevID
+			rc.retryCount = prevRun.RetryCount + 1
+		}
+	}
+	return next(ctx)
+}
+
+// planResume plans the resumption of the previous failed run, if one is
+// eligible. Only resume-only mode fails when none is; incremental mode then
+// runs from scratch.
+func (rc *RunContext) planResume(ctx context.Context) error {
+	inv := rc.Invocation
+	st := rc.Store
+	prevID := ""
+	var perr error
+	if strings.TrimSpace(inv.ResumeRunID) != "" {
+		prevID = strings.TrimSpace(inv.ResumeRunID)
+	} else {
+		prevID, perr = detectPreviousRunID(st, rc.GraphHash, rc.Graph)
+	}
+	if perr != nil {
+		if inv.ExecutionMode == ExecutionModeResumeOnly {
+			rc.abort(ExitConfigError, &state.ExecutionFailureError{NodeID: "", Code: "ResumeIneligible", Message: perr.Error(), Cause: perr})
+			return perr
+		}
+		return nil
+	}
+	if prevID == "" {
+		return nil
+	}
+	prevRun, lerr := st.LoadRun(prevID)
+	if lerr != nil {
+		return nil
+	}
+	graphChanged := !core.SameHash(prevRun.GraphHash, rc.GraphHash)
+	// Resume is only meaningful after a non-successful termination.
+	if _, ferr := st.LoadFailure(prevID); ferr != nil {
+		return nil
+	}
+	checkpoints, cerr := st.LoadAllCheckpoints(prevID)
+	if cerr != nil || len(checkpoints) == 0 {
+		return nil
+	}
+	// The checkpointed artifacts may live in another cache: the previous
+	// run's own cache (clean mode) or a different --cache-dir.
+	var corruption error
+	for _, dir := range previousCacheDirs(st, prevID, inv.CacheDir) {
+		if corruption = importRunCache(encryptedFileCache(dir, rc.cacheKeys), rc.Cache, checkpoints); corruption != nil {
+			break
+		}
+	}
+	var plan *incremental.IncrementalPlan
+	var checkpointNode string
+	var snap *incremental.GraphSnapshot
+	var invMap incremental.InvalidationMap
+	var reasons map[string]string
+	if corruption == nil {
+		plan, checkpointNode, snap, invMap, reasons, corruption = buildResumePlan(ctx, rc.Graph, rc.Runner, rc.cacheRunner, rc.Cache, checkpoints, graphChanged)
+	}
+	if corruption != nil {
+		// Resume-only hard-fails; incremental falls back to scratch execution.
+		if inv.ExecutionMode == ExecutionModeResumeOnly {
+			rc.abort(ExitConfigError, &state.WorkspaceFailureError{Code: "WorkspaceCorrupt", Message: corruption.Error(), Cause: corruption})
+			return corruption
+		}
+		return nil
+	}
+	if plan == nil || checkpointNode == "" {
+		return nil
+	}
+	for _, name := range rc.skip {
+		plan.Decisions[name] = incremental.DecisionSkip
+		reasons[name] = ResumeReasonSkipped
+	}
+	candidatePrevID := prevID
+	candidatePrevPtr := &candidatePrevID
+	candidateRetry := prevRun.RetryCount + 1
+	newRun := state.Run{RunID: rc.RunID, GraphHash: rc.GraphHash, StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: candidateRetry, Status: "running", PreviousRunID: candidatePrevPtr}
+	checker := &state.ResumeEligibilityChecker{Store: st, ProjectRoot: inv.WorkDir}
+	if err := checker.Check(state.ResumeEligibilityRequest{NewRun: newRun, ResumeFromNodeID: checkpointNode, Graph: snap, Invalidation: invMap, NodeLevel: true}); err != nil {
+		if inv.ExecutionMode == ExecutionModeResumeOnly {
+			rc.abort(ExitConfigError, &state.ExecutionFailureError{NodeID: "", Code: "ResumeIneligible", Message: err.Error(), Cause: err})
+			return err
+		}
+		return nil
+	}
+	rc.resumePlan = plan
+	rc.previousRunID = candidatePrevPtr
+	rc.retryCount = candidateRetry
+	rc.Result.Resume = newResumeReport(prevID, graphChanged, rc.Graph, plan, reasons)
+	return nil
+}
+
+// stageStart records the run as started, and the executor that runs it.
+// From here on a panic fails the run instead of the process.
+func stageStart(ctx context.Context, rc *RunContext, next Next) (execErr error) {
+	inv := rc.Invocation
+	res := rc.Result
+	// Record the run metadata now that we know GraphHash and any run linkage.
+	if rc.RunID != "" {
+		if err := rc.Recorder.StartRun(state.Run{RunID: rc.RunID, GraphHash: rc.GraphHash, StartTime: time.Now().UTC(), Mode: state.ExecutionMode(inv.ExecutionMode), RetryCount: rc.retryCount, Status: "running", PreviousRunID: rc.previousRunID}); errors.Is(err, state.ErrRunExists) {
+			// Never write into another run's records.
+			rc.RunID = ""
+			res.RunID = ""
+			res.ExitCode = ExitInternalError
+			return err
+		}
+		saveRunEnv(rc.Store, rc.RunID, captureEnv(rc.Graph))
+		saveRunParams(rc.Store, rc.RunID, newRunParams(inv))
+		saveRunSnapshot(rc.Store, rc.RunID, rc.Graph)
+		saveRunPlan(rc.Store, rc.RunID, newRunPlan(rc.Graph, rc.resumePlan, rc.skip))
+		if inv.ExecutionMode != ExecutionModeClean && rc.scenario == nil {
+			saveRunCacheDir(rc.Store, rc.RunID, inv.CacheDir)
+		}
+		if res.Resume != nil {
+			// Best-effort: the reuse decisions are kept with the run record.
+			if data, rerr := marshalResumeReport(res.Resume); rerr == nil {
+				_ = rc.Store.SaveRunFile(rc.RunID, ResumeFileName, data)
+			}
+		}
+	}
+	traceSink := rc.traceWriter.openJournal(rc.Store, rc.RunID)
+
+	defer func() {
+		if r := recover(); r != nil {
+			res.ExitCode = ExitInternalError
+			res.GraphResult = nil
+			execErr = fmt.Errorf("panic: %v", r)
+			if rc.RunID != "" {
+				_ = rc.Recorder.RecordFailure(rc.RunID, &state.SystemFailureError{Code: "Panic", Message: fmt.Sprintf("panic: %v", r), Cause: execErr})
+			}
+		}
+	}()
+
+	// If the caller provided the default executor, always run through the CLI-owned executor
+	// so we can attach checkpoint observer (even when resume is not possible).
+	rc.Executor = rc.executor
+	if _, ok := rc.executor.(defaultGraphExecutor); ok {
+		rc.Executor = cliGraphExecutor{Plan: rc.resumePlan, Observer: rc.observer, Deduplicate: inv.Deduplicate, Results: resultStoreFor(rc.Graph), Hooks: rc.Hooks, LabelLimits: rc.Config.LabelLimits, Skip: rc.skip, SkipPolicy: inv.SkipPolicy, Workers: inv.Workers, Replan: rc.isolated == nil, TraceSink: traceSink}
+	}
+
+	if ce, ok := rc.Executor.(cliGraphExecutor); ok && inv.ProgressPath != "" {
+		pw, perr := createProgressWriter(inv.ProgressPath)
+		if perr != nil {
+			rc.fail(ExitInvalidInvocation, &state.WorkspaceFailureError{Code: "ProgressFile", Message: perr.Error(), Cause: perr})
+			return perr
+		}
+		defer pw.Close()
+		ce.Progress = pw.write
+		rc.Executor = ce
+	}
+	return next(ctx)
+}
+
+// stageExecute runs the graph and translates the engine outcome to an exit
+// code.
+func stageExecute(ctx context.Context, rc *RunContext, next Next) error {
+	inv := rc.Invocation
+	res := rc.Result
+	var taskRunner dag.TaskRunner = rc.cacheRunner
+	if rc.scenario != nil {
+		taskRunner = dag.NewSimulatedRunner(rc.scenario)
+	} else if inv.Offline {
+		taskRunner = offlineRunner{inner: taskRunner}
+	}
+	if rc.injector != nil {
+		taskRunner = rc.injector.Runner(taskRunner)
+	}
+	rc.timed = newTimingRunner(taskRunner)
+	rc.phases.mark(PhasePlan)
+	rc.startedAt = time.Now().UTC()
+	gr, err := rc.Executor.Run(ctx, rc.Graph, rc.timed)
+	rc.gr = gr
+	res.Duration = time.Since(rc.startedAt)
+	rc.phases.mark(PhaseExecute)
+	if rc.RunID != "" && gr != nil {
+		// Recorded before any outcome handling so cancelled runs keep theirs.
+		saveTimeline(rc.Store, buildTimeline(rc.RunID, rc.Graph, gr, rc.timed.spans(), rc.startedAt))
+		rc.saveAttempts(gr)
+	}
+	if rc.injector != nil {
+		res.Chaos = &ChaosReport{Config: *inv.Chaos, Faults: rc.injector.Faults()}
+		if rc.RunID != "" {
+			saveChaosReport(rc.Store, rc.RunID, res.Chaos)
+		}
+	}
+	if cerr := ctx.Err(); cerr != nil {
+		res.GraphResult = gr
+		rc.fail(ExitCancelled, &state.SystemFailureError{Code: "Cancelled", Message: cerr.Error(), Cause: cerr})
+		return cerr
+	}
+	res.NodeDurations = rc.timed.durations()
+	if rc.stats != nil {
+		cs := rc.stats.Stats()
+		res.CacheStats = &cs
+		if gr != nil {
+			res.CacheDecisions = buildCacheDecisions(rc.Graph, gr, rc.stats, rc.cacheTiers)
+		}
+		if rc.RunID != "" {
+			saveCacheStats(rc.Store, rc.RunID, cs)
+			if res.CacheDecisions != nil {
+				saveCacheDecisions(rc.Store, rc.RunID, res.CacheDecisions)
+			}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, core.ErrCacheCorruption) {
+			// The corrupt entry has already been evicted; a rerun re-executes the task.
+			rc.fail(ExitConfigError, &state.WorkspaceFailureError{Code: "CacheCorruption", Message: err.Error(), Cause: err})
+			return err
+		}
+		if errors.Is(err, ErrOffline) {
+			rc.fail(ExitOffline, &state.WorkspaceFailureError{Code: "OfflineUnavailable", Message: err.Error(), Cause: err})
+			return err
+		}
+		rc.fail(ExitInternalError, &state.SystemFailureError{Code: "EngineError", Message: err.Error(), Kind: classifyEngineError(err), Cause: err})
+		return err
+	}
+	res.GraphResult = gr
+	res.ExitCode = translateGraphResultToExitCode(gr, rc.tolerated)
+	if res.Resume != nil && len(gr.Replanned) > 0 {
+		// Nodes whose checkpoint could not be restored were executed instead.
+		res.Resume.replan(rc.Graph, gr.Replanned)
+		if rc.RunID != "" {
+			if data, rerr := marshalResumeReport(res.Resume); rerr == nil {
+				_ = rc.Store.SaveRunFile(rc.RunID, ResumeFileName, data)
+			}
+		}
+	}
+	if rc.isolated != nil {
+		if err := checkIsolatedUpstream(gr, inv.Node); err != nil {
+			rc.fail(ExitConfigError, &state.WorkspaceFailureError{Code: "UpstreamNotCached", Message: err.Error(), Cause: err})
+			return err
+		}
+	}
+	return next(ctx)
+}
+
+// stageOutputs lists, verifies and publishes the outputs of the run, and
+// records the failures of its nodes.
+func stageOutputs(ctx context.Context, rc *RunContext, next Next) error {
+	inv := rc.Invocation
+	res := rc.Result
+	st := rc.Store
+	runID := rc.RunID
+	gr := rc.gr
+	var provenanceJSON []byte
+	// Best-effort: the output listing and provenance describe the outputs as
+	// the run left them. A simulation produced none to list, verify or publish.
+	if rc.scenario == nil {
+		if outputs, oerr := harvestOutputs(inv.WorkDir, rc.Graph, gr); oerr == nil {
+			res.Outputs = outputs
+			if st != nil {
+				_ = recordOutputOwnership(st, outputs)
+			}
+			if lockFetches(rc.lockfile, rc.Graph, outputs) {
+				_ = SaveLockfile(inv.WorkDir, rc.lockfile)
+			}
+			if runID != "" {
+				if data, merr := MarshalOutputs(outputs); merr == nil {
+					_ = st.SaveRunFile(runID, OutputsFileName, data)
+				}
+				provenanceJSON, _ = writeProvenance(st, buildProvenance(runID, inv.ExecutionMode, rc.Graph, gr, outputs, rc.startedAt, time.Now().UTC()), inv.WorkDir)
+			}
+		}
+	}
+	if res.ExitCode == ExitSuccess && inv.VerifyDeterminism > 0 && rc.scenario == nil {
+		nondet, err := verifyDeterminism(ctx, rc.Graph, rc.Runner, gr, inv.VerifyDeterminism)
+		if err != nil {
+			rc.fail(ExitInternalError, &state.SystemFailureError{Code: "DeterminismCheck", Message: err.Error(), Cause: err})
+			return err
+		}
+		res.Nondeterministic = nondet
+		if len(nondet) > 0 {
+			rc.fail(ExitGraphFailure, &state.ExecutionFailureError{NodeID: nondet[0].Name, Code: "Nondeterministic", Message: fmt.Sprintf("node %s is nondeterministic: %s", nondet[0].Name, strings.Join(nondet[0].Differences, ", "))})
+			return nil
+		}
+	}
+	if res.ExitCode == ExitSuccess && rc.Config.Publish != nil && rc.scenario == nil {
+		dest := rc.Config.Publish.Destination
+		if !filepath.IsAbs(dest) {
+			dest = filepath.Join(inv.WorkDir, dest)
+		}
+		pr, err := publish.Publish(inv.WorkDir, rc.Config.Publish.Outputs, provenanceJSON, publish.DirSink{Dir: dest})
+		if err != nil {
+			rc.fail(ExitInternalError, &state.SystemFailureError{Code: "Publish", Message: err.Error(), Cause: err})
+			return fmt.Errorf("publish: %w", err)
+		}
+		res.PublishedTo = dest
+		res.Published = pr.Files
+	}
+	if res.ExitCode == ExitGraphFailure && runID != "" {
+		res.NodeFailures = nodeFailures(rc.Graph, gr, res.NodeDurations, rc.retryCount)
+		for _, nf := range res.NodeFailures {
+			_ = st.SaveNodeFailure(runID, nf)
+			if ev, ok := rc.Cache.(core.CacheEvicter); ok && nf.Kind.Transient() && rc.scenario == nil {
+				// A transient failure must not be replayed from the cache by a retry.
+				_ = ev.Evict(gr.TaskHashOf(nf.NodeID))
+			}
+		}
+		// Deterministically choose a representative failed node.
+		failedNodes := gr.FailedNodes()
+		failed := failedNodes[0]
+		code, _ := gr.ExitCodeOf(failed)
+		stderr, _ := gr.StderrOf(failed)
+		kind := state.ClassifyExit(code, stderr)
+		msg := fmt.Sprintf("node %s failed with exit code %d (%s)", failed, code, kind)
+		if cause, ok := gr.FailedBySkip[failed]; ok {
+			kind = state.FailureKindSkipPolicy
+			msg = fmt.Sprintf("node %s failed because upstream %s was skipped (%s)", failed, cause, kind)
+		}
+		if node, ok := rc.Graph.Node(failed); ok {
+			msg += annotation(node.Task.Metadata)
+		}
+		skipped := skippedNodes(gr)
+		if len(failedNodes) > 1 || len(skipped) > 0 {
+			msg += fmt.Sprintf("; %d failed, %d skipped", len(failedNodes), len(skipped))
+		}
+		_ = rc.Recorder.RecordFailure(runID, &state.ExecutionFailureError{NodeID: failed, Code: "NodeFailed", Message: msg, Kind: kind, FailedNodes: failedNodes, SkippedNodes: skipped})
+	}
+	return next(ctx)
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestPipeline_InsertedStageWrapsLaterStages(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "in.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Inputs: []string{"in.txt"}, Run: "cat in.txt > a.txt", Outputs: []string{"a.txt"}}}, nil)
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+
+	var seen []string
+	p, err := DefaultPipeline().InsertBefore(StageExecute, Stage{Name: "metrics", Run: func(ctx context.Context, rc *RunContext, next Next) error {
+		if rc.Graph == nil || rc.Executor == nil || rc.RunID == "" {
+			t.Errorf("stage ran before the run was prepared: %+v", rc)
+		}
+		seen = append(seen, "before")
+		err := next(ctx)
+		if rc.Result.ExitCode == ExitSuccess && rc.Result.GraphResult != nil {
+			seen = append(seen, "after")
+		}
+		return err
+	}})
+	if err != nil {
+		t.Fatalf("InsertBefore: %v", err)
+	}
+	res, err := p.Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("run: exit=%d err=%v", res.ExitCode, err)
+	}
+	if want := []string{"before", "after"}; !reflect.DeepEqual(seen, want) {
+		t.Fatalf("stage saw %v, want %v", seen, want)
+	}
+
+	if _, err := DefaultPipeline().InsertBefore("nope", Stage{Name: "x"}); err == nil {
+		t.Fatalf("expected an error for an unknown stage")
+	}
+}
+
+func TestPipeline_StageFailureStopsTheRun(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Inputs: []string{}, Run: "touch ran.txt", Outputs: []string{"ran.txt"}}}, nil)
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+	}
+
+	denied := errors.New("denied by policy")
+	p, err := DefaultPipeline().InsertBefore(StageStart, Stage{Name: "policy", Run: func(ctx context.Context, rc *RunContext, next Next) error {
+		rc.abort(ExitValidationError, &state.GraphFailureError{Code: "PolicyDenied", Message: denied.Error(), Cause: denied})
+		return denied
+	}})
+	if err != nil {
+		t.Fatalf("InsertBefore: %v", err)
+	}
+	res, err := p.Execute(context.Background(), inv)
+	if !errors.Is(err, denied) || res.ExitCode != ExitValidationError {
+		t.Fatalf("run: exit=%d err=%v", res.ExitCode, err)
+	}
+	if _, serr := os.Stat(filepath.Join(workDir, "ran.txt")); !os.IsNotExist(serr) {
+		t.Fatalf("task ran despite the failed stage")
+	}
+	if res.Failure == nil || res.Failure.ErrorCode != "PolicyDenied" {
+		t.Fatalf("failure = %+v", res.Failure)
+	}
+}
@@ -2,10 +2,14 @@ package cli
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"scriptweaver/internal/core"
 	"scriptweaver/internal/dag"
 	"scriptweaver/internal/pluginengine"
 )
@@ -62,3 +66,237 @@ func TestExecute_DiscoversPluginsFromWorkDirDefaultRoot(t *testing.T) {
 		t.Fatalf("discoverPlugins root = %q, want %q", gotRoot, wantRoot)
 	}
 }
+
+type allowlistRecorder struct {
+	manifest pluginengine.PluginManifest
+	calls    *[]string
+}
+
+func (p *allowlistRecorder) Manifest() pluginengine.PluginManifest { return p.manifest }
+
+func (p *allowlistRecorder) BeforeNode(_ context.Context, taskID string) error {
+	*p.calls = append(*p.calls, p.manifest.PluginID+":"+taskID)
+	return nil
+}
+
+func TestExecute_AllowlistedPluginHooksRun(t *testing.T) {
+	workDir := t.TempDir()
+	for _, id := range []string{"on", "off"} {
+		dir := filepath.Join(workDir, pluginengine.DefaultPluginsRoot, id)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		manifest := `{"plugin_id":"` + id + `","version":"1.0.0","hooks":["BeforeNode"],"description":""}`
+		if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0o644); err != nil {
+			t.Fatalf("write manifest: %v", err)
+		}
+	}
+	var calls []string
+	for _, id := range []string{"on", "off"} {
+		pluginengine.RegisterRuntime(id, func(m pluginengine.PluginManifest, _ string) (pluginengine.RuntimePlugin, error) {
+			return &allowlistRecorder{manifest: m, calls: &calls}, nil
+		})
+	}
+	t.Cleanup(func() {
+		pluginengine.RegisterRuntime("on", nil)
+		pluginengine.RegisterRuntime("off", nil)
+	})
+
+	graphPath := filepath.Join(workDir, "graph.json")
+	if err := os.WriteFile(graphPath, []byte(`{"tasks":[{"name":"t1","run":"true"}],"edges":[]}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	inv := CLIInvocation{
+		GraphPath:     graphPath,
+		WorkDir:       workDir,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+		Plugins:       []string{"on"},
+	}
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+	if len(calls) != 1 || calls[0] != "on:t1" {
+		t.Fatalf("calls = %v, want [on:t1]", calls)
+	}
+	if len(res.PluginStats) != 1 || res.PluginStats[0].PluginID != "on" || res.PluginStats[0].Invocations != 1 {
+		t.Fatalf("PluginStats = %#v", res.PluginStats)
+	}
+
+	inv.Plugins = []string{"unknown"}
+	res, err = Execute(context.Background(), inv)
+	if err == nil || res.ExitCode != ExitPluginError {
+		t.Fatalf("expected plugin error, exit=%d err=%v", res.ExitCode, err)
+	}
+}
+
+type artifactReporter struct{}
+
+func (artifactReporter) Manifest() pluginengine.PluginManifest {
+	return pluginengine.PluginManifest{PluginID: "summary", Version: "1.0.0", Hooks: []string{pluginengine.ReportHook}}
+}
+
+func (artifactReporter) Report(_ context.Context, r pluginengine.Report) error {
+	return os.WriteFile(filepath.Join(r.Dir, "summary.txt"), []byte(fmt.Sprintf("exit=%d events=%d", r.ExitCode, len(r.Events))), 0o644)
+}
+
+func TestExecute_ReporterPluginWritesArtifactAfterTraceFinalized(t *testing.T) {
+	workDir := t.TempDir()
+	dir := filepath.Join(workDir, pluginengine.DefaultPluginsRoot, "summary")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"plugin_id":"summary","version":"1.0.0","hooks":["Report"],"description":""}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	pluginengine.RegisterRuntime("summary", func(pluginengine.PluginManifest, string) (pluginengine.RuntimePlugin, error) {
+		return artifactReporter{}, nil
+	})
+	t.Cleanup(func() { pluginengine.RegisterRuntime("summary", nil) })
+
+	graphPath := filepath.Join(workDir, "graph.json")
+	if err := os.WriteFile(graphPath, []byte(`{"tasks":[{"name":"t1","run":"true"},{"name":"t2","run":"exit 1"}],"edges":[]}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	outDir := filepath.Join(workDir, "out")
+	inv := CLIInvocation{
+		GraphPath:     graphPath,
+		WorkDir:       workDir,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     outDir,
+		ExecutionMode: ExecutionModeClean,
+		Trace:         TraceConfig{Enabled: true, Path: filepath.Join(workDir, "trace.json")},
+		Plugins:       []string{"summary"},
+	}
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitGraphFailure {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+	b, err := os.ReadFile(filepath.Join(outDir, "reports", "summary", "summary.txt"))
+	if err != nil {
+		t.Fatalf("read artifact: %v", err)
+	}
+	if string(b) != "exit=3 events=3" {
+		t.Fatalf("artifact = %q", b)
+	}
+}
+
+type noEchoValidator struct{}
+
+func (noEchoValidator) Manifest() pluginengine.PluginManifest {
+	return pluginengine.PluginManifest{PluginID: "lint", Version: "1.0.0", Hooks: []string{pluginengine.ValidateHook}}
+}
+
+func (noEchoValidator) Validate(_ context.Context, g *dag.TaskGraph) ([]pluginengine.Finding, error) {
+	var out []pluginengine.Finding
+	for _, n := range g.Nodes() {
+		if strings.HasPrefix(n.Task.Run, "echo") {
+			out = append(out, pluginengine.Finding{Code: "NO_ECHO", Node: n.Name, Message: "echo is not allowed"})
+		}
+	}
+	return out, nil
+}
+
+func TestExecute_ValidatorPluginRejectsGraphBeforeRun(t *testing.T) {
+	workDir := t.TempDir()
+	dir := filepath.Join(workDir, pluginengine.DefaultPluginsRoot, "lint")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"plugin_id":"lint","version":"1.0.0","hooks":["Validate"],"description":""}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	pluginengine.RegisterRuntime("lint", func(pluginengine.PluginManifest, string) (pluginengine.RuntimePlugin, error) {
+		return noEchoValidator{}, nil
+	})
+	t.Cleanup(func() { pluginengine.RegisterRuntime("lint", nil) })
+
+	graphPath := filepath.Join(workDir, "graph.json")
+	if err := os.WriteFile(graphPath, []byte(`{"tasks":[{"name":"t1","run":"echo hi > out.txt"}],"edges":[]}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	inv := CLIInvocation{
+		GraphPath:     graphPath,
+		WorkDir:       workDir,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+	}
+
+	// Not allowlisted: the rule does not apply.
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+
+	inv.Plugins = []string{"lint"}
+	res, err = Execute(context.Background(), inv)
+	var fe *PluginFindingsError
+	if !errors.As(err, &fe) || res.ExitCode != ExitValidationError {
+		t.Fatalf("expected plugin findings, exit=%d err=%v", res.ExitCode, err)
+	}
+	if len(res.Findings) != 1 || res.Findings[0].String() != "lint/NO_ECHO t1: echo is not allowed" {
+		t.Fatalf("findings = %v", res.Findings)
+	}
+	if res.GraphResult != nil {
+		t.Fatalf("graph ran despite findings")
+	}
+}
+
+type echoRunner struct{}
+
+func (echoRunner) Manifest() pluginengine.PluginManifest {
+	return pluginengine.PluginManifest{PluginID: "remote", Version: "1.0.0", Runners: []string{"lambda"}}
+}
+
+func (echoRunner) RunTask(_ context.Context, runner string, task core.Task, workDir string) (*core.ExecutionResult, error) {
+	if err := os.WriteFile(filepath.Join(workDir, "out.txt"), []byte(runner+" ran "+task.Name), 0o644); err != nil {
+		return nil, err
+	}
+	return &core.ExecutionResult{ExitCode: 0}, nil
+}
+
+func TestExecute_NodeSelectsPluginRunner(t *testing.T) {
+	workDir := t.TempDir()
+	dir := filepath.Join(workDir, pluginengine.DefaultPluginsRoot, "remote")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"plugin_id":"remote","version":"1.0.0","runners":["lambda"],"description":""}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	pluginengine.RegisterRuntime("remote", func(pluginengine.PluginManifest, string) (pluginengine.RuntimePlugin, error) {
+		return echoRunner{}, nil
+	})
+	t.Cleanup(func() { pluginengine.RegisterRuntime("remote", nil) })
+
+	graphPath := filepath.Join(workDir, "graph.json")
+	if err := os.WriteFile(graphPath, []byte(`{"tasks":[{"name":"t1","run":"false","runner":"lambda","outputs":["out.txt"]}],"edges":[]}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	inv := CLIInvocation{
+		GraphPath:     graphPath,
+		WorkDir:       workDir,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+	}
+
+	// No plugin provides the runner unless allowlisted.
+	res, err := Execute(context.Background(), inv)
+	if !errors.Is(err, pluginengine.ErrUnknownRunner) || res.ExitCode != ExitValidationError {
+		t.Fatalf("expected unknown runner, exit=%d err=%v", res.ExitCode, err)
+	}
+
+	inv.Plugins = []string{"remote"}
+	res, err = Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+	b, err := os.ReadFile(filepath.Join(workDir, "out.txt"))
+	if err != nil || string(b) != "lambda ran t1" {
+		t.Fatalf("out.txt = %q err=%v", b, err)
+	}
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+	"scriptweaver/internal/projectintegration/engine/config"
+)
+
+// Policy rules, as reported in PolicyViolation.Rule.
+const (
+	PolicyRuleRunner  = "allowed_runners"
+	PolicyRuleCommand = "forbidden_commands"
+	PolicyRuleTimeout = "require_timeout"
+	PolicyRuleOwner   = "require_owner"
+)
+
+// PolicyViolation is a task that breaks a rule of the workspace policy.
+type PolicyViolation struct {
+	Node    string
+	Rule    string
+	Message string
+}
+
+func (v PolicyViolation) String() string {
+	return fmt.Sprintf("task %q: %s", v.Node, v.Message)
+}
+
+// PolicyViolations returns the violations of p by the tasks of g, sorted by
+// node and rule. A nil policy is never violated.
+func PolicyViolations(g *dag.TaskGraph, p *config.Policy) []PolicyViolation {
+	if p == nil {
+		return nil
+	}
+	var out []PolicyViolation
+	for _, n := range g.Nodes() {
+		t := n.Task
+		if t.Fetch == nil {
+			runner := t.Runner
+			if runner == "" {
+				runner = config.LocalRunner
+			}
+			if p.AllowedRunners != nil && !slices.Contains(p.AllowedRunners, runner) {
+				out = append(out, PolicyViolation{Node: t.Name, Rule: PolicyRuleRunner, Message: fmt.Sprintf("runner %q is not allowed by the workspace policy", runner)})
+			}
+			for _, re := range p.ForbiddenCommands {
+				if re.MatchString(t.Run) {
+					out = append(out, PolicyViolation{Node: t.Name, Rule: PolicyRuleCommand, Message: fmt.Sprintf("command matches forbidden pattern %q", re.String())})
+				}
+			}
+			if p.RequireTimeout && t.TimeoutSeconds == 0 {
+				out = append(out, PolicyViolation{Node: t.Name, Rule: PolicyRuleTimeout, Message: "the workspace policy requires timeout_seconds"})
+			}
+		}
+		if p.RequireOwner && (t.Metadata == nil || strings.TrimSpace(t.Metadata.Owner) == "") {
+			out = append(out, PolicyViolation{Node: t.Name, Rule: PolicyRuleOwner, Message: "the workspace policy requires metadata.owner"})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Node != out[j].Node {
+			return out[i].Node < out[j].Node
+		}
+		return out[i].Rule < out[j].Rule
+	})
+	return out
+}
+
+// policyError reports violations as a semantic error of the graph, or
+// returns nil when there are none.
+func policyError(violations []PolicyViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(violations))
+	for _, v := range violations {
+		lines = append(lines, v.String())
+	}
+	return &graph.SemanticError{Msg: fmt.Sprintf("workspace policy violated (%d violations): %s", len(violations), strings.Join(lines, "; "))}
+}
+
+// CheckPolicy loads the policy of the workspace at workDir and returns the
+// violations of it by g. The error wraps config.ErrInvalidPolicy when the
+// policy file is invalid.
+func CheckPolicy(workDir string, g *dag.TaskGraph) ([]PolicyViolation, error) {
+	p, err := config.LoadPolicy(workDir)
+	if err != nil {
+		return nil, err
+	}
+	return PolicyViolations(g, p), nil
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/graph"
+)
+
+func TestExecute_EnforcesWorkspacePolicy(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	policy := `{"allowed_runners":["local"],"forbidden_commands":["\\bsudo\\b"],"require_timeout":true}`
+	if err := os.WriteFile(filepath.Join(workDir, ".scriptweaver", "policy.json"), []byte(policy), 0o644); err != nil {
+		t.Fatalf("write policy: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	run := func(tasks ...core.Task) (CLIResult, error) {
+		t.Helper()
+		writeGraphJSON(t, graphPath, tasks, nil)
+		return Execute(context.Background(), CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean})
+	}
+
+	res, err := run(
+		core.Task{Name: "a", Run: "touch a.txt", Outputs: []string{"a.txt"}},
+		core.Task{Name: "b", Run: "touch b.txt && sudo -n true", Outputs: []string{"b.txt"}, TimeoutSeconds: 5},
+	)
+	if !errors.Is(err, graph.ErrSemantic) || res.ExitCode != ExitValidationError {
+		t.Fatalf("exit %d, err %v; want a semantic validation error", res.ExitCode, err)
+	}
+	for _, out := range []string{"a.txt", "b.txt"} {
+		if _, statErr := os.Stat(filepath.Join(workDir, out)); !os.IsNotExist(statErr) {
+			t.Fatal("a task ran despite the policy violations")
+		}
+	}
+	want := `semantic error: workspace policy violated (2 violations): task "a": the workspace policy requires timeout_seconds; task "b": command matches forbidden pattern "\\bsudo\\b"`
+	if err.Error() != want {
+		t.Fatalf("error = %q\nwant    %q", err, want)
+	}
+
+	if res, err := run(core.Task{Name: "a", Run: "touch a.txt", Outputs: []string{"a.txt"}, TimeoutSeconds: 5}); err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("compliant graph: exit %d, %v", res.ExitCode, err)
+	}
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"scriptweaver/internal/dag"
+)
+
+// progressWriter appends the aggregate progress events of a run to a file as
+// JSON Lines, one dag.Progress per line, for external dashboards to follow.
+// Write errors are ignored: progress reporting never fails a run.
+type progressWriter struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func createProgressWriter(path string) (*progressWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, invalidInvocationf("create progress file: %v", err)
+	}
+	return &progressWriter{f: f}, nil
+}
+
+func (w *progressWriter) write(p dag.Progress) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = fmt.Fprintf(w.f, "%s\n", data)
+}
+
+func (w *progressWriter) Close() error {
+	return w.f.Close()
+}
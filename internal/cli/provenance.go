@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"time"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/provenance"
+	"scriptweaver/internal/recovery/state"
+)
+
+// buildProvenance assembles the provenance document for a finished run.
+//
+// Output hashes are taken from outputs, harvested from the workspace after
+// execution, so they describe exactly the artifacts downstream consumers will
+// see.
+func buildProvenance(runID string, mode ExecutionMode, g *dag.TaskGraph, gr *dag.GraphResult, outputs []NodeOutputs, start, finish time.Time) *provenance.Document {
+	doc := &provenance.Document{
+		RunID:      runID,
+		GraphHash:  gr.GraphHash.String(),
+		Mode:       string(mode),
+		StartTime:  start.UTC(),
+		FinishTime: finish.UTC(),
+		Toolchain:  provenance.CurrentToolchain(),
+		Nodes:      make([]provenance.Node, 0, len(gr.FinalState)),
+	}
+
+	files := make(map[string][]OutputFile, len(outputs))
+	for _, no := range outputs {
+		files[no.Node] = no.Files
+	}
+	for _, name := range g.TopologicalOrder() {
+		st := gr.FinalState[name]
+		node := provenance.Node{
+			Name:      name,
+			TaskHash:  gr.TaskHashOf(name).String(),
+			State:     string(st),
+			FromCache: st == dag.TaskCached,
+			Outputs:   []provenance.Output{},
+		}
+		if st == dag.TaskCached {
+			doc.CacheHits++
+		}
+		for _, f := range files[name] {
+			node.Outputs = append(node.Outputs, provenance.Output{Path: f.Path, SHA256: f.SHA256})
+		}
+		doc.Nodes = append(doc.Nodes, node)
+	}
+	return doc
+}
+
+// writeProvenance signs the run's provenance with the workspace key, stores it
+// as <run-dir>/provenance.json and returns the stored bytes.
+func writeProvenance(st *state.Store, doc *provenance.Document, workDir string) ([]byte, error) {
+	key, err := provenance.LoadOrCreateKey(workDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Sign(key); err != nil {
+		return nil, err
+	}
+	data, err := doc.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	if err := st.SaveRunFile(doc.RunID, provenance.FileName, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
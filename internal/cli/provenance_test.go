@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"scriptweaver/internal/provenance"
+)
+
+func TestExecute_WritesSignedProvenanceIntoRunDir(t *testing.T) {
+	work := t.TempDir()
+	inv := CLIInvocation{
+		GraphPath:     filepath.Join(work, "graph.json"),
+		WorkDir:       work,
+		CacheDir:      filepath.Join(work, "cache"),
+		OutputDir:     filepath.Join(work, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+	graphJSON := `{"tasks":[{"name":"A","inputs":[],"run":"printf hi > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`
+	if err := os.WriteFile(inv.GraphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("WriteFile graph: %v", err)
+	}
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("Execute: exit=%d err=%v", res.ExitCode, err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(work, ".scriptweaver", "runs", "*", provenance.FileName))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one provenance file, got %v (err=%v)", matches, err)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("read provenance: %v", err)
+	}
+	doc, err := provenance.Parse(data)
+	if err != nil {
+		t.Fatalf("parse provenance: %v", err)
+	}
+	if err := doc.Verify(); err != nil {
+		t.Fatalf("verify provenance: %v", err)
+	}
+	if doc.GraphHash != res.GraphResult.GraphHash.String() || len(doc.Nodes) != 1 {
+		t.Fatalf("unexpected provenance: %+v", doc)
+	}
+	// sha256("hi")
+	const want = "8f434346648f6b96df89dda901c5176b10a6d83961dd3c1ac88b59b2dc327aa4"
+	if outs := doc.Nodes[0].Outputs; len(outs) != 1 || outs[0].Path != "a.txt" || outs[0].SHA256 != want {
+		t.Fatalf("unexpected outputs: %+v", doc.Nodes[0].Outputs)
+	}
+}
@@ -0,0 +1,388 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"scriptweaver/internal/dag"
+)
+
+// ErrInvalidSelector is returned when a node selector does not parse.
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Selector selects nodes of a graph. It is parsed from the small selector
+// language shared by every command that targets a subset of a graph:
+//
+//	name=<glob>     nodes whose name matches the path.Match pattern
+//	type=<type>     nodes of a type: fetch, exec (the built-in local
+//	                executor) or the name of a plugin runner
+//	label=<label>   nodes carrying the label
+//	owner=<owner>   nodes whose metadata names the owner
+//	root, leaf      nodes without dependencies, or without dependents
+//	all             every node
+//	<node>          the named node, which must exist
+//	deps(s)         the nodes the nodes of s depend on, transitively
+//	rdeps(s)        the nodes depending on the nodes of s, transitively
+//	!s, s & t, s | t, (s)
+//
+// & binds tighter than |, and ! tighter than both.
+type Selector struct {
+	expr string
+	root selectorNode
+}
+
+type selectorNode interface {
+	eval(q *queryGraph) (map[string]bool, error)
+}
+
+// ParseSelector parses expr.
+func ParseSelector(expr string) (Selector, error) {
+	p := &selectorParser{expr: expr}
+	p.next()
+	root, err := p.union()
+	if err == nil && p.tok != "" {
+		err = p.errorf("unexpected %q", p.tok)
+	}
+	if err != nil {
+		return Selector{}, err
+	}
+	return Selector{expr: expr, root: root}, nil
+}
+
+// String returns the expression s was parsed from.
+func (s Selector) String() string { return s.expr }
+
+// Select returns the names of the nodes of g that s selects, sorted. A node
+// named explicitly that g does not contain is an ErrUnknownNode.
+func (s Selector) Select(g *dag.TaskGraph) ([]string, error) {
+	set, err := s.root.eval(newQueryGraph(g))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(set))
+	for name := range set {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// selectNodes returns the names of the nodes of g that any of exprs selects,
+// sorted.
+func selectNodes(g *dag.TaskGraph, exprs []string) ([]string, error) {
+	set := make(map[string]bool)
+	for _, expr := range exprs {
+		names, err := QueryNodes(g, expr)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			set[name] = true
+		}
+	}
+	out := make([]string, 0, len(set))
+	for name := range set {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// QueryNodes parses expr and returns the names of the nodes of g it selects.
+func QueryNodes(g *dag.TaskGraph, expr string) ([]string, error) {
+	s, err := ParseSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+	return s.Select(g)
+}
+
+// queryGraph indexes a graph for evaluating selectors.
+type queryGraph struct {
+	g     *dag.TaskGraph
+	nodes []*dag.TaskNode
+	deps  map[string][]string
+	rdeps map[string][]string
+}
+
+func newQueryGraph(g *dag.TaskGraph) *queryGraph {
+	q := &queryGraph{g: g, nodes: g.Nodes(), deps: make(map[string][]string), rdeps: make(map[string][]string)}
+	for _, e := range g.Edges() {
+		q.deps[e.To] = append(q.deps[e.To], e.From)
+		q.rdeps[e.From] = append(q.rdeps[e.From], e.To)
+	}
+	return q
+}
+
+func (q *queryGraph) filter(keep func(n *dag.TaskNode) bool) map[string]bool {
+	out := make(map[string]bool)
+	for _, n := range q.nodes {
+		if keep(n) {
+			out[n.Name] = true
+		}
+	}
+	return out
+}
+
+// closure returns the nodes reachable from start through adj, excluding the
+// nodes of start not reachable from another one.
+func closure(start map[string]bool, adj map[string][]string) map[string]bool {
+	out := make(map[string]bool)
+	var stack []string
+	for name := range start {
+		stack = append(stack, adj[name]...)
+	}
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if out[name] {
+			continue
+		}
+		out[name] = true
+		stack = append(stack, adj[name]...)
+	}
+	return out
+}
+
+func nodeType(n *dag.TaskNode) string {
+	switch {
+	case n.Task.Fetch != nil:
+		return "fetch"
+	case n.Task.Runner != "":
+		return n.Task.Runner
+	default:
+		return "exec"
+	}
+}
+
+type matchSelector struct{ key, value string }
+
+func (s matchSelector) eval(q *queryGraph) (map[string]bool, error) {
+	switch s.key {
+	case "name":
+		return q.filter(func(n *dag.TaskNode) bool {
+			ok, _ := path.Match(s.value, n.Name)
+			return ok
+		}), nil
+	case "type":
+		return q.filter(func(n *dag.TaskNode) bool { return nodeType(n) == s.value }), nil
+	case "label":
+		return q.filter(func(n *dag.TaskNode) bool {
+			for _, l := range n.Task.Labels {
+				if l == s.value {
+					return true
+				}
+			}
+			return false
+		}), nil
+	default: // "owner"
+		return q.filter(func(n *dag.TaskNode) bool {
+			return n.Task.Metadata != nil && n.Task.Metadata.Owner == s.value
+		}), nil
+	}
+}
+
+type keywordSelector string
+
+func (s keywordSelector) eval(q *queryGraph) (map[string]bool, error) {
+	return q.filter(func(n *dag.TaskNode) bool {
+		switch s {
+		case "root":
+			return len(q.deps[n.Name]) == 0
+		case "leaf":
+			return len(q.rdeps[n.Name]) == 0
+		default: // "all"
+			return true
+		}
+	}), nil
+}
+
+type nodeSelector string
+
+func (s nodeSelector) eval(q *queryGraph) (map[string]bool, error) {
+	if _, ok := q.g.Node(string(s)); !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownNode, string(s))
+	}
+	return map[string]bool{string(s): true}, nil
+}
+
+type funcSelector struct {
+	name string
+	arg  selectorNode
+}
+
+func (s funcSelector) eval(q *queryGraph) (map[string]bool, error) {
+	arg, err := s.arg.eval(q)
+	if err != nil {
+		return nil, err
+	}
+	if s.name == "deps" {
+		return closure(arg, q.deps), nil
+	}
+	return closure(arg, q.rdeps), nil
+}
+
+type notSelector struct{ arg selectorNode }
+
+func (s notSelector) eval(q *queryGraph) (map[string]bool, error) {
+	arg, err := s.arg.eval(q)
+	if err != nil {
+		return nil, err
+	}
+	return q.filter(func(n *dag.TaskNode) bool { return !arg[n.Name] }), nil
+}
+
+type binarySelector struct {
+	op          string
+	left, right selectorNode
+}
+
+func (s binarySelector) eval(q *queryGraph) (map[string]bool, error) {
+	left, err := s.left.eval(q)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.right.eval(q)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]bool)
+	for name := range left {
+		if s.op == "|" || right[name] {
+			out[name] = true
+		}
+	}
+	if s.op == "|" {
+		for name := range right {
+			out[name] = true
+		}
+	}
+	return out, nil
+}
+
+// selectorParser is a recursive descent parser over the tokens of expr:
+// the operators ( ) & | ! = and words of any other non-space characters.
+type selectorParser struct {
+	expr string
+	pos  int
+	tok  string
+}
+
+const selectorOperators = "()&|!="
+
+func (p *selectorParser) next() {
+	for p.pos < len(p.expr) && unicode.IsSpace(rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos < len(p.expr) && strings.IndexByte(selectorOperators, p.expr[p.pos]) >= 0 {
+		p.pos++
+	} else {
+		for p.pos < len(p.expr) && !unicode.IsSpace(rune(p.expr[p.pos])) && strings.IndexByte(selectorOperators, p.expr[p.pos]) < 0 {
+			p.pos++
+		}
+	}
+	p.tok = p.expr[start:p.pos]
+}
+
+func (p *selectorParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w %q: %s", ErrInvalidSelector, p.expr, fmt.Sprintf(format, args...))
+}
+
+func (p *selectorParser) expect(tok string) error {
+	if p.tok != tok {
+		if p.tok == "" {
+			return p.errorf("expected %q at end", tok)
+		}
+		return p.errorf("expected %q, found %q", tok, p.tok)
+	}
+	p.next()
+	return nil
+}
+
+func (p *selectorParser) union() (selectorNode, error) {
+	left, err := p.intersection()
+	for err == nil && p.tok == "|" {
+		p.next()
+		var right selectorNode
+		if right, err = p.intersection(); err == nil {
+			left = binarySelector{op: "|", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *selectorParser) intersection() (selectorNode, error) {
+	left, err := p.unary()
+	for err == nil && p.tok == "&" {
+		p.next()
+		var right selectorNode
+		if right, err = p.unary(); err == nil {
+			left = binarySelector{op: "&", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *selectorParser) unary() (selectorNode, error) {
+	switch p.tok {
+	case "!":
+		p.next()
+		arg, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notSelector{arg}, nil
+	case "(":
+		p.next()
+		s, err := p.union()
+		if err != nil {
+			return nil, err
+		}
+		return s, p.expect(")")
+	case "":
+		return nil, p.errorf("unexpected end")
+	}
+	if strings.IndexByte(selectorOperators, p.tok[0]) >= 0 {
+		return nil, p.errorf("unexpected %q", p.tok)
+	}
+
+	word := p.tok
+	p.next()
+	switch {
+	case p.tok == "=":
+		if word != "name" && word != "type" && word != "label" && word != "owner" {
+			return nil, p.errorf("unknown key %q (expected name, type, label or owner)", word)
+		}
+		p.next()
+		if p.tok == "" || strings.IndexByte(selectorOperators, p.tok[0]) >= 0 {
+			return nil, p.errorf("missing value for %s", word)
+		}
+		value := p.tok
+		p.next()
+		if word == "name" {
+			if _, err := path.Match(value, ""); err != nil {
+				return nil, p.errorf("name=%s: %v", value, err)
+			}
+		}
+		return matchSelector{key: word, value: value}, nil
+	case p.tok == "(":
+		if word != "deps" && word != "rdeps" {
+			return nil, p.errorf("unknown function %q (expected deps or rdeps)", word)
+		}
+		p.next()
+		arg, err := p.union()
+		if err != nil {
+			return nil, err
+		}
+		return funcSelector{name: word, arg: arg}, p.expect(")")
+	case word == "root" || word == "leaf" || word == "all":
+		return keywordSelector(word), nil
+	default:
+		return nodeSelector(word), nil
+	}
+}
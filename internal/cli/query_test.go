@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestQueryNodes(t *testing.T) {
+	// Graph:
+	//   src (fetch) -> build -> test -> publish
+	//   lint (runner "docker", label team:infra) -> publish
+	g, err := dag.NewTaskGraph([]core.Task{
+		{Name: "src", Fetch: &core.Fetch{URL: "https://example.com/src.tgz", SHA256: "0000000000000000000000000000000000000000000000000000000000000000"}, Outputs: []string{"src.tgz"}},
+		{Name: "build", Run: "make", Labels: []string{"team:infra"}},
+		{Name: "test", Run: "make test", Metadata: &core.TaskMetadata{Owner: "qa"}},
+		{Name: "lint", Run: "lint", Runner: "docker", Labels: []string{"team:infra"}},
+		{Name: "publish", Run: "publish"},
+	}, []dag.Edge{
+		{From: "src", To: "build"}, {From: "build", To: "test"}, {From: "test", To: "publish"}, {From: "lint", To: "publish"},
+	})
+	if err != nil {
+		t.Fatalf("graph: %v", err)
+	}
+
+	for expr, want := range map[string][]string{
+		"type=exec":              {"build", "publish", "test"},
+		"type=fetch":             {"src"},
+		"type=docker":            {"lint"},
+		"label=team:infra":       {"build", "lint"},
+		"owner=qa":               {"test"},
+		"name=b*":                {"build"},
+		"deps(test)":             {"build", "src"},
+		"rdeps(build) & leaf":    {"publish"},
+		"root":                   {"lint", "src"},
+		"!label=team:infra&root": {"src"},
+		"test | lint":            {"lint", "test"},
+		"(test | lint) & root":   {"lint"},
+		"deps(publish) & !(type=fetch | type=docker)": {"build", "test"},
+		"all & !rdeps(all)":                           {"lint", "src"},
+	} {
+		got, err := QueryNodes(g, expr)
+		if err != nil {
+			t.Fatalf("%q: %v", expr, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q = %v, want %v", expr, got, want)
+		}
+	}
+
+	if _, err := QueryNodes(g, "deps(missing)"); !errors.Is(err, ErrUnknownNode) {
+		t.Fatalf("unknown node: err = %v", err)
+	}
+	for _, expr := range []string{"", "deps(test", "color=red", "parents(test)", "test &", "label=", "name=[", "test)"} {
+		if _, err := QueryNodes(g, expr); !errors.Is(err, ErrInvalidSelector) {
+			t.Fatalf("%q: err = %v, want ErrInvalidSelector", expr, err)
+		}
+	}
+}
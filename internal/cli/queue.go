@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"scriptweaver/internal/projectintegration/engine/config"
+)
+
+// ErrWorkspaceBusy is returned for a run that the run queue policy of the
+// workspace (see config.RunQueueConfig) refuses.
+var ErrWorkspaceBusy = errors.New("workspace busy")
+
+// QueueState is the run queue of a Session.
+type QueueState struct {
+	// Active is the run executing; nil when the session is idle.
+	Active *QueuedRun `json:"active,omitempty"`
+	// Queued are the runs waiting, in the order they will run.
+	Queued []QueuedRun `json:"queued"`
+}
+
+// QueuedRun is a run of a QueueState.
+type QueuedRun struct {
+	// Graph is the graph file, relative to the workdir when inside it.
+	Graph    string        `json:"graph"`
+	Mode     ExecutionMode `json:"mode"`
+	Schedule string        `json:"schedule,omitempty"`
+	// Requested is when the run was requested.
+	Requested time.Time `json:"requested"`
+	// Coalesced counts the identical requests that were answered with the
+	// result of this run instead of running again.
+	Coalesced int `json:"coalesced,omitempty"`
+}
+
+// queuedRun is a run requested of a Session.
+type queuedRun struct {
+	inv QueuedRun
+	// key identifies identical requests for coalescing.
+	key string
+	// ready is closed when the run may start, done when it finished.
+	ready chan struct{}
+	done  chan struct{}
+	res   CLIResult
+	err   error
+}
+
+func newQueuedRun(inv CLIInvocation) *queuedRun {
+	key, _ := json.Marshal(inv)
+	return &queuedRun{
+		inv: QueuedRun{
+			Graph:     newRunParams(inv).Graph,
+			Mode:      inv.ExecutionMode,
+			Schedule:  inv.Schedule,
+			Requested: time.Now(),
+		},
+		key:   string(key),
+		ready: make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+}
+
+// queuePolicy returns the run queue settings of the workspace at workDir. A
+// config that cannot be loaded selects the default; the run reports the error.
+func queuePolicy(workDir string) config.RunQueueConfig {
+	cfg, _, err := config.LoadOptional(workDir)
+	if err != nil || cfg.RunQueue == nil {
+		return config.RunQueueConfig{Policy: config.QueueWait}
+	}
+	return *cfg.RunQueue
+}
+
+// enqueue admits a run of inv per policy. It returns the run to execute once
+// its ready channel is closed, or, when the request was coalesced into a run
+// already waiting, that run with shared set.
+func (s *Session) enqueue(inv CLIInvocation, policy config.RunQueueConfig) (r *queuedRun, shared bool, err error) {
+	r = newQueuedRun(inv)
+	s.qmu.Lock()
+	defer s.qmu.Unlock()
+	if s.active == nil {
+		s.active = r
+		close(r.ready)
+		return r, false, nil
+	}
+	switch policy.Policy {
+	case config.QueueReject:
+		return nil, false, fmt.Errorf("%w: another run is active (run_queue policy %q)", ErrWorkspaceBusy, config.QueueReject)
+	case config.QueueCoalesce:
+		for _, q := range s.queued {
+			if q.key == r.key {
+				q.inv.Coalesced++
+				return q, true, nil
+			}
+		}
+	}
+	if policy.MaxQueued > 0 && len(s.queued) >= policy.MaxQueued {
+		return nil, false, fmt.Errorf("%w: %d runs already queued (run_queue max_queued)", ErrWorkspaceBusy, len(s.queued))
+	}
+	s.queued = append(s.queued, r)
+	return r, false, nil
+}
+
+// finish records the result of the active run r and starts the next one.
+func (s *Session) finish(r *queuedRun, res CLIResult, err error) {
+	s.qmu.Lock()
+	defer s.qmu.Unlock()
+	r.res, r.err = res, err
+	close(r.done)
+	s.active = nil
+	if len(s.queued) > 0 {
+		s.active, s.queued = s.queued[0], s.queued[1:]
+		close(s.active.ready)
+	}
+}
+
+// Queue returns the run queue of the session.
+func (s *Session) Queue() QueueState {
+	s.qmu.Lock()
+	defer s.qmu.Unlock()
+	qs := QueueState{Queued: make([]QueuedRun, 0, len(s.queued))}
+	if s.active != nil {
+		active := s.active.inv
+		qs.Active = &active
+	}
+	for _, q := range s.queued {
+		qs.Queued = append(qs.Queued, q.inv)
+	}
+	return qs
+}
+
+// withdraw removes r from the queue, unless it already started or identical
+// requests were coalesced into it, and reports whether it did.
+func (s *Session) withdraw(r *queuedRun) bool {
+	s.qmu.Lock()
+	defer s.qmu.Unlock()
+	if r.inv.Coalesced > 0 {
+		return false
+	}
+	for i, q := range s.queued {
+		if q == r {
+			s.queued = append(s.queued[:i], s.queued[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/core"
+)
+
+// waitForQueue polls s until cond holds for its queue.
+func waitForQueue(t *testing.T, s *Session, cond func(QueueState) bool) QueueState {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		q := s.Queue()
+		if cond(q) {
+			return q
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queue never reached the expected state: %+v", q)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSession_QueuePolicies(t *testing.T) {
+	workDir := t.TempDir()
+	configPath := filepath.Join(workDir, ".scriptweaver", "config.json")
+	graphPath := filepath.Join(workDir, "graph.json")
+	// The task blocks until the test creates the file "go".
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "wait", Run: "while [ ! -f go ]; do sleep 0.01; done"}}, nil)
+	inv := func(out string) CLIInvocation {
+		return CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, out), ExecutionMode: ExecutionModeClean}
+	}
+	type result struct {
+		res CLIResult
+		err error
+	}
+	start := func(s *Session, inv CLIInvocation) chan result {
+		ch := make(chan result, 1)
+		go func() {
+			res, err := s.Execute(context.Background(), inv)
+			ch <- result{res, err}
+		}()
+		return ch
+	}
+
+	s := NewSession()
+	first := start(s, inv("out"))
+	waitForQueue(t, s, func(q QueueState) bool { return q.Active != nil })
+
+	writeFile(t, configPath, `{"run_queue":{"policy":"reject"}}`)
+	if res, err := s.Execute(context.Background(), inv("out")); !errors.Is(err, ErrWorkspaceBusy) || res.ExitCode != ExitConfigError {
+		t.Fatalf("reject: exit=%d err=%v", res.ExitCode, err)
+	}
+
+	// Identical requests share one run; a request that does not fit the
+	// queue is refused.
+	writeFile(t, configPath, `{"run_queue":{"policy":"coalesce","max_queued":1}}`)
+	second, third := start(s, inv("out")), start(s, inv("out"))
+	q := waitForQueue(t, s, func(q QueueState) bool { return len(q.Queued) == 1 && q.Queued[0].Coalesced == 1 })
+	if q.Queued[0].Graph != "graph.json" || q.Active.Mode != ExecutionModeClean {
+		t.Fatalf("queue = %+v", q)
+	}
+	if _, err := s.Execute(context.Background(), inv("other")); !errors.Is(err, ErrWorkspaceBusy) {
+		t.Fatalf("full queue: err=%v", err)
+	}
+
+	// A waiting request whose context ends leaves the queue.
+	writeFile(t, configPath, `{"run_queue":{"policy":"queue"}}`)
+	ctx, cancel := context.WithCancel(context.Background())
+	withdrawn := make(chan result, 1)
+	go func() {
+		res, err := s.Execute(ctx, inv("other"))
+		withdrawn <- result{res, err}
+	}()
+	waitForQueue(t, s, func(q QueueState) bool { return len(q.Queued) == 2 })
+	cancel()
+	if r := <-withdrawn; !errors.Is(r.err, context.Canceled) || r.res.ExitCode != ExitCancelled {
+		t.Fatalf("withdrawn: exit=%d err=%v", r.res.ExitCode, r.err)
+	}
+	if q := s.Queue(); len(q.Queued) != 1 {
+		t.Fatalf("queue after withdrawal = %+v", q)
+	}
+
+	if err := os.WriteFile(filepath.Join(workDir, "go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r1, r2, r3 := <-first, <-second, <-third
+	for i, r := range []result{r1, r2, r3} {
+		if r.err != nil || r.res.ExitCode != ExitSuccess {
+			t.Fatalf("run %d: exit=%d err=%v", i, r.res.ExitCode, r.err)
+		}
+	}
+	if r1.res.RunID == r2.res.RunID || r2.res.RunID != r3.res.RunID {
+		t.Fatalf("run IDs = %s %s %s, want the last two coalesced", r1.res.RunID, r2.res.RunID, r3.res.RunID)
+	}
+	if q := s.Queue(); q.Active != nil || len(q.Queued) != 0 {
+		t.Fatalf("queue after runs = %+v", q)
+	}
+}
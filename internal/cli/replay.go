@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/pluginengine"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/trace"
+)
+
+// ReplayRunID is the run ID reporters see for a replayed trace.
+const ReplayRunID = "replay"
+
+// ReplayInvocation is the input of Replay.
+type ReplayInvocation struct {
+	// TracePath is a trace written by a run with --trace.
+	TracePath string
+	// WorkDir locates the workspace config (plugin keys) and the default
+	// plugins root. It may be empty when no plugins are replayed.
+	WorkDir string
+	// OutputDir receives reporter artifacts under reports/<plugin-id>, as in
+	// a run.
+	OutputDir            string
+	PluginDir            string
+	Plugins              []string
+	AllowUnsignedPlugins bool
+	// PluginLog receives the plugin engine's warnings; nil logs them to
+	// stderr.
+	PluginLog pluginengine.Logger
+}
+
+// ReplayResult is the outcome of Replay.
+type ReplayResult struct {
+	// ExitCode is the outcome of the replay itself.
+	ExitCode int
+	// RunExitCode is the exit code of the recorded run.
+	RunExitCode int
+	// Events is the replayed trace event stream, in canonical order.
+	Events      []trace.TraceEvent
+	GraphResult *dag.GraphResult
+	PluginStats []pluginengine.PluginStats
+}
+
+// Replay re-drives the observer and plugin pipeline from a recorded trace
+// without executing any task, so that reporters, plugins and UIs can be
+// developed and regression-tested against fixed traces.
+//
+// Nodes are replayed in canonical trace order (by task name). Lifecycle hooks
+// fire as in a run: BeforeRun, BeforeNode and AfterNode for every node that
+// was not skipped, AfterRun, then Report. Observers are notified of nodes
+// that succeeded, with the trace events up to and including the node's. The
+// trace records no task outputs, so exit codes, hashes and stdio are empty.
+func Replay(ctx context.Context, inv ReplayInvocation, observers ...dag.NodeObserver) (ReplayResult, error) {
+	res := ReplayResult{ExitCode: ExitInternalError}
+	data, err := os.ReadFile(inv.TracePath)
+	if err != nil {
+		res.ExitCode = ExitInvalidInvocation
+		return res, err
+	}
+	tr, err := trace.Parse(data)
+	if err != nil {
+		res.ExitCode = ExitValidationError
+		return res, err
+	}
+	canonical, err := tr.CanonicalJSON()
+	if err != nil {
+		res.ExitCode = ExitValidationError
+		return res, err
+	}
+	// Re-parse so that events carry the IDs assigned by canonicalization.
+	if tr, err = trace.Parse(canonical); err != nil {
+		res.ExitCode = ExitValidationError
+		return res, err
+	}
+
+	var keys []ed25519.PublicKey
+	if strings.TrimSpace(inv.WorkDir) != "" {
+		cfg, _, cerr := config.LoadOptional(inv.WorkDir)
+		if cerr != nil {
+			res.ExitCode = ExitConfigError
+			return res, cerr
+		}
+		keys = cfg.PluginKeys
+	}
+	hooks, err := loadPlugins(CLIInvocation{WorkDir: inv.WorkDir, PluginDir: inv.PluginDir, Plugins: inv.Plugins, AllowUnsignedPlugins: inv.AllowUnsignedPlugins}, keys, pluginLogger(inv.PluginLog))
+	if err != nil {
+		res.ExitCode = ExitPluginError
+		return res, err
+	}
+
+	gr := resultFromTrace(tr, canonical)
+	res.Events = tr.Events
+	res.GraphResult = gr
+	res.RunExitCode = translateGraphResultToExitCode(gr, nil)
+
+	hooks.BeforeRun(ctx)
+	err = replayNodes(ctx, tr.Events, gr, hooks, observers)
+	hooks.AfterRun(ctx)
+	if err == nil {
+		hooks.Report(ctx, filepath.Join(inv.OutputDir, reportsDirName), pluginengine.NewReport(ReplayRunID, res.RunExitCode, gr))
+		res.ExitCode = ExitSuccess
+	}
+	res.PluginStats = hooks.Stats()
+	return res, err
+}
+
+// replayNodes fires the node hooks and observers for each node of events, at
+// the node's terminal (last) event.
+func replayNodes(ctx context.Context, events []trace.TraceEvent, gr *dag.GraphResult, hooks *pluginengine.HookEngine, observers []dag.NodeObserver) error {
+	for i, e := range events {
+		if i+1 < len(events) && events[i+1].TaskID == e.TaskID {
+			continue
+		}
+		state := gr.FinalState[e.TaskID]
+		if state == dag.TaskSkipped {
+			continue
+		}
+		hooks.BeforeNode(ctx, e.TaskID)
+		if dag.IsSuccessful(state) {
+			result := &dag.NodeResult{FromCache: state == dag.TaskCached}
+			snapshot := append([]trace.TraceEvent(nil), events[:i+1]...)
+			for _, o := range observers {
+				if err := o.OnTaskTerminal(core.Task{Name: e.TaskID}, result, snapshot); err != nil {
+					hooks.AfterNode(ctx, e.TaskID)
+					return fmt.Errorf("observer: node %s: %w", e.TaskID, err)
+				}
+			}
+		}
+		hooks.AfterNode(ctx, e.TaskID)
+	}
+	return nil
+}
+
+// resultFromTrace reconstructs the GraphResult a trace was recorded from, as
+// far as the trace describes it: terminal states, execution order and skip
+// causes.
+func resultFromTrace(tr trace.ExecutionTrace, canonical []byte) *dag.GraphResult {
+	gr := &dag.GraphResult{
+		GraphHash:    dag.GraphHash(tr.GraphHash),
+		TraceHash:    trace.ComputeTraceHash(canonical),
+		TraceBytes:   canonical,
+		FinalState:   make(dag.ExecutionState),
+		Deduplicated: make(map[string]string),
+		SkipCause:    make(map[string]string),
+		SkippedBy:    make(map[string]string),
+		FailedBySkip: make(map[string]string),
+	}
+	var order []string
+	for _, e := range tr.Events {
+		if len(order) == 0 || order[len(order)-1] != e.TaskID {
+			order = append(order, e.TaskID)
+		}
+		switch e.Kind {
+		case trace.EventTaskExecuted:
+			gr.FinalState[e.TaskID] = dag.TaskCompleted
+		case trace.EventTaskCached:
+			gr.FinalState[e.TaskID] = dag.TaskCached
+		case trace.EventTaskFailed:
+			gr.FinalState[e.TaskID] = dag.TaskFailed
+			if e.Reason == dag.ReasonUpstreamSkipped {
+				gr.FailedBySkip[e.TaskID] = e.CauseTaskID
+			}
+		case trace.EventTaskSkipped:
+			gr.FinalState[e.TaskID] = dag.TaskSkipped
+			switch e.Reason {
+			case dag.ReasonSkipRequested:
+				gr.SkippedBy[e.TaskID] = e.TaskID
+			case dag.ReasonUpstreamSkipped:
+				gr.SkippedBy[e.TaskID] = e.CauseTaskID
+			default:
+				gr.SkipCause[e.TaskID] = e.CauseTaskID
+			}
+		case trace.EventTaskDeduplicated:
+			gr.Deduplicated[e.TaskID] = e.CauseTaskID
+		}
+	}
+	// A deduplicated node shares the outcome of its representative.
+	for name, rep := range gr.Deduplicated {
+		if dag.IsSuccessful(gr.FinalState[rep]) {
+			gr.FinalState[name] = dag.TaskCompleted
+		} else {
+			gr.FinalState[name] = dag.TaskFailed
+		}
+	}
+	// Only executed and failed nodes were started; the trace does not record
+	// when, so they are listed in canonical order.
+	for _, name := range order {
+		if _, dup := gr.Deduplicated[name]; dup {
+			continue
+		}
+		if s := gr.FinalState[name]; s == dag.TaskCompleted || s == dag.TaskFailed {
+			gr.ExecutionOrder = append(gr.ExecutionOrder, name)
+		}
+	}
+	return gr
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/pluginengine"
+	"scriptweaver/internal/trace"
+)
+
+type replayObserver struct {
+	nodes []string
+}
+
+func (o *replayObserver) OnTaskTerminal(task core.Task, _ *dag.NodeResult, events []trace.TraceEvent) error {
+	if last := events[len(events)-1]; last.TaskID != task.Name || last.ID == "" {
+		return os.ErrInvalid
+	}
+	o.nodes = append(o.nodes, task.Name)
+	return nil
+}
+
+func TestReplay_RedrivesReportersAndObserversWithoutExecuting(t *testing.T) {
+	workDir := t.TempDir()
+	dir := filepath.Join(workDir, pluginengine.DefaultPluginsRoot, "summary")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"plugin_id":"summary","version":"1.0.0","hooks":["Report"],"description":""}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	pluginengine.RegisterRuntime("summary", func(pluginengine.PluginManifest, string) (pluginengine.RuntimePlugin, error) {
+		return artifactReporter{}, nil
+	})
+	t.Cleanup(func() { pluginengine.RegisterRuntime("summary", nil) })
+
+	graphPath := filepath.Join(workDir, "graph.json")
+	marker := filepath.Join(workDir, "ran")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: "touch " + marker},
+		{Name: "b", Run: "exit 1"},
+		{Name: "c", Run: "true"},
+	}, []dag.Edge{{From: "b", To: "c"}})
+	tracePath := filepath.Join(workDir, "trace.json")
+	inv := CLIInvocation{
+		GraphPath:     graphPath,
+		WorkDir:       workDir,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+		Trace:         TraceConfig{Enabled: true, Path: tracePath},
+		Plugins:       []string{"summary"},
+	}
+	run, err := Execute(context.Background(), inv)
+	if err != nil || run.ExitCode != ExitGraphFailure {
+		t.Fatalf("exit=%d err=%v", run.ExitCode, err)
+	}
+	recorded, err := os.ReadFile(filepath.Join(inv.OutputDir, "reports", "summary", "summary.txt"))
+	if err != nil {
+		t.Fatalf("read artifact: %v", err)
+	}
+	if err := os.Remove(marker); err != nil {
+		t.Fatalf("remove marker: %v", err)
+	}
+
+	obs := &replayObserver{}
+	replayOut := filepath.Join(workDir, "replay")
+	res, err := Replay(context.Background(), ReplayInvocation{TracePath: tracePath, WorkDir: workDir, OutputDir: replayOut, Plugins: []string{"summary"}}, obs)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("replay exit=%d err=%v", res.ExitCode, err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("replay executed a task")
+	}
+	if res.RunExitCode != ExitGraphFailure {
+		t.Fatalf("run exit code = %d", res.RunExitCode)
+	}
+	if !reflect.DeepEqual(res.GraphResult.FinalState, run.GraphResult.FinalState) {
+		t.Fatalf("final states = %v, want %v", res.GraphResult.FinalState, run.GraphResult.FinalState)
+	}
+	if res.GraphResult.SkipCause["c"] != "b" {
+		t.Fatalf("skip cause = %v", res.GraphResult.SkipCause)
+	}
+	if want := []string{"a"}; !reflect.DeepEqual(obs.nodes, want) {
+		t.Fatalf("observed nodes = %v, want %v", obs.nodes, want)
+	}
+	replayed, err := os.ReadFile(filepath.Join(replayOut, "reports", "summary", "summary.txt"))
+	if err != nil {
+		t.Fatalf("read replayed artifact: %v", err)
+	}
+	if string(replayed) != string(recorded) {
+		t.Fatalf("replayed artifact %q differs from recorded %q", replayed, recorded)
+	}
+}
+
+func TestReplay_InvalidTraceIsValidationError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.json")
+	if err := os.WriteFile(path, []byte(`{"version":99,"graphHash":"g","events":[]}`), 0o644); err != nil {
+		t.Fatalf("write trace: %v", err)
+	}
+	res, err := Replay(context.Background(), ReplayInvocation{TracePath: path})
+	if err == nil || res.ExitCode != ExitValidationError {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+}
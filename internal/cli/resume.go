@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/incremental"
+	"scriptweaver/internal/recovery/state"
+)
+
+// ResumeFileName is the run file that records how a resumed run reused the
+// previous run's checkpoints.
+const ResumeFileName = "resume.json"
+
+// CacheFileName is the run file that records the artifact cache directory a
+// run used, so that a run resumed with a different --cache-dir can still
+// restore the checkpointed artifacts.
+const CacheFileName = "cache.json"
+
+type runCacheRecord struct {
+	CacheDir string `json:"cache_dir"`
+}
+
+// saveRunCacheDir records dir as the cache of runID. It is best-effort: a
+// missing record only means a later resume cannot import from dir.
+func saveRunCacheDir(st *state.Store, runID, dir string) {
+	data, err := json.Marshal(runCacheRecord{CacheDir: dir})
+	if err != nil {
+		return
+	}
+	_ = st.SaveRunFile(runID, CacheFileName, append(data, '\n'))
+}
+
+// CacheStatsFileName is the run file that records the cache hits and misses
+// of a run.
+const CacheStatsFileName = "cache-stats.json"
+
+// saveCacheStats records the cache statistics of runID, best-effort.
+func saveCacheStats(st *state.Store, runID string, stats core.CacheStats) {
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return
+	}
+	_ = st.SaveRunFile(runID, CacheStatsFileName, append(data, '\n'))
+}
+
+// previousCacheDirs lists the cache directories, other than cacheDir, that may
+// hold the checkpointed artifacts of run prevID: its run-scoped cache and the
+// cache directory it recorded.
+func previousCacheDirs(st *state.Store, prevID, cacheDir string) []string {
+	dirs := []string{st.RunCacheDir(prevID)}
+	data, err := st.LoadRunFile(prevID, CacheFileName)
+	if err != nil {
+		return dirs
+	}
+	var rec runCacheRecord
+	if json.Unmarshal(data, &rec) != nil || rec.CacheDir == "" || filepath.Clean(rec.CacheDir) == filepath.Clean(cacheDir) {
+		return dirs
+	}
+	return append(dirs, rec.CacheDir)
+}
+
+// Reasons a node is not restored from a checkpoint. Such a node is scheduled
+// for execution, although the cache may still serve an unchanged task.
+const (
+	ResumeReasonNoCheckpoint        = "no checkpoint"
+	ResumeReasonTaskChanged         = "task changed"
+	ResumeReasonDependenciesChanged = "dependencies changed"
+	ResumeReasonUpstreamNotReused   = "upstream not reused"
+	// ResumeReasonSkipped marks a node skipped on request (--skip); it is
+	// not reused and does not run either.
+	ResumeReasonSkipped = "skipped"
+	// ResumeReasonRestoreFailed marks a node planned for reuse whose restore
+	// failed during the run, such as on a corrupt cache entry, so that it was
+	// executed instead; the reason ends with the restore error.
+	ResumeReasonRestoreFailed = "restore failed"
+)
+
+// ResumeReport describes how a run reused a previous run's checkpoints.
+//
+// Compatibility is decided per node: a node is reused when it has a valid
+// checkpoint, its task hash and direct dependencies are unchanged, and all of
+// its dependencies are reused too. This holds even when the graph hash
+// changed, so fixing one failing node does not force a full restart.
+type ResumeReport struct {
+	PreviousRunID string `json:"previous_run_id"`
+	// GraphChanged is set when the previous run's graph hash differs.
+	GraphChanged bool `json:"graph_changed"`
+	// Reused lists the nodes restored from checkpoints, in topological order.
+	Reused []string `json:"reused"`
+	// Rerun lists the nodes that are not reused, in topological order.
+	Rerun []ResumeNode `json:"rerun"`
+}
+
+// ResumeNode is a node that was not reused, with the reason.
+type ResumeNode struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+func newResumeReport(prevID string, graphChanged bool, g *dag.TaskGraph, plan *incremental.IncrementalPlan, reasons map[string]string) *ResumeReport {
+	r := &ResumeReport{PreviousRunID: prevID, GraphChanged: graphChanged, Reused: []string{}, Rerun: []ResumeNode{}}
+	for _, name := range g.TopologicalOrder() {
+		if plan.Decisions[name] == incremental.DecisionReuseCache {
+			r.Reused = append(r.Reused, name)
+			continue
+		}
+		r.Rerun = append(r.Rerun, ResumeNode{Name: name, Reason: reasons[name]})
+	}
+	return r
+}
+
+// replan moves the nodes the executor re-planned (see dag.Executor.Replan)
+// from Reused to Rerun, keeping both in topological order.
+func (r *ResumeReport) replan(g *dag.TaskGraph, replanned map[string]string) {
+	if len(replanned) == 0 {
+		return
+	}
+	reasons := make(map[string]string, len(r.Rerun))
+	for _, n := range r.Rerun {
+		reasons[n.Name] = n.Reason
+	}
+	for name, cause := range replanned {
+		reasons[name] = ResumeReasonRestoreFailed + ": " + cause
+	}
+	reused := make(map[string]bool, len(r.Reused))
+	for _, name := range r.Reused {
+		reused[name] = true
+	}
+	r.Reused, r.Rerun = []string{}, []ResumeNode{}
+	for _, name := range g.TopologicalOrder() {
+		if reused[name] && replanned[name] == "" {
+			r.Reused = append(r.Reused, name)
+		} else if reason, ok := reasons[name]; ok {
+			r.Rerun = append(r.Rerun, ResumeNode{Name: name, Reason: reason})
+		}
+	}
+}
+
+func marshalResumeReport(r *ResumeReport) ([]byte, error) {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
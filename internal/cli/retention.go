@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"context"
+
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/recovery/state"
+)
+
+// PurgedRun is a run removed by ApplyRetention.
+type PurgedRun struct {
+	RunID string `json:"run_id"`
+	Bytes int64  `json:"bytes"`
+}
+
+// PurgeReport describes the runs ApplyRetention removed, oldest first, and
+// the runs it kept.
+type PurgeReport struct {
+	Purged    []PurgedRun `json:"purged"`
+	Bytes     int64       `json:"bytes"`
+	KeptRuns  int         `json:"kept_runs"`
+	KeptBytes int64       `json:"kept_bytes"`
+}
+
+// ApplyRetention removes the oldest runs recorded in st until at most
+// r.KeepRuns runs are left and they take at most r.MaxBytes, leaving out
+// the bounds that are zero. Runs are removed in run ID order, which is the
+// order they started in; run keep, usually the run that just finished, is
+// never removed. On error the report lists the runs removed so far.
+func ApplyRetention(st *state.Store, r config.RetentionConfig, keep string) (PurgeReport, error) {
+	ids, err := st.ListRunIDs()
+	if err != nil {
+		return PurgeReport{}, err
+	}
+	sizes := make([]int64, len(ids))
+	var report PurgeReport
+	for i, id := range ids {
+		if sizes[i], err = st.RunSize(id); err != nil {
+			return PurgeReport{}, err
+		}
+		report.KeptBytes += sizes[i]
+	}
+	report.KeptRuns = len(ids)
+	over := func() bool {
+		return (r.KeepRuns > 0 && report.KeptRuns > r.KeepRuns) || (r.MaxBytes > 0 && report.KeptBytes > r.MaxBytes)
+	}
+	for i, id := range ids {
+		if !over() {
+			break
+		}
+		if id == keep {
+			continue
+		}
+		if err := st.RemoveRun(id); err != nil {
+			return report, err
+		}
+		report.Purged = append(report.Purged, PurgedRun{RunID: id, Bytes: sizes[i]})
+		report.Bytes += sizes[i]
+		report.KeptRuns--
+		report.KeptBytes -= sizes[i]
+	}
+	return report, nil
+}
+
+// stageRetention purges old runs per the workspace retention settings once
+// the run has finished, whatever its outcome. Purging is best-effort: it
+// never changes the outcome of the run.
+func stageRetention(ctx context.Context, rc *RunContext, next Next) error {
+	err := next(ctx)
+	if r := rc.Config.Retention; r != nil && rc.Store != nil {
+		if report, _ := ApplyRetention(rc.Store, *r, rc.RunID); len(report.Purged) > 0 {
+			rc.Result.Purged = &report
+		}
+	}
+	return err
+}
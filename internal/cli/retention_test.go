@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestApplyRetention_PurgesOldestRunsFirst(t *testing.T) {
+	st, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	for _, id := range []string{"r1", "r2", "r3", "r4"} {
+		if err := st.SaveRunFile(id, "log.txt", []byte(strings.Repeat("x", 100))); err != nil {
+			t.Fatalf("SaveRunFile: %v", err)
+		}
+	}
+
+	// r1 is the run to keep: the bound is met by purging the next oldest.
+	report, err := ApplyRetention(st, config.RetentionConfig{KeepRuns: 3, MaxBytes: 250}, "r1")
+	if err != nil {
+		t.Fatalf("ApplyRetention: %v", err)
+	}
+	want := []PurgedRun{{RunID: "r2", Bytes: 100}, {RunID: "r3", Bytes: 100}}
+	if !reflect.DeepEqual(report.Purged, want) || report.Bytes != 200 || report.KeptRuns != 2 || report.KeptBytes != 200 {
+		t.Fatalf("report = %+v", report)
+	}
+	if ids, _ := st.ListRunIDs(); !reflect.DeepEqual(ids, []string{"r1", "r4"}) {
+		t.Fatalf("runs left = %v", ids)
+	}
+
+	if report, err = ApplyRetention(st, config.RetentionConfig{KeepRuns: 2}, "r4"); err != nil || len(report.Purged) != 0 {
+		t.Fatalf("within bounds: %+v, %v", report, err)
+	}
+}
+
+func TestExecute_AppliesWorkspaceRetention(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, ".scriptweaver", "config.json"), []byte(`{"retention":{"keep_runs":2}}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Run: "true"}}, nil)
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean}
+
+	var runIDs []string
+	for i := 0; i < 3; i++ {
+		res, err := Execute(context.Background(), inv)
+		if err != nil || res.ExitCode != ExitSuccess {
+			t.Fatalf("run %d: exit=%d err=%v", i, res.ExitCode, err)
+		}
+		if (res.Purged != nil) != (i == 2) {
+			t.Fatalf("run %d: purged = %+v", i, res.Purged)
+		}
+		runIDs = append(runIDs, res.RunID)
+	}
+	st, _ := state.NewStore(workDir)
+	if ids, _ := st.ListRunIDs(); !reflect.DeepEqual(ids, runIDs[1:]) {
+		t.Fatalf("runs left = %v, want %v", ids, runIDs[1:])
+	}
+}
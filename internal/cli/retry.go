@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/platform"
+	"scriptweaver/internal/recovery/state"
+)
+
+// DefaultRetryBackoff is the delay before the first automatic retry when
+// CLIInvocation.RetryBackoff is zero.
+const DefaultRetryBackoff = time.Second
+
+// maxRetryBackoff bounds the doubling of the retry delay.
+const maxRetryBackoff = 5 * time.Minute
+
+// RetryAttempt is a run that failed transiently and was retried.
+type RetryAttempt struct {
+	// RunID is the failed run; the retry records it as its PreviousRunID.
+	RunID        string `json:"run_id"`
+	ErrorCode    string `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+	// Backoff is the delay waited before the retry.
+	Backoff time.Duration `json:"backoff_ns"`
+}
+
+// retryClock waits out the backoff between retries. Tests replace it.
+var retryClock = platform.System
+
+// executeWith executes inv and, while the run fails transiently (see
+// state.Failure.Transient) and retries remain, waits with exponential backoff
+// and runs it again.
+//
+// A retry resumes the failed run, so nodes that completed before the failure
+// are restored from its checkpoints; clean runs are re-executed from scratch.
+// Either way the retry is linked to the failed run via PreviousRunID. Graph,
+// workspace and ordinary task failures are deterministic and never retried.
+func executeWith(ctx context.Context, inv CLIInvocation, executor GraphExecutor, session *Session, observers []dag.NodeObserver, p Pipeline) (CLIResult, error) {
+	res, err := executeRecorded(ctx, inv, executor, session, observers, p)
+	var retries []RetryAttempt
+	for attempt := 0; attempt < inv.Retries && res.Failure != nil && res.Failure.Transient; attempt++ {
+		backoff := retryBackoff(inv.RetryBackoff, attempt)
+		retries = append(retries, RetryAttempt{RunID: res.RunID, ErrorCode: res.Failure.ErrorCode, ErrorMessage: res.Failure.ErrorMessage, Backoff: backoff})
+		if serr := retryClock.Sleep(ctx, backoff); serr != nil {
+			res.ExitCode = ExitCancelled
+			err = serr
+			break
+		}
+		next := inv
+		next.retryOf = res.RunID
+		if inv.ExecutionMode != ExecutionModeClean {
+			next.ResumeRunID = res.RunID
+		}
+		if inv.Chaos != nil {
+			// The same seed would inject the same faults into every retry.
+			c := *inv.Chaos
+			c.Seed += int64(attempt + 1)
+			next.Chaos = &c
+		}
+		res, err = executeRecorded(ctx, next, executor, session, observers, p)
+	}
+	res.Retries = retries
+	return res, err
+}
+
+// executeRecorded executes inv once and attaches the failure recorded for an
+// unsuccessful run.
+func executeRecorded(ctx context.Context, inv CLIInvocation, executor GraphExecutor, session *Session, observers []dag.NodeObserver, p Pipeline) (CLIResult, error) {
+	res, err := executeRun(ctx, inv, executor, session, observers, p)
+	if res.ExitCode == ExitSuccess || res.RunID == "" {
+		return res, err
+	}
+	if st, serr := state.NewStore(inv.WorkDir); serr == nil {
+		if f, ferr := st.LoadFailure(res.RunID); ferr == nil {
+			res.Failure = &f
+		}
+	}
+	return res, err
+}
+
+// retryBackoff returns the delay before retry attempt+1: base doubled attempt
+// times, up to maxRetryBackoff.
+func retryBackoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = DefaultRetryBackoff
+	}
+	d := base
+	for i := 0; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > maxRetryBackoff && base < maxRetryBackoff {
+		d = maxRetryBackoff
+	}
+	return d
+}
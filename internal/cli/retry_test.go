@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/platform"
+	"scriptweaver/internal/recovery/state"
+)
+
+// flakyExecutor fails the first `failures` runs with an engine error and
+// executes the graph afterwards.
+type flakyExecutor struct {
+	failures int
+	calls    int
+}
+
+func (e *flakyExecutor) Run(ctx context.Context, g *dag.TaskGraph, runner dag.TaskRunner) (*dag.GraphResult, error) {
+	e.calls++
+	if e.calls <= e.failures {
+		return nil, errors.New("connection reset by peer")
+	}
+	return defaultGraphExecutor{}.Run(ctx, g, runner)
+}
+
+// sleepClock is a platform.Clock whose Sleep calls sleep.
+type sleepClock func(context.Context, time.Duration) error
+
+func (sleepClock) Now() time.Time { return time.Now() }
+
+func (c sleepClock) Sleep(ctx context.Context, d time.Duration) error { return c(ctx, d) }
+
+func stubRetryClock(t *testing.T, clock platform.Clock) {
+	t.Helper()
+	orig := retryClock
+	retryClock = clock
+	t.Cleanup(func() { retryClock = orig })
+}
+
+func stubRetrySleep(t *testing.T, sleep func(context.Context, time.Duration) error) {
+	t.Helper()
+	stubRetryClock(t, sleepClock(sleep))
+}
+
+func retryInvocation(t *testing.T, run string) CLIInvocation {
+	t.Helper()
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "t1", Run: run}}, nil)
+	return CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+		Retries:       3,
+		RetryBackoff:  10 * time.Millisecond,
+	}
+}
+
+func TestExecute_Retries_TransientFailureWithBackoffAndRunChain(t *testing.T) {
+	clock := platform.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	stubRetryClock(t, clock)
+	inv := retryInvocation(t, "true")
+	exec := &flakyExecutor{failures: 2}
+
+	res, err := ExecuteWithExecutor(context.Background(), inv, exec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitSuccess {
+		t.Fatalf("expected exit %d got %d", ExitSuccess, res.ExitCode)
+	}
+	if exec.calls != 3 {
+		t.Fatalf("expected 3 executions, got %d", exec.calls)
+	}
+	if want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}; !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Fatalf("backoff = %v, want %v", clock.Sleeps(), want)
+	}
+	if len(res.Retries) != 2 || res.Retries[0].ErrorCode != "EngineError" {
+		t.Fatalf("unexpected retries: %#v", res.Retries)
+	}
+
+	st, err := state.NewStore(inv.WorkDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	chain := []string{res.Retries[0].RunID, res.Retries[1].RunID, res.RunID}
+	for i, id := range chain {
+		run, err := st.LoadRun(id)
+		if err != nil {
+			t.Fatalf("LoadRun(%s): %v", id, err)
+		}
+		if run.RetryCount != i {
+			t.Fatalf("run %d: retry_count = %d, want %d", i, run.RetryCount, i)
+		}
+		if i == 0 {
+			if run.PreviousRunID != nil {
+				t.Fatalf("first run should have no previous run, got %q", *run.PreviousRunID)
+			}
+			continue
+		}
+		if run.PreviousRunID == nil || *run.PreviousRunID != chain[i-1] {
+			t.Fatalf("run %d: previous_run_id = %v, want %s", i, run.PreviousRunID, chain[i-1])
+		}
+	}
+}
+
+func TestExecute_Retries_DeterministicTaskFailureIsNotRetried(t *testing.T) {
+	stubRetrySleep(t, func(context.Context, time.Duration) error {
+		t.Fatalf("a task failure must not be retried")
+		return nil
+	})
+	inv := retryInvocation(t, "exit 1")
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitGraphFailure {
+		t.Fatalf("expected exit %d got %d", ExitGraphFailure, res.ExitCode)
+	}
+	if len(res.Retries) != 0 {
+		t.Fatalf("unexpected retries: %#v", res.Retries)
+	}
+}
+
+func TestExecute_Retries_ExhaustedReturnsLastFailure(t *testing.T) {
+	stubRetrySleep(t, func(context.Context, time.Duration) error { return nil })
+	inv := retryInvocation(t, "true")
+	inv.Retries = 2
+	exec := &flakyExecutor{failures: 10}
+
+	res, err := ExecuteWithExecutor(context.Background(), inv, exec)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if res.ExitCode != ExitInternalError {
+		t.Fatalf("expected exit %d got %d", ExitInternalError, res.ExitCode)
+	}
+	if exec.calls != 3 || len(res.Retries) != 2 {
+		t.Fatalf("expected 3 executions and 2 retries, got %d and %d", exec.calls, len(res.Retries))
+	}
+}
+
+func TestExecute_Retries_CancelledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stubRetrySleep(t, func(ctx context.Context, _ time.Duration) error {
+		cancel()
+		return ctx.Err()
+	})
+	inv := retryInvocation(t, "true")
+	exec := &flakyExecutor{failures: 1}
+
+	res, err := ExecuteWithExecutor(ctx, inv, exec)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if res.ExitCode != ExitCancelled {
+		t.Fatalf("expected exit %d got %d", ExitCancelled, res.ExitCode)
+	}
+	if exec.calls != 1 {
+		t.Fatalf("expected no retry after cancellation, got %d executions", exec.calls)
+	}
+}
+
+func TestRetryBackoff_DoublesUpToLimit(t *testing.T) {
+	if got := retryBackoff(0, 0); got != DefaultRetryBackoff {
+		t.Fatalf("default backoff = %v", got)
+	}
+	if got := retryBackoff(time.Second, 3); got != 8*time.Second {
+		t.Fatalf("backoff after 3 retries = %v", got)
+	}
+	if got := retryBackoff(time.Second, 30); got != maxRetryBackoff {
+		t.Fatalf("backoff after 30 retries = %v", got)
+	}
+}
+
+func TestExecute_FailureKind_MissingToolIsDeterministic(t *testing.T) {
+	inv := retryInvocation(t, "no-such-tool-scriptweaver")
+	stubRetrySleep(t, func(context.Context, time.Duration) error {
+		t.Fatalf("a missing tool must not be retried")
+		return nil
+	})
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitGraphFailure {
+		t.Fatalf("expected exit %d got %d", ExitGraphFailure, res.ExitCode)
+	}
+	if res.Failure == nil || res.Failure.Kind != state.FailureKindMissingTool || res.Failure.Transient {
+		t.Fatalf("unexpected failure: %#v", res.Failure)
+	}
+}
+
+func TestExecute_Retries_TransientTaskFailure(t *testing.T) {
+	stubRetrySleep(t, func(context.Context, time.Duration) error { return nil })
+	inv := retryInvocation(t, "echo 'curl: (7) Connection refused' >&2; exit 7")
+	inv.Retries = 1
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ExitCode != ExitGraphFailure {
+		t.Fatalf("expected exit %d got %d", ExitGraphFailure, res.ExitCode)
+	}
+	if len(res.Retries) != 1 || res.Retries[0].ErrorCode != "NodeFailed" {
+		t.Fatalf("unexpected retries: %#v", res.Retries)
+	}
+	if res.Failure == nil || res.Failure.Kind != state.FailureKindNetwork || !res.Failure.Transient {
+		t.Fatalf("unexpected failure: %#v", res.Failure)
+	}
+}
@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+// EnvFileName is the run file that records the redacted effective environment
+// of every node of a run.
+const EnvFileName = "env.json"
+
+// RunEnv is the effective environment of a run, redacted: values are only
+// kept as sha256 digests, so that runs can be compared without storing
+// secrets.
+type RunEnv struct {
+	// Digest covers the environment of every node.
+	Digest string             `json:"digest"`
+	Nodes  map[string]NodeEnv `json:"nodes"`
+}
+
+// NodeEnv is the redacted environment a node's command runs with.
+type NodeEnv struct {
+	// Digest is the digest of the whole environment, as in
+	// state.NodeFailure.EnvDigest.
+	Digest string `json:"digest"`
+	// Vars maps each variable to the sha256 hex of its value.
+	Vars map[string]string `json:"vars"`
+}
+
+// Environment change kinds reported by DiffRunEnv.
+const (
+	EnvAdded       = "added"
+	EnvRemoved     = "removed"
+	EnvChanged     = "changed"
+	EnvNodeAdded   = "node added"
+	EnvNodeRemoved = "node removed"
+)
+
+// EnvChange is one difference between the environments of two runs. Var is
+// empty for EnvNodeAdded and EnvNodeRemoved.
+type EnvChange struct {
+	Node   string `json:"node"`
+	Var    string `json:"var,omitempty"`
+	Change string `json:"change"`
+}
+
+// captureEnv records the effective environment of every node of g: its
+// declared variables and the normalized locale and timezone. Commands see
+// nothing else.
+func captureEnv(g *dag.TaskGraph) RunEnv {
+	re := RunEnv{Nodes: make(map[string]NodeEnv)}
+	h := sha256.New()
+	for _, name := range g.TopologicalOrder() {
+		n, _ := g.Node(name)
+		env := core.EffectiveEnv(n.Task.Env)
+		ne := NodeEnv{Digest: envDigest(env), Vars: make(map[string]string, len(env))}
+		for k, v := range env {
+			sum := sha256.Sum256([]byte(v))
+			ne.Vars[k] = hex.EncodeToString(sum[:])
+		}
+		re.Nodes[name] = ne
+	}
+	names := make([]string, 0, len(re.Nodes))
+	for name := range re.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{'='})
+		h.Write([]byte(re.Nodes[name].Digest))
+		h.Write([]byte{'\n'})
+	}
+	re.Digest = hex.EncodeToString(h.Sum(nil))
+	return re
+}
+
+// saveRunEnv records re as a run file, best-effort.
+func saveRunEnv(st *state.Store, runID string, re RunEnv) {
+	data, err := json.MarshalIndent(re, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = st.SaveRunFile(runID, EnvFileName, append(data, '\n'))
+}
+
+// ErrNoRunEnv is returned for runs that recorded no environment, such as runs
+// of earlier versions.
+var ErrNoRunEnv = errors.New("recorded no environment")
+
+// LoadRunEnv returns the environment recorded for run runID.
+func LoadRunEnv(st *state.Store, runID string) (RunEnv, error) {
+	data, err := st.LoadRunFile(runID, EnvFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return RunEnv{}, fmt.Errorf("run %s %w", runID, ErrNoRunEnv)
+	}
+	if err != nil {
+		return RunEnv{}, err
+	}
+	var re RunEnv
+	if err := json.Unmarshal(data, &re); err != nil {
+		return RunEnv{}, fmt.Errorf("parse %s of run %s: %w", EnvFileName, runID, err)
+	}
+	return re, nil
+}
+
+// DiffRunEnv lists the differences from environment a to environment b,
+// ordered by node then variable.
+func DiffRunEnv(a, b RunEnv) []EnvChange {
+	if a.Digest == b.Digest && a.Digest != "" {
+		return nil
+	}
+	nodes := make(map[string]bool)
+	for name := range a.Nodes {
+		nodes[name] = true
+	}
+	for name := range b.Nodes {
+		nodes[name] = true
+	}
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []EnvChange
+	for _, name := range names {
+		na, inA := a.Nodes[name]
+		nb, inB := b.Nodes[name]
+		switch {
+		case !inA:
+			out = append(out, EnvChange{Node: name, Change: EnvNodeAdded})
+			continue
+		case !inB:
+			out = append(out, EnvChange{Node: name, Change: EnvNodeRemoved})
+			continue
+		case na.Digest == nb.Digest:
+			continue
+		}
+		vars := make(map[string]bool)
+		for k := range na.Vars {
+			vars[k] = true
+		}
+		for k := range nb.Vars {
+			vars[k] = true
+		}
+		keys := make([]string, 0, len(vars))
+		for k := range vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			va, okA := na.Vars[k]
+			vb, okB := nb.Vars[k]
+			switch {
+			case !okA:
+				out = append(out, EnvChange{Node: name, Var: k, Change: EnvAdded})
+			case !okB:
+				out = append(out, EnvChange{Node: name, Var: k, Change: EnvRemoved})
+			case va != vb:
+				out = append(out, EnvChange{Node: name, Var: k, Change: EnvChanged})
+			}
+		}
+	}
+	return out
+}
+
+// RunDiff compares two runs.
+type RunDiff struct {
+	A          string      `json:"a"`
+	B          string      `json:"b"`
+	GraphHashA string      `json:"graph_hash_a"`
+	GraphHashB string      `json:"graph_hash_b"`
+	Env        []EnvChange `json:"env"`
+	// Params is nil when either run recorded no parameters.
+	Params []ParamChange `json:"params"`
+}
+
+// SameGraph reports whether both runs executed the same graph.
+func (d RunDiff) SameGraph() bool { return d.GraphHashA == d.GraphHashB }
+
+// DiffRuns compares run a to run b. Runs that do not exist yield an error
+// wrapping fs.ErrNotExist, and runs without a recorded environment one
+// wrapping ErrNoRunEnv. Runs without recorded parameters are compared
+// without them.
+func DiffRuns(st *state.Store, a, b string) (RunDiff, error) {
+	d := RunDiff{A: a, B: b}
+	var envs [2]RunEnv
+	var params [2]*RunParams
+	for i, id := range []string{a, b} {
+		run, err := st.LoadRun(id)
+		if errors.Is(err, fs.ErrNotExist) {
+			return RunDiff{}, fmt.Errorf("run %s not found: %w", id, err)
+		}
+		if err != nil {
+			return RunDiff{}, err
+		}
+		if i == 0 {
+			d.GraphHashA = run.GraphHash
+		} else {
+			d.GraphHashB = run.GraphHash
+		}
+		if envs[i], err = LoadRunEnv(st, id); err != nil {
+			return RunDiff{}, err
+		}
+		rp, err := LoadRunParams(st, id)
+		if err != nil && !errors.Is(err, ErrNoRunParams) {
+			return RunDiff{}, err
+		}
+		if err == nil {
+			params[i] = &rp
+		}
+	}
+	d.Env = DiffRunEnv(envs[0], envs[1])
+	if d.Env == nil {
+		d.Env = []EnvChange{}
+	}
+	if params[0] != nil && params[1] != nil {
+		d.Params = DiffRunParams(*params[0], *params[1])
+	}
+	return d, nil
+}
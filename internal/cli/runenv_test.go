@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestExecute_RecordsRedactedEnvAndDiffsRuns(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, CacheDir: filepath.Join(workDir, "cache"), OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeIncremental}
+	run := func(tasks []core.Task) string {
+		t.Helper()
+		writeGraphJSON(t, graphPath, tasks, nil)
+		res, err := Execute(context.Background(), inv)
+		if err != nil || res.ExitCode != ExitSuccess {
+			t.Fatalf("exit %d err %v", res.ExitCode, err)
+		}
+		return res.RunID
+	}
+	a := run([]core.Task{
+		{Name: "build", Run: "true", Env: map[string]string{"TOKEN": "s3cret-value", "MODE": "debug"}},
+		{Name: "lint", Run: "true"},
+	})
+	b := run([]core.Task{
+		{Name: "build", Run: "true", Env: map[string]string{"TOKEN": "s3cret-value", "MODE": "release", "CI": "1"}},
+		{Name: "test", Run: "true"},
+	})
+
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	data, err := st.LoadRunFile(a, EnvFileName)
+	if err != nil {
+		t.Fatalf("LoadRunFile: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret-value") || !strings.Contains(string(data), "TOKEN") {
+		t.Fatalf("environment not redacted:\n%s", data)
+	}
+
+	d, err := DiffRuns(st, a, b)
+	if err != nil {
+		t.Fatalf("DiffRuns: %v", err)
+	}
+	want := []EnvChange{
+		{Node: "build", Var: "CI", Change: EnvAdded},
+		{Node: "build", Var: "MODE", Change: EnvChanged},
+		{Node: "lint", Change: EnvNodeRemoved},
+		{Node: "test", Change: EnvNodeAdded},
+	}
+	if !reflect.DeepEqual(d.Env, want) {
+		t.Fatalf("env diff = %#v, want %#v", d.Env, want)
+	}
+	if d, err := DiffRuns(st, a, a); err != nil || len(d.Env) != 0 || !d.SameGraph() {
+		t.Fatalf("self diff = %#v, %v", d, err)
+	}
+}
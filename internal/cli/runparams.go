@@ -0,0 +1,326 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"scriptweaver/internal/chaos"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+// ParamsFileName is the run file that records the effective invocation of a
+// run.
+const ParamsFileName = "params.json"
+
+// RunParams is the effective invocation of a run: its mode and every setting
+// that changes what it runs or how. Paths inside the working directory are
+// relative to it, so runs of different checkouts compare equal.
+type RunParams struct {
+	Graph             string            `json:"graph"`
+	OutputDir         string            `json:"output_dir"`
+	CacheDir          string            `json:"cache_dir,omitempty"`
+	Mode              ExecutionMode     `json:"mode"`
+	Trace             string            `json:"trace,omitempty"`
+	ResumeRunID       string            `json:"resume_run_id,omitempty"`
+	RetryOf           string            `json:"retry_of,omitempty"`
+	Deduplicate       bool              `json:"deduplicate,omitempty"`
+	Checkpoint        bool              `json:"checkpoint,omitempty"`
+	VerifyDeterminism int               `json:"verify_determinism,omitempty"`
+	Workers           int               `json:"workers,omitempty"`
+	PluginDir         string            `json:"plugin_dir,omitempty"`
+	Plugins           []string          `json:"plugins,omitempty"`
+	AllowUnsigned     bool              `json:"allow_unsigned_plugins,omitempty"`
+	Retries           int               `json:"retries,omitempty"`
+	RetryBackoff      time.Duration     `json:"retry_backoff_ns,omitempty"`
+	NamespaceOutputs  bool              `json:"namespace_outputs,omitempty"`
+	Progress          string            `json:"progress,omitempty"`
+	Skip              []string          `json:"skip,omitempty"`
+	SkipPolicy        dag.SkipPolicy    `json:"skip_policy,omitempty"`
+	Node              string            `json:"node,omitempty"`
+	Isolated          bool              `json:"isolated,omitempty"`
+	Simulate          string            `json:"simulate,omitempty"`
+	Chaos             *chaos.Config     `json:"chaos,omitempty"`
+	Offline           bool              `json:"offline,omitempty"`
+	Set               map[string]string `json:"set,omitempty"`
+	Schedule          string            `json:"schedule,omitempty"`
+}
+
+// newRunParams returns the parameters of inv.
+func newRunParams(inv CLIInvocation) RunParams {
+	rel := func(p string) string {
+		if p == "" {
+			return ""
+		}
+		if r, err := filepath.Rel(inv.WorkDir, p); err == nil && filepath.IsLocal(r) {
+			return filepath.ToSlash(r)
+		}
+		return p
+	}
+	rp := RunParams{
+		Graph:             rel(inv.GraphPath),
+		OutputDir:         rel(inv.OutputDir),
+		CacheDir:          rel(inv.CacheDir),
+		Mode:              inv.ExecutionMode,
+		ResumeRunID:       inv.ResumeRunID,
+		RetryOf:           inv.retryOf,
+		Deduplicate:       inv.Deduplicate,
+		Checkpoint:        inv.Checkpoint,
+		VerifyDeterminism: inv.VerifyDeterminism,
+		Workers:           inv.Workers,
+		PluginDir:         rel(inv.PluginDir),
+		Plugins:           inv.Plugins,
+		AllowUnsigned:     inv.AllowUnsignedPlugins,
+		Retries:           inv.Retries,
+		RetryBackoff:      inv.RetryBackoff,
+		NamespaceOutputs:  inv.NamespaceOutputs,
+		Progress:          rel(inv.ProgressPath),
+		Skip:              inv.Skip,
+		SkipPolicy:        inv.SkipPolicy,
+		Node:              inv.Node,
+		Isolated:          inv.Isolated,
+		Simulate:          rel(inv.Simulate),
+		Chaos:             inv.Chaos,
+		Offline:           inv.Offline,
+		Set:               inv.Params,
+		Schedule:          inv.Schedule,
+	}
+	if inv.Trace.Enabled {
+		rp.Trace = rel(inv.Trace.Path)
+	}
+	return rp
+}
+
+// ParamField is one parameter of a run. Value is its JSON encoding.
+type ParamField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Fields lists the parameters that are set, ordered by name.
+func (p RunParams) Fields() []ParamField {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	out := make([]ParamField, 0, len(raw))
+	for name, v := range raw {
+		var buf bytes.Buffer
+		if json.Compact(&buf, v) != nil {
+			buf.Reset()
+			buf.Write(v)
+		}
+		out = append(out, ParamField{Name: name, Value: buf.String()})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// saveRunParams records rp as a run file, best-effort.
+func saveRunParams(st *state.Store, runID string, rp RunParams) {
+	data, err := json.MarshalIndent(rp, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = st.SaveRunFile(runID, ParamsFileName, append(data, '\n'))
+}
+
+// ErrNoRunParams is returned for runs that recorded no parameters, such as
+// runs of earlier versions.
+var ErrNoRunParams = errors.New("recorded no parameters")
+
+// LoadRunParams returns the parameters recorded for run runID.
+func LoadRunParams(st *state.Store, runID string) (RunParams, error) {
+	data, err := st.LoadRunFile(runID, ParamsFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return RunParams{}, fmt.Errorf("run %s %w", runID, ErrNoRunParams)
+	}
+	if err != nil {
+		return RunParams{}, err
+	}
+	var rp RunParams
+	if err := json.Unmarshal(data, &rp); err != nil {
+		return RunParams{}, fmt.Errorf("parse %s of run %s: %w", ParamsFileName, runID, err)
+	}
+	return rp, nil
+}
+
+// ParamChange is a parameter that differs between two runs. A and B are the
+// JSON values in either run; an empty value means the parameter was unset.
+type ParamChange struct {
+	Param string `json:"param"`
+	A     string `json:"a,omitempty"`
+	B     string `json:"b,omitempty"`
+}
+
+// DiffRunParams lists the parameters that differ from a to b, ordered by
+// name.
+func DiffRunParams(a, b RunParams) []ParamChange {
+	values := make(map[string][2]string)
+	for _, f := range a.Fields() {
+		v := values[f.Name]
+		v[0] = f.Value
+		values[f.Name] = v
+	}
+	for _, f := range b.Fields() {
+		v := values[f.Name]
+		v[1] = f.Value
+		values[f.Name] = v
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := []ParamChange{}
+	for _, name := range names {
+		if v := values[name]; v[0] != v[1] {
+			out = append(out, ParamChange{Param: name, A: v[0], B: v[1]})
+		}
+	}
+	return out
+}
+
+// LoadRunGraph loads the graph a run with parameters rp executed, from the
+// current graph files: with rp's parameter values, and with outputs namespaced
+// and fetches pinned by the lockfile as the run did. Its hash is the run's
+// graph hash unless the graph files or the lockfile changed since.
+func LoadRunGraph(workDir string, rp RunParams) (*dag.TaskGraph, error) {
+	abs := func(p string) string {
+		p = filepath.FromSlash(p)
+		if p != "" && !filepath.IsAbs(p) {
+			p = filepath.Join(workDir, p)
+		}
+		return p
+	}
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: abs(rp.Graph), OutputDir: abs(rp.OutputDir), Params: rp.Set}
+	g, _, _, err := loadGraphAndHash(inv, newPhaseTimer(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if rp.NamespaceOutputs {
+		rel, err := outputDirRel(inv)
+		if err != nil {
+			return nil, err
+		}
+		if g, err = namespaceOutputs(g, rel); err != nil {
+			return nil, err
+		}
+	}
+	lf, err := LoadLockfile(workDir)
+	if err != nil {
+		return nil, err
+	}
+	return pinFetches(g, lf)
+}
+
+// RunRecord is what is recorded about a run: its metadata, its failure if it
+// failed and, unless the run predates them, its parameters.
+type RunRecord struct {
+	Run     state.Run      `json:"run"`
+	Failure *state.Failure `json:"failure,omitempty"`
+	Params  *RunParams     `json:"params"`
+}
+
+// ShowRun returns the record of run runID. A run that does not exist yields
+// an error wrapping fs.ErrNotExist.
+func ShowRun(st *state.Store, runID string) (RunRecord, error) {
+	run, err := st.LoadRun(runID)
+	if errors.Is(err, fs.ErrNotExist) {
+		return RunRecord{}, fmt.Errorf("run %s not found: %w", runID, err)
+	}
+	if err != nil {
+		return RunRecord{}, err
+	}
+	rec := RunRecord{Run: run}
+	if f, ferr := st.LoadFailure(runID); ferr == nil {
+		rec.Failure = &f
+	}
+	rp, err := LoadRunParams(st, runID)
+	switch {
+	case err == nil:
+		rec.Params = &rp
+	case !errors.Is(err, ErrNoRunParams):
+		return RunRecord{}, err
+	}
+	return rec, nil
+}
+
+// Run statuses. The status recorded in a run's metadata stays "running", so
+// RunStatus derives it: a run is failed once it recorded a failure and
+// succeeded once it recorded its timeline. A run that crashed stays running.
+const (
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+)
+
+// RunStatus returns the status of the run of rec.
+func RunStatus(st *state.Store, rec RunRecord) string {
+	if rec.Failure != nil {
+		return RunStatusFailed
+	}
+	if _, err := st.LoadRunFile(rec.Run.RunID, TimelineFileName); err == nil {
+		return RunStatusSucceeded
+	}
+	return RunStatusRunning
+}
+
+// RunSummary is a run as listed by ListRuns.
+type RunSummary struct {
+	RunID     string    `json:"run_id"`
+	Status    string    `json:"status"`
+	Mode      string    `json:"mode"`
+	StartTime time.Time `json:"start_time"`
+	GraphHash string    `json:"graph_hash"`
+	// Graph is the graph file of the run, relative to the workdir when inside
+	// it; empty for runs that recorded no parameters.
+	Graph string `json:"graph,omitempty"`
+	// Schedule is the schedule that started the run, if any.
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// ListRuns returns the max most recent runs recorded in st, newest first.
+func ListRuns(st *state.Store, max int) ([]RunSummary, error) {
+	ids, err := st.ListRunIDs()
+	if err != nil {
+		return nil, err
+	}
+	// Run IDs are ULIDs, so the newest runs sort last.
+	slices.Reverse(ids)
+	out := []RunSummary{}
+	for _, id := range ids {
+		if len(out) == max {
+			break
+		}
+		rec, err := ShowRun(st, id)
+		if err != nil {
+			// A run that is being created or removed is skipped.
+			continue
+		}
+		sum := RunSummary{
+			RunID:     id,
+			Status:    RunStatus(st, rec),
+			Mode:      string(rec.Run.Mode),
+			StartTime: rec.Run.StartTime,
+			GraphHash: rec.Run.GraphHash,
+		}
+		if rec.Params != nil {
+			sum.Graph = rec.Params.Graph
+			sum.Schedule = rec.Params.Schedule
+		}
+		out = append(out, sum)
+	}
+	return out, nil
+}
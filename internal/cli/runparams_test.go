@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestExecute_RecordsParamsAndDiffsRuns(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "build", Run: "true"}, {Name: "lint", Run: "true"}}, nil)
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeIncremental}
+	run := func(inv CLIInvocation) string {
+		t.Helper()
+		res, err := Execute(context.Background(), inv)
+		if err != nil || res.ExitCode != ExitSuccess {
+			t.Fatalf("exit %d err %v", res.ExitCode, err)
+		}
+		return res.RunID
+	}
+	a := run(inv)
+	inv.ExecutionMode = ExecutionModeClean
+	inv.Workers = 4
+	inv.Skip = []string{"lint"}
+	b := run(inv)
+
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	rec, err := ShowRun(st, a)
+	if err != nil {
+		t.Fatalf("ShowRun: %v", err)
+	}
+	want := RunParams{Graph: "graph.json", OutputDir: "out", CacheDir: ".scriptweaver/cache", Mode: ExecutionModeIncremental}
+	if rec.Params == nil || !reflect.DeepEqual(*rec.Params, want) {
+		t.Fatalf("params = %+v, want %+v", rec.Params, want)
+	}
+
+	d, err := DiffRuns(st, a, b)
+	if err != nil {
+		t.Fatalf("DiffRuns: %v", err)
+	}
+	wantDiff := []ParamChange{
+		{Param: "mode", A: `"incremental"`, B: `"clean"`},
+		{Param: "skip", B: `["lint"]`},
+		{Param: "workers", B: "4"},
+	}
+	if !reflect.DeepEqual(d.Params, wantDiff) {
+		t.Fatalf("params diff = %#v, want %#v", d.Params, wantDiff)
+	}
+	if d, err := DiffRuns(st, a, a); err != nil || d.Params == nil || len(d.Params) != 0 {
+		t.Fatalf("self diff = %#v, %v", d, err)
+	}
+
+	// Runs that predate parameters are shown and compared without them.
+	if err := os.Remove(filepath.Join(workDir, ".scriptweaver", "runs", a, ParamsFileName)); err != nil {
+		t.Fatalf("remove params: %v", err)
+	}
+	if rec, err := ShowRun(st, a); err != nil || rec.Params != nil {
+		t.Fatalf("ShowRun = %+v, %v", rec, err)
+	}
+	if d, err := DiffRuns(st, a, b); err != nil || d.Params != nil {
+		t.Fatalf("DiffRuns = %#v, %v", d, err)
+	}
+}
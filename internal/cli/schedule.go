@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"scriptweaver/internal/cron"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/recovery/state"
+)
+
+// ScheduleStatus is a workspace schedule with its next and last run.
+type ScheduleStatus struct {
+	Name  string `json:"name"`
+	Cron  string `json:"cron"`
+	Graph string `json:"graph"`
+	// Next is the next time the schedule is due; zero when it never is.
+	Next time.Time `json:"next"`
+	// LastRunID, LastStart and LastStatus (see RunStatus) describe the most
+	// recent run the schedule started; empty when it started none.
+	LastRunID  string     `json:"last_run_id,omitempty"`
+	LastStart  *time.Time `json:"last_start,omitempty"`
+	LastStatus string     `json:"last_status,omitempty"`
+}
+
+// ScheduledInvocation returns the invocation of a run of schedule sc of the
+// workspace at workDir, whose config is cfg. Scheduled runs are traced to
+// trace.json in their output directory and recorded with the schedule's name.
+func ScheduledInvocation(workDir string, cfg config.Config, sc config.ScheduleConfig) (CLIInvocation, error) {
+	abs := func(p string) string {
+		if filepath.IsAbs(p) {
+			return filepath.Clean(p)
+		}
+		return filepath.Join(workDir, p)
+	}
+	graph := sc.Graph
+	if graph == "" {
+		graph = cfg.GraphPath
+	}
+	if graph == "" {
+		return CLIInvocation{}, fmt.Errorf("schedule %q: no graph", sc.Name)
+	}
+	out := sc.OutputDir
+	if out == "" {
+		out = filepath.Join(".sw", "schedules", sc.Name)
+	}
+	mode := ExecutionModeIncremental
+	if sc.Mode == config.ScheduleClean {
+		mode = ExecutionModeClean
+	}
+	inv := CLIInvocation{
+		GraphPath:     abs(graph),
+		WorkDir:       workDir,
+		OutputDir:     abs(out),
+		ExecutionMode: mode,
+		Params:        sc.Set,
+		Schedule:      sc.Name,
+	}
+	inv.Trace = TraceConfig{Enabled: true, Path: filepath.Join(inv.OutputDir, "trace.json")}
+	return inv, nil
+}
+
+// ScheduleStatuses returns the schedules of cfg, in configuration order,
+// with their next time after now and their last run recorded in st.
+func ScheduleStatuses(st *state.Store, cfg config.Config, now time.Time) ([]ScheduleStatus, error) {
+	out := make([]ScheduleStatus, 0, len(cfg.Schedules))
+	pending := make(map[string]int, len(cfg.Schedules))
+	for i, sc := range cfg.Schedules {
+		s, err := cron.Parse(sc.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", sc.Name, err)
+		}
+		graph := sc.Graph
+		if graph == "" {
+			graph = cfg.GraphPath
+		}
+		out = append(out, ScheduleStatus{Name: sc.Name, Cron: sc.Cron, Graph: graph, Next: s.Next(now)})
+		pending[sc.Name] = i
+	}
+	if len(pending) == 0 {
+		return out, nil
+	}
+	ids, err := st.ListRunIDs()
+	if err != nil {
+		return nil, err
+	}
+	// Run IDs sort by start time, so the newest runs are visited first.
+	slices.Reverse(ids)
+	for _, id := range ids {
+		rp, err := LoadRunParams(st, id)
+		if err != nil || rp.Schedule == "" {
+			continue
+		}
+		i, ok := pending[rp.Schedule]
+		if !ok {
+			continue
+		}
+		rec, err := ShowRun(st, id)
+		if err != nil {
+			// A run that is being created or removed is skipped.
+			continue
+		}
+		delete(pending, rp.Schedule)
+		start := rec.Run.StartTime
+		out[i].LastRunID, out[i].LastStart, out[i].LastStatus = id, &start, RunStatus(st, rec)
+		if len(pending) == 0 {
+			break
+		}
+	}
+	return out, nil
+}
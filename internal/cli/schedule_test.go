@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestScheduleStatuses_ReportNextAndLastRun(t *testing.T) {
+	workDir := t.TempDir()
+	writeGraphJSON(t, filepath.Join(workDir, "graph.json"), []core.Task{{Name: "a", Run: "echo a > a.txt", Outputs: []string{"a.txt"}}}, nil)
+	cfg := config.Config{GraphPath: "graph.json", Schedules: []config.ScheduleConfig{
+		{Name: "nightly", Cron: "30 2 * * *", Mode: config.ScheduleIncremental},
+		{Name: "weekly", Cron: "@weekly", Mode: config.ScheduleClean},
+	}}
+
+	inv, err := ScheduledInvocation(workDir, cfg, cfg.Schedules[0])
+	if err != nil {
+		t.Fatalf("ScheduledInvocation: %v", err)
+	}
+	outDir := filepath.Join(workDir, ".sw", "schedules", "nightly")
+	if inv.OutputDir != outDir || inv.ExecutionMode != ExecutionModeIncremental || inv.Schedule != "nightly" || inv.Trace.Path != filepath.Join(outDir, "trace.json") {
+		t.Fatalf("invocation = %+v", inv)
+	}
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("Execute: exit=%d err=%v", res.ExitCode, err)
+	}
+	if _, err := os.Stat(inv.Trace.Path); err != nil {
+		t.Fatalf("trace not written: %v", err)
+	}
+
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.Local)
+	statuses, err := ScheduleStatuses(st, cfg, now)
+	if err != nil || len(statuses) != 2 {
+		t.Fatalf("ScheduleStatuses = %+v, %v", statuses, err)
+	}
+	nightly, weekly := statuses[0], statuses[1]
+	if !nightly.Next.Equal(time.Date(2026, 3, 5, 2, 30, 0, 0, time.Local)) || nightly.Graph != "graph.json" {
+		t.Fatalf("nightly = %+v", nightly)
+	}
+	if nightly.LastRunID != res.RunID || nightly.LastStatus != RunStatusSucceeded || nightly.LastStart == nil {
+		t.Fatalf("nightly last run = %+v", nightly)
+	}
+	if !weekly.Next.Equal(time.Date(2026, 3, 8, 0, 0, 0, 0, time.Local)) || weekly.LastRunID != "" {
+		t.Fatalf("weekly = %+v", weekly)
+	}
+
+	runs, err := ListRuns(st, 10)
+	if err != nil || len(runs) != 1 || runs[0].Schedule != "nightly" || runs[0].Graph != "graph.json" {
+		t.Fatalf("ListRuns = %+v, %v", runs, err)
+	}
+}
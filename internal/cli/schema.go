@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"reflect"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+)
+
+// GraphFileSchema returns a JSON Schema of the graph files LoadGraphFromFile
+// reads, which sw run, sw validate and sw hash take, for editors to complete
+// and check them as they are written. It is generated from the types graph
+// files are decoded into (see graph.SchemaOf), so it allows exactly the
+// fields the loader accepts. Like the loader, it only requires the name of
+// each task, and matches the keys of edges in either case. The loader checks
+// more than the schema can express, such as edges naming existing tasks.
+func GraphFileSchema() map[string]any {
+	root := graph.SchemaOf(reflect.TypeOf(graphFile{}), "ScriptWeaver graph file",
+		"A graph file of sw run, sw validate and sw hash. Graph documents, with a schema_version, have a schema of their own.")
+	// A file may hold nothing but includes.
+	root["required"] = []string{}
+	props := root["properties"].(map[string]any)
+	task := props["tasks"].(map[string]any)["items"].(map[string]any)
+	task["required"] = []string{"name"}
+	props["edges"].(map[string]any)["items"] = edgeSchema()
+
+	// A task extending definitions may take any field but its name from
+	// them (see graph.ExpandTaskDefinitions).
+	taskProps := task["properties"].(map[string]any)
+	fragmentProps := make(map[string]any, len(taskProps))
+	for k, v := range taskProps {
+		if k != "name" {
+			fragmentProps[k] = v
+		}
+	}
+	taskProps[graph.ExtendsKey] = map[string]any{"oneOf": []any{
+		map[string]any{"type": "string"},
+		map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	}}
+	props[graph.DefinitionsKey] = map[string]any{
+		"type":                 "object",
+		"additionalProperties": map[string]any{"type": "object", "properties": fragmentProps, "additionalProperties": false},
+	}
+	return root
+}
+
+// edgeSchema returns the schema of an edge. Its keys have no JSON names, so
+// encoding/json matches them in any case; the schema allows them capitalized,
+// as dag.Edge encodes them, and in lower case.
+func edgeSchema() map[string]any {
+	node := func() map[string]any { return map[string]any{"type": "string"} }
+	typ := func() map[string]any {
+		return map[string]any{"type": "string", "enum": []string{string(dag.EdgeData), string(dag.EdgeOrder)}}
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"From": node(), "To": node(), "Type": typ(),
+			"from": node(), "to": node(), "type": typ(),
+		},
+		"allOf": []any{
+			map[string]any{"anyOf": []any{map[string]any{"required": []string{"From"}}, map[string]any{"required": []string{"from"}}}},
+			map[string]any{"anyOf": []any{map[string]any{"required": []string{"To"}}, map[string]any{"required": []string{"to"}}}},
+		},
+		"additionalProperties": false,
+	}
+}
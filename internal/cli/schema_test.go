@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// allowedKeys reports the first object key of v that schema, read as
+// properties, additionalProperties and items only, does not allow.
+func allowedKeys(schema map[string]any, v any, path string) error {
+	switch v := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		for k, child := range v {
+			sub, ok := props[k].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s.%s is not allowed", path, k)
+				}
+				sub = extra
+			}
+			if err := allowedKeys(sub, child, path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, child := range v {
+			if err := allowedKeys(items, child, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestGraphFileSchema_MatchesLoader(t *testing.T) {
+	data, err := json.Marshal(GraphFileSchema())
+	if err != nil {
+		t.Fatalf("marshal schema: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for k := range schema["properties"].(map[string]any) {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if want := []string{"definitions", "edges", "includes", "params", "success", "tasks"}; fmt.Sprint(keys) != fmt.Sprint(want) {
+		t.Fatalf("properties = %v, want %v", keys, want)
+	}
+	task := schema["properties"].(map[string]any)["tasks"].(map[string]any)["items"].(map[string]any)
+	if fmt.Sprint(task["required"]) != "[name]" {
+		t.Fatalf("task required = %v, want [name]", task["required"])
+	}
+
+	// Every graph file the loader accepts, with edges spelled either way,
+	// uses only properties the schema allows.
+	dir := t.TempDir()
+	lower := filepath.Join(dir, "lower.json")
+	if err := os.WriteFile(lower, []byte(`{"tasks": [{"name": "a", "run": "true"}, {"name": "b", "run": "true"}], "edges": [{"from": "a", "to": "b", "type": "order"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defs := filepath.Join(dir, "defs.json")
+	if err := os.WriteFile(defs, []byte(`{"definitions": {"go": {"env": {"CGO_ENABLED": "0"}, "retries": 1}}, "tasks": [{"name": "a", "extends": ["go"], "run": "go build"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join("..", "..", "fixtures", "basic.json"), lower, defs} {
+		if _, err := LoadGraphFromFile(path); err != nil {
+			t.Fatalf("LoadGraphFromFile(%s): %v", path, err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var v any
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Fatal(err)
+		}
+		if err := allowedKeys(schema, v, filepath.Base(path)); err != nil {
+			t.Fatalf("schema rejects a graph file the loader accepts: %v", err)
+		}
+	}
+	if err := allowedKeys(schema, map[string]any{"schema_version": "2.0.0"}, "document"); err == nil {
+		t.Fatalf("schema allows schema_version, which the loader rejects")
+	}
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"os"
+	"sync"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+// Session keeps engine state warm across executions in a long-lived process
+// such as `sw daemon`.
+//
+// It memoizes parsed and validated graphs by file content and serves unchanged
+// task inputs from a core.StatCache. Results are identical to Execute; only
+// repeated work is skipped.
+//
+// Executions within a Session are serialized: a run requested while another
+// is active waits in a queue, is coalesced into an identical queued run or is
+// refused with ErrWorkspaceBusy, per the run_queue settings of the workspace
+// (see config.RunQueueConfig).
+type Session struct {
+	qmu    sync.Mutex
+	active *queuedRun
+	queued []*queuedRun
+
+	mu     sync.Mutex
+	graphs map[string]sessionGraph
+	stats  *core.StatCache
+}
+
+type sessionGraph struct {
+	// sources holds the content of the graph file and of every file it
+	// includes, by path.
+	sources   map[string][]byte
+	params    map[string]string
+	graph     *dag.TaskGraph
+	hash      string
+	tolerated map[string]bool
+}
+
+// unchanged reports whether every source of the graph still has the content
+// it was parsed from.
+func (c sessionGraph) unchanged() bool {
+	for path, want := range c.sources {
+		b, err := os.ReadFile(path)
+		if err != nil || !bytes.Equal(b, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewSession returns an empty Session.
+func NewSession() *Session {
+	return &Session{graphs: make(map[string]sessionGraph), stats: core.NewStatCache()}
+}
+
+// Execute runs inv like Execute, reusing the session's warm state, once the
+// runs queued before it finished. A request whose context ends while it
+// waits leaves the queue, unless identical requests were coalesced into it.
+func (s *Session) Execute(ctx context.Context, inv CLIInvocation) (res CLIResult, err error) {
+	r, shared, err := s.enqueue(inv, queuePolicy(inv.WorkDir))
+	if err != nil {
+		return CLIResult{ExitCode: ExitConfigError}, err
+	}
+	if shared {
+		<-r.done
+		return r.res, r.err
+	}
+	select {
+	case <-r.ready:
+	case <-ctx.Done():
+		if s.withdraw(r) {
+			return CLIResult{ExitCode: ExitCancelled}, ctx.Err()
+		}
+		<-r.ready
+	}
+	defer func() { s.finish(r, res, err) }()
+	return executeWith(ctx, inv, defaultGraphExecutor{}, s, nil, nil)
+}
+
+// loadGraph returns the graph at path with the given parameter values, reusing
+// the previous parse when the values, and the content of the file and of the
+// files it includes, are unchanged. Reading the files is cheap relative to
+// decoding, validating and hashing them.
+func (s *Session) loadGraph(path string, params map[string]string, phases *phaseTimer) (*dag.TaskGraph, string, map[string]bool, error) {
+	s.mu.Lock()
+	cached, ok := s.graphs[path]
+	s.mu.Unlock()
+	if ok && maps.Equal(cached.params, params) && cached.unchanged() {
+		phases.mark(PhaseParse)
+		return cached.graph, cached.hash, cached.tolerated, nil
+	}
+
+	gf, sources, err := readGraph(path, params)
+	phases.mark(PhaseParse)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	g, tolerated, err := buildGraph(gf)
+	phases.mark(PhaseValidate)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	hash := g.Hash().String()
+	phases.mark(PhaseHash)
+
+	s.mu.Lock()
+	s.graphs[path] = sessionGraph{sources: sources, params: params, graph: g, hash: hash, tolerated: tolerated}
+	s.mu.Unlock()
+	return g, hash, tolerated, nil
+}
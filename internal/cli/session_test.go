@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"scriptweaver/internal/core"
+)
+
+func TestSession_ReusesGraphAndInputsAcrossRuns(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "in.txt"), []byte("x"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Inputs: []string{"in.txt"}, Run: "cat in.txt > a.txt", Outputs: []string{"a.txt"}}}, nil)
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeIncremental,
+	}
+
+	s := NewSession()
+	first, err := s.Execute(context.Background(), inv)
+	if err != nil || first.ExitCode != ExitSuccess {
+		t.Fatalf("first run: exit=%d err=%v", first.ExitCode, err)
+	}
+	cached := s.graphs[graphPath].graph
+
+	second, err := s.Execute(context.Background(), inv)
+	if err != nil || second.ExitCode != ExitSuccess {
+		t.Fatalf("second run: exit=%d err=%v", second.ExitCode, err)
+	}
+	if s.graphs[graphPath].graph != cached {
+		t.Fatalf("unchanged graph must be reused")
+	}
+	if s.stats.Len() == 0 {
+		t.Fatalf("expected inputs in the stat cache")
+	}
+	if second.GraphResult.GraphHash != first.GraphResult.GraphHash {
+		t.Fatalf("graph hash changed between runs")
+	}
+
+	// Editing the graph invalidates the memoized parse.
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "b", Inputs: []string{}, Run: "true", Outputs: []string{}}}, nil)
+	third, err := s.Execute(context.Background(), inv)
+	if err != nil || third.ExitCode != ExitSuccess {
+		t.Fatalf("third run: exit=%d err=%v", third.ExitCode, err)
+	}
+	if _, ok := third.GraphResult.FinalState["b"]; !ok {
+		t.Fatalf("edited graph was not reloaded")
+	}
+}
+
+func TestSession_ReloadsWhenAnIncludedFileChanges(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	if err := os.WriteFile(graphPath, []byte(`{"includes": [{"path": "team.json", "namespace": "team"}], "tasks": [], "edges": []}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	teamPath := filepath.Join(workDir, "team.json")
+	writeGraphJSON(t, teamPath, []core.Task{{Name: "a", Inputs: []string{}, Run: "true"}}, nil)
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean}
+
+	s := NewSession()
+	if res, err := s.Execute(context.Background(), inv); err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("first run: exit=%d err=%v", res.ExitCode, err)
+	}
+	writeGraphJSON(t, teamPath, []core.Task{{Name: "b", Inputs: []string{}, Run: "true"}}, nil)
+	res, err := s.Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("second run: exit=%d err=%v", res.ExitCode, err)
+	}
+	if _, ok := res.GraphResult.FinalState["team/b"]; !ok {
+		t.Fatalf("edited include was not reloaded: %v", res.GraphResult.FinalState)
+	}
+}
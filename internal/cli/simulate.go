@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"scriptweaver/internal/dag"
+)
+
+// loadScenario reads the simulation scenario at path and checks it against
+// the graph it is to be played on.
+func loadScenario(path string, g *dag.TaskGraph) (*dag.Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read scenario: %w", err)
+	}
+	sc, err := dag.ParseScenario(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.Validate(g); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
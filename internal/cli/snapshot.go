@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"encoding/json"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/incremental"
+	"scriptweaver/internal/recovery/state"
+)
+
+// SnapshotFileName is the run file that records the graph a run ran.
+const SnapshotFileName = "snapshot.json"
+
+// PlanFileName is the run file that records what a run planned to do with
+// each node.
+const PlanFileName = "plan.json"
+
+// RunPlan is the plan of a run: its nodes in execution order and the
+// decision taken for each before it started. Nodes decided Execute may still
+// be restored from the cache by an incremental run.
+type RunPlan struct {
+	Order     []string                                     `json:"order"`
+	Decisions map[string]incremental.NodeExecutionDecision `json:"decisions"`
+}
+
+// newRunPlan returns the plan of a run of g: plan when the run has one, such
+// as a resumed or isolated run, and otherwise every node executed but the
+// ones in skip.
+func newRunPlan(g *dag.TaskGraph, plan *incremental.IncrementalPlan, skip []string) RunPlan {
+	if plan != nil {
+		return RunPlan{Order: plan.Order, Decisions: plan.Decisions}
+	}
+	rp := RunPlan{Order: g.TopologicalOrder(), Decisions: make(map[string]incremental.NodeExecutionDecision)}
+	for _, name := range rp.Order {
+		rp.Decisions[name] = incremental.DecisionExecute
+	}
+	for _, name := range skip {
+		rp.Decisions[name] = incremental.DecisionSkip
+	}
+	return rp
+}
+
+// saveRunSnapshot records g, the graph of run runID after parameters,
+// includes, output namespacing and lockfile pins, as a run file, best-effort.
+// It is written as a graph file, which loads to a graph of the run's hash.
+func saveRunSnapshot(st *state.Store, runID string, g *dag.TaskGraph) {
+	gf := graphFile{Edges: g.Edges()}
+	for _, n := range g.Nodes() {
+		gf.Tasks = append(gf.Tasks, n.Task)
+	}
+	if gf.Tasks == nil {
+		gf.Tasks = []core.Task{}
+	}
+	data, err := json.MarshalIndent(gf, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = st.SaveRunFile(runID, SnapshotFileName, append(data, '\n'))
+}
+
+// saveRunPlan records rp as a run file, best-effort.
+func saveRunPlan(st *state.Store, runID string, rp RunPlan) {
+	data, err := json.MarshalIndent(rp, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = st.SaveRunFile(runID, PlanFileName, append(data, '\n'))
+}
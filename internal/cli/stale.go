@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scriptweaver/internal/audit"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+// StaleOutput is a file a node produced in an earlier run that no node of the
+// current graph declares as an output any more, typically because its node
+// was removed from the graph.
+type StaleOutput struct {
+	// Node is the node that last produced the file.
+	Node string `json:"node"`
+	// Path is relative to the workdir, in slash form.
+	Path string `json:"path"`
+}
+
+// recordOutputOwnership adds the files of outputs to the workspace's output
+// ownership record.
+func recordOutputOwnership(st *state.Store, outputs []NodeOutputs) error {
+	o, err := st.LoadOutputOwnership()
+	if err != nil {
+		return err
+	}
+	for _, no := range outputs {
+		files := make([]string, 0, len(no.Files))
+		for _, f := range no.Files {
+			files = append(files, f.Path)
+		}
+		o.Add(no.Node, files)
+	}
+	return st.SaveOutputOwnership(o)
+}
+
+// unownedOutputs lists the recorded files of o that no declared output of g
+// covers, whether or not they still exist, ordered by path then node.
+func unownedOutputs(o state.OutputOwnership, g *dag.TaskGraph) []StaleOutput {
+	var declared []string
+	for _, n := range g.Nodes() {
+		for _, out := range n.Task.Outputs {
+			declared = append(declared, path.Clean(filepath.ToSlash(out)))
+		}
+	}
+	covered := func(file string) bool {
+		for _, d := range declared {
+			if file == d || strings.HasPrefix(file, d+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	var out []StaleOutput
+	for node, files := range o.Nodes {
+		for _, f := range files {
+			if !covered(f) {
+				out = append(out, StaleOutput{Node: node, Path: f})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path {
+			return out[i].Path < out[j].Path
+		}
+		return out[i].Node < out[j].Node
+	})
+	return out
+}
+
+// findStaleOutputs returns the stale outputs of g that still exist in
+// workDir.
+func findStaleOutputs(workDir string, st *state.Store, g *dag.TaskGraph) ([]StaleOutput, error) {
+	o, err := st.LoadOutputOwnership()
+	if err != nil {
+		return nil, err
+	}
+	var out []StaleOutput
+	for _, s := range unownedOutputs(o, g) {
+		if _, err := os.Lstat(filepath.Join(workDir, filepath.FromSlash(s.Path))); err == nil {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// CleanStaleOutputs finds the stale outputs of the graph inv describes and,
+// unless dryRun is set, removes them, along with directories left empty, and
+// forgets them. The graph is namespaced as a run with inv would namespace it.
+// Files that are already gone are forgotten without being reported.
+func CleanStaleOutputs(inv CLIInvocation, dryRun bool) ([]StaleOutput, int, error) {
+	g, _, _, err := loadGraphAndHash(inv, newPhaseTimer(), nil)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+			return nil, ExitInvalidInvocation, err
+		}
+		return nil, ExitValidationError, err
+	}
+	if inv.NamespaceOutputs {
+		outputRel, err := outputDirRel(inv)
+		if err != nil {
+			return nil, ExitInvalidInvocation, err
+		}
+		if g, err = namespaceOutputs(g, outputRel); err != nil {
+			return nil, ExitValidationError, err
+		}
+	}
+	st, err := state.NewStore(inv.WorkDir)
+	if err != nil {
+		return nil, ExitInvalidInvocation, err
+	}
+	o, err := st.LoadOutputOwnership()
+	if err != nil {
+		return nil, ExitConfigError, err
+	}
+
+	auditLog, _ := audit.Open(inv.WorkDir, "")
+	st.SetAuditLog(auditLog)
+	var removed []StaleOutput
+	for _, s := range unownedOutputs(o, g) {
+		if !filepath.IsLocal(filepath.FromSlash(s.Path)) {
+			return removed, ExitConfigError, fmt.Errorf("recorded output %q of %q is outside the workdir", s.Path, s.Node)
+		}
+		p := filepath.Join(inv.WorkDir, filepath.FromSlash(s.Path))
+		if _, err := os.Lstat(p); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return removed, ExitConfigError, err
+			}
+			o.Remove(s.Node, s.Path)
+			continue
+		}
+		removed = append(removed, s)
+		if dryRun {
+			continue
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, ExitConfigError, fmt.Errorf("remove stale output: %w", err)
+		}
+		_ = auditLog.Record(audit.KindOutputClear, s.Path)
+		removeEmptyParents(inv.WorkDir, filepath.Dir(p))
+		o.Remove(s.Node, s.Path)
+	}
+	if !dryRun {
+		if err := st.SaveOutputOwnership(o); err != nil {
+			return removed, ExitConfigError, err
+		}
+	}
+	return removed, ExitSuccess, nil
+}
+
+// removeEmptyParents removes dir and its parents while they are empty,
+// stopping at root.
+func removeEmptyParents(root, dir string) {
+	for dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)) {
+		if os.Remove(dir) != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
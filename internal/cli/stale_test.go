@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+)
+
+func TestStaleOutputs_ReportedAfterNodeRemovalAndCleaned(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "keep", Run: "printf k > keep.txt", Outputs: []string{"keep.txt"}},
+		{Name: "gone", Run: "mkdir -p gen/sub && printf g > gen/sub/gone.txt", Outputs: []string{"gen"}},
+	}, nil)
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, CacheDir: filepath.Join(workDir, "cache"), OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeIncremental}
+	if res, err := Execute(context.Background(), inv); err != nil || res.ExitCode != ExitSuccess || len(res.StaleOutputs) != 0 {
+		t.Fatalf("first run: exit %d err %v stale %v", res.ExitCode, err, res.StaleOutputs)
+	}
+
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "keep", Run: "printf k > keep.txt", Outputs: []string{"keep.txt"}},
+	}, nil)
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("second run: exit %d err %v", res.ExitCode, err)
+	}
+	want := []StaleOutput{{Node: "gone", Path: "gen/sub/gone.txt"}}
+	if !reflect.DeepEqual(res.StaleOutputs, want) {
+		t.Fatalf("stale outputs = %#v, want %#v", res.StaleOutputs, want)
+	}
+
+	got, code, err := CleanStaleOutputs(inv, true)
+	if err != nil || code != ExitSuccess || !reflect.DeepEqual(got, want) {
+		t.Fatalf("dry run: %#v code %d err %v", got, code, err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "gen", "sub", "gone.txt")); err != nil {
+		t.Fatalf("dry run removed the file: %v", err)
+	}
+
+	got, code, err = CleanStaleOutputs(inv, false)
+	if err != nil || code != ExitSuccess || !reflect.DeepEqual(got, want) {
+		t.Fatalf("clean: %#v code %d err %v", got, code, err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "gen")); !os.IsNotExist(err) {
+		t.Fatalf("expected gen/ to be removed once empty, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, "keep.txt")); err != nil {
+		t.Fatalf("owned output removed: %v", err)
+	}
+	if got, _, err := CleanStaleOutputs(inv, false); err != nil || len(got) != 0 {
+		t.Fatalf("second clean: %#v err %v", got, err)
+	}
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+)
+
+// SuccessCriteria decide whether a run of the graph succeeded, in place of
+// the default rule that any failed node fails the run. Both fields are
+// selectors (see Selector).
+//
+// A run succeeds when every node that Require selects and AllowFailure does
+// not neither failed nor was skipped because a dependency failed. Nodes
+// skipped on request (--skip) never fail it.
+type SuccessCriteria struct {
+	// Require selects the nodes that must succeed. Empty selects every node.
+	Require string `json:"require,omitempty"`
+	// AllowFailure selects nodes that may fail even when Require selects
+	// them. Empty selects none.
+	AllowFailure string `json:"allow_failure,omitempty"`
+}
+
+// tolerated returns the nodes of g whose failure does not fail the run. It is
+// nil for nil criteria, under which every failure does.
+func (c *SuccessCriteria) tolerated(g *dag.TaskGraph) (map[string]bool, error) {
+	if c == nil {
+		return nil, nil
+	}
+	selected := func(field, expr string) (map[string]bool, error) {
+		names, err := QueryNodes(g, expr)
+		if err != nil {
+			return nil, &graph.SemanticError{Msg: fmt.Sprintf("success.%s: %v", field, err)}
+		}
+		set := make(map[string]bool, len(names))
+		for _, name := range names {
+			set[name] = true
+		}
+		return set, nil
+	}
+	require := "all"
+	if c.Require != "" {
+		require = c.Require
+	}
+	required, err := selected("require", require)
+	if err != nil {
+		return nil, err
+	}
+	allowed := map[string]bool{}
+	if c.AllowFailure != "" {
+		if allowed, err = selected("allow_failure", c.AllowFailure); err != nil {
+			return nil, err
+		}
+	}
+	out := make(map[string]bool)
+	for _, n := range g.Nodes() {
+		if !required[n.Task.Name] || allowed[n.Task.Name] {
+			out[n.Task.Name] = true
+		}
+	}
+	return out, nil
+}
+
+// runSucceeded applies the success criteria, resolved to the nodes whose
+// failure is tolerated, to gr.
+func runSucceeded(gr *dag.GraphResult, tolerated map[string]bool) bool {
+	for name, st := range gr.FinalState {
+		if tolerated[name] {
+			continue
+		}
+		if st == dag.TaskFailed || (st == dag.TaskSkipped && gr.SkipCause[name] != "") {
+			return false
+		}
+	}
+	return true
+}
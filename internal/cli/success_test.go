@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"scriptweaver/internal/graph"
+)
+
+func TestSuccessCriteria_DecideExitCode(t *testing.T) {
+	const tasks = `"tasks": [
+  {"name": "build", "inputs": [], "run": "true", "labels": ["required"]},
+  {"name": "lint", "inputs": [], "run": "exit 1", "labels": ["optional"]},
+  {"name": "report", "inputs": [], "run": "true"}
+ ],
+ "edges": [{"From": "lint", "To": "report"}]`
+
+	cases := []struct {
+		name    string
+		success string
+		want    int
+	}{
+		{"default fails on any failure", ``, ExitGraphFailure},
+		{"allowed failure", `"success": {"allow_failure": "label=optional"},`, ExitGraphFailure},
+		{"allowed failure and its dependents", `"success": {"allow_failure": "label=optional | rdeps(label=optional)"},`, ExitSuccess},
+		{"only required nodes", `"success": {"require": "label=required"},`, ExitSuccess},
+		{"required node failed", `"success": {"require": "lint"},`, ExitGraphFailure},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			graphPath := filepath.Join(workDir, "graph.json")
+			writeFile(t, graphPath, "{"+tc.success+tasks+"}")
+			res, err := Execute(context.Background(), CLIInvocation{WorkDir: workDir, GraphPath: graphPath, OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean})
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if res.ExitCode != tc.want {
+				t.Fatalf("exit code = %d, want %d", res.ExitCode, tc.want)
+			}
+		})
+	}
+}
+
+func TestSuccessCriteria_InvalidSelectorIsSemanticError(t *testing.T) {
+	graphPath := filepath.Join(t.TempDir(), "graph.json")
+	writeFile(t, graphPath, `{"success": {"require": "missing"}, "tasks": [{"name": "a", "inputs": [], "run": "true"}], "edges": []}`)
+	_, err := LoadGraphFromFile(graphPath)
+	var se *graph.SemanticError
+	if !errors.As(err, &se) {
+		t.Fatalf("err = %v, want a *graph.SemanticError", err)
+	}
+}
@@ -3,27 +3,50 @@ package sw
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"log"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"scriptweaver/exitcode"
+	"scriptweaver/internal/audit"
+	"scriptweaver/internal/bench"
+	"scriptweaver/internal/chaos"
 	"scriptweaver/internal/cli"
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/daemon"
 	"scriptweaver/internal/dag"
+	"scriptweaver/internal/dashboard"
+	"scriptweaver/internal/graph"
 	"scriptweaver/internal/pluginengine"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/projectintegration/engine/workspace"
+	"scriptweaver/internal/recovery/state"
 )
 
+// Exit codes returned by Main; see package exitcode for their meaning.
 const (
-	ExitSuccess          = 0
-	ExitValidationError  = 1
-	ExitArgOrSystemError = 2
-	ExitExecutionFailure = 3
-	ExitPluginError      = 4
+	ExitSuccess          = exitcode.Success
+	ExitValidationError  = exitcode.Validation
+	ExitUsageError       = exitcode.Usage
+	ExitExecutionFailure = exitcode.Execution
+	ExitPluginError      = exitcode.Plugin
+	ExitWorkspaceError   = exitcode.Workspace
+	ExitCancelled        = exitcode.Cancelled
+	ExitInternalError    = exitcode.Internal
+	ExitOffline          = exitcode.Offline
+	ExitWarnings         = exitcode.Warnings
 )
 
 // Main is the canonical entrypoint for the `sw` CLI.
@@ -37,8 +60,8 @@ func Main(args []string, stdout, stderr io.Writer) int {
 	}
 
 	if len(args) == 0 {
-		fmt.Fprintln(stderr, "missing command (expected: run|validate|hash|plugins)")
-		return ExitArgOrSystemError
+		say(stderr, MsgMissingCommand, "run|validate|hash|schema|fmt|graph|bench|daemon|serve|cache|plugins|audit|runs|trace|clean")
+		return ExitUsageError
 	}
 
 	switch args[0] {
@@ -51,20 +74,70 @@ func Main(args []string, stdout, stderr io.Writer) int {
 		return cmdValidate(args[1:], stdout, stderr)
 	case "hash":
 		return cmdHash(args[1:], stdout, stderr)
+	case "schema":
+		return cmdSchema(args[1:], stdout, stderr)
+	case "fmt":
+		return cmdFmt(args[1:], stdout, stderr)
+	case "graph":
+		return cmdGraph(args[1:], stdout, stderr)
+	case "bench":
+		return cmdBench(args[1:], stdout, stderr)
+	case "daemon":
+		return cmdDaemon(args[1:], stdout, stderr)
+	case "serve":
+		return cmdServe(args[1:], stdout, stderr)
+	case "cache":
+		return cmdCache(args[1:], stdout, stderr)
 	case "plugins":
 		return cmdPlugins(args[1:], stdout, stderr)
+	case "audit":
+		return cmdAudit(args[1:], stdout, stderr)
+	case "runs":
+		return cmdRuns(args[1:], stdout, stderr)
+	case "trace":
+		return cmdTrace(args[1:], stdout, stderr)
+	case "clean":
+		return cmdClean(args[1:], stdout, stderr)
+	case "workspace":
+		return cmdWorkspace(args[1:], stdout, stderr)
 	default:
-		fmt.Fprintf(stderr, "unknown command: %s\n", args[0])
-		return ExitArgOrSystemError
+		say(stderr, MsgUnknownCommand, args[0])
+		return ExitUsageError
 	}
 }
 
 func printHelp(w io.Writer) {
 	fmt.Fprintln(w, "Usage:")
-	fmt.Fprintln(w, "  sw run --graph <path> --workdir <path> [--cache-dir <path>] [--output-dir <path>] [--resume <run-id>] [--plugin-dir <path>] [--trace] [--mode <clean|incremental>]")
-	fmt.Fprintln(w, "  sw validate --graph <path>")
-	fmt.Fprintln(w, "  sw hash --graph <path> [--workdir <path>]")
-	fmt.Fprintln(w, "  sw plugins list [--plugin-dir <path>]")
+	fmt.Fprintln(w, "  sw run --graph <path> --workdir <path> [--cache-dir <path>] [--output-dir <path>] [--resume <run-id>] [--plugin-dir <path>] [--plugins <id,...>] [--allow-unsigned-plugins] [--trace] [--mode <clean|incremental>] [--checkpoint] [--retries <n>] [--retry-backoff <duration>] [--dedupe] [--namespace-outputs] [--verify-determinism <n>] [--profile <cpu|mem>=<path>] [--list-outputs] [--outputs-json <path>] [--progress <path>] [--skip <selector,...>] [--skip-policy <skip|fail>] [--node <id> [--isolated]] [--print-commands] [--dry-run] [--audit-determinism <workers>] [--simulate <scenario.json>] [--chaos <spec>] [--offline] [--set <name>=<value>]... [-v] [--no-daemon]")
+	fmt.Fprintln(w, "  sw validate --graph <path> [--workdir <path>] [--plugin-dir <path>] [--plugins <id,...>] [--allow-unsigned-plugins] [--locked] [--strict] [--set <name>=<value>]... [--format text|json|sarif]")
+	fmt.Fprintln(w, "  sw hash --graph <path> [--workdir <path>] [--explain <node>]")
+	fmt.Fprintln(w, "  sw schema [--document] [--out <path>]")
+	fmt.Fprintln(w, "  sw fmt --graph <path> [--check]")
+	fmt.Fprintln(w, "  sw graph stats --graph <path> [--format text|json]")
+	fmt.Fprintln(w, "  sw graph query <selector> --graph <path>")
+	fmt.Fprintln(w, "  sw bench --graph <path> --workdir <path> [--iterations <n>] [--mode <clean|incremental>] [--baseline <path>] [--save-baseline <path>]")
+	fmt.Fprintln(w, "  sw daemon --workdir <path>")
+	fmt.Fprintln(w, "  sw daemon metrics --workdir <path>")
+	fmt.Fprintln(w, "  sw daemon queue --workdir <path> [--json]")
+	fmt.Fprintln(w, "  sw serve --workdir <path> [--addr <host:port>]")
+	fmt.Fprintln(w, "  sw cache warm --graph <path> --workdir <path> [--cache-dir <path>]")
+	fmt.Fprintln(w, "  sw cache rekey --workdir <path> [--cache-dir <path>]")
+	fmt.Fprintln(w, "  sw plugins list [--workdir <path>] [--plugin-dir <path>] [--format text|json] [--verbose]")
+	fmt.Fprintln(w, "  sw plugins stats [<run-id>] --workdir <path>")
+	fmt.Fprintln(w, "  sw plugins new <plugin-id> [--plugin-dir <path>] [--protocol <exec|go>] [--hooks <hook,...>]")
+	fmt.Fprintln(w, "  sw plugins doctor [--workdir <path>] [--plugin-dir <path>]")
+	fmt.Fprintln(w, "  sw audit verify --workdir <path>")
+	fmt.Fprintln(w, "  sw runs list --workdir <path> [--limit <n>] [--json]")
+	fmt.Fprintln(w, "  sw runs show <run-id> --workdir <path> [--json]")
+	fmt.Fprintln(w, "  sw runs timeline <run-id> --workdir <path> [--json]")
+	fmt.Fprintln(w, "  sw runs diff <run-id> <run-id> --workdir <path> [--json]")
+	fmt.Fprintln(w, "  sw runs cache <run-id> --workdir <path> [--json]")
+	fmt.Fprintln(w, "  sw runs attempts <run-id> --workdir <path> [--json]")
+	fmt.Fprintln(w, "  sw trace replay <trace.json> [--workdir <path>] [--output-dir <path>] [--plugin-dir <path>] [--plugins <id,...>] [--allow-unsigned-plugins]")
+	fmt.Fprintln(w, "  sw trace finalize <run-id> --workdir <path> [--out <trace.json>] [--json]")
+	fmt.Fprintln(w, "  sw workspace export --run <run-id> --workdir <path> --output <bundle.tar.zst> [--include-cache]")
+	fmt.Fprintln(w, "  sw workspace import <bundle.tar.zst> --workdir <path>")
+	fmt.Fprintln(w, "  sw clean --stale-outputs --graph <path> --workdir <path> [--output-dir <path>] [--namespace-outputs] [--dry-run]")
 }
 
 type strictFlagSet struct {
@@ -81,14 +154,14 @@ func (s *strictFlagSet) parse(args []string, stderr io.Writer) error {
 	if err := s.fs.Parse(args); err != nil {
 		msg := err.Error()
 		if strings.Contains(msg, "flag provided but not defined") {
-			fmt.Fprintln(stderr, "unknown flag")
+			say(stderr, MsgUnknownFlag)
 		} else {
-			fmt.Fprintln(stderr, msg)
+			say(stderr, MsgUsageError, msg)
 		}
 		return err
 	}
 	if s.fs.NArg() != 0 {
-		fmt.Fprintf(stderr, "unexpected positional arguments: %q\n", strings.Join(s.fs.Args(), " "))
+		say(stderr, MsgUnexpectedArgs, strings.Join(s.fs.Args(), " "))
 		return fmt.Errorf("unexpected positional arguments")
 	}
 	return nil
@@ -120,12 +193,56 @@ func absUnderWorkdir(workdirAbs, p string) (string, error) {
 	return filepath.Clean(filepath.Join(workdirAbs, clean)), nil
 }
 
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// paramFlag collects repeated --set name=value graph parameter values.
+type paramFlag map[string]string
+
+func (p *paramFlag) String() string {
+	if p == nil || len(*p) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(*p))
+	for name := range *p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + (*p)[name]
+	}
+	return strings.Join(names, ",")
+}
+
+func (p *paramFlag) Set(v string) error {
+	name, value, err := cli.ParseParamAssignment(v)
+	if err != nil {
+		return err
+	}
+	if *p == nil {
+		*p = make(paramFlag)
+	}
+	if _, dup := (*p)[name]; dup {
+		return fmt.Errorf("parameter %q set twice", name)
+	}
+	(*p)[name] = value
+	return nil
+}
+
 func isGraphValidationErr(err error) bool {
 	if err == nil {
 		return false
 	}
 	var ge *dag.GraphError
-	if errors.As(err, &ge) {
+	if errors.As(err, &ge) || errors.Is(err, graph.ErrSemantic) {
 		return true
 	}
 	msg := strings.ToLower(err.Error())
@@ -148,232 +265,2584 @@ func cmdRun(args []string, stdout, stderr io.Writer) int {
 	var outputDir string
 	var resumeID string
 	var pluginDir string
+	var pluginIDs string
 	var trace bool
 	var mode string
+	var dedupe bool
+	var checkpoint bool
+	var verifyN int
+	var retries int
+	var retryBackoff time.Duration
+	var verbose bool
+	var profiles profileFlag
+	var params paramFlag
+	var noDaemon bool
+	var allowUnsigned bool
+	var listOutputs bool
+	var namespaceOutputs bool
+	var outputsJSON string
+	var progressPath string
+	var skip string
+	var skipPolicy string
+	var node string
+	var isolated bool
+	var printCommands bool
+	var auditWorkers int
+	var simulate string
+	var chaosSpec string
+	var offline bool
+	var dryRun bool
 
 	s.fs.StringVar(&graphPath, "graph", "", "Path to the graph definition file")
 	s.fs.StringVar(&workdir, "workdir", "", "Root directory for execution context")
-	s.fs.StringVar(&cacheDir, "cache-dir", ".sw/cache", "Directory for deterministic artifact caching")
+	s.fs.StringVar(&cacheDir, "cache-dir", "", "Directory for deterministic artifact caching (default <workdir>/.scriptweaver/cache)")
 	s.fs.StringVar(&outputDir, "output-dir", ".sw/output", "Directory for execution outputs")
 	s.fs.StringVar(&resumeID, "resume", "", "ID of a previous run to resume")
 	s.fs.StringVar(&pluginDir, "plugin-dir", "", "Directory containing compiled plugins")
+	s.fs.StringVar(&pluginIDs, "plugins", "", "Comma-separated plugin IDs whose hooks run during execution")
+	s.fs.BoolVar(&allowUnsigned, "allow-unsigned-plugins", false, "Load plugins whose signature is missing or invalid")
 	s.fs.BoolVar(&trace, "trace", false, "Enable deterministic trace logging")
 	s.fs.StringVar(&mode, "mode", "incremental", "Execution strategy: clean|incremental")
+	s.fs.BoolVar(&namespaceOutputs, "namespace-outputs", false, "Place each node's declared outputs under <output-dir>/<node>/")
+	s.fs.BoolVar(&dedupe, "dedupe", false, "Execute byte-identical nodes once and share the result")
+	s.fs.BoolVar(&checkpoint, "checkpoint", false, "Record checkpoints in clean mode so a failed run can be resumed")
+	s.fs.IntVar(&retries, "retries", 0, "Retry a run that fails transiently up to N times, resuming the failed run")
+	s.fs.DurationVar(&retryBackoff, "retry-backoff", cli.DefaultRetryBackoff, "Delay before the first retry; doubled for every further retry")
+	s.fs.IntVar(&verifyN, "verify-determinism", 0, "Re-execute N sampled tasks twice and report nondeterministic ones")
+	s.fs.BoolVar(&verbose, "v", false, "Print engine phase timings to stderr")
+	s.fs.Var(&profiles, "profile", "Write an engine profile: cpu=<path> or mem=<path> (repeatable)")
+	s.fs.BoolVar(&noDaemon, "no-daemon", false, "Execute in this process even if a daemon is running")
+	s.fs.BoolVar(&listOutputs, "list-outputs", false, "Print the files each node produced, with sizes and hashes")
+	s.fs.StringVar(&outputsJSON, "outputs-json", "", "Write the files each node produced, with sizes and hashes, as JSON to this path")
+	s.fs.StringVar(&progressPath, "progress", "", "Write aggregate progress events as JSON Lines to this path while the graph runs")
+	s.fs.StringVar(&skip, "skip", "", "Comma-separated nodes or selectors not to run")
+	s.fs.StringVar(&skipPolicy, "skip-policy", string(dag.SkipDependents), "What happens to dependents of skipped nodes: skip|fail")
+	s.fs.StringVar(&node, "node", "", "Run only this node and its dependencies")
+	s.fs.BoolVar(&isolated, "isolated", false, "With --node, restore the dependencies from the cache instead of running them")
+	s.fs.IntVar(&auditWorkers, "audit-determinism", 0, "Run the graph serially, then in parallel on N workers, and report any divergence (requires --mode clean)")
+	s.fs.StringVar(&simulate, "simulate", "", "Play the scripted outcomes of a scenario file instead of running the graph's commands")
+	s.fs.StringVar(&chaosSpec, "chaos", "", "Inject faults at the given rates, e.g. task-failure=0.1,cache-read-error=0.05,plugin-panic=0.1,restore-delay=0.2,delay=2s,seed=42")
+	s.fs.BoolVar(&offline, "offline", false, "Forbid network access: fail before running anything if a fetch or network-labelled task is not in the local cache")
+	s.fs.BoolVar(&dryRun, "dry-run", false, "Print which nodes would execute or be reused and the expected wall time, without running anything")
+	s.fs.BoolVar(&printCommands, "print-commands", false, "Print the shell command, env and working dir of each node that would run, without running anything")
+	s.fs.Var(&params, "set", "Set a graph parameter: name=value (repeatable)")
 
 	if err := s.parse(args, stderr); err != nil {
-		return ExitArgOrSystemError
+		return ExitUsageError
 	}
 	if strings.TrimSpace(graphPath) == "" {
-		fmt.Fprintln(stderr, "--graph is required")
-		return ExitArgOrSystemError
+		say(stderr, MsgFlagRequired, "--graph")
+		return ExitUsageError
 	}
 	if strings.TrimSpace(workdir) == "" {
-		fmt.Fprintln(stderr, "--workdir is required")
-		return ExitArgOrSystemError
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	if verifyN < 0 {
+		say(stderr, MsgFlagNegative, "--verify-determinism")
+		return ExitUsageError
+	}
+	if retries < 0 {
+		say(stderr, MsgFlagNegative, "--retries")
+		return ExitUsageError
+	}
+	if retryBackoff < 0 {
+		say(stderr, MsgFlagNegative, "--retry-backoff")
+		return ExitUsageError
+	}
+	policy, err := dag.ParseSkipPolicy(skipPolicy)
+	if err != nil {
+		say(stderr, MsgInvalidSkipPolicy, skipPolicy)
+		return ExitUsageError
+	}
+	if isolated && strings.TrimSpace(node) == "" {
+		say(stderr, MsgFlagRequires, "--isolated", "--node")
+		return ExitUsageError
+	}
+	if auditWorkers < 0 {
+		say(stderr, MsgFlagNegative, "--audit-determinism")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(simulate) != "" {
+		conflicts := []struct {
+			flag string
+			set  bool
+		}{
+			{"--resume", strings.TrimSpace(resumeID) != ""},
+			{"--verify-determinism", verifyN > 0},
+			{"--audit-determinism", auditWorkers > 0},
+		}
+		for _, c := range conflicts {
+			if c.set {
+				say(stderr, MsgFlagConflict, "--simulate", c.flag)
+				return ExitUsageError
+			}
+		}
 	}
 
 	absWorkdir, err := absFromCWD(workdir)
 	if err != nil {
-		fmt.Fprintln(stderr, err)
-		return ExitArgOrSystemError
+		return fail(stderr, ExitUsageError, err)
 	}
 	absGraph, err := absFromCWD(graphPath)
 	if err != nil {
-		fmt.Fprintln(stderr, err)
-		return ExitArgOrSystemError
+		return fail(stderr, ExitUsageError, err)
 	}
 
-	cacheAbs, err := absUnderWorkdir(absWorkdir, cacheDir)
-	if err != nil {
-		fmt.Fprintln(stderr, err)
-		return ExitArgOrSystemError
+	var cacheAbs string
+	if strings.TrimSpace(cacheDir) != "" {
+		if cacheAbs, err = absUnderWorkdir(absWorkdir, cacheDir); err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
 	}
 	outAbs, err := absUnderWorkdir(absWorkdir, outputDir)
 	if err != nil {
-		fmt.Fprintln(stderr, err)
-		return ExitArgOrSystemError
+		return fail(stderr, ExitUsageError, err)
+	}
+	var outputsJSONAbs string
+	if strings.TrimSpace(outputsJSON) != "" {
+		outputsJSONAbs, err = absFromCWD(outputsJSON)
+		if err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+	var chaosConfig *chaos.Config
+	if strings.TrimSpace(chaosSpec) != "" {
+		c, err := chaos.ParseConfig(chaosSpec)
+		if err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+		chaosConfig = &c
+	}
+	var simulateAbs string
+	if strings.TrimSpace(simulate) != "" {
+		simulateAbs, err = absFromCWD(simulate)
+		if err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+	var progressAbs string
+	if strings.TrimSpace(progressPath) != "" {
+		progressAbs, err = absFromCWD(progressPath)
+		if err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
 	}
 
 	var execMode cli.ExecutionMode
 	switch strings.ToLower(strings.TrimSpace(mode)) {
 	case "clean":
 		if strings.TrimSpace(resumeID) != "" {
-			fmt.Fprintln(stderr, "--resume is not compatible with --mode clean")
-			return ExitArgOrSystemError
+			say(stderr, MsgResumeWithClean)
+			return ExitUsageError
+		}
+		if isolated {
+			say(stderr, MsgFlagRequires, "--isolated", "--mode incremental")
+			return ExitUsageError
 		}
 		execMode = cli.ExecutionModeClean
 	case "incremental", "":
+		if auditWorkers > 0 {
+			say(stderr, MsgFlagRequires, "--audit-determinism", "--mode clean")
+			return ExitUsageError
+		}
 		execMode = cli.ExecutionModeIncremental
 	default:
-		fmt.Fprintf(stderr, "invalid --mode %q (expected clean|incremental)\n", mode)
-		return ExitArgOrSystemError
+		say(stderr, MsgInvalidMode, mode)
+		return ExitUsageError
 	}
 
+	var absPluginDir string
 	if strings.TrimSpace(pluginDir) != "" {
-		absPluginDir, err := absFromCWD(pluginDir)
+		absPluginDir, err = absFromCWD(pluginDir)
 		if err != nil {
-			fmt.Fprintln(stderr, err)
-			return ExitArgOrSystemError
+			return fail(stderr, ExitUsageError, err)
 		}
-		pluginLog := log.New(stderr, "", 0)
-		_, errs := pluginengine.DiscoverAndRegister(absPluginDir, pluginLog)
+		_, errs := pluginengine.DiscoverAndRegister(absPluginDir, pluginLog{stderr})
 		if len(errs) > 0 {
-			fmt.Fprintln(stderr, "plugin error")
+			say(stderr, MsgPluginError)
 			return ExitPluginError
 		}
 	}
 
 	inv := cli.CLIInvocation{
-		GraphPath:     absGraph,
-		WorkDir:       absWorkdir,
-		CacheDir:      cacheAbs,
-		OutputDir:     outAbs,
-		ExecutionMode: execMode,
-		ResumeRunID:   strings.TrimSpace(resumeID),
+		GraphPath:         absGraph,
+		WorkDir:           absWorkdir,
+		CacheDir:          cacheAbs,
+		OutputDir:         outAbs,
+		ExecutionMode:     execMode,
+		ResumeRunID:       strings.TrimSpace(resumeID),
+		Deduplicate:       dedupe,
+		NamespaceOutputs:  namespaceOutputs,
+		ProgressPath:      progressAbs,
+		Skip:              splitList(skip),
+		SkipPolicy:        policy,
+		Node:              strings.TrimSpace(node),
+		Isolated:          isolated,
+		Simulate:          simulateAbs,
+		Chaos:             chaosConfig,
+		Offline:           offline,
+		Params:            params,
+		Checkpoint:        checkpoint,
+		VerifyDeterminism: verifyN,
+		Retries:           retries,
+		RetryBackoff:      retryBackoff,
+		PluginDir:         absPluginDir,
+		Plugins:           splitList(pluginIDs),
+
+		AllowUnsignedPlugins: allowUnsigned,
+		PluginLog:            pluginLog{stderr},
 	}
 	if trace {
 		inv.Trace = cli.TraceConfig{Enabled: true, Path: filepath.Join(outAbs, "trace.json")}
 	}
+	if printCommands {
+		previews, err := cli.PreviewCommands(inv)
+		if err != nil {
+			if isGraphValidationErr(err) {
+				return fail(stderr, ExitValidationError, err)
+			}
+			return fail(stderr, ExitUsageError, err)
+		}
+		printCommandPreviews(stdout, previews)
+		return ExitSuccess
+	}
+	if dryRun {
+		est, err := cli.EstimateRun(inv)
+		if err != nil {
+			if isGraphValidationErr(err) {
+				return fail(stderr, ExitValidationError, err)
+			}
+			return fail(stderr, ExitUsageError, err)
+		}
+		printEstimate(stdout, est, true)
+		return ExitSuccess
+	}
+	if auditWorkers > 0 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		report, err := cli.AuditDeterminism(ctx, inv, auditWorkers)
+		if err != nil {
+			if isGraphValidationErr(err) {
+				return fail(stderr, ExitValidationError, err)
+			}
+			return fail(stderr, ExitUsageError, err)
+		}
+		return printDeterminismAudit(stdout, report)
+	}
+
+	// The estimate is advisory; when it cannot be made, the run reports why.
+	if simulateAbs == "" {
+		if est, err := cli.EstimateRun(inv); err == nil {
+			printEstimate(stderr, est, false)
+		}
+	}
 
-	res, execErr := cli.Execute(context.Background(), inv)
+	// Plugin hooks and profiles report to this process, so those runs never
+	// go through a daemon.
+	var res cli.CLIResult
+	var execErr error
+	served := false
+	if !noDaemon && strings.TrimSpace(pluginDir) == "" && len(inv.Plugins) == 0 && profiles.String() == "" {
+		res, served, execErr = daemon.Run(inv)
+		if served && verbose {
+			say(stderr, MsgServedByDaemon, workspace.SocketPath(absWorkdir))
+		}
+	}
+	if !served {
+		stopProfiles, err := profiles.start(absWorkdir)
+		if err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		res, execErr = cli.Execute(ctx, inv)
+		stop()
+		if err := stopProfiles(); err != nil {
+			say(stderr, MsgWorkspaceError, err)
+		}
+	}
+	printRetries(stderr, res.Retries)
+	printChaos(stderr, res.Chaos)
+	printStaleOutputs(stderr, res.StaleOutputs)
+	printPurged(stderr, res.Purged)
+	if res.NotifyError != "" {
+		say(stderr, MsgNotifyFailed, res.NotifyError)
+	}
+	if verbose {
+		for _, p := range res.Phases {
+			say(stderr, MsgPhase, p.Name, p.Duration)
+		}
+	}
+	if res.ExitCode == cli.ExitCancelled {
+		say(stderr, MsgCancelled)
+		return ExitCancelled
+	}
 	if execErr != nil {
 		if isGraphValidationErr(execErr) {
 			if errors.Is(execErr, dag.ErrCycleFound) || strings.Contains(strings.ToLower(execErr.Error()), "cycle") {
-				fmt.Fprintln(stderr, "Cycle detected")
+				say(stderr, MsgCycleDetected)
 			} else {
-				fmt.Fprintln(stderr, execErr)
+				say(stderr, MsgInvalidGraph, execErr)
+				printHints(stderr, graph.HintsOf(execErr))
 			}
 			return ExitValidationError
 		}
-		fmt.Fprintln(stderr, execErr)
-		return ExitArgOrSystemError
+		return fail(stderr, failureExitCode(res.ExitCode), execErr)
+	}
+
+	if res.CacheDir != "" {
+		say(stdout, MsgUsingCache, res.CacheDir)
+	}
+	printCacheStats(stdout, res.CacheStats)
+	printResume(stdout, res.Resume)
+	printDeduplicated(stdout, res.GraphResult)
+	if skip != "" {
+		printRequestedSkips(stdout, res.GraphResult)
+	}
+	if listOutputs {
+		printOutputs(stdout, res.Outputs)
+	}
+	if outputsJSONAbs != "" {
+		if err := writeOutputsJSON(outputsJSONAbs, res.Outputs); err != nil {
+			return fail(stderr, ExitWorkspaceError, err)
+		}
 	}
 
 	switch res.ExitCode {
 	case cli.ExitSuccess:
-		fmt.Fprintln(stdout, "Execution succeeded")
+		say(stdout, MsgSucceeded)
+		if res.PublishedTo != "" {
+			say(stdout, MsgPublished, len(res.Published), res.PublishedTo)
+		}
 		return ExitSuccess
 	case cli.ExitGraphFailure:
-		fmt.Fprintln(stderr, "Execution failed")
+		if len(res.Nondeterministic) > 0 {
+			for _, d := range res.Nondeterministic {
+				say(stderr, MsgNondeterministicTask, d.Name, strings.Join(d.Differences, ", "))
+			}
+			say(stderr, MsgDeterminismFailed)
+			return ExitExecutionFailure
+		}
+		printFailure(stderr, res)
+		say(stderr, MsgFailed)
 		return ExitExecutionFailure
-	case cli.ExitInvalidInvocation:
-		return ExitArgOrSystemError
 	default:
-		return ExitArgOrSystemError
+		return failureExitCode(res.ExitCode)
+	}
+}
+
+// failureExitCode passes through an engine exit code, which already uses the
+// canonical table, treating an unexpected success code as an internal error.
+func failureExitCode(code int) int {
+	if code == cli.ExitSuccess {
+		return ExitInternalError
+	}
+	return code
+}
+
+// printFailure reports the recorded failure, whether it is transient, every
+// failed node with its owner and docs and where its context was recorded, and
+// every skipped node with the failure that caused the skip.
+func printFailure(w io.Writer, res cli.CLIResult) {
+	if f := res.Failure; f != nil {
+		tag := "deterministic"
+		if f.Transient {
+			tag = "transient"
+		}
+		say(w, MsgFailure, f.ErrorMessage, tag)
+	}
+	for _, nf := range res.NodeFailures {
+		path := filepath.Join(".scriptweaver", "runs", res.RunID, "failures", nf.NodeID+".json")
+		if nf.ExitCode == nil {
+			say(w, MsgFailedNodeSkipped, nf.NodeID, nf.Cause, nf.Kind, path)
+		} else {
+			say(w, MsgFailedNode, nf.NodeID, *nf.ExitCode, nf.Kind, nf.Duration.Round(time.Millisecond), path)
+		}
+		if nf.Owner != "" {
+			say(w, MsgNodeOwner, nf.Owner)
+		}
+		if nf.Description != "" {
+			say(w, MsgNodeDescription, nf.Description)
+		}
+		if nf.DocsURL != "" {
+			say(w, MsgNodeDocs, nf.DocsURL)
+		}
+	}
+	if res.Failure != nil {
+		for _, sk := range res.Failure.SkippedNodes {
+			say(w, MsgSkippedNode, sk.NodeID, sk.Cause)
+		}
+	}
+}
+
+// printStaleOutputs warns about files left behind by nodes that no longer
+// declare them.
+func printStaleOutputs(w io.Writer, stale []cli.StaleOutput) {
+	for _, so := range stale {
+		say(w, MsgStaleOutput, so.Path, so.Node)
+	}
+	if len(stale) > 0 {
+		say(w, MsgRemoveStaleOutputs)
+	}
+}
+
+// printPurged reports the old runs removed per the retention settings.
+func printPurged(w io.Writer, r *cli.PurgeReport) {
+	if r == nil {
+		return
+	}
+	say(w, MsgPurged, len(r.Purged), r.Bytes, r.KeptRuns, r.KeptBytes)
+	for _, p := range r.Purged {
+		say(w, MsgPurgedRun, p.RunID, p.Bytes)
+	}
+}
+
+// printRetries reports the runs that failed transiently and were retried.
+func printRetries(w io.Writer, retries []cli.RetryAttempt) {
+	for _, r := range retries {
+		say(w, MsgRetried, r.RunID, r.ErrorCode, r.ErrorMessage, r.Backoff)
+	}
+}
+
+// printChaos reports the faults injected into the run, if any were requested.
+func printChaos(w io.Writer, r *cli.ChaosReport) {
+	if r == nil {
+		return
+	}
+	say(w, MsgChaos, r.Config.Seed, len(r.Faults))
+	for _, f := range r.Faults {
+		target := f.Target
+		if f.Detail != "" {
+			target += " (" + f.Detail + ")"
+		}
+		say(w, MsgChaosFault, f.Kind, target)
+	}
+}
+
+// printResume reports which nodes a resumed run reused from the previous
+// run's checkpoints and why the others were not, in topological order.
+func printResume(w io.Writer, r *cli.ResumeReport) {
+	if r == nil {
+		return
+	}
+	id := MsgResumed
+	if r.GraphChanged {
+		id = MsgResumedChangedGraph
+	}
+	say(w, id, r.PreviousRunID, len(r.Reused), len(r.Reused)+len(r.Rerun))
+	for _, name := range r.Reused {
+		say(w, MsgReused, name)
+	}
+	for _, n := range r.Rerun {
+		say(w, MsgNotReused, n.Name, n.Reason)
+	}
+}
+
+// printCacheStats summarizes how the run used the cache.
+func printCacheStats(w io.Writer, cs *core.CacheStats) {
+	if cs == nil {
+		return
+	}
+	say(w, MsgCacheStats, cs.Hits, cs.Misses, 100*cs.HitRate(), cs.BytesRestored)
+	if cs.UploadsSkipped > 0 {
+		say(w, MsgUploadsSkipped, cs.UploadsSkipped, cs.UploadBytesSaved)
+	}
+	if t := cs.Transfer; t.Requests > 0 {
+		say(w, MsgCacheTransfer, t.Requests, t.BytesUploaded, t.BytesDownloaded, t.Throttled.Round(time.Millisecond))
+	}
+}
+
+// printOutputs lists the files each successful node produced, in topological
+// order, with their sizes and sha256 hashes.
+func printOutputs(w io.Writer, outputs []cli.NodeOutputs) {
+	for _, no := range outputs {
+		for _, f := range no.Files {
+			say(w, MsgOutput, no.Node, f.Path, f.Size, f.SHA256)
+		}
+	}
+}
+
+// writeOutputsJSON writes the output listing to path, creating its parent
+// directory.
+func writeOutputsJSON(path string, outputs []cli.NodeOutputs) error {
+	data, err := cli.MarshalOutputs(outputs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// printDeterminismAudit reports whether the serial and the parallel run of
+// an audit agreed, and every divergence, and returns the exit code.
+func printDeterminismAudit(w io.Writer, a cli.DeterminismAudit) int {
+	if a.Deterministic() {
+		say(w, MsgAuditAgreed, a.SerialRunID, a.ParallelRunID, a.Workers, a.Nodes)
+		return ExitSuccess
+	}
+	say(w, MsgAuditDiverged, len(a.Divergences), a.SerialRunID, a.ParallelRunID, a.Workers)
+	for _, d := range a.Divergences {
+		what := d.Aspect
+		if d.Path != "" {
+			what += " " + d.Path
+		}
+		serial, parallel := d.Serial, d.Parallel
+		if serial == "" {
+			serial = "missing"
+		}
+		if parallel == "" {
+			parallel = "missing"
+		}
+		say(w, MsgAuditDivergence, d.Node, what, serial, parallel)
+	}
+	return ExitExecutionFailure
+}
+
+// printCommandPreviews prints the command of each node as a line to paste
+// into a shell, under a comment naming the node. Nodes without a shell
+// command only get the comment.
+func printCommandPreviews(w io.Writer, previews []cli.CommandPreview) {
+	for _, p := range previews {
+		switch {
+		case p.Fetch != "":
+			say(w, MsgCommandFetch, p.Node, p.Fetch)
+		case p.Runner != "":
+			say(w, MsgCommandRunner, p.Node, p.Runner)
+		case p.NoNetwork:
+			say(w, MsgCommandNoNetwork, p.Node)
+		default:
+			say(w, MsgCommandNode, p.Node)
+		}
+		if line := p.ShellLine(); line != "" {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// printEstimate prints the expected cost of a run and, with nodes, the
+// expected outcome of each node first.
+func printEstimate(w io.Writer, est cli.RunEstimate, nodes bool) {
+	if nodes {
+		for _, n := range est.Nodes {
+			if n.Decision == cli.EstimateExecute {
+				d := "?"
+				if n.Known {
+					d = n.Duration.Round(time.Millisecond).String()
+				}
+				say(w, MsgEstimateNodeDuration, n.Decision, n.Node, d)
+				continue
+			}
+			say(w, MsgEstimateNode, n.Decision, n.Node)
+		}
+	}
+	say(w, MsgEstimate, est.Execute, est.Reuse, est.Skip, est.Wall.Round(time.Millisecond))
+	if est.Unknown > 0 {
+		say(w, MsgEstimateUnknown, est.Unknown)
+	}
+}
+
+// printRequestedSkips reports each node skipped on request, and each node
+// skipped because it depends on one.
+func printRequestedSkips(w io.Writer, gr *dag.GraphResult) {
+	if gr == nil {
+		return
+	}
+	for _, name := range gr.RequestedSkips() {
+		if by := gr.SkippedBy[name]; by != name {
+			say(w, MsgSkippedWithUpstream, name, by)
+		} else {
+			say(w, MsgSkippedOnRequest, name)
+		}
+	}
+}
+
+// printDeduplicated reports each node that shared another node's result,
+// in lexical order.
+func printDeduplicated(w io.Writer, gr *dag.GraphResult) {
+	if gr == nil || len(gr.Deduplicated) == 0 {
+		return
+	}
+	names := make([]string, 0, len(gr.Deduplicated))
+	for name := range gr.Deduplicated {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		say(w, MsgDeduplicated, name, gr.Deduplicated[name])
 	}
 }
 
 func cmdValidate(args []string, stdout, stderr io.Writer) int {
 	s := newStrictFlagSet("sw validate")
 	var graphPath string
+	var workdir string
+	var pluginDir string
+	var pluginIDs string
+	var allowUnsigned bool
+	var locked bool
+	var strict bool
+	var format string
+	var params paramFlag
 	s.fs.StringVar(&graphPath, "graph", "", "Path to the graph definition file")
+	s.fs.StringVar(&workdir, "workdir", ".", "Project root whose plugins, config and lockfile apply")
+	s.fs.StringVar(&pluginDir, "plugin-dir", "", "Directory containing compiled plugins")
+	s.fs.StringVar(&pluginIDs, "plugins", "", "Comma-separated plugin IDs whose validation rules run")
+	s.fs.BoolVar(&allowUnsigned, "allow-unsigned-plugins", false, "Load plugins whose signature is missing or invalid")
+	s.fs.BoolVar(&locked, "locked", false, "Fail when a fetch task is not pinned by the graph or the lockfile")
+	s.fs.BoolVar(&strict, "strict", false, "Fail when validation reports warnings")
+	s.fs.StringVar(&format, "format", "text", "Output format: text|json|sarif")
+	s.fs.Var(&params, "set", "Set a graph parameter: name=value (repeatable)")
 	if err := s.parse(args, stderr); err != nil {
-		return ExitArgOrSystemError
+		return ExitUsageError
 	}
 	if strings.TrimSpace(graphPath) == "" {
-		fmt.Fprintln(stderr, "--graph is required")
-		return ExitArgOrSystemError
+		say(stderr, MsgFlagRequired, "--graph")
+		return ExitUsageError
+	}
+	format, err := parseValidateFormat(format)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
 	}
+	out := &validateOutput{format: format}
 
 	absGraph, err := absFromCWD(graphPath)
 	if err != nil {
-		fmt.Fprintln(stderr, err)
-		return ExitArgOrSystemError
+		return out.fail(stdout, stderr, graphPath, ExitUsageError, err)
 	}
 
-	_, err = cli.LoadGraphFromFile(absGraph)
-	if err == nil {
+	g, err := cli.LoadGraphWithParams(absGraph, params)
+	if err != nil {
+		if isSystemPathErr(err) {
+			return out.fail(stdout, stderr, graphPath, ExitUsageError, err)
+		}
+		if errors.Is(err, dag.ErrCycleFound) || strings.Contains(strings.ToLower(err.Error()), "cycle") {
+			out.add(diagnostic{ID: MsgCycleDetected, Severity: "error", Code: "cycle", Message: err.Error()}, stderr)
+			return out.finish(stdout, graphPath, ExitValidationError)
+		}
+		out.add(graphDiagnostic(MsgInvalidGraph, "invalid_graph", err), stderr, err)
+		return out.finish(stdout, graphPath, ExitValidationError)
+	}
+
+	for _, w := range cli.NetworkWarnings(g) {
+		out.add(diagnostic{ID: MsgNetworkTool, Severity: "warning", Code: "network", Message: w}, stderr, w)
+	}
+
+	inv := cli.CLIInvocation{Plugins: splitList(pluginIDs), AllowUnsignedPlugins: allowUnsigned}
+	if inv.WorkDir, err = absFromCWD(workdir); err != nil {
+		return out.fail(stdout, stderr, graphPath, ExitUsageError, err)
+	}
+	deprecated, err := cli.DeprecationWarnings(absGraph, params)
+	if err != nil {
+		return out.fail(stdout, stderr, graphPath, ExitUsageError, err)
+	}
+	for _, w := range append(cli.GraphWarnings(g, inv.WorkDir), deprecated...) {
+		out.add(diagnostic{ID: MsgGraphWarning, Severity: "warning", Code: w.Code, Node: w.Node, Message: w.Message}, stderr, w)
+	}
+	lockfile, err := cli.LoadLockfile(inv.WorkDir)
+	if err != nil {
+		return out.fail(stdout, stderr, graphPath, ExitWorkspaceError, err)
+	}
+	if issues := cli.FetchPinningIssues(g, lockfile); len(issues) > 0 {
+		id, severity := MsgUnpinnedFetch, "warning"
+		if locked {
+			id, severity = MsgUnpinnedFetchLocked, "error"
+		}
+		for _, issue := range issues {
+			out.add(diagnostic{ID: id, Severity: severity, Code: "fetch_pinning", Message: issue}, stderr, issue)
+		}
+		if locked {
+			return out.finish(stdout, graphPath, ExitValidationError)
+		}
+	}
+	violations, err := cli.CheckPolicy(inv.WorkDir, g)
+	if err != nil {
+		return out.fail(stdout, stderr, graphPath, ExitWorkspaceError, err)
+	}
+	for _, v := range violations {
+		out.add(diagnostic{ID: MsgPolicyViolation, Severity: "error", Code: "policy/" + v.Rule, Node: v.Node, Message: v.String()}, stderr, v)
+	}
+	if len(violations) > 0 {
+		return out.finish(stdout, graphPath, ExitValidationError)
+	}
+	if strings.TrimSpace(pluginDir) != "" {
+		if inv.PluginDir, err = absFromCWD(pluginDir); err != nil {
+			return out.fail(stdout, stderr, graphPath, ExitUsageError, err)
+		}
+	}
+	err = cli.ValidateWithPlugins(context.Background(), g, inv, pluginLog{stderr})
+	var findings *cli.PluginFindingsError
+	switch {
+	case err == nil:
+		if n := out.warnings(); strict && n > 0 {
+			out.add(diagnostic{ID: MsgStrictWarnings, Severity: "error", Code: "strict", Message: message(MsgStrictWarnings, n)}, stderr, n)
+			return out.finish(stdout, graphPath, ExitWarnings)
+		}
+		return out.finish(stdout, graphPath, ExitSuccess)
+	case errors.As(err, &findings):
+		for _, f := range findings.Findings {
+			out.add(diagnostic{ID: MsgPluginFinding, Severity: "error", Code: f.PluginID + "/" + f.Code, Node: f.Node, Message: f.Message}, stdout, f)
+		}
+		return out.finish(stdout, graphPath, ExitValidationError)
+	case errors.Is(err, pluginengine.ErrUnknownRunner):
+		out.add(diagnostic{ID: MsgUnknownRunner, Severity: "error", Code: "unknown_runner", Message: err.Error()}, stderr, err)
+		return out.finish(stdout, graphPath, ExitValidationError)
+	case errors.Is(err, config.ErrInvalidConfig):
+		return out.fail(stdout, stderr, graphPath, ExitWorkspaceError, err)
+	default:
+		return out.fail(stdout, stderr, graphPath, ExitPluginError, err)
+	}
+}
+
+// cmdSchema prints the JSON Schema of the graph files sw run reads, or with
+// --document that of graph documents, or writes it to --out.
+func cmdSchema(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw schema")
+	var out string
+	var document bool
+	s.fs.StringVar(&out, "out", "", "Write the schema to this file instead of stdout")
+	s.fs.BoolVar(&document, "document", false, "Print the schema of graph documents instead of graph files")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	schema := cli.GraphFileSchema()
+	if document {
+		schema = graph.JSONSchema(nil)
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return fail(stderr, ExitInternalError, err)
+	}
+	data = append(data, '\n')
+	if out == "" {
+		_, _ = stdout.Write(data)
 		return ExitSuccess
 	}
-	if isSystemPathErr(err) {
-		fmt.Fprintln(stderr, err)
-		return ExitArgOrSystemError
+	absOut, err := absFromCWD(out)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	if err := os.WriteFile(absOut, data, 0o644); err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	return ExitSuccess
+}
+
+// cmdFmt rewrites a graph file, of sw run or a graph document, in canonical
+// form, or with --check only reports whether it is.
+func cmdFmt(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw fmt")
+	var graphPath string
+	var check bool
+	s.fs.StringVar(&graphPath, "graph", "", "Path to the graph file or graph document")
+	s.fs.BoolVar(&check, "check", false, "Fail instead of rewriting the graph file when it is not formatted")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(graphPath) == "" {
+		say(stderr, MsgFlagRequired, "--graph")
+		return ExitUsageError
+	}
+	absGraph, err := absFromCWD(graphPath)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	if filepath.Ext(absGraph) == graph.LinesExt {
+		say(stderr, MsgFmtJSONLines, graphPath)
+		return ExitUsageError
+	}
+	data, err := os.ReadFile(absGraph)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	formatted, err := cli.FormatGraph(data)
+	if err != nil {
+		return fail(stderr, ExitValidationError, err)
+	}
+	if bytes.Equal(formatted, data) {
+		return ExitSuccess
 	}
-	if errors.Is(err, dag.ErrCycleFound) || strings.Contains(strings.ToLower(err.Error()), "cycle") {
-		fmt.Fprintln(stderr, "Cycle detected")
+	if check {
+		say(stderr, MsgNotFormatted, graphPath)
 		return ExitValidationError
 	}
-	fmt.Fprintln(stderr, err)
-	return ExitValidationError
+	if err := os.WriteFile(absGraph, formatted, 0o644); err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	return ExitSuccess
 }
 
 func cmdHash(args []string, stdout, stderr io.Writer) int {
 	s := newStrictFlagSet("sw hash")
 	var graphPath string
-	var _workdir string
+	var workdir string
+	var explain string
 	s.fs.StringVar(&graphPath, "graph", "", "Path to the graph definition file")
-	s.fs.StringVar(&_workdir, "workdir", "", "Accepted but ignored")
+	s.fs.StringVar(&workdir, "workdir", ".", "Project root the task hash of --explain is computed in")
+	s.fs.StringVar(&explain, "explain", "", "Print the components of the task hash of this node instead of the graph hash")
 	if err := s.parse(args, stderr); err != nil {
-		return ExitArgOrSystemError
+		return ExitUsageError
 	}
 	if strings.TrimSpace(graphPath) == "" {
-		fmt.Fprintln(stderr, "--graph is required")
-		return ExitArgOrSystemError
+		say(stderr, MsgFlagRequired, "--graph")
+		return ExitUsageError
 	}
 
 	absGraph, err := absFromCWD(graphPath)
 	if err != nil {
-		fmt.Fprintln(stderr, err)
-		return ExitArgOrSystemError
+		return fail(stderr, ExitUsageError, err)
 	}
 
 	g, err := cli.LoadGraphFromFile(absGraph)
 	if err != nil {
 		if isSystemPathErr(err) {
-			fmt.Fprintln(stderr, err)
-			return ExitArgOrSystemError
+			return fail(stderr, ExitUsageError, err)
 		}
-		fmt.Fprintln(stderr, err)
-		return ExitValidationError
+		return fail(stderr, ExitValidationError, err)
+	}
+	if explain == "" {
+		fmt.Fprintln(stdout, g.Hash().String())
+		return ExitSuccess
+	}
+
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	exp, err := cli.ExplainTaskHash(absWorkdir, g, explain)
+	if err != nil {
+		if errors.Is(err, cli.ErrUnknownNode) {
+			return fail(stderr, ExitUsageError, err)
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	fmt.Fprintf(stdout, "hash %s\n", exp.Hash)
+	for _, c := range exp.Components {
+		fmt.Fprintf(stdout, "%s %s\n", c.Name, c.Value)
 	}
-	fmt.Fprintln(stdout, g.Hash().String())
 	return ExitSuccess
 }
 
-func cmdPlugins(args []string, stdout, stderr io.Writer) int {
+func cmdGraph(args []string, stdout, stderr io.Writer) int {
 	if len(args) == 0 {
-		fmt.Fprintln(stderr, "missing plugins subcommand (expected: list)")
-		return ExitArgOrSystemError
+		say(stderr, MsgMissingSubcommand, "graph", "stats|query")
+		return ExitUsageError
 	}
 	switch args[0] {
-	case "list":
-		return cmdPluginsList(args[1:], stdout, stderr)
+	case "stats":
+		return cmdGraphStats(args[1:], stdout, stderr)
+	case "query":
+		return cmdGraphQuery(args[1:], stdout, stderr)
 	default:
-		fmt.Fprintf(stderr, "unknown plugins subcommand: %s\n", args[0])
-		return ExitArgOrSystemError
+		say(stderr, MsgUnknownSubcommand, "graph", args[0])
+		return ExitUsageError
 	}
 }
 
-func cmdPluginsList(args []string, stdout, stderr io.Writer) int {
-	s := newStrictFlagSet("sw plugins list")
-	var pluginDir string
-	s.fs.StringVar(&pluginDir, "plugin-dir", "", "Directory containing compiled plugins")
+func cmdGraphStats(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw graph stats")
+	var graphPath string
+	var format string
+	s.fs.StringVar(&graphPath, "graph", "", "Path to the graph definition file")
+	s.fs.StringVar(&format, "format", "text", "Output format: text|json")
 	if err := s.parse(args, stderr); err != nil {
-		return ExitArgOrSystemError
+		return ExitUsageError
 	}
-	if strings.TrimSpace(pluginDir) == "" {
+	if strings.TrimSpace(graphPath) == "" {
+		say(stderr, MsgFlagRequired, "--graph")
+		return ExitUsageError
+	}
+	if format != "text" && format != "json" {
+		say(stderr, MsgInvalidFormat, format, "text|json")
+		return ExitUsageError
+	}
+	if format == "json" {
+		stderr = jsonMessages{stderr}
+	}
+
+	absGraph, err := absFromCWD(graphPath)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	g, err := cli.LoadGraphFromFile(absGraph)
+	if err != nil {
+		if isSystemPathErr(err) {
+			return fail(stderr, ExitUsageError, err)
+		}
+		return fail(stderr, ExitValidationError, err)
+	}
+
+	st := g.Stats()
+	if format == "json" {
+		data, err := json.MarshalIndent(st, "", "  ")
+		if err != nil {
+			return fail(stderr, ExitInternalError, err)
+		}
+		fmt.Fprintln(stdout, string(data))
 		return ExitSuccess
 	}
+	fmt.Fprintf(stdout, "nodes        %d\n", st.Nodes)
+	fmt.Fprintf(stdout, "edges        %d\n", st.Edges)
+	fmt.Fprintf(stdout, "depth        %d\n", st.Depth)
+	fmt.Fprintf(stdout, "max width    %d\n", st.MaxWidth)
+	fmt.Fprintf(stdout, "roots        %d\n", st.Roots)
+	fmt.Fprintf(stdout, "leaves       %d\n", st.Leaves)
+	fmt.Fprintf(stdout, "components   %d\n", st.Components)
+	fmt.Fprintf(stdout, "fan-in       avg %.2f, max %d\n", st.AvgFanIn, st.MaxFanIn)
+	fmt.Fprintf(stdout, "fan-out      avg %.2f, max %d\n", st.AvgFanOut, st.MaxFanOut)
+	fmt.Fprintf(stdout, "parallelism  %.2f\n", st.Parallelism)
+	fmt.Fprintln(stdout, "LEVEL  WIDTH")
+	for d, w := range st.Widths {
+		fmt.Fprintf(stdout, "%-6d %d\n", d, w)
+	}
+	return ExitSuccess
+}
+
+// cmdGraphQuery prints the IDs of the nodes a selector matches, one per line
+// in name order. See cli.Selector for the selector language.
+func cmdGraphQuery(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		say(stderr, MsgMissingArgument, "selector")
+		return ExitUsageError
+	}
+	expr, args := args[0], args[1:]
+	s := newStrictFlagSet("sw graph query")
+	var graphPath string
+	s.fs.StringVar(&graphPath, "graph", "", "Path to the graph definition file")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(graphPath) == "" {
+		say(stderr, MsgFlagRequired, "--graph")
+		return ExitUsageError
+	}
+	sel, err := cli.ParseSelector(expr)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	absGraph, err := absFromCWD(graphPath)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	g, err := cli.LoadGraphFromFile(absGraph)
+	if err != nil {
+		if isSystemPathErr(err) {
+			return fail(stderr, ExitUsageError, err)
+		}
+		return fail(stderr, ExitValidationError, err)
+	}
+	ids, err := sel.Select(g)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	for _, id := range ids {
+		fmt.Fprintln(stdout, id)
+	}
+	return ExitSuccess
+}
+
+func cmdCache(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		say(stderr, MsgMissingSubcommand, "cache", "warm|rekey")
+		return ExitUsageError
+	}
+	switch args[0] {
+	case "warm":
+		return cmdCacheWarm(args[1:], stdout, stderr)
+	case "rekey":
+		return cmdCacheRekey(args[1:], stdout, stderr)
+	default:
+		say(stderr, MsgUnknownSubcommand, "cache", args[0])
+		return ExitUsageError
+	}
+}
+
+// cmdCacheWarm prefetches the entries of a graph from the shared cache tiers
+// into the local cache.
+func cmdCacheWarm(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw cache warm")
+	var graphPath string
+	var workdir string
+	var cacheDir string
+	s.fs.StringVar(&graphPath, "graph", "", "Path to the graph definition file")
+	s.fs.StringVar(&workdir, "workdir", "", "Root directory for execution context")
+	s.fs.StringVar(&cacheDir, "cache-dir", "", "Local cache to warm (default <workdir>/.scriptweaver/cache)")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(graphPath) == "" {
+		say(stderr, MsgFlagRequired, "--graph")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	absGraph, err := absFromCWD(graphPath)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	var cacheAbs string
+	if strings.TrimSpace(cacheDir) != "" {
+		if cacheAbs, err = absUnderWorkdir(absWorkdir, cacheDir); err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+
+	g, err := cli.LoadGraphFromFile(absGraph)
+	if err != nil {
+		if isSystemPathErr(err) {
+			return fail(stderr, ExitUsageError, err)
+		}
+		return fail(stderr, ExitValidationError, err)
+	}
+	report, err := cli.WarmCache(absWorkdir, g, cacheAbs)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	for _, n := range report.Nodes {
+		switch n.Status {
+		case cli.WarmFetched:
+			say(stdout, MsgWarmFetched, n.Node, n.Hash)
+		case cli.WarmMissing:
+			say(stdout, MsgWarmMissing, n.Node, n.Hash)
+		case cli.WarmSkipped:
+			say(stdout, MsgWarmSkipped, n.Node)
+		}
+	}
+	say(stdout, MsgWarmed, report.CacheDir,
+		report.Count(cli.WarmFetched), report.Count(cli.WarmLocal), report.Count(cli.WarmMissing), report.Count(cli.WarmSkipped))
+	return ExitSuccess
+}
+
+// cmdCacheRekey re-encrypts the local cache with the current cache key.
+func cmdCacheRekey(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw cache rekey")
+	var workdir string
+	var cacheDir string
+	s.fs.StringVar(&workdir, "workdir", "", "Root directory for execution context")
+	s.fs.StringVar(&cacheDir, "cache-dir", "", "Local cache to re-encrypt (default <workdir>/.scriptweaver/cache)")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	var cacheAbs string
+	if strings.TrimSpace(cacheDir) != "" {
+		if cacheAbs, err = absUnderWorkdir(absWorkdir, cacheDir); err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+	n, err := cli.RekeyCache(absWorkdir, cacheAbs)
+	if err != nil {
+		if errors.Is(err, cli.ErrCacheNotEncrypted) {
+			return fail(stderr, ExitUsageError, err)
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	say(stdout, MsgRekeyed, n)
+	return ExitSuccess
+}
+
+func cmdPlugins(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		say(stderr, MsgMissingSubcommand, "plugins", "list|stats|new|doctor")
+		return ExitUsageError
+	}
+	switch args[0] {
+	case "list":
+		return cmdPluginsList(args[1:], stdout, stderr)
+	case "stats":
+		return cmdPluginsStats(args[1:], stdout, stderr)
+	case "new":
+		return cmdPluginsNew(args[1:], stdout, stderr)
+	case "doctor":
+		return cmdPluginsDoctor(args[1:], stdout, stderr)
+	default:
+		say(stderr, MsgUnknownSubcommand, "plugins", args[0])
+		return ExitUsageError
+	}
+}
+
+func cmdPluginsList(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw plugins list")
+	var workdir string
+	var pluginDir string
+	var format string
+	var verbose bool
+	s.fs.StringVar(&workdir, "workdir", ".", "Project root whose plugins and config apply")
+	s.fs.StringVar(&pluginDir, "plugin-dir", "", "Directory containing compiled plugins")
+	s.fs.StringVar(&format, "format", "text", "Output format: text|json")
+	s.fs.BoolVar(&verbose, "verbose", false, "Print a table with version, status, hooks, directory and description")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if format != "text" && format != "json" {
+		say(stderr, MsgInvalidFormat, format, "text|json")
+		return ExitUsageError
+	}
+	if format == "json" {
+		stderr = jsonMessages{stderr}
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	root := filepath.Join(absWorkdir, pluginengine.DefaultPluginsRoot)
+	if strings.TrimSpace(pluginDir) != "" {
+		if root, err = absFromCWD(pluginDir); err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+	cfg, _, err := config.LoadOptional(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+
+	plugins, err := pluginengine.ListPlugins(root, cfg.PluginKeys)
+	if err != nil {
+		return fail(stderr, ExitPluginError, err)
+	}
+	switch {
+	case format == "json":
+		if plugins == nil {
+			plugins = []pluginengine.PluginInfo{}
+		}
+		data, err := json.MarshalIndent(plugins, "", "  ")
+		if err != nil {
+			return fail(stderr, ExitInternalError, err)
+		}
+		fmt.Fprintln(stdout, string(data))
+	case verbose:
+		printPluginList(stdout, plugins)
+	default:
+		for _, p := range plugins {
+			if p.Enabled {
+				fmt.Fprintln(stdout, p.PluginID)
+			}
+		}
+	}
+	for _, p := range plugins {
+		if !p.Enabled {
+			if format == "text" && !verbose {
+				say(stderr, MsgPluginDisabled, dash(p.PluginID), p.Dir, p.LoadError)
+			}
+			return ExitPluginError
+		}
+	}
+	return ExitSuccess
+}
+
+// printPluginList writes the plugins as a table, one row per plugin with its
+// description, or its load error when it is disabled.
+func printPluginList(w io.Writer, plugins []pluginengine.PluginInfo) {
+	fmt.Fprintf(w, "%-24s %-10s %-9s %-28s %-24s %s\n", "PLUGIN", "VERSION", "STATUS", "HOOKS", "DIR", "DETAIL")
+	for _, p := range plugins {
+		status, detail := "enabled", p.Description
+		if !p.Enabled {
+			status, detail = "disabled", p.LoadError
+		}
+		line := fmt.Sprintf("%-24s %-10s %-9s %-28s %-24s %s", dash(p.PluginID), dash(p.Version), status, dash(strings.Join(p.Hooks, ",")), filepath.Base(p.Dir)+"/", detail)
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
 
+func cmdPluginsNew(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		say(stderr, MsgMissingArgument, "plugin id")
+		return ExitUsageError
+	}
+	id, args := args[0], args[1:]
+	s := newStrictFlagSet("sw plugins new")
+	var pluginDir string
+	var protocol string
+	var hooks string
+	s.fs.StringVar(&pluginDir, "plugin-dir", pluginengine.DefaultPluginsRoot, "Directory to create the plugin in")
+	s.fs.StringVar(&protocol, "protocol", pluginengine.ProtocolExec, "Plugin protocol: exec|go")
+	s.fs.StringVar(&hooks, "hooks", "", "Comma-separated hooks to declare (default: all)")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
 	absPluginDir, err := absFromCWD(pluginDir)
 	if err != nil {
-		fmt.Fprintln(stderr, err)
-		return ExitArgOrSystemError
+		return fail(stderr, ExitUsageError, err)
 	}
 
-	reg, errs := pluginengine.DiscoverAndRegister(absPluginDir, log.New(stderr, "", 0))
-	if len(errs) > 0 {
-		fmt.Fprintln(stderr, "plugin error")
-		return ExitPluginError
+	dir := filepath.Join(absPluginDir, id)
+	files, err := pluginengine.Scaffold(dir, pluginengine.ScaffoldOptions{PluginID: id, Protocol: protocol, Hooks: splitList(hooks)})
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	say(stdout, MsgPluginCreated, id, dir)
+	for _, f := range files {
+		fmt.Fprintf(stdout, "  %s\n", f)
+	}
+	if protocol == pluginengine.ProtocolGo {
+		say(stdout, MsgPluginBuild)
+	}
+	return ExitSuccess
+}
+
+func cmdPluginsStats(args []string, stdout, stderr io.Writer) int {
+	// The run ID may precede the flags.
+	var runID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		runID, args = args[0], args[1:]
+	}
+	s := newStrictFlagSet("sw plugins stats")
+	var workdir string
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	st, err := state.NewStore(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if runID == "" {
+		runID, err = latestRunWithFile(st, pluginengine.StatsFileName)
+		if err != nil {
+			return fail(stderr, ExitWorkspaceError, err)
+		}
+		if runID == "" {
+			say(stderr, MsgNoPluginStats)
+			return ExitUsageError
+		}
+	}
+	data, err := st.LoadRunFile(runID, pluginengine.StatsFileName)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			say(stderr, MsgRunHasNoPluginStats, runID)
+			return ExitUsageError
+		}
+		return fail(stderr, ExitWorkspaceError, err)
 	}
+	stats, err := pluginengine.ParseStats(data)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	printPluginStats(stdout, runID, stats)
+	return ExitSuccess
+}
 
-	ids := make([]string, 0, len(reg.Manifests))
-	for _, m := range reg.Manifests {
-		ids = append(ids, m.PluginID)
+// latestRunWithFile returns the most recently started run that has the named
+// run file, or "" if there is none.
+func latestRunWithFile(st *state.Store, name string) (string, error) {
+	ids, err := st.ListRunIDs()
+	if err != nil {
+		return "", err
 	}
-	sort.Strings(ids)
+	var bestID string
+	var bestTime time.Time
 	for _, id := range ids {
-		fmt.Fprintln(stdout, id)
+		if _, err := st.LoadRunFile(id, name); err != nil {
+			continue
+		}
+		r, err := st.LoadRun(id)
+		if err != nil {
+			continue
+		}
+		if bestID == "" || r.StartTime.After(bestTime) {
+			bestID, bestTime = id, r.StartTime
+		}
+	}
+	return bestID, nil
+}
+
+func cmdPluginsDoctor(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw plugins doctor")
+	var workdir string
+	var pluginDir string
+	s.fs.StringVar(&workdir, "workdir", ".", "Project root whose plugins and config apply")
+	s.fs.StringVar(&pluginDir, "plugin-dir", "", "Directory containing compiled plugins")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	root := filepath.Join(absWorkdir, pluginengine.DefaultPluginsRoot)
+	if strings.TrimSpace(pluginDir) != "" {
+		if root, err = absFromCWD(pluginDir); err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+	cfg, _, err := config.LoadOptional(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+
+	health, err := pluginengine.Doctor(context.Background(), root, cfg.PluginKeys)
+	if err != nil {
+		return fail(stderr, ExitPluginError, err)
+	}
+	if len(health) == 0 {
+		say(stdout, MsgNoPlugins, root)
+		return ExitSuccess
+	}
+	fmt.Fprintf(stdout, "%-24s %-10s %-10s %-7s %s\n", "PLUGIN", "VERSION", "RUNTIME", "STATUS", "DETAIL")
+	healthy := true
+	for _, h := range health {
+		id := h.PluginID
+		if id == "" {
+			id = h.Dir + "/"
+		}
+		status, detail := "ok", ""
+		if !h.OK() {
+			healthy = false
+			status, detail = "error", strings.Join(h.Problems, "; ")
+		}
+		line := fmt.Sprintf("%-24s %-10s %-10s %-7s %s", id, dash(h.Version), dash(h.Runtime), status, detail)
+		fmt.Fprintln(stdout, strings.TrimRight(line, " "))
+	}
+	if !healthy {
+		return ExitPluginError
+	}
+	return ExitSuccess
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func printPluginStats(w io.Writer, runID string, stats []pluginengine.PluginStats) {
+	say(w, MsgPluginStats, runID)
+	fmt.Fprintf(w, "%-24s %-12s %8s %12s %8s\n", "PLUGIN", "HOOK", "CALLS", "TIME", "ERRORS")
+	for _, ps := range stats {
+		fmt.Fprintf(w, "%-24s %-12s %8d %12s %8d\n", ps.PluginID, "total", ps.Invocations, ps.Duration, ps.Errors)
+		hooks := make([]string, 0, len(ps.Hooks))
+		for h := range ps.Hooks {
+			hooks = append(hooks, h)
+		}
+		sort.Strings(hooks)
+		for _, h := range hooks {
+			hs := ps.Hooks[h]
+			fmt.Fprintf(w, "%-24s %-12s %8d %12s %8d\n", "", h, hs.Invocations, hs.Duration, hs.Errors)
+		}
+	}
+}
+
+func cmdAudit(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		say(stderr, MsgMissingSubcommand, "audit", "verify")
+		return ExitUsageError
+	}
+	switch args[0] {
+	case "verify":
+		return cmdAuditVerify(args[1:], stdout, stderr)
+	default:
+		say(stderr, MsgUnknownSubcommand, "audit", args[0])
+		return ExitUsageError
+	}
+}
+
+func cmdAuditVerify(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw audit verify")
+	var workdir string
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	n, err := audit.Verify(absWorkdir)
+	if err != nil {
+		var ve *audit.VerifyError
+		if errors.As(err, &ve) {
+			return fail(stderr, ExitValidationError, err)
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	say(stdout, MsgAuditOK, n)
+	return ExitSuccess
+}
+
+func cmdRuns(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		say(stderr, MsgMissingSubcommand, "runs", "list|show|timeline|diff|cache|attempts")
+		return ExitUsageError
+	}
+	switch args[0] {
+	case "list":
+		return cmdRunsList(args[1:], stdout, stderr)
+	case "attempts":
+		return cmdRunsAttempts(args[1:], stdout, stderr)
+	case "cache":
+		return cmdRunsCache(args[1:], stdout, stderr)
+	case "show":
+		return cmdRunsShow(args[1:], stdout, stderr)
+	case "timeline":
+		return cmdRunsTimeline(args[1:], stdout, stderr)
+	case "diff":
+		return cmdRunsDiff(args[1:], stdout, stderr)
+	default:
+		say(stderr, MsgUnknownSubcommand, "runs", args[0])
+		return ExitUsageError
+	}
+}
+
+// cmdRunsList lists the most recent runs of a workspace, and its schedules
+// with their next and last runs.
+func cmdRunsList(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw runs list")
+	var workdir string
+	var limit int
+	var asJSON bool
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	s.fs.IntVar(&limit, "limit", 20, "Number of most recent runs to list")
+	s.fs.BoolVar(&asJSON, "json", false, "Print the runs and schedules as JSON")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if asJSON {
+		stderr = jsonMessages{stderr}
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	if limit < 1 {
+		say(stderr, MsgFlagTooSmall, "--limit", 1)
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	st, err := state.NewStore(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	runs, err := cli.ListRuns(st, limit)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	// The daemon reports the schedules it runs; without one, they are read
+	// from the config, and do not run.
+	schedules, served, err := daemon.FetchSchedules(absWorkdir)
+	if !served {
+		var cfg config.Config
+		cfg, _, err = config.LoadOptional(absWorkdir)
+		if err == nil {
+			schedules, err = cli.ScheduleStatuses(st, cfg, time.Now())
+		}
+	}
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+
+	if asJSON {
+		if schedules == nil {
+			schedules = []cli.ScheduleStatus{}
+		}
+		data, err := json.MarshalIndent(struct {
+			Runs      []cli.RunSummary     `json:"runs"`
+			Schedules []cli.ScheduleStatus `json:"schedules"`
+			Daemon    bool                 `json:"daemon"`
+		}{runs, schedules, served}, "", "  ")
+		if err != nil {
+			return fail(stderr, ExitInternalError, err)
+		}
+		fmt.Fprintln(stdout, string(data))
+		return ExitSuccess
+	}
+	const stamp = "2006-01-02 15:04:05"
+	if len(runs) == 0 {
+		say(stdout, MsgNoRuns)
+	} else {
+		fmt.Fprintf(stdout, "%-28s %-9s %-19s %-11s %-24s %s\n", "RUN", "STATUS", "STARTED", "MODE", "GRAPH", "SCHEDULE")
+		for _, r := range runs {
+			fmt.Fprintf(stdout, "%-28s %-9s %-19s %-11s %-24s %s\n", r.RunID, r.Status, r.StartTime.Local().Format(stamp), r.Mode, r.Graph, r.Schedule)
+		}
+	}
+	if len(schedules) == 0 {
+		return ExitSuccess
+	}
+	fmt.Fprintln(stdout)
+	fmt.Fprintf(stdout, "%-20s %-16s %-19s %-28s %s\n", "SCHEDULE", "CRON", "NEXT", "LAST RUN", "LAST STATUS")
+	for _, sc := range schedules {
+		next := "never"
+		if !sc.Next.IsZero() {
+			next = sc.Next.Local().Format(stamp)
+		}
+		fmt.Fprintf(stdout, "%-20s %-16s %-19s %-28s %s\n", sc.Name, sc.Cron, next, sc.LastRunID, sc.LastStatus)
+	}
+	if !served {
+		say(stdout, MsgSchedulesNotRun, absWorkdir)
+	}
+	return ExitSuccess
+}
+
+func cmdRunsDiff(args []string, stdout, stderr io.Writer) int {
+	// The run IDs may precede the flags.
+	var runIDs []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		runIDs, args = append(runIDs, args[0]), args[1:]
+	}
+	s := newStrictFlagSet("sw runs diff")
+	var workdir string
+	var asJSON bool
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	s.fs.BoolVar(&asJSON, "json", false, "Print the differences as JSON")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if asJSON {
+		stderr = jsonMessages{stderr}
+	}
+	if len(runIDs) != 2 {
+		say(stderr, MsgExpectedTwoRunIDs)
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	st, err := state.NewStore(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	d, err := cli.DiffRuns(st, runIDs[0], runIDs[1])
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, cli.ErrNoRunEnv) {
+			return fail(stderr, ExitUsageError, err)
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if asJSON {
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return fail(stderr, ExitInternalError, err)
+		}
+		fmt.Fprintln(stdout, string(data))
+		return ExitSuccess
+	}
+	printRunDiff(stdout, d)
+	return ExitSuccess
+}
+
+// printRunDiff reports whether two runs executed the same graph and every
+// environment difference between them. Values are never printed: only
+// their digests were recorded.
+func printRunDiff(w io.Writer, d cli.RunDiff) {
+	if d.SameGraph() {
+		say(w, MsgSameGraph, d.A, d.B, d.GraphHashA)
+	} else {
+		say(w, MsgDifferentGraphs, d.A, d.B, d.GraphHashA, d.GraphHashB)
+	}
+	switch {
+	case d.Params == nil:
+		say(w, MsgParamsNotCompared)
+	case len(d.Params) == 0:
+		say(w, MsgNoParamDifferences)
+	default:
+		say(w, MsgParamDifferences, len(d.Params))
+		for _, c := range d.Params {
+			fmt.Fprintf(w, "  %s: %s -> %s\n", c.Param, paramValue(c.A), paramValue(c.B))
+		}
+	}
+	if len(d.Env) == 0 {
+		say(w, MsgNoEnvDifferences)
+		return
+	}
+	say(w, MsgEnvDifferences, len(d.Env))
+	for _, c := range d.Env {
+		if c.Var == "" {
+			fmt.Fprintf(w, "  %s: %s\n", c.Node, c.Change)
+			continue
+		}
+		fmt.Fprintf(w, "  %s: %s %s\n", c.Node, c.Var, c.Change)
+	}
+}
+
+// paramValue renders a recorded parameter value, which is empty when the
+// parameter was unset.
+func paramValue(v string) string {
+	if v == "" {
+		return "(unset)"
+	}
+	return v
+}
+
+func cmdRunsShow(args []string, stdout, stderr io.Writer) int {
+	// The run ID may precede the flags.
+	var runID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		runID, args = args[0], args[1:]
+	}
+	s := newStrictFlagSet("sw runs show")
+	var workdir string
+	var asJSON bool
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	s.fs.BoolVar(&asJSON, "json", false, "Print the run record as JSON")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if asJSON {
+		stderr = jsonMessages{stderr}
+	}
+	if runID == "" {
+		say(stderr, MsgMissingArgument, "run id")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	st, err := state.NewStore(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	rec, err := cli.ShowRun(st, runID)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			say(stderr, MsgRunNotFound, runID)
+			return ExitUsageError
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if asJSON {
+		data, err := json.MarshalIndent(rec, "", "  ")
+		if err != nil {
+			return fail(stderr, ExitInternalError, err)
+		}
+		fmt.Fprintln(stdout, string(data))
+		return ExitSuccess
+	}
+	printRunRecord(stdout, rec)
+	return ExitSuccess
+}
+
+// printRunRecord reports the metadata of a run and the parameters it was
+// invoked with.
+func printRunRecord(w io.Writer, rec cli.RunRecord) {
+	r := rec.Run
+	say(w, MsgRunRecord, r.RunID, r.Mode, r.GraphHash, r.StartTime.Format(time.RFC3339))
+	if r.PreviousRunID != nil {
+		say(w, MsgRunRetryOf, r.RetryCount, *r.PreviousRunID)
+	}
+	if rec.Failure != nil {
+		say(w, MsgRunFailed, rec.Failure.ErrorCode, rec.Failure.ErrorMessage)
+	}
+	if rec.Params == nil {
+		say(w, MsgRunHasNoParams)
+		return
+	}
+	say(w, MsgRunParams)
+	for _, f := range rec.Params.Fields() {
+		fmt.Fprintf(w, "  %s: %s\n", f.Name, f.Value)
+	}
+}
+
+func cmdRunsTimeline(args []string, stdout, stderr io.Writer) int {
+	// The run ID may precede the flags.
+	var runID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		runID, args = args[0], args[1:]
+	}
+	s := newStrictFlagSet("sw runs timeline")
+	var workdir string
+	var asJSON bool
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	s.fs.BoolVar(&asJSON, "json", false, "Print the timeline as JSON")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if asJSON {
+		stderr = jsonMessages{stderr}
+	}
+	if runID == "" {
+		say(stderr, MsgMissingArgument, "run id")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	st, err := state.NewStore(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	tl, err := cli.LoadTimeline(st, runID)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			say(stderr, MsgRunNotFound, runID)
+			return ExitUsageError
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if asJSON {
+		data, err := json.MarshalIndent(tl, "", "  ")
+		if err != nil {
+			return fail(stderr, ExitInternalError, err)
+		}
+		fmt.Fprintln(stdout, string(data))
+		return ExitSuccess
+	}
+	printTimeline(stdout, tl)
+	return ExitSuccess
+}
+
+func cmdRunsCache(args []string, stdout, stderr io.Writer) int {
+	// The run ID may precede the flags.
+	var runID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		runID, args = args[0], args[1:]
+	}
+	s := newStrictFlagSet("sw runs cache")
+	var workdir string
+	var asJSON bool
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	s.fs.BoolVar(&asJSON, "json", false, "Print the decisions as JSON Lines, one per node")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if asJSON {
+		stderr = jsonMessages{stderr}
+	}
+	if runID == "" {
+		say(stderr, MsgMissingArgument, "run id")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	st, err := state.NewStore(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if _, err := st.LoadRun(runID); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			say(stderr, MsgRunNotFound, runID)
+			return ExitUsageError
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	decisions, err := cli.LoadCacheDecisions(st, runID)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			say(stderr, MsgRunHasNoCacheDecs, runID)
+			return ExitUsageError
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if asJSON {
+		enc := json.NewEncoder(stdout)
+		for _, d := range decisions {
+			if err := enc.Encode(d); err != nil {
+				return fail(stderr, ExitInternalError, err)
+			}
+		}
+		return ExitSuccess
+	}
+	fmt.Fprintf(stdout, "%-24s %-12s %-24s %12s  %s\n", "NODE", "DECISION", "TIER", "BYTES", "KEY")
+	for _, d := range decisions {
+		tier := d.Tier
+		if tier == "" {
+			tier = "-"
+		}
+		fmt.Fprintf(stdout, "%-24s %-12s %-24s %12d  %s\n", d.Node, d.Decision, tier, d.BytesRestored, d.Key)
+	}
+	return ExitSuccess
+}
+
+func cmdRunsAttempts(args []string, stdout, stderr io.Writer) int {
+	// The run ID may precede the flags.
+	var runID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		runID, args = args[0], args[1:]
+	}
+	s := newStrictFlagSet("sw runs attempts")
+	var workdir string
+	var asJSON bool
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	s.fs.BoolVar(&asJSON, "json", false, "Print the attempts as JSON")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if asJSON {
+		stderr = jsonMessages{stderr}
+	}
+	if runID == "" {
+		say(stderr, MsgMissingArgument, "run id")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	st, err := state.NewStore(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if _, err := st.LoadRun(runID); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			say(stderr, MsgRunNotFound, runID)
+			return ExitUsageError
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	attempts, err := st.LoadAttempts(runID)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if len(attempts) == 0 {
+		say(stderr, MsgRunHasNoAttempts, runID)
+		return ExitUsageError
+	}
+	if asJSON {
+		data, err := json.MarshalIndent(attempts, "", "  ")
+		if err != nil {
+			return fail(stderr, ExitInternalError, err)
+		}
+		fmt.Fprintln(stdout, string(data))
+		return ExitSuccess
+	}
+	names := make([]string, 0, len(attempts))
+	for name := range attempts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(stdout, "%-24s %7s %-28s %4s %12s  %s\n", "NODE", "ATTEMPT", "RUN", "EXIT", "DURATION", "CACHED")
+	for _, name := range names {
+		for _, a := range attempts[name] {
+			fmt.Fprintf(stdout, "%-24s %7d %-28s %4d %12s  %v\n", name, a.Attempt, a.RunID, a.ExitCode, a.Duration, a.FromCache)
+		}
+	}
+	if flaky := cli.FlakyNodes(attempts); len(flaky) > 0 {
+		say(stdout, MsgFlakyNodes, strings.Join(flaky, ", "))
+	}
+	return ExitSuccess
+}
+
+func cmdTrace(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		say(stderr, MsgMissingSubcommand, "trace", "replay|finalize")
+		return ExitUsageError
+	}
+	switch args[0] {
+	case "replay":
+		return cmdTraceReplay(args[1:], stdout, stderr)
+	case "finalize":
+		return cmdTraceFinalize(args[1:], stdout, stderr)
+	default:
+		say(stderr, MsgUnknownSubcommand, "trace", args[0])
+		return ExitUsageError
+	}
+}
+
+func cmdTraceReplay(args []string, stdout, stderr io.Writer) int {
+	// The trace path may precede the flags.
+	var tracePath string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		tracePath, args = args[0], args[1:]
+	}
+	s := newStrictFlagSet("sw trace replay")
+	var workdir string
+	var outputDir string
+	var pluginDir string
+	var pluginIDs string
+	var allowUnsigned bool
+	s.fs.StringVar(&workdir, "workdir", ".", "Project root whose plugins and config apply")
+	s.fs.StringVar(&outputDir, "output-dir", ".sw/output", "Directory for reporter artifacts")
+	s.fs.StringVar(&pluginDir, "plugin-dir", "", "Directory containing compiled plugins")
+	s.fs.StringVar(&pluginIDs, "plugins", "", "Comma-separated plugin IDs whose hooks run during the replay")
+	s.fs.BoolVar(&allowUnsigned, "allow-unsigned-plugins", false, "Load plugins whose signature is missing or invalid")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if tracePath == "" {
+		say(stderr, MsgMissingArgument, "trace path")
+		return ExitUsageError
+	}
+
+	inv := cli.ReplayInvocation{Plugins: splitList(pluginIDs), AllowUnsignedPlugins: allowUnsigned, PluginLog: pluginLog{stderr}}
+	var err error
+	if inv.TracePath, err = absFromCWD(tracePath); err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	if inv.WorkDir, err = absFromCWD(workdir); err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	if inv.OutputDir, err = absUnderWorkdir(inv.WorkDir, outputDir); err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	if strings.TrimSpace(pluginDir) != "" {
+		if inv.PluginDir, err = absFromCWD(pluginDir); err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+
+	res, err := cli.Replay(context.Background(), inv)
+	if err != nil {
+		return fail(stderr, res.ExitCode, err)
+	}
+	printReplay(stdout, res)
+	return ExitSuccess
+}
+
+func cmdTraceFinalize(args []string, stdout, stderr io.Writer) int {
+	// The run ID may precede the flags.
+	var runID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		runID, args = args[0], args[1:]
+	}
+	s := newStrictFlagSet("sw trace finalize")
+	var workdir string
+	var out string
+	var asJSON bool
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	s.fs.StringVar(&out, "out", "", "Write the trace to this path instead of the run's --trace path")
+	s.fs.BoolVar(&asJSON, "json", false, "Print the summary as JSON")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if asJSON {
+		stderr = jsonMessages{stderr}
+	}
+	if runID == "" {
+		say(stderr, MsgMissingArgument, "run id")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	if out != "" {
+		if out, err = absFromCWD(out); err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+
+	st, err := state.NewStore(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	ft, err := cli.FinalizeTrace(st, absWorkdir, runID, out)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			say(stderr, MsgRunNotFound, runID)
+			return ExitUsageError
+		}
+		if errors.Is(err, cli.ErrNoTraceJournal) {
+			return fail(stderr, ExitUsageError, err)
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if asJSON {
+		data, err := json.MarshalIndent(ft, "", "  ")
+		if err != nil {
+			return fail(stderr, ExitInternalError, err)
+		}
+		fmt.Fprintln(stdout, string(data))
+		return ExitSuccess
+	}
+	say(stdout, MsgTraceFinalized, ft.RunID, ft.Events, ft.Nodes, ft.Path)
+	return ExitSuccess
+}
+
+func cmdWorkspace(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		say(stderr, MsgMissingSubcommand, "workspace", "export|import")
+		return ExitUsageError
+	}
+	switch args[0] {
+	case "export":
+		return cmdWorkspaceExport(args[1:], stdout, stderr)
+	case "import":
+		return cmdWorkspaceImport(args[1:], stdout, stderr)
+	default:
+		say(stderr, MsgUnknownSubcommand, "workspace", args[0])
+		return ExitUsageError
+	}
+}
+
+func cmdWorkspaceExport(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw workspace export")
+	var runID string
+	var workdir string
+	var output string
+	var includeCache bool
+	s.fs.StringVar(&runID, "run", "", "ID of the run to export")
+	s.fs.StringVar(&workdir, "workdir", "", "Project root containing .scriptweaver")
+	s.fs.StringVar(&output, "output", "", "Path of the bundle to write")
+	s.fs.BoolVar(&includeCache, "include-cache", false, "Bundle the cache entries of the run's checkpointed nodes")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	for _, f := range []struct{ name, value string }{{"--run", runID}, {"--workdir", workdir}, {"--output", output}} {
+		if strings.TrimSpace(f.value) == "" {
+			say(stderr, MsgFlagRequired, f.name)
+			return ExitUsageError
+		}
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	absOutput, err := absFromCWD(output)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	var buf bytes.Buffer
+	m, err := cli.ExportRun(absWorkdir, runID, &buf, cli.ExportOptions{IncludeCache: includeCache})
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			say(stderr, MsgRunNotFound, runID)
+			return ExitUsageError
+		}
+		if errors.Is(err, cli.ErrNoRunParams) {
+			return fail(stderr, ExitUsageError, err)
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if err := os.WriteFile(absOutput, buf.Bytes(), 0o644); err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	say(stdout, MsgExported, m.RunID, len(m.Files), absOutput)
+	if len(m.Redacted) > 0 {
+		say(stdout, MsgExportRedacted, strings.Join(m.Redacted, ", "))
+	}
+	return ExitSuccess
+}
+
+func cmdWorkspaceImport(args []string, stdout, stderr io.Writer) int {
+	// The bundle path may precede the flags.
+	var bundle string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		bundle, args = args[0], args[1:]
+	}
+	s := newStrictFlagSet("sw workspace import")
+	var workdir string
+	s.fs.StringVar(&workdir, "workdir", "", "Directory to import the run into")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if bundle == "" {
+		say(stderr, MsgMissingArgument, "bundle path")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	f, err := os.Open(bundle)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	defer f.Close()
+	m, err := cli.ImportRun(absWorkdir, f)
+	if err != nil {
+		if errors.Is(err, cli.ErrImportConflict) {
+			say(stderr, MsgImportConflict, err)
+			return ExitWorkspaceError
+		}
+		if errors.Is(err, cli.ErrInvalidBundle) {
+			return fail(stderr, ExitValidationError, err)
+		}
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	say(stdout, MsgImported, m.RunID, len(m.Files), m.Graph, absWorkdir)
+	return ExitSuccess
+}
+
+// printReplay writes the replayed event stream and the recorded outcome.
+func printReplay(w io.Writer, res cli.ReplayResult) {
+	say(w, MsgReplayed, res.GraphResult.GraphHash, len(res.GraphResult.FinalState), len(res.Events), res.RunExitCode)
+	for _, e := range res.Events {
+		line := fmt.Sprintf("%s %-24s %-22s", e.ID, e.TaskID, e.Kind)
+		if e.Reason != "" {
+			line += " " + e.Reason
+		}
+		if e.CauseTaskID != "" {
+			line += " (cause " + e.CauseTaskID + ")"
+		}
+		if s := e.Stage; s != nil {
+			line += fmt.Sprintf(" depth %d: %d nodes, %d completed, %d cached, %d failed, %d skipped", s.Depth, s.Nodes, s.Completed, s.Cached, s.Failed, s.Skipped)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
+
+// printTimeline writes tl as a table with offsets from the run start. Nodes
+// that never ran have no lane.
+func printTimeline(w io.Writer, tl cli.Timeline) {
+	say(w, MsgTimeline, tl.RunID, len(tl.Nodes), tl.Lanes, tl.Duration)
+	if tl.Reconstructed {
+		say(w, MsgTimelineFromCheckpts)
+	}
+	fmt.Fprintf(w, "%-24s %-12s %4s %12s %12s %12s %12s\n", "NODE", "STATUS", "LANE", "QUEUED", "START", "WAIT", "DURATION")
+	for _, n := range tl.Nodes {
+		lane := "-"
+		if n.Lane >= 0 {
+			lane = fmt.Sprint(n.Lane)
+		}
+		fmt.Fprintf(w, "%-24s %-12s %4s %12s %12s %12s %12s", n.Name, n.Status, lane, n.Queued, n.Started, n.Wait(), n.Duration())
+		if n.ThrottledBy != "" {
+			fmt.Fprint(w, message(MsgThrottledBy, n.ThrottledBy))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func cmdBench(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw bench")
+
+	var graphPath string
+	var workdir string
+	var cacheDir string
+	var outputDir string
+	var mode string
+	var iterations int
+	var baselinePath string
+	var savePath string
+
+	s.fs.StringVar(&graphPath, "graph", "", "Path to the graph definition file")
+	s.fs.StringVar(&workdir, "workdir", "", "Root directory for execution context")
+	s.fs.StringVar(&cacheDir, "cache-dir", "", "Directory for deterministic artifact caching (default <workdir>/.scriptweaver/cache)")
+	s.fs.StringVar(&outputDir, "output-dir", ".sw/output", "Directory for execution outputs")
+	s.fs.StringVar(&mode, "mode", "clean", "Execution strategy: clean|incremental")
+	s.fs.IntVar(&iterations, "iterations", 5, "Number of times to run the graph")
+	s.fs.StringVar(&baselinePath, "baseline", "", "Baseline file to compare against")
+	s.fs.StringVar(&savePath, "save-baseline", "", "Write the summary to this baseline file")
+
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(graphPath) == "" {
+		say(stderr, MsgFlagRequired, "--graph")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	if iterations < 1 {
+		say(stderr, MsgFlagTooSmall, "--iterations", 1)
+		return ExitUsageError
+	}
+
+	var execMode cli.ExecutionMode
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "clean", "":
+		execMode = cli.ExecutionModeClean
+	case "incremental":
+		execMode = cli.ExecutionModeIncremental
+	default:
+		say(stderr, MsgInvalidMode, mode)
+		return ExitUsageError
+	}
+
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	absGraph, err := absFromCWD(graphPath)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	var cacheAbs string
+	if strings.TrimSpace(cacheDir) != "" {
+		if cacheAbs, err = absUnderWorkdir(absWorkdir, cacheDir); err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+	outAbs, err := absUnderWorkdir(absWorkdir, outputDir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	var baseline *bench.Report
+	if strings.TrimSpace(baselinePath) != "" {
+		absBaseline, err := absFromCWD(baselinePath)
+		if err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+		baseline, err = bench.Load(absBaseline)
+		if err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+
+	inv := cli.CLIInvocation{
+		GraphPath:     absGraph,
+		WorkDir:       absWorkdir,
+		CacheDir:      cacheAbs,
+		OutputDir:     outAbs,
+		ExecutionMode: execMode,
+	}
+
+	rec := bench.NewRecorder()
+	graphHash := ""
+	for i := 1; i <= iterations; i++ {
+		res, execErr := cli.Execute(context.Background(), inv)
+		if execErr != nil {
+			say(stderr, MsgIterationError, i, execErr)
+			if isGraphValidationErr(execErr) {
+				return ExitValidationError
+			}
+			return failureExitCode(res.ExitCode)
+		}
+		if res.ExitCode != cli.ExitSuccess {
+			say(stderr, MsgIterationFailed, i)
+			return ExitExecutionFailure
+		}
+		graphHash = res.GraphResult.GraphHash.String()
+		rec.Add(res.Duration, res.NodeDurations)
+	}
+	report := rec.Report(graphHash, string(execMode))
+
+	printBenchReport(stdout, report)
+	if baseline != nil {
+		printBenchComparison(stdout, bench.Compare(baseline, report))
+	}
+	if strings.TrimSpace(savePath) != "" {
+		absSave, err := absFromCWD(savePath)
+		if err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+		if err := report.Save(absSave); err != nil {
+			return fail(stderr, ExitUsageError, err)
+		}
+	}
+	return ExitSuccess
+}
+
+func printBenchReport(w io.Writer, r *bench.Report) {
+	say(w, MsgBenchmark, r.Iterations, r.Mode)
+	fmt.Fprintf(w, "%-24s %12s %12s %12s\n", "NODE", "MEAN", "MEDIAN", "P95")
+	row := func(name string, st bench.Stats) {
+		fmt.Fprintf(w, "%-24s %12s %12s %12s\n", name, st.Mean, st.Median, st.P95)
+	}
+	row("total", r.Total)
+	for _, name := range r.NodeNames() {
+		row(name, r.Nodes[name])
+	}
+}
+
+func printBenchComparison(w io.Writer, c bench.Comparison) {
+	if !c.SameGraph {
+		say(w, MsgBaselineOtherGraph)
+	}
+	say(w, MsgComparedWithBaseline)
+	row := func(d bench.Delta) {
+		fmt.Fprintf(w, "%-24s %12s -> %12s (%+.1f%%)\n", d.Name, d.Baseline, d.Current, d.Change()*100)
+	}
+	row(c.Total)
+	for _, d := range c.Nodes {
+		row(d)
+	}
+}
+
+func cmdDaemon(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "metrics" {
+		return cmdDaemonMetrics(args[1:], stdout, stderr)
+	}
+	if len(args) > 0 && args[0] == "queue" {
+		return cmdDaemonQueue(args[1:], stdout, stderr)
+	}
+	s := newStrictFlagSet("sw daemon")
+	var workdir string
+	s.fs.StringVar(&workdir, "workdir", "", "Project root to serve")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	srv, err := daemon.Listen(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	go func() {
+		<-stop
+		_ = srv.Close()
+	}()
+
+	var outMu sync.Mutex
+	srv.OnSchedule = func(name string, res cli.CLIResult, err error) {
+		outMu.Lock()
+		defer outMu.Unlock()
+		switch {
+		case name == "":
+			say(stderr, MsgSchedulesNotLoaded, err)
+		case res.RunID != "":
+			say(stdout, MsgScheduledRun, res.RunID, name, res.ExitCode)
+		default:
+			say(stderr, MsgScheduleFailed, name, err)
+		}
+	}
+	say(stdout, MsgDaemonListening, srv.SocketPath())
+	if err := srv.Serve(); err != nil {
+		return fail(stderr, ExitInternalError, err)
+	}
+	return ExitSuccess
+}
+
+// cmdServe serves the web dashboard of a workspace until interrupted.
+func cmdServe(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw serve")
+	var workdir string
+	var addr string
+	s.fs.StringVar(&workdir, "workdir", "", "Project root whose runs to show")
+	s.fs.StringVar(&addr, "addr", "127.0.0.1:7070", "Address to listen on")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	handler, err := dashboard.Handler(absWorkdir)
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	go func() {
+		<-stop
+		_ = srv.Close()
+	}()
+
+	say(stdout, MsgServing, ln.Addr())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fail(stderr, ExitInternalError, err)
+	}
+	return ExitSuccess
+}
+
+// cmdDaemonMetrics prints the metrics of the running daemon in the Prometheus
+// text format, so a scraper can collect them through a textfile exporter.
+func cmdDaemonMetrics(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw daemon metrics")
+	var workdir string
+	s.fs.StringVar(&workdir, "workdir", "", "Project root the daemon serves")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	m, served, err := daemon.FetchMetrics(absWorkdir)
+	if !served {
+		say(stderr, MsgNoDaemon, absWorkdir)
+		return ExitWorkspaceError
+	}
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	fmt.Fprintf(stdout, "scriptweaver_runs_total %d\n", m.Runs)
+	fmt.Fprintf(stdout, "scriptweaver_cache_hits_total %d\n", m.Cache.Hits)
+	fmt.Fprintf(stdout, "scriptweaver_cache_misses_total %d\n", m.Cache.Misses)
+	fmt.Fprintf(stdout, "scriptweaver_cache_restored_bytes_total %d\n", m.Cache.BytesRestored)
+	return ExitSuccess
+}
+
+// cmdDaemonQueue prints the run the daemon executes and the runs waiting for
+// it.
+func cmdDaemonQueue(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw daemon queue")
+	var workdir string
+	var asJSON bool
+	s.fs.StringVar(&workdir, "workdir", "", "Project root the daemon serves")
+	s.fs.BoolVar(&asJSON, "json", false, "Print the queue as JSON")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if asJSON {
+		stderr = jsonMessages{stderr}
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	q, served, err := daemon.FetchQueue(absWorkdir)
+	if !served {
+		say(stderr, MsgNoDaemon, absWorkdir)
+		return ExitWorkspaceError
+	}
+	if err != nil {
+		return fail(stderr, ExitWorkspaceError, err)
+	}
+	if asJSON {
+		data, err := json.MarshalIndent(q, "", "  ")
+		if err != nil {
+			return fail(stderr, ExitInternalError, err)
+		}
+		fmt.Fprintln(stdout, string(data))
+		return ExitSuccess
+	}
+	if q.Active == nil && len(q.Queued) == 0 {
+		say(stdout, MsgQueueEmpty)
+		return ExitSuccess
+	}
+	fmt.Fprintf(stdout, "%-8s %-19s %-11s %-24s %-20s %s\n", "STATE", "REQUESTED", "MODE", "GRAPH", "SCHEDULE", "COALESCED")
+	row := func(state string, r cli.QueuedRun) {
+		fmt.Fprintf(stdout, "%-8s %-19s %-11s %-24s %-20s %d\n", state, r.Requested.Local().Format("2006-01-02 15:04:05"), r.Mode, r.Graph, r.Schedule, r.Coalesced)
+	}
+	if q.Active != nil {
+		row("running", *q.Active)
+	}
+	for _, r := range q.Queued {
+		row("queued", r)
+	}
+	return ExitSuccess
+}
+
+func cmdClean(args []string, stdout, stderr io.Writer) int {
+	s := newStrictFlagSet("sw clean")
+	var graphPath string
+	var workdir string
+	var outputDir string
+	var staleOutputs bool
+	var namespaceOutputs bool
+	var dryRun bool
+	s.fs.StringVar(&graphPath, "graph", "", "Path to the graph definition file")
+	s.fs.StringVar(&workdir, "workdir", "", "Root directory for execution context")
+	s.fs.StringVar(&outputDir, "output-dir", ".sw/output", "Directory for execution outputs")
+	s.fs.BoolVar(&staleOutputs, "stale-outputs", false, "Remove files produced by earlier runs that no node of the graph declares any more")
+	s.fs.BoolVar(&namespaceOutputs, "namespace-outputs", false, "Match the graph as run with --namespace-outputs")
+	s.fs.BoolVar(&dryRun, "dry-run", false, "List what would be removed without removing it")
+	if err := s.parse(args, stderr); err != nil {
+		return ExitUsageError
+	}
+	if !staleOutputs {
+		say(stderr, MsgNothingToClean)
+		return ExitUsageError
+	}
+	if strings.TrimSpace(graphPath) == "" {
+		say(stderr, MsgFlagRequired, "--graph")
+		return ExitUsageError
+	}
+	if strings.TrimSpace(workdir) == "" {
+		say(stderr, MsgFlagRequired, "--workdir")
+		return ExitUsageError
+	}
+	absWorkdir, err := absFromCWD(workdir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	absGraph, err := absFromCWD(graphPath)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+	outAbs, err := absUnderWorkdir(absWorkdir, outputDir)
+	if err != nil {
+		return fail(stderr, ExitUsageError, err)
+	}
+
+	inv := cli.CLIInvocation{GraphPath: absGraph, WorkDir: absWorkdir, OutputDir: outAbs, NamespaceOutputs: namespaceOutputs}
+	removed, code, err := cli.CleanStaleOutputs(inv, dryRun)
+	each, total := MsgRemoved, MsgRemovedTotal
+	if dryRun {
+		each, total = MsgWouldRemove, MsgWouldRemoveTotal
+	}
+	for _, so := range removed {
+		say(stdout, each, so.Path, so.Node)
+	}
+	if err != nil {
+		return fail(stderr, code, err)
 	}
+	say(stdout, total, len(removed))
 	return ExitSuccess
 }
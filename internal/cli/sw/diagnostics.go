@@ -0,0 +1,197 @@
+package sw
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"scriptweaver/exitcode"
+	"scriptweaver/internal/cli"
+	"scriptweaver/internal/graph"
+)
+
+// diagnostic is one problem sw validate found with a graph.
+type diagnostic struct {
+	// ID is the catalog message the diagnostic is printed with in text format.
+	ID MessageID `json:"id"`
+	// Severity is "error" or "warning".
+	Severity string       `json:"severity"`
+	Code     string       `json:"code"`
+	Node     string       `json:"node,omitempty"`
+	Message  string       `json:"message"`
+	Hints    []graph.Hint `json:"hints,omitempty"`
+	// Path, Line and Column locate the diagnostic in the graph file, when
+	// known.
+	Path   string `json:"path,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// graphDiagnostic returns the diagnostic of err, which rejects the graph,
+// located in the graph file when err locates it there.
+func graphDiagnostic(id MessageID, code string, err error) diagnostic {
+	d := diagnostic{ID: id, Severity: "error", Code: code, Message: err.Error(), Hints: graph.HintsOf(err)}
+	// Locations in an included file are not in the graph file.
+	var inc *cli.IncludeError
+	if !errors.As(err, &inc) {
+		var pos graph.Position
+		d.Path, pos = graph.LocationOf(err)
+		d.Line, d.Column = pos.Line, pos.Column
+	}
+	return d
+}
+
+// validateOutput collects the diagnostics of sw validate. In text format each
+// diagnostic is printed as it is found, followed by its hints; in json and
+// sarif format they are reported together by finish.
+type validateOutput struct {
+	format string
+	diags  []diagnostic
+}
+
+// warnings returns the number of warnings recorded.
+func (o *validateOutput) warnings() int {
+	n := 0
+	for _, d := range o.diags {
+		if d.Severity == "warning" {
+			n++
+		}
+	}
+	return n
+}
+
+func parseValidateFormat(s string) (string, error) {
+	switch s {
+	case "text", "json", "sarif":
+		return s, nil
+	default:
+		return "", errors.New(message(MsgInvalidFormat, s, "text|json|sarif"))
+	}
+}
+
+// add records d, printing its message with args to w in text format.
+func (o *validateOutput) add(d diagnostic, w io.Writer, args ...any) {
+	o.diags = append(o.diags, d)
+	if o.format != "text" {
+		return
+	}
+	say(w, d.ID, args...)
+	printHints(w, d.Hints)
+}
+
+// finish writes the json or sarif report of graphPath to w and returns code.
+func (o *validateOutput) finish(w io.Writer, graphPath string, code int) int {
+	var report any
+	switch o.format {
+	case "json":
+		report = struct {
+			Valid       bool         `json:"valid"`
+			Diagnostics []diagnostic `json:"diagnostics"`
+		}{code == ExitSuccess, append([]diagnostic{}, o.diags...)}
+	case "sarif":
+		report = sarifLog(graphPath, o.diags)
+	default:
+		return code
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return ExitInternalError
+	}
+	fmt.Fprintf(w, "%s\n", data)
+	return code
+}
+
+// fail records err, which stops validation with code, and finishes the
+// report of graphPath.
+func (o *validateOutput) fail(stdout, stderr io.Writer, graphPath string, code int, err error) int {
+	o.add(diagnostic{ID: errorID(code), Severity: "error", Code: exitcode.Name(code), Message: err.Error()}, stderr, err)
+	return o.finish(stdout, graphPath, code)
+}
+
+func printHints(w io.Writer, hints []graph.Hint) {
+	for _, h := range hints {
+		say(w, MsgHint, h.Message)
+	}
+}
+
+// sarifLog renders diags as a SARIF 2.1.0 log with one run, so that code
+// scanning tools can display them against graphPath. Each message carries its
+// catalog ID; hints are placed in the property bag of each result.
+func sarifLog(graphPath string, diags []diagnostic) any {
+	type sarifMessage struct {
+		ID   MessageID `json:"id"`
+		Text string    `json:"text"`
+	}
+	type artifactLocation struct {
+		URI string `json:"uri"`
+	}
+	type region struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn"`
+	}
+	type physicalLocation struct {
+		ArtifactLocation artifactLocation `json:"artifactLocation"`
+		Region           *region          `json:"region,omitempty"`
+	}
+	type location struct {
+		PhysicalLocation physicalLocation `json:"physicalLocation"`
+	}
+	type properties struct {
+		Node  string       `json:"node,omitempty"`
+		Path  string       `json:"path,omitempty"`
+		Hints []graph.Hint `json:"hints,omitempty"`
+	}
+	type result struct {
+		RuleID     string       `json:"ruleId"`
+		Level      string       `json:"level"`
+		Message    sarifMessage `json:"message"`
+		Locations  []location   `json:"locations"`
+		Properties *properties  `json:"properties,omitempty"`
+	}
+	type rule struct {
+		ID string `json:"id"`
+	}
+	type driver struct {
+		Name  string `json:"name"`
+		Rules []rule `json:"rules"`
+	}
+	type tool struct {
+		Driver driver `json:"driver"`
+	}
+	type run struct {
+		Tool    tool     `json:"tool"`
+		Results []result `json:"results"`
+	}
+
+	seen := make(map[string]bool)
+	rules := []rule{}
+	results := []result{}
+	for _, d := range diags {
+		if !seen[d.Code] {
+			seen[d.Code] = true
+			rules = append(rules, rule{ID: d.Code})
+		}
+		loc := location{PhysicalLocation: physicalLocation{ArtifactLocation: artifactLocation{URI: filepath.ToSlash(graphPath)}}}
+		if d.Line > 0 {
+			loc.PhysicalLocation.Region = &region{StartLine: d.Line, StartColumn: d.Column}
+		}
+		r := result{RuleID: d.Code, Level: d.Severity, Message: sarifMessage{ID: d.ID, Text: d.Message}, Locations: []location{loc}}
+		if d.Node != "" || d.Path != "" || len(d.Hints) > 0 {
+			r.Properties = &properties{Node: d.Node, Path: d.Path, Hints: d.Hints}
+		}
+		results = append(results, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []run  `json:"runs"`
+	}{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []run{{Tool: tool{Driver: driver{Name: "sw", Rules: rules}}, Results: results}},
+	}
+}
@@ -2,10 +2,21 @@ package sw
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
+
+	"scriptweaver/internal/cli"
+	"scriptweaver/internal/daemon"
+	"scriptweaver/internal/graph"
+	"scriptweaver/internal/pluginengine"
+	"scriptweaver/internal/recovery/state"
 )
 
 func repoRoot(t *testing.T) string {
@@ -45,92 +56,1974 @@ func TestRun_CleanDefault_Succeeds(t *testing.T) {
 	}
 }
 
-func TestValidate_Cycle_FailsWithExit1(t *testing.T) {
-	root := repoRoot(t)
-	if err := os.Chdir(root); err != nil {
-		t.Fatalf("chdir: %v", err)
+func TestRun_Incremental_ReportsDefaultCache(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"true","env":{},"outputs":[]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon"}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if want := "Using cache " + filepath.Join(workdir, ".scriptweaver", "cache") + "\n"; !strings.Contains(out.String(), want) {
+		t.Fatalf("stdout=%q, want %q", out.String(), want)
+	}
+	if want := "Cache: 0 hits, 1 misses (0% hit rate), 0 bytes restored\n"; !strings.Contains(out.String(), want) {
+		t.Fatalf("stdout=%q, want %q", out.String(), want)
+	}
+}
+
+func TestRun_Dedupe_ReportsDeduplicatedNodes(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "dup.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"echo hi","env":{},"outputs":[]},{"name":"b","inputs":[],"run":"echo hi","env":{},"outputs":[]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
 	}
 
 	var out, errBuf bytes.Buffer
-	exit := Main([]string{"validate", "--graph", "fixtures/cyclic.json"}, &out, &errBuf)
-	if exit != ExitValidationError {
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--dedupe"}, &out, &errBuf)
+	if exit != ExitSuccess {
 		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
 	}
-	if !strings.Contains(errBuf.String(), "Cycle detected") {
-		t.Fatalf("stderr=%q", errBuf.String())
+	if !strings.Contains(out.String(), "Deduplicated b (shared result of a)") {
+		t.Fatalf("stdout=%q", out.String())
 	}
 }
 
-func TestRun_UnknownFlag_StrictExit2(t *testing.T) {
-	root := repoRoot(t)
-	if err := os.Chdir(root); err != nil {
-		t.Fatalf("chdir: %v", err)
+func TestRun_Failure_ReportsEveryFailedAndSkippedNode(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "fail.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"exit 1"},{"name":"b","inputs":[],"run":"exit 2"},{"name":"c","inputs":[],"run":"true"}],"edges":[{"from":"a","to":"c"},{"from":"b","to":"c"}]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--no-daemon"}, &out, &errBuf)
+	if exit != ExitExecutionFailure {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, want := range []string{
+		"Failure: node a failed with exit code 1 (exit); 2 failed, 1 skipped [deterministic]",
+		"Failed node a: exit code 1 (exit)",
+		"Failed node b: exit code 2 (exit)",
+		"Skipped node c: upstream a failed",
+	} {
+		if !strings.Contains(errBuf.String(), want) {
+			t.Fatalf("stderr missing %q:\n%s", want, errBuf.String())
+		}
+	}
+}
+
+func TestRun_Skip_AppliesPolicyToDependents(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "skip.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"true"},{"name":"b","inputs":[],"run":"true"},{"name":"c","inputs":[],"run":"true"},{"name":"d","inputs":[],"run":"true"}],"edges":[{"from":"a","to":"b"},{"from":"b","to":"c"}]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	run := func(extra ...string) (int, string, string) {
+		var out, errBuf bytes.Buffer
+		args := append([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--no-daemon"}, extra...)
+		return Main(args, &out, &errBuf), out.String(), errBuf.String()
+	}
+
+	exit, stdout, stderr := run("--skip", "a")
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, stderr)
+	}
+	if !strings.Contains(stdout, "Skipped a (requested)\nSkipped b: upstream a was skipped\nSkipped c: upstream a was skipped\n") {
+		t.Fatalf("stdout=%q", stdout)
+	}
+
+	exit, _, stderr = run("--skip", "a", "--skip-policy", "fail")
+	if exit != ExitExecutionFailure || !strings.Contains(stderr, "Failed node b: upstream a was skipped on request (skip_policy)") || !strings.Contains(stderr, "Skipped node c: upstream b failed") {
+		t.Fatalf("exit=%d stderr=%q", exit, stderr)
 	}
 
+	for _, extra := range [][]string{{"--skip", "z"}, {"--skip", "a", "--skip-policy", "ignore"}} {
+		if exit, _, stderr = run(extra...); exit != ExitUsageError || stderr == "" {
+			t.Fatalf("%v: exit=%d stderr=%q", extra, exit, stderr)
+		}
+	}
+}
+
+func TestRun_PrintCommands_PrintsPastableLinesWithoutRunning(t *testing.T) {
 	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "print.json")
+	graphJSON := `{"tasks":[{"name":"build","inputs":[],"env":{"MODE":"release build"},"run":"echo 'built' > out.txt","outputs":["out.txt"]},{"name":"test","inputs":["out.txt"],"run":"cat out.txt","network":false}],"edges":[{"from":"build","to":"test"}]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
 	var out, errBuf bytes.Buffer
-	exit := Main([]string{"run", "--graph", "fixtures/basic.json", "--workdir", workdir, "--random-flag"}, &out, &errBuf)
-	if exit != ExitArgOrSystemError {
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--print-commands", "--no-daemon"}, &out, &errBuf)
+	if exit != ExitSuccess {
 		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
 	}
-	if !strings.Contains(strings.ToLower(errBuf.String()), "unknown flag") {
+	want := "# build\n" +
+		"(cd " + workdir + " && env -i LANG=C LC_ALL=C 'MODE=release build' TZ=UTC sh -c 'echo '\\''built'\\'' > out.txt')\n" +
+		"# test (runs without network access)\n" +
+		"(cd " + workdir + " && env -i LANG=C LC_ALL=C TZ=UTC sh -c 'cat out.txt')\n"
+	if out.String() != want {
+		t.Fatalf("stdout=%q, want %q", out.String(), want)
+	}
+	if _, err := os.Stat(filepath.Join(workdir, "out.txt")); !os.IsNotExist(err) {
+		t.Fatalf("a node ran: %v", err)
+	}
+
+	out.Reset()
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--print-commands", "--skip", "build"}, &out, &errBuf); exit != ExitSuccess || out.String() != "" {
+		t.Fatalf("with --skip build: exit=%d stdout=%q", exit, out.String())
+	}
+}
+
+func TestRun_DryRun_EstimatesWithoutRunning(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "estimate.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"echo a > a.txt","outputs":["a.txt"]},{"name":"b","inputs":[],"run":"echo b > b.txt","outputs":["b.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--dry-run"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	want := "execute a (?)\n" +
+		"execute b (?)\n" +
+		"Estimate: 2 nodes to execute, 0 to reuse, 0 skipped, about 0s\n" +
+		"  2 nodes to execute have no recorded duration and are not counted\n"
+	if out.String() != want {
+		t.Fatalf("stdout=%q, want %q", out.String(), want)
+	}
+	if _, err := os.Stat(filepath.Join(workdir, "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("a node ran: %v", err)
+	}
+
+	out.Reset()
+	errBuf.Reset()
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "Estimate: 2 nodes to execute") {
+		t.Fatalf("run stderr=%q", errBuf.String())
+	}
+	out.Reset()
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--dry-run"}, &out, &errBuf); exit != ExitSuccess || !strings.Contains(out.String(), "reuse   a\n") || !strings.Contains(out.String(), "0 nodes to execute, 2 to reuse") {
+		t.Fatalf("after a run: exit=%d stdout=%q", exit, out.String())
+	}
+}
+
+func TestRun_AuditDeterminism_ComparesSerialAndParallelRuns(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "audit.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"printf a > a.txt","outputs":["a.txt"]},{"name":"b","inputs":[],"run":"ls .scriptweaver/runs | wc -l > b.txt","outputs":["b.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	run := func(extra ...string) (int, string, string) {
+		var out, errBuf bytes.Buffer
+		args := append([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon"}, extra...)
+		return Main(args, &out, &errBuf), out.String(), errBuf.String()
+	}
+
+	exit, stdout, stderr := run("--mode", "clean", "--audit-determinism", "2")
+	if exit != ExitExecutionFailure || !strings.Contains(stdout, "Determinism audit failed: 1 divergences between") || !strings.Contains(stdout, "  b output b.txt: ") {
+		t.Fatalf("exit=%d stdout=%q stderr=%q", exit, stdout, stderr)
+	}
+
+	if exit, _, stderr = run("--audit-determinism", "2"); exit != ExitUsageError || !strings.Contains(stderr, "--audit-determinism requires --mode clean") {
+		t.Fatalf("incremental: exit=%d stderr=%q", exit, stderr)
+	}
+}
+
+func TestRun_Simulate_PlaysScenarioWithoutRunningCommands(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "sim.json")
+	graphJSON := `{"tasks":[{"name":"build","inputs":[],"run":"touch built.txt","outputs":["built.txt"]},{"name":"test","inputs":[],"run":"touch tested.txt"}],"edges":[{"from":"build","to":"test"}]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	scenarioPath := filepath.Join(workdir, "scenario.json")
+	if err := os.WriteFile(scenarioPath, []byte(`{"nodes":{"build":{"duration":"10ms"},"test":{"exit_code":1,"stderr":"1 failed"}}}`), 0o644); err != nil {
+		t.Fatalf("write scenario: %v", err)
+	}
+	run := func(extra ...string) (int, string, string) {
+		var out, errBuf bytes.Buffer
+		args := append([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon"}, extra...)
+		return Main(args, &out, &errBuf), out.String(), errBuf.String()
+	}
+
+	exit, stdout, stderr := run("--simulate", scenarioPath)
+	if exit != ExitExecutionFailure || !strings.Contains(stderr, "node test failed with exit code 1") || strings.Contains(stdout, "Using cache") {
+		t.Fatalf("exit=%d stdout=%q stderr=%q", exit, stdout, stderr)
+	}
+	for _, name := range []string{"built.txt", "tested.txt"} {
+		if _, err := os.Stat(filepath.Join(workdir, name)); !os.IsNotExist(err) {
+			t.Fatalf("%s exists after a simulated run: %v", name, err)
+		}
+	}
+
+	if err := os.WriteFile(scenarioPath, []byte(`{"nodes":{"deploy":{}}}`), 0o644); err != nil {
+		t.Fatalf("write scenario: %v", err)
+	}
+	if exit, _, stderr = run("--simulate", scenarioPath); exit == ExitSuccess || !strings.Contains(stderr, `unknown nodes ["deploy"]`) {
+		t.Fatalf("unknown node: exit=%d stderr=%q", exit, stderr)
+	}
+	if exit, _, stderr = run("--simulate", scenarioPath, "--verify-determinism", "1"); exit != ExitUsageError || !strings.Contains(stderr, "--simulate is not compatible with --verify-determinism") {
+		t.Fatalf("conflict: exit=%d stderr=%q", exit, stderr)
+	}
+}
+
+func TestRun_Chaos_InjectsAndRecordsFaults(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "chaos.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"true"}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--no-daemon", "--chaos", "task-failure=1,seed=5"}, &out, &errBuf)
+	if exit != ExitExecutionFailure || !strings.Contains(errBuf.String(), "Chaos (seed 5): injected 1 faults") || !strings.Contains(errBuf.String(), "  task-failure a (exit ") {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	records, _ := filepath.Glob(filepath.Join(workdir, ".scriptweaver", "runs", "*", "chaos.json"))
+	if len(records) != 1 {
+		t.Fatalf("expected one chaos record, got %v", records)
+	}
+
+	errBuf.Reset()
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--chaos", "task-failure=2"}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("invalid rate: exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestRun_Failure_ReportsNodeAnnotations(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "fail.json")
+	graphJSON := `{"tasks":[{"name":"migrate","inputs":[],"run":"exit 1","metadata":{"owner":"team-db","description":"Applies schema migrations","docs_url":"https://runbooks.example/migrate"}}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--no-daemon"}, &out, &errBuf)
+	if exit != ExitExecutionFailure {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, want := range []string{
+		"Failure: node migrate failed with exit code 1 (exit) [owner team-db, docs https://runbooks.example/migrate]",
+		"  Owner: team-db",
+		"  Description: Applies schema migrations",
+		"  Docs: https://runbooks.example/migrate",
+	} {
+		if !strings.Contains(errBuf.String(), want) {
+			t.Fatalf("stderr missing %q:\n%s", want, errBuf.String())
+		}
+	}
+
+	records, _ := filepath.Glob(filepath.Join(workdir, ".scriptweaver", "runs", "*", "failures", "migrate.json"))
+	if len(records) != 1 {
+		t.Fatalf("expected one failure record, got %v", records)
+	}
+	data, err := os.ReadFile(records[0])
+	if err != nil {
+		t.Fatalf("read failure record: %v", err)
+	}
+	var nf state.NodeFailure
+	if err := json.Unmarshal(data, &nf); err != nil {
+		t.Fatalf("decode failure record: %v", err)
+	}
+	if nf.Owner != "team-db" || nf.Description != "Applies schema migrations" || nf.DocsURL != "https://runbooks.example/migrate" {
+		t.Fatalf("failure record lacks annotations: %s", data)
+	}
+}
+
+func TestRun_VerifyDeterminism_ReportsNondeterministicTask(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "pid.json")
+	graphJSON := `{"tasks":[{"name":"pid","inputs":[],"run":"echo $$ > pid.txt","env":{},"outputs":["pid.txt"]},{"name":"stable","inputs":[],"run":"echo ok > ok.txt","env":{},"outputs":["ok.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--verify-determinism", "2"}, &out, &errBuf)
+	if exit != ExitExecutionFailure {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "Nondeterministic task pid: artifact:pid.txt") {
 		t.Fatalf("stderr=%q", errBuf.String())
 	}
+	if strings.Contains(errBuf.String(), "task stable") {
+		t.Fatalf("stable task reported as nondeterministic: %q", errBuf.String())
+	}
 }
 
-func TestHash_Stable_IgnoresWorkdirFlag(t *testing.T) {
-	root := repoRoot(t)
-	if err := os.Chdir(root); err != nil {
-		t.Fatalf("chdir: %v", err)
+func TestRun_PublishConfigured_CopiesOutputsWithManifest(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "pub.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"printf hi > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(workdir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	cfg := `{"publish":{"destination":"dist","outputs":["a.txt"]}}`
+	if err := os.WriteFile(filepath.Join(workdir, ".scriptweaver", "config.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
 	}
 
-	var out1, err1 bytes.Buffer
-	exit1 := Main([]string{"hash", "--graph", "fixtures/basic.json"}, &out1, &err1)
-	if exit1 != ExitSuccess {
-		t.Fatalf("exit=%d stderr=%q", exit1, err1.String())
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean"}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
 	}
+	if !strings.Contains(out.String(), "Published 1 files to ") {
+		t.Fatalf("stdout=%q", out.String())
+	}
+	sums, err := os.ReadFile(filepath.Join(workdir, "dist", "SHA256SUMS"))
+	if err != nil || !strings.HasSuffix(string(sums), "  a.txt\n") {
+		t.Fatalf("SHA256SUMS=%q err=%v", sums, err)
+	}
+	for _, name := range []string{"a.txt", "provenance.json"} {
+		if _, err := os.Stat(filepath.Join(workdir, "dist", name)); err != nil {
+			t.Fatalf("%s not published: %v", name, err)
+		}
+	}
+}
 
-	var out2, err2 bytes.Buffer
-	exit2 := Main([]string{"hash", "--graph", "fixtures/basic.json", "--workdir", t.TempDir()}, &out2, &err2)
-	if exit2 != ExitSuccess {
-		t.Fatalf("exit=%d stderr=%q", exit2, err2.String())
+func TestRun_PublishUndeclaredOutput_IsConfigError(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "pub.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"printf hi > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(workdir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	cfg := `{"publish":{"destination":"dist","outputs":["b.txt"]}}`
+	if err := os.WriteFile(filepath.Join(workdir, ".scriptweaver", "config.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
 	}
 
-	if strings.TrimSpace(out1.String()) != strings.TrimSpace(out2.String()) {
-		t.Fatalf("hash mismatch: %q vs %q", out1.String(), out2.String())
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean"}, &out, &errBuf)
+	if exit == ExitSuccess || !strings.Contains(errBuf.String(), "not declared") {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if _, err := os.Stat(filepath.Join(workdir, "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("graph must not execute with invalid publish config")
 	}
 }
 
-func TestPluginsList_OutputSortedPlaintext(t *testing.T) {
-	root := repoRoot(t)
-	if err := os.Chdir(root); err != nil {
-		t.Fatalf("chdir: %v", err)
+func TestRun_CorruptWorkspace_ExitsWorkspaceError(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"true","env":{},"outputs":[]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workdir, ".scriptweaver"), []byte("not a dir"), 0o644); err != nil {
+		t.Fatalf("write workspace file: %v", err)
 	}
 
-	pluginDir := t.TempDir()
-	// Create two plugin dirs with manifests.
-	alpha := filepath.Join(pluginDir, "alpha")
-	beta := filepath.Join(pluginDir, "beta")
-	if err := os.MkdirAll(alpha, 0o755); err != nil {
-		t.Fatalf("mkdir alpha: %v", err)
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean"}, &out, &errBuf)
+	if exit != ExitWorkspaceError {
+		t.Fatalf("expected exit %d got %d stderr=%q", ExitWorkspaceError, exit, errBuf.String())
 	}
-	if err := os.MkdirAll(beta, 0o755); err != nil {
-		t.Fatalf("mkdir beta: %v", err)
+}
+
+func TestErrors_CarryTheirMessageIDInJSON(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	if err := os.WriteFile(graphPath, []byte(`{"tasks":[{"name":"a","run":"true"}]}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
 	}
-	if err := os.WriteFile(filepath.Join(alpha, "manifest.json"), []byte(`{"plugin_id":"Alpha","version":"0.0.0","hooks":["BeforeRun"],"description":"alpha"}`), 0o644); err != nil {
-		t.Fatalf("write alpha manifest: %v", err)
+	if err := os.WriteFile(filepath.Join(workdir, ".scriptweaver"), []byte("not a dir"), 0o644); err != nil {
+		t.Fatalf("write workspace file: %v", err)
 	}
-	if err := os.WriteFile(filepath.Join(beta, "manifest.json"), []byte(`{"plugin_id":"Beta","version":"0.0.0","hooks":["BeforeRun"],"description":"beta"}`), 0o644); err != nil {
-		t.Fatalf("write beta manifest: %v", err)
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"runs", "list", "--workdir", workdir}, &out, &errBuf); exit != ExitWorkspaceError {
+		t.Fatalf("text: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	text := strings.TrimSpace(errBuf.String())
+	if text == "" || strings.HasPrefix(text, "{") {
+		t.Fatalf("text stderr = %q", text)
+	}
+
+	errBuf.Reset()
+	if exit := Main([]string{"runs", "list", "--workdir", workdir, "--json"}, &out, &errBuf); exit != ExitWorkspaceError {
+		t.Fatalf("json: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	var line struct {
+		ID      MessageID `json:"id"`
+		Message string    `json:"message"`
+	}
+	if err := json.Unmarshal(errBuf.Bytes(), &line); err != nil {
+		t.Fatalf("json stderr: %v\n%s", err, errBuf.String())
+	}
+	if line.ID != MsgWorkspaceError || line.Message != text {
+		t.Fatalf("json stderr = %+v, want id %s and message %q", line, MsgWorkspaceError, text)
 	}
 
+	out.Reset()
+	errBuf.Reset()
+	if exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--format", "json"}, &out, &errBuf); exit != ExitWorkspaceError {
+		t.Fatalf("validate: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	var report struct {
+		Valid       bool `json:"valid"`
+		Diagnostics []struct {
+			ID       MessageID `json:"id"`
+			Severity string    `json:"severity"`
+			Code     string    `json:"code"`
+		} `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("report: %v\n%s", err, out.String())
+	}
+	if len(report.Diagnostics) == 0 {
+		t.Fatalf("report = %s", out.String())
+	}
+	last := report.Diagnostics[len(report.Diagnostics)-1]
+	if report.Valid || last.ID != MsgWorkspaceError || last.Severity != "error" || last.Code != "workspace" {
+		t.Fatalf("report = %s", out.String())
+	}
+}
+
+func TestBench_ReportsStatsAndComparesBaseline(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "bench.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"echo a > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	baseline := filepath.Join(workdir, "baseline.json")
+
 	var out, errBuf bytes.Buffer
-	exit := Main([]string{"plugins", "list", "--plugin-dir", pluginDir}, &out, &errBuf)
+	exit := Main([]string{"bench", "--graph", graphPath, "--workdir", workdir, "--iterations", "3", "--save-baseline", baseline}, &out, &errBuf)
 	if exit != ExitSuccess {
 		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
 	}
-	got := strings.TrimSpace(out.String())
-	if got != "Alpha\nBeta" {
+	if !strings.Contains(out.String(), "Benchmark: 3 iterations (clean)") || !strings.Contains(out.String(), "\na ") {
+		t.Fatalf("stdout=%q", out.String())
+	}
+
+	out.Reset()
+	errBuf.Reset()
+	exit = Main([]string{"bench", "--graph", graphPath, "--workdir", workdir, "--iterations", "2", "--mode", "incremental", "--baseline", baseline}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(out.String(), "Compared with baseline (mean):") {
 		t.Fatalf("stdout=%q", out.String())
 	}
 }
+
+func TestBench_RejectsZeroIterations(t *testing.T) {
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"bench", "--graph", "g.json", "--workdir", t.TempDir(), "--iterations", "0"}, &out, &errBuf)
+	if exit != ExitUsageError {
+		t.Fatalf("exit=%d", exit)
+	}
+}
+
+func TestRun_ProfileAndVerbose_WritesProfilesAndPhases(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"echo a > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "-v", "--profile", "cpu=cpu.pprof", "--profile", "mem=mem.pprof"}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, phase := range []string{"parse", "validate", "hash", "plan", "execute", "finalize"} {
+		if !strings.Contains(errBuf.String(), "phase "+phase) {
+			t.Fatalf("missing phase %s in stderr=%q", phase, errBuf.String())
+		}
+	}
+	for _, name := range []string{"cpu.pprof", "mem.pprof"} {
+		info, err := os.Stat(filepath.Join(workdir, name))
+		if err != nil || info.Size() == 0 {
+			t.Fatalf("profile %s not written: %v", name, err)
+		}
+	}
+}
+
+func TestRun_InvalidProfileKind_IsArgError(t *testing.T) {
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", "g.json", "--workdir", t.TempDir(), "--profile", "block=x"}, &out, &errBuf)
+	if exit != ExitUsageError {
+		t.Fatalf("exit=%d", exit)
+	}
+}
+
+func TestRun_NegativeRetries_IsArgError(t *testing.T) {
+	for _, flags := range [][]string{{"--retries", "-1"}, {"--retries", "1", "--retry-backoff", "-1s"}} {
+		var out, errBuf bytes.Buffer
+		args := append([]string{"run", "--graph", "g.json", "--workdir", t.TempDir()}, flags...)
+		if exit := Main(args, &out, &errBuf); exit != ExitUsageError {
+			t.Fatalf("%v: exit=%d stderr=%s", flags, exit, errBuf.String())
+		}
+	}
+}
+
+func TestRun_UsesRunningDaemon(t *testing.T) {
+	workdir := t.TempDir()
+	srv, err := daemon.Listen(workdir)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	go func() { _ = srv.Serve() }()
+	defer srv.Close()
+
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"echo a > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "-v"}, &out, &errBuf)
+	if exit != ExitSuccess || !strings.Contains(errBuf.String(), "served by daemon") {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	out.Reset()
+	errBuf.Reset()
+	exit = Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "-v", "--no-daemon"}, &out, &errBuf)
+	if exit != ExitSuccess || strings.Contains(errBuf.String(), "served by daemon") {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestAuditVerify_AfterRun_Succeeds(t *testing.T) {
+	root := repoRoot(t)
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	workdir := t.TempDir()
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"run", "--graph", "fixtures/basic.json", "--workdir", workdir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	out.Reset()
+	errBuf.Reset()
+	exit := Main([]string{"audit", "verify", "--workdir", workdir}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(out.String(), "Audit log OK") || strings.Contains(out.String(), "(0 entries)") {
+		t.Fatalf("stdout=%q", out.String())
+	}
+}
+
+func TestRunsTimeline_AfterRun_PrintsTextAndJSON(t *testing.T) {
+	root := repoRoot(t)
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	workdir := t.TempDir()
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"run", "--graph", "fixtures/basic.json", "--workdir", workdir, "--no-daemon"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+	st, err := state.NewStore(workdir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	ids, err := st.ListRunIDs()
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("runs=%v err=%v", ids, err)
+	}
+
+	out.Reset()
+	errBuf.Reset()
+	if exit := Main([]string{"runs", "timeline", ids[0], "--workdir", workdir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, want := range []string{"Timeline for run " + ids[0], "NODE", "LANE", "DURATION", "executed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("stdout missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if exit := Main([]string{"runs", "timeline", ids[0], "--workdir", workdir, "--json"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	var tl cli.Timeline
+	if err := json.Unmarshal(out.Bytes(), &tl); err != nil {
+		t.Fatalf("parse json: %v\n%s", err, out.String())
+	}
+	if tl.RunID != ids[0] || len(tl.Nodes) == 0 || tl.Lanes < 1 {
+		t.Fatalf("unexpected timeline: %#v", tl)
+	}
+
+	errBuf.Reset()
+	if exit := Main([]string{"runs", "timeline", "no-such-run", "--workdir", workdir}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "run no-such-run not found") {
+		t.Fatalf("stderr=%q", errBuf.String())
+	}
+}
+
+func TestRun_ListOutputs_PrintsAndWritesJSON(t *testing.T) {
+	root := repoRoot(t)
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	workdir := t.TempDir()
+	jsonPath := filepath.Join(workdir, "artifacts", "outputs.json")
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", "fixtures/basic.json", "--workdir", workdir, "--no-daemon", "--list-outputs", "--outputs-json", jsonPath}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if want := "Output t1 out.txt 3 sha256:dc51b8c96c2d745df3bd5590d990230a482fd247123599548e0632fdbf97fc22\n"; !strings.Contains(out.String(), want) {
+		t.Fatalf("stdout missing %q:\n%s", want, out.String())
+	}
+
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatalf("read outputs json: %v", err)
+	}
+	var outputs []cli.NodeOutputs
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		t.Fatalf("parse outputs json: %v\n%s", err, data)
+	}
+	if len(outputs) != 1 || outputs[0].Node != "t1" || len(outputs[0].Files) != 1 || outputs[0].Files[0].Path != "out.txt" || outputs[0].Files[0].Size != 3 {
+		t.Fatalf("unexpected outputs: %#v", outputs)
+	}
+}
+
+func TestClean_StaleOutputs_WarnsThenRemoves(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "graph.json")
+	writeGraph := func(graphJSON string) {
+		t.Helper()
+		if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+			t.Fatalf("write graph: %v", err)
+		}
+	}
+	writeGraph(`{"tasks":[{"name":"a","inputs":[],"run":"printf a > a.txt","outputs":["a.txt"]},{"name":"b","inputs":[],"run":"printf b > b.txt","outputs":["b.txt"]}],"edges":[]}`)
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	writeGraph(`{"tasks":[{"name":"a","inputs":[],"run":"printf a > a.txt","outputs":["a.txt"]}],"edges":[]}`)
+	errBuf.Reset()
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if want := "Warning: stale output b.txt (last produced by b) is no longer owned by any node"; !strings.Contains(errBuf.String(), want) {
+		t.Fatalf("stderr missing %q:\n%s", want, errBuf.String())
+	}
+
+	out.Reset()
+	if exit := Main([]string{"clean", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("clean without a target: exit=%d", exit)
+	}
+	if exit := Main([]string{"clean", "--stale-outputs", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("clean exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if got, want := out.String(), "Removed b.txt (last produced by b)\nRemoved 1 stale outputs\n"; got != want {
+		t.Fatalf("stdout = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(workdir, "b.txt")); !os.IsNotExist(err) {
+		t.Fatalf("b.txt not removed: %v", err)
+	}
+}
+
+func TestRunsDiff_HighlightsEnvironmentChanges(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "graph.json")
+	var runIDs []string
+	for _, mode := range []string{"debug", "release"} {
+		graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"true","env":{"MODE":"` + mode + `"}}],"edges":[]}`
+		if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+			t.Fatalf("write graph: %v", err)
+		}
+		var out, errBuf bytes.Buffer
+		if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon"}, &out, &errBuf); exit != ExitSuccess {
+			t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+		}
+		st, err := state.NewStore(workdir)
+		if err != nil {
+			t.Fatalf("NewStore: %v", err)
+		}
+		ids, err := st.ListRunIDs()
+		if err != nil {
+			t.Fatalf("ListRunIDs: %v", err)
+		}
+		for _, id := range ids {
+			if len(runIDs) == 0 || id != runIDs[0] {
+				runIDs = append(runIDs, id)
+				break
+			}
+		}
+	}
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"runs", "diff", runIDs[0], runIDs[1], "--workdir", workdir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, want := range []string{"executed different graphs", "No parameter differences", "1 environment differences:", "  a: MODE changed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("stdout missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "release") {
+		t.Fatalf("environment values must not be printed:\n%s", out.String())
+	}
+
+	errBuf.Reset()
+	if exit := Main([]string{"runs", "diff", runIDs[0], "--workdir", workdir}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("one run id: exit=%d", exit)
+	}
+	if exit := Main([]string{"runs", "diff", runIDs[0], "no-such-run", "--workdir", workdir}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("missing run: exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestRunsShow_PrintsInvocationParameters(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "graph.json")
+	if err := os.WriteFile(graphPath, []byte(`{"tasks":[{"name":"a","inputs":[],"run":"true"},{"name":"b","inputs":[],"run":"true"}],"edges":[]}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon", "--mode", "clean", "--skip", "b"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+	st, err := state.NewStore(workdir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	ids, err := st.ListRunIDs()
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("ListRunIDs = %v, %v", ids, err)
+	}
+
+	out.Reset()
+	if exit := Main([]string{"runs", "show", ids[0], "--workdir", workdir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, want := range []string{"Run " + ids[0] + ": clean mode", "Parameters:", `  graph: "graph.json"`, `  mode: "clean"`, `  skip: ["b"]`} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("stdout missing %q:\n%s", want, out.String())
+		}
+	}
+	if exit := Main([]string{"runs", "show", "no-such-run", "--workdir", workdir}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("missing run: exit=%d", exit)
+	}
+}
+
+func TestTraceFinalize_RequiresAJournal(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "graph.json")
+	if err := os.WriteFile(graphPath, []byte(`{"tasks":[{"name":"a","inputs":[],"run":"true"}],"edges":[]}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon", "--trace"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+	st, err := state.NewStore(workdir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	ids, err := st.ListRunIDs()
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("ListRunIDs = %v, %v", ids, err)
+	}
+
+	// A run that finished wrote its trace and dropped its journal.
+	errBuf.Reset()
+	if exit := Main([]string{"trace", "finalize", ids[0], "--workdir", workdir}, &out, &errBuf); exit != ExitUsageError || !strings.Contains(errBuf.String(), "has no trace journal") {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if exit := Main([]string{"trace", "finalize", "--workdir", workdir}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("missing run id: exit=%d", exit)
+	}
+}
+
+func TestWorkspaceExportImport_ReproducesARun(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "graph.json")
+	if err := os.WriteFile(graphPath, []byte(`{"tasks":[{"name":"a","inputs":[],"run":"exit 1"}],"edges":[]}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon"}, &out, &errBuf); exit != ExitExecutionFailure {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+	st, err := state.NewStore(workdir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	ids, err := st.ListRunIDs()
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("ListRunIDs = %v, %v", ids, err)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "repro.tar.zst")
+	if exit := Main([]string{"workspace", "export", "--run", ids[0], "--workdir", workdir, "--output", bundle}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("export exit=%d stderr=%q", exit, errBuf.String())
+	}
+	target := t.TempDir()
+	out.Reset()
+	if exit := Main([]string{"workspace", "import", bundle, "--workdir", target}, &out, &errBuf); exit != ExitSuccess || !strings.Contains(out.String(), "Imported run "+ids[0]) {
+		t.Fatalf("import exit=%d stdout=%q stderr=%q", exit, out.String(), errBuf.String())
+	}
+	out.Reset()
+	if exit := Main([]string{"runs", "show", ids[0], "--workdir", target}, &out, &errBuf); exit != ExitSuccess || !strings.Contains(out.String(), "Failed:") {
+		t.Fatalf("show exit=%d stdout=%q", exit, out.String())
+	}
+	errBuf.Reset()
+	if exit := Main([]string{"workspace", "import", bundle, "--workdir", target}, &out, &errBuf); exit != ExitWorkspaceError || !strings.Contains(errBuf.String(), "Not imported") {
+		t.Fatalf("second import exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if exit := Main([]string{"workspace", "export", "--run", "no-such-run", "--workdir", workdir, "--output", bundle}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("unknown run: exit=%d", exit)
+	}
+}
+
+func TestTraceReplay_PrintsRecordedEvents(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "fail.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"true"},{"name":"b","inputs":[],"run":"exit 1"},{"name":"c","inputs":[],"run":"true"}],"edges":[{"from":"b","to":"c"}]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--trace", "--no-daemon"}, &out, &errBuf); exit != ExitExecutionFailure {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	out.Reset()
+	errBuf.Reset()
+	tracePath := filepath.Join(workdir, ".sw", "output", "trace.json")
+	if exit := Main([]string{"trace", "replay", tracePath, "--workdir", workdir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, want := range []string{"(3 nodes, 5 events, run exit code 3)", "StageCompleted         depth 0: 2 nodes, 1 completed, 0 cached, 1 failed, 0 skipped", "TaskExecuted", "TaskFailed", "TaskSkipped", "UpstreamFailed (cause b)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("stdout missing %q:\n%s", want, out.String())
+		}
+	}
+
+	errBuf.Reset()
+	if exit := Main([]string{"trace", "replay", filepath.Join(workdir, "missing.json")}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestValidate_Cycle_FailsWithExit1(t *testing.T) {
+	root := repoRoot(t)
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", "fixtures/cyclic.json"}, &out, &errBuf)
+	if exit != ExitValidationError {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "Cycle detected") {
+		t.Fatalf("stderr=%q", errBuf.String())
+	}
+}
+
+func TestRun_UnknownFlag_StrictExit2(t *testing.T) {
+	root := repoRoot(t)
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	workdir := t.TempDir()
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", "fixtures/basic.json", "--workdir", workdir, "--random-flag"}, &out, &errBuf)
+	if exit != ExitUsageError {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(strings.ToLower(errBuf.String()), "unknown flag") {
+		t.Fatalf("stderr=%q", errBuf.String())
+	}
+}
+
+func TestValidateRunHash_JSONLinesGraphFile(t *testing.T) {
+	workdir := t.TempDir()
+	linesPath := filepath.Join(workdir, "graph.jsonl")
+	lines := `{"task": {"name": "a", "run": "echo a > a.txt", "outputs": ["a.txt"]}}` + "\n" +
+		`{"task": {"name": "b", "inputs": ["a.txt"], "run": "cat a.txt"}}` + "\n" +
+		`{"edge": {"from": "a", "to": "b"}}` + "\n"
+	jsonPath := filepath.Join(workdir, "graph.json")
+	whole := `{"tasks": [{"name": "a", "run": "echo a > a.txt", "outputs": ["a.txt"]}, {"name": "b", "inputs": ["a.txt"], "run": "cat a.txt"}], "edges": [{"from": "a", "to": "b"}]}`
+	if err := os.WriteFile(linesPath, []byte(lines), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	if err := os.WriteFile(jsonPath, []byte(whole), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"validate", "--graph", linesPath}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("validate exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if exit := Main([]string{"run", "--graph", linesPath, "--workdir", workdir, "--no-daemon"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+	var linesHash, jsonHash bytes.Buffer
+	if exit := Main([]string{"hash", "--graph", linesPath}, &linesHash, &errBuf); exit != ExitSuccess {
+		t.Fatalf("hash exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if exit := Main([]string{"hash", "--graph", jsonPath}, &jsonHash, &errBuf); exit != ExitSuccess {
+		t.Fatalf("hash exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if linesHash.String() != jsonHash.String() {
+		t.Fatalf("hash of JSON Lines graph %q differs from JSON graph %q", linesHash.String(), jsonHash.String())
+	}
+
+	if err := os.WriteFile(linesPath, []byte(lines+`{"task": {"name": "c", "command": "true"}}`+"\n"), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	errBuf.Reset()
+	if exit := Main([]string{"validate", "--graph", linesPath}, &out, &errBuf); exit != ExitValidationError || !strings.Contains(errBuf.String(), "line 4, column 24") {
+		t.Fatalf("validate exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestHash_Stable_IgnoresWorkdirFlag(t *testing.T) {
+	root := repoRoot(t)
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	var out1, err1 bytes.Buffer
+	exit1 := Main([]string{"hash", "--graph", "fixtures/basic.json"}, &out1, &err1)
+	if exit1 != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit1, err1.String())
+	}
+
+	var out2, err2 bytes.Buffer
+	exit2 := Main([]string{"hash", "--graph", "fixtures/basic.json", "--workdir", t.TempDir()}, &out2, &err2)
+	if exit2 != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit2, err2.String())
+	}
+
+	if strings.TrimSpace(out1.String()) != strings.TrimSpace(out2.String()) {
+		t.Fatalf("hash mismatch: %q vs %q", out1.String(), out2.String())
+	}
+}
+
+func TestRun_PluginsAllowlist_RunsOnlyListedPlugins(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"true","env":{},"outputs":[]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	pluginDir := t.TempDir()
+	marker := filepath.Join(workdir, "hooks.log")
+	for _, id := range []string{"on", "off"} {
+		dir := filepath.Join(pluginDir, id)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		manifest := `{"plugin_id":"` + id + `","version":"1.0.0","hooks":["BeforeRun"],"description":""}`
+		if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0o644); err != nil {
+			t.Fatalf("write manifest: %v", err)
+		}
+		script := "#!/bin/sh\necho \"$SCRIPTWEAVER_PLUGIN_ID $1\" >> " + marker + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "plugin"), []byte(script), 0o755); err != nil {
+			t.Fatalf("write plugin: %v", err)
+		}
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--plugin-dir", pluginDir, "--plugins", "on"}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	b, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatalf("read marker: %v", err)
+	}
+	if string(b) != "on BeforeRun\n" {
+		t.Fatalf("hooks.log = %q", b)
+	}
+
+	out.Reset()
+	exit = Main([]string{"plugins", "stats", "--workdir", workdir}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("stats exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(out.String(), "Plugin stats for run ") || !regexp.MustCompile(`(?m)^on\s+total\s+1\s`).MatchString(out.String()) || strings.Contains(out.String(), "off") {
+		t.Fatalf("stats stdout=%q", out.String())
+	}
+
+	errBuf.Reset()
+	exit = Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--plugin-dir", pluginDir, "--plugins", "missing"}, &out, &errBuf)
+	if exit != ExitPluginError {
+		t.Fatalf("expected exit %d got %d stderr=%q", ExitPluginError, exit, errBuf.String())
+	}
+}
+
+func TestValidate_PluginRulesRunOnlyWhenAllowlisted(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"fetch","inputs":[],"run":"curl example.com","env":{},"outputs":[]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	pluginDir := filepath.Join(workdir, "plugins", "net")
+	if err := os.MkdirAll(pluginDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, "manifest.json"), []byte(`{"plugin_id":"net","version":"1.0.0","hooks":["Validate"],"description":""}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	script := "#!/bin/sh\nif grep -q curl; then echo '[{\"code\":\"NO_NETWORK\",\"node\":\"fetch\",\"message\":\"network access\"}]'; fi\n"
+	if err := os.WriteFile(filepath.Join(pluginDir, "plugin"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write plugin: %v", err)
+	}
+	plugins := filepath.Join(workdir, "plugins")
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"validate", "--graph", graphPath, "--plugin-dir", plugins}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	exit := Main([]string{"validate", "--graph", graphPath, "--plugin-dir", plugins, "--plugins", "net"}, &out, &errBuf)
+	if exit != ExitValidationError {
+		t.Fatalf("expected exit %d got %d stderr=%q", ExitValidationError, exit, errBuf.String())
+	}
+	if out.String() != "net/NO_NETWORK fetch: network access\n" {
+		t.Fatalf("stdout=%q", out.String())
+	}
+
+	errBuf.Reset()
+	exit = Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--plugin-dir", plugins, "--plugins", "net"}, &out, &errBuf)
+	if exit != ExitValidationError || !strings.Contains(errBuf.String(), "net/NO_NETWORK fetch") {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestValidate_UnknownRunnerFailsValidation(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"deploy","inputs":[],"run":"deploy.sh","runner":"kubernetes","env":{},"outputs":[]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", graphPath, "--plugin-dir", filepath.Join(workdir, "plugins")}, &out, &errBuf)
+	if exit != ExitValidationError || !strings.Contains(errBuf.String(), `requires runner "kubernetes"`) {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestValidate_WarnsAboutNetworkToolsInNetworklessTasks(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"fetch","inputs":[],"run":"wget https://example.com/dep.tgz","network":false}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf)
+	if exit != ExitSuccess || !strings.Contains(errBuf.String(), `Warning: task "fetch" runs wget`) {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestValidate_Strict_FailsOnWarnings(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":["missing.txt"],"run":"true"}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf)
+	if exit != ExitSuccess || !strings.Contains(errBuf.String(), `Warning: input "missing.txt" of task "a" matches no file`) {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	errBuf.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--strict"}, &out, &errBuf)
+	if exit != ExitWarnings || !strings.Contains(errBuf.String(), "1 warnings, and --strict") {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	out.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--strict", "--format", "json"}, &out, &errBuf)
+	if exit != ExitWarnings || !strings.Contains(out.String(), `"valid": false`) || !strings.Contains(out.String(), `"code": "unused_input"`) {
+		t.Fatalf("exit=%d stdout=%s", exit, out.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(workdir, "missing.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	errBuf.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--strict"}, &out, &errBuf)
+	if exit != ExitSuccess || errBuf.Len() != 0 {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestValidate_Strict_FailsOnDeprecatedFields(t *testing.T) {
+	if err := graph.RegisterDeprecation(graph.Deprecation{Field: "tasks[].platform_independent", RemovedIn: "3.0.0", Use: "tasks[].labels"}); err != nil {
+		t.Fatalf("RegisterDeprecation: %v", err)
+	}
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := "{\"tasks\": [\n  {\"name\": \"a\", \"inputs\": [], \"run\": \"true\", \"platform_independent\": true}\n], \"edges\": []}"
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf)
+	want := graphPath + ": tasks[0].platform_independent is deprecated and will be removed in schema_version 3.0.0; use tasks[].labels instead (line 2, column 70)"
+	if exit != ExitSuccess || !strings.Contains(errBuf.String(), want) {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	out.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--strict", "--format", "json"}, &out, &errBuf)
+	if exit != ExitWarnings || !strings.Contains(out.String(), `"code": "deprecated_field"`) {
+		t.Fatalf("exit=%d stdout=%s", exit, out.String())
+	}
+}
+
+func TestValidate_DanglingEdge_PrintsHintInEveryFormat(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"compile","inputs":[],"run":"true"},{"name":"test","inputs":[],"run":"true"}],"edges":[{"from":"compiel","to":"test"}]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", graphPath}, &out, &errBuf)
+	if exit != ExitValidationError || !strings.Contains(errBuf.String(), `Hint: did you mean "compile"?`) {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	out.Reset()
+	errBuf.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--format", "json"}, &out, &errBuf)
+	var report struct {
+		Valid       bool
+		Diagnostics []struct {
+			Severity, Code string
+			Hints          []struct {
+				Code       string
+				Candidates []string
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v\n%s", err, out.String())
+	}
+	if exit != ExitValidationError || report.Valid || len(report.Diagnostics) != 1 {
+		t.Fatalf("exit=%d report=%+v", exit, report)
+	}
+	if d := report.Diagnostics[0]; d.Severity != "error" || len(d.Hints) != 1 || d.Hints[0].Code != "did_you_mean" || d.Hints[0].Candidates[0] != "compile" {
+		t.Fatalf("diagnostic = %+v", d)
+	}
+
+	out.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--format", "sarif"}, &out, &errBuf)
+	var sarif struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID     string
+				Level      string
+				Properties struct {
+					Hints []struct{ Message string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &sarif); err != nil {
+		t.Fatalf("decode sarif: %v\n%s", err, out.String())
+	}
+	if exit != ExitValidationError || sarif.Version != "2.1.0" || len(sarif.Runs) != 1 || len(sarif.Runs[0].Results) != 1 {
+		t.Fatalf("exit=%d sarif=%s", exit, out.String())
+	}
+	if r := sarif.Runs[0].Results[0]; r.RuleID != "invalid_graph" || r.Level != "error" || len(r.Properties.Hints) != 1 {
+		t.Fatalf("result = %+v", r)
+	}
+}
+
+func TestValidate_LocatesUnknownFieldsInEveryFormat(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := "{\n  \"tasks\": [\n    {\"name\": \"a\", \"run\": \"true\"},\n    {\"name\": \"b\", \"bogus\": 1}\n  ]\n}\n"
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", graphPath}, &out, &errBuf)
+	if exit != ExitValidationError || !strings.Contains(errBuf.String(), "(at tasks[1].bogus, line 4, column 19)") {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	out.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--format", "json"}, &out, &errBuf)
+	var report struct {
+		Diagnostics []struct {
+			Path         string
+			Line, Column int
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v\n%s", err, out.String())
+	}
+	if exit != ExitValidationError || len(report.Diagnostics) != 1 {
+		t.Fatalf("exit=%d report=%s", exit, out.String())
+	}
+	if d := report.Diagnostics[0]; d.Path != "tasks[1].bogus" || d.Line != 4 || d.Column != 19 {
+		t.Fatalf("diagnostic = %+v", d)
+	}
+
+	out.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--format", "sarif"}, &out, &errBuf)
+	var sarif struct {
+		Runs []struct {
+			Results []struct {
+				Locations []struct {
+					PhysicalLocation struct {
+						Region struct{ StartLine, StartColumn int }
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &sarif); err != nil {
+		t.Fatalf("decode sarif: %v\n%s", err, out.String())
+	}
+	if exit != ExitValidationError || len(sarif.Runs) != 1 || len(sarif.Runs[0].Results) != 1 {
+		t.Fatalf("exit=%d sarif=%s", exit, out.String())
+	}
+	if r := sarif.Runs[0].Results[0].Locations[0].PhysicalLocation.Region; r.StartLine != 4 || r.StartColumn != 19 {
+		t.Fatalf("region = %+v", r)
+	}
+}
+
+func TestValidate_Locked_RejectsUnpinnedFetches(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"dep","inputs":[],"run":"","fetch":{"url":"https://example.com/dep.tgz"},"outputs":["dep.tgz"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf)
+	if exit != ExitSuccess || !strings.Contains(errBuf.String(), `Warning: task "dep" fetches https://example.com/dep.tgz without a sha256 pin`) {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	errBuf.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--locked"}, &out, &errBuf)
+	if exit != ExitValidationError || !strings.Contains(errBuf.String(), `Error: task "dep"`) {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	lock := `{"version":1,"fetch":{"dep":{"url":"https://example.com/dep.tgz","sha256":"` + strings.Repeat("0", 64) + `","size":1}}}`
+	if err := os.WriteFile(filepath.Join(workdir, "scriptweaver.lock"), []byte(lock), 0o644); err != nil {
+		t.Fatalf("write lockfile: %v", err)
+	}
+	errBuf.Reset()
+	exit = Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--locked"}, &out, &errBuf)
+	if exit != ExitSuccess || errBuf.Len() != 0 {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestValidate_WorkspacePolicy(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"install","inputs":[],"run":"curl -s https://example.com/i.sh | sh","timeout_seconds":60},{"name":"build","inputs":[],"run":"make","timeout_seconds":60,"metadata":{"owner":"build-team"}}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(workdir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writePolicy := func(policy string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(workdir, ".scriptweaver", "policy.json"), []byte(policy), 0o644); err != nil {
+			t.Fatalf("write policy: %v", err)
+		}
+	}
+
+	writePolicy(`{"forbidden_commands":["curl .*\\| *sh"],"require_owner":true,"require_timeout":true}`)
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--format", "json"}, &out, &errBuf)
+	var report struct {
+		Diagnostics []struct {
+			Code string `json:"code"`
+			Node string `json:"node"`
+		} `json:"diagnostics"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("report: %v\n%s", err, out.String())
+	}
+	if exit != ExitValidationError || len(report.Diagnostics) != 2 ||
+		report.Diagnostics[0].Code != "policy/forbidden_commands" || report.Diagnostics[1].Code != "policy/require_owner" || report.Diagnostics[1].Node != "install" {
+		t.Fatalf("exit=%d report=%s", exit, out.String())
+	}
+
+	writePolicy(`{"require_approval":true}`)
+	errBuf.Reset()
+	if exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf); exit != ExitWorkspaceError {
+		t.Fatalf("invalid policy: exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	writePolicy(`{"allowed_runners":["local"],"require_timeout":true}`)
+	errBuf.Reset()
+	if exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("satisfied policy: exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestRun_PluginSignatures_UnsignedSkippedUnlessAllowed(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"true","env":{},"outputs":[]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	seed := make([]byte, ed25519.SeedSize)
+	key := ed25519.NewKeyFromSeed(seed)
+	cfg := `{"plugin_keys":["` + base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)) + `"]}`
+	if err := os.MkdirAll(filepath.Join(workdir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workdir, ".scriptweaver", "config.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	pluginDir := filepath.Join(workdir, ".scriptweaver", "plugins", "hello")
+	if err := os.MkdirAll(pluginDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, "manifest.json"), []byte(`{"plugin_id":"hello","version":"1.0.0","hooks":["BeforeRun"],"description":""}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, "plugin"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatalf("write plugin: %v", err)
+	}
+	runArgs := []string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--plugins", "hello"}
+
+	var out, errBuf bytes.Buffer
+	exit := Main(runArgs, &out, &errBuf)
+	if exit != ExitPluginError || !strings.Contains(errBuf.String(), "plugin hello: plugin is unsigned") {
+		t.Fatalf("unsigned: exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	errBuf.Reset()
+	if exit := Main(append(runArgs, "--allow-unsigned-plugins"), &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("allow unsigned: exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	if err := pluginengine.SignPlugin(pluginDir, key); err != nil {
+		t.Fatalf("SignPlugin: %v", err)
+	}
+	errBuf.Reset()
+	if exit := Main(runArgs, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("signed: exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestPluginsNew_ScaffoldRunsWithAllowlist(t *testing.T) {
+	workdir := t.TempDir()
+	pluginDir := filepath.Join(workdir, "plugins")
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"plugins", "new", "hello", "--plugin-dir", pluginDir, "--hooks", "BeforeRun,AfterNode"}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(out.String(), "Created plugin hello") || !strings.Contains(out.String(), "manifest.json") {
+		t.Fatalf("stdout=%q", out.String())
+	}
+
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"true","env":{},"outputs":[]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	errBuf.Reset()
+	exit = Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--plugin-dir", pluginDir, "--plugins", "hello"}, &out, &errBuf)
+	if exit != ExitSuccess || strings.Contains(errBuf.String(), "pluginengine") {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	exit = Main([]string{"plugins", "new", "hello", "--plugin-dir", pluginDir}, &out, &errBuf)
+	if exit != ExitUsageError {
+		t.Fatalf("expected existing plugin dir to be rejected, exit=%d", exit)
+	}
+}
+
+func TestPluginsList_OutputSortedPlaintext(t *testing.T) {
+	root := repoRoot(t)
+	if err := os.Chdir(root); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+
+	pluginDir := t.TempDir()
+	// Create two plugin dirs with manifests.
+	alpha := filepath.Join(pluginDir, "alpha")
+	beta := filepath.Join(pluginDir, "beta")
+	if err := os.MkdirAll(alpha, 0o755); err != nil {
+		t.Fatalf("mkdir alpha: %v", err)
+	}
+	if err := os.MkdirAll(beta, 0o755); err != nil {
+		t.Fatalf("mkdir beta: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(alpha, "manifest.json"), []byte(`{"plugin_id":"Alpha","version":"0.0.0","hooks":["BeforeRun"],"description":"alpha"}`), 0o644); err != nil {
+		t.Fatalf("write alpha manifest: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(beta, "manifest.json"), []byte(`{"plugin_id":"Beta","version":"0.0.0","hooks":["BeforeRun"],"description":"beta"}`), 0o644); err != nil {
+		t.Fatalf("write beta manifest: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"plugins", "list", "--plugin-dir", pluginDir}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	got := strings.TrimSpace(out.String())
+	if got != "Alpha\nBeta" {
+		t.Fatalf("stdout=%q", out.String())
+	}
+}
+
+func TestPluginsList_JSONAndVerboseIncludeLoadErrors(t *testing.T) {
+	workdir := t.TempDir()
+	pluginDir := filepath.Join(workdir, ".scriptweaver", "plugins")
+	for id, manifest := range map[string]string{
+		"good": `{"plugin_id":"good","version":"1.2.0","hooks":["BeforeRun"],"description":"says hello"}`,
+		"bad":  `{"plugin_id":"bad"}`,
+	} {
+		if err := os.MkdirAll(filepath.Join(pluginDir, id), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(pluginDir, id, "manifest.json"), []byte(manifest), 0o644); err != nil {
+			t.Fatalf("write manifest: %v", err)
+		}
+	}
+
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"plugins", "list", "--workdir", workdir, "--format", "json"}, &out, &errBuf)
+	var plugins []struct {
+		PluginID    string   `json:"plugin_id"`
+		Version     string   `json:"version"`
+		Hooks       []string `json:"hooks"`
+		Description string   `json:"description"`
+		Dir         string   `json:"dir"`
+		Enabled     bool     `json:"enabled"`
+		LoadError   string   `json:"load_error"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &plugins); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out.String())
+	}
+	if exit != ExitPluginError || len(plugins) != 2 {
+		t.Fatalf("exit=%d plugins=%+v", exit, plugins)
+	}
+	if p := plugins[0]; p.Dir != filepath.Join(pluginDir, "bad") || p.Enabled || p.LoadError == "" {
+		t.Fatalf("plugins[0] = %+v", p)
+	}
+	if p := plugins[1]; p.PluginID != "good" || p.Version != "1.2.0" || len(p.Hooks) != 1 || p.Description != "says hello" || !p.Enabled {
+		t.Fatalf("plugins[1] = %+v", p)
+	}
+
+	out.Reset()
+	Main([]string{"plugins", "list", "--workdir", workdir, "--verbose"}, &out, &errBuf)
+	if !regexp.MustCompile(`good\s+1\.2\.0\s+enabled\s+BeforeRun\s+good/\s+says hello`).MatchString(out.String()) ||
+		!regexp.MustCompile(`-\s+-\s+disabled\s+-\s+bad/\s+\S`).MatchString(out.String()) {
+		t.Fatalf("stdout=%q", out.String())
+	}
+
+	out.Reset()
+	errBuf.Reset()
+	Main([]string{"plugins", "list", "--workdir", workdir}, &out, &errBuf)
+	if out.String() != "good\n" || !strings.Contains(errBuf.String(), "disabled") {
+		t.Fatalf("stdout=%q stderr=%q", out.String(), errBuf.String())
+	}
+}
+
+func TestPluginsDoctor_ReportsHealthTable(t *testing.T) {
+	workdir := t.TempDir()
+	pluginDir := filepath.Join(workdir, "plugins")
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"plugins", "new", "hello", "--plugin-dir", pluginDir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("new: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	out.Reset()
+	doctorArgs := []string{"plugins", "doctor", "--workdir", workdir, "--plugin-dir", pluginDir}
+	if exit := Main(doctorArgs, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("healthy: exit=%d stdout=%q stderr=%q", exit, out.String(), errBuf.String())
+	}
+	if !strings.Contains(out.String(), "PLUGIN") || !regexp.MustCompile(`hello\s+0\.1\.0\s+exec\s+ok`).MatchString(out.String()) {
+		t.Fatalf("stdout=%q", out.String())
+	}
+
+	broken := filepath.Join(pluginDir, "broken")
+	if err := os.MkdirAll(broken, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(broken, "manifest.json"), []byte(`{"plugin_id":"broken","version":"1.0.0","hooks":["BeforeRun"],"requires":">=9.0.0"}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	out.Reset()
+	if exit := Main(doctorArgs, &out, &errBuf); exit != ExitPluginError {
+		t.Fatalf("broken: exit=%d stdout=%q", exit, out.String())
+	}
+	if !regexp.MustCompile(`broken\s+1\.0\.0\s+-\s+error\s+.*incompatible`).MatchString(out.String()) {
+		t.Fatalf("stdout=%q", out.String())
+	}
+}
+
+func TestCacheWarm_ReportsPrefetchedNodes(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":[],"run":"echo a > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"cache", "warm", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf); exit != ExitWorkspaceError {
+		t.Fatalf("without shared tiers: exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	shared := t.TempDir()
+	cfg := `{"cache":{"tiers":["` + shared + `"],"write":"write-all"}}`
+	if err := os.WriteFile(filepath.Join(workdir, ".scriptweaver", "config.json"), []byte(cfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--cache-dir", "ci-cache", "--no-daemon"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	out.Reset()
+	if exit := Main([]string{"cache", "warm", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("warm: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	want := "Warmed cache " + filepath.Join(workdir, ".scriptweaver", "cache") + ": 1 fetched, 0 already local, 0 missing, 0 skipped\n"
+	if !strings.HasPrefix(out.String(), "Fetched a ") || !strings.HasSuffix(out.String(), want) {
+		t.Fatalf("stdout=%q", out.String())
+	}
+}
+
+func TestGraphStats_PrintsShapeInTextAndJSON(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[
+		{"name":"a","inputs":[],"run":"true","outputs":[]},
+		{"name":"b","inputs":[],"run":"true","outputs":[]},
+		{"name":"c","inputs":[],"run":"true","outputs":[]}
+	],"edges":[{"from":"a","to":"b"}]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"graph", "stats", "--graph", graphPath}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, want := range []string{"nodes        3\n", "depth        2\n", "components   2\n", "parallelism  1.50\n", "LEVEL  WIDTH\n0      2\n1      1\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("stdout missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if exit := Main([]string{"graph", "stats", "--graph", graphPath, "--format", "json"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	var st struct {
+		Edges  int   `json:"edges"`
+		Widths []int `json:"widths"`
+		Roots  int   `json:"roots"`
+		Leaves int   `json:"leaves"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &st); err != nil {
+		t.Fatalf("decode: %v\n%s", err, out.String())
+	}
+	if st.Edges != 1 || len(st.Widths) != 2 || st.Roots != 2 || st.Leaves != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestGraphQuery_PrintsMatchingNodes(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[
+		{"name":"a","inputs":[],"run":"true","outputs":[],"labels":["team:infra"]},
+		{"name":"b","inputs":[],"run":"true","outputs":[]},
+		{"name":"c","inputs":[],"run":"true","outputs":[]}
+	],"edges":[{"from":"a","to":"b"},{"from":"b","to":"c"}]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"graph", "query", "rdeps(label=team:infra) & !leaf", "--graph", graphPath}, &out, &errBuf); exit != ExitSuccess || out.String() != "b\n" {
+		t.Fatalf("exit=%d stdout=%q stderr=%q", exit, out.String(), errBuf.String())
+	}
+	for _, expr := range []string{"deps(z)", "deps(a"} {
+		errBuf.Reset()
+		if exit := Main([]string{"graph", "query", expr, "--graph", graphPath}, &out, &errBuf); exit != ExitUsageError || errBuf.Len() == 0 {
+			t.Fatalf("%q: exit=%d stderr=%q", expr, exit, errBuf.String())
+		}
+	}
+}
+
+func TestHash_Explain_PrintsComponents(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"a","inputs":["in.txt"],"run":"cat in.txt","env":{"K":"v"},"outputs":[]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workdir, "in.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"hash", "--graph", graphPath, "--workdir", workdir, "--explain", "a"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, want := range []string{
+		"workdir " + strconv.Quote(workdir) + "\n",
+		"command \"cat in.txt\"\n",
+		"env.key \"K\"\nenv.value \"v\"\n",
+		"input.content sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824 (5 bytes)\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("stdout missing %q:\n%s", want, out.String())
+		}
+	}
+	if !strings.HasPrefix(out.String(), "hash sha256:") {
+		t.Fatalf("stdout=%q", out.String())
+	}
+
+	if exit := Main([]string{"hash", "--graph", graphPath, "--workdir", workdir, "--explain", "nope"}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("unknown node: exit=%d", exit)
+	}
+}
+
+func TestMessages_TranslationFallsBackToEnglish(t *testing.T) {
+	catalogs["xx"] = map[MessageID]string{MsgUnknownCommand: "xx-unknown: %s"}
+	defer delete(catalogs, "xx")
+	t.Setenv("SW_LANG", "xx_YY.UTF-8")
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"frobnicate"}, &out, &errBuf); exit != ExitUsageError || errBuf.String() != "xx-unknown: frobnicate\n" {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	errBuf.Reset()
+	if exit := Main([]string{"run"}, &out, &errBuf); exit != ExitUsageError || errBuf.String() != "--graph is required\n" {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestMessages_ValidateJSONCarriesMessageIDs(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"tasks":[{"name":"fetch","inputs":[],"run":"wget https://example.com/dep.tgz","network":false}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	var out, errBuf bytes.Buffer
+	exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--format", "json"}, &out, &errBuf)
+	var report struct {
+		Valid       bool
+		Diagnostics []struct{ ID, Severity string }
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v\n%s", err, out.String())
+	}
+	if exit != ExitSuccess || !report.Valid || len(report.Diagnostics) != 1 || report.Diagnostics[0].ID != string(MsgNetworkTool) {
+		t.Fatalf("exit=%d report=%+v", exit, report)
+	}
+	if errBuf.Len() != 0 {
+		t.Fatalf("json format printed text diagnostics: %q", errBuf.String())
+	}
+}
+
+func TestRunAndValidate_SetParams(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "g.json")
+	graphJSON := `{"params":{"name":{}},"tasks":[{"name":"greet","inputs":[],"run":"echo {{name}} > greeting.txt","outputs":["greeting.txt"]}],"edges":[]}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir}, &out, &errBuf); exit != ExitValidationError || !strings.Contains(errBuf.String(), "missing value for parameter name") {
+		t.Fatalf("unset parameter: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	errBuf.Reset()
+	if exit := Main([]string{"validate", "--graph", graphPath, "--workdir", workdir, "--set", "name=world", "--set", "nme=x"}, &out, &errBuf); exit != ExitValidationError || !strings.Contains(errBuf.String(), "undeclared parameter nme") {
+		t.Fatalf("undeclared parameter: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	errBuf.Reset()
+	if exit := Main([]string{"validate", "--graph", graphPath, "--set", "name"}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("malformed --set: exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	errBuf.Reset()
+	exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--mode", "clean", "--no-daemon", "--set", "name=world"}, &out, &errBuf)
+	if exit != ExitSuccess {
+		t.Fatalf("run: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	got, err := os.ReadFile(filepath.Join(workdir, "greeting.txt"))
+	if err != nil || string(got) != "world\n" {
+		t.Fatalf("greeting = %q, %v", got, err)
+	}
+}
+
+func TestRunsList_ShowsRunsAndSchedules(t *testing.T) {
+	workdir := t.TempDir()
+	graphPath := filepath.Join(workdir, "graph.json")
+	if err := os.WriteFile(graphPath, []byte(`{"tasks":[{"name":"a","inputs":[],"run":"true"}],"edges":[]}`), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(workdir, ".scriptweaver"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workdir, ".scriptweaver", "config.json"), []byte(`{"schedules":[{"name":"nightly","cron":"@daily","graph":"graph.json"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"runs", "list", "--workdir", workdir}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	for _, want := range []string{"No runs recorded", "nightly", "@daily", "no daemon is serving"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("stdout missing %q:\n%s", want, out.String())
+		}
+	}
+
+	if exit := Main([]string{"run", "--graph", graphPath, "--workdir", workdir, "--no-daemon"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("run exit=%d stderr=%q", exit, errBuf.String())
+	}
+	out.Reset()
+	if exit := Main([]string{"runs", "list", "--workdir", workdir, "--json"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	var list struct {
+		Runs []struct {
+			Status string `json:"status"`
+			Graph  string `json:"graph"`
+		} `json:"runs"`
+		Schedules []struct {
+			Name string `json:"name"`
+		} `json:"schedules"`
+		Daemon bool `json:"daemon"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &list); err != nil {
+		t.Fatalf("json: %v\n%s", err, out.String())
+	}
+	if len(list.Runs) != 1 || list.Runs[0].Status != "succeeded" || list.Runs[0].Graph != "graph.json" || len(list.Schedules) != 1 || list.Daemon {
+		t.Fatalf("list = %+v", list)
+	}
+	if exit := Main([]string{"runs", "list", "--workdir", workdir, "--limit", "0"}, &out, &errBuf); exit != ExitUsageError {
+		t.Fatalf("--limit 0: exit=%d", exit)
+	}
+}
+
+func TestSchema_PrintsAndWritesGraphSchema(t *testing.T) {
+	var out, errBuf bytes.Buffer
+	if exit := Main([]string{"schema"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	if schema["$schema"] != graph.JSONSchemaDialect {
+		t.Fatalf("$schema = %v", schema["$schema"])
+	}
+	if schema["title"] != "ScriptWeaver graph file" {
+		t.Fatalf("title = %v, want the schema of the graph files sw run reads", schema["title"])
+	}
+
+	path := filepath.Join(t.TempDir(), "graph.schema.json")
+	if exit := Main([]string{"schema", "--out", path}, &bytes.Buffer{}, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	written, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(written, out.Bytes()) {
+		t.Fatalf("written schema differs from printed one (err=%v)", err)
+	}
+
+	out.Reset()
+	if exit := Main([]string{"schema", "--document"}, &out, &errBuf); exit != ExitSuccess {
+		t.Fatalf("exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil || schema["title"] != "ScriptWeaver graph document" {
+		t.Fatalf("--document: title = %v (err=%v)", schema["title"], err)
+	}
+}
+
+func TestFmt_RewritesRunGraphFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "graph.json")
+	src := `{"edges": [{"From": "build", "To": "test"}], "tasks": [{"run": "go test ./...", "name": "test", "inputs": ["bin/app", "a_test.go"]}, {"name": "build", "inputs": [], "run": "go build -o bin/app > build.log", "outputs": ["bin/app", "build.log"]}]}`
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var hashBefore bytes.Buffer
+	if exit := Main([]string{"hash", "--graph", path}, &hashBefore, &bytes.Buffer{}); exit != ExitSuccess {
+		t.Fatalf("hash: exit=%d", exit)
+	}
+
+	var errBuf bytes.Buffer
+	if exit := Main([]string{"fmt", "--graph", path}, &bytes.Buffer{}, &errBuf); exit != ExitSuccess {
+		t.Fatalf("fmt: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "tasks": [
+    {
+      "name": "build",
+      "inputs": [],
+      "run": "go build -o bin/app > build.log",
+      "outputs": [
+        "bin/app",
+        "build.log"
+      ]
+    },
+    {
+      "name": "test",
+      "inputs": [
+        "a_test.go",
+        "bin/app"
+      ],
+      "run": "go test ./..."
+    }
+  ],
+  "edges": [
+    {
+      "From": "build",
+      "To": "test"
+    }
+  ]
+}
+`
+	if string(data) != want {
+		t.Fatalf("formatted:\n%s\nwant:\n%s", data, want)
+	}
+	var hashAfter bytes.Buffer
+	if exit := Main([]string{"hash", "--graph", path}, &hashAfter, &bytes.Buffer{}); exit != ExitSuccess || hashAfter.String() != hashBefore.String() {
+		t.Fatalf("hash changed: %q -> %q (exit=%d)", hashBefore.String(), hashAfter.String(), exit)
+	}
+	if exit := Main([]string{"fmt", "--graph", path, "--check"}, &bytes.Buffer{}, &errBuf); exit != ExitSuccess {
+		t.Fatalf("check of formatted file: exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	if err := os.WriteFile(path, []byte(`{"tasks": [{"name": "a", "command": "true"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	errBuf.Reset()
+	if exit := Main([]string{"fmt", "--graph", path}, &bytes.Buffer{}, &errBuf); exit != ExitValidationError || !strings.Contains(errBuf.String(), `unknown field "command"`) {
+		t.Fatalf("invalid graph file: exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	linesPath := filepath.Join(dir, "graph.jsonl")
+	if err := os.WriteFile(linesPath, []byte(`{"schema_version": "2.0.0"}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	errBuf.Reset()
+	if exit := Main([]string{"fmt", "--graph", linesPath}, &bytes.Buffer{}, &errBuf); exit != ExitUsageError || !strings.Contains(errBuf.String(), "sw fmt does not format") {
+		t.Fatalf("JSON Lines document: exit=%d stderr=%q", exit, errBuf.String())
+	}
+}
+
+func TestFmt_RewritesAndChecksGraphDocument(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.json")
+	src := `{"metadata": {}, "graph": {"edges": [], "nodes": [{"id": "b", "type": "exec", "inputs": {"cmd": "true"}, "outputs": []}, {"id": "a", "type": "exec", "inputs": {"cmd": "true"}, "outputs": []}]}, "schema_version": "2.0.0"}`
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var errBuf bytes.Buffer
+	if exit := Main([]string{"fmt", "--graph", path, "--check"}, &bytes.Buffer{}, &errBuf); exit != ExitValidationError {
+		t.Fatalf("check of unformatted file: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	if !strings.Contains(errBuf.String(), "is not formatted") {
+		t.Fatalf("stderr = %q", errBuf.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != src {
+		t.Fatal("--check rewrote the file")
+	}
+
+	errBuf.Reset()
+	if exit := Main([]string{"fmt", "--graph", path}, &bytes.Buffer{}, &errBuf); exit != ExitSuccess {
+		t.Fatalf("fmt: exit=%d stderr=%q", exit, errBuf.String())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := graph.Format([]byte(src))
+	if err != nil || !bytes.Equal(data, want) {
+		t.Fatalf("file not rewritten canonically (err=%v):\n%s", err, data)
+	}
+	if exit := Main([]string{"fmt", "--graph", path, "--check"}, &bytes.Buffer{}, &errBuf); exit != ExitSuccess {
+		t.Fatalf("check of formatted file: exit=%d stderr=%q", exit, errBuf.String())
+	}
+
+	if err := os.WriteFile(path, []byte(`{"schema_version": "2.0.0"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if exit := Main([]string{"fmt", "--graph", path}, &bytes.Buffer{}, &bytes.Buffer{}); exit != ExitValidationError {
+		t.Fatalf("invalid document: exit=%d", exit)
+	}
+}
+
+func TestPluginLog_SaysEngineWarningsWithTheirID(t *testing.T) {
+	var buf bytes.Buffer
+	pluginLog{jsonMessages{&buf}}.Printf("pluginengine: skipping %v", "plugin x is unsigned")
+	var line struct {
+		ID      MessageID `json:"id"`
+		Message string    `json:"message"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("stderr: %v\n%s", err, buf.String())
+	}
+	if line.ID != MsgPluginWarning || line.Message != "pluginengine: skipping plugin x is unsigned" {
+		t.Fatalf("line = %+v", line)
+	}
+}
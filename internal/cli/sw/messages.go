@@ -0,0 +1,419 @@
+package sw
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// MessageID identifies a user-facing message of sw. IDs are stable across
+// releases and languages, so wrappers can match on them instead of on the
+// wording, and machine-readable output carries them next to the text.
+type MessageID string
+
+// Errors that stop a command, one per exit code. Their message is the error
+// itself, as the engine reports it.
+const (
+	MsgUsageError     MessageID = "usage.error"
+	MsgExecutionError MessageID = "run.error"
+	MsgPluginsFailed  MessageID = "plugins.failed"
+	MsgWorkspaceError MessageID = "workspace.error"
+	MsgInterrupted    MessageID = "run.interrupted"
+	MsgInternalError  MessageID = "internal.error"
+	MsgOfflineError   MessageID = "run.offline"
+)
+
+// Usage errors.
+const (
+	MsgMissingCommand    MessageID = "usage.missing_command"
+	MsgUnknownCommand    MessageID = "usage.unknown_command"
+	MsgMissingSubcommand MessageID = "usage.missing_subcommand"
+	MsgUnknownSubcommand MessageID = "usage.unknown_subcommand"
+	MsgUnknownFlag       MessageID = "usage.unknown_flag"
+	MsgUnexpectedArgs    MessageID = "usage.unexpected_arguments"
+	MsgFlagRequired      MessageID = "usage.flag_required"
+	MsgFlagRequires      MessageID = "usage.flag_requires"
+	MsgFlagConflict      MessageID = "usage.flag_conflict"
+	MsgFlagNegative      MessageID = "usage.flag_negative"
+	MsgFlagTooSmall      MessageID = "usage.flag_too_small"
+	MsgInvalidMode       MessageID = "usage.invalid_mode"
+	MsgInvalidFormat     MessageID = "usage.invalid_format"
+	MsgInvalidSkipPolicy MessageID = "usage.invalid_skip_policy"
+	MsgResumeWithClean   MessageID = "usage.resume_with_clean"
+	MsgMissingArgument   MessageID = "usage.missing_argument"
+	MsgExpectedTwoRunIDs MessageID = "usage.expected_two_run_ids"
+	MsgNothingToClean    MessageID = "usage.nothing_to_clean"
+)
+
+// Graph validation.
+const (
+	MsgCycleDetected       MessageID = "graph.cycle_detected"
+	MsgInvalidGraph        MessageID = "graph.invalid"
+	MsgHint                MessageID = "graph.hint"
+	MsgNetworkTool         MessageID = "graph.network_tool"
+	MsgUnpinnedFetch       MessageID = "graph.unpinned_fetch"
+	MsgUnpinnedFetchLocked MessageID = "graph.unpinned_fetch_locked"
+	MsgGraphWarning        MessageID = "graph.warning"
+	MsgStrictWarnings      MessageID = "graph.strict_warnings"
+	MsgPluginFinding       MessageID = "graph.plugin_finding"
+	MsgUnknownRunner       MessageID = "graph.unknown_runner"
+	MsgPolicyViolation     MessageID = "graph.policy_violation"
+	MsgNotFormatted        MessageID = "graph.not_formatted"
+	MsgFmtJSONLines        MessageID = "graph.fmt_json_lines"
+)
+
+// Runs.
+const (
+	MsgServedByDaemon       MessageID = "run.served_by_daemon"
+	MsgPhase                MessageID = "run.phase"
+	MsgCancelled            MessageID = "run.cancelled"
+	MsgUsingCache           MessageID = "run.using_cache"
+	MsgSucceeded            MessageID = "run.succeeded"
+	MsgPublished            MessageID = "run.published"
+	MsgNondeterministicTask MessageID = "run.nondeterministic_task"
+	MsgDeterminismFailed    MessageID = "run.determinism_check_failed"
+	MsgFailed               MessageID = "run.failed"
+	MsgFailure              MessageID = "run.failure"
+	MsgFailedNode           MessageID = "run.failed_node"
+	MsgFailedNodeSkipped    MessageID = "run.failed_node_skipped_upstream"
+	MsgNodeOwner            MessageID = "run.node_owner"
+	MsgNodeDescription      MessageID = "run.node_description"
+	MsgNodeDocs             MessageID = "run.node_docs"
+	MsgSkippedNode          MessageID = "run.skipped_node"
+	MsgSkippedOnRequest     MessageID = "run.skipped_on_request"
+	MsgSkippedWithUpstream  MessageID = "run.skipped_with_upstream"
+	MsgCommandNode          MessageID = "run.command_node"
+	MsgAuditAgreed          MessageID = "run.audit_agreed"
+	MsgAuditDiverged        MessageID = "run.audit_diverged"
+	MsgAuditDivergence      MessageID = "run.audit_divergence"
+	MsgCommandNoNetwork     MessageID = "run.command_no_network"
+	MsgCommandFetch         MessageID = "run.command_fetch"
+	MsgCommandRunner        MessageID = "run.command_runner"
+	MsgStaleOutput          MessageID = "run.stale_output"
+	MsgRemoveStaleOutputs   MessageID = "run.remove_stale_outputs"
+	MsgRetried              MessageID = "run.retried"
+	MsgChaos                MessageID = "run.chaos"
+	MsgChaosFault           MessageID = "run.chaos_fault"
+	MsgResumed              MessageID = "run.resumed"
+	MsgResumedChangedGraph  MessageID = "run.resumed_changed_graph"
+	MsgReused               MessageID = "run.reused"
+	MsgNotReused            MessageID = "run.not_reused"
+	MsgCacheStats           MessageID = "run.cache_stats"
+	MsgUploadsSkipped       MessageID = "run.uploads_skipped"
+	MsgCacheTransfer        MessageID = "run.cache_transfer"
+	MsgOutput               MessageID = "run.output"
+	MsgDeduplicated         MessageID = "run.deduplicated"
+	MsgEstimate             MessageID = "run.estimate"
+	MsgPurged               MessageID = "run.purged"
+	MsgNotifyFailed         MessageID = "run.notify_failed"
+	MsgPurgedRun            MessageID = "run.purged_run"
+	MsgEstimateUnknown      MessageID = "run.estimate_unknown"
+	MsgEstimateNode         MessageID = "run.estimate_node"
+	MsgEstimateNodeDuration MessageID = "run.estimate_node_duration"
+)
+
+// Other commands.
+const (
+	MsgWarmFetched          MessageID = "cache.warm_fetched"
+	MsgWarmMissing          MessageID = "cache.warm_missing"
+	MsgWarmSkipped          MessageID = "cache.warm_skipped"
+	MsgWarmed               MessageID = "cache.warmed"
+	MsgRekeyed              MessageID = "cache.rekeyed"
+	MsgPluginError          MessageID = "plugins.error"
+	MsgPluginWarning        MessageID = "plugins.warning"
+	MsgPluginDisabled       MessageID = "plugins.disabled"
+	MsgPluginCreated        MessageID = "plugins.created"
+	MsgPluginBuild          MessageID = "plugins.build"
+	MsgNoPlugins            MessageID = "plugins.none"
+	MsgNoPluginStats        MessageID = "plugins.no_stats"
+	MsgRunHasNoPluginStats  MessageID = "plugins.run_has_no_stats"
+	MsgPluginStats          MessageID = "plugins.stats"
+	MsgAuditOK              MessageID = "audit.ok"
+	MsgRunNotFound          MessageID = "runs.not_found"
+	MsgRunHasNoCacheDecs    MessageID = "runs.no_cache_decisions"
+	MsgRunHasNoAttempts     MessageID = "runs.no_attempts"
+	MsgFlakyNodes           MessageID = "runs.flaky_nodes"
+	MsgNoRuns               MessageID = "runs.none"
+	MsgSchedulesNotRun      MessageID = "runs.schedules_not_run"
+	MsgSameGraph            MessageID = "runs.same_graph"
+	MsgDifferentGraphs      MessageID = "runs.different_graphs"
+	MsgNoEnvDifferences     MessageID = "runs.no_env_differences"
+	MsgEnvDifferences       MessageID = "runs.env_differences"
+	MsgNoParamDifferences   MessageID = "runs.no_param_differences"
+	MsgParamDifferences     MessageID = "runs.param_differences"
+	MsgParamsNotCompared    MessageID = "runs.params_not_compared"
+	MsgRunRecord            MessageID = "runs.record"
+	MsgRunFailed            MessageID = "runs.failed"
+	MsgRunRetryOf           MessageID = "runs.retry_of"
+	MsgRunParams            MessageID = "runs.params"
+	MsgRunHasNoParams       MessageID = "runs.no_params"
+	MsgTimeline             MessageID = "runs.timeline"
+	MsgTimelineFromCheckpts MessageID = "runs.timeline_from_checkpoints"
+	MsgThrottledBy          MessageID = "runs.throttled_by"
+	MsgReplayed             MessageID = "trace.replayed"
+	MsgTraceFinalized       MessageID = "trace.finalized"
+	MsgExported             MessageID = "workspace.exported"
+	MsgExportRedacted       MessageID = "workspace.export_redacted"
+	MsgImported             MessageID = "workspace.imported"
+	MsgImportConflict       MessageID = "workspace.import_conflict"
+	MsgIterationError       MessageID = "bench.iteration_error"
+	MsgIterationFailed      MessageID = "bench.iteration_failed"
+	MsgBenchmark            MessageID = "bench.summary"
+	MsgBaselineOtherGraph   MessageID = "bench.baseline_other_graph"
+	MsgComparedWithBaseline MessageID = "bench.compared_with_baseline"
+	MsgDaemonListening      MessageID = "daemon.listening"
+	MsgNoDaemon             MessageID = "daemon.none"
+	MsgQueueEmpty           MessageID = "daemon.queue_empty"
+	MsgScheduledRun         MessageID = "daemon.scheduled_run"
+	MsgScheduleFailed       MessageID = "daemon.schedule_failed"
+	MsgSchedulesNotLoaded   MessageID = "daemon.schedules_not_loaded"
+	MsgServing              MessageID = "serve.listening"
+	MsgRemoved              MessageID = "clean.removed"
+	MsgWouldRemove          MessageID = "clean.would_remove"
+	MsgRemovedTotal         MessageID = "clean.removed_total"
+	MsgWouldRemoveTotal     MessageID = "clean.would_remove_total"
+)
+
+// english is the complete catalog, and the fallback for messages missing
+// from a translation. Formats take fmt verbs; a translation must take the
+// same arguments in the same order.
+var english = map[MessageID]string{
+	MsgUsageError:     "%v",
+	MsgExecutionError: "%v",
+	MsgPluginsFailed:  "%v",
+	MsgWorkspaceError: "%v",
+	MsgInterrupted:    "%v",
+	MsgInternalError:  "%v",
+	MsgOfflineError:   "%v",
+
+	MsgMissingCommand:    "missing command (expected: %s)",
+	MsgUnknownCommand:    "unknown command: %s",
+	MsgMissingSubcommand: "missing %s subcommand (expected: %s)",
+	MsgUnknownSubcommand: "unknown %s subcommand: %s",
+	MsgUnknownFlag:       "unknown flag",
+	MsgUnexpectedArgs:    "unexpected positional arguments: %q",
+	MsgFlagRequired:      "%s is required",
+	MsgFlagRequires:      "%s requires %s",
+	MsgFlagConflict:      "%s is not compatible with %s",
+	MsgFlagNegative:      "%s must not be negative",
+	MsgFlagTooSmall:      "%s must be at least %d",
+	MsgInvalidMode:       "invalid --mode %q (expected clean|incremental)",
+	MsgInvalidFormat:     "invalid --format %q (expected %s)",
+	MsgInvalidSkipPolicy: "invalid --skip-policy %q (expected skip|fail)",
+	MsgResumeWithClean:   "--resume is not compatible with --mode clean",
+	MsgMissingArgument:   "missing %s",
+	MsgExpectedTwoRunIDs: "expected two run ids",
+	MsgNothingToClean:    "nothing to clean (expected: --stale-outputs)",
+
+	MsgCycleDetected:       "Cycle detected",
+	MsgInvalidGraph:        "%s",
+	MsgHint:                "Hint: %s",
+	MsgNetworkTool:         "Warning: %s",
+	MsgUnpinnedFetch:       "Warning: %s",
+	MsgUnpinnedFetchLocked: "Error: %s",
+	MsgGraphWarning:        "Warning: %s",
+	MsgStrictWarnings:      "Error: %d warnings, and --strict makes warnings errors",
+	MsgPluginFinding:       "%s",
+	MsgUnknownRunner:       "%s",
+	MsgPolicyViolation:     "Error: %s",
+	MsgNotFormatted:        "%s is not formatted; run sw fmt to rewrite it",
+	MsgFmtJSONLines:        "%s is a JSON Lines graph file, which sw fmt does not format",
+
+	MsgServedByDaemon:       "served by daemon at %s",
+	MsgPhase:                "phase %-8s %s",
+	MsgCancelled:            "Execution cancelled",
+	MsgUsingCache:           "Using cache %s",
+	MsgSucceeded:            "Execution succeeded",
+	MsgPublished:            "Published %d files to %s",
+	MsgNondeterministicTask: "Nondeterministic task %s: %s",
+	MsgDeterminismFailed:    "Determinism check failed",
+	MsgFailed:               "Execution failed",
+	MsgFailure:              "Failure: %s [%s]",
+	MsgFailedNode:           "Failed node %s: exit code %d (%s) after %s; details in %s",
+	MsgFailedNodeSkipped:    "Failed node %s: upstream %s was skipped on request (%s); details in %s",
+	MsgNodeOwner:            "  Owner: %s",
+	MsgNodeDescription:      "  Description: %s",
+	MsgNodeDocs:             "  Docs: %s",
+	MsgSkippedNode:          "Skipped node %s: upstream %s failed",
+	MsgSkippedOnRequest:     "Skipped %s (requested)",
+	MsgSkippedWithUpstream:  "Skipped %s: upstream %s was skipped",
+	MsgCommandNode:          "# %s",
+	MsgAuditAgreed:          "Determinism audit passed: serial run %s and parallel run %s (%d workers) agree on all %d nodes",
+	MsgAuditDiverged:        "Determinism audit failed: %d divergences between serial run %s and parallel run %s (%d workers)",
+	MsgAuditDivergence:      "  %s %s: %s (serial) vs %s (parallel)",
+	MsgCommandNoNetwork:     "# %s (runs without network access)",
+	MsgCommandFetch:         "# %s fetches %s; the engine downloads it, there is no command",
+	MsgCommandRunner:        "# %s runs on plugin runner %s; there is no shell command",
+	MsgStaleOutput:          "Warning: stale output %s (last produced by %s) is no longer owned by any node",
+	MsgRemoveStaleOutputs:   "Remove stale outputs with: sw clean --stale-outputs",
+	MsgRetried:              "Run %s failed transiently (%s: %s); retried after %s",
+	MsgChaos:                "Chaos (seed %d): injected %d faults",
+	MsgChaosFault:           "  %s %s",
+	MsgResumed:              "Resumed run %s: reused %d of %d nodes",
+	MsgResumedChangedGraph:  "Resumed run %s (graph changed): reused %d of %d nodes",
+	MsgReused:               "Reused %s",
+	MsgNotReused:            "Not reused %s: %s",
+	MsgCacheStats:           "Cache: %d hits, %d misses (%.0f%% hit rate), %d bytes restored",
+	MsgUploadsSkipped:       "Cache: skipped %d uploads already in shared tiers, saving %d bytes",
+	MsgCacheTransfer:        "Cache transfers: %d requests, %d bytes uploaded, %d bytes downloaded, throttled %s",
+	MsgOutput:               "Output %s %s %d sha256:%s",
+	MsgDeduplicated:         "Deduplicated %s (shared result of %s)",
+	MsgPurged:               "Purged %d old runs (%d bytes) per the retention settings; kept %d runs (%d bytes)",
+	MsgPurgedRun:            "  %s (%d bytes)",
+	MsgNotifyFailed:         "Warning: notification failed: %s",
+	MsgEstimate:             "Estimate: %d nodes to execute, %d to reuse, %d skipped, about %s",
+	MsgEstimateUnknown:      "  %d nodes to execute have no recorded duration and are not counted",
+	MsgEstimateNode:         "%-7s %s",
+	MsgEstimateNodeDuration: "%-7s %s (%s)",
+
+	MsgWarmFetched:          "Fetched %s %s",
+	MsgWarmMissing:          "Missing %s %s",
+	MsgWarmSkipped:          "Skipped %s: depends on a node without a cached result",
+	MsgWarmed:               "Warmed cache %s: %d fetched, %d already local, %d missing, %d skipped",
+	MsgRekeyed:              "Re-encrypted %d cache entries with the current key",
+	MsgPluginError:          "plugin error",
+	MsgPluginWarning:        "%s",
+	MsgPluginDisabled:       "plugin %s in %s is disabled: %s",
+	MsgPluginCreated:        "Created plugin %s in %s",
+	MsgPluginBuild:          "Build the plugin executable with: go build -o plugin .",
+	MsgNoPlugins:            "No plugins in %s",
+	MsgNoPluginStats:        "no run with plugin stats found",
+	MsgRunHasNoPluginStats:  "run %s has no plugin stats",
+	MsgPluginStats:          "Plugin stats for run %s",
+	MsgAuditOK:              "Audit log OK (%d entries)",
+	MsgRunNotFound:          "run %s not found",
+	MsgRunHasNoCacheDecs:    "run %s recorded no cache decisions; clean runs do not use the cache",
+	MsgRunHasNoAttempts:     "run %s recorded no attempts",
+	MsgFlakyNodes:           "Flaky: %s failed an attempt and passed a later one",
+	MsgNoRuns:               "No runs recorded",
+	MsgSchedulesNotRun:      "Note: no daemon is serving %s, so these schedules are not run",
+	MsgSameGraph:            "Runs %s and %s executed the same graph %s",
+	MsgDifferentGraphs:      "Runs %s and %s executed different graphs (%s, %s)",
+	MsgNoEnvDifferences:     "No environment differences",
+	MsgEnvDifferences:       "%d environment differences:",
+	MsgNoParamDifferences:   "No parameter differences",
+	MsgParamDifferences:     "%d parameter differences:",
+	MsgParamsNotCompared:    "Parameters not compared: a run recorded none",
+	MsgRunRecord:            "Run %s: %s mode, graph %s, started %s",
+	MsgRunFailed:            "Failed: %s: %s",
+	MsgRunRetryOf:           "Retry %d, after run %s",
+	MsgRunParams:            "Parameters:",
+	MsgRunHasNoParams:       "Run recorded no parameters",
+	MsgTimeline:             "Timeline for run %s (%d nodes, %d lanes, %s)",
+	MsgTimelineFromCheckpts: "Reconstructed from checkpoints: start times assume nodes started when their dependencies finished",
+	MsgThrottledBy:          "  throttled by label %s",
+	MsgReplayed:             "Replayed trace of graph %s (%d nodes, %d events, run exit code %d)",
+	MsgTraceFinalized:       "Finalized trace of run %s: %d events for %d nodes written to %s",
+	MsgExported:             "Exported run %s (%d files) to %s",
+	MsgExportRedacted:       "Left out of the config: %s",
+	MsgImported:             "Imported run %s (%d files, graph %s) into %s",
+	MsgImportConflict:       "Not imported: %v; import into an empty directory",
+	MsgIterationError:       "iteration %d: %v",
+	MsgIterationFailed:      "iteration %d: Execution failed",
+	MsgBenchmark:            "Benchmark: %d iterations (%s)",
+	MsgBaselineOtherGraph:   "Note: baseline was recorded for a different graph",
+	MsgComparedWithBaseline: "Compared with baseline (mean):",
+	MsgDaemonListening:      "Daemon listening on %s",
+	MsgNoDaemon:             "no daemon is serving %s",
+	MsgQueueEmpty:           "No run in progress or queued",
+	MsgScheduledRun:         "Scheduled run %s of %s exited %d",
+	MsgScheduleFailed:       "Schedule %s not run: %v",
+	MsgSchedulesNotLoaded:   "Schedules not loaded: %v",
+	MsgServing:              "Dashboard at http://%s",
+	MsgRemoved:              "Removed %s (last produced by %s)",
+	MsgWouldRemove:          "Would remove %s (last produced by %s)",
+	MsgRemovedTotal:         "Removed %d stale outputs",
+	MsgWouldRemoveTotal:     "Would remove %d stale outputs",
+}
+
+// catalogs holds the catalog of every supported language, keyed by its
+// language tag. A translation is added by registering its messages here.
+var catalogs = map[string]map[MessageID]string{
+	"en": english,
+}
+
+// language returns the language messages are rendered in: the language of
+// SW_LANG ("de_DE.UTF-8" selects "de") when a catalog exists for it, else
+// English.
+func language() string {
+	tag := strings.ToLower(os.Getenv("SW_LANG"))
+	if i := strings.IndexAny(tag, "_-."); i >= 0 {
+		tag = tag[:i]
+	}
+	if _, ok := catalogs[tag]; ok {
+		return tag
+	}
+	return "en"
+}
+
+// message renders id with args in the selected language.
+func message(id MessageID, args ...any) string {
+	format, ok := catalogs[language()][id]
+	if !ok {
+		format = english[id]
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// say writes the message id with args to w as a line; to jsonMessages, as a
+// JSON object.
+func say(w io.Writer, id MessageID, args ...any) {
+	if j, ok := w.(jsonMessages); ok {
+		data, err := json.Marshal(struct {
+			ID      MessageID `json:"id"`
+			Message string    `json:"message"`
+		}{id, message(id, args...)})
+		if err == nil {
+			fmt.Fprintf(j.Writer, "%s\n", data)
+			return
+		}
+	}
+	fmt.Fprintln(w, message(id, args...))
+}
+
+// jsonMessages is the stderr of commands printing JSON: say writes each
+// message to it as a JSON object with its ID and text, one per line, so that
+// wrappers can read errors as they read the output. Anything else written to
+// it is passed through.
+type jsonMessages struct {
+	io.Writer
+}
+
+// pluginLog is the plugin engine's logger of a command: it says every
+// warning the engine logs, such as a skipped plugin or a failing hook, as
+// MsgPluginWarning.
+type pluginLog struct {
+	w io.Writer
+}
+
+func (l pluginLog) Printf(format string, args ...any) {
+	say(l.w, MsgPluginWarning, fmt.Sprintf(format, args...))
+}
+
+// errorIDs is the message of the errors that stop a command, by exit code.
+var errorIDs = map[int]MessageID{
+	ExitValidationError:  MsgInvalidGraph,
+	ExitUsageError:       MsgUsageError,
+	ExitExecutionFailure: MsgExecutionError,
+	ExitPluginError:      MsgPluginsFailed,
+	ExitWorkspaceError:   MsgWorkspaceError,
+	ExitCancelled:        MsgInterrupted,
+	ExitInternalError:    MsgInternalError,
+	ExitOffline:          MsgOfflineError,
+}
+
+// errorID returns the message of an error that stops a command with code.
+func errorID(code int) MessageID {
+	if id, ok := errorIDs[code]; ok {
+		return id
+	}
+	return MsgInternalError
+}
+
+// fail writes err to w with the message of its exit code, and returns code.
+func fail(w io.Writer, code int, err error) int {
+	say(w, errorID(code), err)
+	return code
+}
@@ -0,0 +1,98 @@
+package sw
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+)
+
+// profileFlag collects --profile kind=path values.
+type profileFlag struct {
+	cpu string
+	mem string
+}
+
+func (p *profileFlag) String() string {
+	if p == nil {
+		return ""
+	}
+	var parts []string
+	if p.cpu != "" {
+		parts = append(parts, "cpu="+p.cpu)
+	}
+	if p.mem != "" {
+		parts = append(parts, "mem="+p.mem)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (p *profileFlag) Set(v string) error {
+	kind, path, ok := strings.Cut(v, "=")
+	if !ok || strings.TrimSpace(path) == "" {
+		return fmt.Errorf("invalid --profile %q (expected cpu=<path> or mem=<path>)", v)
+	}
+	switch kind {
+	case "cpu":
+		p.cpu = path
+	case "mem":
+		p.mem = path
+	default:
+		return fmt.Errorf("invalid --profile kind %q (expected cpu|mem)", kind)
+	}
+	return nil
+}
+
+// start begins the requested profiles, resolving relative paths against
+// workdir, and returns a function that stops them and writes the results.
+func (p *profileFlag) start(workdir string) (func() error, error) {
+	var cpuFile *os.File
+	if p.cpu != "" {
+		path, err := absUnderWorkdir(workdir, p.cpu)
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("create cpu profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("start cpu profile: %w", err)
+		}
+		cpuFile = f
+	}
+	memPath := ""
+	if p.mem != "" {
+		path, err := absUnderWorkdir(workdir, p.mem)
+		if err != nil {
+			return nil, err
+		}
+		memPath = path
+	}
+
+	return func() error {
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			if err := cpuFile.Close(); err != nil {
+				return fmt.Errorf("write cpu profile: %w", err)
+			}
+		}
+		if memPath != "" {
+			f, err := os.Create(memPath)
+			if err != nil {
+				return fmt.Errorf("create mem profile: %w", err)
+			}
+			runtime.GC() // materialize up-to-date allocation statistics
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				_ = f.Close()
+				return fmt.Errorf("write mem profile: %w", err)
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("write mem profile: %w", err)
+			}
+		}
+		return nil
+	}, nil
+}
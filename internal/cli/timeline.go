@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+// TimelineFileName is the run file that records when each node of a run was
+// queued, started and finished, for investigating slow or stuck runs after
+// the fact. The execution trace is timestamp-free by design, so the timeline
+// is kept separately.
+const TimelineFileName = "timeline.json"
+
+// Timeline node statuses. Runs reconstructed from checkpoints only know which
+// nodes were checkpointed.
+const (
+	TimelineExecuted     = "executed"
+	TimelineCached       = "cached"
+	TimelineFailed       = "failed"
+	TimelineSkipped      = "skipped"
+	TimelineCheckpointed = "checkpointed"
+)
+
+// Timeline is the per-node schedule of a run. Offsets are relative to Start,
+// the moment the graph executor started.
+type Timeline struct {
+	RunID string    `json:"run_id"`
+	Start time.Time `json:"start"`
+	// Duration is the offset at which the last node finished.
+	Duration time.Duration `json:"duration_ns"`
+	// Lanes is the number of concurrency lanes: the most nodes in flight at
+	// once.
+	Lanes int `json:"lanes"`
+	// Reconstructed is set when the run recorded no timeline and it was
+	// rebuilt from checkpoints. Only finish times are then known; a node is
+	// assumed to have started as soon as its dependencies finished.
+	Reconstructed bool           `json:"reconstructed"`
+	Nodes         []TimelineNode `json:"nodes"`
+}
+
+// TimelineNode is one node of a Timeline. A node became ready (Queued) when
+// its last dependency finished, or when the run started if it has none.
+type TimelineNode struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Queued   time.Duration `json:"queued_ns"`
+	Started  time.Duration `json:"started_ns"`
+	Finished time.Duration `json:"finished_ns"`
+	// Lane is the concurrency lane the node ran in, or -1 for nodes that
+	// never ran, such as skipped nodes.
+	Lane int `json:"lane"`
+	// ThrottledBy is the label whose concurrency limit delayed the start of
+	// the node, if any.
+	ThrottledBy string `json:"throttled_by,omitempty"`
+}
+
+// Wait is the time the node spent ready but not running.
+func (n TimelineNode) Wait() time.Duration { return n.Started - n.Queued }
+
+// Duration is the time the node spent running.
+func (n TimelineNode) Duration() time.Duration { return n.Finished - n.Started }
+
+// buildTimeline lays out the nodes of g from the spans observed while the
+// graph executor ran from start.
+func buildTimeline(runID string, g *dag.TaskGraph, gr *dag.GraphResult, spans map[string]nodeSpan, start time.Time) Timeline {
+	tl := Timeline{RunID: runID, Start: start.UTC()}
+	upstream := upstreamByNode(g)
+	finished := make(map[string]time.Duration)
+	for _, name := range g.TopologicalOrder() {
+		n := TimelineNode{Name: name, Status: timelineStatus(gr.FinalState[name]), Lane: -1, ThrottledBy: gr.Throttled[name]}
+		for _, dep := range upstream[name] {
+			if f := finished[dep]; f > n.Queued {
+				n.Queued = f
+			}
+		}
+		n.Started, n.Finished = n.Queued, n.Queued
+		if sp, ok := spans[name]; ok {
+			n.Started, n.Finished = sp.start.Sub(start), sp.end.Sub(start)
+			n.Lane = 0
+		}
+		finished[name] = n.Finished
+		tl.Nodes = append(tl.Nodes, n)
+	}
+	tl.layout()
+	return tl
+}
+
+func timelineStatus(s dag.TaskState) string {
+	switch s {
+	case dag.TaskCompleted:
+		return TimelineExecuted
+	case dag.TaskCached:
+		return TimelineCached
+	case dag.TaskFailed:
+		return TimelineFailed
+	case dag.TaskSkipped:
+		return TimelineSkipped
+	}
+	return string(s)
+}
+
+// layout orders the nodes by start time and assigns lanes to the nodes that
+// ran (Lane >= 0), reusing the lowest lane that is free when a node starts.
+func (tl *Timeline) layout() {
+	sort.SliceStable(tl.Nodes, func(i, j int) bool {
+		a, b := tl.Nodes[i], tl.Nodes[j]
+		if a.Started != b.Started {
+			return a.Started < b.Started
+		}
+		return a.Name < b.Name
+	})
+	var laneEnd []time.Duration
+	for i := range tl.Nodes {
+		n := &tl.Nodes[i]
+		if n.Finished > tl.Duration {
+			tl.Duration = n.Finished
+		}
+		if n.Lane < 0 {
+			continue
+		}
+		n.Lane = len(laneEnd)
+		for l, end := range laneEnd {
+			if end <= n.Started {
+				n.Lane = l
+				break
+			}
+		}
+		if n.Lane == len(laneEnd) {
+			laneEnd = append(laneEnd, 0)
+		}
+		laneEnd[n.Lane] = n.Finished
+	}
+	tl.Lanes = len(laneEnd)
+}
+
+// saveTimeline records tl as a run file. It is best-effort: a missing
+// timeline is reconstructed from checkpoints.
+func saveTimeline(st *state.Store, tl Timeline) {
+	data, err := json.MarshalIndent(tl, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = st.SaveRunFile(tl.RunID, TimelineFileName, append(data, '\n'))
+}
+
+// LoadTimeline returns the timeline of run runID. Runs that did not record one
+// (older runs, or runs that crashed) are reconstructed from their checkpoints.
+func LoadTimeline(st *state.Store, runID string) (Timeline, error) {
+	data, err := st.LoadRunFile(runID, TimelineFileName)
+	if err == nil {
+		var tl Timeline
+		if err := json.Unmarshal(data, &tl); err != nil {
+			return Timeline{}, fmt.Errorf("parse %s of run %s: %w", TimelineFileName, runID, err)
+		}
+		return tl, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return Timeline{}, err
+	}
+	run, err := st.LoadRun(runID)
+	if err != nil {
+		return Timeline{}, err
+	}
+	checkpoints, err := st.LoadAllCheckpoints(runID)
+	if err != nil {
+		return Timeline{}, err
+	}
+	return timelineFromCheckpoints(run, checkpoints), nil
+}
+
+// timelineFromCheckpoints reconstructs the timeline of run from the times its
+// nodes were checkpointed, relative to the run's start.
+func timelineFromCheckpoints(run state.Run, checkpoints map[string]state.Checkpoint) Timeline {
+	tl := Timeline{RunID: run.RunID, Start: run.StartTime, Reconstructed: true}
+	for name, cp := range checkpoints {
+		n := TimelineNode{Name: name, Status: TimelineCheckpointed, Finished: cp.Timestamp.Sub(run.StartTime)}
+		for _, dep := range cp.Upstream {
+			if d, ok := checkpoints[dep]; ok {
+				if f := d.Timestamp.Sub(run.StartTime); f > n.Queued {
+					n.Queued = f
+				}
+			}
+		}
+		if n.Queued > n.Finished {
+			n.Queued = n.Finished
+		}
+		n.Started = n.Queued
+		tl.Nodes = append(tl.Nodes, n)
+	}
+	tl.layout()
+	return tl
+}
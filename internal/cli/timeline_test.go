@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+func TestExecute_RecordsTimeline(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Run: "sleep 0.05"},
+		{Name: "b", Run: "exit 1"},
+		{Name: "c", Run: "true"},
+		{Name: "d", Run: "true"},
+	}, []dag.Edge{{From: "a", To: "c"}, {From: "b", To: "d"}})
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, CacheDir: filepath.Join(workDir, "cache"), OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeClean}
+
+	res, err := Execute(context.Background(), inv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	tl, err := LoadTimeline(st, res.RunID)
+	if err != nil {
+		t.Fatalf("LoadTimeline: %v", err)
+	}
+	if tl.Reconstructed || tl.RunID != res.RunID || tl.Lanes < 1 {
+		t.Fatalf("unexpected timeline: %#v", tl)
+	}
+	nodes := make(map[string]TimelineNode)
+	for _, n := range tl.Nodes {
+		nodes[n.Name] = n
+	}
+	want := map[string]string{"a": TimelineExecuted, "b": TimelineFailed, "c": TimelineExecuted, "d": TimelineSkipped}
+	for name, status := range want {
+		if nodes[name].Status != status {
+			t.Fatalf("node %s: status %q, want %q", name, nodes[name].Status, status)
+		}
+	}
+	a, c := nodes["a"], nodes["c"]
+	if a.Duration() < 50*time.Millisecond {
+		t.Fatalf("node a ran for %s", a.Duration())
+	}
+	if c.Queued != a.Finished || c.Started < c.Queued || c.Lane < 0 {
+		t.Fatalf("node c not queued behind a: a=%#v c=%#v", a, c)
+	}
+	if d := nodes["d"]; d.Lane != -1 || d.Duration() != 0 {
+		t.Fatalf("skipped node d should not run: %#v", d)
+	}
+}
+
+func TestTimelineLayout_AssignsLowestFreeLane(t *testing.T) {
+	tl := Timeline{Nodes: []TimelineNode{
+		{Name: "c", Started: 5, Finished: 20},
+		{Name: "a", Started: 0, Finished: 10},
+		{Name: "b", Started: 0, Finished: 4},
+		{Name: "d", Started: 12, Finished: 15},
+		{Name: "skipped", Started: 10, Finished: 10, Lane: -1},
+	}}
+	tl.layout()
+	got := make(map[string]int)
+	var order []string
+	for _, n := range tl.Nodes {
+		got[n.Name] = n.Lane
+		order = append(order, n.Name)
+	}
+	want := map[string]int{"a": 0, "b": 1, "c": 1, "d": 0, "skipped": -1}
+	for name, lane := range want {
+		if got[name] != lane {
+			t.Fatalf("lanes = %v, want %v", got, want)
+		}
+	}
+	if tl.Lanes != 2 || tl.Duration != 20 {
+		t.Fatalf("lanes=%d duration=%d", tl.Lanes, tl.Duration)
+	}
+	if order[0] != "a" || order[1] != "b" || order[len(order)-1] != "d" {
+		t.Fatalf("nodes not ordered by start: %v", order)
+	}
+}
+
+func TestLoadTimeline_ReconstructsFromCheckpoints(t *testing.T) {
+	st, err := state.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	run := state.Run{RunID: "r1", GraphHash: "g", StartTime: start, Mode: state.ExecutionModeClean, Status: "success"}
+	if err := st.SaveRun(run); err != nil {
+		t.Fatalf("SaveRun: %v", err)
+	}
+	for _, cp := range []state.Checkpoint{
+		{NodeID: "a", Timestamp: start.Add(time.Second), CacheKeys: []string{}, OutputHash: "h", Valid: true, Upstream: []string{}},
+		{NodeID: "b", Timestamp: start.Add(3 * time.Second), CacheKeys: []string{}, OutputHash: "h", Valid: true, Upstream: []string{"a"}},
+	} {
+		if err := st.SaveCheckpoint("r1", cp); err != nil {
+			t.Fatalf("SaveCheckpoint: %v", err)
+		}
+	}
+
+	tl, err := LoadTimeline(st, "r1")
+	if err != nil {
+		t.Fatalf("LoadTimeline: %v", err)
+	}
+	if !tl.Reconstructed || len(tl.Nodes) != 2 || tl.Lanes != 1 || tl.Duration != 3*time.Second {
+		t.Fatalf("unexpected timeline: %#v", tl)
+	}
+	if b := tl.Nodes[1]; b.Name != "b" || b.Started != time.Second || b.Duration() != 2*time.Second || b.Status != TimelineCheckpointed {
+		t.Fatalf("unexpected node: %#v", b)
+	}
+}
+
+func TestBuildTimeline_RecordsThrottledNodes(t *testing.T) {
+	g, err := dag.NewTaskGraph([]core.Task{{Name: "a", Inputs: []string{}, Run: "true", Labels: []string{"network"}}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	gr := &dag.GraphResult{FinalState: dag.ExecutionState{"a": dag.TaskCompleted}, Throttled: map[string]string{"a": "network"}}
+	tl := buildTimeline("run", g, gr, map[string]nodeSpan{"a": {start: start, end: start.Add(time.Second)}}, start)
+	if len(tl.Nodes) != 1 || tl.Nodes[0].ThrottledBy != "network" {
+		t.Fatalf("nodes = %+v", tl.Nodes)
+	}
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+// timingRunner wraps a TaskRunner and accumulates the wall-clock time spent
+// probing and running each node, and the span from the first call for a node
+// to the end of the last. Timings never influence execution.
+type timingRunner struct {
+	inner dag.TaskRunner
+
+	mu    sync.Mutex
+	spent map[string]time.Duration
+	span  map[string]nodeSpan
+}
+
+// nodeSpan is the wall-clock interval in which a node was probed, run or
+// restored.
+type nodeSpan struct {
+	start, end time.Time
+}
+
+func newTimingRunner(inner dag.TaskRunner) *timingRunner {
+	return &timingRunner{inner: inner, spent: make(map[string]time.Duration), span: make(map[string]nodeSpan)}
+}
+
+func (t *timingRunner) Probe(ctx context.Context, task core.Task) (*dag.NodeResult, bool, error) {
+	start := time.Now()
+	res, cached, err := t.inner.Probe(ctx, task)
+	t.add(task.Name, start, time.Now())
+	return res, cached, err
+}
+
+func (t *timingRunner) Run(ctx context.Context, task core.Task) (*dag.NodeResult, error) {
+	start := time.Now()
+	res, err := t.inner.Run(ctx, task)
+	t.add(task.Name, start, time.Now())
+	return res, err
+}
+
+// ProbeBatch forwards batched probing so wrapping preserves the optional
+// capability of the inner runner. The batch is not timed: it belongs to no
+// single node, and each node's Probe is timed as before.
+func (t *timingRunner) ProbeBatch(ctx context.Context, tasks []core.Task) error {
+	if bp, ok := t.inner.(dag.BatchProber); ok {
+		return bp.ProbeBatch(ctx, tasks)
+	}
+	return nil
+}
+
+// Restore forwards incremental-plan restoration so wrapping preserves the
+// optional capability of the inner runner.
+func (t *timingRunner) Restore(ctx context.Context, task core.Task) (*dag.NodeResult, error) {
+	restorer, ok := t.inner.(interface {
+		Restore(ctx context.Context, task core.Task) (*dag.NodeResult, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("runner does not support Restore for incremental plan execution")
+	}
+	start := time.Now()
+	res, err := restorer.Restore(ctx, task)
+	t.add(task.Name, start, time.Now())
+	return res, err
+}
+
+func (t *timingRunner) add(name string, start, end time.Time) {
+	t.mu.Lock()
+	t.spent[name] += end.Sub(start)
+	sp, ok := t.span[name]
+	if !ok {
+		sp.start = start
+	}
+	sp.end = end
+	t.span[name] = sp
+	t.mu.Unlock()
+}
+
+func (t *timingRunner) durations() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]time.Duration, len(t.spent))
+	for k, v := range t.spent {
+		out[k] = v
+	}
+	return out
+}
+
+func (t *timingRunner) spans() map[string]nodeSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]nodeSpan, len(t.span))
+	for k, v := range t.span {
+		out[k] = v
+	}
+	return out
+}
+
+// Engine phases reported in CLIResult.Phases.
+const (
+	PhaseParse    = "parse"
+	PhaseValidate = "validate"
+	PhaseHash     = "hash"
+	PhasePlan     = "plan"
+	PhaseExecute  = "execute"
+	PhaseFinalize = "finalize"
+)
+
+// PhaseTiming is the wall-clock duration of one engine phase.
+type PhaseTiming struct {
+	Name     string
+	Duration time.Duration
+}
+
+// phaseTimer records consecutive phases: each mark closes the phase that
+// started at the previous mark.
+type phaseTimer struct {
+	last   time.Time
+	phases []PhaseTiming
+}
+
+func newPhaseTimer() *phaseTimer {
+	return &phaseTimer{last: time.Now()}
+}
+
+func (p *phaseTimer) mark(name string) {
+	now := time.Now()
+	p.phases = append(p.phases, PhaseTiming{Name: name, Duration: now.Sub(p.last)})
+	p.last = now
+}
+
+func (p *phaseTimer) timings() []PhaseTiming {
+	out := make([]PhaseTiming, len(p.phases))
+	copy(out, p.phases)
+	return out
+}
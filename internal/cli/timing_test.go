@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestExecute_RecordsNodeDurations(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	tasks := []core.Task{
+		{Name: "a", Inputs: []string{}, Run: "echo a > a.txt", Outputs: []string{"a.txt"}},
+		{Name: "b", Inputs: []string{}, Run: "echo b > b.txt", Outputs: []string{"b.txt"}},
+	}
+	writeGraphJSON(t, graphPath, tasks, nil)
+
+	res, err := Execute(context.Background(), CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+	})
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+	if res.Duration <= 0 {
+		t.Fatalf("expected positive run duration, got %v", res.Duration)
+	}
+	for _, name := range []string{"a", "b"} {
+		if res.NodeDurations[name] <= 0 {
+			t.Fatalf("expected duration for %s, got %v", name, res.NodeDurations)
+		}
+	}
+}
+
+func TestExecute_RecordsPhasesInOrder(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Inputs: []string{}, Run: "true", Outputs: []string{}}}, nil)
+
+	res, err := Execute(context.Background(), CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+	})
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+	want := []string{PhaseParse, PhaseValidate, PhaseHash, PhasePlan, PhaseExecute, PhaseFinalize}
+	if len(res.Phases) != len(want) {
+		t.Fatalf("phases = %+v", res.Phases)
+	}
+	for i, p := range res.Phases {
+		if p.Name != want[i] {
+			t.Fatalf("phase %d = %s, want %s", i, p.Name, want[i])
+		}
+	}
+}
+
+func TestExecute_InvalidGraph_StopsAfterFailingPhase(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Inputs: []string{}, Run: "true"}}, []dag.Edge{{From: "a", To: "missing"}})
+
+	res, _ := Execute(context.Background(), CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		CacheDir:      filepath.Join(workDir, "cache"),
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+	})
+	if len(res.Phases) != 2 || res.Phases[1].Name != PhaseValidate {
+		t.Fatalf("phases = %+v", res.Phases)
+	}
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"scriptweaver/internal/recovery/state"
+	"scriptweaver/internal/trace"
+)
+
+// TraceJournalFileName is the run file that journals the trace events of a
+// traced run while it runs. It is removed once the trace is finalized, so it
+// only remains for runs whose process crashed.
+const TraceJournalFileName = "trace.journal"
+
+// ErrNoTraceJournal is returned by FinalizeTrace for runs without a trace
+// journal: runs that were not traced, or whose trace was finalized. The
+// error says which.
+var ErrNoTraceJournal = errors.New("has no trace journal")
+
+// FinalizedTrace describes a trace rebuilt by FinalizeTrace.
+type FinalizedTrace struct {
+	RunID     string `json:"run_id"`
+	Path      string `json:"path"`
+	GraphHash string `json:"graph_hash"`
+	Events    int    `json:"events"`
+	// Nodes counts the nodes with at least one event. Nodes that had not
+	// finished when the run stopped have none.
+	Nodes int `json:"nodes"`
+}
+
+// FinalizeTrace rebuilds the canonical trace of run runID from its trace
+// journal and writes it to path, or, when path is empty, to the trace path
+// the run was invoked with. The trace holds every event recorded before the
+// run stopped; its bytes equal those the run would have written had it
+// stopped there normally. The journal is kept, so finalizing is repeatable.
+func FinalizeTrace(st *state.Store, workDir, runID, path string) (FinalizedTrace, error) {
+	rec, err := ShowRun(st, runID)
+	if err != nil {
+		return FinalizedTrace{}, err
+	}
+	run := rec.Run
+	journalPath, err := st.RunFilePath(runID, TraceJournalFileName)
+	if err != nil {
+		return FinalizedTrace{}, err
+	}
+	events, err := trace.ReadJournal(journalPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return FinalizedTrace{}, fmt.Errorf("run %s %w: %s", runID, ErrNoTraceJournal, noJournalReason(st, rec))
+	}
+	if err != nil {
+		return FinalizedTrace{}, fmt.Errorf("read trace journal of run %s: %w", runID, err)
+	}
+	if path == "" {
+		if rec.Params != nil {
+			path = rec.Params.Trace
+		}
+		if path == "" {
+			return FinalizedTrace{}, fmt.Errorf("run %s recorded no trace path", runID)
+		}
+	}
+	if path = filepath.FromSlash(path); !filepath.IsAbs(path) {
+		path = filepath.Join(workDir, path)
+	}
+
+	data, err := trace.ExecutionTrace{GraphHash: run.GraphHash, Events: events}.CanonicalJSON()
+	if err != nil {
+		return FinalizedTrace{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return FinalizedTrace{}, fmt.Errorf("create trace dir: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		return FinalizedTrace{}, err
+	}
+	nodes := make(map[string]bool)
+	for _, ev := range events {
+		if ev.TaskID != "" {
+			nodes[ev.TaskID] = true
+		}
+	}
+	return FinalizedTrace{RunID: runID, Path: path, GraphHash: run.GraphHash, Events: len(events), Nodes: len(nodes)}, nil
+}
+
+// noJournalReason explains why the run of rec has no trace journal.
+func noJournalReason(st *state.Store, rec RunRecord) string {
+	switch {
+	case rec.Params == nil || rec.Params.Trace == "":
+		return "it was not run with --trace"
+	case RunStatus(st, rec) != RunStatusRunning:
+		return fmt.Sprintf("it stopped normally, so its trace is complete in %s and the journal was removed", rec.Params.Trace)
+	default:
+		return "its journal could not be written"
+	}
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+	"scriptweaver/internal/trace"
+)
+
+func TestFinalizeTrace_RebuildsTraceFromJournal(t *testing.T) {
+	workDir := t.TempDir()
+	g, err := dag.NewTaskGraph(
+		[]core.Task{
+			{Name: "a", Inputs: []string{}, Run: "true"},
+			{Name: "b", Inputs: []string{}, Run: "exit 1"},
+			{Name: "c", Inputs: []string{}, Run: "true"},
+		},
+		[]dag.Edge{{From: "b", To: "c"}},
+	)
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	rec := &state.FailureRecorder{Store: st}
+	runID, _ := rec.NewRunID()
+	if err := rec.StartRun(state.Run{RunID: runID, GraphHash: g.Hash().String(), StartTime: time.Now().UTC(), Mode: state.ExecutionModeClean, Status: "running"}); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	saveRunParams(st, runID, RunParams{Graph: "graph.json", OutputDir: "out", Mode: ExecutionModeClean, Trace: "traces/trace.json"})
+
+	// The run journals its events; its process dies before the trace is written.
+	journalPath, err := st.RunFilePath(runID, TraceJournalFileName)
+	if err != nil {
+		t.Fatalf("RunFilePath: %v", err)
+	}
+	j, err := trace.CreateJournal(journalPath)
+	if err != nil {
+		t.Fatalf("CreateJournal: %v", err)
+	}
+	runner, err := dag.NewCacheAwareRunner(core.NewRunner(workDir, noCache{}))
+	if err != nil {
+		t.Fatalf("NewCacheAwareRunner: %v", err)
+	}
+	exec, err := dag.NewExecutor(g, runner)
+	if err != nil {
+		t.Fatalf("NewExecutor: %v", err)
+	}
+	exec.TraceSink = j
+	gr, err := exec.RunSerial(context.Background())
+	if err != nil {
+		t.Fatalf("RunSerial: %v", err)
+	}
+
+	ft, err := FinalizeTrace(st, workDir, runID, "")
+	if err != nil {
+		t.Fatalf("FinalizeTrace: %v", err)
+	}
+	if ft.Path != filepath.Join(workDir, "traces", "trace.json") || ft.Nodes != 3 {
+		t.Fatalf("finalized = %+v", ft)
+	}
+	data, err := os.ReadFile(ft.Path)
+	if err != nil {
+		t.Fatalf("read trace: %v", err)
+	}
+	if string(data) != string(gr.TraceBytes) {
+		t.Fatalf("finalized trace differs from the run's:\n%s\n%s", data, gr.TraceBytes)
+	}
+
+	if _, err := FinalizeTrace(st, workDir, "no-such-run", ""); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing run: %v", err)
+	}
+	if err := os.Remove(journalPath); err != nil {
+		t.Fatalf("remove journal: %v", err)
+	}
+	if _, err := FinalizeTrace(st, workDir, runID, ""); !errors.Is(err, ErrNoTraceJournal) {
+		t.Fatalf("without journal: %v", err)
+	}
+}
+
+func TestExecute_RemovesTraceJournalOnceTraceIsWritten(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{{Name: "a", Run: "true"}}, nil)
+	inv := CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: ExecutionModeClean,
+		Trace:         TraceConfig{Enabled: true, Path: filepath.Join(workDir, "trace.json")},
+	}
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("exit=%d err=%v", res.ExitCode, err)
+	}
+	if _, err := os.Stat(filepath.Join(workDir, ".scriptweaver", "runs", res.RunID, TraceJournalFileName)); !os.IsNotExist(err) {
+		t.Fatalf("journal of a finalized trace kept: %v", err)
+	}
+
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if _, err := FinalizeTrace(st, workDir, res.RunID, ""); !errors.Is(err, ErrNoTraceJournal) || !strings.Contains(err.Error(), "stopped normally, so its trace is complete in trace.json") {
+		t.Fatalf("finalized run: %v", err)
+	}
+	inv.Trace = TraceConfig{}
+	if res, err = Execute(context.Background(), inv); err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("untraced: exit=%d err=%v", res.ExitCode, err)
+	}
+	if _, err := FinalizeTrace(st, workDir, res.RunID, ""); !errors.Is(err, ErrNoTraceJournal) || !strings.Contains(err.Error(), "not run with --trace") {
+		t.Fatalf("untraced run: %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/projectintegration/engine/workspace"
+)
+
+// ErrNoSharedCache is returned by WarmCache when the workspace config lists no
+// shared cache tiers to prefetch from.
+var ErrNoSharedCache = errors.New("no shared cache tiers configured")
+
+// WarmStatus is the outcome of prefetching the cache entry of one node.
+type WarmStatus string
+
+const (
+	// WarmLocal means the entry was already in the local cache.
+	WarmLocal WarmStatus = "local"
+	// WarmFetched means the entry was copied from a shared tier.
+	WarmFetched WarmStatus = "fetched"
+	// WarmMissing means no tier holds the entry; the run will execute the node.
+	WarmMissing WarmStatus = "missing"
+	// WarmSkipped means the node's hash is unknown because a node it depends
+	// on has no successful entry whose outputs it could be computed from.
+	WarmSkipped WarmStatus = "skipped"
+)
+
+// WarmedNode is the outcome of prefetching the entry of one node.
+type WarmedNode struct {
+	Node string
+	// Hash is the task hash of the node; empty when it was skipped.
+	Hash   core.TaskHash
+	Status WarmStatus
+}
+
+// WarmReport describes a cache warm-up, with nodes in topological order.
+type WarmReport struct {
+	CacheDir string
+	Nodes    []WarmedNode
+}
+
+// Count returns the number of nodes with status s.
+func (r WarmReport) Count(s WarmStatus) int {
+	n := 0
+	for _, w := range r.Nodes {
+		if w.Status == s {
+			n++
+		}
+	}
+	return n
+}
+
+// WarmCache prefetches the cache entries an incremental run of g in workDir
+// would look up from the shared tiers of the workspace config into the local
+// cache, cacheDir or the workspace cache when empty, so that the run starts
+// warm without reaching the shared tiers.
+//
+// Task hashes are computed from the current files in workDir. The outputs of
+// an upstream node are taken from its cache entry rather than from disk, as
+// the run would restore them before its dependents are hashed, so a fresh
+// checkout is warmed completely.
+func WarmCache(workDir string, g *dag.TaskGraph, cacheDir string) (WarmReport, error) {
+	ws, err := workspace.EnsureWorkspace(workDir)
+	if err != nil {
+		return WarmReport{}, err
+	}
+	if cacheDir == "" {
+		cacheDir = ws.CacheDir
+	}
+	cfg, _, err := config.LoadOptional(workDir)
+	if err != nil {
+		return WarmReport{}, err
+	}
+	if cfg.Cache == nil {
+		return WarmReport{}, ErrNoSharedCache
+	}
+	lockfile, err := LoadLockfile(workDir)
+	if err != nil {
+		return WarmReport{}, err
+	}
+	if g, err = pinFetches(g, lockfile); err != nil {
+		return WarmReport{}, err
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return WarmReport{}, fmt.Errorf("create cache dir: %w", err)
+	}
+	keys, err := loadCacheKeys(workDir, cfg.CacheEncryption)
+	if err != nil {
+		return WarmReport{}, err
+	}
+	local := encryptedFileCache(cacheDir, keys)
+	cache, err := withSharedTiers(local, cfg.Cache, workDir, g.Hash().String())
+	if err != nil {
+		return WarmReport{}, err
+	}
+
+	// Only data dependencies produce inputs; order-only ones are ignored.
+	deps := make(map[string][]string)
+	for _, e := range g.Edges() {
+		if !e.IsOrder() {
+			deps[e.To] = append(deps[e.To], e.From)
+		}
+	}
+	hasher := &core.TaskHasher{Algorithm: hashAlgorithm(cfg)}
+	resolver := core.NewInputResolver(workDir)
+	// restored holds the outputs of the nodes warmed so far by normalized
+	// absolute path; usable marks the nodes whose outputs are known.
+	restored := make(map[string][]byte)
+	usable := make(map[string]bool)
+
+	report := WarmReport{CacheDir: cacheDir}
+	for _, name := range g.TopologicalOrder() {
+		node, _ := g.Node(name)
+		known := true
+		for _, d := range deps[name] {
+			known = known && usable[d]
+		}
+		if !known {
+			report.Nodes = append(report.Nodes, WarmedNode{Node: name, Status: WarmSkipped})
+			continue
+		}
+		task := node.Task
+		inputs, err := resolveWithRestored(resolver, task.Inputs, restored)
+		if err != nil {
+			return report, fmt.Errorf("node %s: resolving inputs: %w", name, err)
+		}
+		hash := hashTaskInputs(hasher, task, inputs, workDir)
+
+		status := WarmFetched
+		if ok, err := local.Has(hash); err != nil {
+			return report, err
+		} else if ok {
+			status = WarmLocal
+		}
+		entry, err := cache.Get(hash)
+		if err != nil {
+			return report, fmt.Errorf("node %s: %w", name, err)
+		}
+		if entry == nil {
+			status = WarmMissing
+		} else if entry.ExitCode == 0 {
+			usable[name] = true
+			for _, a := range entry.Artifacts {
+				restored[filepath.ToSlash(filepath.Join(workDir, filepath.FromSlash(a.Path)))] = a.Content
+			}
+		}
+		report.Nodes = append(report.Nodes, WarmedNode{Node: name, Hash: hash, Status: status})
+	}
+	return report, nil
+}
+
+// hashTaskInputs is the task hash of task in workDir over the resolved inputs.
+func hashTaskInputs(hasher *core.TaskHasher, task core.Task, inputs *core.InputSet, workDir string) core.TaskHash {
+	return hasher.ComputeHash(task.HashInput(inputs, workDir))
+}
+
+// resolveWithRestored resolves patterns like resolver, over the files on disk
+// overlaid with restored, whose contents take precedence.
+func resolveWithRestored(resolver *core.InputResolver, patterns []string, restored map[string][]byte) (*core.InputSet, error) {
+	onDisk, err := resolver.Resolve(patterns)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte, len(onDisk.Inputs))
+	for _, in := range onDisk.Inputs {
+		files[in.Path] = in.Content
+	}
+	for _, pattern := range patterns {
+		full := pattern
+		if !filepath.IsAbs(full) {
+			full = filepath.Join(resolver.BaseDir, full)
+		}
+		full = filepath.ToSlash(full)
+		glob := strings.ContainsAny(pattern, "*?[]")
+		for p, content := range restored {
+			if p == full {
+				files[p] = content
+			} else if ok, _ := path.Match(full, p); ok && glob {
+				files[p] = content
+			}
+		}
+	}
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	set := &core.InputSet{Inputs: make([]core.Input, 0, len(paths))}
+	for _, p := range paths {
+		set.Inputs = append(set.Inputs, core.Input{Path: p, Content: files[p]})
+	}
+	return set, nil
+}
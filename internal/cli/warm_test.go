@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+func TestWarmCache_PrefetchesWholeGraphForFreshCheckout(t *testing.T) {
+	workDir := t.TempDir()
+	shared := t.TempDir()
+	cfgPath := filepath.Join(workDir, ".scriptweaver", "config.json")
+	if err := os.MkdirAll(filepath.Dir(cfgPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfgPath, []byte(`{"cache":{"tiers":["`+shared+`"],"write":"write-all"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	graphPath := filepath.Join(workDir, "graph.json")
+	writeGraphJSON(t, graphPath, []core.Task{
+		{Name: "a", Inputs: []string{}, Run: "echo A > a.txt", Outputs: []string{"a.txt"}},
+		{Name: "b", Inputs: []string{"a.txt"}, Run: "cat a.txt > b.txt", Outputs: []string{"b.txt"}},
+		{Name: "c", Inputs: []string{}, Run: "echo C > c.txt", Outputs: []string{"c.txt"}},
+	}, []dag.Edge{{From: "a", To: "b"}})
+	inv := CLIInvocation{WorkDir: workDir, GraphPath: graphPath, CacheDir: filepath.Join(workDir, "cache-ci"), OutputDir: filepath.Join(workDir, "out"), ExecutionMode: ExecutionModeIncremental}
+	if res, err := Execute(context.Background(), inv); err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("populating run: exit %d, %v", res.ExitCode, err)
+	}
+
+	// A fresh checkout has none of the outputs, and c's entry is lost.
+	for _, f := range []string{"a.txt", "b.txt", "c.txt"} {
+		_ = os.Remove(filepath.Join(workDir, f))
+	}
+	g, err := LoadGraphFromFile(graphPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := core.NewFileCache(shared).Evict(computeTaskHashFor(t, workDir, g, "c")); err != nil {
+		t.Fatal(err)
+	}
+	local := filepath.Join(workDir, "cache-dev")
+	report, err := WarmCache(workDir, g, local)
+	if err != nil {
+		t.Fatalf("WarmCache: %v", err)
+	}
+	got := map[string]WarmStatus{}
+	for _, n := range report.Nodes {
+		got[n.Node] = n.Status
+	}
+	if got["a"] != WarmFetched || got["b"] != WarmFetched || got["c"] != WarmMissing {
+		t.Fatalf("report = %+v", report.Nodes)
+	}
+
+	// The warmed cache serves a and b without the shared tier.
+	if err := os.Remove(cfgPath); err != nil {
+		t.Fatal(err)
+	}
+	inv.CacheDir = local
+	res, err := Execute(context.Background(), inv)
+	if err != nil || res.ExitCode != ExitSuccess {
+		t.Fatalf("warm run: exit %d, %v", res.ExitCode, err)
+	}
+	if fs := res.GraphResult.FinalState; fs["a"] != dag.TaskCached || fs["b"] != dag.TaskCached || fs["c"] != dag.TaskCompleted {
+		t.Fatalf("warm run states = %v", fs)
+	}
+
+	if _, err := WarmCache(workDir, g, local); !errors.Is(err, ErrNoSharedCache) {
+		t.Fatalf("WarmCache without tiers: %v", err)
+	}
+}
+
+func computeTaskHashFor(t *testing.T, workDir string, g *dag.TaskGraph, name string) core.TaskHash {
+	t.Helper()
+	n, _ := g.Node(name)
+	h, err := computeTaskHash(core.NewRunner(workDir, nil), n.Task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
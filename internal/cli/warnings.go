@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scriptweaver/internal/dag"
+)
+
+// GraphWarning is a problem with a graph that does not keep it from running,
+// but usually is a mistake. `sw validate --strict` fails on any.
+type GraphWarning struct {
+	// Code is one of the Warn* codes.
+	Code    string `json:"code"`
+	Node    string `json:"node,omitempty"`
+	Message string `json:"message"`
+}
+
+func (w GraphWarning) String() string { return w.Message }
+
+// Warning codes.
+const (
+	// WarnOrphanNode: a node of a graph with edges has none, so nothing
+	// orders it relative to the rest of the graph.
+	WarnOrphanNode = "orphan_node"
+	// WarnUnusedInput: a declared input matches no file in the workdir and
+	// no task produces it, so it does not contribute to the task's hash.
+	WarnUnusedInput = "unused_input"
+	// WarnDuplicateOutput: two tasks declare the same output, so the last
+	// one to run overwrites the other's.
+	WarnDuplicateOutput = "duplicate_output"
+	// WarnDeprecatedField: a graph file uses a field that is slated for
+	// removal (see graph.Deprecation).
+	WarnDeprecatedField = "deprecated_field"
+)
+
+// DeprecationWarnings returns a warning for every use of a deprecated field
+// in the graph file at path and the files it includes, ordered by file and
+// position. params are the values of the graph's parameters, as for
+// LoadGraphWithParams.
+func DeprecationWarnings(path string, params map[string]string) ([]GraphWarning, error) {
+	_, sources, err := readGraph(path, params)
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(sources))
+	for p := range sources {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var out []GraphWarning
+	for _, p := range paths {
+		for _, w := range deprecatedFields(p, sources[p]) {
+			out = append(out, GraphWarning{Code: WarnDeprecatedField, Message: fmt.Sprintf("%s: %s", p, w)})
+		}
+	}
+	return out, nil
+}
+
+// GraphWarnings returns the warnings of g, ordered by node and code. Inputs
+// are resolved against workDir; with an empty workDir they are not checked.
+func GraphWarnings(g *dag.TaskGraph, workDir string) []GraphWarning {
+	nodes := g.Nodes()
+	edges := g.Edges()
+	var out []GraphWarning
+
+	if len(edges) > 0 {
+		connected := make(map[string]bool, len(nodes))
+		for _, e := range edges {
+			connected[e.From], connected[e.To] = true, true
+		}
+		for _, n := range nodes {
+			if !connected[n.Task.Name] {
+				out = append(out, GraphWarning{Code: WarnOrphanNode, Node: n.Task.Name,
+					Message: fmt.Sprintf("task %q has no edges, so it is not ordered relative to the other tasks", n.Task.Name)})
+			}
+		}
+	}
+
+	producers := make(map[string][]string)
+	var outputs []string
+	for _, n := range nodes {
+		for _, o := range n.Task.Outputs {
+			o = filepath.ToSlash(filepath.Clean(o))
+			if len(producers[o]) == 0 {
+				outputs = append(outputs, o)
+			}
+			producers[o] = append(producers[o], n.Task.Name)
+		}
+	}
+	for _, o := range outputs {
+		names := producers[o]
+		for _, name := range names[1:] {
+			out = append(out, GraphWarning{Code: WarnDuplicateOutput, Node: name,
+				Message: fmt.Sprintf("task %q declares output %q, which task %q also declares", name, o, names[0])})
+		}
+	}
+
+	if workDir != "" {
+		for _, n := range nodes {
+			for _, in := range n.Task.Inputs {
+				if inputMatchesFile(workDir, in) || inputProduced(in, outputs) {
+					continue
+				}
+				out = append(out, GraphWarning{Code: WarnUnusedInput, Node: n.Task.Name,
+					Message: fmt.Sprintf("input %q of task %q matches no file and no task produces it", in, n.Task.Name)})
+			}
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Node != out[j].Node {
+			return out[i].Node < out[j].Node
+		}
+		return out[i].Code < out[j].Code
+	})
+	return out
+}
+
+// inputMatchesFile reports whether the input pattern matches an existing
+// path under workDir.
+func inputMatchesFile(workDir, pattern string) bool {
+	full := pattern
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(workDir, pattern)
+	}
+	if matches, err := filepath.Glob(full); err != nil || len(matches) > 0 {
+		// An invalid pattern is reported when the run resolves it.
+		return true
+	}
+	_, err := os.Stat(full)
+	return err == nil
+}
+
+// inputProduced reports whether the input pattern names one of the declared
+// outputs or a file inside an output directory.
+func inputProduced(pattern string, outputs []string) bool {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	for _, o := range outputs {
+		if pattern == o || strings.HasPrefix(pattern, o+"/") {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, o); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+)
+
+func TestGraphWarnings(t *testing.T) {
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "main.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := dag.NewTaskGraph([]core.Task{
+		{Name: "build", Inputs: []string{"main.go", "*.c"}, Run: "true", Outputs: []string{"bin"}},
+		{Name: "test", Inputs: []string{"bin/app", "testdata/**"}, Run: "true", Outputs: []string{"report.txt"}},
+		{Name: "lint", Inputs: []string{"main.go"}, Run: "true", Outputs: []string{"report.txt"}},
+	}, []dag.Edge{{From: "build", To: "test"}})
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	var got []string
+	for _, w := range GraphWarnings(g, workDir) {
+		got = append(got, w.Node+" "+w.Code)
+	}
+	want := []string{"build unused_input", "lint orphan_node", "test duplicate_output", "test unused_input"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("warnings = %q, want %q", got, want)
+	}
+
+	// Without a workdir inputs are not checked, and a graph without edges
+	// has no orphans.
+	g, err = dag.NewTaskGraph([]core.Task{{Name: "a", Inputs: []string{"missing"}, Run: "true"}, {Name: "b", Run: "true"}}, nil)
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	if w := GraphWarnings(g, ""); len(w) != 0 {
+		t.Fatalf("warnings = %+v", w)
+	}
+}
+
+func TestDeprecationWarnings_CheckIncludedFiles(t *testing.T) {
+	if err := graph.RegisterDeprecation(graph.Deprecation{Field: "tasks[].platform_independent", RemovedIn: "3.0.0"}); err != nil {
+		t.Fatalf("RegisterDeprecation: %v", err)
+	}
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "main.json")
+	libPath := filepath.Join(dir, "lib.json")
+	if err := os.WriteFile(mainPath, []byte(`{"includes": [{"path": "lib.json", "namespace": "lib"}], "tasks": [{"name": "a", "inputs": [], "run": "true"}], "edges": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(libPath, []byte(`{"tasks": [{"name": "b", "inputs": [], "run": "true", "platform_independent": true}], "edges": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DeprecationWarnings(mainPath, nil)
+	if err != nil {
+		t.Fatalf("DeprecationWarnings: %v", err)
+	}
+	if len(got) != 1 || got[0].Code != WarnDeprecatedField || !strings.HasPrefix(got[0].Message, libPath+": tasks[0].platform_independent is deprecated") {
+		t.Fatalf("warnings = %+v", got)
+	}
+}
+
+func TestDeprecationWarnings_JSONLinesRecords(t *testing.T) {
+	if err := graph.RegisterDeprecation(graph.Deprecation{Field: "tasks[].platform_independent", RemovedIn: "3.0.0"}); err != nil {
+		t.Fatalf("RegisterDeprecation: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "graph.jsonl")
+	lines := `{"task": {"name": "a", "run": "true"}}` + "\n" + `{"task": {"name": "b", "run": "true", "platform_independent": true}}` + "\n"
+	if err := os.WriteFile(path, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DeprecationWarnings(path, nil)
+	if err != nil {
+		t.Fatalf("DeprecationWarnings: %v", err)
+	}
+	if len(got) != 1 || !strings.HasPrefix(got[0].Message, path+": tasks[1].platform_independent is deprecated") || !strings.Contains(got[0].Message, "line 2") {
+		t.Fatalf("warnings = %+v", got)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+
+	"scriptweaver/internal/platform"
 )
 
 // CacheEntry represents a stored result of a task execution.
@@ -42,6 +45,11 @@ type CachedArtifact struct {
 
 	// Content is the artifact file content.
 	Content []byte `json:"content"`
+
+	// SHA256 is the hex content hash recorded in the entry manifest.
+	// Restore re-hashes against it to detect corruption. Entries written
+	// before it existed leave it empty and skip the manifest comparison.
+	SHA256 string `json:"sha256,omitempty"`
 }
 
 // Cache provides storage and retrieval of task execution results.
@@ -72,14 +80,31 @@ type Cache interface {
 // Structure:
 //
 //	{CacheDir}/
-//	  {hash[0:2]}/
-//	    {hash}/
+//	  {digest[0:2]}/
+//	    {algorithm}-{digest}/
 //	      metadata.json  (stdout, stderr, exit_code, artifact paths)
+//	      access.json    (hit count and last access time)
 //	      artifacts/
 //	        {artifact-hash}.blob
+//
+// With Keys set, stdout, stderr and artifact blobs are encrypted and
+// metadata.json records the ID of the key; artifact paths and the exit code
+// stay readable.
 type FileCache struct {
 	// CacheDir is the root directory for cache storage.
 	CacheDir string
+
+	// Keys encrypt the entries written and decrypt those read; nil writes
+	// plain entries and fails to read encrypted ones.
+	Keys *CacheKeys
+
+	// FS is the filesystem the cache lives on, and Clock stamps its access
+	// records; nil means the host filesystem and the system clock.
+	FS    platform.FS
+	Clock platform.Clock
+
+	// accessMu serializes access record updates.
+	accessMu sync.Mutex
 }
 
 // NewFileCache creates a new filesystem-based cache.
@@ -87,53 +112,116 @@ func NewFileCache(cacheDir string) *FileCache {
 	return &FileCache{CacheDir: cacheDir}
 }
 
+func (c *FileCache) fs() platform.FS { return platform.FSOr(c.FS) }
+
 // Has checks if a cache entry exists for the given hash.
 func (c *FileCache) Has(hash TaskHash) (bool, error) {
-	entryDir := c.entryPath(hash)
-	metadataPath := filepath.Join(entryDir, "metadata.json")
+	_, ok, err := c.findEntry(hash)
+	return ok, err
+}
 
-	_, err := os.Stat(metadataPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
+// findEntry returns the directory holding the entry for hash: its entry path
+// or, for entries written before hashes were tagged, its legacy path.
+func (c *FileCache) findEntry(hash TaskHash) (string, bool, error) {
+	dirs := []string{c.entryPath(hash)}
+	if legacy := c.legacyEntryPath(hash); legacy != "" {
+		dirs = append(dirs, legacy)
+	}
+	for _, dir := range dirs {
+		_, err := c.fs().Stat(filepath.Join(dir, "metadata.json"))
+		if err == nil {
+			return dir, true, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", false, fmt.Errorf("checking cache entry: %w", err)
 		}
-		return false, fmt.Errorf("checking cache entry: %w", err)
 	}
+	return dirs[0], false, nil
+}
 
-	return true, nil
+// entryMetadata is the content of metadata.json.
+type entryMetadata struct {
+	CacheEntry
+	// KeyID identifies the key of an encrypted entry; empty when plain.
+	KeyID string `json:"key_id,omitempty"`
 }
 
 // Get retrieves a cache entry by hash.
 func (c *FileCache) Get(hash TaskHash) (*CacheEntry, error) {
-	entryDir := c.entryPath(hash)
+	entryDir, _, err := c.findEntry(hash)
+	if err != nil {
+		return nil, err
+	}
+	entry, _, err := c.readEntry(entryDir, hash)
+	if entry != nil {
+		c.recordAccess(entryDir, true)
+	}
+	return entry, err
+}
+
+// readEntry reads and decrypts the entry in entryDir, and returns the ID of
+// the key it was encrypted with. It returns nil when there is none.
+func (c *FileCache) readEntry(entryDir string, hash TaskHash) (*CacheEntry, string, error) {
 	metadataPath := filepath.Join(entryDir, "metadata.json")
 
 	// Read metadata
-	data, err := os.ReadFile(metadataPath)
+	data, err := c.fs().ReadFile(metadataPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return nil, "", nil
 		}
-		return nil, fmt.Errorf("reading cache metadata: %w", err)
+		return nil, "", fmt.Errorf("reading cache metadata: %w", err)
 	}
 
-	var entry CacheEntry
-	if err := json.Unmarshal(data, &entry); err != nil {
-		return nil, fmt.Errorf("parsing cache metadata: %w", err)
+	var meta entryMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, "", fmt.Errorf("parsing cache metadata: %w", err)
+	}
+	entry := meta.CacheEntry
+	// A legacy entry records the untagged hash.
+	entry.Hash = hash
+	decrypt := func(data []byte, field string) ([]byte, error) {
+		if meta.KeyID == "" {
+			return data, nil
+		}
+		plain, err := c.Keys.open(meta.KeyID, data, string(meta.Hash)+"/"+field)
+		if err != nil {
+			return nil, fmt.Errorf("cache entry %s: %w", hash, err)
+		}
+		return plain, nil
+	}
+	if entry.Stdout, err = decrypt(entry.Stdout, "stdout"); err != nil {
+		return nil, "", err
+	}
+	if entry.Stderr, err = decrypt(entry.Stderr, "stderr"); err != nil {
+		return nil, "", err
 	}
 
 	// Read artifact contents
 	artifactsDir := filepath.Join(entryDir, "artifacts")
 	for i := range entry.Artifacts {
 		blobPath := filepath.Join(artifactsDir, fmt.Sprintf("%d.blob", i))
-		content, err := os.ReadFile(blobPath)
+		content, err := c.fs().ReadFile(blobPath)
 		if err != nil {
-			return nil, fmt.Errorf("reading artifact %d: %w", i, err)
+			return nil, "", fmt.Errorf("reading artifact %d: %w", i, err)
+		}
+		if entry.Artifacts[i].Content, err = decrypt(content, fmt.Sprintf("artifacts/%d", i)); err != nil {
+			return nil, "", err
 		}
-		entry.Artifacts[i].Content = content
 	}
+	return &entry, meta.KeyID, nil
+}
 
-	return &entry, nil
+// PutIfAbsent stores entry unless the cache already holds an entry for its
+// hash, such as one written by another machine sharing the directory.
+func (c *FileCache) PutIfAbsent(entry *CacheEntry) (bool, error) {
+	if entry == nil {
+		return false, fmt.Errorf("cache entry is nil")
+	}
+	if ok, err := c.Has(entry.Hash); err != nil || ok {
+		return false, err
+	}
+	return true, c.Put(entry)
 }
 
 // Put stores a cache entry.
@@ -141,19 +229,31 @@ func (c *FileCache) Put(entry *CacheEntry) error {
 	if entry == nil {
 		return fmt.Errorf("cache entry is nil")
 	}
+	return c.writeEntry(c.entryPath(entry.Hash), entry)
+}
 
-	entryDir := c.entryPath(entry.Hash)
+// writeEntry stores entry, encrypted under the current key when c has keys,
+// as entryDir.
+func (c *FileCache) writeEntry(entryDir string, entry *CacheEntry) error {
 	parentDir := filepath.Dir(entryDir)
+	var keyID string
+	encrypt := func(data []byte, field string) ([]byte, error) {
+		if c.Keys == nil {
+			return data, nil
+		}
+		keyID = c.Keys.CurrentID()
+		return c.Keys.seal(data, string(entry.Hash)+"/"+field)
+	}
 
 	// Ensure parent exists so temp dir is created on the same filesystem.
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
+	if err := c.fs().MkdirAll(parentDir, 0755); err != nil {
 		return fmt.Errorf("creating cache directory: %w", err)
 	}
 
 	// Write into a temp entry dir, then rename into place.
 	// This prevents crashes from leaving corrupt metadata.json (or partial blobs)
 	// at the canonical entry path.
-	tmpDir, err := os.MkdirTemp(parentDir, "tmp-entry-"+string(entry.Hash)+"-")
+	tmpDir, err := c.fs().MkdirTemp(parentDir, "tmp-entry-"+string(entry.Hash)+"-")
 	if err != nil {
 		return fmt.Errorf("creating temp cache entry dir: %w", err)
 	}
@@ -162,18 +262,22 @@ func (c *FileCache) Put(entry *CacheEntry) error {
 		if committed {
 			return
 		}
-		_ = os.RemoveAll(tmpDir)
+		_ = c.fs().RemoveAll(tmpDir)
 	}()
 
 	artifactsDir := filepath.Join(tmpDir, "artifacts")
-	if err := os.MkdirAll(artifactsDir, 0755); err != nil {
+	if err := c.fs().MkdirAll(artifactsDir, 0755); err != nil {
 		return fmt.Errorf("creating cache artifacts dir: %w", err)
 	}
 
 	// Write artifact blobs first (so metadata only appears after blobs succeed).
 	for i, artifact := range entry.Artifacts {
+		blob, err := encrypt(artifact.Content, fmt.Sprintf("artifacts/%d", i))
+		if err != nil {
+			return err
+		}
 		blobPath := filepath.Join(artifactsDir, fmt.Sprintf("%d.blob", i))
-		if err := writeFileAtomic(blobPath, artifact.Content, 0644); err != nil {
+		if err := writeFileAtomic(c.fs(), blobPath, blob, 0644); err != nil {
 			return fmt.Errorf("writing artifact %d: %w", i, err)
 		}
 	}
@@ -181,50 +285,60 @@ func (c *FileCache) Put(entry *CacheEntry) error {
 	// Create metadata (without content to save space - content is in blobs)
 	metadata := CacheEntry{
 		Hash:     entry.Hash,
-		Stdout:   entry.Stdout,
-		Stderr:   entry.Stderr,
 		ExitCode: entry.ExitCode,
 		Artifacts: make([]CachedArtifact, len(entry.Artifacts)),
 	}
+	if metadata.Stdout, err = encrypt(entry.Stdout, "stdout"); err != nil {
+		return err
+	}
+	if metadata.Stderr, err = encrypt(entry.Stderr, "stderr"); err != nil {
+		return err
+	}
 	for i, a := range entry.Artifacts {
 		metadata.Artifacts[i] = CachedArtifact{
 			Path:    a.Path,
 			Content: nil, // Content stored in blob files
 		}
+		// Encryption authenticates the content, and a hash of the
+		// plaintext would let readers confirm guesses of it.
+		if keyID == "" {
+			metadata.Artifacts[i].SHA256 = sha256Hex(a.Content)
+		}
 	}
 
 	// Write metadata
-	data, err := json.MarshalIndent(metadata, "", "  ")
+	data, err := json.MarshalIndent(entryMetadata{CacheEntry: metadata, KeyID: keyID}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshaling cache metadata: %w", err)
 	}
 
 	metadataPath := filepath.Join(tmpDir, "metadata.json")
-	if err := writeFileAtomic(metadataPath, data, 0644); err != nil {
+	if err := writeFileAtomic(c.fs(), metadataPath, data, 0644); err != nil {
 		return fmt.Errorf("writing cache metadata: %w", err)
 	}
+	c.recordAccess(tmpDir, false)
 
 	// Best-effort remove of any existing entry; a crash between remove and rename
 	// yields a cache miss (safe), not corruption.
-	_ = os.RemoveAll(entryDir)
-	if err := os.Rename(tmpDir, entryDir); err != nil {
+	_ = c.fs().RemoveAll(entryDir)
+	if err := c.fs().Rename(tmpDir, entryDir); err != nil {
 		return fmt.Errorf("committing cache entry: %w", err)
 	}
 	committed = true
 	return nil
 }
 
-func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+func writeFileAtomic(fsys platform.FS, path string, data []byte, perm os.FileMode) error {
 	dir := filepath.Dir(path)
 	base := filepath.Base(path)
-	tmp, err := os.CreateTemp(dir, base+".tmp.*")
+	tmp, err := fsys.CreateTemp(dir, base+".tmp.*")
 	if err != nil {
 		return err
 	}
 	tmpName := tmp.Name()
 	defer func() {
 		_ = tmp.Close()
-		_ = os.Remove(tmpName)
+		_ = fsys.Remove(tmpName)
 	}()
 
 	if _, err := tmp.Write(data); err != nil {
@@ -237,18 +351,50 @@ func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
 	if err := tmp.Close(); err != nil {
 		return err
 	}
-	return os.Rename(tmpName, path)
+	return fsys.Rename(tmpName, path)
+}
+
+// Evict removes the entry for hash. Evicting a missing entry is not an error.
+func (c *FileCache) Evict(hash TaskHash) error {
+	for _, dir := range []string{c.entryPath(hash), c.legacyEntryPath(hash)} {
+		if dir == "" {
+			continue
+		}
+		if err := c.fs().RemoveAll(dir); err != nil {
+			return fmt.Errorf("removing cache entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// EntryDir returns the directory the entry for hash is written to.
+func (c *FileCache) EntryDir(hash TaskHash) string {
+	return c.entryPath(hash)
 }
 
 // entryPath returns the directory path for a cache entry.
-// Uses first 2 characters of hash as a prefix directory to avoid
+// Uses first 2 characters of the digest as a prefix directory to avoid
 // having too many entries in a single directory.
 func (c *FileCache) entryPath(hash TaskHash) string {
-	hashStr := string(hash)
-	if len(hashStr) < 2 {
-		return filepath.Join(c.CacheDir, hashStr)
+	name := string(hash.Algorithm()) + "-" + hash.Digest()
+	return c.shardedPath(hash.Digest(), name)
+}
+
+// legacyEntryPath returns the directory caches written before hashes were
+// tagged used for hash, or "" if they cannot hold it.
+func (c *FileCache) legacyEntryPath(hash TaskHash) string {
+	legacy := string(hash.Legacy())
+	if legacy == "" {
+		return ""
+	}
+	return c.shardedPath(legacy, legacy)
+}
+
+func (c *FileCache) shardedPath(digest, name string) string {
+	if len(digest) < 2 {
+		return filepath.Join(c.CacheDir, name)
 	}
-	return filepath.Join(c.CacheDir, hashStr[:2], hashStr)
+	return filepath.Join(c.CacheDir, digest[:2], name)
 }
 
 // MemoryCache implements Cache using in-memory storage.
@@ -290,6 +436,12 @@ func (c *MemoryCache) Put(entry *CacheEntry) error {
 	return nil
 }
 
+// Evict removes the entry for hash.
+func (c *MemoryCache) Evict(hash TaskHash) error {
+	delete(c.entries, hash)
+	return nil
+}
+
 // copyEntry creates a deep copy of a cache entry.
 func (c *MemoryCache) copyEntry(entry *CacheEntry) *CacheEntry {
 	copy := &CacheEntry{
@@ -308,6 +460,7 @@ func (c *MemoryCache) copyEntry(entry *CacheEntry) *CacheEntry {
 		copy.Artifacts[i] = CachedArtifact{
 			Path:    a.Path,
 			Content: make([]byte, len(a.Content)),
+			SHA256:  a.SHA256,
 		}
 		builtinCopy(copy.Artifacts[i].Content, a.Content)
 	}
@@ -0,0 +1,44 @@
+package core
+
+// BatchHaser is implemented by caches that can check for several entries at
+// once, in one index lookup or one round-trip to a remote store. It is
+// optional; HasBatch checks other caches entry by entry.
+type BatchHaser interface {
+	HasBatch(hashes []TaskHash) (map[TaskHash]bool, error)
+}
+
+// HasBatch reports which of hashes c holds, in a single batch when c is a
+// BatchHaser. Every hash is in the result.
+func HasBatch(c Cache, hashes []TaskHash) (map[TaskHash]bool, error) {
+	if b, ok := c.(BatchHaser); ok {
+		return b.HasBatch(hashes)
+	}
+	out := make(map[TaskHash]bool, len(hashes))
+	for _, h := range hashes {
+		ok, err := c.Has(h)
+		if err != nil {
+			return nil, err
+		}
+		out[h] = ok
+	}
+	return out, nil
+}
+
+// ConditionalPutter is implemented by caches that can store an entry only if
+// they do not hold one for its hash yet, so that runs writing the same
+// result to a shared cache upload it once. It is optional; PutIfAbsent
+// stores entries in other caches unconditionally.
+type ConditionalPutter interface {
+	// PutIfAbsent stores entry unless the cache already holds an entry for
+	// its hash, and reports whether it did.
+	PutIfAbsent(entry *CacheEntry) (stored bool, err error)
+}
+
+// PutIfAbsent stores entry in c, conditionally when c is a
+// ConditionalPutter.
+func PutIfAbsent(c Cache, entry *CacheEntry) (bool, error) {
+	if p, ok := c.(ConditionalPutter); ok {
+		return p.PutIfAbsent(entry)
+	}
+	return true, c.Put(entry)
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrCacheKeyMissing identifies a cache entry encrypted with a key that is
+// not among the configured cache keys.
+var ErrCacheKeyMissing = errors.New("cache key not available")
+
+// CacheKeySize is the size of a cache key: AES-256.
+const CacheKeySize = 32
+
+// CacheKeys are the keys a FileCache encrypts its entries with, using
+// AES-256-GCM. New entries are encrypted with the current key; older keys
+// still decrypt the entries written before a rotation until they are
+// re-encrypted (see FileCache.Rekey).
+type CacheKeys struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// ParseCacheKeys parses a key list: one base64-encoded 32-byte key per line,
+// the current key first. Blank lines and lines starting with # are ignored.
+func ParseCacheKeys(data []byte) (*CacheKeys, error) {
+	k := &CacheKeys{aeads: make(map[string]cipher.AEAD)}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil || len(key) != CacheKeySize {
+			return nil, fmt.Errorf("cache keys: line %d: not a base64-encoded %d-byte key", n, CacheKeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		id := CacheKeyID(key)
+		if k.current == "" {
+			k.current = id
+		}
+		k.aeads[id] = aead
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if k.current == "" {
+		return nil, errors.New("cache keys: no key")
+	}
+	return k, nil
+}
+
+// CacheKeyID identifies key in the entries it encrypted: the first 8 bytes
+// of its SHA-256, in hex.
+func CacheKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// CurrentID is the ID of the key new entries are encrypted with.
+func (k *CacheKeys) CurrentID() string { return k.current }
+
+// seal encrypts plain with the current key, prefixed by the nonce. aad binds
+// the ciphertext to the entry and field it was written for, so that blobs
+// cannot be swapped between entries unnoticed.
+func (k *CacheKeys) seal(plain []byte, aad string) ([]byte, error) {
+	aead := k.aeads[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("cache encryption nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plain, []byte(aad)), nil
+}
+
+// open decrypts data sealed with the key id. Data that does not decrypt was
+// altered: the error wraps ErrCacheCorruption.
+func (k *CacheKeys) open(id string, data []byte, aad string) ([]byte, error) {
+	var aead cipher.AEAD
+	if k != nil {
+		aead = k.aeads[id]
+	}
+	if aead == nil {
+		return nil, fmt.Errorf("%w: key %s", ErrCacheKeyMissing, id)
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: %s is truncated", ErrCacheCorruption, aad)
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s does not decrypt", ErrCacheCorruption, aad)
+	}
+	return plain, nil
+}
+
+// Rekey re-encrypts with the current key every entry encrypted with an older
+// key or written plain, and returns how many it rewrote. Access records are
+// kept. Entries whose key is no longer configured fail the rekey: the error
+// wraps ErrCacheKeyMissing.
+func (c *FileCache) Rekey() (int, error) {
+	if c.Keys == nil {
+		return 0, errors.New("rekey: no cache keys configured")
+	}
+	shards, err := c.fs().ReadDir(c.CacheDir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, shard := range shards {
+		if !shard.IsDir() {
+			continue
+		}
+		entries, err := c.fs().ReadDir(filepath.Join(c.CacheDir, shard.Name()))
+		if err != nil {
+			return n, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() || strings.HasPrefix(e.Name(), "tmp-entry-") {
+				continue
+			}
+			dir := filepath.Join(c.CacheDir, shard.Name(), e.Name())
+			data, err := c.fs().ReadFile(filepath.Join(dir, "metadata.json"))
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return n, err
+			}
+			var meta entryMetadata
+			if err := json.Unmarshal(data, &meta); err != nil {
+				return n, fmt.Errorf("parsing cache metadata of %s: %w", dir, err)
+			}
+			if meta.KeyID == c.Keys.CurrentID() {
+				continue
+			}
+			entry, _, err := c.readEntry(dir, meta.Hash)
+			if err != nil {
+				return n, err
+			}
+			access, accessErr := c.fs().ReadFile(filepath.Join(dir, accessFileName))
+			if err := c.writeEntry(dir, entry); err != nil {
+				return n, err
+			}
+			if accessErr == nil {
+				_ = writeFileAtomic(c.fs(), filepath.Join(dir, accessFileName), access, 0644)
+			}
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testCacheKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, CacheKeySize))
+}
+
+func mustCacheKeys(t *testing.T, keys ...string) *CacheKeys {
+	t.Helper()
+	k, err := ParseCacheKeys([]byte("# cache keys\n" + strings.Join(keys, "\n") + "\n"))
+	if err != nil {
+		t.Fatalf("ParseCacheKeys: %v", err)
+	}
+	return k
+}
+
+func TestParseCacheKeys_Rejects(t *testing.T) {
+	for _, bad := range []string{"", "# only a comment\n", "not base64\n", base64.StdEncoding.EncodeToString([]byte("short")) + "\n"} {
+		if _, err := ParseCacheKeys([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestFileCache_EncryptsEntries(t *testing.T) {
+	dir := t.TempDir()
+	c := NewFileCache(dir)
+	c.Keys = mustCacheKeys(t, testCacheKey(1))
+	entry := &CacheEntry{Hash: "sha256:abcd", Stdout: []byte("secret stdout"), ExitCode: 3, Artifacts: []CachedArtifact{{Path: "out.txt", Content: []byte("secret artifact")}}}
+	if err := c.Put(entry); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	entryDir := c.EntryDir(entry.Hash)
+	meta, _ := os.ReadFile(filepath.Join(entryDir, "metadata.json"))
+	blob, _ := os.ReadFile(filepath.Join(entryDir, "artifacts", "0.blob"))
+	if bytes.Contains(meta, []byte("secret")) || bytes.Contains(blob, []byte("secret")) {
+		t.Fatalf("plaintext on disk:\n%s\n%s", meta, blob)
+	}
+	if !bytes.Contains(meta, []byte(`"key_id": "`+c.Keys.CurrentID()+`"`)) {
+		t.Fatalf("metadata does not record the key ID:\n%s", meta)
+	}
+	got, err := c.Get(entry.Hash)
+	if err != nil || string(got.Stdout) != "secret stdout" || got.ExitCode != 3 || string(got.Artifacts[0].Content) != "secret artifact" {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+
+	if _, err := NewFileCache(dir).Get(entry.Hash); !errors.Is(err, ErrCacheKeyMissing) {
+		t.Fatalf("Get without keys: %v", err)
+	}
+	blob[len(blob)-1] ^= 1
+	if err := os.WriteFile(filepath.Join(entryDir, "artifacts", "0.blob"), blob, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(entry.Hash); !errors.Is(err, ErrCacheCorruption) {
+		t.Fatalf("Get of altered blob: %v", err)
+	}
+}
+
+func TestFileCache_Rekey(t *testing.T) {
+	dir := t.TempDir()
+	plain := NewFileCache(dir)
+	if err := plain.Put(&CacheEntry{Hash: "sha256:aa01", Stdout: []byte("plain")}); err != nil {
+		t.Fatal(err)
+	}
+	old := NewFileCache(dir)
+	old.Keys = mustCacheKeys(t, testCacheKey(1))
+	if err := old.Put(&CacheEntry{Hash: "sha256:bb02", Stdout: []byte("old")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a rotation the new key comes first; the old one still decrypts.
+	rotated := NewFileCache(dir)
+	rotated.Keys = mustCacheKeys(t, testCacheKey(2), testCacheKey(1))
+	if n, err := rotated.Rekey(); err != nil || n != 2 {
+		t.Fatalf("Rekey = %d, %v; want 2 entries rewritten", n, err)
+	}
+	if n, err := rotated.Rekey(); err != nil || n != 0 {
+		t.Fatalf("second Rekey = %d, %v", n, err)
+	}
+
+	// Once rekeyed, the old key can be dropped.
+	current := NewFileCache(dir)
+	current.Keys = mustCacheKeys(t, testCacheKey(2))
+	for hash, want := range map[TaskHash]string{"sha256:aa01": "plain", "sha256:bb02": "old"} {
+		got, err := current.Get(hash)
+		if err != nil || got == nil || string(got.Stdout) != want {
+			t.Fatalf("Get(%s) = %+v, %v", hash, got, err)
+		}
+	}
+	if _, err := old.Get("sha256:bb02"); !errors.Is(err, ErrCacheKeyMissing) {
+		t.Fatalf("Get with the retired key: %v", err)
+	}
+}
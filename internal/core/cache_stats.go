@@ -0,0 +1,258 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"scriptweaver/internal/platform"
+)
+
+// CacheStats counts how a run used the artifact cache.
+type CacheStats struct {
+	// Hits is the number of tasks replayed or restored from a cache entry.
+	Hits int `json:"hits"`
+	// Misses is the number of tasks executed and stored because no entry
+	// existed.
+	Misses int `json:"misses"`
+	// BytesRestored is the size of the stdout, stderr and artifacts of the
+	// entries hit.
+	BytesRestored int64 `json:"bytes_restored"`
+	// UploadsSkipped is the number of results not written to a shared tier
+	// because it already held them, such as results uploaded by a concurrent
+	// run; UploadBytesSaved is the size of what was not uploaded.
+	UploadsSkipped   int   `json:"uploads_skipped"`
+	UploadBytesSaved int64 `json:"upload_bytes_saved"`
+	// Transfer is the traffic to and from remote tiers.
+	Transfer TransferStats `json:"transfer"`
+}
+
+// TransferStats counts the traffic of a cache with remote tiers.
+type TransferStats struct {
+	// Requests is the number of requests sent, retries included.
+	Requests        int   `json:"requests"`
+	BytesUploaded   int64 `json:"bytes_uploaded"`
+	BytesDownloaded int64 `json:"bytes_downloaded"`
+	// Throttled is the time transfers were held back by bandwidth caps.
+	Throttled time.Duration `json:"throttled_ns"`
+}
+
+// Add adds the counts of o to s.
+func (s *TransferStats) Add(o TransferStats) {
+	s.Requests += o.Requests
+	s.BytesUploaded += o.BytesUploaded
+	s.BytesDownloaded += o.BytesDownloaded
+	s.Throttled += o.Throttled
+}
+
+// TransferCounter is implemented by caches that transfer entries over the
+// network and count their traffic.
+type TransferCounter interface {
+	TransferStats() TransferStats
+}
+
+// HitRate is the fraction of lookups that hit, or 0 when there were none.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Add adds the counts of o to s.
+func (s *CacheStats) Add(o CacheStats) {
+	s.Hits += o.Hits
+	s.Misses += o.Misses
+	s.BytesRestored += o.BytesRestored
+	s.UploadsSkipped += o.UploadsSkipped
+	s.UploadBytesSaved += o.UploadBytesSaved
+	s.Transfer.Add(o.Transfer)
+}
+
+// CacheLookup records how a run used the cache entry of one task hash.
+type CacheLookup struct {
+	// Hit is true when the entry was read, false when it was stored.
+	Hit bool
+	// Tier is the index of the tier the entry was read from; 0 is the local
+	// cache, and the only tier of a cache that is not a TierGetter.
+	Tier int
+	// Bytes is the size of the stdout, stderr and artifacts of the entry
+	// read.
+	Bytes int64
+}
+
+// StatsCache counts the hits and misses of the cache it wraps. A Get that
+// returns an entry is a hit; a Put, which stores the result of an executed
+// task, is a miss. Has is not counted, since planning probes entries that a
+// run may never use.
+type StatsCache struct {
+	Cache
+
+	mu      sync.Mutex
+	stats   CacheStats
+	lookups map[TaskHash]CacheLookup
+}
+
+// NewStatsCache wraps c.
+func NewStatsCache(c Cache) *StatsCache {
+	return &StatsCache{Cache: c}
+}
+
+// Get retrieves the entry for hash, counting a hit when there is one.
+func (c *StatsCache) Get(hash TaskHash) (*CacheEntry, error) {
+	entry, _, err := c.GetTier(hash)
+	return entry, err
+}
+
+// GetTier is Get that also returns the tier the entry was read from.
+func (c *StatsCache) GetTier(hash TaskHash) (*CacheEntry, int, error) {
+	var entry *CacheEntry
+	var tier int
+	var err error
+	if tg, ok := c.Cache.(TierGetter); ok {
+		entry, tier, err = tg.GetTier(hash)
+	} else {
+		entry, err = c.Cache.Get(hash)
+	}
+	if err != nil || entry == nil {
+		return entry, tier, err
+	}
+	size := entry.size()
+	c.mu.Lock()
+	c.stats.Hits++
+	c.stats.BytesRestored += size
+	c.record(hash, CacheLookup{Hit: true, Tier: tier, Bytes: size})
+	c.mu.Unlock()
+	return entry, tier, nil
+}
+
+// Put stores entry, counting a miss and the uploads the wrapped cache
+// skipped when it is a SavingPutter.
+func (c *StatsCache) Put(entry *CacheEntry) error {
+	var saved int64
+	if sp, ok := c.Cache.(SavingPutter); ok {
+		var err error
+		if saved, err = sp.PutSaving(entry); err != nil {
+			return err
+		}
+	} else if err := c.Cache.Put(entry); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.stats.Misses++
+	if saved > 0 {
+		c.stats.UploadsSkipped++
+		c.stats.UploadBytesSaved += saved
+	}
+	c.record(entry.Hash, CacheLookup{})
+	c.mu.Unlock()
+	return nil
+}
+
+// record sets the lookup of hash; c.mu is held.
+func (c *StatsCache) record(hash TaskHash, l CacheLookup) {
+	if c.lookups == nil {
+		c.lookups = make(map[TaskHash]CacheLookup)
+	}
+	c.lookups[hash] = l
+}
+
+// Lookup returns how the entry for hash was last used. ok is false when it
+// was neither read nor stored.
+func (c *StatsCache) Lookup(hash TaskHash) (l CacheLookup, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok = c.lookups[hash]
+	return l, ok
+}
+
+// HasBatch forwards to the wrapped cache; like Has, it is not counted.
+func (c *StatsCache) HasBatch(hashes []TaskHash) (map[TaskHash]bool, error) {
+	return HasBatch(c.Cache, hashes)
+}
+
+// Evict forwards to the wrapped cache when it supports eviction.
+func (c *StatsCache) Evict(hash TaskHash) error {
+	ev, ok := c.Cache.(CacheEvicter)
+	if !ok {
+		return nil
+	}
+	return ev.Evict(hash)
+}
+
+// Stats returns the counts so far, with the traffic of the wrapped cache
+// when it is a TransferCounter.
+func (c *StatsCache) Stats() CacheStats {
+	c.mu.Lock()
+	stats := c.stats
+	c.mu.Unlock()
+	if tc, ok := c.Cache.(TransferCounter); ok {
+		stats.Transfer = tc.TransferStats()
+	}
+	return stats
+}
+
+func (e *CacheEntry) size() int64 {
+	n := int64(len(e.Stdout) + len(e.Stderr))
+	for _, a := range e.Artifacts {
+		n += int64(len(a.Content))
+	}
+	return n
+}
+
+// accessFileName is the file in a FileCache entry directory that records how
+// the entry is used. It is kept apart from metadata.json because it changes on
+// every read and, like execution timestamps, is no part of the cached result.
+const accessFileName = "access.json"
+
+// EntryAccess records how often and how recently a cache entry was read, so
+// that garbage collection can evict the least recently used entries.
+type EntryAccess struct {
+	// Hits is the number of times the entry was read.
+	Hits int64 `json:"hits"`
+	// LastAccess is when the entry was last read, or written if it never was.
+	LastAccess time.Time `json:"last_access"`
+}
+
+// Access returns the access record of the entry for hash. ok is false when
+// the entry does not exist; an entry written before access records existed
+// reports the modification time of its metadata.
+func (c *FileCache) Access(hash TaskHash) (access EntryAccess, ok bool, err error) {
+	entryDir := c.entryPath(hash)
+	info, err := c.fs().Stat(filepath.Join(entryDir, "metadata.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return EntryAccess{}, false, nil
+		}
+		return EntryAccess{}, false, fmt.Errorf("checking cache entry: %w", err)
+	}
+	data, err := c.fs().ReadFile(filepath.Join(entryDir, accessFileName))
+	if err != nil || json.Unmarshal(data, &access) != nil {
+		return EntryAccess{LastAccess: info.ModTime().UTC()}, true, nil
+	}
+	return access, true, nil
+}
+
+// recordAccess updates the access record in entryDir; hit counts a read.
+// It is best-effort: a read-only shared cache simply keeps no records.
+func (c *FileCache) recordAccess(entryDir string, hit bool) {
+	c.accessMu.Lock()
+	defer c.accessMu.Unlock()
+	var access EntryAccess
+	path := filepath.Join(entryDir, accessFileName)
+	if data, err := c.fs().ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &access)
+	}
+	if hit {
+		access.Hits++
+	}
+	access.LastAccess = platform.ClockOr(c.Clock).Now().UTC()
+	data, err := json.Marshal(access)
+	if err != nil {
+		return
+	}
+	_ = writeFileAtomic(c.fs(), path, data, 0644)
+}
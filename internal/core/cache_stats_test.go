@@ -0,0 +1,119 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"scriptweaver/internal/platform"
+)
+
+func TestStatsCache_CountsHitsAndMisses(t *testing.T) {
+	c := NewStatsCache(NewMemoryCache())
+	if err := c.Put(&CacheEntry{Hash: "h1", Stdout: []byte("out"), Artifacts: []CachedArtifact{{Path: "a", Content: []byte("12345")}}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if entry, err := c.Get("h1"); err != nil || entry == nil {
+			t.Fatalf("Get = %+v, %v", entry, err)
+		}
+	}
+	if entry, _ := c.Get("missing"); entry != nil {
+		t.Fatalf("Get(missing) = %+v", entry)
+	}
+	want := CacheStats{Hits: 2, Misses: 1, BytesRestored: 16}
+	if got := c.Stats(); got != want {
+		t.Fatalf("Stats = %+v, want %+v", got, want)
+	}
+	if r := want.HitRate(); r < 0.66 || r > 0.67 {
+		t.Fatalf("HitRate = %v", r)
+	}
+	if err := c.Evict("h1"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.Has("h1"); ok {
+		t.Fatalf("Evict did not reach the wrapped cache")
+	}
+}
+
+// remoteCache is a MemoryCache that reports fixed traffic.
+type remoteCache struct {
+	*MemoryCache
+	stats TransferStats
+}
+
+func (c remoteCache) TransferStats() TransferStats { return c.stats }
+
+func TestStatsCache_SumsTransferStatsOfTiers(t *testing.T) {
+	a := remoteCache{NewMemoryCache(), TransferStats{Requests: 2, BytesUploaded: 10, Throttled: time.Second}}
+	b := remoteCache{NewMemoryCache(), TransferStats{Requests: 1, BytesDownloaded: 5}}
+	tiered, err := NewTieredCache(WriteAll, NewMemoryCache(), a, b)
+	if err != nil {
+		t.Fatalf("NewTieredCache: %v", err)
+	}
+	want := TransferStats{Requests: 3, BytesUploaded: 10, BytesDownloaded: 5, Throttled: time.Second}
+	if got := NewStatsCache(tiered).Stats().Transfer; got != want {
+		t.Fatalf("Transfer = %+v, want %+v", got, want)
+	}
+}
+
+func TestStatsCache_RecordsTierOfEachLookup(t *testing.T) {
+	local, shared := NewMemoryCache(), NewMemoryCache()
+	if err := shared.Put(&CacheEntry{Hash: "h1", Stdout: []byte("out")}); err != nil {
+		t.Fatal(err)
+	}
+	tiered, err := NewTieredCache(WriteLocal, local, shared)
+	if err != nil {
+		t.Fatalf("NewTieredCache: %v", err)
+	}
+	c := NewStatsCache(tiered)
+	if _, err := c.Get("h1"); err != nil {
+		t.Fatal(err)
+	}
+	if l, ok := c.Lookup("h1"); !ok || l != (CacheLookup{Hit: true, Tier: 1, Bytes: 3}) {
+		t.Fatalf("first read: %+v, %v", l, ok)
+	}
+	// The entry was copied locally, so the next read is answered there.
+	if _, err := c.Get("h1"); err != nil {
+		t.Fatal(err)
+	}
+	if l, _ := c.Lookup("h1"); l.Tier != 0 {
+		t.Fatalf("second read: %+v", l)
+	}
+	if err := c.Put(&CacheEntry{Hash: "h2"}); err != nil {
+		t.Fatal(err)
+	}
+	if l, ok := c.Lookup("h2"); !ok || l.Hit {
+		t.Fatalf("stored: %+v, %v", l, ok)
+	}
+	if _, ok := c.Lookup("missing"); ok {
+		t.Fatalf("lookup recorded for an entry never used")
+	}
+}
+
+func TestFileCache_RecordsAccess(t *testing.T) {
+	c := NewFileCache(t.TempDir())
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := platform.NewFakeClock(now)
+	c.Clock = clock
+
+	if _, ok, err := c.Access("h1"); err != nil || ok {
+		t.Fatalf("Access before Put: ok=%v err=%v", ok, err)
+	}
+	if err := c.Put(&CacheEntry{Hash: "h1"}); err != nil {
+		t.Fatal(err)
+	}
+	if a, ok, err := c.Access("h1"); err != nil || !ok || a.Hits != 0 || !a.LastAccess.Equal(now) {
+		t.Fatalf("Access after Put = %+v, %v, %v", a, ok, err)
+	}
+
+	clock.Advance(time.Hour)
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get("h1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a, _, _ := c.Access("h1"); a.Hits != 3 || !a.LastAccess.Equal(now) {
+		t.Fatalf("Access after reads = %+v", a)
+	}
+}
@@ -2,9 +2,12 @@ package core
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"scriptweaver/internal/platform"
 )
 
 // TestCache_SameHashPreventsReExecution verifies tdd.md#Test-2:
@@ -186,7 +189,7 @@ func TestFileCache_PersistsToFilesystem(t *testing.T) {
 	cache := NewFileCache(tmpDir)
 
 	entry := &CacheEntry{
-		Hash:     TaskHash("abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890"),
+		Hash:     TaskHash("sha256:abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890"),
 		Stdout:   []byte("stdout content"),
 		Stderr:   []byte("stderr content"),
 		ExitCode: 0,
@@ -201,7 +204,7 @@ func TestFileCache_PersistsToFilesystem(t *testing.T) {
 	}
 
 	// Verify directory structure
-	entryDir := filepath.Join(tmpDir, "ab", string(entry.Hash))
+	entryDir := filepath.Join(tmpDir, "ab", "sha256-abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890")
 	if _, err := os.Stat(entryDir); os.IsNotExist(err) {
 		t.Error("entry directory not created")
 	}
@@ -307,7 +310,7 @@ func TestCache_NoTimestampsStored(t *testing.T) {
 	}
 
 	// Read the metadata file directly
-	metadataPath := filepath.Join(tmpDir, "te", "test", "metadata.json")
+	metadataPath := filepath.Join(tmpDir, "te", "sha256-test", "metadata.json")
 	data, err := os.ReadFile(metadataPath)
 	if err != nil {
 		t.Fatalf("failed to read metadata: %v", err)
@@ -378,3 +381,39 @@ func TestFileCache_PutIsCrashSafe_ReplacesCorruptEntry(t *testing.T) {
 		t.Fatalf("artifact mismatch")
 	}
 }
+
+// FuzzFileCache_PutIsAtomicUnderFaults fails the nth filesystem operation of
+// a Put and checks that the entry is then either complete or absent.
+func FuzzFileCache_PutIsAtomicUnderFaults(f *testing.F) {
+	for n := 0; n < 24; n++ {
+		f.Add(n, []byte("artifact"))
+	}
+	f.Fuzz(func(t *testing.T, n int, content []byte) {
+		dir := t.TempDir()
+		ops := 0
+		faulty := NewFileCache(dir)
+		faulty.FS = platform.FaultFS{Fault: func(op, name string) error {
+			ops++
+			if ops == n {
+				return errors.New("injected " + op + " failure")
+			}
+			return nil
+		}}
+		entry := &CacheEntry{Hash: "h1", Stdout: []byte("out"), Artifacts: []CachedArtifact{{Path: "a.txt", Content: content}}}
+		putErr := faulty.Put(entry)
+
+		got, err := NewFileCache(dir).Get("h1")
+		if err != nil {
+			t.Fatalf("Get after faulty Put: %v", err)
+		}
+		if got == nil {
+			if putErr == nil {
+				t.Fatalf("Put succeeded but the entry is missing")
+			}
+			return
+		}
+		if !bytes.Equal(got.Stdout, entry.Stdout) || len(got.Artifacts) != 1 || !bytes.Equal(got.Artifacts[0].Content, content) {
+			t.Fatalf("partial entry after Put error %v: %+v", putErr, got)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+// Package core defines the domain models for deterministic task execution.
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCacheCorruption identifies a cache entry whose artifacts no longer match
+// the hashes recorded in its manifest.
+var ErrCacheCorruption = errors.New("cache corruption")
+
+// CacheCorruptionError reports a single artifact that failed re-hash
+// verification during restore.
+type CacheCorruptionError struct {
+	// Hash is the cache entry the artifact belongs to.
+	Hash TaskHash
+
+	// Path is the artifact path as recorded in the manifest.
+	Path string
+
+	// Want is the SHA-256 recorded in the manifest; Got is what was observed.
+	Want string
+	Got  string
+}
+
+func (e *CacheCorruptionError) Error() string {
+	if e == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s: entry %s artifact %q: expected sha256 %s, got %s", ErrCacheCorruption, e.Hash, e.Path, e.Want, e.Got)
+}
+
+func (e *CacheCorruptionError) Unwrap() error { return ErrCacheCorruption }
+
+// CacheEvicter is implemented by caches that can drop individual entries.
+// It is optional; caches without it keep corrupt entries but still fail restore.
+type CacheEvicter interface {
+	Evict(hash TaskHash) error
+}
+
+// EvictOnCorruption evicts the entry for hash when err reports cache
+// corruption, so the next run re-executes the task instead of replaying bad
+// artifacts again. err is returned unchanged (eviction failures are joined).
+func EvictOnCorruption(cache Cache, hash TaskHash, err error) error {
+	if err == nil || !errors.Is(err, ErrCacheCorruption) {
+		return err
+	}
+	ev, ok := cache.(CacheEvicter)
+	if !ok {
+		return err
+	}
+	if evErr := ev.Evict(hash); evErr != nil {
+		return errors.Join(err, fmt.Errorf("evicting corrupt cache entry %s: %w", hash, evErr))
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunner_TamperedCacheBlob_FailsWithCorruptionAndEvicts(t *testing.T) {
+	workDir := t.TempDir()
+	cacheDir := t.TempDir()
+	cache := NewFileCache(cacheDir)
+	runner := NewRunner(workDir, cache)
+
+	task := &Task{Name: "A", Run: "printf hello > foo.txt", Outputs: []string{"foo.txt"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := runner.Run(ctx, task)
+	if err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+
+	// Poison the stored blob and drop the workspace copy so restore must use it.
+	blob := filepath.Join(cache.entryPath(res.Hash), "artifacts", "0.blob")
+	if err := os.WriteFile(blob, []byte("evil"), 0o644); err != nil {
+		t.Fatalf("tamper blob: %v", err)
+	}
+	if err := os.Remove(filepath.Join(workDir, "foo.txt")); err != nil {
+		t.Fatalf("remove output: %v", err)
+	}
+
+	_, err = runner.Run(ctx, task)
+	if !errors.Is(err, ErrCacheCorruption) {
+		t.Fatalf("expected cache corruption error, got %v", err)
+	}
+	var ce *CacheCorruptionError
+	if !errors.As(err, &ce) || ce.Path != "foo.txt" || ce.Hash != res.Hash {
+		t.Fatalf("unexpected corruption details: %v", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(workDir, "foo.txt")); !os.IsNotExist(statErr) {
+		t.Fatalf("corrupt artifact must not be restored (stat err=%v)", statErr)
+	}
+
+	if exists, _ := cache.Has(res.Hash); exists {
+		t.Fatalf("corrupt entry must be evicted")
+	}
+
+	// After eviction the task re-executes cleanly.
+	res3, err := runner.Run(ctx, task)
+	if err != nil {
+		t.Fatalf("third run failed: %v", err)
+	}
+	if res3.FromCache {
+		t.Fatalf("expected re-execution after eviction")
+	}
+}
+
+func TestFileCache_PutRecordsArtifactManifestHash(t *testing.T) {
+	cache := NewFileCache(t.TempDir())
+	entry := &CacheEntry{Hash: "abcd", Artifacts: []CachedArtifact{{Path: "x", Content: []byte("hello")}}}
+	if err := cache.Put(entry); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	got, err := cache.Get("abcd")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Artifacts[0].SHA256 != sha256Hex([]byte("hello")) {
+		t.Fatalf("manifest hash = %q", got.Artifacts[0].SHA256)
+	}
+}
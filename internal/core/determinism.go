@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+)
+
+// DeterminismResult reports whether two fresh executions of a task agreed.
+type DeterminismResult struct {
+	// Name is the task name.
+	Name string
+
+	// Hash is the computed task hash (identical for both executions).
+	Hash TaskHash
+
+	// Differences lists what differed between the executions, sorted:
+	// "exit_code", "stderr", "stdout", or "artifact:<path>".
+	// Empty means the task behaved deterministically.
+	Differences []string
+}
+
+// Deterministic reports whether both executions produced identical results.
+func (d *DeterminismResult) Deterministic() bool {
+	return d != nil && len(d.Differences) == 0
+}
+
+// VerifyDeterminism executes a task twice, bypassing the cache, and compares
+// the results.
+//
+// Before each execution the declared outputs are removed so the second run
+// cannot observe artifacts of the first. Artifacts are compared by the hash of
+// their harvested (normalized) content. The cache is neither read nor written.
+// When the task's outputs exist beforehand, as after the run being verified,
+// they are put back afterwards, so the workspace keeps the result that was
+// cached; otherwise it is left holding those of the second execution.
+//
+// Typical offenders are tasks embedding timestamps, random file names or
+// unordered directory listings in their outputs; such tasks silently poison
+// caching because their hash does not determine their result.
+func (r *Runner) VerifyDeterminism(ctx context.Context, task *Task) (*DeterminismResult, error) {
+	if err := r.validateTask(task); err != nil {
+		return nil, err
+	}
+
+	inputSet, err := r.Resolver.Resolve(task.Inputs)
+	if err != nil {
+		return nil, fmt.Errorf("resolving inputs: %w", err)
+	}
+	hash := r.Hasher.ComputeHash(task.HashInput(inputSet, r.WorkingDir))
+
+	kept, keepErr := r.harvestArtifacts(task.Outputs)
+
+	first, err := r.fingerprintExecution(ctx, task, hash)
+	if err != nil {
+		return nil, fmt.Errorf("first execution: %w", err)
+	}
+	second, err := r.fingerprintExecution(ctx, task, hash)
+	if err != nil {
+		return nil, fmt.Errorf("second execution: %w", err)
+	}
+
+	if keepErr == nil {
+		if err := r.CleanArtifacts(task.Outputs); err != nil {
+			return nil, err
+		}
+		if _, err := r.Replayer.RestoreArtifacts(task.Name, &CacheEntry{Hash: hash, Artifacts: kept}); err != nil {
+			return nil, fmt.Errorf("restoring outputs: %w", err)
+		}
+	}
+
+	return &DeterminismResult{Name: task.Name, Hash: hash, Differences: first.diff(second)}, nil
+}
+
+// executionFingerprint captures the comparable outcome of one execution.
+type executionFingerprint struct {
+	stdout    []byte
+	stderr    []byte
+	exitCode  int
+	artifacts map[string][sha256.Size]byte
+}
+
+func (r *Runner) fingerprintExecution(ctx context.Context, task *Task, hash TaskHash) (*executionFingerprint, error) {
+	if err := r.CleanArtifacts(task.Outputs); err != nil {
+		return nil, err
+	}
+	ex, err := r.executorFor(task)
+	if err != nil {
+		return nil, err
+	}
+	res, err := ex.Execute(ctx, task, hash)
+	if err != nil {
+		return nil, fmt.Errorf("executing task: %w", err)
+	}
+
+	fp := &executionFingerprint{
+		stdout:    res.Stdout,
+		stderr:    res.Stderr,
+		exitCode:  res.ExitCode,
+		artifacts: make(map[string][sha256.Size]byte),
+	}
+	if r.Normalizer != nil {
+		fp.stdout = r.Normalizer.Normalize(fp.stdout)
+		fp.stderr = r.Normalizer.Normalize(fp.stderr)
+	}
+
+	// Mirror executeAndCache: failed executions do not produce artifacts.
+	if res.ExitCode == 0 && len(task.Outputs) > 0 {
+		set, err := r.Harvester.Harvest(task.Outputs)
+		if err != nil {
+			return nil, fmt.Errorf("harvesting artifacts: %w", err)
+		}
+		for _, a := range set.Artifacts {
+			fp.artifacts[a.Path] = sha256.Sum256(a.Content)
+		}
+	}
+	return fp, nil
+}
+
+func (f *executionFingerprint) diff(other *executionFingerprint) []string {
+	var out []string
+	if f.exitCode != other.exitCode {
+		out = append(out, "exit_code")
+	}
+	if !bytes.Equal(f.stderr, other.stderr) {
+		out = append(out, "stderr")
+	}
+	if !bytes.Equal(f.stdout, other.stdout) {
+		out = append(out, "stdout")
+	}
+
+	var changed []string
+	for p, h := range f.artifacts {
+		if oh, ok := other.artifacts[p]; !ok || oh != h {
+			changed = append(changed, p)
+		}
+	}
+	for p := range other.artifacts {
+		if _, ok := f.artifacts[p]; !ok {
+			changed = append(changed, p)
+		}
+	}
+	sort.Strings(changed)
+	for _, p := range changed {
+		out = append(out, "artifact:"+p)
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunner_VerifyDeterminism_StableTaskHasNoDifferences(t *testing.T) {
+	tmpDir := t.TempDir()
+	cache := NewMemoryCache()
+	runner := NewRunner(tmpDir, cache)
+
+	task := &Task{Name: "stable", Run: "echo ok > out.txt; echo done", Outputs: []string{"out.txt"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := runner.VerifyDeterminism(ctx, task)
+	if err != nil {
+		t.Fatalf("VerifyDeterminism: %v", err)
+	}
+	if !res.Deterministic() {
+		t.Fatalf("expected deterministic task, got differences %v", res.Differences)
+	}
+	if res.Name != "stable" || res.Hash == "" {
+		t.Fatalf("unexpected result identity: %+v", res)
+	}
+	if exists, _ := cache.Has(res.Hash); exists {
+		t.Fatalf("verification must not write to the cache")
+	}
+}
+
+func TestRunner_VerifyDeterminism_ReportsDifferingOutputs(t *testing.T) {
+	tmpDir := t.TempDir()
+	runner := NewRunner(tmpDir, NewMemoryCache())
+
+	// $$ is the shell PID, which differs between executions.
+	task := &Task{Name: "pid", Run: "echo $$ > out.txt; echo $$", Outputs: []string{"out.txt"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := runner.VerifyDeterminism(ctx, task)
+	if err != nil {
+		t.Fatalf("VerifyDeterminism: %v", err)
+	}
+	want := []string{"stdout", "artifact:out.txt"}
+	if !reflect.DeepEqual(res.Differences, want) {
+		t.Fatalf("differences = %v, want %v", res.Differences, want)
+	}
+}
+
+func TestRunner_VerifyDeterminism_RestoresOutputsOfVerifiedRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	runner := NewRunner(tmpDir, NewMemoryCache())
+
+	task := &Task{Name: "pid", Run: "mkdir -p out; echo $$ > out/pid.txt", Outputs: []string{"out"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := runner.Run(ctx, task); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	before, err := os.ReadFile(filepath.Join(tmpDir, "out", "pid.txt"))
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if _, err := runner.VerifyDeterminism(ctx, task); err != nil {
+		t.Fatalf("VerifyDeterminism: %v", err)
+	}
+	after, err := os.ReadFile(filepath.Join(tmpDir, "out", "pid.txt"))
+	if err != nil || string(after) != string(before) {
+		t.Fatalf("output after verification = %q (%v), want %q", after, err, before)
+	}
+}
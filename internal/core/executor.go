@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os/exec"
 	"syscall"
+
+	"scriptweaver/internal/platform"
 )
 
 // ExecutionResult contains the results of a task execution.
@@ -34,6 +36,12 @@ type ExecutionResult struct {
 	Hash TaskHash
 }
 
+// TaskExecutor runs a single task's command. Executor is the built-in local
+// implementation; plugins provide others, selected by Task.Runner.
+type TaskExecutor interface {
+	Execute(ctx context.Context, task *Task, hash TaskHash) (*ExecutionResult, error)
+}
+
 // Executor runs tasks in an isolated, deterministic environment.
 //
 // From spec.md Deterministic Guarantees:
@@ -58,6 +66,8 @@ func NewExecutor(workingDir string) *Executor {
 //   - ONLY variables declared in task.Env are visible to the command.
 //   - Host environment variables (HOME, USER, PATH, etc.) are NOT passed through.
 //   - If PATH is not in env, the task sees no PATH.
+//   - LANG, LC_ALL and TZ are fixed (see NormalizedEnv) unless declared.
+//   - Tasks with network set to false run in a new network namespace.
 //
 // This is an ALLOWLIST approach: the environment starts empty and only
 // declared variables are added.
@@ -79,11 +89,15 @@ func (e *Executor) Execute(ctx context.Context, task *Task, hash TaskHash) (*Exe
 
 	// CRITICAL: Build environment from ALLOWLIST only
 	// Start with EMPTY environment, NOT os.Environ()
-	// Only add variables explicitly declared in task.Env
-	cmd.Env = buildIsolatedEnv(task.Env)
+	// Only add variables explicitly declared in task.Env, plus the fixed
+	// locale and timezone the task did not declare
+	cmd.Env = buildIsolatedEnv(EffectiveEnv(task.Env))
 
 	// Set process group so we can kill the entire process tree on cancellation
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if !task.NetworkAllowed() {
+		platform.IsolateNetwork(cmd)
+	}
 
 	// Capture stdout and stderr
 	var stdout, stderr bytes.Buffer
@@ -92,6 +106,10 @@ func (e *Executor) Execute(ctx context.Context, task *Task, hash TaskHash) (*Exe
 
 	// Start the command
 	if err := cmd.Start(); err != nil {
+		if !task.NetworkAllowed() {
+			// Never fall back to running with network access.
+			return nil, fmt.Errorf("failed to start command without network access: %w", err)
+		}
 		return nil, fmt.Errorf("failed to start command: %w", err)
 	}
 
@@ -134,6 +152,29 @@ func (e *Executor) Execute(ctx context.Context, task *Task, hash TaskHash) (*Exe
 	}, nil
 }
 
+// NormalizedEnv returns the locale and timezone variables every task process
+// runs with, so that output artifacts do not vary with the machine's locale or
+// timezone. A task opts out of a variable by declaring it in its env; the
+// declared value is then part of the task hash like any other variable. The
+// normalized values are fixed, so they are not hashed.
+func NormalizedEnv() map[string]string {
+	return map[string]string{
+		"LANG":   "C",
+		"LC_ALL": "C",
+		"TZ":     "UTC",
+	}
+}
+
+// EffectiveEnv returns the environment of a task that declares env: env plus
+// every NormalizedEnv variable it does not declare.
+func EffectiveEnv(env map[string]string) map[string]string {
+	out := NormalizedEnv()
+	for k, v := range env {
+		out[k] = v
+	}
+	return out
+}
+
 // buildIsolatedEnv constructs an isolated environment from the declared variables.
 //
 // CRITICAL: This uses an ALLOWLIST approach.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -599,3 +600,55 @@ func TestExecute_HostEnvCompletelyIsolated(t *testing.T) {
 		t.Errorf("allowed variable not visible: %s", stdout)
 	}
 }
+
+// TestExecute_NormalizesLocaleAndTimezone verifies that tasks see the fixed
+// locale and timezone unless they declare their own.
+func TestExecute_NormalizesLocaleAndTimezone(t *testing.T) {
+	t.Setenv("TZ", "America/New_York")
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+	executor := NewExecutor(t.TempDir())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	task := &Task{Name: "locale", Run: `printf '%s|%s|%s' "$LANG" "$LC_ALL" "$TZ"`}
+	result, err := executor.Execute(ctx, task, TaskHash("test-hash"))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := string(result.Stdout); got != "C|C|UTC" {
+		t.Errorf("normalized env = %q, want %q", got, "C|C|UTC")
+	}
+
+	task.Env = map[string]string{"TZ": "Europe/Paris"}
+	result, err = executor.Execute(ctx, task, TaskHash("test-hash"))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := string(result.Stdout); got != "C|C|Europe/Paris" {
+		t.Errorf("overridden env = %q, want %q", got, "C|C|Europe/Paris")
+	}
+}
+
+// TestExecute_NetworkFalseRunsWithoutInterfaces verifies that a task declared
+// with network: false sees only a loopback interface.
+func TestExecute_NetworkFalseRunsWithoutInterfaces(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("network isolation is only enforced on Linux")
+	}
+	executor := NewExecutor(t.TempDir())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	noNetwork := false
+	task := &Task{Name: "hermetic", Run: "tail -n +3 /proc/net/dev | cut -d: -f1 | tr -d ' '", Network: &noNetwork}
+	result, err := executor.Execute(ctx, task, TaskHash("test-hash"))
+	if err != nil {
+		if strings.Contains(err.Error(), "without network access") {
+			t.Skipf("network namespaces unavailable: %v", err)
+		}
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := strings.TrimSpace(string(result.Stdout)); got != "lo" {
+		t.Errorf("interfaces = %q, want only lo", got)
+	}
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Fetch declares a fetch task: a download of URL to the task's single
+// declared output, instead of a run command. SHA256 pins the expected content;
+// a fetch without it is unpinned and accepts whatever the URL serves.
+type Fetch struct {
+	URL    string `json:"url" yaml:"url"`
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+}
+
+var fetchSHA256 = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Key identifies the fetched artifact: "sha256:<hex>" for a pinned fetch, so
+// that it is cached by content whatever mirror serves it, or "url:<url>" for
+// an unpinned one. It is "" for a nil Fetch.
+func (f *Fetch) Key() string {
+	switch {
+	case f == nil:
+		return ""
+	case f.SHA256 != "":
+		return "sha256:" + f.SHA256
+	default:
+		return "url:" + f.URL
+	}
+}
+
+// ValidateFetch checks the declaration of a fetch task: an http(s) URL, a
+// well-formed pin if any, exactly one output and no run command or runner. It
+// returns nil for tasks that do not fetch.
+func (t Task) ValidateFetch() error {
+	f := t.Fetch
+	if f == nil {
+		return nil
+	}
+	u, err := url.Parse(f.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("fetch url %q must be an http or https URL", f.URL)
+	}
+	if f.SHA256 != "" && !fetchSHA256.MatchString(f.SHA256) {
+		return fmt.Errorf("fetch sha256 %q must be 64 lowercase hex digits", f.SHA256)
+	}
+	if t.Run != "" {
+		return fmt.Errorf("a fetch task cannot declare run")
+	}
+	if t.Runner != "" {
+		return fmt.Errorf("a fetch task cannot declare a runner")
+	}
+	if !t.NetworkAllowed() {
+		return fmt.Errorf("a fetch task cannot declare network: false")
+	}
+	if len(t.Outputs) != 1 {
+		return fmt.Errorf("a fetch task must declare exactly one output, got %d", len(t.Outputs))
+	}
+	return nil
+}
+
+// Fetcher is the TaskExecutor of fetch tasks. It downloads the task's URL to
+// its declared output and, for a pinned fetch, verifies the content before
+// the output is replaced. A mismatch or an HTTP error status fails the task.
+type Fetcher struct {
+	// WorkingDir is the directory outputs are relative to.
+	WorkingDir string
+
+	// Client performs the requests.
+	Client *http.Client
+}
+
+// NewFetcher creates a Fetcher for the given working directory.
+func NewFetcher(workingDir string) *Fetcher {
+	return &Fetcher{WorkingDir: workingDir, Client: http.DefaultClient}
+}
+
+// Execute downloads task.Fetch.URL to task.Outputs[0]. Errors reaching the
+// server are returned as errors; a response the task rejects is a failed
+// execution, with the reason on stderr.
+func (f *Fetcher) Execute(ctx context.Context, task *Task, hash TaskHash) (*ExecutionResult, error) {
+	if task == nil {
+		return nil, fmt.Errorf("task is nil")
+	}
+	if err := task.ValidateFetch(); err != nil {
+		return nil, err
+	}
+	if task.Fetch == nil {
+		return nil, fmt.Errorf("task %q does not fetch", task.Name)
+	}
+	spec := task.Fetch
+	failed := func(format string, args ...any) (*ExecutionResult, error) {
+		msg := fmt.Sprintf("fetch %s: ", spec.URL) + fmt.Sprintf(format, args...) + "\n"
+		return &ExecutionResult{Stderr: []byte(msg), ExitCode: 1, Hash: hash}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, spec.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", spec.URL, err)
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", spec.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return failed("%s", resp.Status)
+	}
+
+	dest := filepath.Join(f.WorkingDir, filepath.FromSlash(task.Outputs[0]))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", spec.URL, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".fetch-*")
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", spec.URL, err)
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", spec.URL, err)
+	}
+	got := hex.EncodeToString(h.Sum(nil))
+	if spec.SHA256 != "" && got != spec.SHA256 {
+		return failed("sha256 mismatch: expected %s, got %s", spec.SHA256, got)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", spec.URL, err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", spec.URL, err)
+	}
+	return &ExecutionResult{
+		Stdout:   []byte(fmt.Sprintf("fetched %s (%d bytes, sha256:%s)\n", spec.URL, n, got)),
+		ExitCode: 0,
+		Hash:     hash,
+	}, nil
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func serveBody(t *testing.T, body *string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/dep.tgz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(*body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func sha256Of(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFetcher_DownloadsAndVerifiesPin(t *testing.T) {
+	body := "dependency v1"
+	srv := serveBody(t, &body)
+	dir := t.TempDir()
+	task := &Task{Name: "dep", Fetch: &Fetch{URL: srv.URL + "/dep.tgz", SHA256: sha256Of(body)}, Outputs: []string{"vendor/dep.tgz"}}
+
+	res, err := NewFetcher(dir).Execute(context.Background(), task, "h")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if res.ExitCode != 0 {
+		t.Fatalf("exit %d: %s", res.ExitCode, res.Stderr)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "vendor", "dep.tgz"))
+	if err != nil || string(got) != body {
+		t.Fatalf("output = %q, %v", got, err)
+	}
+}
+
+func TestFetcher_MismatchFailsAndKeepsOutput(t *testing.T) {
+	body := "tampered"
+	srv := serveBody(t, &body)
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "dep.tgz")
+	if err := os.WriteFile(dest, []byte("previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	task := &Task{Name: "dep", Fetch: &Fetch{URL: srv.URL + "/dep.tgz", SHA256: sha256Of("expected")}, Outputs: []string{"dep.tgz"}}
+
+	res, err := NewFetcher(dir).Execute(context.Background(), task, "h")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if res.ExitCode == 0 || !strings.Contains(string(res.Stderr), "sha256 mismatch") {
+		t.Fatalf("exit %d stderr %q", res.ExitCode, res.Stderr)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "previous" {
+		t.Fatalf("output replaced on mismatch: %q", got)
+	}
+}
+
+func TestFetcher_HTTPErrorFails(t *testing.T) {
+	body := ""
+	srv := serveBody(t, &body)
+	task := &Task{Name: "dep", Fetch: &Fetch{URL: srv.URL + "/missing"}, Outputs: []string{"dep.tgz"}}
+
+	res, err := NewFetcher(t.TempDir()).Execute(context.Background(), task, "h")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if res.ExitCode == 0 || !strings.Contains(string(res.Stderr), "404") {
+		t.Fatalf("exit %d stderr %q", res.ExitCode, res.Stderr)
+	}
+}
+
+func TestTask_ValidateFetch(t *testing.T) {
+	ok := Fetch{URL: "https://example.com/dep.tgz"}
+	no := false
+	cases := []struct {
+		name string
+		task Task
+		want string
+	}{
+		{"plain task", Task{Run: "true"}, ""},
+		{"valid", Task{Fetch: &ok, Outputs: []string{"dep.tgz"}}, ""},
+		{"scheme", Task{Fetch: &Fetch{URL: "file:///etc/passwd"}, Outputs: []string{"x"}}, "http or https"},
+		{"pin", Task{Fetch: &Fetch{URL: ok.URL, SHA256: "ABC"}, Outputs: []string{"x"}}, "64 lowercase hex"},
+		{"run", Task{Fetch: &ok, Run: "true", Outputs: []string{"x"}}, "cannot declare run"},
+		{"network", Task{Fetch: &ok, Network: &no, Outputs: []string{"x"}}, "network: false"},
+		{"outputs", Task{Fetch: &ok}, "exactly one output"},
+	}
+	for _, tc := range cases {
+		err := tc.task.ValidateFetch()
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestRunner_FetchCachedByContentNotFailures(t *testing.T) {
+	body := "wrong"
+	srv := serveBody(t, &body)
+	dir := t.TempDir()
+	cache := NewMemoryCache()
+	runner := NewRunner(dir, cache)
+	task := &Task{Name: "dep", Fetch: &Fetch{URL: srv.URL + "/dep.tgz", SHA256: sha256Of("right")}, Outputs: []string{"dep.tgz"}}
+	ctx := context.Background()
+
+	res, err := runner.Run(ctx, task)
+	if err != nil || res.ExitCode == 0 {
+		t.Fatalf("expected a failed fetch, got %+v, %v", res, err)
+	}
+	body = "right"
+	if res, err = runner.Run(ctx, task); err != nil || res.ExitCode != 0 || res.FromCache {
+		t.Fatalf("expected a fresh successful fetch, got %+v, %v", res, err)
+	}
+
+	// The same content from another URL is a cache hit.
+	mirror := *task
+	mirror.Fetch = &Fetch{URL: srv.URL + "/mirror/dep.tgz", SHA256: task.Fetch.SHA256}
+	if res, err = runner.Run(ctx, &mirror); err != nil || !res.FromCache {
+		t.Fatalf("expected a cache hit, got %+v, %v", res, err)
+	}
+}
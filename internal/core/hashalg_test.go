@@ -0,0 +1,91 @@
+package core
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBLAKE3_MatchesReferenceVectors checks inputs of the official test
+// vectors (bytes 0..250 repeating) spanning one block, one chunk and a tree
+// of chunks.
+func TestBLAKE3_MatchesReferenceVectors(t *testing.T) {
+	for _, tc := range []struct {
+		n    int
+		want string
+	}{
+		{0, "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{1, "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+		{1023, "10108970eeda3eb932baac1428c7a2163b0e924c9a9e25b35bba72b28f70bd11"},
+		{1024, "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+		{1025, "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+		{2048, "e776b6028c7cd22a4d0ba182a8bf62205d2ef576467e838ed6f2529b85fba24a"},
+		{2049, "5f4d72f40d7a5f82b15ca2b2e44b1de3c2ef86c426c95c1af0b6879522563030"},
+	} {
+		in := make([]byte, tc.n)
+		for i := range in {
+			in[i] = byte(i % 251)
+		}
+		h := BLAKE3.New()
+		// Split writes must not change the digest.
+		h.Write(in[:tc.n/3])
+		h.Write(in[tc.n/3:])
+		if got := hex.EncodeToString(h.Sum(nil)); got != tc.want {
+			t.Errorf("BLAKE3 of %d bytes = %s, want %s", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestTaskHasher_AlgorithmTagsHash(t *testing.T) {
+	input := HashInput{Inputs: &InputSet{Inputs: []Input{}}, Command: "test", WorkingDir: "/"}
+	sha := NewTaskHasher().ComputeHash(input)
+	b3 := (&TaskHasher{Algorithm: BLAKE3}).ComputeHash(input)
+	if sha.Algorithm() != SHA256 || b3.Algorithm() != BLAKE3 || len(b3.Digest()) != 64 {
+		t.Fatalf("hashes = %s, %s", sha, b3)
+	}
+	if sha.Digest() == b3.Digest() {
+		t.Fatalf("algorithms produced the same digest")
+	}
+	if _, err := ParseHashAlgorithm("md5"); err == nil {
+		t.Fatalf("expected an unknown algorithm to be rejected")
+	}
+	if !SameHash(string(sha), sha.Digest()) || SameHash(string(b3), b3.Digest()) {
+		t.Fatalf("SameHash must treat only untagged digests as sha256")
+	}
+}
+
+// TestFileCache_ReadsLegacyEntries verifies that entries written before hashes
+// were tagged, under the bare digest, remain readable.
+func TestFileCache_ReadsLegacyEntries(t *testing.T) {
+	dir := t.TempDir()
+	digest := "abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890"
+	legacyDir := filepath.Join(dir, "ab", digest)
+	if err := os.MkdirAll(filepath.Join(legacyDir, "artifacts"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	meta, _ := json.Marshal(CacheEntry{Hash: TaskHash(digest), Stdout: []byte("old"), Artifacts: []CachedArtifact{}})
+	if err := os.WriteFile(filepath.Join(legacyDir, "metadata.json"), meta, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewFileCache(dir)
+	hash := TaskHash("sha256:" + digest)
+	if ok, err := c.Has(hash); err != nil || !ok {
+		t.Fatalf("Has = %v, %v", ok, err)
+	}
+	entry, err := c.Get(hash)
+	if err != nil || entry == nil || string(entry.Stdout) != "old" || entry.Hash != hash {
+		t.Fatalf("Get = %+v, %v", entry, err)
+	}
+	if ok, _ := c.Has(TaskHash("blake3:" + digest)); ok {
+		t.Fatalf("a BLAKE3 hash must not match a legacy SHA-256 entry")
+	}
+	if err := c.Evict(hash); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.Has(hash); ok {
+		t.Fatalf("legacy entry survived Evict")
+	}
+}
@@ -4,7 +4,9 @@ package core
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"sort"
+	"strconv"
 )
 
 // TaskHash represents a deterministic identifier for a task execution.
@@ -17,6 +19,12 @@ import (
 // From spec.md Cache Key Definition:
 //
 //	Any change to these components MUST produce a different Task Hash.
+//
+// The platform a task runs on is not excluded: a result built on Linux is not
+// a valid result on macOS, unless the task is declared platform-independent.
+//
+// A TaskHash is tagged with its algorithm: "sha256:<hex digest>". Hashes
+// computed before tags existed are bare SHA-256 digests; see Legacy.
 type TaskHash string
 
 // TaskHasher computes deterministic hashes for task executions.
@@ -25,11 +33,14 @@ type TaskHash string
 //   - Deterministic: identical inputs always produce identical hashes
 //   - Content-based: uses file contents, not metadata
 //   - Ordered: all components are sorted before hashing
-type TaskHasher struct{}
+type TaskHasher struct {
+	// Algorithm is the hash function; empty selects DefaultHashAlgorithm.
+	Algorithm HashAlgorithm
+}
 
-// NewTaskHasher creates a new TaskHasher.
+// NewTaskHasher creates a new TaskHasher using the default algorithm.
 func NewTaskHasher() *TaskHasher {
-	return &TaskHasher{}
+	return &TaskHasher{Algorithm: DefaultHashAlgorithm}
 }
 
 // HashInput contains all components required for computing a Task Hash.
@@ -55,12 +66,53 @@ type HashInput struct {
 	// Outputs is the list of declared output paths.
 	Outputs []string
 
+	// NoNetwork is set for tasks that run without network access. It is
+	// only hashed when set, so hashes of other tasks are unaffected.
+	NoNetwork bool
+
+	// Fetch is the Fetch.Key of a fetch task. It is only hashed when set.
+	Fetch string
+
+	// Runner names the plugin runner that executes the task, so that
+	// results of different runners are not confused. It is only hashed when
+	// set; the built-in executor is unnamed.
+	Runner string
+
+	// Platform is the "GOOS/GOARCH" the task runs on, so that results built
+	// on one platform are not reused on another. It is only hashed when set,
+	// which it is not for platform-independent tasks.
+	Platform string
+
+	// TimeoutSeconds and Retries are the task's execution limits. A result
+	// obtained under other limits, such as a success that a shorter timeout
+	// would have failed, is not reused. Each is only hashed when set.
+	TimeoutSeconds int
+	Retries        int
+
 	// WorkingDir is the working directory identity.
 	// This is included to ensure tasks with different working directories
 	// produce different hashes even with identical other inputs.
 	WorkingDir string
 }
 
+// HashInput returns the HashInput of task t with its resolved inputs, run
+// in workDir.
+func (t Task) HashInput(inputs *InputSet, workDir string) HashInput {
+	return HashInput{
+		Inputs:         inputs,
+		Command:        t.Run,
+		Env:            t.Env,
+		Outputs:        t.Outputs,
+		NoNetwork:      !t.NetworkAllowed(),
+		Fetch:          t.Fetch.Key(),
+		Runner:         t.Runner,
+		Platform:       t.HashPlatform(),
+		TimeoutSeconds: t.TimeoutSeconds,
+		Retries:        t.Retries,
+		WorkingDir:     workDir,
+	}
+}
+
 // ComputeHash computes a deterministic TaskHash from the given inputs.
 //
 // The hash is computed by concatenating all components in a deterministic order:
@@ -69,20 +121,25 @@ type HashInput struct {
 //  3. Sorted environment variables (key=value pairs)
 //  4. Sorted declared outputs
 //  5. For each input (already sorted): path + content
+//  6. Network isolation, for tasks without network access
+//  7. Fetched artifact key, for fetch tasks
+//  8. Runner, for tasks run by a plugin runner
+//  9. Platform, for platform-specific tasks
+//  10. Timeout and retries, for tasks that declare them
 //
-// All components are length-prefixed to prevent ambiguity.
+// All components are length-prefixed to prevent ambiguity. The digest is
+// tagged with the hasher's algorithm.
 //
 // From tdd.md:
 //   - Test 1: Identical inputs = Identical Hash
 //   - Test 3: Changed content = New Hash
 //   - Test 4: Changed env = New Hash
 func (h *TaskHasher) ComputeHash(input HashInput) TaskHash {
-	hasher := sha256.New()
+	hasher := h.Algorithm.New()
 
-	// Helper to write length-prefixed data
-	writeField := func(data []byte) {
+	for _, f := range canonicalFields(input) {
 		// Write 8-byte length prefix (big-endian)
-		length := uint64(len(data))
+		length := uint64(len(f.data))
 		lengthBytes := []byte{
 			byte(length >> 56),
 			byte(length >> 48),
@@ -94,14 +151,47 @@ func (h *TaskHasher) ComputeHash(input HashInput) TaskHash {
 			byte(length),
 		}
 		hasher.Write(lengthBytes)
-		hasher.Write(data)
+		hasher.Write(f.data)
+	}
+
+	// Compute final hash
+	sum := hasher.Sum(nil)
+	return TaskHash(h.Algorithm.Tag(hex.EncodeToString(sum)))
+}
+
+// Kinds of the fields of the canonical encoding of a HashInput.
+const (
+	fieldCount   = "count"
+	fieldText    = "text"
+	fieldContent = "content"
+)
+
+// hashField is one length-prefixed field of the canonical encoding of a
+// HashInput.
+type hashField struct {
+	name string
+	kind string
+	data []byte
+	// tag is the length of the "key=" prefix a tagged text field is encoded
+	// with, which its explanation leaves out.
+	tag int
+}
+
+// canonicalFields returns the fields ComputeHash hashes, in order.
+func canonicalFields(input HashInput) []hashField {
+	var fields []hashField
+	add := func(name, kind string, data []byte) {
+		fields = append(fields, hashField{name: name, kind: kind, data: data})
+	}
+	addTagged := func(name, key, value string) {
+		fields = append(fields, hashField{name: name, kind: fieldText, data: []byte(key + "=" + value), tag: len(key) + 1})
 	}
 
 	// 1. Working directory identity
-	writeField([]byte(input.WorkingDir))
+	add("workdir", fieldText, []byte(input.WorkingDir))
 
 	// 2. Command string
-	writeField([]byte(input.Command))
+	add("command", fieldText, []byte(input.Command))
 
 	// 3. Environment variables - MUST be sorted for determinism
 	envKeys := make([]string, 0, len(input.Env))
@@ -111,10 +201,10 @@ func (h *TaskHasher) ComputeHash(input HashInput) TaskHash {
 	sort.Strings(envKeys)
 
 	// Write env count for unambiguous parsing
-	writeField([]byte{byte(len(envKeys))})
+	add("env", fieldCount, []byte{byte(len(envKeys))})
 	for _, k := range envKeys {
-		writeField([]byte(k))
-		writeField([]byte(input.Env[k]))
+		add("env.key", fieldText, []byte(k))
+		add("env.value", fieldText, []byte(input.Env[k]))
 	}
 
 	// 4. Declared outputs - MUST be sorted for determinism
@@ -123,9 +213,9 @@ func (h *TaskHasher) ComputeHash(input HashInput) TaskHash {
 	sort.Strings(sortedOutputs)
 
 	// Write outputs count
-	writeField([]byte{byte(len(sortedOutputs))})
+	add("outputs", fieldCount, []byte{byte(len(sortedOutputs))})
 	for _, out := range sortedOutputs {
-		writeField([]byte(out))
+		add("output", fieldText, []byte(out))
 	}
 
 	// 5. Inputs - path and content for each (already sorted by InputResolver)
@@ -133,19 +223,84 @@ func (h *TaskHasher) ComputeHash(input HashInput) TaskHash {
 	if input.Inputs != nil {
 		inputCount = len(input.Inputs.Inputs)
 	}
-	writeField([]byte{byte(inputCount)})
+	add("inputs", fieldCount, []byte{byte(inputCount)})
 
 	if input.Inputs != nil {
 		for _, inp := range input.Inputs.Inputs {
 			// Both path and content contribute to identity
-			writeField([]byte(inp.Path))
-			writeField(inp.Content)
+			add("input.path", fieldText, []byte(inp.Path))
+			add("input.content", fieldContent, inp.Content)
 		}
 	}
 
-	// Compute final hash
-	sum := hasher.Sum(nil)
-	return TaskHash(hex.EncodeToString(sum))
+	// 6. Network isolation, only when requested
+	if input.NoNetwork {
+		addTagged("network", "network", "false")
+	}
+
+	// 7. Fetched artifact, only for fetch tasks
+	if input.Fetch != "" {
+		addTagged("fetch", "fetch", input.Fetch)
+	}
+
+	// 8. Runner identity, only for plugin runners
+	if input.Runner != "" {
+		addTagged("runner", "runner", input.Runner)
+	}
+
+	// 9. Platform, unless the task is platform-independent
+	if input.Platform != "" {
+		addTagged("platform", "platform", input.Platform)
+	}
+
+	// 10. Execution limits, only when declared
+	if input.TimeoutSeconds != 0 {
+		addTagged("timeout", "timeout_seconds", strconv.Itoa(input.TimeoutSeconds))
+	}
+	if input.Retries != 0 {
+		addTagged("retries", "retries", strconv.Itoa(input.Retries))
+	}
+	return fields
+}
+
+// HashComponent is one field of the canonical encoding hashed into a
+// TaskHash.
+type HashComponent struct {
+	// Name identifies the field, such as "command", "env.key" or
+	// "input.content".
+	Name string `json:"name"`
+	// Value renders the field: text quoted, counts in decimal, and file
+	// contents as their SHA-256 digest and size.
+	Value string `json:"value"`
+}
+
+// HashExplanation lists the components a TaskHash is computed from, so that
+// the explanations of one task on two machines can be diffed to find why
+// their hashes diverge.
+type HashExplanation struct {
+	Hash       TaskHash        `json:"hash"`
+	Components []HashComponent `json:"components"`
+}
+
+// Explain returns the hash of input with the components it is computed from,
+// in the order ComputeHash hashes them.
+func (h *TaskHasher) Explain(input HashInput) HashExplanation {
+	fields := canonicalFields(input)
+	exp := HashExplanation{Hash: h.ComputeHash(input), Components: make([]HashComponent, 0, len(fields))}
+	for _, f := range fields {
+		var value string
+		switch f.kind {
+		case fieldCount:
+			value = strconv.Itoa(int(f.data[0]))
+		case fieldContent:
+			sum := sha256.Sum256(f.data)
+			value = fmt.Sprintf("sha256:%x (%d bytes)", sum, len(f.data))
+		default:
+			value = strconv.Quote(string(f.data[f.tag:]))
+		}
+		exp.Components = append(exp.Components, HashComponent{Name: f.name, Value: value})
+	}
+	return exp
 }
 
 // String returns the string representation of the TaskHash.
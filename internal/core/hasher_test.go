@@ -1,6 +1,7 @@
 package core
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -147,6 +148,32 @@ func TestComputeHash_CommandChangeInvalidatesHash(t *testing.T) {
 	}
 }
 
+// TestComputeHash_LimitsChangeInvalidateHash verifies that the timeout and
+// retries affect the hash, and that tasks declaring neither hash as before.
+func TestComputeHash_LimitsChangeInvalidateHash(t *testing.T) {
+	hasher := NewTaskHasher()
+	task := Task{Name: "t", Run: "echo hello"}
+	base := hasher.ComputeHash(task.HashInput(&InputSet{}, "/work"))
+
+	legacy := hasher.ComputeHash(HashInput{Inputs: &InputSet{}, Command: "echo hello", Platform: Platform(), WorkingDir: "/work"})
+	if base != legacy {
+		t.Error("a task without limits must keep its hash")
+	}
+
+	withTimeout := task
+	withTimeout.TimeoutSeconds = 30
+	withRetries := task
+	withRetries.Retries = 2
+	seen := map[TaskHash]bool{base: true}
+	for _, tk := range []Task{withTimeout, withRetries} {
+		h := hasher.ComputeHash(tk.HashInput(&InputSet{}, "/work"))
+		if seen[h] {
+			t.Errorf("timeout %d, retries %d did not change the hash", tk.TimeoutSeconds, tk.Retries)
+		}
+		seen[h] = true
+	}
+}
+
 // TestComputeHash_OutputsChangeInvalidatesHash verifies declared outputs affect hash.
 func TestComputeHash_OutputsChangeInvalidatesHash(t *testing.T) {
 	hasher := NewTaskHasher()
@@ -368,7 +395,8 @@ func TestComputeHash_Deterministic(t *testing.T) {
 	}
 }
 
-// TestComputeHash_HashFormat verifies hash is hex-encoded SHA256.
+// TestComputeHash_HashFormat verifies hash is hex-encoded SHA256 tagged with
+// its algorithm.
 func TestComputeHash_HashFormat(t *testing.T) {
 	hasher := NewTaskHasher()
 
@@ -382,15 +410,111 @@ func TestComputeHash_HashFormat(t *testing.T) {
 
 	hash := hasher.ComputeHash(input)
 
+	if hash.Algorithm() != SHA256 || !strings.HasPrefix(string(hash), "sha256:") {
+		t.Errorf("expected a sha256: tag, got %s", hash)
+	}
+
 	// SHA256 produces 32 bytes = 64 hex characters
-	if len(hash) != 64 {
-		t.Errorf("expected 64 character hash, got %d", len(hash))
+	if len(hash.Digest()) != 64 {
+		t.Errorf("expected 64 character digest, got %d", len(hash.Digest()))
 	}
 
 	// Verify all characters are valid hex
-	for _, c := range hash {
+	for _, c := range hash.Digest() {
 		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'f')) {
 			t.Errorf("invalid hex character in hash: %c", c)
 		}
 	}
 }
+
+// TestComputeHash_NetworkIsolationChangesHash verifies that a task run
+// without network access never shares a cache entry with one run with it.
+func TestComputeHash_NetworkIsolationChangesHash(t *testing.T) {
+	hasher := NewTaskHasher()
+
+	input := HashInput{
+		Inputs:     &InputSet{Inputs: []Input{}},
+		Command:    "make",
+		Env:        map[string]string{},
+		Outputs:    []string{},
+		WorkingDir: "/work",
+	}
+	networked := hasher.ComputeHash(input)
+	input.NoNetwork = true
+	if isolated := hasher.ComputeHash(input); isolated == networked {
+		t.Errorf("network isolation did not change the hash: %s", isolated)
+	}
+}
+
+// TestComputeHash_RunnerAndPlatformChangeHash verifies that results of
+// different runners or platforms never share a cache entry.
+func TestComputeHash_RunnerAndPlatformChangeHash(t *testing.T) {
+	hasher := NewTaskHasher()
+
+	input := HashInput{
+		Inputs:     &InputSet{Inputs: []Input{}},
+		Command:    "make",
+		Outputs:    []string{},
+		WorkingDir: "/work",
+		Platform:   "linux/amd64",
+	}
+	linux := hasher.ComputeHash(input)
+	input.Platform = "darwin/arm64"
+	if darwin := hasher.ComputeHash(input); darwin == linux {
+		t.Errorf("platform did not change the hash: %s", darwin)
+	}
+	input.Platform = "linux/amd64"
+	input.Runner = "docker"
+	if docker := hasher.ComputeHash(input); docker == linux {
+		t.Errorf("runner did not change the hash: %s", docker)
+	}
+}
+
+func TestTask_HashPlatform(t *testing.T) {
+	if got := (Task{}).HashPlatform(); got != Platform() || !strings.Contains(got, "/") {
+		t.Errorf("HashPlatform() = %q, want %q", got, Platform())
+	}
+	if got := (Task{PlatformIndependent: true}).HashPlatform(); got != "" {
+		t.Errorf("HashPlatform() of a platform-independent task = %q, want empty", got)
+	}
+}
+
+func TestExplain_ListsHashedComponents(t *testing.T) {
+	hasher := NewTaskHasher()
+	input := HashInput{
+		Inputs:     &InputSet{Inputs: []Input{{Path: "/work/a.txt", Content: []byte("abc")}}},
+		Command:    "make",
+		Env:        map[string]string{"B": "2", "A": "1"},
+		Outputs:    []string{"out"},
+		WorkingDir: "/work",
+		Platform:   "linux/amd64",
+
+		TimeoutSeconds: 30,
+	}
+
+	exp := hasher.Explain(input)
+	if exp.Hash != hasher.ComputeHash(input) {
+		t.Fatalf("Explain hash %s differs from ComputeHash", exp.Hash)
+	}
+	var got []string
+	for _, c := range exp.Components {
+		got = append(got, c.Name+" "+c.Value)
+	}
+	want := []string{
+		`workdir "/work"`,
+		`command "make"`,
+		`env 2`,
+		`env.key "A"`, `env.value "1"`,
+		`env.key "B"`, `env.value "2"`,
+		`outputs 1`,
+		`output "out"`,
+		`inputs 1`,
+		`input.path "/work/a.txt"`,
+		`input.content sha256:ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad (3 bytes)`,
+		`platform "linux/amd64"`,
+		`timeout "30"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("components:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -86,6 +86,8 @@ func (r *Replayer) Replay(entry *CacheEntry) (*ReplayResult, error) {
 //   - Check if expected output files exist with correct content hashes.
 //   - If missing or mismatched, restore from cache using an atomic write/replace.
 //   - Fail hard if an artifact cannot be retrieved from cache.
+//   - Fail with a CacheCorruptionError if a blob does not match its manifest
+//     hash, or a restored file does not re-hash to the cached content.
 //
 // taskID is used only for error messages.
 func (r *Replayer) RestoreArtifacts(taskID string, entry *CacheEntry) (int, error) {
@@ -111,6 +113,10 @@ func (r *Replayer) RestoreArtifacts(taskID string, entry *CacheEntry) (int, erro
 		}
 
 		wantHash := sha256Hex(artifact.Content)
+		// The blob must match the manifest before it may feed downstream tasks.
+		if artifact.SHA256 != "" && artifact.SHA256 != wantHash {
+			return restored, &CacheCorruptionError{Hash: entry.Hash, Path: artifact.Path, Want: artifact.SHA256, Got: wantHash}
+		}
 		haveHash, ok, err := fileSHA256HexIfExists(targetPath)
 		if err != nil {
 			return restored, fmt.Errorf("task %q: hashing existing artifact %q: %w", taskID, artifact.Path, err)
@@ -122,6 +128,15 @@ func (r *Replayer) RestoreArtifacts(taskID string, entry *CacheEntry) (int, erro
 		if err := atomicWriteFile(targetPath, artifact.Content, 0644); err != nil {
 			return restored, fmt.Errorf("task %q: restoring artifact %q: %w", taskID, artifact.Path, err)
 		}
+
+		// Re-hash what actually landed in the workspace.
+		haveHash, ok, err = fileSHA256HexIfExists(targetPath)
+		if err != nil {
+			return restored, fmt.Errorf("task %q: re-hashing restored artifact %q: %w", taskID, artifact.Path, err)
+		}
+		if !ok || haveHash != wantHash {
+			return restored, &CacheCorruptionError{Hash: entry.Hash, Path: artifact.Path, Want: wantHash, Got: haveHash}
+		}
 		restored++
 	}
 
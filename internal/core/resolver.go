@@ -22,6 +22,9 @@ type InputResolver struct {
 	// BaseDir is the working directory for resolving relative paths.
 	// All paths are resolved relative to this directory.
 	BaseDir string
+
+	// Stat, when non-nil, serves unchanged input files from memory.
+	Stat *StatCache
 }
 
 // NewInputResolver creates a new InputResolver with the given base directory.
@@ -133,6 +136,9 @@ func (r *InputResolver) expandPattern(pattern string) ([]string, error) {
 func (r *InputResolver) readFileContent(path string) ([]byte, error) {
 	// Convert back to OS path for reading
 	osPath := filepath.FromSlash(path)
+	if r.Stat != nil {
+		return r.Stat.ReadFile(osPath)
+	}
 	content, err := os.ReadFile(osPath)
 	if err != nil {
 		return nil, err
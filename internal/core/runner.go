@@ -3,9 +3,11 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // Runner orchestrates deterministic task execution with caching.
@@ -29,6 +31,12 @@ type Runner struct {
 	// Executor runs tasks in isolated environments.
 	Executor *Executor
 
+	// Runners holds the named executors that tasks select via Task.Runner.
+	Runners map[string]TaskExecutor
+
+	// Fetcher executes fetch tasks.
+	Fetcher TaskExecutor
+
 	// Resolver expands input patterns to files.
 	Resolver *InputResolver
 
@@ -43,6 +51,10 @@ type Runner struct {
 
 	// Normalizer for output normalization (optional).
 	Normalizer OutputNormalizer
+
+	// Rerun names the tasks that execute even when the cache holds their
+	// result; the new result replaces the cached one.
+	Rerun map[string]bool
 }
 
 // NewRunner creates a Runner with the given working directory and cache.
@@ -51,6 +63,7 @@ func NewRunner(workingDir string, cache Cache) *Runner {
 		WorkingDir: workingDir,
 		Cache:      cache,
 		Executor:   NewExecutor(workingDir),
+		Fetcher:    NewFetcher(workingDir),
 		Resolver:   NewInputResolver(workingDir),
 		Hasher:     NewTaskHasher(),
 		Harvester:  NewHarvester(workingDir),
@@ -94,7 +107,7 @@ type RunResult struct {
 //  1. Validate task
 //  2. Resolve inputs
 //  3. Compute hash
-//  4. Check cache → if hit, replay and return
+//  4. Check cache → if hit, replay and return, unless the task is in Rerun
 //  5. Execute task
 //  6. If success (exit code 0): harvest artifacts, cache, return
 //  7. If failure (non-zero): cache stdout/stderr/exitcode (NO artifacts), return
@@ -118,14 +131,7 @@ func (r *Runner) Run(ctx context.Context, task *Task) (*RunResult, error) {
 	}
 
 	// Compute hash
-	hashInput := HashInput{
-		Inputs:     inputSet,
-		Command:    task.Run,
-		Env:        task.Env,
-		Outputs:    task.Outputs,
-		WorkingDir: r.WorkingDir,
-	}
-	hash := r.Hasher.ComputeHash(hashInput)
+	hash := r.Hasher.ComputeHash(task.HashInput(inputSet, r.WorkingDir))
 
 	// Check cache
 	exists, err := r.Cache.Has(hash)
@@ -133,7 +139,7 @@ func (r *Runner) Run(ctx context.Context, task *Task) (*RunResult, error) {
 		return nil, fmt.Errorf("checking cache: %w", err)
 	}
 
-	if exists {
+	if exists && !r.Rerun[task.Name] {
 		// Cache hit - replay
 		return r.replayFromCache(hash)
 	}
@@ -150,12 +156,40 @@ func (r *Runner) validateTask(task *Task) error {
 	if task.Name == "" {
 		return fmt.Errorf("task name is required")
 	}
-	if task.Run == "" {
+	if task.Run == "" && task.Fetch == nil {
 		return fmt.Errorf("task run command is required")
 	}
+	if err := task.ValidateFetch(); err != nil {
+		return fmt.Errorf("task %q: %w", task.Name, err)
+	}
+	if err := task.ValidateLimits(); err != nil {
+		return fmt.Errorf("task %q: %w", task.Name, err)
+	}
+	if _, err := r.executorFor(task); err != nil {
+		return err
+	}
 	return nil
 }
 
+// executorFor returns the executor selected by task.Runner, or the Fetcher
+// for fetch tasks.
+func (r *Runner) executorFor(task *Task) (TaskExecutor, error) {
+	if task.Fetch != nil {
+		if r.Fetcher == nil {
+			return nil, fmt.Errorf("task %q fetches, but the runner has no fetcher", task.Name)
+		}
+		return r.Fetcher, nil
+	}
+	if task.Runner == "" {
+		return r.Executor, nil
+	}
+	ex, ok := r.Runners[task.Runner]
+	if !ok {
+		return nil, fmt.Errorf("task %q requires unknown runner %q", task.Name, task.Runner)
+	}
+	return ex, nil
+}
+
 // replayFromCache retrieves and replays a cached result.
 func (r *Runner) replayFromCache(hash TaskHash) (*RunResult, error) {
 	entry, err := r.Cache.Get(hash)
@@ -168,7 +202,7 @@ func (r *Runner) replayFromCache(hash TaskHash) (*RunResult, error) {
 
 	replayResult, err := r.Replayer.Replay(entry)
 	if err != nil {
-		return nil, fmt.Errorf("replaying cached result: %w", err)
+		return nil, fmt.Errorf("replaying cached result: %w", EvictOnCorruption(r.Cache, hash, err))
 	}
 
 	return &RunResult{
@@ -187,7 +221,11 @@ func (r *Runner) replayFromCache(hash TaskHash) (*RunResult, error) {
 // This ensures "Failed tasks MUST NOT partially update artifacts."
 func (r *Runner) executeAndCache(ctx context.Context, task *Task, hash TaskHash) (*RunResult, error) {
 	// Execute task
-	execResult, err := r.Executor.Execute(ctx, task, hash)
+	ex, err := r.executorFor(task)
+	if err != nil {
+		return nil, err
+	}
+	execResult, transient, err := r.executeWithRetries(ctx, ex, task, hash)
 	if err != nil {
 		return nil, fmt.Errorf("executing task: %w", err)
 	}
@@ -215,9 +253,14 @@ func (r *Runner) executeAndCache(ctx context.Context, task *Task, hash TaskHash)
 		entry.Artifacts = []CachedArtifact{}
 	}
 
-	// Store in cache
-	if err := r.Cache.Put(entry); err != nil {
-		return nil, fmt.Errorf("caching result: %w", err)
+	// Store in cache. A failed fetch is not cached: the key of a pinned
+	// fetch is the expected content, which a later fetch may still produce.
+	// Nor is a failure that timed out or exhausted the task's retries: the
+	// task declared it may fail transiently.
+	if execResult.ExitCode == 0 || (task.Fetch == nil && !transient) {
+		if err := r.Cache.Put(entry); err != nil {
+			return nil, fmt.Errorf("caching result: %w", err)
+		}
 	}
 
 	return &RunResult{
@@ -230,6 +273,51 @@ func (r *Runner) executeAndCache(ctx context.Context, task *Task, hash TaskHash)
 	}, nil
 }
 
+// executeWithRetries executes the task, bounding each execution by the
+// task's timeout and repeating failed executions up to task.Retries times.
+// transient reports that the returned failure may not recur: the execution
+// timed out or the task declares retries.
+func (r *Runner) executeWithRetries(ctx context.Context, ex TaskExecutor, task *Task, hash TaskHash) (res *ExecutionResult, transient bool, err error) {
+	for attempt := 0; ; attempt++ {
+		res, err = r.executeOnce(ctx, ex, task, hash)
+		if err != nil {
+			return nil, false, err
+		}
+		if res.ExitCode == 0 {
+			return res, false, nil
+		}
+		transient = task.Retries > 0 || res.ExitCode == TimeoutExitCode && task.TimeoutSeconds > 0
+		if attempt >= task.Retries || ctx.Err() != nil {
+			return res, transient, nil
+		}
+	}
+}
+
+// executeOnce executes the task once, within its timeout. An execution that
+// exceeds the timeout fails with TimeoutExitCode.
+func (r *Runner) executeOnce(ctx context.Context, ex TaskExecutor, task *Task, hash TaskHash) (*ExecutionResult, error) {
+	if task.TimeoutSeconds <= 0 {
+		return ex.Execute(ctx, task, hash)
+	}
+	timeout := time.Duration(task.TimeoutSeconds) * time.Second
+	tctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	res, err := ex.Execute(tctx, task, hash)
+	if ctx.Err() == nil && errors.Is(tctx.Err(), context.DeadlineExceeded) {
+		var stdout []byte
+		if res != nil {
+			stdout = res.Stdout
+		}
+		return &ExecutionResult{
+			Stdout:   stdout,
+			Stderr:   []byte(fmt.Sprintf("task %q timed out after %s\n", task.Name, timeout)),
+			ExitCode: TimeoutExitCode,
+			Hash:     hash,
+		}, nil
+	}
+	return res, err
+}
+
 // harvestArtifacts collects artifacts from declared outputs.
 func (r *Runner) harvestArtifacts(outputs []string) ([]CachedArtifact, error) {
 	if len(outputs) == 0 {
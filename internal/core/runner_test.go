@@ -328,6 +328,112 @@ func TestRunner_ValidatesTask(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for empty run")
 	}
+
+	// Unknown runner
+	_, err = runner.Run(ctx, &Task{Name: "test", Run: "echo", Runner: "kubernetes"})
+	if err == nil {
+		t.Error("expected error for unknown runner")
+	}
+
+	// Out-of-range limits
+	_, err = runner.Run(ctx, &Task{Name: "test", Run: "echo", TimeoutSeconds: -1})
+	if err == nil {
+		t.Error("expected error for negative timeout")
+	}
+	_, err = runner.Run(ctx, &Task{Name: "test", Run: "echo", Retries: MaxTaskRetries + 1})
+	if err == nil {
+		t.Error("expected error for too many retries")
+	}
+}
+
+// TestRunner_TimeoutFailsUncached verifies that an execution exceeding the
+// task's timeout fails with TimeoutExitCode and is not cached.
+func TestRunner_TimeoutFailsUncached(t *testing.T) {
+	runner := NewRunner(t.TempDir(), NewMemoryCache())
+	task := &Task{Name: "slow", Run: "sleep 5", TimeoutSeconds: 1}
+
+	start := time.Now()
+	res, err := runner.Run(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.ExitCode != TimeoutExitCode || !bytes.Contains(res.Stderr, []byte("timed out after 1s")) {
+		t.Fatalf("unexpected result: exit %d, stderr %q", res.ExitCode, res.Stderr)
+	}
+	if elapsed := time.Since(start); elapsed > 4*time.Second {
+		t.Fatalf("timeout did not stop the task: took %s", elapsed)
+	}
+	if ok, _ := runner.Cache.Has(res.Hash); ok {
+		t.Fatal("a timed-out execution must not be cached")
+	}
+}
+
+// TestRunner_RetriesFailedExecutions verifies that a failed execution is
+// repeated up to Task.Retries times within the same run.
+func TestRunner_RetriesFailedExecutions(t *testing.T) {
+	tmpDir := t.TempDir()
+	runner := NewRunner(tmpDir, NewMemoryCache())
+	// Fails until its third execution.
+	task := &Task{Name: "flaky", Run: "echo x >> attempts; [ $(wc -l < attempts) -ge 3 ]", Retries: 2}
+
+	res, err := runner.Run(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.ExitCode != 0 {
+		t.Fatalf("expected success on the last retry, got exit %d", res.ExitCode)
+	}
+
+	// With retries exhausted the failure is reported, but not cached.
+	if err := os.Remove(filepath.Join(tmpDir, "attempts")); err != nil {
+		t.Fatal(err)
+	}
+	task.Retries = 1
+	res, err = runner.Run(context.Background(), task)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.ExitCode == 0 {
+		t.Fatal("expected failure after exhausting retries")
+	}
+	if ok, _ := runner.Cache.Has(res.Hash); ok {
+		t.Fatal("a failure of a task with retries must not be cached")
+	}
+}
+
+type fakeTaskExecutor struct {
+	calls int
+}
+
+func (f *fakeTaskExecutor) Execute(_ context.Context, task *Task, hash TaskHash) (*ExecutionResult, error) {
+	f.calls++
+	return &ExecutionResult{Stdout: []byte("remote:" + task.Run), Hash: hash}, nil
+}
+
+// TestRunner_SelectsNamedRunner verifies that Task.Runner dispatches to the
+// named executor and that its result is cached like local execution.
+func TestRunner_SelectsNamedRunner(t *testing.T) {
+	tmpDir := t.TempDir()
+	runner := NewRunner(tmpDir, NewMemoryCache())
+	remote := &fakeTaskExecutor{}
+	runner.Runners = map[string]TaskExecutor{"remote": remote}
+	task := &Task{Name: "t", Run: "exit 7", Runner: "remote"}
+
+	ctx := context.Background()
+	res, err := runner.Run(ctx, task)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.ExitCode != 0 || string(res.Stdout) != "remote:exit 7" || remote.calls != 1 {
+		t.Fatalf("unexpected result %#v (calls=%d)", res, remote.calls)
+	}
+	res, err = runner.Run(ctx, task)
+	if err != nil {
+		t.Fatalf("second Run: %v", err)
+	}
+	if !res.FromCache || remote.calls != 1 {
+		t.Fatalf("expected cache hit, FromCache=%v calls=%d", res.FromCache, remote.calls)
+	}
 }
 
 // TestRunner_CleanArtifacts verifies artifact cleanup.
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"scriptweaver/internal/platform"
+)
+
+// statRacyWindow is how recently before caching a file may have been modified
+// for its cached content to be distrusted. Filesystem timestamps are coarse, so
+// a file rewritten within the same tick with the same size would otherwise be
+// indistinguishable from the cached version.
+const statRacyWindow = time.Second
+
+// StatCache memoizes file contents keyed by path and validated by file
+// metadata, so long-lived processes (see `sw daemon`) avoid re-reading inputs
+// that have not changed.
+//
+// Content identity is unaffected: a cached entry is only reused when size,
+// mode and modification time all match and the file was not modified shortly
+// before it was cached. A StatCache is safe for concurrent use.
+type StatCache struct {
+	// FS is the filesystem files are read from, and Clock tells when they
+	// were cached; nil means the host filesystem and the system clock.
+	FS    platform.FS
+	Clock platform.Clock
+
+	mu      sync.Mutex
+	entries map[string]statEntry
+}
+
+type statEntry struct {
+	size     int64
+	mode     os.FileMode
+	modTime  time.Time
+	cachedAt time.Time
+	content  []byte
+}
+
+// NewStatCache returns an empty StatCache.
+func NewStatCache() *StatCache {
+	return &StatCache{entries: make(map[string]statEntry)}
+}
+
+// ReadFile returns the content of path, from cache when the file is unchanged.
+func (c *StatCache) ReadFile(path string) ([]byte, error) {
+	fsys := platform.FSOr(c.FS)
+	info, err := fsys.Stat(path)
+	if err != nil {
+		c.forget(path)
+		return nil, err
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && e.size == info.Size() && e.mode == info.Mode() && e.modTime.Equal(info.ModTime()) &&
+		info.ModTime().Before(e.cachedAt.Add(-statRacyWindow)) {
+		return e.content, nil
+	}
+
+	cachedAt := platform.ClockOr(c.Clock).Now()
+	content, err := fsys.ReadFile(path)
+	if err != nil {
+		c.forget(path)
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[path] = statEntry{size: info.Size(), mode: info.Mode(), modTime: info.ModTime(), cachedAt: cachedAt, content: content}
+	c.mu.Unlock()
+	return content, nil
+}
+
+// Len returns the number of cached files.
+func (c *StatCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *StatCache) forget(path string) {
+	c.mu.Lock()
+	delete(c.entries, path)
+	c.mu.Unlock()
+}
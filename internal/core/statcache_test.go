@@ -0,0 +1,77 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatCache_ReusesUnchangedFilesAndRereadsChanged(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(p, []byte("one"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(p, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewStatCache()
+	if got, err := c.ReadFile(p); err != nil || string(got) != "one" {
+		t.Fatalf("ReadFile = %q, %v", got, err)
+	}
+
+	// Same size and mtime: the cached content is served.
+	if err := os.WriteFile(p, []byte("two"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.ReadFile(p); string(got) != "one" {
+		t.Fatalf("expected cached content, got %q", got)
+	}
+
+	// A new mtime invalidates the entry.
+	newer := old.Add(time.Minute)
+	if err := os.Chtimes(p, newer, newer); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.ReadFile(p); string(got) != "two" {
+		t.Fatalf("expected fresh content, got %q", got)
+	}
+
+	if err := os.Remove(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ReadFile(p); !os.IsNotExist(err) {
+		t.Fatalf("expected not-exist, got %v", err)
+	}
+	if c.Len() != 0 {
+		t.Fatalf("removed file must be forgotten")
+	}
+}
+
+func TestStatCache_DistrustsRecentlyModifiedFiles(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "in.txt")
+	if err := os.WriteFile(p, []byte("one"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := NewStatCache()
+	if _, err := c.ReadFile(p); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(p)
+	// Rewrite within the same timestamp tick.
+	if err := os.WriteFile(p, []byte("two"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.ReadFile(p); string(got) != "two" {
+		t.Fatalf("racily-clean file must be re-read, got %q", got)
+	}
+}
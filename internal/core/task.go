@@ -9,6 +9,11 @@
 //   - Structures support exact serialization for hash computation
 package core
 
+import (
+	"fmt"
+	"runtime"
+)
+
 // Task represents a declarative definition of work to be executed deterministically.
 //
 // From data-dictionary.md:
@@ -19,7 +24,9 @@ package core
 // From spec.md Task Definition Format:
 //
 //	Required: name, inputs, run
-//	Optional: env, outputs
+//	Optional: env, outputs, runner, timeout_seconds, retries
+//
+// A fetch task declares fetch instead of run.
 type Task struct {
 	// Name is the logical identifier for the task.
 	// Used only for user reference; does not affect task identity/hash.
@@ -43,4 +50,102 @@ type Task struct {
 	// Only declared outputs are eligible for artifact capture and caching.
 	// Optional field.
 	Outputs []string `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+
+	// Runner names the plugin-provided runner that executes the task.
+	// Empty selects the built-in local executor.
+	// Optional field.
+	Runner string `json:"runner,omitempty" yaml:"runner,omitempty"`
+
+	// Network set to false runs the command without network access, so that a
+	// hermetic task provably cannot fetch dependencies. Enforced with a
+	// network namespace on Linux; elsewhere it is not enforced.
+	// Optional field; network access is allowed by default.
+	Network *bool `json:"network,omitempty" yaml:"network,omitempty"`
+
+	// Fetch makes the task a fetch task, which downloads a URL to its single
+	// declared output instead of running a command (see Fetcher).
+	// Optional field; exclusive with Run.
+	Fetch *Fetch `json:"fetch,omitempty" yaml:"fetch,omitempty"`
+
+	// PlatformIndependent marks a task whose result does not depend on the
+	// operating system and architecture it runs on, such as one that only
+	// copies or templates files, so that its cache entries are shared
+	// across platforms.
+	// Optional field; results are platform-specific by default.
+	PlatformIndependent bool `json:"platform_independent,omitempty" yaml:"platform_independent,omitempty"`
+
+	// Mutex names a lock the task holds while it runs: tasks sharing a mutex
+	// never run concurrently, even when the graph allows it, such as tasks
+	// using the same local database. It does not affect task identity/hash.
+	// Optional field.
+	Mutex string `json:"mutex,omitempty" yaml:"mutex,omitempty"`
+
+	// Labels classify the task, for instance "network" for tasks calling an
+	// external service, so that the scheduler can limit how many tasks of a
+	// class run at once. They do not affect task identity/hash.
+	// Optional field.
+	Labels []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// TimeoutSeconds bounds each execution of the task: an execution still
+	// running after it is killed and fails with TimeoutExitCode.
+	// Optional field; 0 means no timeout.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
+
+	// Retries is the number of times a failed execution of the task is
+	// repeated, within the same run, before the task fails.
+	// Optional field; at most MaxTaskRetries.
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+
+	// Metadata describes the task for the people operating the graph and is
+	// reported when the task fails. It does not affect task identity/hash.
+	// Optional field.
+	Metadata *TaskMetadata `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// MaxTaskRetries bounds Task.Retries.
+const MaxTaskRetries = 10
+
+// TimeoutExitCode is the exit code of a task execution that exceeded its
+// timeout, as with timeout(1).
+const TimeoutExitCode = 124
+
+// ValidateLimits checks the timeout and retries of the task.
+func (t Task) ValidateLimits() error {
+	if t.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout_seconds must not be negative")
+	}
+	if t.Retries < 0 || t.Retries > MaxTaskRetries {
+		return fmt.Errorf("retries must be between 0 and %d", MaxTaskRetries)
+	}
+	return nil
+}
+
+// TaskMetadata annotates a task with who owns it and where it is documented,
+// so that whoever is on call when it fails knows whom to ask and where its
+// runbook lives.
+type TaskMetadata struct {
+	Owner       string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// DocsURL links the task's documentation or runbook.
+	DocsURL string `json:"docs_url,omitempty" yaml:"docs_url,omitempty"`
+}
+
+// NetworkAllowed reports whether the task may access the network.
+func (t Task) NetworkAllowed() bool {
+	return t.Network == nil || *t.Network
+}
+
+// Platform identifies the operating system and architecture the engine runs
+// on, as "GOOS/GOARCH".
+func Platform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// HashPlatform returns the platform the hash of t depends on: Platform, or
+// "" for a platform-independent task.
+func (t Task) HashPlatform() string {
+	if t.PlatformIndependent {
+		return ""
+	}
+	return Platform()
 }
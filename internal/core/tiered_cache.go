@@ -0,0 +1,190 @@
+package core
+
+import "fmt"
+
+// CacheWritePolicy selects the tiers of a TieredCache that results are
+// written to.
+type CacheWritePolicy string
+
+const (
+	// WriteLocal writes results to the first tier only; the other tiers are
+	// read-only, as when a CI job populates a shared cache for developers.
+	WriteLocal CacheWritePolicy = "write-local"
+	// WriteAll writes results to every tier.
+	WriteAll CacheWritePolicy = "write-all"
+)
+
+// TierGetter is implemented by caches made of tiers that can tell which tier
+// an entry was read from. It is optional; other caches are a single tier.
+type TierGetter interface {
+	GetTier(hash TaskHash) (*CacheEntry, int, error)
+}
+
+// TieredCache is an ordered list of caches read through in order: the first
+// tier is the local cache, later tiers are typically directories on a shared
+// network filesystem, so that a team on a LAN shares results without a cache
+// server.
+//
+// An entry found in a later tier is copied into the first tier, so it is read
+// locally from then on. Only the first tier is authoritative: errors from
+// later tiers, such as an unreachable mount, are treated as misses on reads
+// and ignored on writes, so a shared tier can never fail a run.
+type TieredCache struct {
+	Tiers []Cache
+	Write CacheWritePolicy
+}
+
+// NewTieredCache creates a TieredCache over tiers, the first of which is the
+// local cache.
+func NewTieredCache(write CacheWritePolicy, tiers ...Cache) (*TieredCache, error) {
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("tiered cache needs at least one tier")
+	}
+	switch write {
+	case WriteLocal, WriteAll:
+	default:
+		return nil, fmt.Errorf("unknown cache write policy %q", write)
+	}
+	return &TieredCache{Tiers: tiers, Write: write}, nil
+}
+
+// Has reports whether any tier holds an entry for hash.
+func (c *TieredCache) Has(hash TaskHash) (bool, error) {
+	for i, tier := range c.Tiers {
+		ok, err := tier.Has(hash)
+		if err != nil {
+			if i == 0 {
+				return false, err
+			}
+			continue
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasBatch reports which of hashes any tier holds, asking each tier once for
+// the hashes the tiers before it lack.
+func (c *TieredCache) HasBatch(hashes []TaskHash) (map[TaskHash]bool, error) {
+	out := make(map[TaskHash]bool, len(hashes))
+	missing := hashes
+	for i, tier := range c.Tiers {
+		if len(missing) == 0 {
+			break
+		}
+		found, err := HasBatch(tier, missing)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			continue
+		}
+		var still []TaskHash
+		for _, h := range missing {
+			if found[h] {
+				out[h] = true
+			} else {
+				still = append(still, h)
+			}
+		}
+		missing = still
+	}
+	for _, h := range missing {
+		out[h] = false
+	}
+	return out, nil
+}
+
+// Get returns the entry of the first tier that holds hash, copying it into
+// the first tier when it came from a later one.
+func (c *TieredCache) Get(hash TaskHash) (*CacheEntry, error) {
+	entry, _, err := c.GetTier(hash)
+	return entry, err
+}
+
+// GetTier is Get that also returns the index in Tiers of the tier the entry
+// was read from.
+func (c *TieredCache) GetTier(hash TaskHash) (*CacheEntry, int, error) {
+	for i, tier := range c.Tiers {
+		entry, err := tier.Get(hash)
+		if err != nil {
+			if i == 0 {
+				return nil, 0, err
+			}
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+		if i > 0 {
+			// Best-effort: a failed copy only costs another shared read.
+			_ = c.Tiers[0].Put(entry)
+		}
+		return entry, i, nil
+	}
+	return nil, 0, nil
+}
+
+// SavingPutter is implemented by caches that skip writing entries to tiers
+// that already hold them, and report the bytes they did not upload.
+type SavingPutter interface {
+	PutSaving(entry *CacheEntry) (saved int64, err error)
+}
+
+// Put stores entry in the first tier and, with WriteAll, in every other tier.
+func (c *TieredCache) Put(entry *CacheEntry) error {
+	_, err := c.PutSaving(entry)
+	return err
+}
+
+// PutSaving is Put that writes entry to the other tiers only if they do not
+// hold it yet (see ConditionalPutter), so that concurrent runs producing the
+// same result do not upload it again. It returns the size of the stdout,
+// stderr and artifacts of entry times the number of tiers it was not
+// written to.
+func (c *TieredCache) PutSaving(entry *CacheEntry) (int64, error) {
+	if err := c.Tiers[0].Put(entry); err != nil {
+		return 0, err
+	}
+	var saved int64
+	if c.Write == WriteAll {
+		for _, tier := range c.Tiers[1:] {
+			if stored, err := PutIfAbsent(tier, entry); err == nil && !stored {
+				saved += entry.size()
+			}
+		}
+	}
+	return saved, nil
+}
+
+// TransferStats sums the traffic of the tiers that count it.
+func (c *TieredCache) TransferStats() TransferStats {
+	var out TransferStats
+	for _, tier := range c.Tiers {
+		if tc, ok := tier.(TransferCounter); ok {
+			out.Add(tc.TransferStats())
+		}
+	}
+	return out
+}
+
+// Evict removes the entry for hash from the first tier and, with WriteAll,
+// from every other tier that supports eviction. Read-only tiers are left
+// alone; the entry re-executed after a corrupt read is then found locally.
+func (c *TieredCache) Evict(hash TaskHash) error {
+	for i, tier := range c.Tiers {
+		if i > 0 && c.Write != WriteAll {
+			break
+		}
+		ev, ok := tier.(CacheEvicter)
+		if !ok {
+			continue
+		}
+		if err := ev.Evict(hash); err != nil && i == 0 {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// brokenCache fails every operation, like a cache on an unreachable mount.
+type brokenCache struct{}
+
+func (brokenCache) Has(TaskHash) (bool, error)        { return false, errors.New("stale file handle") }
+func (brokenCache) Get(TaskHash) (*CacheEntry, error) { return nil, errors.New("stale file handle") }
+func (brokenCache) Put(*CacheEntry) error             { return errors.New("stale file handle") }
+
+func TestTieredCache_ReadsThroughAndCopiesLocally(t *testing.T) {
+	local, shared := NewMemoryCache(), NewMemoryCache()
+	if err := shared.Put(&CacheEntry{Hash: "h1", Stdout: []byte("out")}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewTieredCache(WriteLocal, local, shared)
+	if err != nil {
+		t.Fatalf("NewTieredCache: %v", err)
+	}
+
+	if ok, err := c.Has("h1"); err != nil || !ok {
+		t.Fatalf("Has = %v, %v", ok, err)
+	}
+	entry, err := c.Get("h1")
+	if err != nil || entry == nil || string(entry.Stdout) != "out" {
+		t.Fatalf("Get = %+v, %v", entry, err)
+	}
+	if ok, _ := local.Has("h1"); !ok {
+		t.Fatalf("entry read from a shared tier was not copied locally")
+	}
+	if entry, err := c.Get("missing"); err != nil || entry != nil {
+		t.Fatalf("Get(missing) = %+v, %v", entry, err)
+	}
+}
+
+func TestTieredCache_HasBatch(t *testing.T) {
+	local, shared := NewMemoryCache(), NewMemoryCache()
+	_ = local.Put(&CacheEntry{Hash: "h1"})
+	_ = shared.Put(&CacheEntry{Hash: "h2"})
+	c, err := NewTieredCache(WriteLocal, local, shared, brokenCache{})
+	if err != nil {
+		t.Fatalf("NewTieredCache: %v", err)
+	}
+	got, err := HasBatch(c, []TaskHash{"h1", "h2", "h3"})
+	want := map[TaskHash]bool{"h1": true, "h2": true, "h3": false}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("HasBatch = %v, %v; want %v", got, err, want)
+	}
+}
+
+func TestTieredCache_WritePolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy     CacheWritePolicy
+		wantShared bool
+	}{{WriteLocal, false}, {WriteAll, true}} {
+		local, shared := NewMemoryCache(), NewMemoryCache()
+		c, err := NewTieredCache(tc.policy, local, shared)
+		if err != nil {
+			t.Fatalf("NewTieredCache: %v", err)
+		}
+		if err := c.Put(&CacheEntry{Hash: "h1"}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		inLocal, _ := local.Has("h1")
+		inShared, _ := shared.Has("h1")
+		if !inLocal || inShared != tc.wantShared {
+			t.Errorf("%s: local=%v shared=%v", tc.policy, inLocal, inShared)
+		}
+	}
+	if _, err := NewTieredCache("write-some", NewMemoryCache()); err == nil {
+		t.Fatalf("expected an unknown write policy to be rejected")
+	}
+}
+
+func TestTieredCache_SharedTierErrorsNeverFail(t *testing.T) {
+	local := NewMemoryCache()
+	c, err := NewTieredCache(WriteAll, local, brokenCache{})
+	if err != nil {
+		t.Fatalf("NewTieredCache: %v", err)
+	}
+	if ok, err := c.Has("h1"); err != nil || ok {
+		t.Fatalf("Has = %v, %v", ok, err)
+	}
+	if entry, err := c.Get("h1"); err != nil || entry != nil {
+		t.Fatalf("Get = %+v, %v", entry, err)
+	}
+	if err := c.Put(&CacheEntry{Hash: "h1"}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if ok, _ := local.Has("h1"); !ok {
+		t.Fatalf("entry not written locally")
+	}
+
+	// The local tier stays authoritative.
+	c, _ = NewTieredCache(WriteLocal, brokenCache{}, NewMemoryCache())
+	if err := c.Put(&CacheEntry{Hash: "h1"}); err == nil {
+		t.Fatalf("expected a local write error")
+	}
+}
+
+func TestTieredCache_SkipsUploadsSharedTiersHold(t *testing.T) {
+	shared := NewFileCache(t.TempDir())
+	entry := &CacheEntry{Hash: "h1", Stdout: []byte("out"), Artifacts: []CachedArtifact{{Path: "a", Content: []byte("AAAA")}}}
+	if err := shared.Put(entry); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewTieredCache(WriteAll, NewMemoryCache(), shared)
+	if err != nil {
+		t.Fatalf("NewTieredCache: %v", err)
+	}
+	stats := NewStatsCache(c)
+	if err := stats.Put(entry); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := stats.Put(&CacheEntry{Hash: "h2", Stdout: []byte("new")}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if ok, _ := shared.Has("h2"); !ok {
+		t.Fatalf("new entry was not written to the shared tier")
+	}
+	got := stats.Stats()
+	if got.UploadsSkipped != 1 || got.UploadBytesSaved != 7 {
+		t.Fatalf("stats = %+v, want 1 upload of 7 bytes skipped", got)
+	}
+}
@@ -0,0 +1,189 @@
+// Package cron parses cron expressions and computes the times they match.
+//
+// An expression has the five standard fields, minute (0-59), hour (0-23),
+// day of month (1-31), month (1-12 or JAN-DEC) and day of week (0-7 or
+// SUN-SAT, 0 and 7 both being Sunday), each "*", a value, a range "a-b" or a
+// comma-separated list of those, optionally stepped with "/n". As in Vixie
+// cron, when both day fields are restricted a day matching either matches.
+// The macros @yearly (or @annually), @monthly, @weekly, @daily (or @midnight)
+// and @hourly stand for their usual expressions.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression. Times are matched in their own
+// location.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record an unrestricted day field ("*"), which
+	// decides how the two day fields combine.
+	domAny, dowAny bool
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    []string // names[i] is value min+i
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}}
+	dowField    = field{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}}
+)
+
+// Parse parses a cron expression.
+func Parse(expr string) (Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(spec)]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("cron expression %q: want 5 fields, got %d", expr, len(fields))
+	}
+	var s Schedule
+	var err error
+	if s.minute, _, err = minuteField.parse(fields[0]); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: %w", expr, err)
+	}
+	if s.hour, _, err = hourField.parse(fields[1]); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: %w", expr, err)
+	}
+	if s.dom, s.domAny, err = domField.parse(fields[2]); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: %w", expr, err)
+	}
+	if s.month, _, err = monthField.parse(fields[3]); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: %w", expr, err)
+	}
+	if s.dow, s.dowAny, err = dowField.parse(fields[4]); err != nil {
+		return Schedule{}, fmt.Errorf("cron expression %q: %w", expr, err)
+	}
+	// Day of week 7 is Sunday.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// parse returns the set of values of a field as a bitset, and whether the
+// field starts with "*", which, as in Vixie cron, leaves it unrestricted for
+// combining the day fields.
+func (f field) parse(spec string) (bits uint64, star bool, err error) {
+	star = strings.HasPrefix(spec, "*")
+	for _, part := range strings.Split(spec, ",") {
+		rng, stepSpec, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			if step, err = strconv.Atoi(stepSpec); err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("%s: invalid step %q", f.name, stepSpec)
+			}
+		}
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			a, b, _ := strings.Cut(rng, "-")
+			if lo, err = f.value(a); err != nil {
+				return 0, false, err
+			}
+			if hi, err = f.value(b); err != nil {
+				return 0, false, err
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("%s: empty range %q", f.name, rng)
+			}
+		default:
+			if lo, err = f.value(rng); err != nil {
+				return 0, false, err
+			}
+			if !stepped {
+				hi = lo
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, star, nil
+}
+
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s: invalid value %q (expected %d-%d)", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Matches reports whether s matches the minute of t.
+func (s Schedule) Matches(t time.Time) bool {
+	return s.minute&(1<<t.Minute()) != 0 && s.hour&(1<<t.Hour()) != 0 && s.dayMatches(t)
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	if s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// maxDays bounds the search of Next: every valid expression matches within
+// eight years, the longest gap between two February 29ths.
+const maxDays = 8 * 366
+
+// Next returns the first minute after t that s matches, in t's location, or
+// the zero time when s never matches (such as on February 30th).
+func (s Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	for i := 0; i < maxDays; i++ {
+		if s.dayMatches(day) {
+			for h := 0; h < 24; h++ {
+				if s.hour&(1<<h) == 0 {
+					continue
+				}
+				for m := 0; m < 60; m++ {
+					if s.minute&(1<<m) == 0 {
+						continue
+					}
+					c := time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, loc)
+					// Times skipped by a daylight saving change normalize
+					// to another hour; they never happen.
+					if c.Hour() == h && c.After(t) {
+						return c
+					}
+				}
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+	}
+	return time.Time{}
+}
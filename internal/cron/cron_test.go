@@ -0,0 +1,70 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for _, c := range []struct {
+		expr, after, want string
+	}{
+		{"* * * * *", "2026-03-10 12:00", "2026-03-10 12:01"},
+		{"*/15 * * * *", "2026-03-10 12:07", "2026-03-10 12:15"},
+		{"0 2 * * *", "2026-03-10 02:00", "2026-03-11 02:00"},
+		{"30 9 * * MON-FRI", "2026-03-13 10:00", "2026-03-16 09:30"}, // Friday to Monday
+		{"0 0 1,15 * *", "2026-03-02 00:00", "2026-03-15 00:00"},
+		{"0 0 * * 7", "2026-03-10 00:00", "2026-03-15 00:00"},  // 7 is Sunday
+		{"0 0 13 * 5", "2026-03-10 00:00", "2026-03-13 00:00"}, // the 13th or a Friday
+		{"0 0 29 FEB *", "2026-03-01 00:00", "2028-02-29 00:00"},
+		{"@hourly", "2026-03-10 12:59", "2026-03-10 13:00"},
+		{"@monthly", "2026-12-31 23:59", "2027-01-01 00:00"},
+		{"0 0 30 2 *", "2026-03-01 00:00", ""},
+	} {
+		s, err := Parse(c.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.expr, err)
+		}
+		got := s.Next(at(c.after))
+		if c.want == "" {
+			if !got.IsZero() {
+				t.Errorf("%q after %s = %s, want never", c.expr, c.after, got)
+			}
+			continue
+		}
+		if !got.Equal(at(c.want)) {
+			t.Errorf("%q after %s = %s, want %s", c.expr, c.after, got.Format("2006-01-02 15:04"), c.want)
+		}
+		if !s.Matches(got) {
+			t.Errorf("%q does not match its next time %s", c.expr, got)
+		}
+	}
+}
+
+func TestNext_SkipsTimesLostToDaylightSaving(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database")
+	}
+	s, _ := Parse("30 2 * * *")
+	// 02:30 does not exist on 2026-03-08 in New York.
+	got := s.Next(time.Date(2026, 3, 7, 12, 0, 0, 0, loc))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, loc); !got.Equal(want) {
+		t.Fatalf("Next = %s, want %s", got, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * FOO *", "1,,2 * * * *", "@often"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q): expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,499 @@
+// Package daemon serves runs from a long-lived process with warm state.
+//
+// `sw daemon` listens on <projectRoot>/.scriptweaver/daemon.sock. `sw run`
+// connects to that socket when it exists and forwards its canonical
+// invocation; the daemon executes it through a cli.Session, which keeps parsed
+// graphs and unchanged input contents in memory. Each connection carries one
+// JSON request and one JSON response. A request for metrics instead returns the
+// counters the daemon accumulated over the runs it served, a request for
+// schedules the next and last runs of the workspace's schedules, and a request
+// for the queue the run in progress and the runs waiting for it.
+//
+// Runs are executed one at a time; a run requested while another is active is
+// queued, coalesced or refused per the workspace's run_queue settings (see
+// config.RunQueueConfig).
+//
+// The daemon also starts the runs of the schedules of the workspace config
+// (see config.ScheduleConfig) when they are due, checking at the start of
+// every minute; the config is reloaded each time.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"scriptweaver/internal/cli"
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/cron"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/projectintegration/engine/workspace"
+	"scriptweaver/internal/publish"
+	"scriptweaver/internal/recovery/state"
+)
+
+// ErrAlreadyRunning is returned by Listen when a daemon already serves the project.
+var ErrAlreadyRunning = errors.New("daemon already running")
+
+// ErrScheduleBusy is reported for a schedule that is due while its previous
+// run is still in progress; that occurrence is skipped.
+var ErrScheduleBusy = errors.New("previous scheduled run still in progress")
+
+// dialTimeout bounds how long a client waits to reach a daemon before running locally.
+const dialTimeout = 200 * time.Millisecond
+
+// Request asks the daemon to execute one invocation, or for its metrics,
+// schedules or queue.
+type Request struct {
+	Invocation cli.CLIInvocation `json:"invocation"`
+	Metrics    bool              `json:"metrics,omitempty"`
+	Schedules  bool              `json:"schedules,omitempty"`
+	Queue      bool              `json:"queue,omitempty"`
+}
+
+// Metrics are the counters a daemon accumulated since it started.
+type Metrics struct {
+	// Runs is the number of runs served.
+	Runs int `json:"runs"`
+	// Cache sums the cache statistics of those runs.
+	Cache core.CacheStats `json:"cache"`
+}
+
+// Error kinds preserved across the socket so callers can classify failures.
+const (
+	ErrorKindGraph = "graph"
+	ErrorKindCycle = "cycle"
+	ErrorKindBusy  = "busy"
+)
+
+// Response carries the parts of a cli.CLIResult that callers report on.
+type Response struct {
+	ExitCode  int    `json:"exit_code"`
+	Error     string `json:"error,omitempty"`
+	ErrorKind string `json:"error_kind,omitempty"`
+
+	Deduplicated     map[string]string        `json:"deduplicated,omitempty"`
+	SkippedBy        map[string]string        `json:"skipped_by,omitempty"`
+	Nondeterministic []core.DeterminismResult `json:"nondeterministic,omitempty"`
+	PublishedTo      string                   `json:"published_to,omitempty"`
+	Published        []publish.File           `json:"published,omitempty"`
+	Duration         time.Duration            `json:"duration_ns"`
+	NodeDurations    map[string]time.Duration `json:"node_durations_ns,omitempty"`
+	Phases           []cli.PhaseTiming        `json:"phases,omitempty"`
+	RunID            string                   `json:"run_id,omitempty"`
+	Resume           *cli.ResumeReport        `json:"resume,omitempty"`
+	Retries          []cli.RetryAttempt       `json:"retries,omitempty"`
+	Failure          *state.Failure           `json:"failure,omitempty"`
+	NodeFailures     []state.NodeFailure      `json:"node_failures,omitempty"`
+	Outputs          []cli.NodeOutputs        `json:"outputs,omitempty"`
+	StaleOutputs     []cli.StaleOutput        `json:"stale_outputs,omitempty"`
+	CacheDir         string                   `json:"cache_dir,omitempty"`
+	CacheStats       *core.CacheStats         `json:"cache_stats,omitempty"`
+	Chaos            *cli.ChaosReport         `json:"chaos,omitempty"`
+	Purged           *cli.PurgeReport         `json:"purged,omitempty"`
+	NotifyError      string                   `json:"notify_error,omitempty"`
+
+	Metrics   *Metrics             `json:"metrics,omitempty"`
+	Schedules []cli.ScheduleStatus `json:"schedules,omitempty"`
+	Queue     *cli.QueueState      `json:"queue,omitempty"`
+}
+
+// Server executes forwarded and scheduled runs for a single project root.
+type Server struct {
+	// OnSchedule, when set before Serve, is called after every scheduled run
+	// with its result, or with the error that kept it from running. name is
+	// empty when the schedules could not be loaded.
+	OnSchedule func(name string, res cli.CLIResult, err error)
+
+	root    string
+	path    string
+	session *cli.Session
+	ln      net.Listener
+	wg      sync.WaitGroup
+	now     func() time.Time
+	done    chan struct{}
+	closing sync.Once
+
+	mu      sync.Mutex
+	metrics Metrics
+	// scheduled holds the schedules with a run in progress.
+	scheduled map[string]bool
+	// configErr is the last error loading the schedules, reported once.
+	configErr string
+}
+
+// Listen validates the workspace at projectRoot and binds its daemon socket.
+// A stale socket left by a crashed daemon is replaced.
+func Listen(projectRoot string) (*Server, error) {
+	root := filepath.Clean(projectRoot)
+	if _, err := workspace.EnsureWorkspace(root); err != nil {
+		return nil, err
+	}
+	path := workspace.SocketPath(root)
+	if _, err := os.Lstat(path); err == nil {
+		if conn, derr := net.DialTimeout("unix", path, dialTimeout); derr == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%w: %s", ErrAlreadyRunning, path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	return &Server{root: root, path: path, session: cli.NewSession(), ln: ln, now: time.Now, done: make(chan struct{}), scheduled: make(map[string]bool)}, nil
+}
+
+// SocketPath returns the socket the server listens on.
+func (s *Server) SocketPath() string { return s.path }
+
+// Serve accepts connections, and starts scheduled runs, until Close is called.
+func (s *Server) Serve() error {
+	s.wg.Add(1)
+	go s.runSchedules()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				s.wg.Wait()
+				return nil
+			}
+			return err
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// Close stops accepting connections and scheduling runs, waits for in-flight
+// runs and removes the socket.
+func (s *Server) Close() error {
+	s.closing.Do(func() { close(s.done) })
+	err := s.ln.Close()
+	s.wg.Wait()
+	return err
+}
+
+// runSchedules starts the scheduled runs due at the start of every minute
+// until the server closes.
+func (s *Server) runSchedules() {
+	defer s.wg.Done()
+	for {
+		now := s.now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-s.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.startDue(next)
+	}
+}
+
+// startDue starts a run of every schedule due at minute t, unless its
+// previous run is still in progress.
+func (s *Server) startDue(t time.Time) {
+	cfg, _, err := config.LoadOptional(s.root)
+	s.mu.Lock()
+	repeated := err != nil && err.Error() == s.configErr
+	s.configErr = ""
+	if err != nil {
+		s.configErr = err.Error()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		if !repeated {
+			s.report("", cli.CLIResult{}, err)
+		}
+		return
+	}
+	for _, sc := range cfg.Schedules {
+		if sched, err := cron.Parse(sc.Cron); err != nil || !sched.Matches(t) {
+			continue
+		}
+		s.mu.Lock()
+		busy := s.scheduled[sc.Name]
+		s.scheduled[sc.Name] = true
+		s.mu.Unlock()
+		if busy {
+			s.report(sc.Name, cli.CLIResult{}, ErrScheduleBusy)
+			continue
+		}
+		s.wg.Add(1)
+		go func(sc config.ScheduleConfig) {
+			defer s.wg.Done()
+			res, err := s.runScheduled(cfg, sc)
+			s.mu.Lock()
+			delete(s.scheduled, sc.Name)
+			s.mu.Unlock()
+			s.report(sc.Name, res, err)
+		}(sc)
+	}
+}
+
+func (s *Server) runScheduled(cfg config.Config, sc config.ScheduleConfig) (cli.CLIResult, error) {
+	inv, err := cli.ScheduledInvocation(s.root, cfg, sc)
+	if err != nil {
+		return cli.CLIResult{ExitCode: cli.ExitInvalidInvocation}, err
+	}
+	res, err := s.session.Execute(context.Background(), inv)
+	s.count(res)
+	return res, err
+}
+
+func (s *Server) report(name string, res cli.CLIResult, err error) {
+	if s.OnSchedule != nil {
+		s.OnSchedule(name, res, err)
+	}
+}
+
+// count adds a served run to the metrics.
+func (s *Server) count(res cli.CLIResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metrics.Runs++
+	if res.CacheStats != nil {
+		s.metrics.Cache.Add(*res.CacheStats)
+	}
+}
+
+// schedules returns the schedules of the workspace with their next and last
+// runs.
+func (s *Server) schedules() ([]cli.ScheduleStatus, error) {
+	cfg, _, err := config.LoadOptional(s.root)
+	if err != nil {
+		return nil, err
+	}
+	st, err := state.NewStore(s.root)
+	if err != nil {
+		return nil, err
+	}
+	return cli.ScheduleStatuses(st, cfg, s.now())
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		_ = json.NewEncoder(conn).Encode(Response{ExitCode: cli.ExitInvalidInvocation, Error: fmt.Sprintf("decode request: %v", err)})
+		return
+	}
+	if req.Metrics {
+		s.mu.Lock()
+		m := s.metrics
+		s.mu.Unlock()
+		_ = json.NewEncoder(conn).Encode(Response{Metrics: &m})
+		return
+	}
+	if req.Queue {
+		q := s.session.Queue()
+		_ = json.NewEncoder(conn).Encode(Response{Queue: &q})
+		return
+	}
+	if req.Schedules {
+		statuses, err := s.schedules()
+		if err != nil {
+			_ = json.NewEncoder(conn).Encode(Response{ExitCode: cli.ExitConfigError, Error: err.Error()})
+			return
+		}
+		if statuses == nil {
+			statuses = []cli.ScheduleStatus{}
+		}
+		_ = json.NewEncoder(conn).Encode(Response{Schedules: statuses})
+		return
+	}
+	if filepath.Clean(req.Invocation.WorkDir) != s.root {
+		_ = json.NewEncoder(conn).Encode(Response{ExitCode: cli.ExitInvalidInvocation, Error: fmt.Sprintf("daemon serves %s, not %s", s.root, req.Invocation.WorkDir)})
+		return
+	}
+	res, err := s.session.Execute(context.Background(), req.Invocation)
+	s.count(res)
+	_ = json.NewEncoder(conn).Encode(newResponse(res, err))
+}
+
+func newResponse(res cli.CLIResult, err error) Response {
+	resp := Response{
+		ExitCode:         res.ExitCode,
+		Nondeterministic: res.Nondeterministic,
+		PublishedTo:      res.PublishedTo,
+		Published:        res.Published,
+		Duration:         res.Duration,
+		NodeDurations:    res.NodeDurations,
+		Phases:           res.Phases,
+		RunID:            res.RunID,
+		Resume:           res.Resume,
+		Retries:          res.Retries,
+		Failure:          res.Failure,
+		NodeFailures:     res.NodeFailures,
+		Outputs:          res.Outputs,
+		StaleOutputs:     res.StaleOutputs,
+		CacheDir:         res.CacheDir,
+		CacheStats:       res.CacheStats,
+		Chaos:            res.Chaos,
+		Purged:           res.Purged,
+		NotifyError:      res.NotifyError,
+	}
+	if res.GraphResult != nil {
+		resp.Deduplicated = res.GraphResult.Deduplicated
+		resp.SkippedBy = res.GraphResult.SkippedBy
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		var ge *dag.GraphError
+		switch {
+		case errors.Is(err, dag.ErrCycleFound):
+			resp.ErrorKind = ErrorKindCycle
+		case errors.As(err, &ge):
+			resp.ErrorKind = ErrorKindGraph
+		case errors.Is(err, cli.ErrWorkspaceBusy):
+			resp.ErrorKind = ErrorKindBusy
+		}
+	}
+	return resp
+}
+
+// result rebuilds the caller-facing result and error from a response.
+func (r Response) result() (cli.CLIResult, error) {
+	res := cli.CLIResult{
+		ExitCode:         r.ExitCode,
+		Nondeterministic: r.Nondeterministic,
+		PublishedTo:      r.PublishedTo,
+		Published:        r.Published,
+		Duration:         r.Duration,
+		NodeDurations:    r.NodeDurations,
+		Phases:           r.Phases,
+		RunID:            r.RunID,
+		Resume:           r.Resume,
+		Retries:          r.Retries,
+		Failure:          r.Failure,
+		NodeFailures:     r.NodeFailures,
+		Outputs:          r.Outputs,
+		StaleOutputs:     r.StaleOutputs,
+		CacheDir:         r.CacheDir,
+		CacheStats:       r.CacheStats,
+		Chaos:            r.Chaos,
+		Purged:           r.Purged,
+		NotifyError:      r.NotifyError,
+		GraphResult:      &dag.GraphResult{Deduplicated: r.Deduplicated, SkippedBy: r.SkippedBy},
+	}
+	if r.Error == "" {
+		return res, nil
+	}
+	switch r.ErrorKind {
+	case ErrorKindCycle:
+		return res, &dag.GraphError{Kind: dag.ErrCycleFound}
+	case ErrorKindGraph:
+		return res, &dag.GraphError{Kind: errors.New(r.Error)}
+	case ErrorKindBusy:
+		return res, fmt.Errorf("%w%s", cli.ErrWorkspaceBusy, strings.TrimPrefix(r.Error, cli.ErrWorkspaceBusy.Error()))
+	default:
+		return res, errors.New(r.Error)
+	}
+}
+
+// Run forwards inv to the daemon serving inv.WorkDir.
+//
+// served is false when no daemon is listening; the caller should then execute
+// locally. Once a daemon accepted the connection, failures are returned as errors.
+func Run(inv cli.CLIInvocation) (res cli.CLIResult, served bool, err error) {
+	conn, err := net.DialTimeout("unix", workspace.SocketPath(inv.WorkDir), dialTimeout)
+	if err != nil {
+		return cli.CLIResult{}, false, nil
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(Request{Invocation: inv}); err != nil {
+		return cli.CLIResult{}, true, fmt.Errorf("daemon: send request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return cli.CLIResult{}, true, fmt.Errorf("daemon: read response: %w", err)
+	}
+	res, err = resp.result()
+	return res, true, err
+}
+
+// FetchMetrics returns the metrics of the daemon serving projectRoot. served
+// is false when no daemon is listening.
+func FetchMetrics(projectRoot string) (m Metrics, served bool, err error) {
+	conn, err := net.DialTimeout("unix", workspace.SocketPath(projectRoot), dialTimeout)
+	if err != nil {
+		return Metrics{}, false, nil
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(Request{Metrics: true}); err != nil {
+		return Metrics{}, true, fmt.Errorf("daemon: send request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Metrics{}, true, fmt.Errorf("daemon: read response: %w", err)
+	}
+	if resp.Metrics == nil {
+		return Metrics{}, true, fmt.Errorf("daemon: no metrics in response")
+	}
+	return *resp.Metrics, true, nil
+}
+
+// FetchSchedules returns the schedules of projectRoot, with their next and
+// last runs, from the daemon serving it. served is false when no daemon is
+// listening, so no schedule is being run.
+func FetchSchedules(projectRoot string) (statuses []cli.ScheduleStatus, served bool, err error) {
+	conn, err := net.DialTimeout("unix", workspace.SocketPath(projectRoot), dialTimeout)
+	if err != nil {
+		return nil, false, nil
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(Request{Schedules: true}); err != nil {
+		return nil, true, fmt.Errorf("daemon: send request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, true, fmt.Errorf("daemon: read response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, true, fmt.Errorf("daemon: %s", resp.Error)
+	}
+	if resp.Schedules == nil {
+		return nil, true, fmt.Errorf("daemon: no schedules in response")
+	}
+	return resp.Schedules, true, nil
+}
+
+// FetchQueue returns the run queue of the daemon serving projectRoot. served
+// is false when no daemon is listening.
+func FetchQueue(projectRoot string) (q cli.QueueState, served bool, err error) {
+	conn, err := net.DialTimeout("unix", workspace.SocketPath(projectRoot), dialTimeout)
+	if err != nil {
+		return cli.QueueState{}, false, nil
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(Request{Queue: true}); err != nil {
+		return cli.QueueState{}, true, fmt.Errorf("daemon: send request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return cli.QueueState{}, true, fmt.Errorf("daemon: read response: %w", err)
+	}
+	if resp.Queue == nil {
+		return cli.QueueState{}, true, fmt.Errorf("daemon: no queue in response")
+	}
+	return *resp.Queue, true, nil
+}
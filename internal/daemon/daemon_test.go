@@ -0,0 +1,266 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/cli"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/recovery/state"
+)
+
+func startServer(t *testing.T, root string) *Server {
+	t.Helper()
+	srv, err := Listen(root)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve() }()
+	t.Cleanup(func() {
+		_ = srv.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	return srv
+}
+
+func writeGraph(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write graph: %v", err)
+	}
+}
+
+func invocation(root, graph string) cli.CLIInvocation {
+	return cli.CLIInvocation{
+		GraphPath:     graph,
+		WorkDir:       root,
+		CacheDir:      filepath.Join(root, "cache"),
+		OutputDir:     filepath.Join(root, "out"),
+		ExecutionMode: cli.ExecutionModeIncremental,
+	}
+}
+
+func TestRun_ForwardsToDaemon(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".scriptweaver", "config.json"), []byte(`{"retention":{"keep_runs":1}}`), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	startServer(t, root)
+	graph := filepath.Join(root, "g.json")
+	writeGraph(t, graph, `{"tasks":[{"name":"a","inputs":[],"run":"echo a > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`)
+
+	var first string
+	for i := 0; i < 2; i++ {
+		res, served, err := Run(invocation(root, graph))
+		if !served || err != nil || res.ExitCode != cli.ExitSuccess {
+			t.Fatalf("run %d: served=%v exit=%d err=%v", i, served, res.ExitCode, err)
+		}
+		if len(res.Phases) == 0 {
+			t.Fatalf("expected phases in response")
+		}
+		if i == 0 {
+			first = res.RunID
+		} else if res.Purged == nil || len(res.Purged.Purged) != 1 || res.Purged.Purged[0].RunID != first {
+			// The retention summary travels back with the result.
+			t.Fatalf("run %d: purged = %+v, want run %s", i, res.Purged, first)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.txt")); err != nil {
+		t.Fatalf("daemon did not execute the graph: %v", err)
+	}
+}
+
+func TestFetchMetrics_SumsServedRuns(t *testing.T) {
+	root := t.TempDir()
+	startServer(t, root)
+	graph := filepath.Join(root, "g.json")
+	writeGraph(t, graph, `{"tasks":[{"name":"a","inputs":[],"run":"echo a > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`)
+
+	for i := 0; i < 3; i++ {
+		res, _, err := Run(invocation(root, graph))
+		if err != nil || res.CacheStats == nil {
+			t.Fatalf("run %d: stats %+v, %v", i, res.CacheStats, err)
+		}
+	}
+	m, served, err := FetchMetrics(root)
+	if !served || err != nil {
+		t.Fatalf("served=%v err=%v", served, err)
+	}
+	if m.Runs != 3 || m.Cache.Hits != 2 || m.Cache.Misses != 1 || m.Cache.BytesRestored != 4 {
+		t.Fatalf("metrics = %+v", m)
+	}
+}
+
+func TestFetchQueue_ReportsActiveRunAndRefusals(t *testing.T) {
+	root := t.TempDir()
+	startServer(t, root)
+	if err := os.MkdirAll(filepath.Join(root, ".scriptweaver"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".scriptweaver", "config.json"), []byte(`{"run_queue":{"policy":"reject"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	graph := filepath.Join(root, "g.json")
+	writeGraph(t, graph, `{"tasks":[{"name":"wait","inputs":[],"run":"while [ ! -f go ]; do sleep 0.01; done","env":{},"outputs":[]}],"edges":[]}`)
+
+	q, served, err := FetchQueue(root)
+	if !served || err != nil || q.Active != nil || len(q.Queued) != 0 {
+		t.Fatalf("idle queue = %+v, served=%v err=%v", q, served, err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := Run(invocation(root, graph))
+		done <- err
+	}()
+	deadline := time.Now().Add(10 * time.Second)
+	for q.Active == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("run never became active")
+		}
+		time.Sleep(5 * time.Millisecond)
+		q, _, _ = FetchQueue(root)
+	}
+	if q.Active.Graph != "g.json" {
+		t.Fatalf("active = %+v", q.Active)
+	}
+	res, _, err := Run(invocation(root, graph))
+	if !errors.Is(err, cli.ErrWorkspaceBusy) || res.ExitCode != cli.ExitConfigError {
+		t.Fatalf("second run: exit=%d err=%v", res.ExitCode, err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+}
+
+func TestRun_PreservesGraphErrorKinds(t *testing.T) {
+	root := t.TempDir()
+	startServer(t, root)
+	graph := filepath.Join(root, "cycle.json")
+	writeGraph(t, graph, `{"tasks":[{"name":"a","inputs":[],"run":"true","env":{},"outputs":[]},{"name":"b","inputs":[],"run":"true","env":{},"outputs":[]}],"edges":[{"From":"a","To":"b"},{"From":"b","To":"a"}]}`)
+
+	_, served, err := Run(invocation(root, graph))
+	if !served || !errors.Is(err, dag.ErrCycleFound) {
+		t.Fatalf("served=%v err=%v", served, err)
+	}
+}
+
+func TestRun_NoDaemonIsNotServed(t *testing.T) {
+	_, served, err := Run(invocation(t.TempDir(), "g.json"))
+	if served || err != nil {
+		t.Fatalf("served=%v err=%v", served, err)
+	}
+}
+
+func TestListen_RejectsSecondDaemon(t *testing.T) {
+	root := t.TempDir()
+	startServer(t, root)
+	if _, err := Listen(root); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("expected ErrAlreadyRunning, got %v", err)
+	}
+}
+
+func TestServer_RejectsOtherWorkdir(t *testing.T) {
+	root := t.TempDir()
+	srv := startServer(t, root)
+	other := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(other, ".scriptweaver"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(srv.SocketPath(), filepath.Join(other, ".scriptweaver", "daemon.sock")); err != nil {
+		t.Fatal(err)
+	}
+	_, served, err := Run(invocation(other, "g.json"))
+	if !served || err == nil {
+		t.Fatalf("served=%v err=%v", served, err)
+	}
+}
+
+type scheduledRun struct {
+	name string
+	res  cli.CLIResult
+	err  error
+}
+
+func TestServer_RunsDueSchedules(t *testing.T) {
+	root := t.TempDir()
+	writeGraph(t, filepath.Join(root, "g.json"), `{"params":{"word":{}},"tasks":[{"name":"a","inputs":[],"run":"echo {{word}} > a.txt","env":{},"outputs":["a.txt"]}],"edges":[]}`)
+	if err := os.MkdirAll(filepath.Join(root, ".scriptweaver"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// The schedule is only due on February 29th at 03:00, so the minute
+	// ticker of the server does not start it during the test.
+	writeGraph(t, filepath.Join(root, ".scriptweaver", "config.json"),
+		`{"schedules":[{"name":"leap","cron":"0 3 29 2 *","graph":"g.json","set":{"word":"hi"}}]}`)
+
+	srv, err := Listen(root)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	runs := make(chan scheduledRun, 4)
+	srv.OnSchedule = func(name string, res cli.CLIResult, err error) { runs <- scheduledRun{name, res, err} }
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve() }()
+	t.Cleanup(func() {
+		_ = srv.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	due := time.Date(2028, 2, 29, 3, 0, 0, 0, time.Local)
+	srv.startDue(due.Add(time.Minute))
+	srv.startDue(due)
+	r := <-runs
+	if r.name != "leap" || r.err != nil || r.res.ExitCode != cli.ExitSuccess {
+		t.Fatalf("scheduled run = %+v", r)
+	}
+	st, _ := state.NewStore(root)
+	if rp, err := cli.LoadRunParams(st, r.res.RunID); err != nil || rp.Schedule != "leap" || rp.Set["word"] != "hi" || rp.Trace == "" {
+		t.Fatalf("run params = %+v, %v", rp, err)
+	}
+	if b, err := os.ReadFile(filepath.Join(root, "a.txt")); err != nil || string(b) != "hi\n" {
+		t.Fatalf("a.txt = %q, %v", b, err)
+	}
+
+	statuses, served, err := FetchSchedules(root)
+	if !served || err != nil || len(statuses) != 1 {
+		t.Fatalf("FetchSchedules: %+v served=%v err=%v", statuses, served, err)
+	}
+	if s := statuses[0]; s.LastRunID != r.res.RunID || s.LastStatus != cli.RunStatusSucceeded || !s.Next.Equal(due) {
+		t.Fatalf("status = %+v", s)
+	}
+
+	// A schedule whose previous run is in progress is skipped.
+	srv.mu.Lock()
+	srv.scheduled["leap"] = true
+	srv.mu.Unlock()
+	srv.startDue(due)
+	if r := <-runs; r.name != "leap" || !errors.Is(r.err, ErrScheduleBusy) {
+		t.Fatalf("busy schedule = %+v", r)
+	}
+
+	// An invalid config is reported once.
+	writeGraph(t, filepath.Join(root, ".scriptweaver", "config.json"), `{"schedules":[{"name":"leap"}]}`)
+	srv.startDue(due)
+	srv.startDue(due)
+	if r := <-runs; r.name != "" || r.err == nil {
+		t.Fatalf("invalid config = %+v", r)
+	}
+	select {
+	case r := <-runs:
+		t.Fatalf("unexpected report %+v", r)
+	default:
+	}
+}
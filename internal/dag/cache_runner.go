@@ -3,6 +3,7 @@ package dag
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"scriptweaver/internal/core"
 )
@@ -35,6 +36,18 @@ type NodeResult struct {
 // and bit-for-bit replay.
 type CacheAwareRunner struct {
 	Runner *core.Runner
+
+	// probed holds the answers of ProbeBatch by task name until the task's
+	// next Probe.
+	mu     sync.Mutex
+	probed map[string]probeAnswer
+}
+
+// probeAnswer is whether the cache held the entry for hash when it was
+// checked in a batch.
+type probeAnswer struct {
+	hash   core.TaskHash
+	exists bool
 }
 
 func NewCacheAwareRunner(r *core.Runner) (*CacheAwareRunner, error) {
@@ -49,6 +62,8 @@ func (r *CacheAwareRunner) Run(ctx context.Context, task core.Task) (*NodeResult
 	if err != nil {
 		return nil, err
 	}
+	// The result may now be cached: a batch answer for the same hash is stale.
+	r.forget(res.Hash)
 	return &NodeResult{
 		Hash:              res.Hash,
 		Stdout:            res.Stdout,
@@ -68,19 +83,10 @@ func (r *CacheAwareRunner) Restore(ctx context.Context, task core.Task) (*NodeRe
 		return nil, fmt.Errorf("nil core runner")
 	}
 
-	inputSet, err := r.Runner.Resolver.Resolve(task.Inputs)
+	hash, err := r.taskHash(task)
 	if err != nil {
-		return nil, fmt.Errorf("resolving inputs: %w", err)
-	}
-
-	hashInput := core.HashInput{
-		Inputs:     inputSet,
-		Command:    task.Run,
-		Env:        task.Env,
-		Outputs:    task.Outputs,
-		WorkingDir: r.Runner.WorkingDir,
+		return nil, err
 	}
-	hash := r.Runner.Hasher.ComputeHash(hashInput)
 
 	entry, err := r.Runner.Cache.Get(hash)
 	if err != nil {
@@ -92,7 +98,7 @@ func (r *CacheAwareRunner) Restore(ctx context.Context, task core.Task) (*NodeRe
 
 	restored, err := r.Runner.Replayer.RestoreArtifacts(task.Name, entry)
 	if err != nil {
-		return nil, err
+		return nil, core.EvictOnCorruption(r.Runner.Cache, hash, err)
 	}
 
 	return &NodeResult{
@@ -112,27 +118,20 @@ func (r *CacheAwareRunner) Probe(ctx context.Context, task core.Task) (*NodeResu
 	if task.Name == "" {
 		return nil, false, fmt.Errorf("task name is required")
 	}
-	if task.Run == "" {
+	if task.Run == "" && task.Fetch == nil {
 		return nil, false, fmt.Errorf("task run command is required")
 	}
 
-	inputSet, err := r.Runner.Resolver.Resolve(task.Inputs)
-	if err != nil {
-		return nil, false, fmt.Errorf("resolving inputs: %w", err)
-	}
-
-	hashInput := core.HashInput{
-		Inputs:     inputSet,
-		Command:    task.Run,
-		Env:        task.Env,
-		Outputs:    task.Outputs,
-		WorkingDir: r.Runner.WorkingDir,
-	}
-	hash := r.Runner.Hasher.ComputeHash(hashInput)
-
-	exists, err := r.Runner.Cache.Has(hash)
-	if err != nil {
-		return nil, false, fmt.Errorf("checking cache: %w", err)
+	answer, batched := r.takeProbed(task.Name)
+	hash, exists := answer.hash, answer.exists
+	if !batched {
+		var err error
+		if hash, err = r.taskHash(task); err != nil {
+			return nil, false, err
+		}
+		if exists, err = r.Runner.Cache.Has(hash); err != nil {
+			return nil, false, fmt.Errorf("checking cache: %w", err)
+		}
 	}
 	if !exists {
 		return nil, false, nil
@@ -143,12 +142,16 @@ func (r *CacheAwareRunner) Probe(ctx context.Context, task core.Task) (*NodeResu
 		return nil, false, fmt.Errorf("retrieving cache entry: %w", err)
 	}
 	if entry == nil {
+		if batched {
+			// Evicted since the batch: a miss like any other.
+			return nil, false, nil
+		}
 		return nil, false, fmt.Errorf("cache entry disappeared")
 	}
 
 	replayResult, err := r.Runner.Replayer.Replay(entry)
 	if err != nil {
-		return nil, false, fmt.Errorf("replaying cached result: %w", err)
+		return nil, false, fmt.Errorf("replaying cached result: %w", core.EvictOnCorruption(r.Runner.Cache, hash, err))
 	}
 
 	return &NodeResult{
@@ -160,3 +163,71 @@ func (r *CacheAwareRunner) Probe(ctx context.Context, task core.Task) (*NodeResu
 		ArtifactsRestored: replayResult.ArtifactsRestored,
 	}, true, nil
 }
+
+// ProbeBatch checks the cache for all of tasks in one batch (see
+// core.HasBatch) and keeps the answers for their next Probe, which then only
+// reads and replays the entries found. Tasks that cannot be hashed yet, such
+// as tasks whose inputs do not exist, are left for Probe to check and report.
+func (r *CacheAwareRunner) ProbeBatch(ctx context.Context, tasks []core.Task) error {
+	if r == nil || r.Runner == nil {
+		return fmt.Errorf("nil core runner")
+	}
+	names := make([]string, 0, len(tasks))
+	hashes := make([]core.TaskHash, 0, len(tasks))
+	for _, task := range tasks {
+		if task.Name == "" || (task.Run == "" && task.Fetch == nil) {
+			continue
+		}
+		hash, err := r.taskHash(task)
+		if err != nil {
+			continue
+		}
+		names = append(names, task.Name)
+		hashes = append(hashes, hash)
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	found, err := core.HasBatch(r.Runner.Cache, hashes)
+	if err != nil {
+		return fmt.Errorf("checking cache: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.probed == nil {
+		r.probed = make(map[string]probeAnswer)
+	}
+	for i, name := range names {
+		r.probed[name] = probeAnswer{hash: hashes[i], exists: found[hashes[i]]}
+	}
+	return nil
+}
+
+// takeProbed returns and forgets the batch answer for task name.
+func (r *CacheAwareRunner) takeProbed(name string) (probeAnswer, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.probed[name]
+	delete(r.probed, name)
+	return a, ok
+}
+
+// forget drops the batch answers for hash.
+func (r *CacheAwareRunner) forget(hash core.TaskHash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, a := range r.probed {
+		if a.hash == hash {
+			delete(r.probed, name)
+		}
+	}
+}
+
+// taskHash resolves the inputs of task and computes its TaskHash.
+func (r *CacheAwareRunner) taskHash(task core.Task) (core.TaskHash, error) {
+	inputSet, err := r.Runner.Resolver.Resolve(task.Inputs)
+	if err != nil {
+		return "", fmt.Errorf("resolving inputs: %w", err)
+	}
+	return r.Runner.Hasher.ComputeHash(task.HashInput(inputSet, r.Runner.WorkingDir)), nil
+}
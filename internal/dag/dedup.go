@@ -0,0 +1,78 @@
+package dag
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+)
+
+// DuplicateNodes returns the deduplication mapping for the graph.
+//
+// Two nodes are duplicates when they are byte-identical declaratively:
+// same run command, env, input set and output set. Upstream dependencies are
+// also part of the key so both nodes are guaranteed to observe their inputs in
+// the same state when they become ready.
+//
+// Each duplicate maps to the representative of its group, which is the
+// lexically smallest task name. Representatives themselves are not keys.
+// Because duplicates share upstream sets they share a topological depth, so the
+// representative is always scheduled first.
+func (g *TaskGraph) DuplicateNodes() map[string]string {
+	out := make(map[string]string)
+	if g == nil {
+		return out
+	}
+
+	groups := make(map[string][]string, len(g.nodes))
+	for _, n := range g.nodes {
+		key := g.dedupKey(n)
+		groups[key] = append(groups[key], n.Name)
+	}
+	for _, names := range groups {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+		for _, dup := range names[1:] {
+			out[dup] = names[0]
+		}
+	}
+	return out
+}
+
+// dedupKey hashes the node definition together with its declared outputs and
+// upstream task names.
+func (g *TaskGraph) dedupKey(n *TaskNode) string {
+	h := sha256.New()
+
+	writeCount := func(count int) {
+		h.Write(binary.AppendUvarint(nil, uint64(count)))
+	}
+	writeField := func(data []byte) {
+		writeCount(len(data))
+		h.Write(data)
+	}
+
+	writeField([]byte(n.DefinitionHash))
+
+	outputs := make([]string, len(n.Task.Outputs))
+	copy(outputs, n.Task.Outputs)
+	sort.Strings(outputs)
+	writeCount(len(outputs))
+	for _, o := range outputs {
+		writeField([]byte(o))
+	}
+
+	upstream := make([]string, 0, len(g.incoming[n.canonicalIndex]))
+	for _, p := range g.incoming[n.canonicalIndex] {
+		upstream = append(upstream, g.nodes[p].Name)
+	}
+	sort.Strings(upstream)
+	writeCount(len(upstream))
+	for _, u := range upstream {
+		writeField([]byte(u))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,247 @@
+package dag
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/trace"
+)
+
+type countingRunner struct {
+	mu   sync.Mutex
+	runs map[string]int
+	exit map[string]int
+}
+
+func (r *countingRunner) Probe(_ context.Context, _ core.Task) (*NodeResult, bool, error) {
+	return nil, false, nil
+}
+
+func (r *countingRunner) Run(_ context.Context, task core.Task) (*NodeResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.runs == nil {
+		r.runs = make(map[string]int)
+	}
+	r.runs[task.Name]++
+	return &NodeResult{Hash: core.TaskHash("hash:" + task.Run), Stdout: []byte(task.Run), ExitCode: r.exit[task.Name]}, nil
+}
+
+func dedupGraph(t *testing.T) *TaskGraph {
+	t.Helper()
+	// A, B and C are byte-identical roots; D differs only by name and upstream.
+	g, err := NewTaskGraph(
+		[]core.Task{
+			{Name: "A", Inputs: []string{"in"}, Run: "gen", Outputs: []string{"out"}},
+			{Name: "B", Inputs: []string{"in"}, Run: "gen", Outputs: []string{"out"}},
+			{Name: "C", Inputs: []string{"in"}, Run: "gen", Outputs: []string{"out"}},
+			{Name: "D", Inputs: []string{"in"}, Run: "gen", Outputs: []string{"out"}},
+			{Name: "E", Inputs: []string{"e"}, Run: "use"},
+		},
+		[]Edge{{From: "A", To: "D"}, {From: "B", To: "E"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return g
+}
+
+func TestDuplicateNodes_GroupsByDefinitionOutputsAndUpstream(t *testing.T) {
+	got := dedupGraph(t).DuplicateNodes()
+	want := map[string]string{"B": "A", "C": "A"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("duplicates mismatch: got %v want %v", got, want)
+	}
+}
+
+func TestDuplicateNodes_DifferentOutputsAreNotDuplicates(t *testing.T) {
+	g, err := NewTaskGraph(
+		[]core.Task{
+			{Name: "A", Run: "gen", Outputs: []string{"a"}},
+			{Name: "B", Run: "gen", Outputs: []string{"b"}},
+		},
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := g.DuplicateNodes(); len(got) != 0 {
+		t.Fatalf("expected no duplicates, got %v", got)
+	}
+}
+
+func TestExecutorSerial_Deduplicate_RunsOnceAndSharesResult(t *testing.T) {
+	r := &countingRunner{}
+	exec, err := NewExecutor(dedupGraph(t), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec.Deduplicate = true
+
+	res, err := exec.RunSerial(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r.runs["B"] != 0 || r.runs["C"] != 0 || r.runs["A"] != 1 {
+		t.Fatalf("unexpected runner calls: %v", r.runs)
+	}
+	wantOrder := []string{"A", "D", "E"}
+	if !reflect.DeepEqual(res.ExecutionOrder, wantOrder) {
+		t.Fatalf("execution order mismatch: got %v want %v", res.ExecutionOrder, wantOrder)
+	}
+	if !reflect.DeepEqual(res.Deduplicated, map[string]string{"B": "A", "C": "A"}) {
+		t.Fatalf("deduplicated mismatch: %v", res.Deduplicated)
+	}
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		if res.FinalState[name] != TaskCompleted {
+			t.Fatalf("expected %s COMPLETED, got %s", name, res.FinalState[name])
+		}
+	}
+	if res.TaskHashes["B"] != res.TaskHashes["A"] || string(res.Stdout["C"]) != "gen" {
+		t.Fatalf("duplicate did not inherit representative result")
+	}
+
+	var tr trace.ExecutionTrace
+	if err := json.Unmarshal(res.TraceBytes, &tr); err != nil {
+		t.Fatalf("parse trace: %v", err)
+	}
+	found := false
+	for _, ev := range tr.Events {
+		if ev.Kind == trace.EventTaskDeduplicated && ev.TaskID == "B" && ev.CauseTaskID == "A" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected TaskDeduplicated event for B")
+	}
+}
+
+func TestExecutorSerial_Deduplicate_RepresentativeFailureFailsDuplicates(t *testing.T) {
+	r := &countingRunner{exit: map[string]int{"A": 1}}
+	exec, err := NewExecutor(dedupGraph(t), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec.Deduplicate = true
+
+	res, err := exec.RunSerial(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]TaskState{"A": TaskFailed, "B": TaskFailed, "C": TaskFailed, "D": TaskSkipped, "E": TaskSkipped}
+	for name, st := range want {
+		if res.FinalState[name] != st {
+			t.Fatalf("expected %s %s, got %s", name, st, res.FinalState[name])
+		}
+	}
+}
+
+func TestExecutorSerial_DeduplicateDisabledByDefault(t *testing.T) {
+	r := &countingRunner{}
+	exec, err := NewExecutor(dedupGraph(t), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := exec.RunSerial(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.runs["B"] != 1 || r.runs["C"] != 1 || len(res.Deduplicated) != 0 {
+		t.Fatalf("expected every node to run, got %v (dedup %v)", r.runs, res.Deduplicated)
+	}
+}
+
+func TestExecutorParallel_Deduplicate_MatchesSerialTrace(t *testing.T) {
+	serial, err := NewExecutor(dedupGraph(t), &countingRunner{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	serial.Deduplicate = true
+	sres, err := serial.RunSerial(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := &countingRunner{}
+	par, err := NewExecutor(dedupGraph(t), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	par.Deduplicate = true
+	pres, err := par.RunParallel(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if r.runs["B"] != 0 || r.runs["C"] != 0 {
+		t.Fatalf("duplicates executed in parallel mode: %v", r.runs)
+	}
+	if !reflect.DeepEqual(pres.Deduplicated, sres.Deduplicated) {
+		t.Fatalf("deduplicated mismatch: serial %v parallel %v", sres.Deduplicated, pres.Deduplicated)
+	}
+	if pres.TraceHash != sres.TraceHash {
+		t.Fatalf("trace hash mismatch: serial %s parallel %s", sres.TraceHash, pres.TraceHash)
+	}
+}
+
+// lockCheckingHooks records the node hooks it receives and whether the
+// executor's lock was free during each.
+type lockCheckingHooks struct {
+	NopLifecycleHooks
+	e      *Executor
+	events []string
+	locked []string
+}
+
+func (h *lockCheckingHooks) node(kind, taskID string) {
+	if !h.e.mu.TryLock() {
+		h.locked = append(h.locked, kind+" "+taskID)
+	} else {
+		h.e.mu.Unlock()
+	}
+	h.events = append(h.events, kind+" "+taskID)
+}
+
+func (h *lockCheckingHooks) BeforeNode(_ context.Context, taskID string) { h.node("before", taskID) }
+func (h *lockCheckingHooks) AfterNode(_ context.Context, taskID string)  { h.node("after", taskID) }
+
+func TestExecutor_Deduplicate_FiresNodeHooksUnlocked(t *testing.T) {
+	for _, workers := range []int{0, 4} {
+		e, err := NewExecutor(dedupGraph(t), &countingRunner{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		e.Deduplicate = true
+		hooks := &lockCheckingHooks{e: e}
+		e.Hooks = hooks
+		if workers > 0 {
+			_, err = e.RunParallel(context.Background(), workers)
+		} else {
+			_, err = e.RunSerial(context.Background())
+		}
+		if err != nil {
+			t.Fatalf("workers %d: unexpected error: %v", workers, err)
+		}
+		if len(hooks.locked) != 0 {
+			t.Fatalf("workers %d: hooks called with the lock held: %v", workers, hooks.locked)
+		}
+		// Every node, duplicate or not, gets a BeforeNode and then an AfterNode.
+		seen := make(map[string]string)
+		for _, ev := range hooks.events {
+			kind, name, _ := strings.Cut(ev, " ")
+			if want := map[string]string{"before": "", "after": "before"}[kind]; seen[name] != want {
+				t.Fatalf("workers %d: %s after %q in %v", workers, ev, seen[name], hooks.events)
+			}
+			seen[name] = kind
+		}
+		if len(seen) != 5 {
+			t.Fatalf("workers %d: hooks for %v, want all 5 nodes", workers, seen)
+		}
+	}
+}
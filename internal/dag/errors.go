@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	"scriptweaver/internal/graph"
 )
 
 var (
@@ -15,6 +17,8 @@ var (
 type GraphError struct {
 	Kind error
 	Msg  string
+	// Hints are optional suggested fixes.
+	Hints []graph.Hint
 }
 
 func (e *GraphError) Error() string {
@@ -29,6 +33,8 @@ func (e *GraphError) Error() string {
 
 func (e *GraphError) Unwrap() error { return e.Kind }
 
+func (e *GraphError) FixHints() []graph.Hint { return e.Hints }
+
 func invalidf(format string, args ...any) error {
 	return &GraphError{Kind: ErrInvalidGraph, Msg: fmt.Sprintf(format, args...)}
 }
@@ -40,3 +46,13 @@ func cycleError(path []string) error {
 	}
 	return &GraphError{Kind: ErrCycleFound, Msg: msg}
 }
+
+// unknownTaskError reports an edge naming a task that does not exist, with
+// the closest task names as hints.
+func unknownTaskError(end, name string, known map[string]*TaskNode) error {
+	names := make([]string, 0, len(known))
+	for n := range known {
+		names = append(names, n)
+	}
+	return &GraphError{Kind: ErrInvalidGraph, Msg: fmt.Sprintf("edge references unknown task (%s): %q", end, name), Hints: graph.DidYouMean(name, names)}
+}
@@ -70,6 +70,14 @@ type TaskRunner interface {
 	Run(ctx context.Context, task core.Task) (*NodeResult, error)
 }
 
+// BatchProber is implemented by TaskRunners that can check the cache for
+// several tasks at once. Without a Plan, the executor hands it every ready
+// node before probing them one by one, so that a wide graph costs one cache
+// lookup, or one round-trip to a remote cache, instead of one per node.
+type BatchProber interface {
+	ProbeBatch(ctx context.Context, tasks []core.Task) error
+}
+
 // Executor executes a TaskGraph deterministically.
 //
 // In Prompt 4 we implement serial execution; the struct is designed so that
@@ -83,6 +91,9 @@ type Executor struct {
 	Plan *incremental.IncrementalPlan
 
 	// Observer is an optional hook invoked when a task reaches a successful terminal state.
+	// It is called from the coordinating goroutine only, never concurrently and
+	// never with the executor's lock held, in both serial and parallel mode. Use
+	// a MultiObserver to notify several observers.
 	//
 	// This enables durable checkpoint persistence during execution, which is required for
 	// crash recovery semantics (system failure resumable if checkpoints exist).
@@ -90,10 +101,51 @@ type Executor struct {
 
 	// Hooks provides optional lifecycle hook points.
 	// Hook implementations are responsible for isolation (panic recovery, logging).
+	// Like Observer, they are called from the coordinating goroutine only and
+	// never with the executor's lock held; every node started or deduplicated
+	// gets a BeforeNode and then an AfterNode.
 	Hooks LifecycleHooks
 
-	mu    sync.Mutex
-	state ExecutionState
+	// Deduplicate enables sharing results between byte-identical nodes
+	// (see TaskGraph.DuplicateNodes). A duplicate is never started; once its
+	// representative reaches a terminal state the duplicate inherits that result.
+	Deduplicate bool
+
+	// Results, when non-nil, receives per-node outputs instead of the
+	// GraphResult maps (see GraphResult.Results). Use it for very large graphs.
+	Results *ResultStore
+
+	// LabelLimits caps the number of tasks carrying a label that RunParallel
+	// runs at once, such as at most 2 "network" tasks, to avoid saturating
+	// external services. Labels without a limit are unlimited.
+	LabelLimits map[string]int
+
+	// Progress, if set, receives aggregate progress events while the graph
+	// runs (see Progress). Like Observer, it is called from the coordinating
+	// goroutine only and never with the executor's lock held.
+	Progress func(Progress)
+
+	// Skip names nodes that are not run, such as a slow deploy step while
+	// iterating locally. Their dependents are settled by SkipPolicy. Nodes
+	// the Plan decides to skip (incremental.DecisionSkip) are skipped too.
+	Skip []string
+	// SkipPolicy decides what happens to the dependents of skipped nodes;
+	// empty means SkipDependents.
+	SkipPolicy SkipPolicy
+
+	// Replan executes a node whose planned cache restoration fails, such as
+	// when its cache entry turns out to be corrupt, instead of failing it:
+	// the node's decision is downgraded from ReuseCache to Execute and the
+	// incident is recorded in GraphResult.Replanned.
+	Replan bool
+
+	// TraceSink, if set, also receives every trace event as it is recorded,
+	// before the run ends and GraphResult.TraceBytes is encoded.
+	TraceSink trace.Sink
+
+	mu       sync.Mutex
+	state    ExecutionState
+	progress *progressTracker
 }
 
 // NodeObserver is an optional execution observer.
@@ -107,6 +159,31 @@ type NodeObserver interface {
 	OnTaskTerminal(task core.Task, result *NodeResult, traceEvents []trace.TraceEvent) error
 }
 
+// probeBatch hands the tasks of names that are pending, are not held back as
+// duplicates and were not handed over before to the runner's ProbeBatch, if
+// it is a BatchProber. With a Plan nothing is probed. e.mu is held.
+func (e *Executor) probeBatch(ctx context.Context, names []string, dups map[string]string, batched map[string]bool) error {
+	bp, ok := e.Runner.(BatchProber)
+	if !ok || e.Plan != nil {
+		return nil
+	}
+	var tasks []core.Task
+	for _, name := range names {
+		if _, dup := dups[name]; dup || batched[name] || e.state[name] != TaskPending {
+			continue
+		}
+		batched[name] = true
+		tasks = append(tasks, e.Graph.nodesByName[name].Task)
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+	if err := bp.ProbeBatch(ctx, tasks); err != nil {
+		return fmt.Errorf("probing cache: %w", err)
+	}
+	return nil
+}
+
 // NewExecutor creates an executor with all nodes initialized to PENDING.
 func NewExecutor(g *TaskGraph, runner TaskRunner) (*Executor, error) {
 	if g == nil {
@@ -136,6 +213,14 @@ func (e *Executor) StateSnapshot() ExecutionState {
 	return cp
 }
 
+// duplicatesFor returns the deduplication mapping when enabled, or nil.
+func (e *Executor) duplicatesFor() map[string]string {
+	if !e.Deduplicate {
+		return nil
+	}
+	return e.Graph.DuplicateNodes()
+}
+
 // RunSerial executes the graph in serial mode.
 //
 // Determinism:
@@ -154,13 +239,17 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 	}
 
 	rec := trace.NewRecorder()
+	rec.Forward = e.TraceSink
 	skipCause := make(map[string]string)
+	var skippedBy map[string]string
+	dups := e.duplicatesFor()
+	batched := make(map[string]bool)
+	e.progress = newProgressTracker(e.Graph, e.Progress)
 
 	order := make([]string, 0, len(e.Graph.nodes))
-	taskHashes := make(map[string]core.TaskHash, len(e.Graph.nodes))
-	stdout := make(map[string][]byte, len(e.Graph.nodes))
-	stderr := make(map[string][]byte, len(e.Graph.nodes))
-	exitCodes := make(map[string]int, len(e.Graph.nodes))
+	outs := newNodeOutputs(e.Results, len(e.Graph.nodes))
+	deduplicated := make(map[string]string)
+	var replanned map[string]string
 
 	// noteSkipped updates the stable skip cause for all currently-skipped downstream nodes.
 	// This is crucial for the "race to failure" case: if multiple upstream failures can skip the same node,
@@ -171,7 +260,7 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 			return err
 		}
 		for _, name := range downstream {
-			if e.state[name] != TaskSkipped {
+			if _, requested := skippedBy[name]; requested || e.state[name] != TaskSkipped {
 				continue
 			}
 			prev, ok := skipCause[name]
@@ -182,7 +271,15 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 		return nil
 	}
 
+	e.mu.Lock()
+	skippedBy, failedBySkip, err := e.applySkips(rec, outs, noteSkipped)
+	e.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
 	for {
+		e.progress.flush()
 		// 1) Lock state + 2) poll scheduler
 		e.mu.Lock()
 		ready := GetReadyTasks(e.Graph, e.state)
@@ -210,32 +307,90 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 					trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskSkipped, TaskID: name, Reason: "UpstreamFailed", CauseTaskID: skipCause[name]})
 				}
 
+				final := e.StateSnapshot()
+				recordStages(rec, e.Graph, final)
 				execTrace := rec.Trace(graphHash)
 				traceBytes, _ := execTrace.CanonicalJSON()
 				traceHash := trace.ComputeTraceHash(traceBytes)
 
-				final := e.StateSnapshot()
-				return &GraphResult{
+				if outs.err != nil {
+					return nil, outs.err
+				}
+				gr := &GraphResult{
 					GraphHash:      e.Graph.Hash(),
-					TraceHash:     traceHash,
-					TraceBytes:    traceBytes,
+					TraceHash:      traceHash,
+					TraceBytes:     traceBytes,
 					FinalState:     final,
 					ExecutionOrder: order,
-					TaskHashes:     taskHashes,
-					Stdout:         stdout,
-					Stderr:         stderr,
-					ExitCode:       exitCodes,
-				}, nil
+					Deduplicated:   deduplicated,
+					SkipCause:      skipCause,
+					SkippedBy:      skippedBy,
+					FailedBySkip:   failedBySkip,
+					Replanned:      replanned,
+				}
+				outs.fill(gr)
+				return gr, nil
 			}
 			return nil, fmt.Errorf("no ready tasks but graph not finished")
 		}
 
+		if err := e.probeBatch(ctx, ready, dups, batched); err != nil {
+			e.mu.Unlock()
+			return nil, err
+		}
 		next := ready[0]
 		if hooks != nil {
+			e.mu.Unlock()
 			hooks.BeforeNode(ctx, next)
+			e.mu.Lock()
 		}
 		task := e.Graph.nodesByName[next].Task
 
+		// Deduplication: inherit the representative's terminal result instead of running.
+		if rep, ok := dups[next]; ok && IsTerminal(e.state[rep]) {
+			shared := outs.shared(rep)
+			if err := e.transition(next, TaskPending, TaskRunning); err != nil {
+				e.mu.Unlock()
+				return nil, err
+			}
+			deduplicated[next] = rep
+			outs.record(next, shared.Hash, shared.Stdout, shared.Stderr, shared.ExitCode)
+			trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskDeduplicated, TaskID: next, Reason: "IdenticalDefinition", CauseTaskID: rep})
+
+			if IsSuccessful(e.state[rep]) {
+				if err := e.transition(next, TaskRunning, TaskCompleted); err != nil {
+					e.mu.Unlock()
+					return nil, err
+				}
+				obs := e.Observer
+				traceSnap := rec.Snapshot()
+				e.mu.Unlock()
+				if obs != nil && shared.ExitCode == 0 {
+					if err := obs.OnTaskTerminal(task, shared, traceSnap); err != nil {
+						return nil, err
+					}
+				}
+				if hooks != nil {
+					hooks.AfterNode(ctx, next)
+				}
+				continue
+			}
+			trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskFailed, TaskID: next})
+			_, err := e.failAndPropagate(next)
+			if err == nil {
+				err = noteSkipped(next)
+			}
+			if err != nil {
+				e.mu.Unlock()
+				return nil, err
+			}
+			e.mu.Unlock()
+			if hooks != nil {
+				hooks.AfterNode(ctx, next)
+			}
+			continue
+		}
+
 		// Incremental plan mode: obey the precomputed decision overlay.
 		if e.Plan != nil {
 			decision := e.Plan.Decisions[next]
@@ -244,7 +399,7 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 				trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskCached, TaskID: next, Reason: "PlannedReuseCache"})
 
 				// Treat restoration as a deterministic "run" step so failures propagate via Sprint-01 rules.
-				if err := Transition(e.state, next, TaskPending, TaskRunning); err != nil {
+				if err := e.transition(next, TaskPending, TaskRunning); err != nil {
 					e.mu.Unlock()
 					return nil, err
 				}
@@ -258,15 +413,28 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 				}
 
 				res, err := restoreRunner.Restore(ctx, task)
+				replan := ""
+				if e.Replan && (err != nil || res == nil) {
+					// The planned cache entry is unusable: execute the node instead.
+					replan = "nil restore result"
+					if err != nil {
+						replan = err.Error()
+					}
+					if res, err = e.Runner.Run(ctx, task); err != nil {
+						return nil, fmt.Errorf("executing %q: %w", next, err)
+					}
+					if res == nil {
+						return nil, fmt.Errorf("executing %q: nil result", next)
+					}
+				}
 				if err != nil {
 					// Cached restoration failure is treated as a task failure (not an executor fatal error).
 					e.mu.Lock()
 					order = append(order, next)
-					stderr[next] = []byte(err.Error())
-					exitCodes[next] = 1
+					outs.fail(next, []byte(err.Error()))
 					trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskFailed, TaskID: next})
 					ferr := func() error {
-						_, err := FailAndPropagate(e.Graph, e.state, next)
+						_, err := e.failAndPropagate(next)
 						if err != nil {
 							return err
 						}
@@ -282,11 +450,10 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 				if res == nil {
 					e.mu.Lock()
 					order = append(order, next)
-					stderr[next] = []byte("nil restore result")
-					exitCodes[next] = 1
+					outs.fail(next, []byte("nil restore result"))
 					trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskFailed, TaskID: next})
 					ferr := func() error {
-						_, err := FailAndPropagate(e.Graph, e.state, next)
+						_, err := e.failAndPropagate(next)
 						if err != nil {
 							return err
 						}
@@ -302,14 +469,21 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 
 				e.mu.Lock()
 				order = append(order, next)
-				taskHashes[next] = res.Hash
-				stdout[next] = res.Stdout
-				stderr[next] = res.Stderr
-				exitCodes[next] = res.ExitCode
+				outs.record(next, res.Hash, res.Stdout, res.Stderr, res.ExitCode)
+				if replan != "" {
+					if replanned == nil {
+						replanned = make(map[string]string)
+					}
+					replanned[next] = replan
+				}
 
 				if res.ExitCode == 0 {
-					trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskArtifactsRestored, TaskID: next, Reason: "CacheRestore"})
-					if err := Transition(e.state, next, TaskRunning, TaskCompleted); err != nil {
+					if replan != "" {
+						trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskExecuted, TaskID: next, Reason: "Replanned"})
+					} else {
+						trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskArtifactsRestored, TaskID: next, Reason: "CacheRestore"})
+					}
+					if err := e.transition(next, TaskRunning, TaskCompleted); err != nil {
 						e.mu.Unlock()
 						return nil, err
 					}
@@ -327,7 +501,7 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 					continue
 				}
 				trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskFailed, TaskID: next})
-				if _, err := FailAndPropagate(e.Graph, e.state, next); err == nil {
+				if _, err := e.failAndPropagate(next); err == nil {
 					err = noteSkipped(next)
 				}
 				if err != nil {
@@ -343,7 +517,7 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 
 			// DecisionExecute: do not probe cache. Always execute.
 			if decision == incremental.DecisionExecute {
-				if err := Transition(e.state, next, TaskPending, TaskRunning); err != nil {
+				if err := e.transition(next, TaskPending, TaskRunning); err != nil {
 					e.mu.Unlock()
 					return nil, err
 				}
@@ -359,14 +533,11 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 
 				e.mu.Lock()
 				order = append(order, next)
-				taskHashes[next] = runRes.Hash
-				stdout[next] = runRes.Stdout
-				stderr[next] = runRes.Stderr
-				exitCodes[next] = runRes.ExitCode
+				outs.record(next, runRes.Hash, runRes.Stdout, runRes.Stderr, runRes.ExitCode)
 
 				if runRes.ExitCode == 0 {
 					trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskExecuted, TaskID: next, Reason: "PlannedExecute"})
-					if err := Transition(e.state, next, TaskRunning, TaskCompleted); err != nil {
+					if err := e.transition(next, TaskRunning, TaskCompleted); err != nil {
 						e.mu.Unlock()
 						return nil, err
 					}
@@ -384,7 +555,7 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 					continue
 				}
 				trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskFailed, TaskID: next})
-				if _, err := FailAndPropagate(e.Graph, e.state, next); err == nil {
+				if _, err := e.failAndPropagate(next); err == nil {
 					err = noteSkipped(next)
 				}
 				if err != nil {
@@ -410,16 +581,13 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 				e.mu.Unlock()
 				return nil, fmt.Errorf("probing cache for %q: nil result", next)
 			}
-			if err := Transition(e.state, next, TaskPending, TaskCached); err != nil {
+			if err := e.transition(next, TaskPending, TaskCached); err != nil {
 				e.mu.Unlock()
 				return nil, err
 			}
 			trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskCached, TaskID: next, Reason: "CacheHit"})
 			trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskArtifactsRestored, TaskID: next, Reason: "CacheReplay"})
-			taskHashes[next] = probeRes.Hash
-			stdout[next] = probeRes.Stdout
-			stderr[next] = probeRes.Stderr
-			exitCodes[next] = probeRes.ExitCode
+			outs.record(next, probeRes.Hash, probeRes.Stdout, probeRes.Stderr, probeRes.ExitCode)
 			obs := e.Observer
 			traceSnap := rec.Snapshot()
 			e.mu.Unlock()
@@ -434,7 +602,7 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 			continue
 		}
 
-		if err := Transition(e.state, next, TaskPending, TaskRunning); err != nil {
+		if err := e.transition(next, TaskPending, TaskRunning); err != nil {
 			e.mu.Unlock()
 			return nil, err
 		}
@@ -452,14 +620,11 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 		// 4) update state (under lock)
 		e.mu.Lock()
 		order = append(order, next)
-		taskHashes[next] = runRes.Hash
-		stdout[next] = runRes.Stdout
-		stderr[next] = runRes.Stderr
-		exitCodes[next] = runRes.ExitCode
+		outs.record(next, runRes.Hash, runRes.Stdout, runRes.Stderr, runRes.ExitCode)
 
 		if runRes.ExitCode == 0 {
 			trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskExecuted, TaskID: next, Reason: "FreshWork"})
-			if err := Transition(e.state, next, TaskRunning, TaskCompleted); err != nil {
+			if err := e.transition(next, TaskRunning, TaskCompleted); err != nil {
 				e.mu.Unlock()
 				return nil, err
 			}
@@ -479,7 +644,7 @@ func (e *Executor) RunSerial(ctx context.Context) (*GraphResult, error) {
 
 		// Failure: mark failed and propagate skipped.
 		trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskFailed, TaskID: next})
-		if _, err := FailAndPropagate(e.Graph, e.state, next); err == nil {
+		if _, err := e.failAndPropagate(next); err == nil {
 			err = noteSkipped(next)
 		}
 		if err != nil {
@@ -501,10 +666,20 @@ type workItem struct {
 	reuseCache bool
 }
 
+type observedTerminal struct {
+	task        core.Task
+	result      *NodeResult
+	traceEvents []trace.TraceEvent
+}
+
 type workResult struct {
 	name   string
 	result *NodeResult
 	err    error
+
+	// replanned is why the planned restoration failed when the task was
+	// executed instead (see Executor.Replan).
+	replanned string
 }
 
 // RunParallel executes the graph using up to `concurrency` workers.
@@ -512,6 +687,10 @@ type workResult struct {
 // Determinism strategy:
 //   - Depth-staged dispatch: tasks are dispatched in increasing topological depth.
 //   - Within the same depth: lexical order by task name.
+//   - Tasks sharing a Mutex never run concurrently. A task whose mutex is held
+//     waits, and so do the tasks after it, so mutexes are acquired in the same
+//     lexical order.
+//   - Likewise, a task waits while a label it carries is at its LabelLimits.
 //
 // All state reads/writes are synchronized by e.mu. Task execution happens outside the lock.
 func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResult, error) {
@@ -521,6 +700,11 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 	if concurrency <= 0 {
 		return nil, fmt.Errorf("concurrency must be > 0")
 	}
+	for label, limit := range e.LabelLimits {
+		if limit <= 0 {
+			return nil, fmt.Errorf("limit of label %q must be > 0", label)
+		}
+	}
 
 	hooks := e.Hooks
 	if hooks != nil {
@@ -529,7 +713,14 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 	}
 
 	rec := trace.NewRecorder()
+	rec.Forward = e.TraceSink
 	skipCause := make(map[string]string)
+	var skippedBy map[string]string
+	dups := e.duplicatesFor()
+	batched := make(map[string]bool)
+	deduplicated := make(map[string]string)
+	var replanned map[string]string
+	e.progress = newProgressTracker(e.Graph, e.Progress)
 
 	noteSkipped := func(cause string) error {
 		downstream, err := downstreamReachable(e.Graph, cause)
@@ -537,7 +728,7 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 			return err
 		}
 		for _, name := range downstream {
-			if e.state[name] != TaskSkipped {
+			if _, requested := skippedBy[name]; requested || e.state[name] != TaskSkipped {
 				continue
 			}
 			prev, ok := skipCause[name]
@@ -588,6 +779,16 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 						continue
 					}
 					res, err := restoreRunner.Restore(ctx, w.task)
+					if e.Replan && (err != nil || res == nil) {
+						// The planned cache entry is unusable: execute the task instead.
+						reason := "nil restore result"
+						if err != nil {
+							reason = err.Error()
+						}
+						res, err = e.Runner.Run(ctx, w.task)
+						doneCh <- workResult{name: w.name, result: res, err: err, replanned: reason}
+						continue
+					}
 					if err != nil {
 						// Treat restoration failure as a task failure (exit code != 0), not a fatal executor error.
 						res = &NodeResult{ExitCode: 1, Stderr: []byte(err.Error())}
@@ -604,12 +805,53 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 	}
 
 	order := make([]string, 0, len(e.Graph.nodes))
-	taskHashes := make(map[string]core.TaskHash, len(e.Graph.nodes))
-	stdout := make(map[string][]byte, len(e.Graph.nodes))
-	stderr := make(map[string][]byte, len(e.Graph.nodes))
-	exitCodes := make(map[string]int, len(e.Graph.nodes))
+	outs := newNodeOutputs(e.Results, len(e.Graph.nodes))
 	inFlight := 0
 
+	e.mu.Lock()
+	skippedBy, failedBySkip, err := e.applySkips(rec, outs, noteSkipped)
+	e.mu.Unlock()
+	if err != nil {
+		stopWorkers()
+		return nil, err
+	}
+	// held maps each mutex held by an in-flight task to that task.
+	held := make(map[string]string)
+	// labelled counts the in-flight tasks carrying each limited label.
+	labelled := make(map[string]int)
+	throttled := make(map[string]string)
+	// limitedBy returns the first label of task that is at its limit.
+	limitedBy := func(task core.Task) string {
+		for _, label := range task.Labels {
+			if limit, ok := e.LabelLimits[label]; ok && labelled[label] >= limit {
+				return label
+			}
+		}
+		return ""
+	}
+
+	// Successful terminal events are collected while e.mu is held and delivered
+	// to the observer by the coordinator after the lock is released, so the
+	// observer is never called concurrently or with the executor locked.
+	var observed []observedTerminal
+	noteObserved := func(name string, res *NodeResult) {
+		if e.Observer == nil || res == nil || res.ExitCode != 0 {
+			return
+		}
+		observed = append(observed, observedTerminal{task: e.Graph.nodesByName[name].Task, result: res, traceEvents: rec.Snapshot()})
+	}
+	flushObserved := func() error {
+		e.progress.flush()
+		pending := observed
+		observed = nil
+		for _, o := range pending {
+			if err := e.Observer.OnTaskTerminal(o.task, o.result, o.traceEvents); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	// Helper: check dependency success for a node index.
 	depsSatisfied := func(idx int) bool {
 		for _, p := range e.Graph.incoming[idx] {
@@ -621,10 +863,72 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 		return true
 	}
 
+	// resolveHeld settles held-back duplicates from their (now terminal) representatives.
+	resolveHeld := func(held []string) error {
+		for _, name := range held {
+			e.mu.Lock()
+			rep := dups[name]
+			st, repSt := e.state[name], e.state[rep]
+			e.mu.Unlock()
+			if st != TaskPending {
+				continue
+			}
+			if !IsTerminal(repSt) {
+				return fmt.Errorf("duplicate %q resolved before representative %q finished", name, rep)
+			}
+			if hooks != nil {
+				hooks.BeforeNode(ctx, name)
+			}
+			e.mu.Lock()
+			shared := outs.shared(rep)
+			if err := e.transition(name, TaskPending, TaskRunning); err != nil {
+				e.mu.Unlock()
+				return err
+			}
+			deduplicated[name] = rep
+			outs.record(name, shared.Hash, shared.Stdout, shared.Stderr, shared.ExitCode)
+			trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskDeduplicated, TaskID: name, Reason: "IdenticalDefinition", CauseTaskID: rep})
+			var err error
+			if IsSuccessful(e.state[rep]) {
+				if err = e.transition(name, TaskRunning, TaskCompleted); err == nil {
+					noteObserved(name, shared)
+				}
+			} else {
+				trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskFailed, TaskID: name})
+				if _, err = e.failAndPropagate(name); err == nil {
+					err = noteSkipped(name)
+				}
+			}
+			e.mu.Unlock()
+			if err != nil {
+				return err
+			}
+			if err := flushObserved(); err != nil {
+				return err
+			}
+			if hooks != nil {
+				hooks.AfterNode(ctx, name)
+			}
+		}
+		return nil
+	}
+
 	// Coordinator loop: stage by depth.
 	for depth := 0; depth <= maxDepth; depth++ {
 		names := byDepth[depth]
+		// The nodes of a depth are all ready once the depths before it finished.
+		e.mu.Lock()
+		err := e.probeBatch(ctx, names, dups, batched)
+		e.mu.Unlock()
+		if err != nil {
+			stopWorkers()
+			return nil, err
+		}
 		nextToStart := 0
+		// Duplicates are held back until their representatives (same depth) finish.
+		var heldDuplicates []string
+		// Nodes started while e.mu is held are handed to the workers after.
+		var dispatched []workItem
 
 		for {
 			// Dispatch as many tasks as possible for this depth.
@@ -649,6 +953,25 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 					stopWorkers()
 					return nil, fmt.Errorf("task %q at depth %d is pending but dependencies are not successful", name, depth)
 				}
+				if _, ok := dups[name]; ok {
+					heldDuplicates = append(heldDuplicates, name)
+					nextToStart++
+					continue
+				}
+
+				// A node held back by its mutex or a label limit is not probed
+				// until it can start, so each dispatch pass waiting on it costs
+				// no cache lookup.
+				mutex := node.Task.Mutex
+				if mutex != "" && held[mutex] != "" {
+					break
+				}
+				if label := limitedBy(node.Task); label != "" {
+					if _, ok := throttled[name]; !ok {
+						throttled[name] = label
+					}
+					break
+				}
 
 				// Incremental plan mode: do not probe cache; schedule based on decision.
 				reuseCache := false
@@ -667,17 +990,15 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 							stopWorkers()
 							return nil, fmt.Errorf("probing cache for %q: nil result", name)
 						}
-						if err := Transition(e.state, name, TaskPending, TaskCached); err != nil {
+						if err := e.transition(name, TaskPending, TaskCached); err != nil {
 							e.mu.Unlock()
 							stopWorkers()
 							return nil, err
 						}
 						trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskCached, TaskID: name, Reason: "CacheHit"})
 						trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskArtifactsRestored, TaskID: name, Reason: "CacheReplay"})
-						taskHashes[name] = res.Hash
-						stdout[name] = res.Stdout
-						stderr[name] = res.Stderr
-						exitCodes[name] = res.ExitCode
+						outs.record(name, res.Hash, res.Stdout, res.Stderr, res.ExitCode)
+						noteObserved(name, res)
 						nextToStart++
 						continue
 					}
@@ -688,25 +1009,44 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 						trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskCached, TaskID: name, Reason: "PlannedReuseCache"})
 				}
 
-				if hooks != nil {
-					hooks.BeforeNode(ctx, name)
-				}
-
-				if err := Transition(e.state, name, TaskPending, TaskRunning); err != nil {
+				if err := e.transition(name, TaskPending, TaskRunning); err != nil {
 					e.mu.Unlock()
 					stopWorkers()
 					return nil, err
 				}
 				order = append(order, name)
+				if mutex != "" {
+					held[mutex] = name
+				}
+				for _, label := range node.Task.Labels {
+					labelled[label]++
+				}
 				inFlight++
 				nextToStart++
-				workCh <- workItem{name: name, task: node.Task, reuseCache: reuseCache}
+				dispatched = append(dispatched, workItem{name: name, task: node.Task, reuseCache: reuseCache})
 			}
 
 			// Are we done with this depth stage?
 			stageDone := (nextToStart >= len(names) && inFlight == 0)
 			e.mu.Unlock()
+			// Hooks run unlocked, before the workers start their nodes. The
+			// channel holds every in-flight item, so sending never blocks.
+			for _, w := range dispatched {
+				if hooks != nil {
+					hooks.BeforeNode(ctx, w.name)
+				}
+				workCh <- w
+			}
+			dispatched = dispatched[:0]
+			if err := flushObserved(); err != nil {
+				stopWorkers()
+				return nil, err
+			}
 			if stageDone {
+				if err := resolveHeld(heldDuplicates); err != nil {
+					stopWorkers()
+					return nil, err
+				}
 				break
 			}
 
@@ -732,36 +1072,58 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 					stopWorkers()
 					return nil, fmt.Errorf("completion for %q but state is %s", r.name, cur)
 				}
+				if mutex := e.Graph.nodesByName[r.name].Task.Mutex; mutex != "" {
+					delete(held, mutex)
+				}
+				for _, label := range e.Graph.nodesByName[r.name].Task.Labels {
+					labelled[label]--
+				}
 
 				// Record result data.
-				taskHashes[r.name] = r.result.Hash
-				stdout[r.name] = r.result.Stdout
-				stderr[r.name] = r.result.Stderr
-				exitCodes[r.name] = r.result.ExitCode
+				outs.record(r.name, r.result.Hash, r.result.Stdout, r.result.Stderr, r.result.ExitCode)
+				if r.replanned != "" {
+					if replanned == nil {
+						replanned = make(map[string]string)
+					}
+					replanned[r.name] = r.replanned
+				}
 
 				if r.result.ExitCode == 0 {
-					if e.Plan != nil && (e.Plan.Decisions[r.name] == incremental.DecisionReuseCache) {
+					if e.Plan != nil && (e.Plan.Decisions[r.name] == incremental.DecisionReuseCache) && r.replanned == "" {
 						trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskArtifactsRestored, TaskID: r.name, Reason: "CacheRestore"})
 						// Do NOT emit TaskExecuted for cached reuse.
-						if err := Transition(e.state, r.name, TaskRunning, TaskCompleted); err != nil {
+						if err := e.transition(r.name, TaskRunning, TaskCompleted); err != nil {
 							e.mu.Unlock()
 							stopWorkers()
 							return nil, err
 						}
+						noteObserved(r.name, r.result)
 						inFlight--
 						e.mu.Unlock()
+						if err := flushObserved(); err != nil {
+							stopWorkers()
+							return nil, err
+						}
 						continue
 					}
-					trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskExecuted, TaskID: r.name, Reason: "FreshWork"})
-					if err := Transition(e.state, r.name, TaskRunning, TaskCompleted); err != nil {
+					// Same reasons as RunSerial, so both schedulers trace a plan alike.
+					reason := "FreshWork"
+					if r.replanned != "" {
+						reason = "Replanned"
+					} else if e.Plan != nil {
+						reason = "PlannedExecute"
+					}
+					trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskExecuted, TaskID: r.name, Reason: reason})
+					if err := e.transition(r.name, TaskRunning, TaskCompleted); err != nil {
 						e.mu.Unlock()
 						stopWorkers()
 						return nil, err
 					}
+					noteObserved(r.name, r.result)
 				} else {
 					trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskFailed, TaskID: r.name})
 						ferr := func() error {
-							_, err := FailAndPropagate(e.Graph, e.state, r.name)
+							_, err := e.failAndPropagate(r.name)
 							if err != nil {
 								return err
 							}
@@ -775,6 +1137,10 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 				}
 				inFlight--
 				e.mu.Unlock()
+				if err := flushObserved(); err != nil {
+					stopWorkers()
+					return nil, err
+				}
 				if hooks != nil {
 					hooks.AfterNode(ctx, r.name)
 				}
@@ -795,19 +1161,27 @@ func (e *Executor) RunParallel(ctx context.Context, concurrency int) (*GraphResu
 	for _, name := range skippedNames {
 		trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskSkipped, TaskID: name, Reason: "UpstreamFailed", CauseTaskID: skipCause[name]})
 	}
+	recordStages(rec, e.Graph, final)
 
 	execTrace := rec.Trace(graphHash)
 	traceBytes, _ := execTrace.CanonicalJSON()
 	traceHash := trace.ComputeTraceHash(traceBytes)
-	return &GraphResult{
+	if outs.err != nil {
+		return nil, outs.err
+	}
+	gr := &GraphResult{
 		GraphHash:      e.Graph.Hash(),
-		TraceHash:     traceHash,
-		TraceBytes:    traceBytes,
+		TraceHash:      traceHash,
+		TraceBytes:     traceBytes,
 		FinalState:     final,
 		ExecutionOrder: order,
-		TaskHashes:     taskHashes,
-		Stdout:         stdout,
-		Stderr:         stderr,
-		ExitCode:       exitCodes,
-	}, nil
+		Deduplicated:   deduplicated,
+		SkipCause:      skipCause,
+		SkippedBy:      skippedBy,
+		FailedBySkip:   failedBySkip,
+		Throttled:      throttled,
+		Replanned:      replanned,
+	}
+	outs.fill(gr)
+	return gr, nil
 }
@@ -293,3 +293,161 @@ func TestExecutorParallel_RaceToFailure_StableSkipCauseAndTrace(t *testing.T) {
 		t.Fatalf("expected TaskSkipped for C")
 	}
 }
+
+// overlapRunner records the largest number of tasks of each mutex running at
+// once.
+type overlapRunner struct {
+	mu      sync.Mutex
+	running map[string]int
+	max     map[string]int
+}
+
+func (r *overlapRunner) Probe(_ context.Context, _ core.Task) (*NodeResult, bool, error) {
+	return nil, false, nil
+}
+
+func (r *overlapRunner) Run(_ context.Context, task core.Task) (*NodeResult, error) {
+	r.mu.Lock()
+	r.running[task.Mutex]++
+	if r.running[task.Mutex] > r.max[task.Mutex] {
+		r.max[task.Mutex] = r.running[task.Mutex]
+	}
+	r.mu.Unlock()
+	time.Sleep(2 * time.Millisecond)
+	r.mu.Lock()
+	r.running[task.Mutex]--
+	r.mu.Unlock()
+	return &NodeResult{Hash: core.TaskHash("hash:" + task.Name), ExitCode: 0}, nil
+}
+
+func TestExecutorParallel_MutexSerializesTasks(t *testing.T) {
+	g, err := NewTaskGraph(
+		[]core.Task{
+			{Name: "A", Inputs: []string{"a"}, Run: "run-a", Mutex: "db"},
+			{Name: "B", Inputs: []string{"b"}, Run: "run-b"},
+			{Name: "C", Inputs: []string{"c"}, Run: "run-c", Mutex: "db"},
+			{Name: "D", Inputs: []string{"d"}, Run: "run-d", Mutex: "db"},
+		},
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		runner := &overlapRunner{running: map[string]int{}, max: map[string]int{}}
+		exec, err := NewExecutor(g, runner)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		res, err := exec.RunParallel(context.Background(), 4)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if runner.max["db"] != 1 {
+			t.Fatalf("run %d: %d tasks holding mutex db ran at once", i, runner.max["db"])
+		}
+		if want := []string{"A", "B", "C", "D"}; !reflect.DeepEqual(res.ExecutionOrder, want) {
+			t.Fatalf("run %d: order = %v, want %v", i, res.ExecutionOrder, want)
+		}
+	}
+}
+
+func TestExecutorParallel_LabelLimitCapsConcurrency(t *testing.T) {
+	tasks := []core.Task{{Name: "local", Inputs: []string{"l"}, Run: "run-l"}}
+	for _, name := range []string{"n1", "n2", "n3", "n4"} {
+		tasks = append(tasks, core.Task{Name: name, Inputs: []string{name}, Run: "run-" + name, Labels: []string{"network"}})
+	}
+	g, err := NewTaskGraph(tasks, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	runner := &labelRunner{overlapRunner: overlapRunner{running: map[string]int{}, max: map[string]int{}}}
+	exec, err := NewExecutor(g, runner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec.LabelLimits = map[string]int{"network": 2}
+	res, err := exec.RunParallel(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if runner.max["network"] != 2 {
+		t.Fatalf("%d network tasks ran at once, want 2", runner.max["network"])
+	}
+	if res.Throttled["n3"] != "network" || res.Throttled["n1"] != "" {
+		t.Fatalf("Throttled = %v", res.Throttled)
+	}
+
+	exec, _ = NewExecutor(g, runner)
+	exec.LabelLimits = map[string]int{"network": 0}
+	if _, err := exec.RunParallel(context.Background(), 5); err == nil {
+		t.Fatalf("expected error for a zero limit")
+	}
+}
+
+// probeRunner is an overlapRunner that counts Probe calls by task, and whose
+// mutex tasks outlast the others.
+type probeRunner struct {
+	overlapRunner
+	probes map[string]int
+}
+
+func (r *probeRunner) Probe(_ context.Context, task core.Task) (*NodeResult, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.probes[task.Name]++
+	return nil, false, nil
+}
+
+func (r *probeRunner) Run(ctx context.Context, task core.Task) (*NodeResult, error) {
+	if task.Mutex != "" {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return r.overlapRunner.Run(ctx, task)
+}
+
+func TestExecutorParallel_BlockedNodeIsProbedOnce(t *testing.T) {
+	tasks := []core.Task{
+		{Name: "A", Inputs: []string{"a"}, Run: "run-a", Mutex: "db"},
+		{Name: "B", Inputs: []string{"b"}, Run: "run-b"},
+		{Name: "C", Inputs: []string{"c"}, Run: "run-c", Mutex: "db"},
+		{Name: "D", Inputs: []string{"d"}, Run: "run-d", Labels: []string{"network"}},
+		{Name: "E", Inputs: []string{"e"}, Run: "run-e"},
+		{Name: "F", Inputs: []string{"f"}, Run: "run-f", Labels: []string{"network"}},
+	}
+	g, err := NewTaskGraph(tasks, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// C waits on A's mutex and F on D's label while B and E finish, so the
+	// coordinator makes several dispatch passes with C and F held back.
+	runner := &probeRunner{overlapRunner: overlapRunner{running: map[string]int{}, max: map[string]int{}}, probes: map[string]int{}}
+	exec, err := NewExecutor(g, runner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec.LabelLimits = map[string]int{"network": 1}
+	if _, err := exec.RunParallel(context.Background(), 6); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, task := range tasks {
+		if n := runner.probes[task.Name]; n != 1 {
+			t.Fatalf("%s probed %d times, want 1 (probes %v)", task.Name, n, runner.probes)
+		}
+	}
+}
+
+// labelRunner is an overlapRunner that counts tasks by label instead of mutex.
+type labelRunner struct {
+	overlapRunner
+}
+
+func (r *labelRunner) Run(ctx context.Context, task core.Task) (*NodeResult, error) {
+	if len(task.Labels) > 0 {
+		task.Mutex = task.Labels[0]
+	}
+	return r.overlapRunner.Run(ctx, task)
+}
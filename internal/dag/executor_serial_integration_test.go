@@ -125,3 +125,53 @@ func TestExecutorSerial_FailurePropagatesAndContinuesIndependentWork(t *testing.
 		t.Fatalf("expected D completed, got %s", res.FinalState["D"])
 	}
 }
+
+func TestExecutor_ReportsAllFailedAndSkippedNodesWithCauses(t *testing.T) {
+	// Graph:
+	//   A -> C -> D
+	//   B -> C
+	//   B -> E
+	//   F (independent)
+	//
+	// A and B fail. C is skipped by both; the lexically smallest failure (A) is
+	// its cause regardless of completion order.
+	g, err := NewTaskGraph(
+		[]core.Task{
+			{Name: "A", Run: "run-a"},
+			{Name: "B", Run: "run-b"},
+			{Name: "C", Run: "run-c"},
+			{Name: "D", Run: "run-d"},
+			{Name: "E", Run: "run-e"},
+			{Name: "F", Run: "run-f"},
+		},
+		[]Edge{{From: "A", To: "C"}, {From: "B", To: "C"}, {From: "C", To: "D"}, {From: "B", To: "E"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, mode := range []string{"serial", "parallel"} {
+		exec, err := NewExecutor(g, &fakeRunner{exit: map[string]int{"A": 1, "B": 2}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var res *GraphResult
+		if mode == "serial" {
+			res, err = exec.RunSerial(context.Background())
+		} else {
+			res, err = exec.RunParallel(context.Background(), 4)
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", mode, err)
+		}
+		if got, want := res.FailedNodes(), []string{"A", "B"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: failed nodes = %v, want %v", mode, got, want)
+		}
+		if got, want := res.SkippedNodes(), []string{"C", "D", "E"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: skipped nodes = %v, want %v", mode, got, want)
+		}
+		if want := map[string]string{"C": "A", "D": "A", "E": "B"}; !reflect.DeepEqual(res.SkipCause, want) {
+			t.Fatalf("%s: skip causes = %v, want %v", mode, res.SkipCause, want)
+		}
+	}
+}
@@ -152,3 +152,80 @@ func TestExecutorSerial_IncrementalPlan_MixedCachedAndExecuted_RestorationFeedsD
 		t.Fatalf("unexpected B output: %q", b)
 	}
 }
+
+// Re-planning: a planned restore that fails executes the node instead.
+func TestExecutor_IncrementalPlan_ReplansFailedRestore(t *testing.T) {
+	for _, mode := range []string{"serial", "parallel"} {
+		t.Run(mode, func(t *testing.T) {
+			workDir := t.TempDir()
+			// The cache is empty, so restoring A fails.
+			cacheRunner, err := NewCacheAwareRunner(core.NewRunner(workDir, core.NewMemoryCache()))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			g, err := NewTaskGraph(
+				[]core.Task{
+					{Name: "A", Run: "printf 'A1' > a.txt", Outputs: []string{"a.txt"}},
+					{Name: "B", Inputs: []string{"a.txt"}, Run: `IFS= read -r x < a.txt; printf '%sB' "$x" > b.txt`, Outputs: []string{"b.txt"}},
+				},
+				[]Edge{{From: "A", To: "B"}},
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			run := func(replan bool) *GraphResult {
+				exec, err := NewExecutor(g, cacheRunner)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				exec.Plan = &incremental.IncrementalPlan{
+					Order:     []string{"A", "B"},
+					Decisions: map[string]incremental.NodeExecutionDecision{"A": incremental.DecisionReuseCache, "B": incremental.DecisionExecute},
+				}
+				exec.Replan = replan
+				var res *GraphResult
+				if mode == "serial" {
+					res, err = exec.RunSerial(context.Background())
+				} else {
+					res, err = exec.RunParallel(context.Background(), 2)
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return res
+			}
+
+			if res := run(false); res.FinalState["A"] != TaskFailed || res.FinalState["B"] != TaskSkipped || res.Replanned != nil {
+				t.Fatalf("without replan: state %v, replanned %v", res.FinalState, res.Replanned)
+			}
+
+			res := run(true)
+			if res.FinalState["A"] != TaskCompleted || res.FinalState["B"] != TaskCompleted {
+				t.Fatalf("with replan: state %v", res.FinalState)
+			}
+			if len(res.Replanned) != 1 || res.Replanned["A"] == "" {
+				t.Fatalf("replanned = %v", res.Replanned)
+			}
+			var tr struct {
+				Events []struct {
+					Kind   string `json:"kind"`
+					TaskID string `json:"taskId"`
+					Reason string `json:"reason"`
+				} `json:"events"`
+			}
+			if err := json.Unmarshal(res.TraceBytes, &tr); err != nil {
+				t.Fatalf("unmarshal trace: %v", err)
+			}
+			seen := map[string]bool{}
+			for _, e := range tr.Events {
+				seen[e.TaskID+":"+e.Kind+":"+e.Reason] = true
+			}
+			if !seen["A:TaskExecuted:Replanned"] || seen["A:TaskArtifactsRestored:CacheRestore"] {
+				t.Fatalf("trace events = %v", seen)
+			}
+			if b, err := os.ReadFile(filepath.Join(workDir, "b.txt")); err != nil || string(b) != "A1B" {
+				t.Fatalf("b.txt = %q, %v", b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package dag
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/trace"
+)
+
+// MultiObserver fans terminal events out to several NodeObservers so that
+// checkpointing, tracing, metrics and plugins can observe a run independently.
+//
+// Observers are notified in the order they were given, one event at a time:
+// MultiObserver serializes OnTaskTerminal calls, so individual observers need
+// not be safe for concurrent use even when the MultiObserver is shared by
+// executors running in parallel. Every observer sees every event; a failing
+// observer does not prevent the others from being notified.
+type MultiObserver struct {
+	mu        sync.Mutex
+	observers []NodeObserver
+}
+
+// NewMultiObserver returns a MultiObserver over the non-nil observers.
+func NewMultiObserver(observers ...NodeObserver) *MultiObserver {
+	m := &MultiObserver{}
+	for _, o := range observers {
+		m.Add(o)
+	}
+	return m
+}
+
+// Add appends o to the observers notified of subsequent events. A nil o is
+// ignored.
+func (m *MultiObserver) Add(o NodeObserver) {
+	if o == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observers = append(m.observers, o)
+}
+
+// Len returns the number of observers.
+func (m *MultiObserver) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.observers)
+}
+
+// OnTaskTerminal notifies every observer and returns their errors joined, or
+// nil if all of them succeeded. A panicking observer is reported as an error.
+func (m *MultiObserver) OnTaskTerminal(task core.Task, result *NodeResult, traceEvents []trace.TraceEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for i, o := range m.observers {
+		// Each observer gets its own copy so one cannot mutate what the next sees.
+		events := append([]trace.TraceEvent(nil), traceEvents...)
+		if err := notifyObserver(o, task, result, events); err != nil {
+			errs = append(errs, fmt.Errorf("observer %d (%T): %w", i, o, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func notifyObserver(o NodeObserver, task core.Task, result *NodeResult, traceEvents []trace.TraceEvent) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic: %v", rec)
+		}
+	}()
+	return o.OnTaskTerminal(task, result, traceEvents)
+}
@@ -0,0 +1,119 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/trace"
+)
+
+// recordingObserver is deliberately not safe for concurrent use: the race
+// detector flags any unsynchronized delivery.
+type recordingObserver struct {
+	names []string
+	err   error
+}
+
+func (o *recordingObserver) OnTaskTerminal(task core.Task, _ *NodeResult, _ []trace.TraceEvent) error {
+	o.names = append(o.names, task.Name)
+	return o.err
+}
+
+type panickingObserver struct{}
+
+func (panickingObserver) OnTaskTerminal(core.Task, *NodeResult, []trace.TraceEvent) error {
+	panic("boom")
+}
+
+func TestMultiObserver_NotifiesEveryObserverAndJoinsErrors(t *testing.T) {
+	errA := errors.New("checkpoint failed")
+	a := &recordingObserver{err: errA}
+	b := &recordingObserver{}
+	m := NewMultiObserver(a, nil, panickingObserver{}, b)
+	if m.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", m.Len())
+	}
+
+	err := m.OnTaskTerminal(core.Task{Name: "build"}, &NodeResult{}, nil)
+	if !errors.Is(err, errA) || !strings.Contains(err.Error(), "panic: boom") {
+		t.Fatalf("err = %v", err)
+	}
+	if !reflect.DeepEqual(a.names, []string{"build"}) || !reflect.DeepEqual(b.names, []string{"build"}) {
+		t.Fatalf("a=%v b=%v", a.names, b.names)
+	}
+	if err := NewMultiObserver(b).OnTaskTerminal(core.Task{Name: "test"}, &NodeResult{}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMultiObserver_SerializesConcurrentEvents(t *testing.T) {
+	obs := &recordingObserver{}
+	m := NewMultiObserver(obs)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = m.OnTaskTerminal(core.Task{Name: "t"}, &NodeResult{}, nil)
+		}()
+	}
+	wg.Wait()
+	if len(obs.names) != 50 {
+		t.Fatalf("observed %d events, want 50", len(obs.names))
+	}
+}
+
+func TestExecutorParallel_NotifiesObserverOfSuccessfulNodes(t *testing.T) {
+	g, err := NewTaskGraph(
+		[]core.Task{
+			{Name: "A", Inputs: []string{"a"}, Run: "run-a"},
+			{Name: "B", Inputs: []string{"b"}, Run: "run-b"},
+			{Name: "C", Inputs: []string{"c"}, Run: "run-c"},
+			{Name: "D", Inputs: []string{"d"}, Run: "run-d"},
+		},
+		[]Edge{{From: "A", To: "C"}, {From: "B", To: "D"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkpoints, metrics := &recordingObserver{}, &recordingObserver{}
+	exec, err := NewExecutor(g, &sleepyCountingRunner{exit: map[string]int{"B": 1}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec.Observer = NewMultiObserver(checkpoints, metrics)
+	if _, err := exec.RunParallel(context.Background(), 4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, o := range []*recordingObserver{checkpoints, metrics} {
+		got := append([]string(nil), o.names...)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, []string{"A", "C"}) {
+			t.Fatalf("observed %v, want [A C]", o.names)
+		}
+	}
+}
+
+func TestExecutorParallel_ObserverErrorAbortsRun(t *testing.T) {
+	g, err := NewTaskGraph([]core.Task{{Name: "A", Inputs: []string{"a"}, Run: "run-a"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec, err := NewExecutor(g, &sleepyCountingRunner{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	errObs := errors.New("disk full")
+	exec.Observer = NewMultiObserver(&recordingObserver{err: errObs})
+	if _, err := exec.RunParallel(context.Background(), 2); !errors.Is(err, errObs) {
+		t.Fatalf("expected observer error, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package dag_test
+
+import (
+	"context"
+	"testing"
+
+	"scriptweaver/determinismtest"
+)
+
+// TestPlanTrace_SerialAndParallelAgree runs a planned graph on both
+// schedulers: an executed node must be traced with the same reason by each.
+func TestPlanTrace_SerialAndParallelAgree(t *testing.T) {
+	s := determinismtest.Scenario{
+		Tasks: []determinismtest.Task{
+			{Name: "A", Run: "run-a"}, {Name: "B", Run: "run-b"}, {Name: "C", Run: "run-c"},
+			{Name: "D", Run: "run-d"}, {Name: "E", Run: "run-e"},
+		},
+		Edges:     []determinismtest.Edge{{From: "A", To: "C"}, {From: "B", To: "C"}, {From: "C", To: "D"}, {From: "C", To: "E"}},
+		Cached:    []string{"A"},
+		ExitCodes: map[string]int{"E": 2},
+	}
+	if err := determinismtest.Check(context.Background(), s, 6); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,96 @@
+package dag
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"scriptweaver/internal/core"
+)
+
+// countingCache counts the existence checks made against the cache it wraps.
+type countingCache struct {
+	core.Cache
+
+	mu      sync.Mutex
+	has     int
+	batches []int
+}
+
+func (c *countingCache) Has(hash core.TaskHash) (bool, error) {
+	c.mu.Lock()
+	c.has++
+	c.mu.Unlock()
+	return c.Cache.Has(hash)
+}
+
+func (c *countingCache) HasBatch(hashes []core.TaskHash) (map[core.TaskHash]bool, error) {
+	c.mu.Lock()
+	c.batches = append(c.batches, len(hashes))
+	c.mu.Unlock()
+	out := make(map[core.TaskHash]bool, len(hashes))
+	for _, h := range hashes {
+		ok, err := c.Cache.Has(h)
+		if err != nil {
+			return nil, err
+		}
+		out[h] = ok
+	}
+	return out, nil
+}
+
+func TestExecutor_ProbesReadyNodesInOneBatch(t *testing.T) {
+	var tasks []core.Task
+	for i := 0; i < 8; i++ {
+		tasks = append(tasks, core.Task{Name: fmt.Sprintf("n%d", i), Run: fmt.Sprintf("echo %d", i)})
+	}
+	tasks = append(tasks, core.Task{Name: "sink", Run: "echo sink"})
+	var edges []Edge
+	for _, task := range tasks[:8] {
+		edges = append(edges, Edge{From: task.Name, To: "sink"})
+	}
+	g, err := NewTaskGraph(tasks, edges)
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+
+	for _, parallel := range []bool{false, true} {
+		workDir := t.TempDir()
+		cache := &countingCache{Cache: core.NewFileCache(t.TempDir())}
+		runner, err := NewCacheAwareRunner(core.NewRunner(workDir, cache))
+		if err != nil {
+			t.Fatalf("NewCacheAwareRunner: %v", err)
+		}
+		run := func() *GraphResult {
+			t.Helper()
+			exec, err := NewExecutor(g, runner)
+			if err != nil {
+				t.Fatalf("NewExecutor: %v", err)
+			}
+			var res *GraphResult
+			if parallel {
+				res, err = exec.RunParallel(context.Background(), 4)
+			} else {
+				res, err = exec.RunSerial(context.Background())
+			}
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			return res
+		}
+
+		run()
+		cache.has, cache.batches = 0, nil
+		res := run()
+		for _, task := range tasks {
+			if res.FinalState[task.Name] != TaskCached {
+				t.Fatalf("parallel=%v: %s is %s", parallel, task.Name, res.FinalState[task.Name])
+			}
+		}
+		// The eight independent nodes are checked together, then the sink.
+		if cache.has != 0 || len(cache.batches) != 2 || cache.batches[0] != 8 || cache.batches[1] != 1 {
+			t.Fatalf("parallel=%v: has=%d batches=%v", parallel, cache.has, cache.batches)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package dag
+
+import "scriptweaver/internal/trace"
+
+// Progress is an aggregate progress event of a graph execution: how many
+// nodes are terminal overall and in each stage, a stage being the nodes of one
+// topological depth. Consumers of very wide graphs can render progress from
+// it without tracking every node.
+type Progress struct {
+	// Done is the number of nodes in a terminal state, of Total.
+	Done   int             `json:"done"`
+	Total  int             `json:"total"`
+	Stages []StageProgress `json:"stages"`
+}
+
+// StageProgress is the progress of the nodes of one topological depth.
+type StageProgress struct {
+	Depth int `json:"depth"`
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// progressTracker counts the nodes reaching a terminal state and queues a
+// Progress event whenever a stage completes or overall progress passes
+// another percent, so that a 50k-node graph yields about a hundred events
+// rather than one per node. Events are delivered by flush, outside e.mu.
+type progressTracker struct {
+	g       *TaskGraph
+	emit    func(Progress)
+	current Progress
+	percent int
+	pending []Progress
+}
+
+func newProgressTracker(g *TaskGraph, emit func(Progress)) *progressTracker {
+	if emit == nil {
+		return nil
+	}
+	maxDepth := 0
+	for _, d := range g.depth {
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+	p := &progressTracker{g: g, emit: emit, current: Progress{Total: len(g.nodes), Stages: make([]StageProgress, maxDepth+1)}}
+	for d := range p.current.Stages {
+		p.current.Stages[d].Depth = d
+	}
+	for _, n := range g.nodes {
+		p.current.Stages[g.depth[n.canonicalIndex]].Total++
+	}
+	return p
+}
+
+// terminal counts names, which have just reached a terminal state.
+func (p *progressTracker) terminal(names ...string) {
+	if p == nil {
+		return
+	}
+	for _, name := range names {
+		stage := &p.current.Stages[p.g.depth[p.g.nodesByName[name].canonicalIndex]]
+		stage.Done++
+		p.current.Done++
+		percent := p.current.Done * 100 / p.current.Total
+		if stage.Done == stage.Total || percent > p.percent {
+			p.percent = percent
+			snap := p.current
+			snap.Stages = append([]StageProgress(nil), p.current.Stages...)
+			p.pending = append(p.pending, snap)
+		}
+	}
+}
+
+// flush delivers the queued events.
+func (p *progressTracker) flush() {
+	if p == nil {
+		return
+	}
+	pending := p.pending
+	p.pending = nil
+	for _, ev := range pending {
+		p.emit(ev)
+	}
+}
+
+// transition is Transition on e.state, counting terminal nodes for progress.
+func (e *Executor) transition(name string, from, to TaskState) error {
+	if err := Transition(e.state, name, from, to); err != nil {
+		return err
+	}
+	if IsTerminal(to) {
+		e.progress.terminal(name)
+	}
+	return nil
+}
+
+// failAndPropagate is FailAndPropagate on e.state, counting the failed node
+// and the nodes it skipped for progress.
+func (e *Executor) failAndPropagate(name string) ([]string, error) {
+	wasFailed := e.state[name] == TaskFailed
+	skipped, err := FailAndPropagate(e.Graph, e.state, name)
+	if err != nil {
+		return nil, err
+	}
+	if !wasFailed {
+		e.progress.terminal(name)
+	}
+	e.progress.terminal(skipped...)
+	return skipped, nil
+}
+
+// recordStages records a StageCompleted event per topological depth with the
+// final states of its nodes.
+func recordStages(rec trace.Sink, g *TaskGraph, final ExecutionState) {
+	var stages []trace.StageSummary
+	for _, n := range g.nodes {
+		d := g.depth[n.canonicalIndex]
+		for len(stages) <= d {
+			stages = append(stages, trace.StageSummary{Depth: len(stages)})
+		}
+		s := &stages[d]
+		s.Nodes++
+		switch final[n.Name] {
+		case TaskCompleted:
+			s.Completed++
+		case TaskCached:
+			s.Cached++
+		case TaskFailed:
+			s.Failed++
+		case TaskSkipped:
+			s.Skipped++
+		}
+	}
+	for i := range stages {
+		trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventStageCompleted, Stage: &stages[i]})
+	}
+}
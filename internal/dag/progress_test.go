@@ -0,0 +1,81 @@
+package dag
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/trace"
+)
+
+func TestExecutor_ReportsProgressPerStage(t *testing.T) {
+	// Graph:
+	//   A -> C
+	//   B (independent, fails)
+	//
+	// Stage 0 (A, B) finishes before stage 1 (C).
+	g, err := NewTaskGraph(
+		[]core.Task{
+			{Name: "A", Inputs: []string{"a"}, Run: "run-a"},
+			{Name: "B", Inputs: []string{"b"}, Run: "run-b"},
+			{Name: "C", Inputs: []string{"c"}, Run: "run-c"},
+		},
+		[]Edge{{From: "A", To: "C"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, mode := range []string{"serial", "parallel"} {
+		t.Run(mode, func(t *testing.T) {
+			exec, err := NewExecutor(g, &fakeRunner{exit: map[string]int{"B": 1}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var events []Progress
+			exec.Progress = func(p Progress) { events = append(events, p) }
+
+			var res *GraphResult
+			if mode == "serial" {
+				res, err = exec.RunSerial(context.Background())
+			} else {
+				res, err = exec.RunParallel(context.Background(), 2)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(events) != 3 {
+				t.Fatalf("got %d progress events, want 3: %+v", len(events), events)
+			}
+			want := Progress{Done: 3, Total: 3, Stages: []StageProgress{{Depth: 0, Done: 2, Total: 2}, {Depth: 1, Done: 1, Total: 1}}}
+			if got := events[len(events)-1]; !reflect.DeepEqual(got, want) {
+				t.Fatalf("final progress = %+v, want %+v", got, want)
+			}
+			for i := 1; i < len(events); i++ {
+				if events[i].Done <= events[i-1].Done {
+					t.Fatalf("progress did not advance: %+v", events)
+				}
+			}
+
+			tr, err := trace.Parse(res.TraceBytes)
+			if err != nil {
+				t.Fatalf("parse trace: %v", err)
+			}
+			var stages []trace.StageSummary
+			for _, ev := range tr.Events {
+				if ev.Kind == trace.EventStageCompleted {
+					stages = append(stages, *ev.Stage)
+				}
+			}
+			wantStages := []trace.StageSummary{
+				{Depth: 0, Nodes: 2, Completed: 1, Failed: 1},
+				{Depth: 1, Nodes: 1, Completed: 1},
+			}
+			if !reflect.DeepEqual(stages, wantStages) {
+				t.Fatalf("stage summaries = %+v, want %+v", stages, wantStages)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package dag
 
-import "scriptweaver/internal/core"
+import (
+	"sort"
+
+	"scriptweaver/internal/core"
+)
 
 // GraphResult is the deterministic summary of a graph execution attempt.
 //
@@ -33,4 +37,109 @@ type GraphResult struct {
 	Stdout   map[string][]byte
 	Stderr   map[string][]byte
 	ExitCode map[string]int
+
+	// Results holds per-node outputs when the executor ran with a ResultStore;
+	// TaskHashes, Stdout, Stderr and ExitCode are then nil. The *Of accessors
+	// read from whichever representation is present.
+	Results *ResultStore
+
+	// Deduplicated maps each node that shared a byte-identical node's result to
+	// that representative node. Deduplicated nodes never appear in ExecutionOrder.
+	Deduplicated map[string]string
+
+	// SkipCause maps each skipped node to the failed node whose failure
+	// skipped it. When several upstream failures skip a node, the lexically
+	// smallest failed node is the cause, independent of completion order.
+	SkipCause map[string]string
+
+	// SkippedBy maps each node skipped on request (see Executor.Skip) to
+	// itself, and each node skipped because it depends on one to the
+	// lexically smallest such requested node.
+	SkippedBy map[string]string
+
+	// FailedBySkip maps each node failed under FailDependents, which never
+	// ran, to the lexically smallest node skipped on request it depends on.
+	FailedBySkip map[string]string
+
+	// Throttled maps each node whose start a label limit held back to that
+	// label (see Executor.LabelLimits).
+	Throttled map[string]string
+
+	// Replanned maps each node whose planned cache restoration failed and
+	// that was executed instead (see Executor.Replan) to the restore error.
+	Replanned map[string]string
+}
+
+// FailedNodes returns the nodes that failed, in lexical order.
+func (r *GraphResult) FailedNodes() []string {
+	return r.nodesIn(TaskFailed)
+}
+
+// SkippedNodes returns the nodes that were skipped because an upstream node
+// failed, in lexical order.
+func (r *GraphResult) SkippedNodes() []string {
+	names := []string{}
+	for _, name := range r.nodesIn(TaskSkipped) {
+		if _, requested := r.SkippedBy[name]; !requested {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// RequestedSkips returns the nodes that were skipped on request or because
+// they depend on such a node, in lexical order.
+func (r *GraphResult) RequestedSkips() []string {
+	names := make([]string, 0, len(r.SkippedBy))
+	for name := range r.SkippedBy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *GraphResult) nodesIn(st TaskState) []string {
+	names := []string{}
+	for name, s := range r.FinalState {
+		if s == st {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TaskHashOf returns the TaskHash recorded for name.
+func (r *GraphResult) TaskHashOf(name string) core.TaskHash {
+	if r.Results != nil {
+		h, _ := r.Results.TaskHash(name)
+		return h
+	}
+	return r.TaskHashes[name]
+}
+
+// ExitCodeOf returns the exit code recorded for name.
+func (r *GraphResult) ExitCodeOf(name string) (int, bool) {
+	if r.Results != nil {
+		return r.Results.ExitCode(name)
+	}
+	code, ok := r.ExitCode[name]
+	return code, ok
+}
+
+// StdoutOf returns the stdout recorded for name. Disk-backed results are read
+// on demand, which is the only source of error.
+func (r *GraphResult) StdoutOf(name string) ([]byte, error) {
+	if r.Results != nil {
+		return r.Results.Stdout(name)
+	}
+	return r.Stdout[name], nil
+}
+
+// StderrOf returns the stderr recorded for name.
+func (r *GraphResult) StderrOf(name string) ([]byte, error) {
+	if r.Results != nil {
+		return r.Results.Stderr(name)
+	}
+	return r.Stderr[name], nil
 }
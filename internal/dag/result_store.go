@@ -0,0 +1,280 @@
+package dag
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"scriptweaver/internal/core"
+)
+
+// ResultStore keeps per-node execution outputs in columnar form.
+//
+// GraphResult's map fields cost a map entry per node per field plus a separate
+// allocation for every stdout/stderr slice. A ResultStore packs hashes and exit
+// codes into parallel slices and appends log bytes to a single arena, or, when
+// opened with a backing file, spills log bytes to disk and reads them back
+// only when asked for.
+//
+// A ResultStore is safe for concurrent use.
+type ResultStore struct {
+	mu sync.Mutex
+
+	index  map[string]int
+	hashes []core.TaskHash
+	exit   []int32
+	stdout []span
+	stderr []span
+
+	// arena holds log bytes for in-memory stores.
+	arena []byte
+	// file holds log bytes for disk-backed stores; size is its current length.
+	file *os.File
+	size int64
+
+	// writeErr is the first failure to persist log bytes.
+	writeErr error
+}
+
+// span locates one log within the arena or backing file.
+type span struct {
+	off int64
+	n   int
+}
+
+// NewResultStore returns an in-memory ResultStore.
+func NewResultStore() *ResultStore {
+	return &ResultStore{index: make(map[string]int)}
+}
+
+// OpenResultStore returns a ResultStore whose log bytes are written to path.
+// The file is truncated; callers must Close the store when done.
+func OpenResultStore(path string) (*ResultStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open result store: %w", err)
+	}
+	s := NewResultStore()
+	s.file = f
+	return s, nil
+}
+
+// Close releases the backing file, if any.
+func (s *ResultStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Len returns the number of nodes with recorded outputs.
+func (s *ResultStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.index)
+}
+
+// Names returns the recorded node names in lexical order.
+func (s *ResultStore) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.index))
+	for name := range s.index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Put records a node's outputs, replacing any earlier record for the node.
+func (s *ResultStore) Put(name string, hash core.TaskHash, stdout, stderr []byte, exitCode int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out, err := s.appendLog(stdout)
+	if err != nil {
+		return err
+	}
+	errSpan, err := s.appendLog(stderr)
+	if err != nil {
+		return err
+	}
+
+	i, ok := s.index[name]
+	if !ok {
+		i = len(s.hashes)
+		s.index[name] = i
+		s.hashes = append(s.hashes, "")
+		s.exit = append(s.exit, 0)
+		s.stdout = append(s.stdout, span{})
+		s.stderr = append(s.stderr, span{})
+	}
+	s.hashes[i] = hash
+	s.exit[i] = int32(exitCode)
+	s.stdout[i] = out
+	s.stderr[i] = errSpan
+	return nil
+}
+
+func (s *ResultStore) appendLog(b []byte) (span, error) {
+	if s.writeErr != nil {
+		return span{}, s.writeErr
+	}
+	if len(b) == 0 {
+		return span{}, nil
+	}
+	if s.file == nil {
+		sp := span{off: int64(len(s.arena)), n: len(b)}
+		s.arena = append(s.arena, b...)
+		return sp, nil
+	}
+	if _, err := s.file.WriteAt(b, s.size); err != nil {
+		s.writeErr = fmt.Errorf("write result store: %w", err)
+		return span{}, s.writeErr
+	}
+	sp := span{off: s.size, n: len(b)}
+	s.size += int64(len(b))
+	return sp, nil
+}
+
+// TaskHash returns the node's task hash.
+func (s *ResultStore) TaskHash(name string) (core.TaskHash, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.index[name]
+	if !ok {
+		return "", false
+	}
+	return s.hashes[i], true
+}
+
+// ExitCode returns the node's exit code.
+func (s *ResultStore) ExitCode(name string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.index[name]
+	if !ok {
+		return 0, false
+	}
+	return int(s.exit[i]), true
+}
+
+// Stdout returns a copy of the node's stdout, or nil if none was recorded.
+func (s *ResultStore) Stdout(name string) ([]byte, error) {
+	return s.readLog(name, func(i int) span { return s.stdout[i] })
+}
+
+// Stderr returns a copy of the node's stderr, or nil if none was recorded.
+func (s *ResultStore) Stderr(name string) ([]byte, error) {
+	return s.readLog(name, func(i int) span { return s.stderr[i] })
+}
+
+func (s *ResultStore) readLog(name string, pick func(int) span) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.index[name]
+	if !ok {
+		return nil, nil
+	}
+	sp := pick(i)
+	if sp.n == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, sp.n)
+	if s.file == nil {
+		copy(buf, s.arena[sp.off:sp.off+int64(sp.n)])
+		return buf, nil
+	}
+	if _, err := s.file.ReadAt(buf, sp.off); err != nil {
+		return nil, fmt.Errorf("read result store: %w", err)
+	}
+	return buf, nil
+}
+
+// nodeOutputs records per-node outputs during a run, either into the
+// GraphResult maps or into a ResultStore.
+type nodeOutputs struct {
+	store *ResultStore
+
+	taskHashes map[string]core.TaskHash
+	stdout     map[string][]byte
+	stderr     map[string][]byte
+	exitCodes  map[string]int
+
+	err error
+}
+
+func newNodeOutputs(store *ResultStore, n int) *nodeOutputs {
+	if store != nil {
+		return &nodeOutputs{store: store}
+	}
+	return &nodeOutputs{
+		taskHashes: make(map[string]core.TaskHash, n),
+		stdout:     make(map[string][]byte, n),
+		stderr:     make(map[string][]byte, n),
+		exitCodes:  make(map[string]int, n),
+	}
+}
+
+func (o *nodeOutputs) record(name string, hash core.TaskHash, stdout, stderr []byte, exitCode int) {
+	if o.store != nil {
+		if err := o.store.Put(name, hash, stdout, stderr, exitCode); err != nil && o.err == nil {
+			o.err = err
+		}
+		return
+	}
+	o.taskHashes[name] = hash
+	o.stdout[name] = stdout
+	o.stderr[name] = stderr
+	o.exitCodes[name] = exitCode
+}
+
+// fail records a node that failed before producing a result.
+func (o *nodeOutputs) fail(name string, msg []byte) {
+	if o.store != nil {
+		o.record(name, "", nil, msg, 1)
+		return
+	}
+	o.stderr[name] = msg
+	o.exitCodes[name] = 1
+}
+
+// shared builds the NodeResult a duplicate inherits from its representative.
+func (o *nodeOutputs) shared(rep string) *NodeResult {
+	if o.store != nil {
+		hash, _ := o.store.TaskHash(rep)
+		exitCode, _ := o.store.ExitCode(rep)
+		stdout, err := o.store.Stdout(rep)
+		if err != nil && o.err == nil {
+			o.err = err
+		}
+		stderr, err := o.store.Stderr(rep)
+		if err != nil && o.err == nil {
+			o.err = err
+		}
+		return &NodeResult{Hash: hash, Stdout: stdout, Stderr: stderr, ExitCode: exitCode}
+	}
+	return &NodeResult{
+		Hash:     o.taskHashes[rep],
+		Stdout:   o.stdout[rep],
+		Stderr:   o.stderr[rep],
+		ExitCode: o.exitCodes[rep],
+	}
+}
+
+// fill attaches the recorded outputs to gr.
+func (o *nodeOutputs) fill(gr *GraphResult) {
+	if o.store != nil {
+		gr.Results = o.store
+		return
+	}
+	gr.TaskHashes = o.taskHashes
+	gr.Stdout = o.stdout
+	gr.Stderr = o.stderr
+	gr.ExitCode = o.exitCodes
+}
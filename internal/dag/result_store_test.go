@@ -0,0 +1,97 @@
+package dag
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+)
+
+func TestResultStore_PutAndReadBack(t *testing.T) {
+	disk, err := OpenResultStore(filepath.Join(t.TempDir(), "results.bin"))
+	if err != nil {
+		t.Fatalf("OpenResultStore: %v", err)
+	}
+	defer disk.Close()
+
+	for name, s := range map[string]*ResultStore{"memory": NewResultStore(), "disk": disk} {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Put("a", "h1", []byte("out"), nil, 0); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if err := s.Put("b", "h2", nil, []byte("boom"), 2); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			// A later record replaces the earlier one.
+			if err := s.Put("a", "h3", []byte("out2"), nil, 0); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			if got := s.Names(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+				t.Fatalf("Names = %v", got)
+			}
+			if h, ok := s.TaskHash("a"); !ok || h != "h3" {
+				t.Fatalf("TaskHash(a) = %q, %v", h, ok)
+			}
+			if code, ok := s.ExitCode("b"); !ok || code != 2 {
+				t.Fatalf("ExitCode(b) = %d, %v", code, ok)
+			}
+			if out, err := s.Stdout("a"); err != nil || string(out) != "out2" {
+				t.Fatalf("Stdout(a) = %q, %v", out, err)
+			}
+			if errOut, err := s.Stderr("b"); err != nil || string(errOut) != "boom" {
+				t.Fatalf("Stderr(b) = %q, %v", errOut, err)
+			}
+			if out, err := s.Stdout("b"); err != nil || out != nil {
+				t.Fatalf("Stdout(b) = %q, %v", out, err)
+			}
+			if _, ok := s.ExitCode("missing"); ok {
+				t.Fatalf("missing node must not be found")
+			}
+		})
+	}
+}
+
+func TestExecutor_ResultStore_MatchesMapResults(t *testing.T) {
+	run := func(store *ResultStore) *GraphResult {
+		exec, err := NewExecutor(dedupGraph(t), &countingRunner{exit: map[string]int{"E": 3}})
+		if err != nil {
+			t.Fatalf("NewExecutor: %v", err)
+		}
+		exec.Results = store
+		gr, err := exec.RunSerial(context.Background())
+		if err != nil {
+			t.Fatalf("RunSerial: %v", err)
+		}
+		return gr
+	}
+
+	maps := run(nil)
+	columnar := run(NewResultStore())
+	if columnar.Stdout != nil || columnar.TaskHashes != nil || columnar.Results == nil {
+		t.Fatalf("columnar result must not populate maps")
+	}
+	if !reflect.DeepEqual(maps.FinalState, columnar.FinalState) || !reflect.DeepEqual(maps.ExecutionOrder, columnar.ExecutionOrder) {
+		t.Fatalf("state/order diverged")
+	}
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		if maps.TaskHashOf(name) != columnar.TaskHashOf(name) {
+			t.Fatalf("%s: hash %q vs %q", name, maps.TaskHashOf(name), columnar.TaskHashOf(name))
+		}
+		mc, mok := maps.ExitCodeOf(name)
+		cc, cok := columnar.ExitCodeOf(name)
+		if mc != cc || mok != cok {
+			t.Fatalf("%s: exit %d/%v vs %d/%v", name, mc, mok, cc, cok)
+		}
+		mo, _ := maps.StdoutOf(name)
+		co, err := columnar.StdoutOf(name)
+		if err != nil || string(mo) != string(co) {
+			t.Fatalf("%s: stdout %q vs %q (%v)", name, mo, co, err)
+		}
+	}
+	if columnar.TaskHashOf("A") != core.TaskHash("hash:gen") {
+		t.Fatalf("unexpected hash %q", columnar.TaskHashOf("A"))
+	}
+}
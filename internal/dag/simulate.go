@@ -0,0 +1,178 @@
+package dag
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/platform"
+)
+
+// Scenario scripts what every node of a simulated run does, so that the
+// scheduler, planners and reports can be exercised and benchmarked without
+// running real commands. See SimulatedRunner.
+//
+// A scenario file is JSON:
+//
+//	{
+//	  "default": {"duration": "10ms"},
+//	  "nodes": {
+//	    "build": {"duration": "2s", "stdout": "built\n"},
+//	    "test":  {"duration": "500ms", "exit_code": 1, "stderr": "1 failed\n"},
+//	    "lint":  {"cached": true},
+//	    "fetch": {"error": "connection reset by peer"}
+//	  }
+//	}
+//
+// A node listed under nodes follows its own script only; nodes not listed
+// follow the default script.
+type Scenario struct {
+	Default NodeScript            `json:"default"`
+	Nodes   map[string]NodeScript `json:"nodes"`
+}
+
+// NodeScript is the scripted outcome of one node.
+type NodeScript struct {
+	// Duration is how long the node runs, or is restored for when cached.
+	Duration time.Duration `json:"-"`
+	ExitCode int           `json:"exit_code,omitempty"`
+	// Cached makes the node a cache hit: its result is replayed instead of
+	// being run.
+	Cached bool   `json:"cached,omitempty"`
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+	// Error makes running the node fail with an engine error, such as a
+	// crashed executor, instead of a result.
+	Error string `json:"error,omitempty"`
+}
+
+// UnmarshalJSON decodes a script whose duration is a time.ParseDuration
+// string such as "1.5s".
+func (s *NodeScript) UnmarshalJSON(data []byte) error {
+	type plain NodeScript
+	var raw struct {
+		plain
+		Duration string `json:"duration"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	*s = NodeScript(raw.plain)
+	if raw.Duration != "" {
+		d, err := time.ParseDuration(raw.Duration)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid duration %q", raw.Duration)
+		}
+		s.Duration = d
+	}
+	return nil
+}
+
+// ErrInvalidScenario is returned for a scenario that does not parse or does
+// not fit the graph.
+var ErrInvalidScenario = errors.New("invalid scenario")
+
+// ParseScenario decodes a scenario file. Unknown fields are rejected so that
+// a misspelled script is not silently ignored.
+func ParseScenario(data []byte) (*Scenario, error) {
+	var sc Scenario
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&sc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidScenario, err)
+	}
+	return &sc, nil
+}
+
+// Validate checks that every node the scenario scripts is a node of g.
+func (sc *Scenario) Validate(g *TaskGraph) error {
+	var unknown []string
+	for name := range sc.Nodes {
+		if _, ok := g.Node(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: unknown nodes %q", ErrInvalidScenario, unknown)
+	}
+	return nil
+}
+
+// Script returns the script of the named node.
+func (sc *Scenario) Script(name string) NodeScript {
+	if s, ok := sc.Nodes[name]; ok {
+		return s
+	}
+	return sc.Default
+}
+
+// SimulatedRunner is a TaskRunner that plays a Scenario instead of running
+// commands: each node takes its scripted duration on Clock and produces its
+// scripted result. Nothing is read from or written to the workspace.
+//
+// Task hashes are computed from the task definition alone, as no input is
+// read, so they are deterministic but differ from those of real runs.
+type SimulatedRunner struct {
+	Scenario *Scenario
+	// Clock paces the scripted durations; nil means the system clock, and a
+	// platform.FakeClock completes every node at once.
+	Clock platform.Clock
+
+	hasher *core.TaskHasher
+}
+
+// NewSimulatedRunner returns a SimulatedRunner playing sc.
+func NewSimulatedRunner(sc *Scenario) *SimulatedRunner {
+	return &SimulatedRunner{Scenario: sc, hasher: core.NewTaskHasher()}
+}
+
+// Probe reports the nodes scripted as cached as cache hits.
+func (r *SimulatedRunner) Probe(ctx context.Context, task core.Task) (*NodeResult, bool, error) {
+	if !r.Scenario.Script(task.Name).Cached {
+		return nil, false, nil
+	}
+	res, err := r.play(ctx, task)
+	if err != nil {
+		return nil, false, err
+	}
+	return res, true, nil
+}
+
+// Run plays the script of task.
+func (r *SimulatedRunner) Run(ctx context.Context, task core.Task) (*NodeResult, error) {
+	return r.play(ctx, task)
+}
+
+// Restore plays the script of a node an incremental plan restores, as a
+// cache hit.
+func (r *SimulatedRunner) Restore(ctx context.Context, task core.Task) (*NodeResult, error) {
+	res, err := r.play(ctx, task)
+	if res != nil {
+		res.FromCache = true
+	}
+	return res, err
+}
+
+func (r *SimulatedRunner) play(ctx context.Context, task core.Task) (*NodeResult, error) {
+	s := r.Scenario.Script(task.Name)
+	if err := platform.ClockOr(r.Clock).Sleep(ctx, s.Duration); err != nil {
+		return nil, err
+	}
+	if s.Error != "" {
+		return nil, errors.New(s.Error)
+	}
+	hasher := r.hasher
+	if hasher == nil {
+		hasher = core.NewTaskHasher()
+	}
+	hash := hasher.ComputeHash(task.HashInput(nil, ""))
+	return &NodeResult{Hash: hash, Stdout: []byte(s.Stdout), Stderr: []byte(s.Stderr), ExitCode: s.ExitCode, FromCache: s.Cached}, nil
+}
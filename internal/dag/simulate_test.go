@@ -0,0 +1,103 @@
+package dag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/platform"
+)
+
+func TestSimulatedRunner_PlaysScenario(t *testing.T) {
+	// Graph:
+	//   A -> B
+	//   C (cached)
+	g, err := NewTaskGraph(
+		[]core.Task{
+			{Name: "A", Inputs: []string{"a"}, Run: "run-a"},
+			{Name: "B", Inputs: []string{"b"}, Run: "run-b"},
+			{Name: "C", Inputs: []string{"c"}, Run: "run-c"},
+		},
+		[]Edge{{From: "A", To: "B"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sc, err := ParseScenario([]byte(`{
+		"default": {"duration": "1s"},
+		"nodes": {
+			"A": {"duration": "2m", "stdout": "built"},
+			"B": {"exit_code": 3, "stderr": "boom"},
+			"C": {"duration": "5s", "cached": true}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseScenario: %v", err)
+	}
+	if err := sc.Validate(g); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	clock := platform.NewFakeClock(time.Unix(0, 0))
+	runner := NewSimulatedRunner(sc)
+	runner.Clock = clock
+	exec, err := NewExecutor(g, runner)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res, err := exec.RunSerial(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := ExecutionState{"A": TaskCompleted, "B": TaskFailed, "C": TaskCached}
+	if !reflect.DeepEqual(res.FinalState, want) {
+		t.Fatalf("final state = %v, want %v", res.FinalState, want)
+	}
+	if string(res.Stdout["A"]) != "built" || res.ExitCode["B"] != 3 || string(res.Stderr["B"]) != "boom" {
+		t.Fatalf("results: stdout %q, exit %v, stderr %q", res.Stdout["A"], res.ExitCode, res.Stderr["B"])
+	}
+	if res.TaskHashOf("A") == "" || res.TaskHashOf("A") == res.TaskHashOf("B") {
+		t.Fatalf("task hashes = %v", res.TaskHashes)
+	}
+	if got := clock.Now().Sub(time.Unix(0, 0)); got != 2*time.Minute+5*time.Second {
+		t.Fatalf("simulated time = %v", got)
+	}
+
+	// A scripted engine error aborts the run like a crashed executor.
+	sc.Nodes["A"] = NodeScript{Error: "connection reset by peer"}
+	if exec, err = NewExecutor(g, runner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := exec.RunSerial(context.Background()); err == nil || !strings.Contains(err.Error(), "connection reset by peer") {
+		t.Fatalf("expected the scripted error, got %v", err)
+	}
+}
+
+func TestParseScenario_RejectsInvalidScripts(t *testing.T) {
+	g, err := NewTaskGraph([]core.Task{{Name: "A", Inputs: []string{"a"}, Run: "run-a"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, data := range []string{
+		`{"nodes": {"A": {"duration": "soon"}}}`,
+		`{"nodes": {"A": {"duration": "-1s"}}}`,
+		`{"nodes": {"A": {"exit": 1}}}`,
+		`{"defaults": {}}`,
+	} {
+		if _, err := ParseScenario([]byte(data)); !errors.Is(err, ErrInvalidScenario) {
+			t.Errorf("ParseScenario(%s) = %v", data, err)
+		}
+	}
+	sc, err := ParseScenario([]byte(`{"nodes": {"Z": {}}}`))
+	if err != nil {
+		t.Fatalf("ParseScenario: %v", err)
+	}
+	if err := sc.Validate(g); !errors.Is(err, ErrInvalidScenario) {
+		t.Fatalf("Validate = %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package dag
+
+import (
+	"fmt"
+	"sort"
+
+	"scriptweaver/internal/incremental"
+	"scriptweaver/internal/trace"
+)
+
+// SkipPolicy decides what happens to the dependents of nodes skipped on
+// request (see Executor.Skip). A skipped node is a barrier like a cached
+// result that is unavailable: its dependents cannot run.
+type SkipPolicy string
+
+const (
+	// SkipDependents skips every node depending on a skipped node,
+	// transitively. It is the default.
+	SkipDependents SkipPolicy = "skip"
+	// FailDependents fails the nodes directly depending on a skipped node,
+	// which skips their own dependents as any failure does, so that the run
+	// fails instead of silently doing less.
+	FailDependents SkipPolicy = "fail"
+)
+
+// ParseSkipPolicy parses a SkipPolicy; empty selects SkipDependents.
+func ParseSkipPolicy(s string) (SkipPolicy, error) {
+	switch SkipPolicy(s) {
+	case "", SkipDependents:
+		return SkipDependents, nil
+	case FailDependents:
+		return FailDependents, nil
+	default:
+		return "", fmt.Errorf("invalid skip policy %q (expected skip|fail)", s)
+	}
+}
+
+// Trace reasons of requested skips.
+const (
+	// ReasonSkipRequested marks a node that was skipped on request.
+	ReasonSkipRequested = "Requested"
+	// ReasonUpstreamSkipped marks a node skipped, or failed under
+	// FailDependents, because a node it depends on (CauseTaskID) was skipped
+	// on request.
+	ReasonUpstreamSkipped = "UpstreamSkipped"
+)
+
+// requestedSkips returns the nodes to skip on request: those of e.Skip and
+// those the plan decided to skip, in lexical order.
+func (e *Executor) requestedSkips() ([]string, error) {
+	set := make(map[string]bool)
+	for _, name := range e.Skip {
+		if _, ok := e.Graph.nodesByName[name]; !ok {
+			return nil, fmt.Errorf("skip: unknown task: %q", name)
+		}
+		set[name] = true
+	}
+	if e.Plan != nil {
+		for name, d := range e.Plan.Decisions {
+			if _, ok := e.Graph.nodesByName[name]; ok && d == incremental.DecisionSkip {
+				set[name] = true
+			}
+		}
+	}
+	out := make([]string, 0, len(set))
+	for name := range set {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// applySkips marks the requested skips SKIPPED before any node runs and
+// settles their dependents according to e.SkipPolicy. It returns the
+// requested skip that skipped each node (the node itself for a requested one),
+// and the one that failed each dependent failed under FailDependents.
+// Those dependents are recorded in outs, and their own skipped dependents
+// are reported to noteSkipped like those of any failure. The caller holds
+// e.mu.
+func (e *Executor) applySkips(rec trace.Sink, outs *nodeOutputs, noteSkipped func(cause string) error) (map[string]string, map[string]string, error) {
+	requested, err := e.requestedSkips()
+	if err != nil || len(requested) == 0 {
+		return nil, nil, err
+	}
+	skippedBy := make(map[string]string)
+	for _, name := range requested {
+		if err := e.transition(name, TaskPending, TaskSkipped); err != nil {
+			return nil, nil, err
+		}
+		skippedBy[name] = name
+		trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskSkipped, TaskID: name, Reason: ReasonSkipRequested})
+	}
+
+	if e.SkipPolicy == FailDependents {
+		// The lexically smallest skipped dependency is the cause of a failure.
+		failCause := make(map[int]string)
+		failedBy := make(map[string]string)
+		for _, name := range requested {
+			for _, d := range e.Graph.outgoing[e.Graph.nodesByName[name].canonicalIndex] {
+				if _, ok := failCause[d]; !ok {
+					failCause[d] = name
+				}
+			}
+		}
+		for idx := range e.Graph.nodes {
+			cause, ok := failCause[idx]
+			name := e.Graph.nodes[idx].Name
+			if !ok || e.state[name] != TaskPending {
+				continue
+			}
+			if err := e.transition(name, TaskPending, TaskRunning); err != nil {
+				return nil, nil, err
+			}
+			outs.fail(name, []byte(fmt.Sprintf("upstream %s was skipped", cause)))
+			failedBy[name] = cause
+			trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskFailed, TaskID: name, Reason: ReasonUpstreamSkipped, CauseTaskID: cause})
+			if _, err := e.failAndPropagate(name); err != nil {
+				return nil, nil, err
+			}
+			if err := noteSkipped(name); err != nil {
+				return nil, nil, err
+			}
+		}
+		return skippedBy, failedBy, nil
+	}
+
+	for _, name := range requested {
+		downstream, err := downstreamReachable(e.Graph, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, d := range downstream {
+			if _, ok := skippedBy[d]; ok {
+				continue
+			}
+			if err := e.transition(d, TaskPending, TaskSkipped); err != nil {
+				return nil, nil, err
+			}
+			skippedBy[d] = name
+			trace.SafeRecord(rec, trace.TraceEvent{Kind: trace.EventTaskSkipped, TaskID: d, Reason: ReasonUpstreamSkipped, CauseTaskID: name})
+		}
+	}
+	return skippedBy, nil, nil
+}
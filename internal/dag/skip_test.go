@@ -0,0 +1,110 @@
+package dag
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/trace"
+)
+
+func TestExecutor_SkipPropagatesByPolicy(t *testing.T) {
+	// Graph:
+	//   A -> B -> C
+	//   D (independent)
+	g, err := NewTaskGraph(
+		[]core.Task{
+			{Name: "A", Inputs: []string{"a"}, Run: "run-a"},
+			{Name: "B", Inputs: []string{"b"}, Run: "run-b"},
+			{Name: "C", Inputs: []string{"c"}, Run: "run-c"},
+			{Name: "D", Inputs: []string{"d"}, Run: "run-d"},
+		},
+		[]Edge{{From: "A", To: "B"}, {From: "B", To: "C"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, tc := range []struct {
+		policy    SkipPolicy
+		state     ExecutionState
+		skippedBy map[string]string
+		skipCause map[string]string
+		reasons   map[string]string
+	}{
+		{
+			policy:    SkipDependents,
+			state:     ExecutionState{"A": TaskSkipped, "B": TaskSkipped, "C": TaskSkipped, "D": TaskCompleted},
+			skippedBy: map[string]string{"A": "A", "B": "A", "C": "A"},
+			skipCause: map[string]string{},
+			reasons:   map[string]string{"A": "TaskSkipped/Requested", "B": "TaskSkipped/UpstreamSkipped", "C": "TaskSkipped/UpstreamSkipped"},
+		},
+		{
+			policy:    FailDependents,
+			state:     ExecutionState{"A": TaskSkipped, "B": TaskFailed, "C": TaskSkipped, "D": TaskCompleted},
+			skippedBy: map[string]string{"A": "A"},
+			skipCause: map[string]string{"C": "B"},
+			reasons:   map[string]string{"A": "TaskSkipped/Requested", "B": "TaskFailed/UpstreamSkipped", "C": "TaskSkipped/UpstreamFailed"},
+		},
+	} {
+		for _, mode := range []string{"serial", "parallel"} {
+			t.Run(string(tc.policy)+"/"+mode, func(t *testing.T) {
+				exec, err := NewExecutor(g, &fakeRunner{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				exec.Skip = []string{"A"}
+				exec.SkipPolicy = tc.policy
+
+				var res *GraphResult
+				if mode == "serial" {
+					res, err = exec.RunSerial(context.Background())
+				} else {
+					res, err = exec.RunParallel(context.Background(), 2)
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !reflect.DeepEqual(res.FinalState, tc.state) {
+					t.Fatalf("final state = %v, want %v", res.FinalState, tc.state)
+				}
+				if !reflect.DeepEqual(res.SkippedBy, tc.skippedBy) || !reflect.DeepEqual(res.SkipCause, tc.skipCause) {
+					t.Fatalf("skipped by %v, skip cause %v", res.SkippedBy, res.SkipCause)
+				}
+				if !reflect.DeepEqual(res.ExecutionOrder, []string{"D"}) {
+					t.Fatalf("execution order = %v", res.ExecutionOrder)
+				}
+
+				tr, err := trace.Parse(res.TraceBytes)
+				if err != nil {
+					t.Fatalf("parse trace: %v", err)
+				}
+				reasons := make(map[string]string)
+				for _, ev := range tr.Events {
+					if ev.TaskID != "" && ev.TaskID != "D" {
+						reasons[ev.TaskID] = string(ev.Kind) + "/" + ev.Reason
+					}
+				}
+				if !reflect.DeepEqual(reasons, tc.reasons) {
+					t.Fatalf("trace reasons = %v, want %v", reasons, tc.reasons)
+				}
+			})
+		}
+	}
+}
+
+func TestExecutor_SkipUnknownTaskFails(t *testing.T) {
+	g, err := NewTaskGraph([]core.Task{{Name: "A", Inputs: []string{"a"}, Run: "run-a"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec, err := NewExecutor(g, &fakeRunner{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exec.Skip = []string{"Z"}
+	if _, err := exec.RunSerial(context.Background()); err == nil {
+		t.Fatalf("expected error for unknown skipped task")
+	}
+}
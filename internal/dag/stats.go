@@ -0,0 +1,78 @@
+package dag
+
+// GraphStats summarizes the shape of a graph, for reviewing generated graphs
+// for pathological shapes such as a single long chain or one huge fan-in.
+type GraphStats struct {
+	Nodes int `json:"nodes"`
+	Edges int `json:"edges"`
+	// Depth is the number of levels, a level being the nodes of one
+	// topological depth; it is the length of the critical path in nodes.
+	Depth int `json:"depth"`
+	// Widths is the number of nodes per level, by depth.
+	Widths   []int `json:"widths"`
+	MaxWidth int   `json:"max_width"`
+	// AvgFanIn and AvgFanOut are the mean number of dependencies and
+	// dependents of a node; both equal Edges/Nodes.
+	AvgFanIn  float64 `json:"avg_fan_in"`
+	AvgFanOut float64 `json:"avg_fan_out"`
+	MaxFanIn  int     `json:"max_fan_in"`
+	MaxFanOut int     `json:"max_fan_out"`
+	// Roots have no dependencies; Leaves have no dependents.
+	Roots  int `json:"roots"`
+	Leaves int `json:"leaves"`
+	// Components is the number of weakly connected components.
+	Components int `json:"components"`
+	// Parallelism estimates the speedup of unlimited workers over one,
+	// assuming every node takes equally long: Nodes/Depth.
+	Parallelism float64 `json:"parallelism"`
+}
+
+// Stats computes the statistics of g.
+func (g *TaskGraph) Stats() GraphStats {
+	s := GraphStats{Nodes: len(g.nodes), Edges: len(g.edges)}
+	for i := range g.nodes {
+		d := g.depth[i]
+		for len(s.Widths) <= d {
+			s.Widths = append(s.Widths, 0)
+		}
+		s.Widths[d]++
+		if len(g.incoming[i]) == 0 {
+			s.Roots++
+		}
+		if len(g.outgoing[i]) == 0 {
+			s.Leaves++
+		}
+		s.MaxFanIn = max(s.MaxFanIn, len(g.incoming[i]))
+		s.MaxFanOut = max(s.MaxFanOut, len(g.outgoing[i]))
+	}
+	s.Depth = len(s.Widths)
+	for _, w := range s.Widths {
+		s.MaxWidth = max(s.MaxWidth, w)
+	}
+	if s.Nodes > 0 {
+		s.AvgFanIn = float64(s.Edges) / float64(s.Nodes)
+		s.AvgFanOut = s.AvgFanIn
+		s.Parallelism = float64(s.Nodes) / float64(s.Depth)
+	}
+
+	// Count components with union-find over the edges.
+	parent := make([]int, len(g.nodes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	s.Components = len(g.nodes)
+	for _, e := range g.edges {
+		if a, b := find(e.from), find(e.to); a != b {
+			parent[a] = b
+			s.Components--
+		}
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+package dag
+
+import (
+	"reflect"
+	"testing"
+
+	"scriptweaver/internal/core"
+)
+
+func TestGraphStats(t *testing.T) {
+	// Graph:
+	//   A -> C, B -> C, C -> D
+	//   E (independent)
+	g, err := NewTaskGraph(
+		[]core.Task{
+			{Name: "A", Inputs: []string{"a"}, Run: "run-a"},
+			{Name: "B", Inputs: []string{"b"}, Run: "run-b"},
+			{Name: "C", Inputs: []string{"c"}, Run: "run-c"},
+			{Name: "D", Inputs: []string{"d"}, Run: "run-d"},
+			{Name: "E", Inputs: []string{"e"}, Run: "run-e"},
+		},
+		[]Edge{{From: "A", To: "C"}, {From: "B", To: "C"}, {From: "C", To: "D"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := GraphStats{
+		Nodes:       5,
+		Edges:       3,
+		Depth:       3,
+		Widths:      []int{3, 1, 1},
+		MaxWidth:    3,
+		AvgFanIn:    0.6,
+		AvgFanOut:   0.6,
+		MaxFanIn:    2,
+		MaxFanOut:   1,
+		Roots:       3,
+		Leaves:      2,
+		Components:  2,
+		Parallelism: 5.0 / 3,
+	}
+	if got := g.Stats(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Stats() = %+v, want %+v", got, want)
+	}
+}
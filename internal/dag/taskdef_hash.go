@@ -7,13 +7,13 @@ import (
 )
 
 // computeTaskDefHash hashes only the declarative definition fields required by the
-// DAG prompt: inputs, env, run.
+// DAG prompt: inputs, env, run, and the Fetch.Key of fetch tasks.
 //
 // Determinism rules:
 //   - Inputs are treated as a set for identity and thus sorted.
 //   - Env map is sorted by key.
 //   - All fields are length-prefixed to avoid ambiguity.
-func computeTaskDefHash(inputs []string, env map[string]string, run string) TaskDefHash {
+func computeTaskDefHash(inputs []string, env map[string]string, run string, fetch string) TaskDefHash {
 	h := sha256.New()
 
 	writeField := func(data []byte) {
@@ -56,6 +56,11 @@ func computeTaskDefHash(inputs []string, env map[string]string, run string) Task
 	// Run
 	writeField([]byte(run))
 
+	// Fetch, only for fetch tasks so other definitions hash as before
+	if fetch != "" {
+		writeField([]byte(fetch))
+	}
+
 	sum := h.Sum(nil)
 	return TaskDefHash(hex.EncodeToString(sum))
 }
@@ -9,8 +9,9 @@ import (
 )
 
 type edgeIndex struct {
-	from int
-	to   int
+	from  int
+	to    int
+	order bool
 }
 
 // TaskGraph is an immutable, validated DAG definition.
@@ -35,7 +36,8 @@ type TaskGraph struct {
 // Validation runs immediately and rejects:
 //   - empty or duplicate task names
 //   - edges referencing unknown tasks
-//   - duplicate edges
+//   - duplicate edges, whatever their types
+//   - edges of an unknown type
 //   - self-loops
 //   - any cycle (direct or indirect)
 func NewTaskGraph(tasks []core.Task, edges []Edge) (*TaskGraph, error) {
@@ -54,7 +56,14 @@ func NewTaskGraph(tasks []core.Task, edges []Edge) (*TaskGraph, error) {
 			return nil, invalidf("duplicate task name: %q", t.Name)
 		}
 
-		defHash := computeTaskDefHash(t.Inputs, t.Env, t.Run)
+		if err := t.ValidateFetch(); err != nil {
+			return nil, invalidf("task %q: %v", t.Name, err)
+		}
+		if err := t.ValidateLimits(); err != nil {
+			return nil, invalidf("task %q: %v", t.Name, err)
+		}
+
+		defHash := computeTaskDefHash(t.Inputs, t.Env, t.Run, t.Fetch.Key())
 		node := &TaskNode{Name: t.Name, Task: t, DefinitionHash: defHash}
 		nodesByName[t.Name] = node
 		nodes = append(nodes, node)
@@ -84,20 +93,26 @@ func NewTaskGraph(tasks []core.Task, edges []Edge) (*TaskGraph, error) {
 		fromNode, okFrom := nodesByName[e.From]
 		toNode, okTo := nodesByName[e.To]
 		if !okFrom {
-			return nil, invalidf("edge references unknown task (from): %q", e.From)
+			return nil, unknownTaskError("from", e.From, nodesByName)
 		}
 		if !okTo {
-			return nil, invalidf("edge references unknown task (to): %q", e.To)
+			return nil, unknownTaskError("to", e.To, nodesByName)
 		}
 		if fromNode.Name == toNode.Name {
 			return nil, invalidf("self-loop: %q -> %q", e.From, e.To)
 		}
+		switch e.Type {
+		case "", EdgeData, EdgeOrder:
+		default:
+			return nil, invalidf("edge %q -> %q: unknown type %q (expected %q or %q)", e.From, e.To, e.Type, EdgeData, EdgeOrder)
+		}
 
 		pair := edgeIndex{from: nameToIndex[fromNode.Name], to: nameToIndex[toNode.Name]}
 		if _, exists := seen[pair]; exists {
 			return nil, invalidf("duplicate edge: %q -> %q", e.From, e.To)
 		}
 		seen[pair] = struct{}{}
+		pair.order = e.IsOrder()
 		mapped = append(mapped, pair)
 	}
 
@@ -160,10 +175,15 @@ func (g *TaskGraph) Nodes() []*TaskNode {
 }
 
 // Edges returns the dependency edges as stable (From, To) name pairs in canonical order.
+// Data edges are returned with an empty Type, order-only edges with EdgeOrder.
 func (g *TaskGraph) Edges() []Edge {
 	out := make([]Edge, 0, len(g.edges))
 	for _, e := range g.edges {
-		out = append(out, Edge{From: g.nodes[e.from].Name, To: g.nodes[e.to].Name})
+		edge := Edge{From: g.nodes[e.from].Name, To: g.nodes[e.to].Name}
+		if e.order {
+			edge.Type = EdgeOrder
+		}
+		out = append(out, edge)
 	}
 	return out
 }
@@ -232,13 +252,17 @@ func (g *TaskGraph) computeGraphHash() GraphHash {
 		writeField([]byte(n.DefinitionHash))
 	}
 
-	// Edges (canonical order)
+	// Edges (canonical order). Only order-only edges add a type field, so
+	// graphs of data edges keep the hash they had before edges were typed.
 	writeField([]byte{byte(len(g.edges))})
 	for _, e := range g.edges {
 		writeField([]byte{byte(e.from >> 24), byte(e.from >> 16), byte(e.from >> 8), byte(e.from)})
 		writeField([]byte{byte(e.to >> 24), byte(e.to >> 16), byte(e.to >> 8), byte(e.to)})
+		if e.order {
+			writeField([]byte(EdgeOrder))
+		}
 	}
 
 	sum := h.Sum(nil)
-	return GraphHash(hex.EncodeToString(sum))
+	return GraphHash(core.SHA256.Tag(hex.EncodeToString(sum)))
 }
@@ -175,3 +175,46 @@ func TestCycleDetection_IndirectCycleRejected(t *testing.T) {
 		t.Fatalf("expected cycle error, got %v", err)
 	}
 }
+
+func TestGraphConstruction_OrderOnlyEdges(t *testing.T) {
+	tasks := []core.Task{
+		{Name: "A", Inputs: []string{"a"}, Run: "run-a"},
+		{Name: "B", Inputs: []string{"b"}, Run: "run-b"},
+	}
+	untyped, err := NewTaskGraph(tasks, []Edge{{From: "A", To: "B"}})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	data, err := NewTaskGraph(tasks, []Edge{{From: "A", To: "B", Type: EdgeData}})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	order, err := NewTaskGraph(tasks, []Edge{{From: "A", To: "B", Type: EdgeOrder}})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if data.Hash() != untyped.Hash() {
+		t.Fatalf("explicit data edge changed the hash: %s != %s", data.Hash(), untyped.Hash())
+	}
+	if order.Hash() == untyped.Hash() {
+		t.Fatalf("order-only edge must change the graph hash")
+	}
+	if got := order.TopologicalOrder(); len(got) != 2 || got[0] != "A" {
+		t.Fatalf("order-only edge must constrain scheduling: %v", got)
+	}
+	if got := order.Edges(); len(got) != 1 || !got[0].IsOrder() {
+		t.Fatalf("Edges() = %+v", got)
+	}
+	if got := data.Edges(); len(got) != 1 || got[0].Type != "" {
+		t.Fatalf("data Edges() = %+v", got)
+	}
+
+	for _, edges := range [][]Edge{
+		{{From: "A", To: "B", Type: "soft"}},
+		{{From: "A", To: "B"}, {From: "A", To: "B", Type: EdgeOrder}},
+	} {
+		if _, err := NewTaskGraph(tasks, edges); !errors.Is(err, ErrInvalidGraph) {
+			t.Fatalf("edges %+v: expected ErrInvalidGraph, got %v", edges, err)
+		}
+	}
+}
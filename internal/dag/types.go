@@ -6,6 +6,8 @@ import "scriptweaver/internal/core"
 //
 // It is computed solely from task definition content and dependency structure.
 // It MUST be stable across different insertion orders of tasks and edges.
+// It is a SHA-256 digest tagged like a core.TaskHash, "sha256:<hex digest>";
+// compare hashes that may predate the tag with core.SameHash.
 type GraphHash string
 
 // TaskDefHash is the deterministic identity of a task definition as used by the DAG model.
@@ -19,11 +21,27 @@ type TaskDefHash string
 //
 // Semantics (from spec.md): a directed edge From -> To means To can only run after
 // From completes successfully.
+//
+// Type distinguishes data edges, across which To consumes artifacts of From,
+// from order-only edges, which constrain scheduling alone: a change to From
+// invalidates To only across a data edge. The zero Type is EdgeData.
 type Edge struct {
 	From string
 	To   string
+	Type EdgeType `json:"Type,omitempty"`
 }
 
+// EdgeType is the kind of an Edge.
+type EdgeType string
+
+const (
+	EdgeData  EdgeType = "data"
+	EdgeOrder EdgeType = "order"
+)
+
+// IsOrder reports whether e is an order-only edge.
+func (e Edge) IsOrder() bool { return e.Type == EdgeOrder }
+
 // TaskNode is an immutable node in the TaskGraph.
 //
 // Name is an external identifier used for addressing edges and debugging.
@@ -0,0 +1,251 @@
+// Package dashboard serves a read-only web UI over the runs recorded in a
+// workspace.
+//
+// `sw serve` mounts Handler on a local address. The UI is a single embedded
+// page that polls a small JSON API:
+//
+//	GET /api/runs             recent runs, newest first
+//	GET /api/runs/{id}        the record, node states and failure logs of a run
+//	GET /api/runs/{id}/graph  the nodes and edges of the graph the run executed
+//	GET /api/schedules        the workspace's schedules with their next and last runs
+//	GET /api/queue            the run the daemon executes and the runs waiting for it
+//
+// Node states come from the run's timeline, or, while the run is still in
+// progress, from its checkpoints, so a running graph fills in as nodes
+// finish. Nothing is written to the workspace.
+package dashboard
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"scriptweaver/internal/cli"
+	"scriptweaver/internal/daemon"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/projectintegration/engine/config"
+	"scriptweaver/internal/recovery/state"
+)
+
+// MaxRuns is the number of most recent runs GET /api/runs lists.
+const MaxRuns = 50
+
+//go:embed assets
+var assets embed.FS
+
+// Run statuses, as derived by cli.RunStatus.
+const (
+	StatusRunning   = cli.RunStatusRunning
+	StatusSucceeded = cli.RunStatusSucceeded
+	StatusFailed    = cli.RunStatusFailed
+)
+
+// RunSummary is a run as listed by GET /api/runs.
+type RunSummary = cli.RunSummary
+
+// RunView is a run as returned by GET /api/runs/{id}.
+type RunView struct {
+	Status   string              `json:"status"`
+	Record   cli.RunRecord       `json:"record"`
+	Timeline cli.Timeline        `json:"timeline"`
+	Failures []state.NodeFailure `json:"failures"`
+}
+
+// GraphView is the graph of a run as returned by GET /api/runs/{id}/graph.
+type GraphView struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []dag.Edge  `json:"edges"`
+	// Stale is set when the graph files changed since the run, so they no
+	// longer hash to the graph it executed; the nodes and edges are those of
+	// the current files.
+	Stale bool `json:"stale"`
+}
+
+// SchedulesView is returned by GET /api/schedules. Daemon is set when a
+// daemon serves the workspace; schedules only run while one does.
+type SchedulesView struct {
+	Daemon    bool                 `json:"daemon"`
+	Schedules []cli.ScheduleStatus `json:"schedules"`
+}
+
+// QueueView is returned by GET /api/queue. Runs are only queued by a daemon;
+// without one, Daemon is unset and the queue is empty.
+type QueueView struct {
+	Daemon bool           `json:"daemon"`
+	Queue  cli.QueueState `json:"queue"`
+}
+
+// GraphNode is a node of a GraphView. Depth is its topological depth, which
+// the UI lays nodes out by.
+type GraphNode struct {
+	Name   string   `json:"name"`
+	Depth  int      `json:"depth"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+type server struct {
+	workDir string
+	st      *state.Store
+}
+
+// Handler returns the dashboard of the workspace at workDir.
+func Handler(workDir string) (http.Handler, error) {
+	st, err := state.NewStore(workDir)
+	if err != nil {
+		return nil, err
+	}
+	static, err := fs.Sub(assets, "assets")
+	if err != nil {
+		return nil, err
+	}
+	s := &server{workDir: workDir, st: st}
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServer(http.FS(static)))
+	mux.HandleFunc("GET /api/runs", s.runs)
+	mux.HandleFunc("GET /api/runs/{id}", s.run)
+	mux.HandleFunc("GET /api/runs/{id}/graph", s.graph)
+	mux.HandleFunc("GET /api/schedules", s.schedules)
+	mux.HandleFunc("GET /api/queue", s.queue)
+	return mux, nil
+}
+
+func (s *server) runs(w http.ResponseWriter, _ *http.Request) {
+	runs, err := cli.ListRuns(s.st, MaxRuns)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, runs)
+}
+
+func (s *server) run(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.runID(w, r)
+	if !ok {
+		return
+	}
+	rec, err := cli.ShowRun(s.st, id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	tl, err := cli.LoadTimeline(s.st, id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	sort.Slice(tl.Nodes, func(i, j int) bool { return tl.Nodes[i].Name < tl.Nodes[j].Name })
+	failures, err := s.st.LoadNodeFailures(id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	if failures == nil {
+		failures = []state.NodeFailure{}
+	}
+	writeJSON(w, RunView{Status: cli.RunStatus(s.st, rec), Record: rec, Timeline: tl, Failures: failures})
+}
+
+func (s *server) graph(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.runID(w, r)
+	if !ok {
+		return
+	}
+	rec, err := cli.ShowRun(s.st, id)
+	if err != nil {
+		writeError(w, statusOf(err), err)
+		return
+	}
+	if rec.Params == nil || rec.Params.Graph == "" {
+		writeError(w, http.StatusNotFound, errors.New("the run recorded no graph file"))
+		return
+	}
+	g, err := cli.LoadRunGraph(s.workDir, *rec.Params)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	view := GraphView{Nodes: []GraphNode{}, Edges: g.Edges(), Stale: g.Hash().String() != rec.Run.GraphHash}
+	for _, n := range g.Nodes() {
+		depth, _ := g.Depth(n.Task.Name)
+		view.Nodes = append(view.Nodes, GraphNode{Name: n.Task.Name, Depth: depth, Labels: n.Task.Labels})
+	}
+	writeJSON(w, view)
+}
+
+func (s *server) schedules(w http.ResponseWriter, _ *http.Request) {
+	statuses, served, err := daemon.FetchSchedules(s.workDir)
+	if !served {
+		var cfg config.Config
+		cfg, _, err = config.LoadOptional(s.workDir)
+		if err == nil {
+			statuses, err = cli.ScheduleStatuses(s.st, cfg, time.Now())
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if statuses == nil {
+		statuses = []cli.ScheduleStatus{}
+	}
+	writeJSON(w, SchedulesView{Daemon: served, Schedules: statuses})
+}
+
+func (s *server) queue(w http.ResponseWriter, _ *http.Request) {
+	q, served, err := daemon.FetchQueue(s.workDir)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if q.Queued == nil {
+		q.Queued = []cli.QueuedRun{}
+	}
+	writeJSON(w, QueueView{Daemon: served, Queue: q})
+}
+
+// runID returns the run of the request path, answering 404 for runs that are
+// not in the workspace. Only listed run IDs are used, so the ID can never
+// name a path outside the runs directory.
+func (s *server) runID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.PathValue("id")
+	ids, err := s.st.ListRunIDs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return "", false
+	}
+	if _, found := slices.BinarySearch(ids, id); !found {
+		writeError(w, http.StatusNotFound, errors.New("run "+id+" not found"))
+		return "", false
+	}
+	return id, true
+}
+
+func statusOf(err error) int {
+	if errors.Is(err, fs.ErrNotExist) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_, _ = w.Write(append(data, '\n'))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(data, '\n'))
+}
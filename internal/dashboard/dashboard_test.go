@@ -0,0 +1,105 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/cli"
+)
+
+func get(t *testing.T, h http.Handler, path string, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: %v\n%s", path, err, rec.Body.String())
+		}
+	}
+	return rec.Code
+}
+
+func TestHandler(t *testing.T) {
+	workDir := t.TempDir()
+	graphPath := filepath.Join(workDir, "graph.json")
+	graph := `{"params": {"word": {}}, "tasks": [
+		{"name": "build", "inputs": [], "run": "echo {{word}} > out.txt", "outputs": ["out.txt"]},
+		{"name": "test", "inputs": ["out.txt"], "run": "echo broken >&2; exit 3"}
+	], "edges": [{"From": "build", "To": "test"}]}`
+	if err := os.WriteFile(graphPath, []byte(graph), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := cli.Execute(context.Background(), cli.CLIInvocation{
+		WorkDir:       workDir,
+		GraphPath:     graphPath,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: cli.ExecutionModeIncremental,
+		Params:        map[string]string{"word": "hello"},
+	})
+	if res.ExitCode == cli.ExitSuccess {
+		t.Fatalf("run succeeded, want a failed run: %v", err)
+	}
+
+	h, err := Handler(workDir)
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+	var runs []RunSummary
+	if code := get(t, h, "/api/runs", &runs); code != http.StatusOK || len(runs) != 1 {
+		t.Fatalf("GET /api/runs: %d %+v", code, runs)
+	}
+	if runs[0].Status != StatusFailed || runs[0].Graph != "graph.json" {
+		t.Fatalf("run = %+v", runs[0])
+	}
+	id := runs[0].RunID
+
+	var view RunView
+	if code := get(t, h, "/api/runs/"+id, &view); code != http.StatusOK {
+		t.Fatalf("GET run: %d", code)
+	}
+	if len(view.Failures) != 1 || view.Failures[0].NodeID != "test" || !strings.Contains(view.Failures[0].StderrTail, "broken") {
+		t.Fatalf("failures = %+v", view.Failures)
+	}
+
+	var g GraphView
+	if code := get(t, h, "/api/runs/"+id+"/graph", &g); code != http.StatusOK {
+		t.Fatalf("GET graph: %d", code)
+	}
+	if g.Stale || len(g.Nodes) != 2 || g.Nodes[1].Name != "test" || g.Nodes[1].Depth != 1 || len(g.Edges) != 1 {
+		t.Fatalf("graph = %+v", g)
+	}
+
+	// Editing the graph marks it stale.
+	if err := os.WriteFile(graphPath, []byte(strings.Replace(graph, "exit 3", "exit 0", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := get(t, h, "/api/runs/"+id+"/graph", &g); code != http.StatusOK || !g.Stale {
+		t.Fatalf("edited graph: %d stale=%v", code, g.Stale)
+	}
+
+	for _, path := range []string{"/api/runs/nope", "/api/runs/..%2f..%2fetc", "/api/runs/nope/graph"} {
+		if code := get(t, h, path, nil); code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, code)
+		}
+	}
+	var schedules SchedulesView
+	if code := get(t, h, "/api/schedules", &schedules); code != http.StatusOK || schedules.Daemon || len(schedules.Schedules) != 0 {
+		t.Fatalf("GET /api/schedules: %d %+v", code, schedules)
+	}
+	var queue QueueView
+	if code := get(t, h, "/api/queue", &queue); code != http.StatusOK || queue.Daemon || queue.Queue.Active != nil || len(queue.Queue.Queued) != 0 {
+		t.Fatalf("GET /api/queue: %d %+v", code, queue)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/api/runs") {
+		t.Fatalf("GET / = %d", rec.Code)
+	}
+}
@@ -0,0 +1,220 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Builder constructs a Document programmatically.
+//
+// Structural invariants are enforced as each call is made rather than only at
+// Build time:
+//   - AddNode rejects empty and duplicate node IDs
+//   - AddEdge rejects self-references, unknown endpoints, duplicates and any
+//     edge that would close a cycle
+//   - SetEnv rejects unknown nodes and invalid variable names
+//
+// Builder methods return the receiver so calls can be chained. The first
+// error is sticky: once a call fails, subsequent calls are no-ops and the
+// error is reported by Err and Build.
+type Builder struct {
+	nodes    []Node
+	index    map[string]int
+	edges    map[Edge]struct{}
+	outgoing map[string][]string
+	metadata Metadata
+	err      error
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{
+		index:    make(map[string]int),
+		edges:    make(map[Edge]struct{}),
+		outgoing: make(map[string][]string),
+	}
+}
+
+// Err returns the first error recorded by the builder, if any.
+func (b *Builder) Err() error {
+	return b.err
+}
+
+// AddNode adds a node with the given ID, type, inputs and outputs.
+//
+// Inputs are shallow-copied; a nil map is stored as an empty map so the
+// resulting document satisfies the schema's required-field rules.
+func (b *Builder) AddNode(id, nodeType string, inputs map[string]any, outputs ...string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if id == "" {
+		b.err = &SchemaError{Field: fmt.Sprintf("graph.nodes[%d].id", len(b.nodes)), Msg: "required field is missing"}
+		return b
+	}
+	if nodeType == "" {
+		b.err = &SchemaError{Field: fmt.Sprintf("graph.nodes[%d].type", len(b.nodes)), Msg: "required field is missing"}
+		return b
+	}
+	if _, exists := b.index[id]; exists {
+		b.err = &StructuralError{Kind: "duplicate_id", Msg: fmt.Sprintf("duplicate node ID: %q", id)}
+		return b
+	}
+
+	in := make(map[string]any, len(inputs))
+	for k, v := range inputs {
+		in[k] = v
+	}
+	out := make([]string, len(outputs))
+	copy(out, outputs)
+
+	b.index[id] = len(b.nodes)
+	b.nodes = append(b.nodes, Node{ID: id, Type: nodeType, Inputs: in, Outputs: out})
+	return b
+}
+
+// AddEdge adds a dependency edge from -> to (to depends on from).
+func (b *Builder) AddEdge(from, to string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if from == to {
+		b.err = &StructuralError{Kind: "self_reference", Msg: fmt.Sprintf("self-referential edge: %q -> %q", from, to)}
+		return b
+	}
+	if _, ok := b.index[from]; !ok {
+		b.err = &StructuralError{Kind: "dangling_edge", Msg: fmt.Sprintf("edge references unknown node: %q", from), Hints: DidYouMean(from, b.nodeIDs())}
+		return b
+	}
+	if _, ok := b.index[to]; !ok {
+		b.err = &StructuralError{Kind: "dangling_edge", Msg: fmt.Sprintf("edge references unknown node: %q", to), Hints: DidYouMean(to, b.nodeIDs())}
+		return b
+	}
+	e := Edge{From: from, To: to}
+	if _, exists := b.edges[e]; exists {
+		b.err = &StructuralError{Kind: "duplicate_edge", Msg: fmt.Sprintf("duplicate edge: %q -> %q", from, to)}
+		return b
+	}
+	// The new edge closes a cycle iff from is already reachable from to.
+	if path := b.pathBetween(to, from); path != nil {
+		cyclePath := append([]string{from}, path...)
+		b.err = &StructuralError{Kind: "cycle", Msg: fmt.Sprintf("cycle detected: %v", cyclePath)}
+		return b
+	}
+
+	b.edges[e] = struct{}{}
+	b.outgoing[from] = append(b.outgoing[from], to)
+	sort.Strings(b.outgoing[from])
+	return b
+}
+
+// SetEnv sets a single environment variable on an existing node, in its env
+// field, as a graph document sets it.
+func (b *Builder) SetEnv(nodeID, key, value string) *Builder {
+	if b.err != nil {
+		return b
+	}
+	i, ok := b.index[nodeID]
+	if !ok {
+		b.err = &StructuralError{Kind: "unknown_node", Msg: fmt.Sprintf("env set on unknown node: %q", nodeID)}
+		return b
+	}
+	if key == "" || strings.Contains(key, "=") {
+		b.err = &SchemaError{Field: fmt.Sprintf("graph.nodes[%d].env", i), Msg: fmt.Sprintf("invalid variable name %q", key)}
+		return b
+	}
+
+	n := &b.nodes[i]
+	if n.Env == nil {
+		n.Env = make(map[string]string)
+	}
+	n.Env[key] = value
+	return b
+}
+
+// SetMetadata replaces the document metadata. Metadata does not affect the
+// graph hash.
+func (b *Builder) SetMetadata(m Metadata) *Builder {
+	if b.err != nil {
+		return b
+	}
+	labels := make([]string, len(m.Labels))
+	copy(labels, m.Labels)
+	if len(labels) == 0 {
+		labels = nil
+	}
+	b.metadata = Metadata{Name: m.Name, Description: m.Description, Labels: labels}
+	return b
+}
+
+// Build returns the canonical Document described by the builder.
+//
+// The returned graph is normalized (see Graph.Normalize) and carries
+// SupportedSchemaVersion. As a final guard the result is run through
+// Validate, so a successfully built document always passes structural
+// validation.
+func (b *Builder) Build() (*Document, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	edges := make([]Edge, 0, len(b.edges))
+	for e := range b.edges {
+		edges = append(edges, e)
+	}
+	g := &Graph{Nodes: b.nodes, Edges: edges}
+	// Normalized copies env maps, so later SetEnv calls cannot mutate a
+	// document that has already been built.
+	normalized := g.Normalized()
+
+	if err := Validate(normalized); err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		SchemaVersion: SupportedSchemaVersion,
+		Graph:         *normalized,
+		Metadata:      b.metadata,
+	}, nil
+}
+
+// pathBetween returns a deterministic path from start to target (inclusive)
+// following existing edges, or nil if target is unreachable.
+func (b *Builder) pathBetween(start, target string) []string {
+	visited := make(map[string]bool)
+	var path []string
+
+	var dfs func(n string) bool
+	dfs = func(n string) bool {
+		visited[n] = true
+		path = append(path, n)
+		if n == target {
+			return true
+		}
+		for _, next := range b.outgoing[n] {
+			if visited[next] {
+				continue
+			}
+			if dfs(next) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	if dfs(start) {
+		return path
+	}
+	return nil
+}
+
+// nodeIDs returns the IDs of the nodes added so far.
+func (b *Builder) nodeIDs() []string {
+	ids := make([]string, len(b.nodes))
+	for i, n := range b.nodes {
+		ids[i] = n.ID
+	}
+	return ids
+}
@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuilder_BuildsCanonicalDocument(t *testing.T) {
+	doc, err := NewBuilder().
+		AddNode("c", "shell", map[string]any{"cmd": "c"}, "z.txt", "a.txt").
+		AddNode("a", "shell", map[string]any{"cmd": "a"}).
+		AddNode("b", "shell", nil).
+		AddEdge("b", "c").
+		AddEdge("a", "b").
+		SetEnv("a", "LANG", "C").
+		SetMetadata(Metadata{Name: "built"}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if doc.SchemaVersion != SupportedSchemaVersion {
+		t.Fatalf("schema_version = %q", doc.SchemaVersion)
+	}
+	if got := []string{doc.Graph.Nodes[0].ID, doc.Graph.Nodes[1].ID, doc.Graph.Nodes[2].ID}; got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Fatalf("nodes not sorted: %v", got)
+	}
+	if doc.Graph.Edges[0] != (Edge{From: "a", To: "b"}) || doc.Graph.Edges[1] != (Edge{From: "b", To: "c"}) {
+		t.Fatalf("edges not sorted: %v", doc.Graph.Edges)
+	}
+	if out := doc.Graph.Nodes[2].Outputs; out[0] != "a.txt" || out[1] != "z.txt" {
+		t.Fatalf("outputs not sorted: %v", out)
+	}
+	if doc.Graph.Nodes[1].Inputs == nil {
+		t.Fatalf("nil inputs must be stored as an empty map")
+	}
+	if env := doc.Graph.Nodes[0].Env; env["LANG"] != "C" {
+		t.Fatalf("env not recorded: %#v", doc.Graph.Nodes[0])
+	}
+	if _, ok := doc.Graph.Nodes[0].Inputs["env"]; ok {
+		t.Fatalf("env recorded in inputs: %#v", doc.Graph.Nodes[0].Inputs)
+	}
+}
+
+func TestBuilder_OutputRoundTripsThroughParse(t *testing.T) {
+	doc, err := NewBuilder().
+		AddNode("a", "shell", map[string]any{"cmd": "a"}).
+		AddNode("b", "shell", map[string]any{"cmd": "b"}).
+		AddEdge("a", "b").
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	parsed, err := Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	h1, _ := ComputeHash(&doc.Graph)
+	h2, _ := ComputeHash(&parsed.Graph)
+	if h1 != h2 {
+		t.Fatalf("hash mismatch after round trip: %s != %s", h1, h2)
+	}
+}
+
+func TestBuilder_EnvHashesLikeTheDocumentField(t *testing.T) {
+	doc, err := NewBuilder().
+		AddNode("a", "exec", map[string]any{"cmd": "make"}).
+		SetEnv("a", "CI", "1").
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	written, err := Parse(strings.NewReader(`{"schema_version": "2.0.0", "graph": {"nodes": [
+		{"id": "a", "type": "exec", "inputs": {"cmd": "make"}, "outputs": [], "env": {"CI": "1"}}
+	], "edges": []}}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	h1, _ := ComputeHash(&doc.Graph)
+	h2, _ := ComputeHash(&written.Graph)
+	if h1 != h2 {
+		t.Fatalf("built graph hashes %s, written graph %s", h1, h2)
+	}
+
+	for _, key := range []string{"", "A=B"} {
+		b := NewBuilder().AddNode("a", "t", nil).SetEnv("a", key, "x")
+		var se *SchemaError
+		if !errors.As(b.Err(), &se) || se.Field != "graph.nodes[0].env" {
+			t.Fatalf("SetEnv(%q): expected schema error on graph.nodes[0].env, got %v", key, b.Err())
+		}
+	}
+}
+
+func TestBuilder_InsertionOrderDoesNotAffectHash(t *testing.T) {
+	d1, err := NewBuilder().
+		AddNode("a", "t", nil).AddNode("b", "t", nil).AddNode("c", "t", nil).
+		AddEdge("a", "b").AddEdge("a", "c").
+		Build()
+	if err != nil {
+		t.Fatalf("Build 1: %v", err)
+	}
+	d2, err := NewBuilder().
+		AddNode("c", "t", nil).AddNode("b", "t", nil).AddNode("a", "t", nil).
+		AddEdge("a", "c").AddEdge("a", "b").
+		Build()
+	if err != nil {
+		t.Fatalf("Build 2: %v", err)
+	}
+	h1, _ := ComputeHash(&d1.Graph)
+	h2, _ := ComputeHash(&d2.Graph)
+	if h1 != h2 {
+		t.Fatalf("expected identical hashes, got %s != %s", h1, h2)
+	}
+}
+
+func TestBuilder_RejectsStructuralViolationsIncrementally(t *testing.T) {
+	cases := []struct {
+		name string
+		b    *Builder
+		kind string
+	}{
+		{"duplicate_id", NewBuilder().AddNode("a", "t", nil).AddNode("a", "t", nil), "duplicate_id"},
+		{"self_reference", NewBuilder().AddNode("a", "t", nil).AddEdge("a", "a"), "self_reference"},
+		{"dangling_from", NewBuilder().AddNode("a", "t", nil).AddEdge("x", "a"), "dangling_edge"},
+		{"dangling_to", NewBuilder().AddNode("a", "t", nil).AddEdge("a", "x"), "dangling_edge"},
+		{"duplicate_edge", NewBuilder().AddNode("a", "t", nil).AddNode("b", "t", nil).AddEdge("a", "b").AddEdge("a", "b"), "duplicate_edge"},
+		{"cycle", NewBuilder().AddNode("a", "t", nil).AddNode("b", "t", nil).AddNode("c", "t", nil).AddEdge("a", "b").AddEdge("b", "c").AddEdge("c", "a"), "cycle"},
+		{"env_unknown_node", NewBuilder().SetEnv("a", "K", "V"), "unknown_node"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.b.Err()
+			if err == nil {
+				t.Fatalf("expected error before Build")
+			}
+			if !errors.Is(err, ErrStructural) {
+				t.Fatalf("expected structural error, got %T: %v", err, err)
+			}
+			var se *StructuralError
+			if !errors.As(err, &se) || se.Kind != tc.kind {
+				t.Fatalf("expected kind %q, got %v", tc.kind, err)
+			}
+			if _, berr := tc.b.Build(); berr != err {
+				t.Fatalf("Build must return the sticky error, got %v", berr)
+			}
+		})
+	}
+}
+
+func TestBuilder_FirstErrorIsSticky(t *testing.T) {
+	b := NewBuilder().AddNode("", "t", nil).AddNode("a", "t", nil).AddNode("a", "t", nil)
+	if !errors.Is(b.Err(), ErrSchema) {
+		t.Fatalf("expected first (schema) error to be kept, got %v", b.Err())
+	}
+}
+
+func TestBuilder_BuiltDocumentIsDetachedFromBuilder(t *testing.T) {
+	b := NewBuilder().AddNode("a", "t", nil).SetEnv("a", "K", "1")
+	doc, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	b.SetEnv("a", "K", "2")
+	if env := doc.Graph.Nodes[0].Env; env["K"] != "1" {
+		t.Fatalf("built document mutated by later SetEnv: %v", env)
+	}
+}
@@ -0,0 +1,226 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DefinitionsKey is the top-level document key holding reusable node
+// fragments, and ExtendsKey the node key referencing them:
+//
+//	"definitions": {"go": {"type": "exec", "inputs": {"env": {"GOFLAGS": "-mod=vendor"}}}},
+//	"graph": {"nodes": [{"id": "build", "extends": "go", "inputs": {"cmd": "go build ./..."}, "outputs": []}], ...}
+//
+// A fragment holds any node fields but id and extends. A node extends one
+// fragment or, given a list, several, applied in order; its own fields come
+// last. Each later field replaces an earlier one, except that inputs and env
+// are merged key by key. Parse merges the fragments into the nodes before
+// validating the document, so a document hashes exactly like the same
+// document written out without definitions.
+const (
+	DefinitionsKey = "definitions"
+	ExtendsKey     = "extends"
+)
+
+// sourceDocument is the shape of a document as written, with its
+// definitions unmerged; it is only used to locate errors in the source.
+type sourceDocument struct {
+	SchemaVersion string                `json:"schema_version"`
+	Graph         sourceGraph           `json:"graph"`
+	Metadata      Metadata              `json:"metadata"`
+	Definitions   map[string]sourceNode `json:"definitions"`
+}
+
+type sourceGraph struct {
+	Nodes []sourceNode `json:"nodes"`
+	Edges []Edge       `json:"edges"`
+}
+
+type sourceNode struct {
+	Node
+	Extends any `json:"extends"`
+}
+
+var sourceDocumentType = reflect.TypeOf(sourceDocument{})
+
+// fragmentLayout says where the items extending fragments are in a file and
+// how fragments merge into them.
+type fragmentLayout struct {
+	// list holds the keys of the object holding the items, from the root,
+	// and the key of the list itself.
+	list []string
+	// id is the item field a fragment cannot set.
+	id string
+	// merged are the item fields whose objects are merged key by key.
+	merged map[string]bool
+}
+
+// documentLayout is the layout of graph documents.
+var documentLayout = fragmentLayout{list: []string{"graph", "nodes"}, id: "id", merged: map[string]bool{"inputs": true, "env": true}}
+
+// taskLayout is the layout of the graph files of sw run.
+var taskLayout = fragmentLayout{list: []string{"tasks"}, id: "name", merged: map[string]bool{"env": true}}
+
+// expandDefinitions returns data with the fragments of its definitions
+// merged into the nodes extending them, and the definitions removed. data is
+// returned unchanged when it declares no definitions, or is not an object
+// whose graph nodes are objects; decoding it then reports the problem.
+func expandDefinitions(data []byte) ([]byte, error) {
+	return documentLayout.expand(data)
+}
+
+// ExpandTaskDefinitions returns data, a graph file of sw run, with the
+// fragments of its definitions merged into the tasks extending them, and the
+// definitions removed, as Parse merges those of a graph document into its
+// nodes. A fragment holds any task fields but name and extends; only env is
+// merged key by key. data is returned unchanged when it declares no
+// definitions, or is not an object whose tasks are objects. Errors are
+// located in data.
+func ExpandTaskDefinitions(data []byte) ([]byte, error) {
+	out, err := taskLayout.expand(data)
+	if err != nil {
+		return nil, locateIn(indexSource(data, nil), err)
+	}
+	return out, nil
+}
+
+// LocateExpanded sets the location of err, a ParseError or SchemaError
+// returned for expanded, the expansion of data by ExpandTaskDefinitions, in
+// data. A field a task takes from a fragment is located at the task.
+func LocateExpanded(data, expanded []byte, err error) error {
+	if bytes.Equal(data, expanded) {
+		return err
+	}
+	idx := indexSource(data, nil)
+	var pe *ParseError
+	var se *SchemaError
+	switch {
+	case errors.As(err, &pe) && pe.Path != "":
+		pe.Pos = idx.locate(pe.Path)
+	case errors.As(err, &se):
+		se.Pos = idx.locate(se.Field)
+	}
+	return err
+}
+
+// expand returns data with the fragments of its definitions merged into the
+// items of l extending them, and the definitions removed.
+func (l fragmentLayout) expand(data []byte) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if json.Unmarshal(data, &doc) != nil {
+		return data, nil
+	}
+	rawDefs, ok := doc[DefinitionsKey]
+	if !ok {
+		return data, nil
+	}
+	var defs map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(rawDefs, &defs); err != nil {
+		return nil, &SchemaError{Field: DefinitionsKey, Msg: "must be an object of fragments"}
+	}
+	names := make([]string, 0, len(defs))
+	for name, frag := range defs {
+		names = append(names, name)
+		for _, key := range []string{l.id, ExtendsKey} {
+			if _, ok := frag[key]; ok {
+				return nil, &SchemaError{Field: DefinitionsKey + "." + name + "." + key, Msg: "not allowed in a definition"}
+			}
+		}
+	}
+	sort.Strings(names)
+	delete(doc, DefinitionsKey)
+
+	// parents are the objects holding the list, from doc down.
+	parents := []map[string]json.RawMessage{doc}
+	for _, key := range l.list[:len(l.list)-1] {
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(parents[len(parents)-1][key], &obj) != nil {
+			return data, nil
+		}
+		parents = append(parents, obj)
+	}
+	listKey := l.list[len(l.list)-1]
+	prefix := strings.Join(l.list, ".")
+	var items []map[string]json.RawMessage
+	if json.Unmarshal(parents[len(parents)-1][listKey], &items) != nil {
+		return data, nil
+	}
+	for i, item := range items {
+		rawExtends, ok := item[ExtendsKey]
+		if !ok {
+			continue
+		}
+		field := fmt.Sprintf("%s[%d].%s", prefix, i, ExtendsKey)
+		var refs []string
+		var one string
+		if json.Unmarshal(rawExtends, &one) == nil {
+			refs = []string{one}
+		} else if json.Unmarshal(rawExtends, &refs) != nil {
+			return nil, &SchemaError{Field: field, Msg: "must be a definition name or a list of them"}
+		}
+		delete(item, ExtendsKey)
+		merged := make(map[string]json.RawMessage)
+		for _, ref := range refs {
+			frag, ok := defs[ref]
+			if !ok {
+				return nil, &SchemaError{Field: field, Msg: fmt.Sprintf("unknown definition %q", ref), Hints: DidYouMean(ref, names)}
+			}
+			if key, ok := l.mergeFields(merged, frag); !ok {
+				return nil, &SchemaError{Field: fmt.Sprintf("%s.%s.%s", DefinitionsKey, ref, key), Msg: "must be an object"}
+			}
+		}
+		if key, ok := l.mergeFields(merged, item); !ok {
+			return nil, &SchemaError{Field: fmt.Sprintf("%s[%d].%s", prefix, i, key), Msg: "must be an object"}
+		}
+		items[i] = merged
+	}
+
+	var err error
+	if parents[len(parents)-1][listKey], err = json.Marshal(items); err != nil {
+		return nil, &ParseError{Msg: err.Error(), Err: err}
+	}
+	for i := len(parents) - 1; i > 0; i-- {
+		if parents[i-1][l.list[i-1]], err = json.Marshal(parents[i]); err != nil {
+			return nil, &ParseError{Msg: err.Error(), Err: err}
+		}
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, &ParseError{Msg: err.Error(), Err: err}
+	}
+	return out, nil
+}
+
+// mergeFields applies the item fields of src to dst. It returns the key of a
+// merged field that cannot be merged because it is not an object on either
+// side, and false.
+func (l fragmentLayout) mergeFields(dst, src map[string]json.RawMessage) (string, bool) {
+	for key, v := range src {
+		prev, ok := dst[key]
+		if !ok || !l.merged[key] || bytes.Equal(bytes.TrimSpace(v), []byte("null")) {
+			dst[key] = v
+			continue
+		}
+		var a, b map[string]json.RawMessage
+		if json.Unmarshal(prev, &a) != nil || json.Unmarshal(v, &b) != nil {
+			return key, false
+		}
+		if a == nil {
+			a = make(map[string]json.RawMessage, len(b))
+		}
+		for k, bv := range b {
+			a[k] = bv
+		}
+		m, err := json.Marshal(a)
+		if err != nil {
+			return key, false
+		}
+		dst[key] = m
+	}
+	return "", true
+}
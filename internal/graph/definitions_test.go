@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse_DefinitionsMergeIntoNodes(t *testing.T) {
+	withDefs := `{
+		"schema_version": "2.0.0",
+		"definitions": {
+			"go": {"type": "exec", "inputs": {"cmd": "go build", "env": {"GOOS": "linux"}}, "outputs": ["bin"], "retries": 2},
+			"ci": {"env": {"CI": "1"}, "timeout_seconds": 60}
+		},
+		"graph": {
+			"nodes": [
+				{"id": "build", "extends": "go", "inputs": {"cmd": "go build ./..."}, "outputs": ["bin/app"]},
+				{"id": "test", "extends": ["go", "ci"], "inputs": {"cmd": "go test ./..."}, "env": {"CI": "true"}, "retries": 0}
+			],
+			"edges": [{"from": "build", "to": "test"}]
+		},
+		"metadata": {}
+	}`
+	written := `{
+		"schema_version": "2.0.0",
+		"graph": {
+			"nodes": [
+				{"id": "build", "type": "exec", "inputs": {"cmd": "go build ./...", "env": {"GOOS": "linux"}}, "outputs": ["bin/app"], "retries": 2},
+				{"id": "test", "type": "exec", "inputs": {"cmd": "go test ./...", "env": {"GOOS": "linux"}}, "outputs": ["bin"], "env": {"CI": "true"}, "timeout_seconds": 60}
+			],
+			"edges": [{"from": "build", "to": "test"}]
+		},
+		"metadata": {}
+	}`
+	got, err := Parse(strings.NewReader(withDefs))
+	if err != nil {
+		t.Fatalf("Parse with definitions: %v", err)
+	}
+	want, err := Parse(strings.NewReader(written))
+	if err != nil {
+		t.Fatalf("Parse written out: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("merged document = %+v\nwant %+v", got.Graph.Nodes, want.Graph.Nodes)
+	}
+	gh, _ := ComputeHash(&got.Graph)
+	wh, _ := ComputeHash(&want.Graph)
+	if gh != wh {
+		t.Fatalf("hash = %s, want %s", gh, wh)
+	}
+}
+
+func TestParse_DefinitionErrors(t *testing.T) {
+	doc := func(defs, node string) string {
+		return `{"schema_version": "2.0.0", "definitions": ` + defs + `, "graph": {"nodes": [` + node + `], "edges": []}, "metadata": {}}`
+	}
+	tests := []struct {
+		name, defs, node, field string
+	}{
+		{"unknown definition", `{"go": {"type": "exec"}}`, `{"id": "a", "extends": "og", "inputs": {"cmd": "x"}, "outputs": []}`, "graph.nodes[0].extends"},
+		{"id in definition", `{"go": {"id": "x"}}`, `{"id": "a", "type": "t", "inputs": {}, "outputs": []}`, "definitions.go.id"},
+		{"inputs not an object", `{"go": {"inputs": []}}`, `{"id": "a", "type": "t", "extends": "go", "inputs": {}, "outputs": []}`, "graph.nodes[0].inputs"},
+		{"bad extends", `{"go": {}}`, `{"id": "a", "type": "t", "extends": 3, "inputs": {}, "outputs": []}`, "graph.nodes[0].extends"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(doc(tt.defs, tt.node)))
+			var se *SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected SchemaError, got %T: %v", err, err)
+			}
+			if se.Field != tt.field || se.Pos.Line == 0 {
+				t.Errorf("field = %q at %+v, want %q", se.Field, se.Pos, tt.field)
+			}
+		})
+	}
+
+	_, err := Parse(strings.NewReader(doc(`{"go": {"type": "t", "bogus": 1}}`, `{"id": "a", "extends": "go", "inputs": {}, "outputs": []}`)))
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Path != "definitions.go.bogus" {
+		t.Fatalf("unknown field in a definition: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Deprecation marks a field of a graph file that is slated for removal. Until
+// the schema version that removes it, Parse still accepts the field, and
+// ParseWithWarnings and DeprecatedFields report each use, so that users can
+// migrate their graph files before the field becomes an unknown field.
+type Deprecation struct {
+	// Field is the path of the field, with "[]" standing for any index, such
+	// as "graph.nodes[].retries" in graph documents or "tasks[].retries" in
+	// the graph files of sw run. Fields of definitions are matched as the
+	// node fields they become.
+	Field string
+	// RemovedIn is the schema_version that no longer accepts the field.
+	RemovedIn string
+	// Use names what to use instead; empty when nothing replaces the field.
+	Use string
+}
+
+// deprecations is the registry of deprecated fields, by field. A field of
+// graph documents is added here while the document types still accept it, at
+// the latest one schema version before it is removed from them.
+var deprecations = struct {
+	sync.RWMutex
+	m map[string]Deprecation
+}{m: map[string]Deprecation{
+	// Builder.SetEnv wrote env into the inputs before nodes had an env field.
+	"graph.nodes[].inputs.env": {Field: "graph.nodes[].inputs.env", RemovedIn: "3.0.0", Use: "graph.nodes[].env"},
+}}
+
+// RegisterDeprecation deprecates a field, replacing the deprecation
+// registered before for the same field, so that runner plugins and embedders
+// can deprecate the inputs and fields they define.
+func RegisterDeprecation(d Deprecation) error {
+	if d.Field == "" {
+		return fmt.Errorf("register deprecation: empty field")
+	}
+	if d.RemovedIn == "" {
+		return fmt.Errorf("register deprecation %q: empty removed_in version", d.Field)
+	}
+	deprecations.Lock()
+	deprecations.m[d.Field] = d
+	deprecations.Unlock()
+	return nil
+}
+
+// Deprecations returns the registry of deprecated fields, ordered by field.
+func Deprecations() []Deprecation {
+	deprecations.RLock()
+	out := make([]Deprecation, 0, len(deprecations.m))
+	for _, d := range deprecations.m {
+		out = append(out, d)
+	}
+	deprecations.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+// deprecation returns the deprecation of field, if any.
+func deprecation(field string) (Deprecation, bool) {
+	deprecations.RLock()
+	defer deprecations.RUnlock()
+	d, ok := deprecations.m[field]
+	return d, ok
+}
+
+// DeprecationWarning reports a use of a deprecated field.
+type DeprecationWarning struct {
+	Deprecation
+	// Path is the field as used, such as "graph.nodes[3].retries".
+	Path string
+	Pos  Position
+}
+
+func (w DeprecationWarning) String() string {
+	msg := fmt.Sprintf("%s is deprecated and will be removed in schema_version %s", w.Path, w.RemovedIn)
+	if w.Use != "" {
+		msg += "; use " + w.Use + " instead"
+	}
+	if pos := w.Pos.String(); pos != "" {
+		msg += " (" + pos + ")"
+	}
+	return msg
+}
+
+// ParseWithWarnings is Parse, also returning a warning for every use of a
+// deprecated field (see Deprecation), in document order.
+func ParseWithWarnings(r io.Reader) (*Document, []DeprecationWarning, error) {
+	return ParseWithNodeTypes(r, nil)
+}
+
+// ParseWithNodeTypes is ParseWithWarnings, also checking the inputs of nodes
+// of the types registered with types against their schemas.
+func ParseWithNodeTypes(r io.Reader, types *NodeTypes) (*Document, []DeprecationWarning, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, &ParseError{Msg: err.Error(), Err: err}
+	}
+	doc, err := parse(data, types)
+	if err != nil {
+		return nil, nil, locateError(data, err)
+	}
+	return doc, DeprecatedFields(data), nil
+}
+
+// DeprecatedFields returns a warning for every use of a deprecated field in
+// data, a graph document or a graph file of sw run, in document order. A
+// malformed file is checked up to its first syntax error.
+func DeprecatedFields(data []byte) []DeprecationWarning {
+	return matchDeprecations(indexSource(data, nil), func(path string) string { return path })
+}
+
+var indexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// matchDeprecations returns the uses of deprecated fields among the values
+// idx spans, in source order. used maps the path of a value in the source to
+// the path of the field it sets.
+func matchDeprecations(idx *sourceIndex, used func(path string) string) []DeprecationWarning {
+	deprecations.RLock()
+	defer deprecations.RUnlock()
+	if len(deprecations.m) == 0 {
+		return nil
+	}
+	var out []DeprecationWarning
+	for path, s := range idx.spans {
+		path = used(path)
+		field := indexPattern.ReplaceAllString(path, "[]")
+		if rest, ok := strings.CutPrefix(field, DefinitionsKey+"."); ok {
+			// definitions.<name>.<field> becomes graph.nodes[].<field>.
+			if _, f, ok := strings.Cut(rest, "."); ok {
+				field = "graph.nodes[]." + f
+			}
+		}
+		if d, ok := deprecations.m[field]; ok {
+			out = append(out, DeprecationWarning{Deprecation: d, Path: path, Pos: idx.position(s.start)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Pos.Offset < out[j].Pos.Offset })
+	return out
+}
+
+// markDeprecated annotates the properties of schema, a JSONSchema, that the
+// registry deprecates.
+func markDeprecated(schema map[string]any) {
+	for _, d := range Deprecations() {
+		s := schema
+		for _, seg := range strings.Split(d.Field, ".") {
+			name, array := strings.CutSuffix(seg, "[]")
+			props, _ := s["properties"].(map[string]any)
+			next, _ := props[name].(map[string]any)
+			if next != nil && array {
+				next, _ = next["items"].(map[string]any)
+			}
+			if next == nil {
+				s = nil
+				break
+			}
+			s = next
+		}
+		if s != nil {
+			annotateDeprecated(s, d.Field)
+		}
+	}
+}
+
+// annotateDeprecated marks s, the JSONSchema of field, deprecated if the
+// registry deprecates field.
+func annotateDeprecated(s map[string]any, field string) {
+	d, ok := deprecation(field)
+	if !ok {
+		return
+	}
+	s["deprecated"] = true
+	desc := "Deprecated: removed in schema_version " + d.RemovedIn + "."
+	if d.Use != "" {
+		desc += " Use " + d.Use + " instead."
+	}
+	s["description"] = desc
+}
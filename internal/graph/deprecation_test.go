@@ -0,0 +1,160 @@
+package graph
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// withDeprecations replaces the registry for the duration of a test.
+func withDeprecations(t *testing.T, ds ...Deprecation) {
+	t.Helper()
+	m := make(map[string]Deprecation, len(ds))
+	for _, d := range ds {
+		m[d.Field] = d
+	}
+	deprecations.Lock()
+	saved := deprecations.m
+	deprecations.m = m
+	deprecations.Unlock()
+	t.Cleanup(func() {
+		deprecations.Lock()
+		deprecations.m = saved
+		deprecations.Unlock()
+	})
+}
+
+func TestParseWithWarnings_ReportsDeprecatedFields(t *testing.T) {
+	withDeprecations(t,
+		Deprecation{Field: "graph.nodes[].retries", RemovedIn: "3.0.0", Use: "a retry policy"},
+		Deprecation{Field: "metadata.description", RemovedIn: "3.0.0"},
+	)
+	src := `{
+  "schema_version": "2.0.0",
+  "definitions": {"flaky": {"retries": 2}},
+  "graph": {
+    "nodes": [
+      {"id": "a", "type": "exec", "inputs": {"cmd": "true"}, "outputs": []},
+      {"id": "b", "extends": "flaky", "type": "exec", "inputs": {"cmd": "true"}, "outputs": [], "retries": 1}
+    ],
+    "edges": []
+  },
+  "metadata": {"description": "demo"}
+}`
+	doc, warnings, err := ParseWithWarnings(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("ParseWithWarnings: %v", err)
+	}
+	if doc.Graph.Nodes[1].Retries != 1 {
+		t.Fatalf("deprecated field not decoded: retries = %d", doc.Graph.Nodes[1].Retries)
+	}
+	var got []string
+	for _, w := range warnings {
+		got = append(got, w.String())
+	}
+	want := []string{
+		"definitions.flaky.retries is deprecated and will be removed in schema_version 3.0.0; use a retry policy instead (line 3, column 40)",
+		"graph.nodes[1].retries is deprecated and will be removed in schema_version 3.0.0; use a retry policy instead (line 7, column 108)",
+		"metadata.description is deprecated and will be removed in schema_version 3.0.0 (line 11, column 31)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := Parse(strings.NewReader(src)); err != nil {
+		t.Fatalf("Parse rejected deprecated fields: %v", err)
+	}
+}
+
+func TestParseWithWarnings_NoneWithoutDeprecatedFields(t *testing.T) {
+	withDeprecations(t, Deprecation{Field: "graph.nodes[].retries", RemovedIn: "3.0.0"})
+	src := `{"schema_version": "2.0.0", "graph": {"nodes": [{"id": "a", "type": "exec", "inputs": {"cmd": "true"}, "outputs": []}], "edges": []}, "metadata": {}}`
+	_, warnings, err := ParseWithWarnings(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("ParseWithWarnings: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("warnings = %v, want none", warnings)
+	}
+}
+
+func TestJSONSchema_MarksDeprecatedFields(t *testing.T) {
+	withDeprecations(t, Deprecation{Field: "graph.nodes[].retries", RemovedIn: "3.0.0", Use: "a retry policy"})
+	props := JSONSchema(nil)["properties"].(map[string]any)
+	node := props["graph"].(map[string]any)["properties"].(map[string]any)["nodes"].(map[string]any)["items"].(map[string]any)
+	retries := node["properties"].(map[string]any)["retries"].(map[string]any)
+	if retries["deprecated"] != true {
+		t.Fatalf("retries not marked deprecated: %v", retries)
+	}
+	if desc := retries["description"]; desc != "Deprecated: removed in schema_version 3.0.0. Use a retry policy instead." {
+		t.Fatalf("description = %v", desc)
+	}
+}
+
+func TestParseWithWarnings_EnvInputIsDeprecated(t *testing.T) {
+	src := `{"schema_version": "2.0.0", "graph": {"nodes": [
+  {"id": "a", "type": "exec", "inputs": {"cmd": "make", "env": {"CI": "1"}}, "outputs": []}
+], "edges": []}}`
+	doc, warnings, err := ParseWithWarnings(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("ParseWithWarnings: %v", err)
+	}
+	if doc.Graph.Nodes[0].Inputs["env"] == nil {
+		t.Fatalf("deprecated env input not decoded")
+	}
+	want := "graph.nodes[0].inputs.env is deprecated and will be removed in schema_version 3.0.0; use graph.nodes[].env instead (line 2, column 64)"
+	if len(warnings) != 1 || warnings[0].String() != want {
+		t.Fatalf("warnings = %v, want [%s]", warnings, want)
+	}
+}
+
+func TestParseLinesWithWarnings_NamesDocumentFields(t *testing.T) {
+	src := `{"schema_version": "2.0.0"}
+{"node": {"id": "a", "type": "exec", "inputs": {"cmd": "make"}, "outputs": []}}
+{"node": {"id": "b", "type": "exec", "inputs": {"cmd": "make", "env": {}}, "outputs": []}}
+`
+	_, warnings, err := ParseLinesWithWarnings(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("ParseLinesWithWarnings: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Path != "graph.nodes[1].inputs.env" || warnings[0].Pos.Line != 3 || warnings[0].Pos.Column != 71 {
+		t.Fatalf("warnings = %+v", warnings)
+	}
+}
+
+func TestDeprecatedFields_ChecksRunGraphFiles(t *testing.T) {
+	withDeprecations(t, Deprecation{Field: "tasks[].mutex", RemovedIn: "3.0.0", Use: "tasks[].labels"})
+	src := `{"tasks": [{"name": "a", "inputs": [], "run": "true"}, {"name": "b", "inputs": [], "run": "true", "mutex": "db"}], "edges": []}`
+	warnings := DeprecatedFields([]byte(src))
+	if len(warnings) != 1 || warnings[0].Path != "tasks[1].mutex" {
+		t.Fatalf("warnings = %+v", warnings)
+	}
+}
+
+func TestRegisterDeprecation(t *testing.T) {
+	withDeprecations(t)
+	if err := RegisterDeprecation(Deprecation{RemovedIn: "3.0.0"}); err == nil {
+		t.Fatalf("expected an error for an empty field")
+	}
+	if err := RegisterDeprecation(Deprecation{Field: "tasks[].mutex"}); err == nil {
+		t.Fatalf("expected an error for an empty removed_in version")
+	}
+	d := Deprecation{Field: "tasks[].mutex", RemovedIn: "3.0.0"}
+	if err := RegisterDeprecation(d); err != nil {
+		t.Fatalf("RegisterDeprecation: %v", err)
+	}
+	if got := Deprecations(); len(got) != 1 || got[0] != d {
+		t.Fatalf("Deprecations() = %v", got)
+	}
+}
+
+func TestJSONSchema_MarksDeprecatedEnvInput(t *testing.T) {
+	data, err := json.Marshal(JSONSchema(nil))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	want := `"env":{"deprecated":true,"description":"Deprecated: removed in schema_version 3.0.0. Use graph.nodes[].env instead.","type":"object"}`
+	if !strings.Contains(string(data), want) {
+		t.Fatalf("schema does not mark the env input deprecated:\n%s", data)
+	}
+}
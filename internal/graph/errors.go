@@ -3,6 +3,7 @@ package graph
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // Sentinel errors for programmatic error checking via errors.Is().
@@ -23,8 +24,10 @@ var (
 // ParseError represents a failure to parse the graph JSON.
 // Wraps ErrParse for errors.Is() compatibility.
 type ParseError struct {
-	Msg string // Deterministic error message
-	Err error  // Optional underlying error (e.g., from json.Unmarshal)
+	Msg  string   // Deterministic error message
+	Err  error    // Optional underlying error (e.g., from json.Unmarshal)
+	Path string   // JSON path of the offending value (if known), e.g. "graph.nodes[3]"
+	Pos  Position // Location in the source (if known)
 }
 
 func (e *ParseError) Error() string {
@@ -34,6 +37,16 @@ func (e *ParseError) Error() string {
 	if e.Msg == "" {
 		return ErrParse.Error()
 	}
+	var at []string
+	if e.Path != "" {
+		at = append(at, "at "+e.Path)
+	}
+	if pos := e.Pos.String(); pos != "" {
+		at = append(at, pos)
+	}
+	if len(at) > 0 {
+		return fmt.Sprintf("%s: %s (%s)", ErrParse.Error(), e.Msg, strings.Join(at, ", "))
+	}
 	return fmt.Sprintf("%s: %s", ErrParse.Error(), e.Msg)
 }
 
@@ -42,30 +55,53 @@ func (e *ParseError) Unwrap() error { return ErrParse }
 // SchemaError represents a schema validation failure.
 // Wraps ErrSchema for errors.Is() compatibility.
 type SchemaError struct {
-	Field string // The field that caused the error (if applicable)
-	Msg   string // Deterministic error message
+	Field string   // JSON path of the field that caused the error (if applicable)
+	Msg   string   // Deterministic error message
+	Hints []Hint   // Optional suggested fixes
+	Pos   Position // Location of the field, or of the object missing it (if known)
 }
 
 func (e *SchemaError) Error() string {
 	if e == nil {
 		return ""
 	}
-	if e.Field != "" {
-		return fmt.Sprintf("%s: %s: %s", ErrSchema.Error(), e.Field, e.Msg)
-	}
-	if e.Msg == "" {
+	var msg string
+	switch {
+	case e.Field != "":
+		msg = fmt.Sprintf("%s: %s: %s", ErrSchema.Error(), e.Field, e.Msg)
+	case e.Msg == "":
 		return ErrSchema.Error()
+	default:
+		msg = fmt.Sprintf("%s: %s", ErrSchema.Error(), e.Msg)
+	}
+	if pos := e.Pos.String(); pos != "" {
+		msg += " (" + pos + ")"
 	}
-	return fmt.Sprintf("%s: %s", ErrSchema.Error(), e.Msg)
+	return msg
 }
 
 func (e *SchemaError) Unwrap() error { return ErrSchema }
 
+// LocationOf returns the JSON path and the source position of the first
+// ParseError or SchemaError in err's chain. Either is zero when unknown.
+func LocationOf(err error) (string, Position) {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.Path, parseErr.Pos
+	}
+	var schemaErr *SchemaError
+	if errors.As(err, &schemaErr) {
+		return schemaErr.Field, schemaErr.Pos
+	}
+	return "", Position{}
+}
+
 // StructuralError represents a structural validation failure.
 // Wraps ErrStructural for errors.Is() compatibility.
 type StructuralError struct {
-	Kind string // Type of structural issue: "cycle", "duplicate_id", "dangling_edge"
-	Msg  string // Deterministic error message
+	Kind  string // Type of structural issue: "cycle", "duplicate_id", "dangling_edge"
+	Msg   string // Deterministic error message
+	Hints []Hint // Optional suggested fixes
 }
 
 func (e *StructuralError) Error() string {
@@ -83,7 +119,8 @@ func (e *StructuralError) Unwrap() error { return ErrStructural }
 // SemanticError represents a semantic validation failure.
 // Wraps ErrSemantic for errors.Is() compatibility.
 type SemanticError struct {
-	Msg string // Deterministic error message
+	Msg   string // Deterministic error message
+	Hints []Hint // Optional suggested fixes
 }
 
 func (e *SemanticError) Error() string {
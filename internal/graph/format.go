@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Format validates data, a graph document, and returns it in canonical form:
+// the normalized graph (see Graph.Normalize) encoded with the keys in the
+// order of the document types and two-space indentation, ending in a
+// newline. Formatting a formatted document returns it unchanged, and
+// formatting never changes the graph hash.
+//
+// A document that declares definitions keeps them and the extends of its
+// nodes, so the fields a node sets itself, including zero values overriding
+// a fragment, are written as they are; nodes, their outputs and the edges
+// are still sorted, and definitions are written by name.
+func Format(data []byte) ([]byte, error) {
+	doc, err := parse(data, nil)
+	if err != nil {
+		return nil, locateError(data, err)
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, &ParseError{Msg: err.Error(), Err: err}
+	}
+	if _, ok := probe[DefinitionsKey]; !ok {
+		doc.Graph.Normalize()
+		return encodeCanonical(doc)
+	}
+
+	var src formatDocument
+	if err := json.Unmarshal(data, &src); err != nil {
+		return nil, &ParseError{Msg: err.Error(), Err: err}
+	}
+	nodes := src.Graph.Nodes
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	for _, n := range nodes {
+		if n.Outputs != nil {
+			sort.Strings(*n.Outputs)
+		}
+	}
+	edges := src.Graph.Edges
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return encodeCanonical(&src)
+}
+
+// encodeCanonical encodes v as Format writes documents. HTML characters, as
+// in the commands of nodes, are left unescaped.
+func encodeCanonical(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, &ParseError{Msg: err.Error(), Err: err}
+	}
+	return buf.Bytes(), nil
+}
+
+// formatDocument is a document with definitions as Format writes it. Its
+// node fields are pointers so that exactly the fields set in the source are
+// written.
+type formatDocument struct {
+	SchemaVersion string                `json:"schema_version"`
+	Definitions   map[string]formatNode `json:"definitions"`
+	Graph         struct {
+		Nodes []formatNode `json:"nodes"`
+		Edges []Edge       `json:"edges"`
+	} `json:"graph"`
+	Metadata Metadata `json:"metadata"`
+}
+
+type formatNode struct {
+	ID             string             `json:"id,omitempty"`
+	Extends        any                `json:"extends,omitempty"`
+	Type           *string            `json:"type,omitempty"`
+	Inputs         *map[string]any    `json:"inputs,omitempty"`
+	Outputs        *[]string          `json:"outputs,omitempty"`
+	Env            *map[string]string `json:"env,omitempty"`
+	TimeoutSeconds *int               `json:"timeout_seconds,omitempty"`
+	Retries        *int               `json:"retries,omitempty"`
+}
@@ -0,0 +1,161 @@
+package graph
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormat_WritesCanonicalDocument(t *testing.T) {
+	src := `{"metadata": {"name": "demo"}, "graph": {"edges": [{"to": "b", "from": "a"}],
+	  "nodes": [{"outputs": ["z", "a"], "id": "b", "type": "exec", "inputs": {"cmd": "make && make install"}, "retries": 0},
+	            {"id": "a", "type": "exec", "inputs": {"cmd": "true"}, "outputs": []}]},
+	  "schema_version": "2.0.0"}`
+	want := `{
+  "schema_version": "2.0.0",
+  "graph": {
+    "nodes": [
+      {
+        "id": "a",
+        "type": "exec",
+        "inputs": {
+          "cmd": "true"
+        },
+        "outputs": []
+      },
+      {
+        "id": "b",
+        "type": "exec",
+        "inputs": {
+          "cmd": "make && make install"
+        },
+        "outputs": [
+          "a",
+          "z"
+        ]
+      }
+    ],
+    "edges": [
+      {
+        "from": "a",
+        "to": "b"
+      }
+    ]
+  },
+  "metadata": {
+    "name": "demo"
+  }
+}
+`
+	got, err := Format([]byte(src))
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("Format =\n%s\nwant:\n%s", got, want)
+	}
+	again, err := Format(got)
+	if err != nil || !bytes.Equal(again, got) {
+		t.Fatalf("formatting a formatted document changed it (err=%v):\n%s", err, again)
+	}
+	assertSameHash(t, src, string(got))
+}
+
+func TestFormat_KeepsDefinitions(t *testing.T) {
+	src := `{"schema_version": "2.0.0",
+	  "graph": {"nodes": [{"id": "b", "extends": ["go"], "inputs": {"cmd": "go test"}, "retries": 0}, {"id": "a", "extends": "go", "outputs": ["y", "x"]}], "edges": []},
+	  "metadata": {}, "definitions": {"go": {"type": "exec", "inputs": {"cmd": "go build"}, "outputs": [], "retries": 2}}}`
+	want := `{
+  "schema_version": "2.0.0",
+  "definitions": {
+    "go": {
+      "type": "exec",
+      "inputs": {
+        "cmd": "go build"
+      },
+      "outputs": [],
+      "retries": 2
+    }
+  },
+  "graph": {
+    "nodes": [
+      {
+        "id": "a",
+        "extends": "go",
+        "outputs": [
+          "x",
+          "y"
+        ]
+      },
+      {
+        "id": "b",
+        "extends": [
+          "go"
+        ],
+        "inputs": {
+          "cmd": "go test"
+        },
+        "retries": 0
+      }
+    ],
+    "edges": []
+  },
+  "metadata": {}
+}
+`
+	got, err := Format([]byte(src))
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	if string(got) != want {
+		t.Fatalf("Format =\n%s\nwant:\n%s", got, want)
+	}
+	assertSameHash(t, src, string(got))
+}
+
+func TestFormat_IsIdempotentOnTestdata(t *testing.T) {
+	for _, name := range []string{"minimal.graph.json", "maximal.graph.json"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		once, err := Format(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		twice, err := Format(once)
+		if err != nil || !bytes.Equal(once, twice) {
+			t.Fatalf("%s: second Format differs (err=%v)", name, err)
+		}
+		assertSameHash(t, string(data), string(once))
+	}
+}
+
+func TestFormat_RejectsInvalidDocument(t *testing.T) {
+	_, err := Format([]byte(`{"schema_version": "2.0.0", "graph": {"nodes": [{"id": "a"}], "edges": []}, "metadata": {}}`))
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.Pos.Line == 0 {
+		t.Fatalf("err = %v, want a located SchemaError", err)
+	}
+}
+
+func assertSameHash(t *testing.T, a, b string) {
+	t.Helper()
+	hash := func(s string) string {
+		doc, err := Parse(strings.NewReader(s))
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		h, err := ComputeHash(&doc.Graph)
+		if err != nil {
+			t.Fatalf("ComputeHash: %v", err)
+		}
+		return h
+	}
+	if hash(a) != hash(b) {
+		t.Fatal("formatting changed the graph hash")
+	}
+}
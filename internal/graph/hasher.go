@@ -0,0 +1,275 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"hash"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// parallelHashThreshold is the node count from which nodes are encoded
+// concurrently.
+const parallelHashThreshold = 512
+
+// maxHashMemo bounds the per-node memo; it is cleared when exceeded.
+const maxHashMemo = 1 << 17
+
+// Hasher computes the same hash as ComputeHash, but memoizes the canonical
+// encoding of each node by node content and encodes nodes of large graphs in
+// parallel.
+//
+// Repeated hashing of a large graph where few nodes changed then only
+// re-encodes the changed nodes. The content key is derived by walking the node
+// directly, which is much cheaper than JSON encoding. Nodes holding input
+// values of types other than those produced by JSON decoding are encoded
+// without memoization.
+//
+// A Hasher is safe for concurrent use.
+type Hasher struct {
+	mu   sync.Mutex
+	memo map[[32]byte][]byte
+}
+
+// NewHasher returns a Hasher with an empty memo.
+func NewHasher() *Hasher {
+	return &Hasher{memo: make(map[[32]byte][]byte)}
+}
+
+// defaultHasher backs ComputeHash and ComputeHashBytes.
+var defaultHasher = NewHasher()
+
+// Hash returns the SHA-256 of the graph's normalized JSON encoding.
+//
+// The result is byte-for-byte identical to hashing json.Marshal(g.Normalized()):
+// nodes are encoded individually and spliced into the same canonical stream.
+func (h *Hasher) Hash(g *Graph) ([32]byte, error) {
+	order := make([]int, len(g.Nodes))
+	for i := range order {
+		order[i] = i
+	}
+	// Same comparator and algorithm as Normalize, so ties resolve identically.
+	sort.Slice(order, func(a, b int) bool { return g.Nodes[order[a]].ID < g.Nodes[order[b]].ID })
+
+	encoded := make([][]byte, len(order))
+	var firstErr error
+	var errOnce sync.Once
+	encodeRange := func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			b, err := h.encodeNode(&g.Nodes[order[i]])
+			if err != nil {
+				errOnce.Do(func() { firstErr = err })
+				return
+			}
+			encoded[i] = b
+		}
+	}
+
+	if n := len(order); n < parallelHashThreshold {
+		encodeRange(0, n)
+	} else {
+		workers := runtime.GOMAXPROCS(0)
+		chunk := (n + workers - 1) / workers
+		var wg sync.WaitGroup
+		for lo := 0; lo < n; lo += chunk {
+			hi := lo + chunk
+			if hi > n {
+				hi = n
+			}
+			wg.Add(1)
+			go func(lo, hi int) {
+				defer wg.Done()
+				encodeRange(lo, hi)
+			}(lo, hi)
+		}
+		wg.Wait()
+	}
+	if firstErr != nil {
+		return [32]byte{}, &ParseError{Msg: "failed to serialize graph for hashing", Err: firstErr}
+	}
+
+	edges := make([]Edge, len(g.Edges))
+	copy(edges, g.Edges)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	edgeBytes, err := json.Marshal(edges)
+	if err != nil {
+		return [32]byte{}, &ParseError{Msg: "failed to serialize graph for hashing", Err: err}
+	}
+
+	sum := sha256.New()
+	sum.Write([]byte(`{"nodes":[`))
+	for i, b := range encoded {
+		if i > 0 {
+			sum.Write([]byte{','})
+		}
+		sum.Write(b)
+	}
+	sum.Write([]byte(`],"edges":`))
+	sum.Write(edgeBytes)
+	sum.Write([]byte{'}'})
+
+	var out [32]byte
+	copy(out[:], sum.Sum(nil))
+	return out, nil
+}
+
+// encodeNode returns the canonical JSON of a node, reusing a memoized encoding
+// when a node with identical content was encoded before.
+func (h *Hasher) encodeNode(n *Node) ([]byte, error) {
+	outputs := make([]string, len(n.Outputs))
+	copy(outputs, n.Outputs)
+	sort.Strings(outputs)
+
+	key, ok := nodeContentKey(n, outputs)
+	if ok {
+		h.mu.Lock()
+		b, hit := h.memo[key]
+		h.mu.Unlock()
+		if hit {
+			return b, nil
+		}
+	}
+
+	inputs := make(map[string]any, len(n.Inputs))
+	for k, v := range n.Inputs {
+		inputs[k] = v
+	}
+	b, err := json.Marshal(Node{ID: n.ID, Type: n.Type, Inputs: inputs, Outputs: outputs, Env: n.Env, TimeoutSeconds: n.TimeoutSeconds, Retries: n.Retries})
+	if err != nil {
+		return nil, err
+	}
+
+	if ok {
+		h.mu.Lock()
+		if len(h.memo) >= maxHashMemo {
+			h.memo = make(map[[32]byte][]byte)
+		}
+		h.memo[key] = b
+		h.mu.Unlock()
+	}
+	return b, nil
+}
+
+// nodeContentKey fingerprints a node with sorted outputs. It reports false when
+// an input value has a type the walker does not know how to fingerprint.
+func nodeContentKey(n *Node, sortedOutputs []string) ([32]byte, bool) {
+	w := contentWriter{h: sha256.New()}
+	w.str(n.ID)
+	w.str(n.Type)
+	if !w.value(map[string]any(n.Inputs)) {
+		return [32]byte{}, false
+	}
+	w.tag('o', len(sortedOutputs))
+	for _, o := range sortedOutputs {
+		w.str(o)
+	}
+	envKeys := make([]string, 0, len(n.Env))
+	for k := range n.Env {
+		envKeys = append(envKeys, k)
+	}
+	sort.Strings(envKeys)
+	w.tag('e', len(envKeys))
+	for _, k := range envKeys {
+		w.str(k)
+		w.str(n.Env[k])
+	}
+	w.tag('T', n.TimeoutSeconds)
+	w.tag('R', n.Retries)
+	var key [32]byte
+	copy(key[:], w.h.Sum(nil))
+	return key, true
+}
+
+// contentWriter writes a type-tagged, length-prefixed walk of a value.
+type contentWriter struct {
+	h hash.Hash
+}
+
+func (w contentWriter) tag(t byte, n int) {
+	var buf [9]byte
+	buf[0] = t
+	u := uint64(n)
+	for i := 0; i < 8; i++ {
+		buf[8-i] = byte(u >> (8 * i))
+	}
+	w.h.Write(buf[:])
+}
+
+func (w contentWriter) str(s string) {
+	w.tag('s', len(s))
+	w.h.Write([]byte(s))
+}
+
+func (w contentWriter) value(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		w.tag('n', 0)
+	case bool:
+		if x {
+			w.tag('t', 0)
+		} else {
+			w.tag('f', 0)
+		}
+	case string:
+		w.str(x)
+	case float64:
+		w.tag('d', 0)
+		w.str(strconv.FormatUint(math.Float64bits(x), 16))
+	case int:
+		w.tag('i', 0)
+		w.str(strconv.Itoa(x))
+	case int64:
+		w.tag('i', 0)
+		w.str(strconv.FormatInt(x, 10))
+	case json.Number:
+		w.tag('N', 0)
+		w.str(string(x))
+	case []any:
+		w.tag('a', len(x))
+		for _, e := range x {
+			if !w.value(e) {
+				return false
+			}
+		}
+	case []string:
+		w.tag('a', len(x))
+		for _, e := range x {
+			w.str(e)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		w.tag('m', len(keys))
+		for _, k := range keys {
+			w.str(k)
+			if !w.value(x[k]) {
+				return false
+			}
+		}
+	case map[string]string:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		w.tag('m', len(keys))
+		for _, k := range keys {
+			w.str(k)
+			w.str(x[k])
+		}
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// referenceHash is the original whole-graph hashing algorithm.
+func referenceHash(t *testing.T, g *Graph) [32]byte {
+	t.Helper()
+	data, err := json.Marshal(g.Normalized())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return sha256.Sum256(data)
+}
+
+func largeGraph(n int) *Graph {
+	g := &Graph{}
+	for i := n - 1; i >= 0; i-- {
+		g.Nodes = append(g.Nodes, Node{
+			ID:   fmt.Sprintf("n%05d", i),
+			Type: "task",
+			Inputs: map[string]any{
+				"run":    fmt.Sprintf("echo %d <&>", i),
+				"env":    map[string]any{"B": "2", "A": float64(i)},
+				"nested": []any{true, nil, "x", map[string]any{"k": []any{1.5}}},
+			},
+			Outputs: []string{"z.txt", fmt.Sprintf("out/%d", i)},
+		})
+		if i > 0 {
+			g.Edges = append(g.Edges, Edge{From: fmt.Sprintf("n%05d", i-1), To: fmt.Sprintf("n%05d", i)})
+		}
+	}
+	return g
+}
+
+func TestHasher_MatchesReferenceEncoding(t *testing.T) {
+	for _, n := range []int{0, 1, 7, parallelHashThreshold + 3} {
+		g := largeGraph(n)
+		got, err := NewHasher().Hash(g)
+		if err != nil {
+			t.Fatalf("Hash: %v", err)
+		}
+		if want := referenceHash(t, g); got != want {
+			t.Fatalf("n=%d: hash mismatch", n)
+		}
+	}
+}
+
+func TestHasher_MemoizesNodesAndTracksChanges(t *testing.T) {
+	h := NewHasher()
+	g := largeGraph(20)
+	first, err := h.Hash(g)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if len(h.memo) != 20 {
+		t.Fatalf("expected 20 memoized nodes, got %d", len(h.memo))
+	}
+
+	// Mutating one node re-encodes only that node and changes the hash.
+	g.Nodes[3].Inputs["run"] = "changed"
+	second, err := h.Hash(g)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if second == first || second != referenceHash(t, g) {
+		t.Fatalf("changed node must change the hash")
+	}
+	if len(h.memo) != 21 {
+		t.Fatalf("expected one new memo entry, got %d", len(h.memo))
+	}
+}
+
+func TestHasher_UnknownInputTypeIsNotMemoized(t *testing.T) {
+	type custom struct{ V int }
+	g := &Graph{Nodes: []Node{{ID: "a", Type: "t", Inputs: map[string]any{"c": custom{V: 1}}}}}
+	h := NewHasher()
+	got, err := h.Hash(g)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if got != referenceHash(t, g) || len(h.memo) != 0 {
+		t.Fatalf("unexpected result: memo=%d", len(h.memo))
+	}
+}
+
+func TestHasher_SchemaV2FieldsChangeHash(t *testing.T) {
+	h := NewHasher()
+	g := largeGraph(3)
+	base, err := h.Hash(g)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	seen := map[[32]byte]bool{base: true}
+	for _, mutate := range []func(n *Node){
+		func(n *Node) { n.Env = map[string]string{"MODE": "ci"} },
+		func(n *Node) { n.TimeoutSeconds = 30 },
+		func(n *Node) { n.Retries = 2 },
+	} {
+		mutate(&g.Nodes[1])
+		got, err := h.Hash(g)
+		if err != nil {
+			t.Fatalf("Hash: %v", err)
+		}
+		if seen[got] || got != referenceHash(t, g) {
+			t.Fatalf("node %+v: hash unchanged or differs from the reference encoding", g.Nodes[1])
+		}
+		seen[got] = true
+	}
+
+	// Unset fields are omitted, so 1.0.0 graphs keep their hashes.
+	g.Nodes[1].Env, g.Nodes[1].TimeoutSeconds, g.Nodes[1].Retries = map[string]string{}, 0, 0
+	if got, _ := h.Hash(g); got != base {
+		t.Fatalf("unset fields changed the hash")
+	}
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Hint is a machine-readable suggestion for fixing a validation error, shown
+// by the CLI and included in its JSON and SARIF reports.
+type Hint struct {
+	// Code identifies the kind of fix: HintDidYouMean or HintSchemaVersion.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Candidates are the node IDs closest to an unknown one, nearest first.
+	Candidates []string `json:"candidates,omitempty"`
+	// Command, when set, applies the fix.
+	Command string `json:"command,omitempty"`
+}
+
+const (
+	// HintDidYouMean lists the existing node IDs closest to an unknown one.
+	HintDidYouMean = "did_you_mean"
+	// HintSchemaVersion names the schema version to declare instead of an
+	// unsupported one.
+	HintSchemaVersion = "schema_version"
+)
+
+// Hinted is implemented by errors that carry hints.
+type Hinted interface {
+	error
+	FixHints() []Hint
+}
+
+// HintsOf returns the hints of the first Hinted error in err's chain, or nil.
+func HintsOf(err error) []Hint {
+	var h Hinted
+	if errors.As(err, &h) {
+		return h.FixHints()
+	}
+	return nil
+}
+
+func (e *SchemaError) FixHints() []Hint     { return e.Hints }
+func (e *StructuralError) FixHints() []Hint { return e.Hints }
+func (e *SemanticError) FixHints() []Hint   { return e.Hints }
+
+// maxCandidates bounds the node IDs a did_you_mean hint lists.
+const maxCandidates = 3
+
+// DidYouMean returns a did_you_mean hint with the IDs among known closest to
+// unknown by edit distance, or nil when none is close enough to be a likely
+// typo: at most a third of the longer ID's length, and at least 1, apart.
+func DidYouMean(unknown string, known []string) []Hint {
+	type scored struct {
+		id   string
+		dist int
+	}
+	var near []scored
+	for _, id := range known {
+		limit := len(id)
+		if len(unknown) > limit {
+			limit = len(unknown)
+		}
+		limit /= 3
+		if limit < 1 {
+			limit = 1
+		}
+		if d := editDistance(unknown, id); d <= limit {
+			near = append(near, scored{id, d})
+		}
+	}
+	if len(near) == 0 {
+		return nil
+	}
+	sort.Slice(near, func(i, j int) bool {
+		if near[i].dist != near[j].dist {
+			return near[i].dist < near[j].dist
+		}
+		return near[i].id < near[j].id
+	})
+	if len(near) > maxCandidates {
+		near = near[:maxCandidates]
+	}
+	ids := make([]string, len(near))
+	quoted := make([]string, len(near))
+	for i, c := range near {
+		ids[i] = c.id
+		quoted[i] = fmt.Sprintf("%q", c.id)
+	}
+	return []Hint{{Code: HintDidYouMean, Message: "did you mean " + strings.Join(quoted, " or ") + "?", Candidates: ids}}
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b in bytes: the Levenshtein distance, with swapping two adjacent bytes, the
+// commonest typo, counted as one edit.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDidYouMean_ListsClosestNodeIDs(t *testing.T) {
+	known := []string{"build", "built", "test", "deploy-production"}
+
+	hints := DidYouMean("biuld", known)
+	if len(hints) != 1 || hints[0].Code != HintDidYouMean || !reflect.DeepEqual(hints[0].Candidates, []string{"build"}) {
+		t.Fatalf("hints = %+v", hints)
+	}
+
+	hints = DidYouMean("buil", known)
+	if len(hints) != 1 || !reflect.DeepEqual(hints[0].Candidates, []string{"build", "built"}) {
+		t.Fatalf("hints = %+v", hints)
+	}
+	if hints[0].Message != `did you mean "build" or "built"?` {
+		t.Fatalf("message = %q", hints[0].Message)
+	}
+
+	if hints := DidYouMean("lint", known); hints != nil {
+		t.Fatalf("expected no hint for an unrelated ID, got %+v", hints)
+	}
+}
+
+func TestValidate_DanglingEdgeHasDidYouMeanHint(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{{ID: "compile", Type: "task", Inputs: map[string]any{}, Outputs: []string{}}},
+		Edges: []Edge{{From: "compiel", To: "compile"}},
+	}
+	hints := HintsOf(Validate(g))
+	if len(hints) != 1 || !reflect.DeepEqual(hints[0].Candidates, []string{"compile"}) {
+		t.Fatalf("hints = %+v", hints)
+	}
+}
+
+func TestParse_UnsupportedSchemaVersionHasHint(t *testing.T) {
+	_, err := Parse(strings.NewReader(strings.Replace(validMinimalJSON, "1.0.0", "3.0.0", 1)))
+	hints := HintsOf(err)
+	if len(hints) != 1 || hints[0].Code != HintSchemaVersion || hints[0].Message != `set schema_version to "2.0.0"` {
+		t.Fatalf("hints = %+v (err %v)", hints, err)
+	}
+}
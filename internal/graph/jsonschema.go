@@ -0,0 +1,186 @@
+package graph
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema draft JSONSchema is written in.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema of graph documents of
+// SupportedSchemaVersion, for editors to complete and check graph documents.
+// It does not describe the graph files of sw run (see SchemaOf).
+//
+// It is generated from Document and the types it holds: every field is a
+// property, fields without omitempty are required, and, as Parse decodes with
+// DisallowUnknownFields, no other property is allowed. It also describes
+// definitions and extends (see DefinitionsKey), and the inputs of the node
+// types with an input schema, built in or registered with types. Parse checks more
+// than the schema can express, such as retries staying within MaxRetries.
+// Deprecated fields are marked deprecated.
+func JSONSchema(types *NodeTypes) map[string]any {
+	root := typeSchema(documentType)
+	root["$schema"] = JSONSchemaDialect
+	root["title"] = "ScriptWeaver graph document"
+	root["description"] = "A graph document of schema_version " + SupportedSchemaVersion + ", as graph discovery reads it. The graph files of sw run, with tasks, have a schema of their own."
+	props := root["properties"].(map[string]any)
+	props["schema_version"].(map[string]any)["enum"] = []string{SupportedSchemaVersion, SchemaVersion1}
+
+	// A node extending definitions may take any field but its id from them,
+	// so it requires the others only when it extends none.
+	node := props["graph"].(map[string]any)["properties"].(map[string]any)["nodes"].(map[string]any)["items"].(map[string]any)
+	nodeProps := node["properties"].(map[string]any)
+	fragmentProps := make(map[string]any, len(nodeProps))
+	for k, v := range nodeProps {
+		if k != "id" {
+			fragmentProps[k] = v
+		}
+	}
+	nodeProps[ExtendsKey] = map[string]any{"oneOf": []any{
+		map[string]any{"type": "string"},
+		map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	}}
+	var rest []string
+	for _, k := range node["required"].([]string) {
+		if k != "id" {
+			rest = append(rest, k)
+		}
+	}
+	node["required"] = []string{"id"}
+	node["anyOf"] = []any{map[string]any{"required": []string{ExtendsKey}}, map[string]any{"required": rest}}
+	if rules := inputRules(types); len(rules) > 0 {
+		node["allOf"] = rules
+	}
+	props[DefinitionsKey] = map[string]any{
+		"type":                 "object",
+		"additionalProperties": map[string]any{"type": "object", "properties": fragmentProps, "additionalProperties": false},
+	}
+	markDeprecated(root)
+	return root
+}
+
+// SchemaOf returns a JSON Schema of the JSON encoding of values of t, with
+// title and description, generated as JSONSchema generates that of Document:
+// every field is a property, fields without omitempty are required, no other
+// property is allowed, and deprecated fields are marked deprecated. Loaders
+// of other graph formats describe theirs with it.
+func SchemaOf(t reflect.Type, title, description string) map[string]any {
+	root := typeSchema(t)
+	root["$schema"] = JSONSchemaDialect
+	root["title"] = title
+	root["description"] = description
+	markDeprecated(root)
+	return root
+}
+
+// inputRules returns, for every node type of types with an input schema,
+// the rule checking the inputs of its nodes.
+func inputRules(types *NodeTypes) []any {
+	names := types.Names()
+	for t := range builtinNodeTypes {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+
+	var rules []any
+	for _, t := range names {
+		schema, _ := types.Lookup(t)
+		if schema == nil {
+			continue
+		}
+		props := make(map[string]any, len(schema)+1)
+		env := map[string]any{"type": InputObject}
+		annotateDeprecated(env, "graph.nodes[].inputs.env")
+		props["env"] = env
+		var required []string
+		for key, spec := range schema {
+			props[key] = inputTypeSchema(spec.Type)
+			if spec.Required {
+				required = append(required, key)
+			}
+		}
+		sort.Strings(required)
+		then := map[string]any{"properties": map[string]any{
+			"inputs": map[string]any{"type": "object", "properties": props, "additionalProperties": false},
+		}}
+		if len(required) > 0 {
+			then["anyOf"] = []any{
+				map[string]any{"required": []string{ExtendsKey}},
+				map[string]any{"properties": map[string]any{"inputs": map[string]any{"required": required}}},
+			}
+		}
+		rules = append(rules, map[string]any{
+			"if":   map[string]any{"properties": map[string]any{"type": map[string]any{"const": t}}, "required": []string{"type"}},
+			"then": then,
+		})
+	}
+	return rules
+}
+
+func inputTypeSchema(t string) map[string]any {
+	if t == InputAny {
+		return map[string]any{}
+	}
+	return map[string]any{"type": t}
+}
+
+// typeSchema returns the JSON Schema of the JSON encoding of values of t.
+func typeSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.Struct:
+		props := make(map[string]any)
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			props[name] = typeSchema(f.Type)
+			if !strings.Contains(opts, "omitempty") && hasRequired(f.Type) {
+				required = append(required, name)
+			}
+		}
+		sort.Strings(required)
+		return map[string]any{"type": "object", "properties": props, "required": required, "additionalProperties": false}
+	}
+	// Interface values hold any JSON value.
+	return map[string]any{}
+}
+
+// hasRequired reports whether a field of type t is required when not
+// omitempty: a struct field only is when the struct has a required field,
+// so an object of optional fields, such as metadata, may be left out.
+func hasRequired(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		_, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.IsExported() && !strings.Contains(opts, "omitempty") && hasRequired(f.Type) {
+			return true
+		}
+	}
+	return false
+}
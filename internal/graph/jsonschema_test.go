@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// checkKeys reports the first object key of v that schema, read as
+// properties, additionalProperties and items only, does not allow.
+func checkKeys(schema map[string]any, v any, path string) error {
+	switch v := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		extra, _ := schema["additionalProperties"].(map[string]any)
+		for k, child := range v {
+			sub, ok := props[k].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s.%s is not allowed", path, k)
+				}
+				sub = extra
+			}
+			if err := checkKeys(sub, child, path+"."+k); err != nil {
+				return err
+			}
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, child := range v {
+			if err := checkKeys(items, child, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestJSONSchema_MatchesDecoder(t *testing.T) {
+	schema := JSONSchema(nil)
+	// A round trip through JSON checks that the schema marshals, and gives
+	// it the shape checkKeys reads.
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("marshal schema: %v", err)
+	}
+	var s map[string]any
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	node := s["properties"].(map[string]any)["graph"].(map[string]any)["properties"].(map[string]any)["nodes"].(map[string]any)["items"].(map[string]any)
+	var keys []string
+	for k := range node["properties"].(map[string]any) {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	want := []string{"env", "extends", "id", "inputs", "outputs", "retries", "timeout_seconds", "type"}
+	if fmt.Sprint(keys) != fmt.Sprint(want) {
+		t.Fatalf("node properties = %v, want %v", keys, want)
+	}
+
+	// Every document Parse accepts uses only properties the schema allows,
+	// and a key DisallowUnknownFields rejects is not allowed either.
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.graph.json"))
+	checked := 0
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Parse(bytes.NewReader(b)); err != nil {
+			continue
+		}
+		var doc any
+		if err := json.Unmarshal(b, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if err := checkKeys(s, doc, ""); err != nil {
+			t.Errorf("%s: %v", f, err)
+		}
+		checked++
+	}
+	if checked == 0 {
+		t.Fatal("no valid testdata document")
+	}
+	var doc any
+	_ = json.Unmarshal([]byte(`{"schema_version": "2.0.0", "graph": {"nodes": [{"id": "a", "type": "t", "inputs": {}, "outputs": [], "bogus": 1}], "edges": []}}`), &doc)
+	if err := checkKeys(s, doc, ""); err == nil {
+		t.Error("schema allows an unknown node field")
+	}
+}
@@ -0,0 +1,240 @@
+package graph
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LinesExt is the file extension of graph documents written as JSON Lines,
+// which ParseLines reads.
+const LinesExt = ".jsonl"
+
+// lineRecord is one line of a JSON Lines document: the header, holding
+// schema_version and metadata, or a node or an edge.
+type lineRecord struct {
+	SchemaVersion *string   `json:"schema_version"`
+	Metadata      *Metadata `json:"metadata"`
+	Node          *Node     `json:"node"`
+	Edge          *Edge     `json:"edge"`
+}
+
+var lineRecordType = reflect.TypeOf(lineRecord{})
+
+// ParseLines decodes a graph document written as JSON Lines, one record per
+// line, so that generators can stream large graphs without holding the whole
+// document:
+//
+//	{"schema_version": "2.0.0", "metadata": {"name": "big"}}
+//	{"node": {"id": "a", "type": "exec", "inputs": {"cmd": "true"}, "outputs": []}}
+//	{"edge": {"from": "a", "to": "b"}}
+//
+// The first record is the header, with schema_version and optionally
+// metadata; every other record holds one node or one edge, in any order.
+// Blank lines are skipped. Definitions are not supported. The records are
+// assembled into the Document Parse returns for the same graph, and
+// validated as Parse validates it, returning the same errors. Errors locate
+// the record by line; within a record they give the column too.
+func ParseLines(r io.Reader) (*Document, error) {
+	doc, _, err := ParseLinesWithWarnings(r)
+	return doc, err
+}
+
+// ParseLinesWithWarnings is ParseLines, also returning a warning for every
+// use of a deprecated field (see Deprecation), in document order. Warnings
+// name the field as in the assembled Document, and locate it in its record.
+func ParseLinesWithWarnings(r io.Reader) (*Document, []DeprecationWarning, error) {
+	return ParseLinesWithNodeTypes(r, nil)
+}
+
+// ParseLinesWithNodeTypes is ParseLinesWithWarnings, also checking the
+// inputs of nodes of the types registered with types against their schemas.
+func ParseLinesWithNodeTypes(r io.Reader, types *NodeTypes) (*Document, []DeprecationWarning, error) {
+	br := bufio.NewReader(r)
+	doc := &Document{Graph: Graph{Nodes: []Node{}, Edges: []Edge{}}}
+	// nodeAt and edgeAt hold the start of the record of each node and edge,
+	// to locate validation errors.
+	var nodeAt, edgeAt []Position
+	var warnings []DeprecationWarning
+	var offset int64
+	lineNo, header := 0, false
+	for {
+		line, readErr := br.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, nil, &ParseError{Msg: readErr.Error(), Err: readErr}
+		}
+		lineNo++
+		start := offset
+		offset += int64(len(line))
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			at := Position{Offset: start, Line: lineNo, Column: 1}
+			if err := addRecord(doc, line, !header); err != nil {
+				return nil, nil, locateLine(line, at, err)
+			}
+			prefix := ""
+			switch {
+			case !header:
+				header = true
+			case len(doc.Graph.Nodes) > len(nodeAt):
+				prefix = fmt.Sprintf("graph.nodes[%d]", len(nodeAt))
+				nodeAt = append(nodeAt, at)
+			default:
+				prefix = fmt.Sprintf("graph.edges[%d]", len(edgeAt))
+				edgeAt = append(edgeAt, at)
+			}
+			warnings = append(warnings, lineDeprecations(line, at, prefix)...)
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+	if !header {
+		return nil, nil, &SchemaError{Field: "schema_version", Msg: "required field is missing"}
+	}
+	if err := validateDocument(doc, types); err != nil {
+		return nil, nil, locateRecord(err, nodeAt, edgeAt)
+	}
+	return doc, warnings, nil
+}
+
+// addRecord decodes line, the header when header is set, into doc.
+func addRecord(doc *Document, line []byte, header bool) error {
+	var rec lineRecord
+	if err := decodeStrict(line, &rec); err != nil {
+		return err
+	}
+	if !json.Valid(line) {
+		return &ParseError{Msg: "a record must be a single JSON object"}
+	}
+	isHeader := rec.SchemaVersion != nil || rec.Metadata != nil
+	switch {
+	case header && (!isHeader || rec.Node != nil || rec.Edge != nil):
+		return &SchemaError{Field: "schema_version", Msg: "the first record must be the header, holding schema_version and metadata only"}
+	case header:
+		if rec.SchemaVersion != nil {
+			doc.SchemaVersion = *rec.SchemaVersion
+		}
+		if rec.Metadata != nil {
+			doc.Metadata = *rec.Metadata
+		}
+	case isHeader:
+		return &SchemaError{Msg: "only the first record may hold schema_version or metadata"}
+	case (rec.Node == nil) == (rec.Edge == nil):
+		return &SchemaError{Msg: "a record must hold exactly one node or edge"}
+	case rec.Node != nil:
+		doc.Graph.Nodes = append(doc.Graph.Nodes, *rec.Node)
+	default:
+		doc.Graph.Edges = append(doc.Graph.Edges, *rec.Edge)
+	}
+	return nil
+}
+
+// lineDeprecations returns the uses of deprecated fields in the record line
+// starting at at. prefix is the path in the Document of the node or edge the
+// record holds; it is empty for the header.
+func lineDeprecations(line []byte, at Position, prefix string) []DeprecationWarning {
+	return recordDeprecations(line, lineRecordType, at, func(path string) string {
+		for _, key := range []string{"node", "edge"} {
+			if rest, ok := strings.CutPrefix(path, key); ok && (rest == "" || rest[0] == '.') {
+				return prefix + rest
+			}
+		}
+		return path
+	})
+}
+
+// DeprecatedRecordFields is DeprecatedFields for line, a record of a JSON
+// Lines file starting at at, for graph formats other than graph documents to
+// be written as JSON Lines. The record holds one item under key, such as
+// {"task": {...}}, whose fields are named as the fields of the item at path,
+// such as "tasks[3]".
+func DeprecatedRecordFields(line []byte, at Position, key, path string) []DeprecationWarning {
+	return recordDeprecations(line, nil, at, func(p string) string {
+		if rest, ok := strings.CutPrefix(p, key); ok && (rest == "" || rest[0] == '.' || rest[0] == '[') {
+			return path + rest
+		}
+		return p
+	})
+}
+
+// recordDeprecations returns the uses of deprecated fields in the record line
+// of type typ starting at at; used is as for matchDeprecations.
+func recordDeprecations(line []byte, typ reflect.Type, at Position, used func(path string) string) []DeprecationWarning {
+	warnings := matchDeprecations(indexSource(line, typ), used)
+	for i := range warnings {
+		// A record is a single line.
+		warnings[i].Pos.Offset += at.Offset
+		warnings[i].Pos.Line = at.Line
+	}
+	return warnings
+}
+
+// DecodeRecord decodes line, a record of a JSON Lines file starting at at,
+// into v as DecodeStrict does, locating errors in the file, for graph formats
+// other than graph documents to be written as JSON Lines.
+func DecodeRecord(line []byte, at Position, v any) error {
+	if err := DecodeStrict(line, v); err != nil {
+		return atRecord(err, at)
+	}
+	return nil
+}
+
+// locateLine locates err, returned for the record line starting at at, in
+// the document.
+func locateLine(line []byte, at Position, err error) error {
+	return atRecord(locateIn(indexSource(line, lineRecordType), err), at)
+}
+
+// atRecord moves the location of err, located in a record, to the record
+// starting at at, which it leaves the location at when err lacks one.
+func atRecord(err error, at Position) error {
+	var pos *Position
+	var parseErr *ParseError
+	var schemaErr *SchemaError
+	switch {
+	case errors.As(err, &parseErr):
+		pos = &parseErr.Pos
+	case errors.As(err, &schemaErr):
+		pos = &schemaErr.Pos
+	default:
+		return err
+	}
+	if pos.Line == 0 {
+		*pos = at
+		return err
+	}
+	// A record is a single line.
+	pos.Offset += at.Offset
+	pos.Line = at.Line
+	return err
+}
+
+var recordPath = regexp.MustCompile(`^graph\.(nodes|edges)\[(\d+)\]`)
+
+// locateRecord locates a validation error of the assembled document at the
+// record of the node or edge it names.
+func locateRecord(err error, nodeAt, edgeAt []Position) error {
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		return err
+	}
+	m := recordPath.FindStringSubmatch(schemaErr.Field)
+	if m == nil {
+		return err
+	}
+	at := nodeAt
+	if m[1] == "edges" {
+		at = edgeAt
+	}
+	if i, convErr := strconv.Atoi(m[2]); convErr == nil && i < len(at) {
+		schemaErr.Pos = at[i]
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLines_AssemblesSameDocumentAsParse(t *testing.T) {
+	lines := `{"schema_version": "2.0.0", "metadata": {"name": "big"}}
+{"node": {"id": "b", "type": "exec", "inputs": {"cmd": "go test"}, "outputs": [], "retries": 1}}
+
+{"edge": {"from": "a", "to": "b"}}
+{"node": {"id": "a", "type": "exec", "inputs": {"cmd": "go build"}, "outputs": ["bin"]}}
+`
+	whole := `{
+		"schema_version": "2.0.0",
+		"graph": {
+			"nodes": [
+				{"id": "b", "type": "exec", "inputs": {"cmd": "go test"}, "outputs": [], "retries": 1},
+				{"id": "a", "type": "exec", "inputs": {"cmd": "go build"}, "outputs": ["bin"]}
+			],
+			"edges": [{"from": "a", "to": "b"}]
+		},
+		"metadata": {"name": "big"}
+	}`
+	got, err := ParseLines(strings.NewReader(lines))
+	if err != nil {
+		t.Fatalf("ParseLines: %v", err)
+	}
+	want, err := Parse(strings.NewReader(whole))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseLines = %+v, want %+v", got, want)
+	}
+	gotHash, _ := ComputeHash(&got.Graph)
+	wantHash, _ := ComputeHash(&want.Graph)
+	if gotHash != wantHash {
+		t.Fatalf("hash %s, want %s", gotHash, wantHash)
+	}
+}
+
+func TestParseLines_HeaderOnly(t *testing.T) {
+	doc, err := ParseLines(strings.NewReader(`{"schema_version": "1.0.0"}`))
+	if err != nil {
+		t.Fatalf("ParseLines: %v", err)
+	}
+	if doc.Graph.Nodes == nil || doc.Graph.Edges == nil || len(doc.Graph.Nodes)+len(doc.Graph.Edges) != 0 {
+		t.Fatalf("graph = %+v, want empty", doc.Graph)
+	}
+}
+
+func TestParseLines_Errors(t *testing.T) {
+	const header = `{"schema_version": "2.0.0"}` + "\n"
+	tests := []struct {
+		name  string
+		input string
+		want  error
+		msg   string
+	}{
+		{"empty", "", ErrSchema, "schema error: schema_version: required field is missing"},
+		{"node before header", `{"node": {"id": "a", "type": "exec", "inputs": {"cmd": "true"}, "outputs": []}}`, ErrSchema,
+			"schema error: schema_version: the first record must be the header, holding schema_version and metadata only (line 1, column 1)"},
+		{"second header", header + `{"metadata": {}}`, ErrSchema,
+			"schema error: only the first record may hold schema_version or metadata (line 2, column 1)"},
+		{"node and edge", header + `{"node": {"id": "a"}, "edge": {"from": "a", "to": "b"}}`, ErrSchema,
+			"schema error: a record must hold exactly one node or edge (line 2, column 1)"},
+		{"two records on a line", header + `{"edge": {"from": "a", "to": "b"}} {"edge": {"from": "b", "to": "c"}}`, ErrParse,
+			"parse error: a record must be a single JSON object (line 2, column 1)"},
+		{"malformed", header + "\n" + `{"edge": {"from": "a", "to": }}`, ErrParse,
+			"parse error: malformed JSON at offset 30 (at edge.to, line 3, column 30)"},
+		{"unknown field", header + `{"node": {"id": "a", "extends": "go"}}`, ErrParse,
+			`parse error: json: unknown field "extends" (at node.extends, line 2, column 22)`},
+		{"wrong type", header + `{"node": {"id": "a", "type": "exec", "inputs": [], "outputs": []}}`, ErrSchema,
+			"schema error: node.inputs: invalid field type: json: cannot unmarshal array into Go struct field lineRecord.node.inputs of type map[string]interface {} (line 2, column 48)"},
+		{"missing node field", header + `{"edge": {"from": "a", "to": "b"}}` + "\n" + `{"node": {"id": "a", "type": "exec", "inputs": {"cmd": "true"}}}`, ErrSchema,
+			"schema error: graph.nodes[0].outputs: required field is missing (line 3, column 1)"},
+		{"bad input", header + `{"node": {"id": "a", "type": "exec", "inputs": {"cmd": 1}, "outputs": []}}`, ErrSchema,
+			"schema error: graph.nodes[0].inputs.cmd: must be of type string (line 2, column 1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLines(strings.NewReader(tt.input))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if err.Error() != tt.msg {
+				t.Fatalf("err = %q\nwant  %q", err.Error(), tt.msg)
+			}
+		})
+	}
+}
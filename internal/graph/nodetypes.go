@@ -0,0 +1,211 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Input types of an InputSpec.
+const (
+	InputString  = "string"
+	InputNumber  = "number"
+	InputBoolean = "boolean"
+	InputObject  = "object"
+	InputArray   = "array"
+	InputAny     = "any"
+)
+
+// InputSpec declares one input of a node type.
+type InputSpec struct {
+	// Type is one of the Input* types.
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// InputSchema declares the inputs that nodes of a type accept, by key.
+// Parse rejects a node of a built-in or registered type that has an input the schema
+// does not declare, lacks a required one, or has one of the wrong type.
+// The deprecated "env" input, which the node's env field replaces, is always
+// accepted as an object unless the schema declares it.
+type InputSchema map[string]InputSpec
+
+// Validate checks that every input has a key and a known type.
+func (s InputSchema) Validate() error {
+	for key, spec := range s {
+		if key == "" {
+			return fmt.Errorf("input schema: empty input key")
+		}
+		switch spec.Type {
+		case InputString, InputNumber, InputBoolean, InputObject, InputArray, InputAny:
+		default:
+			return fmt.Errorf("input schema: input %q: unknown type %q", key, spec.Type)
+		}
+	}
+	return nil
+}
+
+// builtinNodeTypes are the input schemas of the built-in node types, which
+// mirror the kinds of task: "exec" runs the command cmd, and "fetch"
+// downloads url, verified against sha256 when set. They cannot be registered
+// again.
+var builtinNodeTypes = map[string]InputSchema{
+	"exec": {
+		"cmd": {Type: InputString, Required: true},
+	},
+	"fetch": {
+		"url":    {Type: InputString, Required: true},
+		"sha256": {Type: InputString},
+	},
+}
+
+// NodeTypes is a registry of node types, for one load of graphs: the
+// built-in types and those registered with it, such as the runners of the
+// plugins a run loaded. The nil *NodeTypes holds the built-in types only.
+type NodeTypes struct {
+	mu sync.RWMutex
+	m  map[string]InputSchema
+}
+
+// NewNodeTypes returns a registry holding the built-in types.
+func NewNodeTypes() *NodeTypes {
+	return &NodeTypes{m: make(map[string]InputSchema)}
+}
+
+// Register registers nodeType with the input schema of its nodes, replacing
+// the schema registered before, so that loads with t validate their inputs.
+// A nil schema registers the type without checking the inputs of its nodes.
+func (t *NodeTypes) Register(nodeType string, schema InputSchema) error {
+	if nodeType == "" {
+		return fmt.Errorf("register node type: empty type")
+	}
+	if _, ok := builtinNodeTypes[nodeType]; ok {
+		return fmt.Errorf("register node type: %q is a built-in type", nodeType)
+	}
+	if err := schema.Validate(); err != nil {
+		return fmt.Errorf("register node type %q: %w", nodeType, err)
+	}
+	var cp InputSchema
+	if schema != nil {
+		cp = make(InputSchema, len(schema))
+		for k, v := range schema {
+			cp[k] = v
+		}
+	}
+	t.mu.Lock()
+	t.m[nodeType] = cp
+	t.mu.Unlock()
+	return nil
+}
+
+// Lookup reports whether nodeType is built in or registered with t, and
+// returns the input schema of its nodes, nil when they are not checked.
+func (t *NodeTypes) Lookup(nodeType string) (InputSchema, bool) {
+	if s, ok := builtinNodeTypes[nodeType]; ok {
+		return s, true
+	}
+	if t == nil {
+		return nil, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	s, ok := t.m[nodeType]
+	return s, ok
+}
+
+// Registered reports whether nodeType was registered with t, as the runners
+// of plugins are; built-in types are not.
+func (t *NodeTypes) Registered(nodeType string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.m[nodeType]
+	return ok
+}
+
+// Names returns the node types registered with t, in order.
+func (t *NodeTypes) Names() []string {
+	if t == nil {
+		return nil
+	}
+	t.mu.RLock()
+	names := make([]string, 0, len(t.m))
+	for name := range t.m {
+		names = append(names, name)
+	}
+	t.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// validateNodeInputs checks the inputs of every node of a type with an input
+// schema in types against it.
+func validateNodeInputs(doc *Document, types *NodeTypes) error {
+	for i, node := range doc.Graph.Nodes {
+		schema, ok := types.Lookup(node.Type)
+		if !ok || schema == nil {
+			continue
+		}
+		field := func(key string) string { return fmt.Sprintf("graph.nodes[%d].inputs.%s", i, key) }
+		keys := make([]string, 0, len(node.Inputs))
+		for k := range node.Inputs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			spec, declared := schema[key]
+			if !declared && key == "env" {
+				spec, declared = InputSpec{Type: InputObject}, true
+			}
+			if !declared {
+				known := make([]string, 0, len(schema))
+				for k := range schema {
+					known = append(known, k)
+				}
+				return &SchemaError{Field: field(key), Msg: fmt.Sprintf("unknown input of node type %q", node.Type), Hints: DidYouMean(key, known)}
+			}
+			if !inputHasType(node.Inputs[key], spec.Type) {
+				return &SchemaError{Field: field(key), Msg: fmt.Sprintf("must be of type %s", spec.Type)}
+			}
+		}
+		required := make([]string, 0, len(schema))
+		for key, spec := range schema {
+			if _, ok := node.Inputs[key]; spec.Required && !ok {
+				required = append(required, key)
+			}
+		}
+		if len(required) > 0 {
+			sort.Strings(required)
+			return &SchemaError{Field: field(required[0]), Msg: fmt.Sprintf("required input of node type %q is missing", node.Type)}
+		}
+	}
+	return nil
+}
+
+// inputHasType reports whether the decoded JSON value v is of type t.
+func inputHasType(v any, t string) bool {
+	switch t {
+	case InputString:
+		_, ok := v.(string)
+		return ok
+	case InputNumber:
+		switch v.(type) {
+		case float64, json.Number, int, int64:
+			return true
+		}
+		return false
+	case InputBoolean:
+		_, ok := v.(bool)
+		return ok
+	case InputObject:
+		_, ok := v.(map[string]any)
+		return ok
+	case InputArray:
+		_, ok := v.([]any)
+		return ok
+	}
+	return true
+}
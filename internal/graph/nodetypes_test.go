@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse_NodeInputsMatchTypeSchema(t *testing.T) {
+	types := NewNodeTypes()
+	if err := types.Register("exec", InputSchema{}); err == nil {
+		t.Fatal("re-registering a built-in type succeeded")
+	}
+	if err := types.Register("deploy", InputSchema{"target": {Type: InputString, Required: true}, "replicas": {Type: InputNumber}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := types.Register("lambda", nil); err != nil {
+		t.Fatalf("Register without schema: %v", err)
+	}
+	parse := func(data string) error {
+		_, _, err := ParseWithNodeTypes(strings.NewReader(data), types)
+		return err
+	}
+	doc := func(nodeType, inputs string) string {
+		return `{"schema_version": "2.0.0", "graph": {"nodes": [{"id": "n", "type": "` + nodeType + `", "inputs": ` + inputs + `, "outputs": []}], "edges": []}, "metadata": {}}`
+	}
+
+	valid := []struct{ nodeType, inputs string }{
+		{"exec", `{"cmd": "make", "env": {"CI": "1"}}`},
+		{"fetch", `{"url": "https://example.com/a.tgz", "sha256": "00"}`},
+		{"deploy", `{"target": "prod", "replicas": 3}`},
+		{"lambda", `{"anything": [1, 2]}`},
+		{"unregistered", `{"anything": [1, 2]}`},
+	}
+	for _, tt := range valid {
+		if err := parse(doc(tt.nodeType, tt.inputs)); err != nil {
+			t.Errorf("%s %s: %v", tt.nodeType, tt.inputs, err)
+		}
+	}
+
+	invalid := []struct {
+		name, nodeType, inputs, field, hint string
+	}{
+		{"typo", "exec", `{"cmdd": "make"}`, "graph.nodes[0].inputs.cmdd", `did you mean "cmd"?`},
+		{"missing required", "fetch", `{"sha256": "00"}`, "graph.nodes[0].inputs.url", ""},
+		{"wrong type", "deploy", `{"target": "prod", "replicas": "3"}`, "graph.nodes[0].inputs.replicas", ""},
+		{"env not an object", "exec", `{"cmd": "make", "env": "CI=1"}`, "graph.nodes[0].inputs.env", ""},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parse(doc(tt.nodeType, tt.inputs))
+			var se *SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected SchemaError, got %T: %v", err, err)
+			}
+			if se.Field != tt.field {
+				t.Errorf("field = %q, want %q", se.Field, tt.field)
+			}
+			if tt.hint != "" && (len(se.Hints) == 0 || se.Hints[0].Message != tt.hint) {
+				t.Errorf("hints = %+v, want %q", se.Hints, tt.hint)
+			}
+		})
+	}
+}
+
+func TestParse_NodeTypesDoNotOutliveTheirLoad(t *testing.T) {
+	types := NewNodeTypes()
+	if err := types.Register("deploy", InputSchema{"target": {Type: InputString, Required: true}}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	doc := `{"schema_version": "2.0.0", "graph": {"nodes": [{"id": "n", "type": "deploy", "inputs": {}, "outputs": []}], "edges": []}, "metadata": {}}`
+	if _, _, err := ParseWithNodeTypes(strings.NewReader(doc), types); err == nil {
+		t.Fatal("missing required input accepted with the registry")
+	}
+	if _, err := Parse(strings.NewReader(doc)); err != nil {
+		t.Fatalf("a load without the registry checked its type: %v", err)
+	}
+}
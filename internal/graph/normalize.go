@@ -52,11 +52,23 @@ func (g *Graph) Normalized() *Graph {
 		outputs := make([]string, len(n.Outputs))
 		copy(outputs, n.Outputs)
 
+		// Copy env map
+		var env map[string]string
+		if n.Env != nil {
+			env = make(map[string]string, len(n.Env))
+			for k, v := range n.Env {
+				env[k] = v
+			}
+		}
+
 		nodes[i] = Node{
-			ID:      n.ID,
-			Type:    n.Type,
-			Inputs:  inputs,
-			Outputs: outputs,
+			ID:             n.ID,
+			Type:           n.Type,
+			Inputs:         inputs,
+			Outputs:        outputs,
+			Env:            env,
+			TimeoutSeconds: n.TimeoutSeconds,
+			Retries:        n.Retries,
 		}
 	}
 
@@ -1,49 +1,208 @@
 package graph
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"reflect"
+	"strings"
 )
 
-// SupportedSchemaVersion is the only schema version this package supports.
-const SupportedSchemaVersion = "1.0.0"
+// SupportedSchemaVersion is the latest schema version, emitted by Builder.
+// Version 2.0.0 adds the optional env, timeout_seconds and retries node
+// fields.
+const SupportedSchemaVersion = "2.0.0"
+
+// SchemaVersion1 is the original schema version, still accepted: its
+// documents are 2.0.0 documents that use none of the fields 2.0.0 added.
+const SchemaVersion1 = "1.0.0"
+
+// MaxRetries bounds Node.Retries.
+const MaxRetries = 10
 
 // Parse decodes a graph definition from JSON and validates it.
 // It returns ParseError for malformed JSON, SchemaError for missing or
-// invalid fields, and SemanticError for unsupported schema versions.
+// invalid fields, including node inputs that do not match the input schema
+// of their built-in node type (see ParseWithNodeTypes), and SemanticError for
+// unsupported schema versions.
+// ParseError and SchemaError locate the error in the source by line, column
+// and JSON path. Deprecated fields are accepted; ParseWithWarnings also
+// reports their uses.
 func Parse(r io.Reader) (*Document, error) {
-	dec := json.NewDecoder(r)
-	dec.DisallowUnknownFields()
+	doc, _, err := ParseWithWarnings(r)
+	return doc, err
+}
 
+func parse(data []byte, types *NodeTypes) (*Document, error) {
+	data, err := expandDefinitions(data)
+	if err != nil {
+		return nil, err
+	}
 	var doc Document
-	if err := dec.Decode(&doc); err != nil {
+	if err := decodeStrict(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if err := validateDocument(&doc, types); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// validateDocument checks a decoded document beyond what decoding does.
+func validateDocument(doc *Document, types *NodeTypes) error {
+	// Validate required fields
+	if err := validateRequired(doc); err != nil {
+		return err
+	}
+
+	// Validate schema version
+	if doc.SchemaVersion != SupportedSchemaVersion && doc.SchemaVersion != SchemaVersion1 {
+		return &SemanticError{
+			Msg: fmt.Sprintf("unsupported schema_version %q, expected %q or %q", doc.SchemaVersion, SupportedSchemaVersion, SchemaVersion1),
+			// Every 1.0.0 document is a valid 2.0.0 document, so there is
+			// nothing to migrate; the document only needs to declare the latest.
+			Hints: []Hint{{Code: HintSchemaVersion, Message: fmt.Sprintf("set schema_version to %q", SupportedSchemaVersion)}},
+		}
+	}
+
+	// Validate the fields introduced by schema 2.0.0
+	if err := validateNodeFields(doc); err != nil {
+		return err
+	}
+
+	// Validate the inputs of node types with an input schema
+	if err := validateNodeInputs(doc, types); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// decodeStrict decodes data into v, a pointer, rejecting unknown fields. It
+// returns SchemaError for a value of the wrong type and ParseError otherwise.
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
 		// Check if this is a type error (wrong field type)
-		if _, ok := err.(*json.UnmarshalTypeError); ok {
-			return nil, &SchemaError{Msg: fmt.Sprintf("invalid field type: %v", err)}
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			// The decoder reports the offset just past the start of the
+			// mistyped value, which names its field.
+			field := indexSource(data, reflect.TypeOf(v).Elem()).enclosing(typeErr.Offset)
+			return &SchemaError{Field: field, Msg: fmt.Sprintf("invalid field type: %v", err)}
 		}
-		// Check if this is an unknown field error
+		// Check if this is a syntax error
 		if syntaxErr, ok := err.(*json.SyntaxError); ok {
-			return nil, &ParseError{Msg: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset), Err: err}
+			return &ParseError{Msg: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset), Err: err}
 		}
 		// Unknown field errors from DisallowUnknownFields come as generic errors
 		// containing "unknown field"
-		return nil, &ParseError{Msg: err.Error(), Err: err}
+		return &ParseError{Msg: err.Error(), Err: err}
 	}
+	return nil
+}
 
-	// Validate required fields
-	if err := validateRequired(&doc); err != nil {
-		return nil, err
+// DecodeStrict decodes data, a single JSON value, into v, a pointer, as
+// Parse decodes graph documents, for the other graph formats to report errors
+// as Parse does: unknown fields and data after the value are rejected with a
+// ParseError, and values of the wrong type with a SchemaError, either locating
+// the error by line, column and JSON path.
+func DecodeStrict(data []byte, v any) error {
+	err := decodeStrict(data, v)
+	if err == nil {
+		err = checkTrailing(data)
+	}
+	if err != nil {
+		return locateIn(indexSource(data, reflect.TypeOf(v).Elem()), err)
 	}
+	return nil
+}
 
-	// Validate schema version
-	if doc.SchemaVersion != SupportedSchemaVersion {
-		return nil, &SemanticError{
-			Msg: fmt.Sprintf("unsupported schema_version %q, expected %q", doc.SchemaVersion, SupportedSchemaVersion),
+// checkTrailing returns a located ParseError when data holds anything but
+// whitespace after its first JSON value.
+func checkTrailing(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var first json.RawMessage
+	if err := dec.Decode(&first); err != nil {
+		return &ParseError{Msg: err.Error(), Err: err}
+	}
+	end := skipSeparators(data, dec.InputOffset())
+	var trailing json.RawMessage
+	switch err := dec.Decode(&trailing); {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return &ParseError{Msg: "trailing data", Err: err}
+	default:
+		return &ParseError{Msg: "trailing data", Pos: (&sourceIndex{data: data}).position(end)}
+	}
+}
+
+var documentType = reflect.TypeOf(Document{})
+
+// locateError sets the source location of a ParseError or SchemaError
+// returned for data. The source is only scanned once decoding failed. A field
+// of a node that extends definitions is located at the node when the node
+// does not set it itself.
+func locateError(data []byte, err error) error {
+	return locateIn(indexSource(data, sourceDocumentType), err)
+}
+
+// locateIn sets the location of a ParseError or SchemaError in the source
+// idx indexes.
+func locateIn(idx *sourceIndex, err error) error {
+	switch e := err.(type) {
+	case *ParseError:
+		var syntaxErr *json.SyntaxError
+		switch {
+		case errors.As(e.Err, &syntaxErr):
+			// The offending byte is the last one read.
+			e.Path, e.Pos = idx.stopped, idx.position(max(syntaxErr.Offset-1, 0))
+		case idx.hasUnknown:
+			e.Path, e.Pos = idx.unknown, idx.position(idx.unknownPos)
 		}
+	case *SchemaError:
+		e.Pos = idx.locate(e.Field)
 	}
+	return err
+}
 
-	return &doc, nil
+// validateNodeFields checks the env, timeout_seconds and retries node fields,
+// which a 1.0.0 document must not use.
+func validateNodeFields(doc *Document) error {
+	for i, node := range doc.Graph.Nodes {
+		field := func(name string) string { return fmt.Sprintf("graph.nodes[%d].%s", i, name) }
+		if doc.SchemaVersion == SchemaVersion1 {
+			name := ""
+			switch {
+			case node.Env != nil:
+				name = "env"
+			case node.TimeoutSeconds != 0:
+				name = "timeout_seconds"
+			case node.Retries != 0:
+				name = "retries"
+			}
+			if name != "" {
+				return &SchemaError{Field: field(name), Msg: fmt.Sprintf("requires schema_version %q", SupportedSchemaVersion)}
+			}
+			continue
+		}
+		for k := range node.Env {
+			if k == "" || strings.Contains(k, "=") {
+				return &SchemaError{Field: field("env"), Msg: fmt.Sprintf("invalid variable name %q", k)}
+			}
+		}
+		if node.TimeoutSeconds < 0 {
+			return &SchemaError{Field: field("timeout_seconds"), Msg: "must not be negative"}
+		}
+		if node.Retries < 0 || node.Retries > MaxRetries {
+			return &SchemaError{Field: field("retries"), Msg: fmt.Sprintf("must be between 0 and %d", MaxRetries)}
+		}
+	}
+	return nil
 }
 
 // validateRequired checks that all required fields are present.
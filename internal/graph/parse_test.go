@@ -165,7 +165,7 @@ func TestParse_IncorrectTypeForSchemaVersion(t *testing.T) {
 
 func TestParse_UnsupportedVersion(t *testing.T) {
 	json := `{
-		"schema_version": "2.0.0",
+		"schema_version": "3.0.0",
 		"graph": {"nodes": [], "edges": []},
 		"metadata": {}
 	}`
@@ -284,3 +284,120 @@ func TestParse_MissingEdgeFields(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_SchemaV2NodeFields(t *testing.T) {
+	doc := func(version, fields string) string {
+		return `{
+			"schema_version": "` + version + `",
+			"graph": {"nodes": [{"id": "a", "type": "t", "inputs": {}, "outputs": []` + fields + `}], "edges": []},
+			"metadata": {}
+		}`
+	}
+
+	got, err := Parse(strings.NewReader(doc("2.0.0", `, "env": {"MODE": "ci"}, "timeout_seconds": 30, "retries": 2`)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	n := got.Graph.Nodes[0]
+	if n.Env["MODE"] != "ci" || n.TimeoutSeconds != 30 || n.Retries != 2 {
+		t.Fatalf("node = %+v", n)
+	}
+	if _, err := Parse(strings.NewReader(doc("1.0.0", ""))); err != nil {
+		t.Fatalf("1.0.0 document: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		fields  string
+		field   string
+	}{
+		{"env in 1.0.0", "1.0.0", `, "env": {}`, "graph.nodes[0].env"},
+		{"timeout in 1.0.0", "1.0.0", `, "timeout_seconds": 5`, "graph.nodes[0].timeout_seconds"},
+		{"retries in 1.0.0", "1.0.0", `, "retries": 1`, "graph.nodes[0].retries"},
+		{"empty env name", "2.0.0", `, "env": {"": "x"}`, "graph.nodes[0].env"},
+		{"env name with =", "2.0.0", `, "env": {"A=B": "x"}`, "graph.nodes[0].env"},
+		{"negative timeout", "2.0.0", `, "timeout_seconds": -1`, "graph.nodes[0].timeout_seconds"},
+		{"too many retries", "2.0.0", `, "retries": 11`, "graph.nodes[0].retries"},
+		{"non-string env value", "2.0.0", `, "env": {"A": 1}`, "graph.nodes[0].env.A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(doc(tt.version, tt.fields)))
+			var se *SchemaError
+			if !errors.As(err, &se) {
+				t.Fatalf("expected SchemaError, got %T: %v", err, err)
+			}
+			if se.Field != tt.field {
+				t.Errorf("field = %q, want %q", se.Field, tt.field)
+			}
+		})
+	}
+}
+
+func TestParse_ErrorsCarryLocation(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		path string
+		line int
+		col  int
+	}{
+		{"syntax error", "{\n  \"schema_version\": \"1.0.0\",\n  \"graph\": {\"nodes\": [}\n}", "graph.nodes[0]", 3, 23},
+		{"unknown field", "{\n  \"schema_version\": \"1.0.0\",\n  \"graph\": {\n    \"nodes\": [{\"id\": \"a\", \"type\": \"t\", \"inputs\": {}, \"outputs\": [], \"bogus\": 1}],\n    \"edges\": []\n  },\n  \"metadata\": {}\n}", "graph.nodes[0].bogus", 4, 69},
+		{"missing field", "{\n  \"schema_version\": \"1.0.0\",\n  \"graph\": {\n    \"nodes\": [\n      {\"id\": \"a\", \"type\": \"t\", \"outputs\": []}\n    ],\n    \"edges\": []\n  },\n  \"metadata\": {}\n}", "graph.nodes[0].inputs", 5, 7},
+		{"wrong type", "{\n  \"schema_version\": \"1.0.0\",\n  \"graph\": {\"nodes\": [], \"edges\": []},\n  \"metadata\": {\"name\": 7}\n}", "metadata.name", 4, 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.json))
+			var path string
+			var pos Position
+			var pe *ParseError
+			var se *SchemaError
+			switch {
+			case errors.As(err, &pe):
+				path, pos = pe.Path, pe.Pos
+			case errors.As(err, &se):
+				path, pos = se.Field, se.Pos
+			default:
+				t.Fatalf("unexpected error %T: %v", err, err)
+			}
+			if path != tt.path || pos.Line != tt.line || pos.Column != tt.col {
+				t.Fatalf("located at %q %s, want %q line %d, column %d", path, pos, tt.path, tt.line, tt.col)
+			}
+			if !strings.Contains(err.Error(), pos.String()) {
+				t.Errorf("error %q does not mention %s", err, pos)
+			}
+		})
+	}
+}
+
+func TestDecodeStrict_ErrorsCarryLocation(t *testing.T) {
+	type task struct {
+		Name string `json:"name"`
+	}
+	type file struct {
+		Tasks []task `json:"tasks"`
+	}
+	tests := []struct {
+		name string
+		json string
+		path string
+		line int
+		col  int
+	}{
+		{"unknown field", "{\"tasks\": [\n  {\"name\": \"a\", \"bogus\": 1}\n]}", "tasks[0].bogus", 2, 17},
+		{"wrong type", "{\"tasks\": [\n  {\"name\": 7}\n]}", "tasks[0].name", 2, 12},
+		{"trailing data", "{\"tasks\": []}\n{}", "", 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DecodeStrict([]byte(tt.json), &file{})
+			path, pos := LocationOf(err)
+			if err == nil || path != tt.path || pos.Line != tt.line || pos.Column != tt.col {
+				t.Fatalf("located at %q %s, want %q line %d, column %d (%v)", path, pos, tt.path, tt.line, tt.col, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Position locates an error in the source of a document. The zero value
+// means the location is unknown.
+type Position struct {
+	Offset int64 // Byte offset from the start of the document
+	Line   int   // 1-based line
+	Column int   // 1-based column, in bytes
+}
+
+// String returns "line L, column C", or "" for an unknown position.
+func (p Position) String() string {
+	if p.Line == 0 {
+		return ""
+	}
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+// sourceIndex maps the JSON paths of a document, such as
+// "graph.nodes[3].inputs", to the byte spans of their values.
+type sourceIndex struct {
+	data  []byte
+	spans map[string]span
+	// stopped is the path of the value being scanned when the document
+	// turned out malformed.
+	stopped string
+	// unknown is the first key, in document order, that names no field of
+	// the struct it decodes into; DisallowUnknownFields rejects that key.
+	unknown    string
+	unknownPos int64
+	hasUnknown bool
+}
+
+type span struct{ start, end int64 }
+
+type scanFrame struct {
+	path  string
+	start int64
+	array bool
+	n     int
+	key   string
+	// expectKey is set in an object whenever the next token is a key.
+	expectKey bool
+	typ       reflect.Type
+}
+
+// indexSource scans data, a document decoding into a value of type root.
+// Scanning stops at the first syntax error, leaving the spans of the values
+// read so far.
+func indexSource(data []byte, root reflect.Type) *sourceIndex {
+	idx := &sourceIndex{data: data, spans: make(map[string]span)}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var stack []*scanFrame
+	// next is the path and type of the value the next token starts.
+	next := func() (string, reflect.Type) {
+		if len(stack) == 0 {
+			return "", root
+		}
+		f := stack[len(stack)-1]
+		if f.array {
+			return fmt.Sprintf("%s[%d]", f.path, f.n), elemType(f.typ)
+		}
+		path := f.key
+		if f.path != "" {
+			path = f.path + "." + f.key
+		}
+		return path, fieldType(f.typ, f.key)
+	}
+	valueDone := func() bool {
+		if len(stack) == 0 {
+			return true
+		}
+		f := stack[len(stack)-1]
+		if f.array {
+			f.n++
+		} else {
+			f.expectKey = true
+		}
+		return false
+	}
+	for {
+		start := skipSeparators(data, dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			idx.stopped, _ = next()
+			if n := len(stack); n > 0 && stack[n-1].expectKey {
+				idx.stopped = stack[n-1].path
+			}
+			return idx
+		}
+		if n := len(stack); n > 0 && stack[n-1].expectKey {
+			if key, ok := tok.(string); ok {
+				f := stack[n-1]
+				f.key, f.expectKey = key, false
+				if f.typ != nil && f.typ.Kind() == reflect.Struct && fieldType(f.typ, key) == nil && !idx.hasUnknown {
+					idx.unknown, idx.unknownPos, idx.hasUnknown = joinPath(f.path, key), start, true
+				}
+				continue
+			}
+		}
+		if d, ok := tok.(json.Delim); ok {
+			switch d {
+			case '{', '[':
+				path, typ := next()
+				stack = append(stack, &scanFrame{path: path, start: start, array: d == '[', expectKey: d == '{', typ: indirect(typ)})
+				continue
+			default:
+				f := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				idx.spans[f.path] = span{f.start, dec.InputOffset()}
+				if valueDone() {
+					return idx
+				}
+				continue
+			}
+		}
+		path, _ := next()
+		idx.spans[path] = span{start, dec.InputOffset()}
+		if valueDone() {
+			return idx
+		}
+	}
+}
+
+// skipSeparators returns the offset of the first byte at or after off that
+// is not whitespace or a separator, which is where the next token starts.
+func skipSeparators(data []byte, off int64) int64 {
+	for off < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// indirect returns the type pointers of type t point to, or t.
+func indirect(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// elemType returns the type of the elements of a slice type, or nil.
+func elemType(t reflect.Type) reflect.Type {
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		return t.Elem()
+	}
+	return nil
+}
+
+// fieldType returns the type key decodes into in a value of type t: the
+// field of a struct whose JSON name matches key as encoding/json matches it,
+// or the element of a map. It is nil when nothing matches or t is unknown.
+func fieldType(t reflect.Type, key string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		var folded reflect.Type
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous && f.Tag.Get("json") == "" {
+				if ft := fieldType(f.Type, key); ft != nil {
+					return ft
+				}
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if name == key {
+				return f.Type
+			}
+			if folded == nil && strings.EqualFold(name, key) {
+				folded = f.Type
+			}
+		}
+		return folded
+	}
+	return nil
+}
+
+// position converts a byte offset of the document into a Position.
+func (idx *sourceIndex) position(off int64) Position {
+	if off < 0 || off > int64(len(idx.data)) {
+		return Position{}
+	}
+	before := idx.data[:off]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndexByte(before, '\n')
+	return Position{Offset: off, Line: line, Column: col}
+}
+
+// locate returns the position of the value at path, or, when the document
+// lacks it, of its nearest enclosing value.
+func (idx *sourceIndex) locate(path string) Position {
+	for {
+		if s, ok := idx.spans[path]; ok {
+			return idx.position(s.start)
+		}
+		if path == "" {
+			return Position{}
+		}
+		path = parentPath(path)
+	}
+}
+
+// enclosing returns the path of the innermost value whose span contains off.
+func (idx *sourceIndex) enclosing(off int64) string {
+	best, size := "", int64(-1)
+	for path, s := range idx.spans {
+		if s.start < off && off <= s.end && (size < 0 || s.end-s.start < size) {
+			best, size = path, s.end-s.start
+		}
+	}
+	return best
+}
+
+// parentPath strips the last key or index of path.
+func parentPath(path string) string {
+	if i := strings.LastIndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
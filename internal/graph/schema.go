@@ -15,11 +15,18 @@ type Graph struct {
 }
 
 // Node represents a single execution unit in the graph.
+//
+// Env, TimeoutSeconds and Retries are optional and require schema 2.0.0.
+// TimeoutSeconds bounds each execution of the node, 0 meaning no timeout;
+// Retries is how many times a failed execution is repeated.
 type Node struct {
-	ID      string         `json:"id"`
-	Type    string         `json:"type"`
-	Inputs  map[string]any `json:"inputs"`
-	Outputs []string       `json:"outputs"`
+	ID             string            `json:"id"`
+	Type           string            `json:"type"`
+	Inputs         map[string]any    `json:"inputs"`
+	Outputs        []string          `json:"outputs"`
+	Env            map[string]string `json:"env,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+	Retries        int               `json:"retries,omitempty"`
 }
 
 // Edge defines a directed dependency between two nodes.
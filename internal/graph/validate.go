@@ -27,6 +27,10 @@ func Validate(g *Graph) error {
 		}
 		nodeIDs[node.ID] = true
 	}
+	ids := make([]string, len(sortedNodes))
+	for i, node := range sortedNodes {
+		ids[i] = node.ID
+	}
 
 	// Sort edges for deterministic error reporting
 	sortedEdges := make([]Edge, len(g.Edges))
@@ -51,15 +55,17 @@ func Validate(g *Graph) error {
 		// Dangling edge check - 'from' must exist
 		if !nodeIDs[edge.From] {
 			return &StructuralError{
-				Kind: "dangling_edge",
-				Msg:  fmt.Sprintf("edge references unknown node: %q", edge.From),
+				Kind:  "dangling_edge",
+				Msg:   fmt.Sprintf("edge references unknown node: %q", edge.From),
+				Hints: DidYouMean(edge.From, ids),
 			}
 		}
 		// Dangling edge check - 'to' must exist
 		if !nodeIDs[edge.To] {
 			return &StructuralError{
-				Kind: "dangling_edge",
-				Msg:  fmt.Sprintf("edge references unknown node: %q", edge.To),
+				Kind:  "dangling_edge",
+				Msg:   fmt.Sprintf("edge references unknown node: %q", edge.To),
+				Hints: DidYouMean(edge.To, ids),
 			}
 		}
 		adjacency[edge.From] = append(adjacency[edge.From], edge.To)
@@ -75,6 +75,9 @@ func equalNodeSnapshot(a, b NodeSnapshot) bool {
 	if !equalStringSet(a.Upstream, b.Upstream) {
 		return false
 	}
+	if !equalStringSet(a.OrderUpstream, b.OrderUpstream) {
+		return false
+	}
 	if !equalStringMap(a.Env, b.Env) {
 		return false
 	}
@@ -260,9 +260,15 @@ type NodeSnapshot struct {
 	// It is treated as a set for identity.
 	Outputs []string
 
-	// Upstream is the list of direct dependency node names.
+	// Upstream is the list of direct dependency node names across data edges.
 	// It is treated as a set for identity.
 	Upstream []string
+
+	// OrderUpstream is the list of direct dependency node names across
+	// order-only edges (see dag.EdgeOrder). No artifact flows across them, so
+	// they neither propagate invalidation nor invalidate the node when they
+	// change. It is treated as a set.
+	OrderUpstream []string
 }
 
 // GraphSnapshot represents the minimal information needed to compute an incremental invalidation plan.
@@ -319,8 +325,9 @@ func (m InvalidationMap) MarshalBinary() ([]byte, error) {
 
 // CalculateInvalidation computes which nodes in newGraph are invalidated relative to oldGraph.
 //
-// Invalidation is strictly transitive: if A is invalidated, every downstream dependent of A
-// in the new graph is invalidated as well.
+// Invalidation is strictly transitive across data edges: if A is invalidated, every
+// downstream dependent of A in the new graph is invalidated as well. Order-only
+// dependencies (NodeSnapshot.OrderUpstream) are ignored.
 func CalculateInvalidation(oldGraph, newGraph *GraphSnapshot) InvalidationMap {
 	result := make(InvalidationMap)
 	if newGraph == nil || len(newGraph.Nodes) == 0 {
@@ -317,3 +317,39 @@ func TestInvalidationMap_DeterministicSerialization_IgnoresMapOrder(t *testing.T
 		t.Fatalf("expected identical bytes for maps with same content")
 	}
 }
+
+func TestCalculateInvalidation_OrderOnlyUpstreamDoesNotPropagate(t *testing.T) {
+	oldGraph := &GraphSnapshot{Nodes: map[string]NodeSnapshot{
+		"migrate": {Name: "migrate", Command: "migrate v1"},
+		"build":   {Name: "build", Command: "build"},
+		"test":    {Name: "test", Command: "test", Upstream: []string{"build"}, OrderUpstream: []string{"migrate"}},
+	}}
+	newGraph := &GraphSnapshot{Nodes: map[string]NodeSnapshot{
+		"migrate": {Name: "migrate", Command: "migrate v2"},
+		"build":   {Name: "build", Command: "build"},
+		"test":    {Name: "test", Command: "test", Upstream: []string{"build"}, OrderUpstream: []string{"migrate"}},
+	}}
+
+	inv := CalculateInvalidation(oldGraph, newGraph)
+	if !inv["migrate"].Invalidated {
+		t.Fatalf("expected migrate invalidated")
+	}
+	if inv["test"].Invalidated {
+		t.Fatalf("order-only upstream must not invalidate test: %+v", inv["test"].Reasons)
+	}
+
+	// The same change across a data edge does propagate.
+	newGraph.Nodes["test"] = NodeSnapshot{Name: "test", Command: "test", Upstream: []string{"build", "migrate"}}
+	oldGraph.Nodes["test"] = newGraph.Nodes["test"]
+	inv = CalculateInvalidation(oldGraph, newGraph)
+	if r := inv["test"].Reasons; !inv["test"].Invalidated || len(r) != 1 || r[0].Type != ReasonTypeDependencyInvalidated || r[0].SourceTaskID != "migrate" {
+		t.Fatalf("expected test invalidated by migrate, got %+v", inv["test"])
+	}
+
+	// Adding or removing order-only edges changes nothing but scheduling.
+	oldGraph.Nodes["test"] = NodeSnapshot{Name: "test", Command: "test", Upstream: []string{"build"}}
+	newGraph.Nodes["test"] = NodeSnapshot{Name: "test", Command: "test", Upstream: []string{"build"}, OrderUpstream: []string{"migrate"}}
+	if inv = CalculateInvalidation(oldGraph, newGraph); inv["test"].Invalidated {
+		t.Fatalf("adding an order-only edge must not invalidate test: %+v", inv["test"].Reasons)
+	}
+}
@@ -12,12 +12,14 @@ import (
 // NodeExecutionDecision represents the deterministic plan decision for a task.
 //
 // From docs/sprints/sprint-02/in-process/incremental-engine/tdd.md:
-// decisions are strictly Execute or ReuseCache (no Skip state).
+// decisions are Execute or ReuseCache. Skip is only ever decided on the
+// user's explicit request (see dag.Executor.Skip), never by planning.
 type NodeExecutionDecision string
 
 const (
 	DecisionExecute    NodeExecutionDecision = "Execute"
 	DecisionReuseCache NodeExecutionDecision = "ReuseCache"
+	DecisionSkip       NodeExecutionDecision = "Skip"
 )
 
 // IncrementalPlan maps every node name to a deterministic execution decision.
@@ -0,0 +1,155 @@
+// Package notify posts run outcomes to chat incoming webhooks, Slack's and
+// Microsoft Teams', as configured by the workspace's notify settings (see
+// config.NotifyConfig).
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"scriptweaver/internal/projectintegration/engine/config"
+)
+
+// Run statuses, as substituted for {{status}}.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// DefaultTemplate is the message used when the configuration sets none.
+// When nodes failed, they are listed on a second line.
+const DefaultTemplate = "ScriptWeaver run {{run_id}} of {{graph}} {{status}} with exit code {{exit_code}} in {{duration}}"
+
+// Timeout bounds a webhook request.
+const Timeout = 10 * time.Second
+
+// Outcome is what a notification reports about a run.
+type Outcome struct {
+	RunID    string
+	Graph    string
+	ExitCode int
+	// FailedNodes are the nodes that failed, sorted.
+	FailedNodes []string
+	Duration    time.Duration
+	// PreviousFailed is set when the previous run of the same graph failed.
+	PreviousFailed bool
+}
+
+// Status returns StatusSucceeded or StatusFailed.
+func (o Outcome) Status() string {
+	if o.ExitCode == 0 {
+		return StatusSucceeded
+	}
+	return StatusFailed
+}
+
+// ShouldNotify applies the notify-on policy on to o.
+func ShouldNotify(on string, o Outcome) bool {
+	failed := o.ExitCode != 0
+	switch on {
+	case config.NotifyAlways:
+		return true
+	case config.NotifyRecovery:
+		return failed || o.PreviousFailed
+	default:
+		return failed
+	}
+}
+
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_]+)\s*\}\}`)
+
+// Message renders the message and the report link of o per cfg.
+func Message(cfg config.NotifyConfig, o Outcome) (text, link string) {
+	link = expand(cfg.Link, map[string]string{"run_id": url.PathEscape(o.RunID)})
+	fields := map[string]string{
+		"run_id":       o.RunID,
+		"status":       o.Status(),
+		"exit_code":    strconv.Itoa(o.ExitCode),
+		"graph":        o.Graph,
+		"failed_nodes": strings.Join(o.FailedNodes, ", "),
+		"duration":     o.Duration.Round(time.Millisecond).String(),
+		"link":         link,
+	}
+	if cfg.Template != "" {
+		return expand(cfg.Template, fields), link
+	}
+	text = expand(DefaultTemplate, fields)
+	if len(o.FailedNodes) > 0 {
+		text += "\nFailed nodes: " + fields["failed_nodes"]
+	}
+	return text, link
+}
+
+func expand(tmpl string, fields map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		return fields[placeholder.FindStringSubmatch(m)[1]]
+	})
+}
+
+// Payload returns the webhook request body of text and link in format.
+func Payload(format, text, link string) ([]byte, error) {
+	switch format {
+	case config.NotifySlack:
+		// Slack reserves &, < and > for its link and mention syntax.
+		text = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+		if link != "" {
+			text += "\n<" + link + "|View report>"
+		}
+		return json.Marshal(map[string]string{"text": text})
+	case config.NotifyTeams:
+		summary, _, _ := strings.Cut(text, "\n")
+		card := map[string]any{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  summary,
+			// Teams renders markdown, where a single newline is not a break.
+			"text": strings.ReplaceAll(text, "\n", "\n\n"),
+		}
+		if link != "" {
+			card["potentialAction"] = []any{map[string]any{
+				"@type":   "OpenUri",
+				"name":    "View report",
+				"targets": []any{map[string]string{"os": "default", "uri": link}},
+			}}
+		}
+		return json.Marshal(card)
+	default:
+		return nil, fmt.Errorf("unknown notification format %q", format)
+	}
+}
+
+// Send posts payload to webhookURL. Errors never include the URL, which is
+// a secret.
+func Send(ctx context.Context, client *http.Client, webhookURL string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return errors.New("notify: invalid webhook URL")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("notify: post: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("notify: webhook answered %s", resp.Status)
+	}
+	return nil
+}
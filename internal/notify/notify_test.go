@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"scriptweaver/internal/projectintegration/engine/config"
+)
+
+func TestShouldNotify(t *testing.T) {
+	ok := Outcome{}
+	failed := Outcome{ExitCode: 1}
+	recovered := Outcome{PreviousFailed: true}
+	for _, c := range []struct {
+		on   string
+		o    Outcome
+		want bool
+	}{
+		{config.NotifyAlways, ok, true},
+		{config.NotifyFailure, ok, false},
+		{config.NotifyFailure, failed, true},
+		{config.NotifyFailure, recovered, false},
+		{config.NotifyRecovery, ok, false},
+		{config.NotifyRecovery, failed, true},
+		{config.NotifyRecovery, recovered, true},
+	} {
+		if got := ShouldNotify(c.on, c.o); got != c.want {
+			t.Errorf("ShouldNotify(%s, %+v) = %v, want %v", c.on, c.o, got, c.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	o := Outcome{RunID: "01J/X", Graph: "graph.json", ExitCode: 1, FailedNodes: []string{"lint", "test"}, Duration: 1500 * time.Millisecond}
+	text, link := Message(config.NotifyConfig{Link: "http://127.0.0.1:7070/#{{run_id}}"}, o)
+	if text != "ScriptWeaver run 01J/X of graph.json failed with exit code 1 in 1.5s\nFailed nodes: lint, test" {
+		t.Fatalf("text = %q", text)
+	}
+	if link != "http://127.0.0.1:7070/#01J%2FX" {
+		t.Fatalf("link = %q", link)
+	}
+	text, _ = Message(config.NotifyConfig{Template: "{{ status }}: {{failed_nodes}} ({{link}})"}, o)
+	if text != "failed: lint, test ()" {
+		t.Fatalf("template text = %q", text)
+	}
+}
+
+func TestPayload(t *testing.T) {
+	b, err := Payload(config.NotifySlack, "a <b> & c", "http://h/#1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slack map[string]string
+	if err := json.Unmarshal(b, &slack); err != nil || slack["text"] != "a &lt;b&gt; &amp; c\n<http://h/#1|View report>" {
+		t.Fatalf("slack = %s, %v", b, err)
+	}
+
+	b, err = Payload(config.NotifyTeams, "first\nsecond", "http://h/#1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var teams struct {
+		Type    string `json:"@type"`
+		Summary string `json:"summary"`
+		Text    string `json:"text"`
+		Actions []struct {
+			Targets []struct{ URI string } `json:"targets"`
+		} `json:"potentialAction"`
+	}
+	if err := json.Unmarshal(b, &teams); err != nil || teams.Type != "MessageCard" || teams.Summary != "first" || teams.Text != "first\n\nsecond" ||
+		len(teams.Actions) != 1 || teams.Actions[0].Targets[0].URI != "http://h/#1" {
+		t.Fatalf("teams = %s, %v", b, err)
+	}
+
+	if _, err := Payload("irc", "x", ""); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = r.Header.Get("Content-Type") + " " + string(b)
+		if r.URL.Path == "/secret-token/broken" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	if err := Send(context.Background(), srv.Client(), srv.URL+"/secret-token", []byte(`{"text":"hi"}`)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got != `application/json {"text":"hi"}` {
+		t.Fatalf("request = %q", got)
+	}
+	err := Send(context.Background(), srv.Client(), srv.URL+"/secret-token/broken", []byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "403") || strings.Contains(err.Error(), "secret-token") {
+		t.Fatalf("Send to a rejecting webhook = %v", err)
+	}
+	srv.Close()
+	err = Send(context.Background(), srv.Client(), srv.URL+"/secret-token", []byte(`{}`))
+	if err == nil || strings.Contains(err.Error(), "secret-token") {
+		t.Fatalf("Send to a closed server = %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"scriptweaver/internal/core"
+)
+
+// DigestMeta is the user metadata under which every entry object records the
+// hex SHA-256 of its content.
+const DigestMeta = "sha256"
+
+// Location is a bucket and key prefix in an object store.
+type Location struct {
+	// Scheme is "s3" or "gs".
+	Scheme string
+	Bucket string
+	Prefix string
+}
+
+// IsURL reports whether s names an object store location rather than a
+// directory.
+func IsURL(s string) bool {
+	return strings.HasPrefix(s, "s3://") || strings.HasPrefix(s, "gs://")
+}
+
+// ParseURL parses an s3://bucket/prefix or gs://bucket/prefix URL.
+func ParseURL(s string) (Location, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return Location{}, fmt.Errorf("object store url %q: %w", s, err)
+	}
+	if u.Scheme != "s3" && u.Scheme != "gs" {
+		return Location{}, fmt.Errorf("object store url %q: scheme must be s3 or gs", s)
+	}
+	if u.Host == "" {
+		return Location{}, fmt.Errorf("object store url %q: bucket is required", s)
+	}
+	return Location{Scheme: u.Scheme, Bucket: u.Host, Prefix: strings.Trim(u.Path, "/")}, nil
+}
+
+// DefaultEndpoint is the endpoint of the store l lives in, for region.
+func (l Location) DefaultEndpoint(region string) string {
+	if l.Scheme == "gs" {
+		return "https://storage.googleapis.com"
+	}
+	return "https://s3." + region + ".amazonaws.com"
+}
+
+// Cache is a core.Cache that stores each entry as one object,
+// <Prefix>/<digest[0:2]>/<algorithm>-<digest>.json, holding the entry's JSON. The object's
+// SHA-256 is recorded in its metadata when it is written and verified when it
+// is read, so an entry damaged in storage or in transit is reported as cache
+// corruption instead of being replayed. Entries written under the bare
+// SHA-256 digest, before hashes were tagged, are still read.
+type Cache struct {
+	Client *Client
+	Prefix string
+}
+
+// NewCache creates a Cache storing entries under prefix.
+func NewCache(client *Client, prefix string) *Cache {
+	return &Cache{Client: client, Prefix: strings.Trim(prefix, "/")}
+}
+
+func (c *Cache) key(hash core.TaskHash) string {
+	return c.shardedKey(hash.Digest(), string(hash.Algorithm())+"-"+hash.Digest())
+}
+
+// keys returns the keys the entry for hash may be stored under, the current
+// one first.
+func (c *Cache) keys(hash core.TaskHash) []string {
+	keys := []string{c.key(hash)}
+	if legacy := string(hash.Legacy()); legacy != "" {
+		keys = append(keys, c.shardedKey(legacy, legacy))
+	}
+	return keys
+}
+
+func (c *Cache) shardedKey(digest, name string) string {
+	if len(digest) >= 2 {
+		return path.Join(c.Prefix, digest[:2], name+".json")
+	}
+	return path.Join(c.Prefix, name+".json")
+}
+
+// Has checks if an entry exists for hash.
+func (c *Cache) Has(hash core.TaskHash) (bool, error) {
+	for _, key := range c.keys(hash) {
+		if ok, err := c.Client.Head(context.Background(), key); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// batchConcurrency bounds the requests HasBatch has in flight.
+const batchConcurrency = 16
+
+// HasBatch checks for the entries of hashes with concurrent requests, so that
+// checking many entries costs about one round-trip.
+func (c *Cache) HasBatch(hashes []core.TaskHash) (map[core.TaskHash]bool, error) {
+	found := make([]bool, len(hashes))
+	errs := make([]error, len(hashes))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, h := range hashes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, h core.TaskHash) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			found[i], errs[i] = c.Has(h)
+		}(i, h)
+	}
+	wg.Wait()
+	out := make(map[core.TaskHash]bool, len(hashes))
+	for i, h := range hashes {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		out[h] = found[i]
+	}
+	return out, nil
+}
+
+// Get retrieves and verifies the entry for hash, or returns nil when there is
+// none.
+func (c *Cache) Get(hash core.TaskHash) (*core.CacheEntry, error) {
+	var (
+		key  string
+		data []byte
+		meta map[string]string
+		err  error
+	)
+	for _, key = range c.keys(hash) {
+		data, meta, err = c.Client.Get(context.Background(), key)
+		if !errors.Is(err, ErrNotFound) {
+			break
+		}
+	}
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if got := hexSHA256(data); meta[DigestMeta] != got {
+		return nil, &core.CacheCorruptionError{Hash: hash, Path: key, Want: meta[DigestMeta], Got: got}
+	}
+	var entry core.CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("parsing cache entry %s: %w", key, err)
+	}
+	entry.Hash = hash
+	return &entry, nil
+}
+
+// Put stores entry with its digest.
+func (c *Cache) Put(entry *core.CacheEntry) error {
+	if entry == nil {
+		return fmt.Errorf("cache entry is nil")
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return c.Client.Put(context.Background(), c.key(entry.Hash), data, map[string]string{DigestMeta: hexSHA256(data)})
+}
+
+// PutIfAbsent stores entry unless an object already holds it: it checks
+// first, so that an entry uploaded by another run is not uploaded again, and
+// writes conditionally, so that of concurrent uploads only the first is kept.
+func (c *Cache) PutIfAbsent(entry *core.CacheEntry) (bool, error) {
+	if entry == nil {
+		return false, fmt.Errorf("cache entry is nil")
+	}
+	if ok, err := c.Has(entry.Hash); err != nil || ok {
+		return false, err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return false, err
+	}
+	err = c.Client.PutIfAbsent(context.Background(), c.key(entry.Hash), data, map[string]string{DigestMeta: hexSHA256(data)})
+	if errors.Is(err, ErrExists) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// TransferStats returns the traffic of the cache's client.
+func (c *Cache) TransferStats() core.TransferStats {
+	return c.Client.TransferStats()
+}
+
+// Evict removes the entry for hash.
+func (c *Cache) Evict(hash core.TaskHash) error {
+	for _, key := range c.keys(hash) {
+		if err := c.Client.Delete(context.Background(), key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,417 @@
+// Package objectstore stores cache entries in cloud object stores: Amazon S3,
+// Google Cloud Storage and S3-compatible stores such as MinIO. It speaks the
+// S3 REST API, which Cloud Storage serves through its XML API with HMAC keys,
+// so no SDK is needed.
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"scriptweaver/internal/core"
+)
+
+// ErrNotFound is returned for objects that do not exist.
+var ErrNotFound = errors.New("object not found")
+
+// ErrExists is returned for conditional writes of objects that already exist.
+var ErrExists = errors.New("object already exists")
+
+// Defaults of a Client.
+const (
+	// DefaultPartSize is the size above which objects are uploaded in parts,
+	// and the size of each part. S3 requires parts of at least 5 MiB.
+	DefaultPartSize = 16 << 20
+	// DefaultRetries is the number of times a failed request is retried.
+	DefaultRetries = 3
+	// DefaultBackoff is the delay before the first retry; it doubles for
+	// every further retry.
+	DefaultBackoff = 200 * time.Millisecond
+)
+
+// Client is a minimal client of the S3 REST API for one bucket, using
+// path-style addressing ({Endpoint}/{Bucket}/{key}).
+type Client struct {
+	Endpoint    string
+	Region      string
+	Bucket      string
+	Credentials Credentials
+	HTTP        *http.Client
+
+	// PartSize is the multipart threshold and part size; 0 selects
+	// DefaultPartSize.
+	PartSize int64
+	// Retries and Backoff control how requests that fail with a network
+	// error, a 5xx status or 429 are retried.
+	Retries int
+	Backoff time.Duration
+
+	// MaxConcurrent bounds the requests in flight; UploadRate and
+	// DownloadRate cap the bytes per second sent and received, across all
+	// requests. Zero values mean no limit.
+	MaxConcurrent int
+	UploadRate    int64
+	DownloadRate  int64
+
+	mu       sync.Mutex
+	sem      chan struct{}
+	upNext   time.Time // when the bytes sent so far are due, at UploadRate
+	downNext time.Time
+	stats    core.TransferStats
+
+	// now and sleep are replaced in tests.
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// throttleChunk is the size of the reads between which capped transfers
+// pause.
+const throttleChunk = 32 << 10
+
+// NewClient creates a Client with the default part size and retry policy.
+func NewClient(endpoint, region, bucket string, creds Credentials) *Client {
+	return &Client{
+		Endpoint:    strings.TrimSuffix(endpoint, "/"),
+		Region:      region,
+		Bucket:      bucket,
+		Credentials: creds,
+		HTTP:        http.DefaultClient,
+		Retries:     DefaultRetries,
+		Backoff:     DefaultBackoff,
+	}
+}
+
+// StatusError is a request that failed with an unexpected HTTP status.
+type StatusError struct {
+	Method string
+	Key    string
+	Status int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: status %d: %s", e.Method, e.Key, e.Status, strings.TrimSpace(e.Body))
+}
+
+// TransferStats returns the traffic of the client so far.
+func (c *Client) TransferStats() core.TransferStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Head reports whether the object key exists.
+func (c *Client) Head(ctx context.Context, key string) (bool, error) {
+	_, _, err := c.do(ctx, http.MethodHead, key, nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get returns the content of the object key and its user metadata, keyed by
+// lowercase name without the x-amz-meta- prefix.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, map[string]string, error) {
+	resp, body, err := c.do(ctx, http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta := map[string]string{}
+	for name, values := range resp.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-meta-") && len(values) > 0 {
+			meta[strings.TrimPrefix(lower, "x-amz-meta-")] = values[0]
+		}
+	}
+	return body, meta, nil
+}
+
+// Put stores data as the object key with the given user metadata. Objects
+// larger than the part size are uploaded in parts.
+func (c *Client) Put(ctx context.Context, key string, data []byte, meta map[string]string) error {
+	return c.put(ctx, key, data, meta, false)
+}
+
+// PutIfAbsent is Put with the If-None-Match: * precondition: the write fails
+// with ErrExists, leaving the object untouched, if key already exists. Stores
+// without conditional writes ignore the precondition and overwrite it.
+func (c *Client) PutIfAbsent(ctx context.Context, key string, data []byte, meta map[string]string) error {
+	return c.put(ctx, key, data, meta, true)
+}
+
+func (c *Client) put(ctx context.Context, key string, data []byte, meta map[string]string, ifAbsent bool) error {
+	hdr := http.Header{}
+	for k, v := range meta {
+		hdr.Set("X-Amz-Meta-"+k, v)
+	}
+	var cond http.Header
+	if ifAbsent {
+		cond = http.Header{"If-None-Match": {"*"}}
+	}
+	if int64(len(data)) <= c.partSize() {
+		for k, v := range cond {
+			hdr[k] = v
+		}
+		_, _, err := c.do(ctx, http.MethodPut, key, nil, data, hdr)
+		return err
+	}
+	return c.putMultipart(ctx, key, data, hdr, cond)
+}
+
+// Delete removes the object key. Deleting a missing object is not an error.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	_, _, err := c.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) partSize() int64 {
+	if c.PartSize > 0 {
+		return c.PartSize
+	}
+	return DefaultPartSize
+}
+
+// putMultipart uploads data in parts, aborting the upload if any part fails
+// so that no incomplete parts are left billed in the bucket. cond holds the
+// preconditions of the completion, where conditional writes are evaluated.
+func (c *Client) putMultipart(ctx context.Context, key string, data []byte, hdr, cond http.Header) error {
+	_, body, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, hdr)
+	if err != nil {
+		return err
+	}
+	var initiated struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(body, &initiated); err != nil || initiated.UploadID == "" {
+		return fmt.Errorf("POST %s?uploads: no upload id in response", key)
+	}
+	uploadID := initiated.UploadID
+
+	type part struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	complete := struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []part   `xml:"Part"`
+	}{}
+	size := c.partSize()
+	for n, off := 1, int64(0); off < int64(len(data)); n, off = n+1, off+size {
+		end := off + size
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		q := url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {uploadID}}
+		resp, _, err := c.do(ctx, http.MethodPut, key, q, data[off:end], nil)
+		if err != nil {
+			c.abort(key, uploadID)
+			return err
+		}
+		complete.Parts = append(complete.Parts, part{PartNumber: n, ETag: resp.Header.Get("ETag")})
+	}
+
+	doc, err := xml.Marshal(complete)
+	if err != nil {
+		c.abort(key, uploadID)
+		return err
+	}
+	_, body, err = c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, doc, cond)
+	if err == nil && bytes.Contains(body, []byte("<Error>")) {
+		// Completion can fail after the 200 status is sent.
+		err = &StatusError{Method: http.MethodPost, Key: key, Status: http.StatusOK, Body: string(body)}
+	}
+	if err != nil {
+		c.abort(key, uploadID)
+	}
+	return err
+}
+
+// abort cancels a multipart upload, best-effort.
+func (c *Client) abort(key, uploadID string) {
+	_, _, _ = c.do(context.Background(), http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+}
+
+// do sends a signed request for key, retrying transient failures, and
+// returns the response with its body read. A 404 is ErrNotFound, a 412 of a
+// write with If-None-Match: * is ErrExists; any other non-2xx status is a
+// *StatusError.
+func (c *Client) do(ctx context.Context, method, key string, query url.Values, body []byte, hdr http.Header) (*http.Response, []byte, error) {
+	u, err := url.Parse(c.Endpoint + "/" + c.Bucket + "/" + strings.TrimPrefix(key, "/"))
+	if err != nil {
+		return nil, nil, err
+	}
+	u.RawQuery = canonicalQuery(query)
+	payloadHash := emptySHA256
+	if len(body) > 0 {
+		payloadHash = hexSHA256(body)
+	}
+	now, sleep := c.now, c.sleep
+	if now == nil {
+		now = time.Now
+	}
+	if sleep == nil {
+		sleep = sleepContext
+	}
+
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader = bytes.NewReader(body)
+		if c.UploadRate > 0 {
+			reqBody = &throttledReader{r: reqBody, ctx: ctx, c: c, upload: true}
+		}
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.ContentLength = int64(len(body))
+		for k, v := range hdr {
+			req.Header[k] = v
+		}
+		release, err := c.acquire(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		sign(req, payloadHash, c.Credentials, c.Region, now())
+		c.count(core.TransferStats{Requests: 1, BytesUploaded: int64(len(body))})
+		resp, err := c.HTTP.Do(req)
+		var respBody []byte
+		if err == nil {
+			var r io.Reader = resp.Body
+			if c.DownloadRate > 0 {
+				r = &throttledReader{r: r, ctx: ctx, c: c}
+			}
+			respBody, err = io.ReadAll(r)
+			resp.Body.Close()
+			c.count(core.TransferStats{BytesDownloaded: int64(len(respBody))})
+		}
+		release()
+		retryable := err != nil && ctx.Err() == nil
+		if err == nil {
+			switch {
+			case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+				return resp, respBody, nil
+			case resp.StatusCode == http.StatusNotFound:
+				return resp, respBody, fmt.Errorf("%s %s: %w", method, key, ErrNotFound)
+			case resp.StatusCode == http.StatusPreconditionFailed && req.Header.Get("If-None-Match") == "*":
+				return resp, respBody, fmt.Errorf("%s %s: %w", method, key, ErrExists)
+			}
+			err = &StatusError{Method: method, Key: key, Status: resp.StatusCode, Body: string(respBody)}
+			retryable = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		}
+		if !retryable || attempt >= c.Retries {
+			return nil, nil, err
+		}
+		if serr := sleep(ctx, backoff); serr != nil {
+			return nil, nil, serr
+		}
+		backoff *= 2
+	}
+}
+
+// acquire waits for a request slot under MaxConcurrent and returns the
+// function that frees it.
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	c.mu.Lock()
+	if c.MaxConcurrent > 0 && c.sem == nil {
+		c.sem = make(chan struct{}, c.MaxConcurrent)
+	}
+	sem := c.sem
+	c.mu.Unlock()
+	if sem == nil {
+		return func() {}, nil
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Client) count(s core.TransferStats) {
+	c.mu.Lock()
+	c.stats.Add(s)
+	c.mu.Unlock()
+}
+
+// throttle paces a transfer of n more bytes to the upload or download rate:
+// it waits until the bytes transferred so far in that direction are due.
+// Time not used, while idle, is not saved up for bursts.
+func (c *Client) throttle(ctx context.Context, upload bool, n int) error {
+	rate, next := c.DownloadRate, &c.downNext
+	if upload {
+		rate, next = c.UploadRate, &c.upNext
+	}
+	if rate <= 0 {
+		return nil
+	}
+	now, sleep := c.now, c.sleep
+	if now == nil {
+		now = time.Now
+	}
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	t := now()
+	c.mu.Lock()
+	if next.Before(t) {
+		*next = t
+	}
+	*next = next.Add(time.Duration(int64(n) * int64(time.Second) / rate))
+	wait := next.Sub(t)
+	c.stats.Throttled += wait
+	c.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	return sleep(ctx, wait)
+}
+
+// throttledReader reads in chunks of at most throttleChunk bytes, pausing
+// after each as the client's rate for its direction requires.
+type throttledReader struct {
+	r      io.Reader
+	ctx    context.Context
+	c      *Client
+	upload bool
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunk {
+		p = p[:throttleChunk]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if serr := t.c.throttle(t.ctx, t.upload, n); serr != nil {
+			return n, serr
+		}
+	}
+	return n, err
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
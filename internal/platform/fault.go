@@ -0,0 +1,145 @@
+package platform
+
+import (
+	"io/fs"
+)
+
+// FaultFS wraps an FS and fails the operations Fault selects, to test how
+// callers cope with a filesystem that breaks partway through, such as a full
+// disk or a crash between two writes.
+type FaultFS struct {
+	// FS performs the operations that do not fail; nil means OS.
+	FS FS
+	// Fault returns the error operation op on name fails with, or nil to
+	// perform it. op is the name of the FS method, or "Write", "Sync",
+	// "Chmod" or "Close" for an operation on a file created or opened
+	// through the FaultFS. A nil Fault fails nothing.
+	Fault func(op, name string) error
+}
+
+func (f FaultFS) fault(op, name string) error {
+	if f.Fault == nil {
+		return nil
+	}
+	return f.Fault(op, name)
+}
+
+func (f FaultFS) Stat(name string) (fs.FileInfo, error) {
+	if err := f.fault("Stat", name); err != nil {
+		return nil, err
+	}
+	return FSOr(f.FS).Stat(name)
+}
+
+func (f FaultFS) ReadFile(name string) ([]byte, error) {
+	if err := f.fault("ReadFile", name); err != nil {
+		return nil, err
+	}
+	return FSOr(f.FS).ReadFile(name)
+}
+
+func (f FaultFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := f.fault("ReadDir", name); err != nil {
+		return nil, err
+	}
+	return FSOr(f.FS).ReadDir(name)
+}
+
+func (f FaultFS) Open(name string) (File, error) {
+	if err := f.fault("Open", name); err != nil {
+		return nil, err
+	}
+	file, err := FSOr(f.FS).Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return faultFile{File: file, fs: f}, nil
+}
+
+func (f FaultFS) CreateTemp(dir, pattern string) (File, error) {
+	if err := f.fault("CreateTemp", dir); err != nil {
+		return nil, err
+	}
+	file, err := FSOr(f.FS).CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return faultFile{File: file, fs: f}, nil
+}
+
+func (f FaultFS) MkdirTemp(dir, pattern string) (string, error) {
+	if err := f.fault("MkdirTemp", dir); err != nil {
+		return "", err
+	}
+	return FSOr(f.FS).MkdirTemp(dir, pattern)
+}
+
+func (f FaultFS) MkdirAll(path string, perm fs.FileMode) error {
+	if err := f.fault("MkdirAll", path); err != nil {
+		return err
+	}
+	return FSOr(f.FS).MkdirAll(path, perm)
+}
+
+func (f FaultFS) Rename(oldpath, newpath string) error {
+	if err := f.fault("Rename", newpath); err != nil {
+		return err
+	}
+	return FSOr(f.FS).Rename(oldpath, newpath)
+}
+
+func (f FaultFS) Link(oldname, newname string) error {
+	if err := f.fault("Link", newname); err != nil {
+		return err
+	}
+	return FSOr(f.FS).Link(oldname, newname)
+}
+
+func (f FaultFS) Remove(name string) error {
+	if err := f.fault("Remove", name); err != nil {
+		return err
+	}
+	return FSOr(f.FS).Remove(name)
+}
+
+func (f FaultFS) RemoveAll(path string) error {
+	if err := f.fault("RemoveAll", path); err != nil {
+		return err
+	}
+	return FSOr(f.FS).RemoveAll(path)
+}
+
+type faultFile struct {
+	File
+	fs FaultFS
+}
+
+func (f faultFile) Write(p []byte) (int, error) {
+	if err := f.fs.fault("Write", f.Name()); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+func (f faultFile) Sync() error {
+	if err := f.fs.fault("Sync", f.Name()); err != nil {
+		return err
+	}
+	return f.File.Sync()
+}
+
+func (f faultFile) Chmod(mode fs.FileMode) error {
+	if err := f.fs.fault("Chmod", f.Name()); err != nil {
+		return err
+	}
+	return f.File.Chmod(mode)
+}
+
+func (f faultFile) Close() error {
+	if err := f.fs.fault("Close", f.Name()); err != nil {
+		// The file is still closed, so that a failing Close leaks nothing.
+		_ = f.File.Close()
+		return err
+	}
+	return f.File.Close()
+}
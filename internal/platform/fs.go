@@ -0,0 +1,79 @@
+package platform
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// FS is the filesystem the cache and the state store read and write. Paths
+// are host paths, as for package os, and errors wrap the same fs errors, so
+// errors.Is(err, fs.ErrNotExist) and os.IsNotExist keep working.
+type FS interface {
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	// ReadDir returns the entries of a directory sorted by filename.
+	ReadDir(name string) ([]fs.DirEntry, error)
+	// Open opens a file or directory for reading.
+	Open(name string) (File, error)
+	// CreateTemp creates a new file in dir, as os.CreateTemp.
+	CreateTemp(dir, pattern string) (File, error)
+	// MkdirTemp creates a new directory in dir, as os.MkdirTemp.
+	MkdirTemp(dir, pattern string) (string, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	Rename(oldpath, newpath string) error
+	Link(oldname, newname string) error
+	Remove(name string) error
+	RemoveAll(path string) error
+}
+
+// File is an open file of an FS.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Name() string
+	Sync() error
+	Chmod(mode fs.FileMode) error
+}
+
+// OS is the host filesystem.
+var OS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+func (osFS) MkdirTemp(dir, pattern string) (string, error) { return os.MkdirTemp(dir, pattern) }
+func (osFS) Rename(oldpath, newpath string) error          { return os.Rename(oldpath, newpath) }
+func (osFS) Link(oldname, newname string) error            { return os.Link(oldname, newname) }
+func (osFS) Remove(name string) error                      { return os.Remove(name) }
+func (osFS) RemoveAll(path string) error                   { return os.RemoveAll(path) }
+
+func (osFS) Open(name string) (File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (osFS) CreateTemp(dir, pattern string) (File, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// FSOr returns fsys, or OS when fsys is nil.
+func FSOr(fsys FS) FS {
+	if fsys == nil {
+		return OS
+	}
+	return fsys
+}
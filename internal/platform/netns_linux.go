@@ -0,0 +1,23 @@
+package platform
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// IsolateNetwork makes cmd start in a new network namespace, whose only
+// interface is a loopback that is down. Without root, a user namespace
+// mapping just the caller's uid and gid is created alongside it.
+func IsolateNetwork(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	if uid := os.Getuid(); uid != 0 {
+		gid := os.Getgid()
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: uid, HostID: uid, Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: gid, HostID: gid, Size: 1}}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package platform
+
+import "os/exec"
+
+// IsolateNetwork does nothing: network namespaces only exist on Linux, so
+// network isolation is not enforced on other platforms.
+func IsolateNetwork(*exec.Cmd) {}
@@ -0,0 +1,64 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFakeClock_SleepAdvancesTime(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	if err := c.Sleep(context.Background(), time.Second); err != nil {
+		t.Fatalf("Sleep: %v", err)
+	}
+	c.Advance(time.Minute)
+	if got := c.Now(); !got.Equal(start.Add(time.Minute + time.Second)) {
+		t.Fatalf("Now = %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Sleep(ctx, time.Hour); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Sleep on a done context = %v", err)
+	}
+	if got := c.Sleeps(); !reflect.DeepEqual(got, []time.Duration{time.Second}) {
+		t.Fatalf("Sleeps = %v", got)
+	}
+}
+
+func TestFaultFS_FailsSelectedOperations(t *testing.T) {
+	dir := t.TempDir()
+	var ops []string
+	fsys := FaultFS{Fault: func(op, name string) error {
+		ops = append(ops, op)
+		if op == "Rename" {
+			return errors.New("injected")
+		}
+		return nil
+	}}
+
+	f, err := fsys.CreateTemp(dir, "x.*")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	if _, err := f.Write([]byte("data")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := fsys.Rename(f.Name(), filepath.Join(dir, "x")); err == nil {
+		t.Fatalf("Rename succeeded")
+	}
+	if _, err := fsys.Stat(filepath.Join(dir, "x")); !os.IsNotExist(err) {
+		t.Fatalf("Stat = %v, want not-exist", err)
+	}
+	if want := []string{"CreateTemp", "Write", "Close", "Rename", "Stat"}; !reflect.DeepEqual(ops, want) {
+		t.Fatalf("ops = %v, want %v", ops, want)
+	}
+}
@@ -31,6 +31,11 @@ func loggerOrNop(l Logger) Logger {
 type Registry struct {
 	Manifests []PluginManifest
 	ByID      map[string]PluginManifest
+	// Dirs maps plugin_id to the directory the plugin was discovered in.
+	Dirs map[string]string
+	// Rejected maps the plugin_id of each discovered plugin that failed
+	// signature verification to the reason it was skipped.
+	Rejected map[string]error
 }
 
 // DiscoverAndRegister scans a plugins root directory for plugin subdirectories
@@ -57,7 +62,7 @@ func DiscoverAndRegister(root string, log Logger) (Registry, []error) {
 	// Deterministic discovery: sort directory entries by name.
 	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
 
-	reg := Registry{ByID: make(map[string]PluginManifest)}
+	reg := Registry{ByID: make(map[string]PluginManifest), Dirs: make(map[string]string)}
 	var errs []error
 
 	for _, ent := range entries {
@@ -92,6 +97,7 @@ func DiscoverAndRegister(root string, log Logger) (Registry, []error) {
 			continue
 		}
 		reg.ByID[m.PluginID] = m
+		reg.Dirs[m.PluginID] = pluginDir
 	}
 
 	reg.Manifests = make([]PluginManifest, 0, len(reg.ByID))
@@ -0,0 +1,109 @@
+package pluginengine
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// HandshakeCommand is the argument a plugin executable is invoked with by
+// Doctor: `plugin Handshake`. It must exit 0 without side effects.
+const HandshakeCommand = "Handshake"
+
+// Runtime kinds reported by Doctor.
+const (
+	RuntimeExec      = "exec"
+	RuntimeInProcess = "in-process"
+)
+
+// Health is the diagnosis of one plugin directory.
+type Health struct {
+	// Dir is the plugin directory name under the plugins root.
+	Dir      string
+	PluginID string
+	Version  string
+	// Runtime is RuntimeExec or RuntimeInProcess; empty when the plugin
+	// could not be instantiated.
+	Runtime string
+	// Problems lists everything that would keep the plugin from loading or
+	// running; empty means healthy.
+	Problems []string
+}
+
+// OK reports whether the plugin is healthy.
+func (h Health) OK() bool { return len(h.Problems) == 0 }
+
+// Doctor loads every plugin directory under root, in directory name order,
+// and checks that it would load and run: its manifest is valid and its
+// plugin_id unique, its version is MAJOR.MINOR.PATCH, its requires constraint
+// admits APIVersion, its signature verifies when keys are trusted, and its
+// runtime instantiates and, for plugin executables, answers a handshake.
+//
+// Directories without a manifest.json are not plugins and are not reported.
+func Doctor(ctx context.Context, root string, keys []ed25519.PublicKey) ([]Health, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var out []Health
+	seen := make(map[string]string)
+	for _, ent := range entries {
+		if !ent.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, ent.Name())
+		manifestPath := filepath.Join(dir, "manifest.json")
+		if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+			continue
+		}
+		h := Health{Dir: ent.Name()}
+		m, err := LoadPluginManifestFile(manifestPath)
+		if err != nil {
+			h.Problems = append(h.Problems, err.Error())
+			out = append(out, h)
+			continue
+		}
+		h.PluginID, h.Version = m.PluginID, m.Version
+		if other, dup := seen[m.PluginID]; dup {
+			h.Problems = append(h.Problems, fmt.Sprintf("%v: %s (also in %s)", ErrDuplicatePluginID, m.PluginID, other))
+		}
+		seen[m.PluginID] = ent.Name()
+		if _, err := parseSemver(m.Version); err != nil {
+			h.Problems = append(h.Problems, err.Error())
+		}
+		if len(keys) > 0 {
+			if err := VerifyPlugin(dir, keys); err != nil {
+				h.Problems = append(h.Problems, err.Error())
+			}
+		}
+		p, err := instantiate(m, dir)
+		if err != nil {
+			h.Problems = append(h.Problems, err.Error())
+			out = append(out, h)
+			continue
+		}
+		h.Runtime = RuntimeInProcess
+		if pp, ok := p.(*processPlugin); ok {
+			h.Runtime = RuntimeExec
+			if err := pp.handshake(ctx); err != nil {
+				h.Problems = append(h.Problems, fmt.Sprintf("handshake failed: %v", err))
+			}
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// handshake invokes the executable with HandshakeCommand under the plugin's
+// sandbox policy.
+func (p *processPlugin) handshake(ctx context.Context) error {
+	return p.invoke(ctx, HandshakeCommand, nil, nil)
+}
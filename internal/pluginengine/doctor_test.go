@@ -0,0 +1,103 @@
+package pluginengine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSatisfies_Constraints(t *testing.T) {
+	for _, tc := range []struct {
+		constraint string
+		want       bool
+	}{
+		{">=1.0.0", true},
+		{">=1.0.0, <2.0.0", true},
+		{"=1.0.0", true},
+		{">1.0.0", false},
+		{"<1.0.0", false},
+		{"<=0.9.9", false},
+	} {
+		got, err := satisfies("1.0.0", tc.constraint)
+		if err != nil || got != tc.want {
+			t.Fatalf("satisfies(1.0.0, %q) = %v, %v; want %v", tc.constraint, got, err, tc.want)
+		}
+	}
+	for _, bad := range []string{"1.0.0", ">=1.0", "~1.0.0", ">=1.0.0,"} {
+		if _, err := parseConstraint(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestInstantiate_RejectsIncompatibleRequires(t *testing.T) {
+	root := t.TempDir()
+	writeExecPlugin(t, root, "future", `["BeforeRun"],"requires":">=2.0.0"`, "#!/bin/sh\n")
+	reg, errs := DiscoverAndRegister(root, nil)
+	if len(errs) != 0 {
+		t.Fatalf("errs = %v", errs)
+	}
+	if _, err := Instantiate(reg); !errors.Is(err, ErrIncompatiblePlugin) {
+		t.Fatalf("expected ErrIncompatiblePlugin, got %v", err)
+	}
+}
+
+func TestDoctor_ReportsEveryPlugin(t *testing.T) {
+	root := t.TempDir()
+	writeExecPlugin(t, root, "a-ok", `["BeforeRun"]`, "#!/bin/sh\n[ \"$1\" = Handshake ]\n")
+	writeExecPlugin(t, root, "b-nohandshake", `["BeforeRun"]`, "#!/bin/sh\necho \"unsupported hook: $1\" >&2\nexit 1\n")
+	writeExecPlugin(t, root, "c-noexe", `["BeforeRun"]`, "")
+	writeExecPlugin(t, root, "d-future", `["BeforeRun"],"requires":">=2.0.0"`, "#!/bin/sh\n")
+	bad := filepath.Join(root, "e-bad")
+	if err := os.MkdirAll(bad, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(bad, "manifest.json"), []byte(`{"plugin_id":"e"}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "not-a-plugin"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	health, err := Doctor(context.Background(), root, nil)
+	if err != nil {
+		t.Fatalf("Doctor: %v", err)
+	}
+	if len(health) != 5 {
+		t.Fatalf("health = %#v", health)
+	}
+	want := []struct {
+		dir, runtime, problem string
+	}{
+		{"a-ok", RuntimeExec, ""},
+		{"b-nohandshake", RuntimeExec, "handshake failed"},
+		{"c-noexe", "", ErrNoRuntime.Error()},
+		{"d-future", "", ErrIncompatiblePlugin.Error()},
+		{"e-bad", "", ErrMissingVersion.Error()},
+	}
+	for i, w := range want {
+		h := health[i]
+		problems := strings.Join(h.Problems, "; ")
+		if h.Dir != w.dir || h.Runtime != w.runtime || (w.problem == "") != h.OK() || !strings.Contains(problems, w.problem) {
+			t.Fatalf("health[%d] = %#v, want dir=%s runtime=%q problem containing %q", i, h, w.dir, w.runtime, w.problem)
+		}
+	}
+}
+
+func TestDoctor_InProcessRuntimeSkipsHandshake(t *testing.T) {
+	root := t.TempDir()
+	writeExecPlugin(t, root, "mem", `["BeforeRun"]`, "")
+	RegisterRuntime("mem", func(m PluginManifest, _ string) (RuntimePlugin, error) { return &runnerPlugin{id: m.PluginID}, nil })
+	t.Cleanup(func() { RegisterRuntime("mem", nil) })
+
+	health, err := Doctor(context.Background(), root, nil)
+	if err != nil {
+		t.Fatalf("Doctor: %v", err)
+	}
+	if len(health) != 1 || !health[0].OK() || health[0].Runtime != RuntimeInProcess {
+		t.Fatalf("health = %#v", health)
+	}
+}
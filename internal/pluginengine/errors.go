@@ -16,4 +16,12 @@ var (
 	ErrMissingVersion     = errors.New("missing version")
 	ErrMissingHooks       = errors.New("missing hooks")
 	ErrEmptyHooks         = errors.New("empty hooks")
+	ErrPluginNotFound     = errors.New("plugin not found")
+	ErrNoRuntime          = errors.New("plugin has no runtime")
+	ErrDuplicateRunner    = errors.New("duplicate runner")
+	ErrUnknownRunner      = errors.New("unknown runner")
+	ErrUnsignedPlugin     = errors.New("plugin is unsigned")
+	ErrInvalidSignature   = errors.New("plugin signature invalid")
+	ErrHookTimeout        = errors.New("plugin hook timed out")
+	ErrIncompatiblePlugin = errors.New("plugin incompatible with engine")
 )
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
+	"scriptweaver/internal/audit"
 	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
 )
 
 // RuntimePlugin is the minimal runtime interface used for Phase 3 hook execution.
@@ -34,9 +37,10 @@ type afterNodePlugin interface {
 }
 
 type pluginEntry struct {
-	plugin RuntimePlugin
-	id     string
-	hooks  map[string]struct{}
+	plugin  RuntimePlugin
+	id      string
+	hooks   map[string]struct{}
+	runners []string
 }
 
 // HookEngine executes registered plugin lifecycle hooks.
@@ -51,9 +55,14 @@ type pluginEntry struct {
 type HookEngine struct {
 	log Logger
 
-	mu   sync.Mutex
-	err  []error
-	plug []pluginEntry
+	mu    sync.Mutex
+	err   []error
+	plug  []pluginEntry
+	types *graph.NodeTypes
+	audit *audit.Log
+	stats hookStats
+	// panics, when set, selects lifecycle hooks to panic instead of running.
+	panics func(pluginID, hook, taskID string) bool
 }
 
 // NewHookEngine creates a HookEngine from runtime plugin implementations.
@@ -74,7 +83,7 @@ func NewHookEngine(plugins []RuntimePlugin, log Logger) (*HookEngine, error) {
 		for _, h := range m.Hooks {
 			hset[h] = struct{}{}
 		}
-		entries = append(entries, pluginEntry{plugin: p, id: m.PluginID, hooks: hset})
+		entries = append(entries, pluginEntry{plugin: p, id: m.PluginID, hooks: hset, runners: m.Runners})
 	}
 
 	// Reject duplicate plugin IDs at runtime too.
@@ -85,7 +94,74 @@ func NewHookEngine(plugins []RuntimePlugin, log Logger) (*HookEngine, error) {
 		}
 	}
 
-	return &HookEngine{log: log, plug: entries}, nil
+	// A runner name selects exactly one plugin.
+	providers := make(map[string]string)
+	for _, ent := range entries {
+		for _, name := range ent.runners {
+			if other, dup := providers[name]; dup {
+				return nil, fmt.Errorf("%w: %s (plugins %s and %s)", ErrDuplicateRunner, name, other, ent.id)
+			}
+			providers[name] = ent.id
+		}
+	}
+
+	// Every runner is a node type of the graphs loaded with the engine; graph
+	// documents validate the inputs of its nodes against its schema.
+	types := graph.NewNodeTypes()
+	for _, ent := range entries {
+		inputs := ent.plugin.Manifest().RunnerInputs
+		for _, name := range ent.runners {
+			if err := types.Register(name, inputs[name]); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrManifestInvalid, err)
+			}
+		}
+	}
+
+	return &HookEngine{log: log, plug: entries, types: types}, nil
+}
+
+// NodeTypes returns the node types of graphs loaded with the engine: the
+// built-in types and the runners of its plugins. It is nil, holding the
+// built-in types only, for a nil engine.
+func (e *HookEngine) NodeTypes() *graph.NodeTypes {
+	if e == nil {
+		return nil
+	}
+	return e.types
+}
+
+// SetAuditLog records every plugin hook invocation in the workspace audit log.
+func (e *HookEngine) SetAuditLog(l *audit.Log) {
+	e.mu.Lock()
+	e.audit = l
+	e.mu.Unlock()
+}
+
+// SetPanicInjector makes the lifecycle hooks for which inject returns true
+// panic instead of running, to exercise panic isolation (see package chaos).
+// taskID is empty for BeforeRun and AfterRun.
+func (e *HookEngine) SetPanicInjector(inject func(pluginID, hook, taskID string) bool) {
+	e.mu.Lock()
+	e.panics = inject
+	e.mu.Unlock()
+}
+
+// injectPanic panics when the panic injector selects the hook.
+func (e *HookEngine) injectPanic(pluginID, hook, taskID string) {
+	e.mu.Lock()
+	inject := e.panics
+	e.mu.Unlock()
+	if inject != nil && inject(pluginID, hook, taskID) {
+		panic("chaos: injected panic")
+	}
+}
+
+// recordInvocation audits a hook invocation, best-effort.
+func (e *HookEngine) recordInvocation(pluginID, hook string) {
+	e.mu.Lock()
+	l := e.audit
+	e.mu.Unlock()
+	_ = l.Record(audit.KindPluginHook, pluginID+"/"+hook)
 }
 
 // Errors returns a snapshot of hook errors observed so far.
@@ -123,22 +199,30 @@ func (e *HookEngine) BeforeRun(ctx context.Context) {
 			err := fmt.Errorf("plugin %s declares BeforeRun but does not implement it", ent.id)
 			e.log.Printf("pluginengine: %v", err)
 			e.recordError(err)
+			e.stats.record(ent.id, "BeforeRun", 0, false, true)
 			continue
 		}
+		e.recordInvocation(ent.id, "BeforeRun")
+		start := time.Now()
+		failed := false
 		func() {
 			defer func() {
 				if r := recover(); r != nil {
+					failed = true
 					err := fmt.Errorf("plugin %s hook BeforeRun panic: %v", ent.id, r)
 					e.log.Printf("pluginengine: %v", err)
 					e.recordError(err)
 				}
 			}()
+			e.injectPanic(ent.id, "BeforeRun", "")
 			if err := h.BeforeRun(ctx); err != nil {
+				failed = true
 				err2 := fmt.Errorf("plugin %s hook BeforeRun error: %w", ent.id, err)
 				e.log.Printf("pluginengine: %v", err2)
 				e.recordError(err2)
 			}
 		}()
+		e.stats.record(ent.id, "BeforeRun", time.Since(start), true, failed)
 	}
 }
 
@@ -156,22 +240,30 @@ func (e *HookEngine) AfterRun(ctx context.Context) {
 			err := fmt.Errorf("plugin %s declares AfterRun but does not implement it", ent.id)
 			e.log.Printf("pluginengine: %v", err)
 			e.recordError(err)
+			e.stats.record(ent.id, "AfterRun", 0, false, true)
 			continue
 		}
+		e.recordInvocation(ent.id, "AfterRun")
+		start := time.Now()
+		failed := false
 		func() {
 			defer func() {
 				if r := recover(); r != nil {
+					failed = true
 					err := fmt.Errorf("plugin %s hook AfterRun panic: %v", ent.id, r)
 					e.log.Printf("pluginengine: %v", err)
 					e.recordError(err)
 				}
 			}()
+			e.injectPanic(ent.id, "AfterRun", "")
 			if err := h.AfterRun(ctx); err != nil {
+				failed = true
 				err2 := fmt.Errorf("plugin %s hook AfterRun error: %w", ent.id, err)
 				e.log.Printf("pluginengine: %v", err2)
 				e.recordError(err2)
 			}
 		}()
+		e.stats.record(ent.id, "AfterRun", time.Since(start), true, failed)
 	}
 }
 
@@ -189,22 +281,30 @@ func (e *HookEngine) BeforeNode(ctx context.Context, taskID string) {
 			err := fmt.Errorf("plugin %s declares BeforeNode but does not implement it", ent.id)
 			e.log.Printf("pluginengine: %v", err)
 			e.recordError(err)
+			e.stats.record(ent.id, "BeforeNode", 0, false, true)
 			continue
 		}
+		e.recordInvocation(ent.id, "BeforeNode")
+		start := time.Now()
+		failed := false
 		func() {
 			defer func() {
 				if r := recover(); r != nil {
+					failed = true
 					err := fmt.Errorf("plugin %s hook BeforeNode panic: %v", ent.id, r)
 					e.log.Printf("pluginengine: %v", err)
 					e.recordError(err)
 				}
 			}()
+			e.injectPanic(ent.id, "BeforeNode", taskID)
 			if err := h.BeforeNode(ctx, taskID); err != nil {
+				failed = true
 				err2 := fmt.Errorf("plugin %s hook BeforeNode error: %w", ent.id, err)
 				e.log.Printf("pluginengine: %v", err2)
 				e.recordError(err2)
 			}
 		}()
+		e.stats.record(ent.id, "BeforeNode", time.Since(start), true, failed)
 	}
 }
 
@@ -222,21 +322,29 @@ func (e *HookEngine) AfterNode(ctx context.Context, taskID string) {
 			err := fmt.Errorf("plugin %s declares AfterNode but does not implement it", ent.id)
 			e.log.Printf("pluginengine: %v", err)
 			e.recordError(err)
+			e.stats.record(ent.id, "AfterNode", 0, false, true)
 			continue
 		}
+		e.recordInvocation(ent.id, "AfterNode")
+		start := time.Now()
+		failed := false
 		func() {
 			defer func() {
 				if r := recover(); r != nil {
+					failed = true
 					err := fmt.Errorf("plugin %s hook AfterNode panic: %v", ent.id, r)
 					e.log.Printf("pluginengine: %v", err)
 					e.recordError(err)
 				}
 			}()
+			e.injectPanic(ent.id, "AfterNode", taskID)
 			if err := h.AfterNode(ctx, taskID); err != nil {
+				failed = true
 				err2 := fmt.Errorf("plugin %s hook AfterNode error: %w", ent.id, err)
 				e.log.Printf("pluginengine: %v", err2)
 				e.recordError(err2)
 			}
 		}()
+		e.stats.record(ent.id, "AfterNode", time.Since(start), true, failed)
 	}
 }
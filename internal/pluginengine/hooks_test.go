@@ -248,3 +248,29 @@ func TestExecutor_RunSerial_PluginErrorDoesNotCrashEngine(t *testing.T) {
 		t.Fatalf("Errors() = %#v, want 1 error", got)
 	}
 }
+
+func TestHookEngine_InjectedPanicIsRecovered(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	p := &recordingPlugin{
+		manifest: PluginManifest{PluginID: "p", Version: "0.1.0", Hooks: []string{"BeforeNode", "AfterNode"}},
+		calls:    &calls,
+	}
+	eng, err := NewHookEngine([]RuntimePlugin{p}, nil)
+	if err != nil {
+		t.Fatalf("NewHookEngine error: %v", err)
+	}
+	eng.SetPanicInjector(func(pluginID, hook, taskID string) bool {
+		return pluginID == "p" && hook == "BeforeNode" && taskID == "A"
+	})
+
+	eng.BeforeNode(context.Background(), "A")
+	eng.AfterNode(context.Background(), "A")
+	if len(calls) != 1 || calls[0] != "p:AfterNode:A" {
+		t.Fatalf("calls = %v, want only AfterNode to run", calls)
+	}
+	if got := eng.Errors(); len(got) != 1 {
+		t.Fatalf("Errors() = %#v, want the injected panic", got)
+	}
+}
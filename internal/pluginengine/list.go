@@ -0,0 +1,77 @@
+package pluginengine
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// PluginInfo describes a discovered plugin for listing. A plugin is enabled
+// when it would load; LoadError says why a disabled one would not.
+type PluginInfo struct {
+	PluginID    string   `json:"plugin_id"`
+	Version     string   `json:"version"`
+	Hooks       []string `json:"hooks"`
+	Description string   `json:"description"`
+	// Dir is the directory the plugin was discovered in.
+	Dir       string `json:"dir"`
+	Enabled   bool   `json:"enabled"`
+	LoadError string `json:"load_error,omitempty"`
+}
+
+// ListPlugins describes every plugin directory under root, ordered by
+// plugin_id and then directory, without running any plugin. A plugin is
+// disabled when its manifest is invalid, its plugin_id is taken by a
+// directory sorting before it, or, when keys are trusted, its signature does
+// not verify.
+//
+// Directories without a manifest.json are not plugins and are not listed.
+func ListPlugins(root string, keys []ed25519.PublicKey) ([]PluginInfo, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var out []PluginInfo
+	seen := make(map[string]string)
+	for _, ent := range entries {
+		if !ent.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, ent.Name())
+		manifestPath := filepath.Join(dir, "manifest.json")
+		if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+			continue
+		}
+		info := PluginInfo{Dir: dir, Hooks: []string{}}
+		m, err := LoadPluginManifestFile(manifestPath)
+		if err != nil {
+			info.LoadError = err.Error()
+			out = append(out, info)
+			continue
+		}
+		info.PluginID, info.Version, info.Description = m.PluginID, m.Version, m.Description
+		info.Hooks = append(info.Hooks, m.Hooks...)
+		switch other, dup := seen[m.PluginID]; {
+		case dup:
+			info.LoadError = fmt.Sprintf("%v: %s (also in %s)", ErrDuplicatePluginID, m.PluginID, other)
+		case len(keys) > 0:
+			if err := VerifyPlugin(dir, keys); err != nil {
+				info.LoadError = err.Error()
+			}
+		}
+		if _, dup := seen[m.PluginID]; !dup {
+			seen[m.PluginID] = ent.Name()
+		}
+		info.Enabled = info.LoadError == ""
+		out = append(out, info)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].PluginID < out[j].PluginID })
+	return out, nil
+}
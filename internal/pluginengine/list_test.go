@@ -0,0 +1,53 @@
+package pluginengine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListPlugins_DescribesEveryPluginWithLoadErrors(t *testing.T) {
+	root := t.TempDir()
+	writeExecPlugin(t, root, "b", `["BeforeRun","AfterNode"]`, "")
+	dup := filepath.Join(root, "z-dup")
+	if err := os.MkdirAll(dup, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dup, "manifest.json"), []byte(`{"plugin_id":"b","version":"2.0.0","hooks":["BeforeRun"],"description":"copy"}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	bad := filepath.Join(root, "a-bad")
+	if err := os.MkdirAll(bad, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(bad, "manifest.json"), []byte(`{"plugin_id":"a"}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "not-a-plugin"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	plugins, err := ListPlugins(root, nil)
+	if err != nil {
+		t.Fatalf("ListPlugins: %v", err)
+	}
+	if len(plugins) != 3 {
+		t.Fatalf("plugins = %#v", plugins)
+	}
+	if p := plugins[0]; p.PluginID != "" || p.Dir != bad || p.Enabled || !strings.Contains(p.LoadError, ErrMissingVersion.Error()) {
+		t.Fatalf("plugins[0] = %#v", p)
+	}
+	want := PluginInfo{PluginID: "b", Version: "1.0.0", Hooks: []string{"BeforeRun", "AfterNode"}, Dir: filepath.Join(root, "b"), Enabled: true}
+	if !reflect.DeepEqual(plugins[1], want) {
+		t.Fatalf("plugins[1] = %#v, want %#v", plugins[1], want)
+	}
+	if p := plugins[2]; p.PluginID != "b" || p.Dir != dup || p.Enabled || !strings.Contains(p.LoadError, ErrDuplicatePluginID.Error()) {
+		t.Fatalf("plugins[2] = %#v", p)
+	}
+
+	if plugins, err := ListPlugins(filepath.Join(root, "missing"), nil); err != nil || plugins != nil {
+		t.Fatalf("missing root: %#v, %v", plugins, err)
+	}
+}
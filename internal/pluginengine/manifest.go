@@ -7,6 +7,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+
+	"scriptweaver/internal/graph"
 )
 
 // PluginManifest is defined by the Sprint-09 Data Dictionary.
@@ -16,6 +18,20 @@ type PluginManifest struct {
 	Version      string   `json:"version"`
 	Hooks        []string `json:"hooks"`
 	Description  string   `json:"description"`
+	// Runners lists the task runners the plugin provides, selected by a
+	// node's runner field.
+	Runners      []string `json:"runners,omitempty"`
+	// RunnerInputs declares, by runner, the inputs that graph document
+	// nodes of the runner's type accept; they are registered with the
+	// engine's node types (see HookEngine.NodeTypes) when the plugin is
+	// loaded.
+	RunnerInputs map[string]graph.InputSchema `json:"runner_inputs,omitempty"`
+	// Sandbox limits the plugin executable's resources; nil applies the
+	// default policy.
+	Sandbox      *SandboxPolicy `json:"sandbox,omitempty"`
+	// Requires constrains the plugin API versions (see APIVersion) the
+	// plugin works with, for example ">=1.0.0, <2.0.0".
+	Requires     string `json:"requires,omitempty"`
 }
 
 // RuntimePluginState is defined by the Sprint-09 Data Dictionary.
@@ -33,6 +49,8 @@ func SupportedHooks() map[string]struct{} {
 		"AfterRun":   {},
 		"BeforeNode": {},
 		"AfterNode":  {},
+		ReportHook:   {},
+		ValidateHook: {},
 	}
 }
 
@@ -43,10 +61,11 @@ func ValidatePluginManifest(m PluginManifest) error {
 	if m.Version == "" {
 		return fmt.Errorf("%w: %w", ErrManifestInvalid, ErrMissingVersion)
 	}
-	if m.Hooks == nil {
+	// A plugin that only provides runners need not declare hooks.
+	if m.Hooks == nil && len(m.Runners) == 0 {
 		return fmt.Errorf("%w: %w", ErrManifestInvalid, ErrMissingHooks)
 	}
-	if len(m.Hooks) == 0 {
+	if len(m.Hooks) == 0 && len(m.Runners) == 0 {
 		return fmt.Errorf("%w: %w", ErrManifestInvalid, ErrEmptyHooks)
 	}
 
@@ -57,6 +76,34 @@ func ValidatePluginManifest(m PluginManifest) error {
 		}
 	}
 
+	if err := validateSandbox(m.Sandbox); err != nil {
+		return err
+	}
+	if m.Requires != "" {
+		if _, err := parseConstraint(m.Requires); err != nil {
+			return fmt.Errorf("%w: requires: %v", ErrManifestInvalid, err)
+		}
+	}
+
+	seen := make(map[string]struct{}, len(m.Runners))
+	for _, name := range m.Runners {
+		if !runnerNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: invalid runner name %q", ErrManifestInvalid, name)
+		}
+		if _, dup := seen[name]; dup {
+			return fmt.Errorf("%w: %w: %s", ErrManifestInvalid, ErrDuplicateRunner, name)
+		}
+		seen[name] = struct{}{}
+	}
+	for name, schema := range m.RunnerInputs {
+		if _, ok := seen[name]; !ok {
+			return fmt.Errorf("%w: runner_inputs: %q is not a runner of the plugin", ErrManifestInvalid, name)
+		}
+		if err := schema.Validate(); err != nil {
+			return fmt.Errorf("%w: runner_inputs: %q: %v", ErrManifestInvalid, name, err)
+		}
+	}
+
 	return nil
 }
 
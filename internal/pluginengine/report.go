@@ -0,0 +1,145 @@
+package pluginengine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/trace"
+)
+
+// ReportHook is the manifest hook name declared by reporter plugins.
+const ReportHook = "Report"
+
+// Report is the outcome of a run as handed to reporter plugins.
+type Report struct {
+	RunID    string
+	ExitCode int
+	// Events is the run's execution trace event stream, in canonical order.
+	Events []trace.TraceEvent
+	Result *dag.GraphResult
+	// Dir is the directory reserved for the receiving plugin's artifacts.
+	// The engine sets it per plugin.
+	Dir string
+}
+
+// Reporter is implemented by plugins that declare the Report hook. Report is
+// invoked once per run, after the execution trace has been finalized, and may
+// write arbitrary artifacts (HTML, CI service messages, custom JSON) to r.Dir.
+type Reporter interface {
+	Report(ctx context.Context, r Report) error
+}
+
+// NewReport builds the Report for a finished run. The event stream is decoded
+// from the result's canonical trace bytes.
+func NewReport(runID string, exitCode int, gr *dag.GraphResult) Report {
+	r := Report{RunID: runID, ExitCode: exitCode, Result: gr}
+	if gr != nil && len(gr.TraceBytes) > 0 {
+		if t, err := trace.Parse(gr.TraceBytes); err == nil {
+			r.Events = t.Events
+		}
+	}
+	return r
+}
+
+// Report invokes every reporter plugin in plugin_id order. Each plugin gets
+// its own artifact directory, baseDir/<plugin_id>. Like the other hooks,
+// reporter errors and panics are logged and recorded but never returned.
+func (e *HookEngine) Report(ctx context.Context, baseDir string, r Report) {
+	if e == nil {
+		return
+	}
+	for _, ent := range e.plug {
+		if _, ok := ent.hooks[ReportHook]; !ok {
+			continue
+		}
+		h, ok := ent.plugin.(Reporter)
+		if !ok {
+			err := fmt.Errorf("plugin %s declares Report but does not implement it", ent.id)
+			e.log.Printf("pluginengine: %v", err)
+			e.recordError(err)
+			e.stats.record(ent.id, ReportHook, 0, false, true)
+			continue
+		}
+		pr := r
+		pr.Dir = filepath.Join(baseDir, ent.id)
+		if err := os.MkdirAll(pr.Dir, 0o755); err != nil {
+			err = fmt.Errorf("plugin %s report dir: %w", ent.id, err)
+			e.log.Printf("pluginengine: %v", err)
+			e.recordError(err)
+			e.stats.record(ent.id, ReportHook, 0, false, true)
+			continue
+		}
+		e.recordInvocation(ent.id, ReportHook)
+		start := time.Now()
+		failed := false
+		func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					failed = true
+					err := fmt.Errorf("plugin %s hook Report panic: %v", ent.id, rec)
+					e.log.Printf("pluginengine: %v", err)
+					e.recordError(err)
+				}
+			}()
+			if err := h.Report(ctx, pr); err != nil {
+				failed = true
+				err2 := fmt.Errorf("plugin %s hook Report error: %w", ent.id, err)
+				e.log.Printf("pluginengine: %v", err2)
+				e.recordError(err2)
+			}
+		}()
+		e.stats.record(ent.id, ReportHook, time.Since(start), true, failed)
+	}
+}
+
+// reportDocument is the JSON a plugin executable reads from stdin for the
+// Report hook.
+type reportDocument struct {
+	RunID     string          `json:"run_id"`
+	ExitCode  int             `json:"exit_code"`
+	GraphHash string          `json:"graph_hash"`
+	Trace     json.RawMessage `json:"trace"`
+	Nodes     []reportNode    `json:"nodes"`
+}
+
+type reportNode struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	TaskHash string `json:"task_hash,omitempty"`
+}
+
+func marshalReport(r Report) ([]byte, error) {
+	doc := reportDocument{RunID: r.RunID, ExitCode: r.ExitCode, Trace: json.RawMessage("null"), Nodes: []reportNode{}}
+	if gr := r.Result; gr != nil {
+		doc.GraphHash = string(gr.GraphHash)
+		if len(gr.TraceBytes) > 0 {
+			doc.Trace = json.RawMessage(gr.TraceBytes)
+		}
+		names := make([]string, 0, len(gr.FinalState))
+		for name := range gr.FinalState {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			n := reportNode{Name: name, State: string(gr.FinalState[name]), TaskHash: string(gr.TaskHashOf(name))}
+			if code, ok := gr.ExitCodeOf(name); ok {
+				n.ExitCode = &code
+			}
+			doc.Nodes = append(doc.Nodes, n)
+		}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
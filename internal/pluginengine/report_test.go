@@ -0,0 +1,96 @@
+package pluginengine
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/trace"
+)
+
+type reportingPlugin struct {
+	got []Report
+}
+
+func (p *reportingPlugin) Manifest() PluginManifest {
+	return PluginManifest{PluginID: "rep", Version: "1", Hooks: []string{ReportHook}}
+}
+
+func (p *reportingPlugin) Report(_ context.Context, r Report) error {
+	p.got = append(p.got, r)
+	return os.WriteFile(filepath.Join(r.Dir, "out.txt"), []byte(r.RunID), 0o644)
+}
+
+func runForReport(t *testing.T) *dag.GraphResult {
+	t.Helper()
+	g, err := dag.NewTaskGraph([]core.Task{{Name: "a", Run: "true"}, {Name: "b", Run: "true"}}, []dag.Edge{{From: "a", To: "b"}})
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	exec, err := dag.NewExecutor(g, okRunner{})
+	if err != nil {
+		t.Fatalf("NewExecutor: %v", err)
+	}
+	gr, err := exec.RunSerial(context.Background())
+	if err != nil {
+		t.Fatalf("RunSerial: %v", err)
+	}
+	return gr
+}
+
+func TestHookEngine_ReportReceivesEventsAndResult(t *testing.T) {
+	gr := runForReport(t)
+	p := &reportingPlugin{}
+	eng, err := NewHookEngine([]RuntimePlugin{p}, nil)
+	if err != nil {
+		t.Fatalf("NewHookEngine: %v", err)
+	}
+	base := t.TempDir()
+	eng.Report(context.Background(), base, NewReport("run1", 0, gr))
+
+	if len(p.got) != 1 {
+		t.Fatalf("Report calls = %d, want 1", len(p.got))
+	}
+	r := p.got[0]
+	if r.Result != gr || len(r.Events) != 4 || r.Events[0].Kind != trace.EventStageCompleted || r.Events[2].Kind != trace.EventTaskExecuted || r.Events[2].TaskID != "a" {
+		t.Fatalf("unexpected report: %#v", r)
+	}
+	if b, err := os.ReadFile(filepath.Join(base, "rep", "out.txt")); err != nil || string(b) != "run1" {
+		t.Fatalf("artifact = %q, %v", b, err)
+	}
+	if st := eng.Stats(); st[0].Hooks[ReportHook].Invocations != 1 {
+		t.Fatalf("stats = %#v", st)
+	}
+}
+
+func TestProcessPlugin_ReportReadsDocumentFromStdin(t *testing.T) {
+	root := t.TempDir()
+	writeExecPlugin(t, root, "html", `["Report"]`, "#!/bin/sh\ncat > \"$SCRIPTWEAVER_REPORT_DIR/report.json\"\n")
+	reg, _ := DiscoverAndRegister(root, nil)
+	plugins, err := Instantiate(reg)
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	eng, _ := NewHookEngine(plugins, nil)
+	base := t.TempDir()
+	eng.Report(context.Background(), base, NewReport("run2", 0, runForReport(t)))
+	if errs := eng.Errors(); len(errs) != 0 {
+		t.Fatalf("hook errors: %v", errs)
+	}
+
+	b, err := os.ReadFile(filepath.Join(base, "html", "report.json"))
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var doc reportDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if doc.RunID != "run2" || len(doc.Nodes) != 2 || doc.Nodes[0].Name != "a" || doc.Nodes[0].State != string(dag.TaskCompleted) || string(doc.Trace) == "null" {
+		t.Fatalf("unexpected document: %s", b)
+	}
+}
@@ -0,0 +1,114 @@
+package pluginengine
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+)
+
+// RunTaskHook is the name under which runner invocations are counted in
+// plugin stats and recorded in the audit log.
+const RunTaskHook = "RunTask"
+
+// runnerNamePattern constrains runner names, for example "kubernetes" or
+// "lambda".
+var runnerNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// TaskRunner is implemented by plugins that declare runners in their
+// manifest. RunTask executes task with the named runner and returns its
+// outcome. Declared outputs must be produced in workDir, where the engine
+// harvests and caches them exactly as for local execution.
+//
+// Unlike hook errors, a RunTask error fails the node.
+type TaskRunner interface {
+	RunTask(ctx context.Context, runner string, task core.Task, workDir string) (*core.ExecutionResult, error)
+}
+
+// Runners returns the runner names provided by the engine's plugins, mapped
+// to the providing plugin ID.
+func (e *HookEngine) Runners() map[string]string {
+	out := make(map[string]string)
+	if e == nil {
+		return out
+	}
+	for _, ent := range e.plug {
+		for _, name := range ent.runners {
+			out[name] = ent.id
+		}
+	}
+	return out
+}
+
+// CheckRunners reports an ErrUnknownRunner for the first node, in canonical
+// order, whose runner is not a node type registered with the engine's node
+// types, that is, that no plugin in the engine provides. It is the node type
+// check of graph files of sw run, whose tasks carry no inputs for a runner's
+// input schema to check. A nil engine provides no runners.
+func (e *HookEngine) CheckRunners(g *dag.TaskGraph) error {
+	types := e.NodeTypes()
+	for _, n := range g.Nodes() {
+		if n.Task.Runner == "" || types.Registered(n.Task.Runner) {
+			continue
+		}
+		err := fmt.Errorf("%w: node %q requires runner %q, which no enabled plugin provides", ErrUnknownRunner, n.Name, n.Task.Runner)
+		if hints := graph.DidYouMean(n.Task.Runner, types.Names()); len(hints) > 0 {
+			err = fmt.Errorf("%w; %s", err, hints[0].Message)
+		}
+		return err
+	}
+	return nil
+}
+
+// TaskExecutors returns a core.TaskExecutor for every provided runner, for use
+// as core.Runner.Runners. workDir is the task working directory.
+func (e *HookEngine) TaskExecutors(workDir string) map[string]core.TaskExecutor {
+	if e == nil {
+		return nil
+	}
+	out := make(map[string]core.TaskExecutor)
+	for _, ent := range e.plug {
+		for _, name := range ent.runners {
+			out[name] = &pluginExecutor{engine: e, ent: ent, runner: name, workDir: workDir}
+		}
+	}
+	return out
+}
+
+// pluginExecutor adapts a plugin runner to core.TaskExecutor.
+type pluginExecutor struct {
+	engine  *HookEngine
+	ent     pluginEntry
+	runner  string
+	workDir string
+}
+
+func (x *pluginExecutor) Execute(ctx context.Context, task *core.Task, hash core.TaskHash) (res *core.ExecutionResult, err error) {
+	e, id := x.engine, x.ent.id
+	tr, ok := x.ent.plugin.(TaskRunner)
+	if !ok {
+		e.stats.record(id, RunTaskHook, 0, false, true)
+		return nil, fmt.Errorf("plugin %s declares runner %s but does not implement RunTask", id, x.runner)
+	}
+	e.recordInvocation(id, RunTaskHook)
+	start := time.Now()
+	defer func() {
+		if rec := recover(); rec != nil {
+			res, err = nil, fmt.Errorf("plugin %s runner %s panic: %v", id, x.runner, rec)
+		}
+		e.stats.record(id, RunTaskHook, time.Since(start), true, err != nil)
+	}()
+	res, err = tr.RunTask(ctx, x.runner, *task, x.workDir)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s runner %s: %w", id, x.runner, err)
+	}
+	if res == nil {
+		return nil, fmt.Errorf("plugin %s runner %s returned no result", id, x.runner)
+	}
+	res.Hash = hash
+	return res, nil
+}
@@ -0,0 +1,167 @@
+package pluginengine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/graph"
+)
+
+type runnerPlugin struct {
+	id      string
+	runners []string
+	got     []string
+}
+
+func (p *runnerPlugin) Manifest() PluginManifest {
+	return PluginManifest{PluginID: p.id, Version: "1", Runners: p.runners}
+}
+
+func (p *runnerPlugin) RunTask(_ context.Context, runner string, task core.Task, _ string) (*core.ExecutionResult, error) {
+	p.got = append(p.got, runner+":"+task.Name)
+	return &core.ExecutionResult{Stdout: []byte("remote " + task.Run), ExitCode: 0}, nil
+}
+
+func TestValidatePluginManifest_Runners(t *testing.T) {
+	if err := ValidatePluginManifest(PluginManifest{PluginID: "k", Version: "1", Runners: []string{"kubernetes"}}); err != nil {
+		t.Fatalf("runner-only manifest rejected: %v", err)
+	}
+	if err := ValidatePluginManifest(PluginManifest{PluginID: "k", Version: "1", Runners: []string{"a", "a"}}); !errors.Is(err, ErrDuplicateRunner) {
+		t.Fatalf("expected ErrDuplicateRunner, got %v", err)
+	}
+	if err := ValidatePluginManifest(PluginManifest{PluginID: "k", Version: "1", Runners: []string{"bad name"}}); !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("expected ErrManifestInvalid, got %v", err)
+	}
+}
+
+func TestValidatePluginManifest_RunnerInputs(t *testing.T) {
+	inputs := map[string]graph.InputSchema{"lambda": {"function": {Type: graph.InputString, Required: true}}}
+	if err := ValidatePluginManifest(PluginManifest{PluginID: "k", Version: "1", Runners: []string{"lambda"}, RunnerInputs: inputs}); err != nil {
+		t.Fatalf("runner inputs rejected: %v", err)
+	}
+	if err := ValidatePluginManifest(PluginManifest{PluginID: "k", Version: "1", Runners: []string{"other"}, RunnerInputs: inputs}); !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("inputs of an undeclared runner: expected ErrManifestInvalid, got %v", err)
+	}
+	bad := map[string]graph.InputSchema{"lambda": {"function": {Type: "text"}}}
+	if err := ValidatePluginManifest(PluginManifest{PluginID: "k", Version: "1", Runners: []string{"lambda"}, RunnerInputs: bad}); !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("unknown input type: expected ErrManifestInvalid, got %v", err)
+	}
+}
+
+func TestHookEngine_RegistersRunnerInputs(t *testing.T) {
+	p := &inputsPlugin{runnerPlugin{id: "a", runners: []string{"pe-test-runner"}}}
+	e, err := NewHookEngine([]RuntimePlugin{p}, nil)
+	if err != nil {
+		t.Fatalf("NewHookEngine: %v", err)
+	}
+	schema, ok := e.NodeTypes().Lookup("pe-test-runner")
+	if !ok || !schema["function"].Required {
+		t.Fatalf("registered schema = %v, %v", schema, ok)
+	}
+	other, err := NewHookEngine([]RuntimePlugin{&runnerPlugin{id: "b", runners: []string{"other"}}}, nil)
+	if err != nil {
+		t.Fatalf("NewHookEngine: %v", err)
+	}
+	if _, ok := other.NodeTypes().Lookup("pe-test-runner"); ok {
+		t.Fatal("node type registered by one engine is visible to another")
+	}
+	if schema, ok := other.NodeTypes().Lookup("other"); !ok || schema != nil {
+		t.Fatalf("runner without inputs = %v, %v", schema, ok)
+	}
+}
+
+type inputsPlugin struct{ runnerPlugin }
+
+func (p *inputsPlugin) Manifest() PluginManifest {
+	m := p.runnerPlugin.Manifest()
+	m.RunnerInputs = map[string]graph.InputSchema{"pe-test-runner": {"function": {Type: graph.InputString, Required: true}}}
+	return m
+}
+
+func TestHookEngine_RunnerNamesAreUnique(t *testing.T) {
+	_, err := NewHookEngine([]RuntimePlugin{
+		&runnerPlugin{id: "a", runners: []string{"lambda"}},
+		&runnerPlugin{id: "b", runners: []string{"lambda"}},
+	}, nil)
+	if !errors.Is(err, ErrDuplicateRunner) {
+		t.Fatalf("expected ErrDuplicateRunner, got %v", err)
+	}
+}
+
+func TestHookEngine_CheckRunnersAndExecute(t *testing.T) {
+	g, err := dag.NewTaskGraph([]core.Task{{Name: "a", Run: "x", Runner: "lambda"}, {Name: "b", Run: "y"}}, nil)
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	var none *HookEngine
+	if err := none.CheckRunners(g); !errors.Is(err, ErrUnknownRunner) {
+		t.Fatalf("expected ErrUnknownRunner without plugins, got %v", err)
+	}
+
+	p := &runnerPlugin{id: "aws", runners: []string{"lambda"}}
+	eng, err := NewHookEngine([]RuntimePlugin{p}, nil)
+	if err != nil {
+		t.Fatalf("NewHookEngine: %v", err)
+	}
+	if err := eng.CheckRunners(g); err != nil {
+		t.Fatalf("CheckRunners: %v", err)
+	}
+	typo, err := dag.NewTaskGraph([]core.Task{{Name: "a", Run: "x", Runner: "lamda"}}, nil)
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	if err := eng.CheckRunners(typo); !errors.Is(err, ErrUnknownRunner) || !strings.Contains(err.Error(), `did you mean "lambda"?`) {
+		t.Fatalf("misspelled runner: %v", err)
+	}
+	ex := eng.TaskExecutors(t.TempDir())["lambda"]
+	if ex == nil {
+		t.Fatalf("no executor for lambda")
+	}
+	res, err := ex.Execute(context.Background(), &core.Task{Name: "a", Run: "x", Runner: "lambda"}, "h1")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if string(res.Stdout) != "remote x" || res.Hash != "h1" || len(p.got) != 1 || p.got[0] != "lambda:a" {
+		t.Fatalf("unexpected result %#v calls %v", res, p.got)
+	}
+	if s := eng.Stats()[0].Hooks[RunTaskHook]; s.Invocations != 1 || s.Errors != 0 {
+		t.Fatalf("stats = %#v", s)
+	}
+}
+
+func TestProcessPlugin_RunTaskMirrorsExitStatus(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "remote")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{"plugin_id":"remote","version":"1.0.0","runners":["ssh"],"description":""}`), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	script := "#!/bin/sh\necho \"$1 $2 $3 $SCRIPTWEAVER_WORKDIR\"\necho oops >&2\nexit 3\n"
+	if err := os.WriteFile(filepath.Join(dir, ExecutableName), []byte(script), 0o755); err != nil {
+		t.Fatalf("write executable: %v", err)
+	}
+	reg, errs := DiscoverAndRegister(root, nil)
+	if len(errs) != 0 {
+		t.Fatalf("errs = %v", errs)
+	}
+	plugins, err := Instantiate(reg)
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	eng, _ := NewHookEngine(plugins, nil)
+	res, err := eng.TaskExecutors("/work")["ssh"].Execute(context.Background(), &core.Task{Name: "build", Run: "make", Runner: "ssh"}, "h")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if string(res.Stdout) != "RunTask ssh build /work\n" || string(res.Stderr) != "oops\n" || res.ExitCode != 3 {
+		t.Fatalf("unexpected result: stdout=%q stderr=%q exit=%d", res.Stdout, res.Stderr, res.ExitCode)
+	}
+}
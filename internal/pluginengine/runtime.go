@@ -0,0 +1,242 @@
+package pluginengine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+// ExecutableName is the file a plugin directory may contain to implement its
+// hooks out of process.
+//
+// The executable is invoked once per hook as `plugin <Hook> [<task-id>]` with
+// the plugin directory as working directory. A non-zero exit status is reported
+// as a hook error. For the Report hook the run report is written to stdin as
+// JSON and SCRIPTWEAVER_REPORT_DIR names the artifact directory. For the
+// Validate hook the graph is written to stdin as JSON and the executable prints
+// its findings as a JSON array of {"code", "node", "message"} objects.
+//
+// Runners are invoked as `plugin RunTask <runner> <task-name>` with the task
+// definition as JSON on stdin and SCRIPTWEAVER_WORKDIR naming the task working
+// directory. The executable's stdout, stderr and exit status become the task's.
+//
+// `sw plugins doctor` invokes `plugin Handshake`, which must exit 0 without side
+// effects. Every invocation runs under the manifest's SandboxPolicy.
+const ExecutableName = "plugin"
+
+// RuntimeFactory instantiates an in-process plugin from its discovered
+// manifest and directory.
+type RuntimeFactory func(m PluginManifest, dir string) (RuntimePlugin, error)
+
+var (
+	runtimeMu        sync.Mutex
+	runtimeFactories = map[string]RuntimeFactory{}
+)
+
+// RegisterRuntime registers an in-process implementation for pluginID.
+// Registered implementations take precedence over a plugin executable.
+func RegisterRuntime(pluginID string, f RuntimeFactory) {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+	if f == nil {
+		delete(runtimeFactories, pluginID)
+		return
+	}
+	runtimeFactories[pluginID] = f
+}
+
+func runtimeFactory(pluginID string) RuntimeFactory {
+	runtimeMu.Lock()
+	defer runtimeMu.Unlock()
+	return runtimeFactories[pluginID]
+}
+
+// Filter returns the registry restricted to the plugin IDs in allow.
+// Every allowed ID must have been discovered and not rejected.
+func (r Registry) Filter(allow []string) (Registry, error) {
+	out := Registry{ByID: make(map[string]PluginManifest), Dirs: make(map[string]string)}
+	for _, id := range allow {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		m, ok := r.ByID[id]
+		if !ok {
+			if err, rejected := r.Rejected[id]; rejected {
+				return Registry{}, err
+			}
+			return Registry{}, fmt.Errorf("%w: %s", ErrPluginNotFound, id)
+		}
+		if _, dup := out.ByID[id]; dup {
+			continue
+		}
+		out.ByID[id] = m
+		if dir, ok := r.Dirs[id]; ok {
+			out.Dirs[id] = dir
+		}
+		out.Manifests = append(out.Manifests, m)
+	}
+	sort.Slice(out.Manifests, func(i, j int) bool { return out.Manifests[i].PluginID < out.Manifests[j].PluginID })
+	return out, nil
+}
+
+// Instantiate creates a RuntimePlugin for every manifest in the registry, in
+// plugin_id order. Each plugin uses its registered in-process implementation
+// when one exists and its plugin executable otherwise.
+func Instantiate(r Registry) ([]RuntimePlugin, error) {
+	plugins := make([]RuntimePlugin, 0, len(r.Manifests))
+	for _, m := range r.Manifests {
+		p, err := instantiate(m, r.Dirs[m.PluginID])
+		if err != nil {
+			return nil, err
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+func instantiate(m PluginManifest, dir string) (RuntimePlugin, error) {
+	if err := checkCompatible(m); err != nil {
+		return nil, err
+	}
+	if f := runtimeFactory(m.PluginID); f != nil {
+		p, err := f(m, dir)
+		if err != nil {
+			return nil, fmt.Errorf("instantiate plugin %s: %w", m.PluginID, err)
+		}
+		return p, nil
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoRuntime, m.PluginID)
+	}
+	exe := filepath.Join(dir, ExecutableName)
+	info, err := os.Stat(exe)
+	if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+		return nil, fmt.Errorf("%w: %s (expected executable %s)", ErrNoRuntime, m.PluginID, exe)
+	}
+	return &processPlugin{manifest: m, dir: dir, exe: exe}, nil
+}
+
+// processPlugin runs hooks through a plugin executable.
+type processPlugin struct {
+	manifest PluginManifest
+	dir      string
+	exe      string
+}
+
+func (p *processPlugin) Manifest() PluginManifest { return p.manifest }
+
+func (p *processPlugin) BeforeRun(ctx context.Context) error {
+	return p.invoke(ctx, "BeforeRun", nil, nil)
+}
+
+func (p *processPlugin) AfterRun(ctx context.Context) error {
+	return p.invoke(ctx, "AfterRun", nil, nil)
+}
+
+func (p *processPlugin) BeforeNode(ctx context.Context, taskID string) error {
+	return p.invoke(ctx, "BeforeNode", nil, nil, taskID)
+}
+
+func (p *processPlugin) AfterNode(ctx context.Context, taskID string) error {
+	return p.invoke(ctx, "AfterNode", nil, nil, taskID)
+}
+
+func (p *processPlugin) Report(ctx context.Context, r Report) error {
+	doc, err := marshalReport(r)
+	if err != nil {
+		return err
+	}
+	return p.invoke(ctx, ReportHook, doc, []string{"SCRIPTWEAVER_REPORT_DIR=" + r.Dir})
+}
+
+func (p *processPlugin) Validate(ctx context.Context, g *dag.TaskGraph) ([]Finding, error) {
+	doc, err := marshalGraph(g)
+	if err != nil {
+		return nil, err
+	}
+	var stdout bytes.Buffer
+	if err := p.run(ctx, ValidateHook, doc, &stdout, nil); err != nil {
+		return nil, err
+	}
+	return parseFindings(stdout.Bytes())
+}
+
+func (p *processPlugin) RunTask(ctx context.Context, runner string, task core.Task, workDir string) (*core.ExecutionResult, error) {
+	doc, err := json.Marshal(task)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := sandboxContext(ctx, p.policy())
+	defer cancel()
+	cmd := p.command(ctx, RunTaskHook, doc, []string{"SCRIPTWEAVER_RUNNER=" + runner, "SCRIPTWEAVER_WORKDIR=" + workDir}, runner, task.Name)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, p.timeoutError(RunTaskHook)
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			return nil, err
+		}
+		exitCode = exitErr.ExitCode()
+	}
+	return &core.ExecutionResult{Stdout: stdout.Bytes(), Stderr: stderr.Bytes(), ExitCode: exitCode}, nil
+}
+
+func (p *processPlugin) invoke(ctx context.Context, hook string, stdin []byte, env []string, args ...string) error {
+	return p.run(ctx, hook, stdin, nil, env, args...)
+}
+
+func (p *processPlugin) run(ctx context.Context, hook string, stdin []byte, stdout io.Writer, env []string, args ...string) error {
+	ctx, cancel := sandboxContext(ctx, p.policy())
+	defer cancel()
+	cmd := p.command(ctx, hook, stdin, env, args...)
+	cmd.Stdout = stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return p.timeoutError(hook)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func (p *processPlugin) timeoutError(hook string) error {
+	return fmt.Errorf("%w: %s after %dms", ErrHookTimeout, hook, p.policy().TimeoutMS)
+}
+
+// command builds the sandboxed invocation of the plugin executable.
+func (p *processPlugin) command(ctx context.Context, hook string, stdin []byte, env []string, args ...string) *exec.Cmd {
+	cmd := sandboxCommand(ctx, p.policy(), p.exe, append([]string{hook}, args...)...)
+	cmd.Dir = p.dir
+	cmd.Env = append([]string{
+		"PATH=" + os.Getenv("PATH"),
+		"SCRIPTWEAVER_PLUGIN_ID=" + p.manifest.PluginID,
+		"SCRIPTWEAVER_HOOK=" + hook,
+	}, env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	return cmd
+}
@@ -0,0 +1,131 @@
+package pluginengine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeExecPlugin(t *testing.T, root, id string, hooks string, script string) string {
+	t.Helper()
+	dir := filepath.Join(root, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	manifest := `{"plugin_id":"` + id + `","version":"1.0.0","hooks":` + hooks + `,"description":""}`
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0o644); err != nil {
+		t.Fatalf("write manifest: %v", err)
+	}
+	if script != "" {
+		if err := os.WriteFile(filepath.Join(dir, ExecutableName), []byte(script), 0o755); err != nil {
+			t.Fatalf("write executable: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestRegistryFilter_KeepsOnlyAllowlisted(t *testing.T) {
+	root := t.TempDir()
+	writeExecPlugin(t, root, "a", `["BeforeRun"]`, "")
+	writeExecPlugin(t, root, "b", `["BeforeRun"]`, "")
+
+	reg, errs := DiscoverAndRegister(root, nil)
+	if len(errs) != 0 {
+		t.Fatalf("errs = %v", errs)
+	}
+	got, err := reg.Filter([]string{"b", "b"})
+	if err != nil {
+		t.Fatalf("Filter: %v", err)
+	}
+	if len(got.Manifests) != 1 || got.Manifests[0].PluginID != "b" || got.Dirs["b"] != filepath.Join(root, "b") {
+		t.Fatalf("unexpected filtered registry: %#v", got)
+	}
+
+	if _, err := reg.Filter([]string{"missing"}); !errors.Is(err, ErrPluginNotFound) {
+		t.Fatalf("expected ErrPluginNotFound, got %v", err)
+	}
+}
+
+func TestInstantiate_ExecutableRunsDeclaredHooks(t *testing.T) {
+	root := t.TempDir()
+	logPath := filepath.Join(root, "calls.log")
+	script := "#!/bin/sh\necho \"$SCRIPTWEAVER_PLUGIN_ID $@\" >> " + logPath + "\n"
+	writeExecPlugin(t, root, "rec", `["BeforeRun","AfterNode"]`, script)
+
+	reg, _ := DiscoverAndRegister(root, nil)
+	plugins, err := Instantiate(reg)
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	eng, err := NewHookEngine(plugins, nil)
+	if err != nil {
+		t.Fatalf("NewHookEngine: %v", err)
+	}
+	ctx := context.Background()
+	eng.BeforeRun(ctx)
+	eng.BeforeNode(ctx, "t1")
+	eng.AfterNode(ctx, "t1")
+	if errs := eng.Errors(); len(errs) != 0 {
+		t.Fatalf("hook errors: %v", errs)
+	}
+
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read log: %v", err)
+	}
+	if got, want := string(b), "rec BeforeRun\nrec AfterNode t1\n"; got != want {
+		t.Fatalf("calls = %q, want %q", got, want)
+	}
+}
+
+func TestInstantiate_ExecutableFailureIsHookError(t *testing.T) {
+	root := t.TempDir()
+	writeExecPlugin(t, root, "bad", `["BeforeRun"]`, "#!/bin/sh\necho nope >&2\nexit 3\n")
+
+	reg, _ := DiscoverAndRegister(root, nil)
+	plugins, err := Instantiate(reg)
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	eng, _ := NewHookEngine(plugins, nil)
+	eng.BeforeRun(context.Background())
+	errs := eng.Errors()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "nope") {
+		t.Fatalf("errors = %v", errs)
+	}
+}
+
+func TestInstantiate_RegisteredRuntimeTakesPrecedence(t *testing.T) {
+	root := t.TempDir()
+	writeExecPlugin(t, root, "mem", `["BeforeRun"]`, "")
+
+	var calls []string
+	RegisterRuntime("mem", func(m PluginManifest, _ string) (RuntimePlugin, error) {
+		return &recordingPlugin{manifest: m, calls: &calls}, nil
+	})
+	t.Cleanup(func() { RegisterRuntime("mem", nil) })
+
+	reg, _ := DiscoverAndRegister(root, nil)
+	plugins, err := Instantiate(reg)
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	eng, _ := NewHookEngine(plugins, nil)
+	eng.BeforeRun(context.Background())
+	if len(calls) != 1 || calls[0] != "mem:BeforeRun" {
+		t.Fatalf("calls = %v", calls)
+	}
+}
+
+func TestInstantiate_MissingRuntimeFails(t *testing.T) {
+	root := t.TempDir()
+	writeExecPlugin(t, root, "none", `["BeforeRun"]`, "")
+
+	reg, _ := DiscoverAndRegister(root, nil)
+	if _, err := Instantiate(reg); !errors.Is(err, ErrNoRuntime) {
+		t.Fatalf("expected ErrNoRuntime, got %v", err)
+	}
+}
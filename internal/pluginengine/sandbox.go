@@ -0,0 +1,80 @@
+package pluginengine
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"scriptweaver/internal/platform"
+)
+
+// SandboxPolicy limits the resources a plugin executable may use. It is
+// declared under "sandbox" in manifest.json and enforced for every hook (and
+// runner) invocation of the executable; in-process plugins registered with
+// RegisterRuntime are trusted code and are not sandboxed.
+//
+// Network access is denied unless Network is true. Zero limits mean no cap.
+type SandboxPolicy struct {
+	// TimeoutMS caps the wall-clock time of one invocation.
+	TimeoutMS int `json:"timeout_ms,omitempty"`
+	// CPUSeconds caps the CPU time of one invocation.
+	CPUSeconds int `json:"cpu_seconds,omitempty"`
+	// MemoryMB caps the address space of the plugin process.
+	MemoryMB int `json:"memory_mb,omitempty"`
+	// Network allows network access.
+	Network bool `json:"network,omitempty"`
+}
+
+func validateSandbox(s *SandboxPolicy) error {
+	if s == nil {
+		return nil
+	}
+	if s.TimeoutMS < 0 || s.CPUSeconds < 0 || s.MemoryMB < 0 {
+		return fmt.Errorf("%w: sandbox limits must not be negative", ErrManifestInvalid)
+	}
+	return nil
+}
+
+// policy returns the plugin's sandbox policy; a manifest without one gets
+// the default: no resource caps and no network access.
+func (p *processPlugin) policy() SandboxPolicy {
+	if p.manifest.Sandbox == nil {
+		return SandboxPolicy{}
+	}
+	return *p.manifest.Sandbox
+}
+
+// sandboxContext applies the policy's wall-clock timeout to ctx.
+func sandboxContext(ctx context.Context, s SandboxPolicy) (context.Context, context.CancelFunc) {
+	if s.TimeoutMS > 0 {
+		return context.WithTimeout(ctx, time.Duration(s.TimeoutMS)*time.Millisecond)
+	}
+	return context.WithCancel(ctx)
+}
+
+// sandboxCommand builds the command that runs exe with args under s. CPU and
+// memory caps are applied as resource limits by a /bin/sh wrapper that then
+// execs the plugin, so the limits bind the plugin process itself.
+func sandboxCommand(ctx context.Context, s SandboxPolicy, exe string, args ...string) *exec.Cmd {
+	var limits string
+	if s.CPUSeconds > 0 {
+		limits += fmt.Sprintf("ulimit -t %d; ", s.CPUSeconds)
+	}
+	if s.MemoryMB > 0 {
+		limits += fmt.Sprintf("ulimit -v %d; ", s.MemoryMB*1024)
+	}
+	var cmd *exec.Cmd
+	if limits == "" {
+		cmd = exec.CommandContext(ctx, exe, args...)
+	} else {
+		cmd = exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", limits + `exec "$0" "$@"`, exe}, args...)...)
+	}
+	// Children that outlive a killed plugin must not keep the hook waiting on
+	// their output.
+	cmd.WaitDelay = time.Second
+	if !s.Network {
+		platform.IsolateNetwork(cmd)
+	}
+	return cmd
+}
@@ -0,0 +1,109 @@
+package pluginengine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sandboxedEngine instantiates a single exec plugin whose BeforeRun hook runs
+// script, with sandbox as the manifest's sandbox policy (raw JSON, may be empty).
+func sandboxedEngine(t *testing.T, sandbox, script string) *HookEngine {
+	t.Helper()
+	root := t.TempDir()
+	hooks := `["BeforeRun"]`
+	if sandbox != "" {
+		hooks += `,"sandbox":` + sandbox
+	}
+	writeExecPlugin(t, root, "sb", hooks, script)
+	reg, errs := DiscoverAndRegister(root, nil)
+	if len(errs) != 0 {
+		t.Fatalf("errs = %v", errs)
+	}
+	plugins, err := Instantiate(reg)
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	eng, err := NewHookEngine(plugins, nil)
+	if err != nil {
+		t.Fatalf("NewHookEngine: %v", err)
+	}
+	return eng
+}
+
+func TestValidatePluginManifest_RejectsNegativeSandboxLimits(t *testing.T) {
+	m := PluginManifest{PluginID: "p", Version: "1", Hooks: []string{"BeforeRun"}, Sandbox: &SandboxPolicy{MemoryMB: -1}}
+	if err := ValidatePluginManifest(m); !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("expected ErrManifestInvalid, got %v", err)
+	}
+}
+
+func TestSandbox_TimeoutStopsStalledHook(t *testing.T) {
+	eng := sandboxedEngine(t, `{"timeout_ms":100}`, "#!/bin/sh\nexec sleep 5\n")
+	start := time.Now()
+	eng.BeforeRun(context.Background())
+	if d := time.Since(start); d > 3*time.Second {
+		t.Fatalf("hook ran for %s despite timeout", d)
+	}
+	errs := eng.Errors()
+	if len(errs) != 1 || !errors.Is(errs[0], ErrHookTimeout) {
+		t.Fatalf("errors = %v", errs)
+	}
+}
+
+func TestSandbox_AppliesResourceLimits(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "limits")
+	eng := sandboxedEngine(t, `{"cpu_seconds":7,"memory_mb":512}`, "#!/bin/sh\necho \"$(ulimit -t) $(ulimit -v)\" > "+out+"\n")
+	eng.BeforeRun(context.Background())
+	if errs := eng.Errors(); len(errs) != 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read limits: %v", err)
+	}
+	if got := strings.TrimSpace(string(b)); got != "7 524288" {
+		t.Fatalf("limits = %q, want %q", got, "7 524288")
+	}
+}
+
+func TestSandbox_NetworkDeniedByDefault(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("network isolation is only enforced on Linux")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\ngrep -c : /proc/self/net/dev > " + filepath.Join(dir, "$SCRIPTWEAVER_PLUGIN_ID") + "\n"
+
+	denied := sandboxedEngine(t, "", strings.Replace(script, "$SCRIPTWEAVER_PLUGIN_ID", "denied", 1))
+	denied.BeforeRun(context.Background())
+	allowed := sandboxedEngine(t, `{"network":true}`, strings.Replace(script, "$SCRIPTWEAVER_PLUGIN_ID", "allowed", 1))
+	allowed.BeforeRun(context.Background())
+	if errs := append(denied.Errors(), allowed.Errors()...); len(errs) != 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+
+	host, err := os.ReadFile("/proc/self/net/dev")
+	if err != nil {
+		t.Skipf("no /proc/self/net/dev: %v", err)
+	}
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return strings.TrimSpace(string(b))
+	}
+	// Only loopback is visible in the isolated namespace.
+	if got := read("denied"); got != "1" {
+		t.Fatalf("denied plugin sees %s interfaces, want only loopback", got)
+	}
+	if got, want := read("allowed"), strconv.Itoa(strings.Count(string(host), ":")); got != want {
+		t.Fatalf("allowed plugin sees %s interfaces, want host's %s", got, want)
+	}
+}
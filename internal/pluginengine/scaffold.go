@@ -0,0 +1,254 @@
+package pluginengine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Scaffold protocols.
+const (
+	// ProtocolExec scaffolds a shell-script plugin executable.
+	ProtocolExec = "exec"
+	// ProtocolGo scaffolds a Go program that is built into the plugin
+	// executable.
+	ProtocolGo = "go"
+)
+
+// ScaffoldOptions describes the plugin to generate.
+type ScaffoldOptions struct {
+	PluginID string
+	// Protocol is ProtocolExec or ProtocolGo; empty means ProtocolExec.
+	Protocol string
+	// Hooks lists the hooks to declare; empty means every supported hook.
+	Hooks []string
+}
+
+// Scaffold creates a skeleton plugin in dir: a manifest.json, an example
+// implementation of every declared hook for the chosen protocol, and a test
+// that invokes each hook. dir must not exist or be empty. It returns the
+// created file names, relative to dir, in lexical order.
+func Scaffold(dir string, opts ScaffoldOptions) ([]string, error) {
+	id := strings.TrimSpace(opts.PluginID)
+	if id == "" || id != filepath.Base(id) || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid plugin id %q", opts.PluginID)
+	}
+	protocol := opts.Protocol
+	if protocol == "" {
+		protocol = ProtocolExec
+	}
+	hooks := opts.Hooks
+	if len(hooks) == 0 {
+		for h := range SupportedHooks() {
+			hooks = append(hooks, h)
+		}
+	}
+	hooks = append([]string(nil), hooks...)
+	sort.Strings(hooks)
+
+	m := PluginManifest{PluginID: id, Version: "0.1.0", Hooks: hooks, Description: "TODO: describe " + id}
+	if err := ValidatePluginManifest(m); err != nil {
+		return nil, err
+	}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]scaffoldFile{"manifest.json": {data: append(manifest, '\n'), mode: 0o644}}
+	switch protocol {
+	case ProtocolExec:
+		files[ExecutableName] = scaffoldFile{data: []byte(execPluginScript(hooks)), mode: 0o755}
+		files["plugin_test.sh"] = scaffoldFile{data: []byte(execPluginTest(id, hooks)), mode: 0o755}
+	case ProtocolGo:
+		files["go.mod"] = scaffoldFile{data: []byte("module swplugin\n\ngo 1.22\n"), mode: 0o644}
+		files["main.go"] = scaffoldFile{data: []byte(goPluginMain(hooks)), mode: 0o644}
+		files["main_test.go"] = scaffoldFile{data: []byte(goPluginTest(hooks)), mode: 0o644}
+	default:
+		return nil, fmt.Errorf("unknown plugin protocol %q (expected %s|%s)", protocol, ProtocolExec, ProtocolGo)
+	}
+
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("plugin directory %s is not empty", dir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create plugin dir: %w", err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := files[name]
+		if err := os.WriteFile(filepath.Join(dir, name), f.data, f.mode); err != nil {
+			return nil, fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return names, nil
+}
+
+type scaffoldFile struct {
+	data []byte
+	mode os.FileMode
+}
+
+func execPluginScript(hooks []string) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# ScriptWeaver plugin executable.\n")
+	b.WriteString("#\n")
+	b.WriteString("# Invoked once per declared hook as: plugin <Hook> [<task-id>]\n")
+	b.WriteString("# SCRIPTWEAVER_PLUGIN_ID and SCRIPTWEAVER_HOOK are set; the working directory\n")
+	b.WriteString("# is the plugin directory. A non-zero exit status is reported as a hook error\n")
+	b.WriteString("# but never fails the run.\n")
+	b.WriteString("set -eu\n\n")
+	b.WriteString("hook=\"${1:-}\"\n\n")
+	b.WriteString("case \"$hook\" in\n")
+	for _, h := range hooks {
+		if h == ReportHook {
+			b.WriteString("Report)\n\t# The run report (JSON) is on stdin; write artifacts to the report dir.\n\tcat > \"$SCRIPTWEAVER_REPORT_DIR/report.json\"\n\t;;\n")
+		} else if h == ValidateHook {
+			b.WriteString("Validate)\n\t# The graph (JSON) is on stdin. Print findings as a JSON array of\n\t# {\"code\", \"node\", \"message\"} objects; any finding fails validation.\n\tcat > /dev/null\n\techo '[]'\n\t;;\n")
+		} else if strings.HasSuffix(h, "Node") {
+			fmt.Fprintf(&b, "%s)\n\ttask_id=\"$2\"\n\t# TODO: handle %s for \"$task_id\".\n\t;;\n", h, h)
+		} else {
+			fmt.Fprintf(&b, "%s)\n\t# TODO: handle %s.\n\t;;\n", h, h)
+		}
+	}
+	b.WriteString("Handshake)\n\t# Health check by `sw plugins doctor`; must succeed without side effects.\n\t;;\n")
+	b.WriteString("*)\n\techo \"unsupported hook: $hook\" >&2\n\texit 1\n\t;;\nesac\n")
+	return b.String()
+}
+
+func execPluginTest(id string, hooks []string) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Invokes every declared hook the way the engine does and fails on the first\n")
+	b.WriteString("# hook that exits non-zero. Run from the plugin directory: ./plugin_test.sh\n")
+	b.WriteString("set -eu\n\n")
+	b.WriteString("cd \"$(dirname \"$0\")\"\n\n")
+	b.WriteString("report_dir=\"$(mktemp -d)\"\n")
+	b.WriteString("trap 'rm -rf \"$report_dir\"' EXIT\n\n")
+	b.WriteString("run_hook() {\n")
+	fmt.Fprintf(&b, "\tenv -i PATH=\"$PATH\" SCRIPTWEAVER_PLUGIN_ID=%s SCRIPTWEAVER_HOOK=\"$1\" SCRIPTWEAVER_REPORT_DIR=\"$report_dir\" ./plugin \"$@\" < /dev/null\n", shellQuote(id))
+	b.WriteString("\techo \"ok $1\"\n")
+	b.WriteString("}\n\n")
+	b.WriteString("run_hook Handshake\n")
+	for _, h := range hooks {
+		if strings.HasSuffix(h, "Node") {
+			fmt.Fprintf(&b, "run_hook %s example-task\n", h)
+		} else {
+			fmt.Fprintf(&b, "run_hook %s\n", h)
+		}
+	}
+	return b.String()
+}
+
+func goPluginMain(hooks []string) string {
+	var b strings.Builder
+	b.WriteString(`// Command plugin is a ScriptWeaver plugin executable.
+//
+// Build it next to manifest.json with:
+//
+//	go build -o plugin .
+//
+// The engine invokes it once per declared hook as: plugin <Hook> [<task-id>]
+// SCRIPTWEAVER_PLUGIN_ID and SCRIPTWEAVER_HOOK are set; the working directory
+// is the plugin directory. A non-zero exit status is reported as a hook error
+// but never fails the run. For Report, the run report (JSON) is on stdin and
+// SCRIPTWEAVER_REPORT_DIR names the directory for the plugin's artifacts. For
+// Validate, the graph (JSON) is on stdin and the findings are printed to stdout
+// as a JSON array of {"code", "node", "message"} objects.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing hook name")
+	}
+	switch args[0] {
+	case "Handshake":
+		// Health check by sw plugins doctor; must succeed without side effects.
+		return nil
+`)
+	for _, h := range hooks {
+		if strings.HasSuffix(h, "Node") {
+			fmt.Fprintf(&b, "\tcase %q:\n\t\tif len(args) < 2 {\n\t\t\treturn fmt.Errorf(\"%%s: missing task id\", args[0])\n\t\t}\n\t\treturn %s(args[1])\n", h, lowerFirst(h))
+		} else {
+			fmt.Fprintf(&b, "\tcase %q:\n\t\treturn %s()\n", h, lowerFirst(h))
+		}
+	}
+	b.WriteString("\tdefault:\n\t\treturn fmt.Errorf(\"unsupported hook: %s\", args[0])\n\t}\n}\n")
+	for _, h := range hooks {
+		if h == ReportHook {
+			b.WriteString("\nfunc report() error {\n\t// TODO: read the run report from os.Stdin and write artifacts to\n\t// os.Getenv(\"SCRIPTWEAVER_REPORT_DIR\").\n\treturn nil\n}\n")
+		} else if h == ValidateHook {
+			b.WriteString("\nfunc validate() error {\n\t// TODO: read the graph from os.Stdin and print findings, for example\n\t// [{\"code\": \"EXAMPLE\", \"node\": \"build\", \"message\": \"...\"}].\n\tfmt.Println(\"[]\")\n\treturn nil\n}\n")
+		} else if strings.HasSuffix(h, "Node") {
+			fmt.Fprintf(&b, "\nfunc %s(taskID string) error {\n\t// TODO: handle %s.\n\treturn nil\n}\n", lowerFirst(h), h)
+		} else {
+			fmt.Fprintf(&b, "\nfunc %s() error {\n\t// TODO: handle %s.\n\treturn nil\n}\n", lowerFirst(h), h)
+		}
+	}
+	return b.String()
+}
+
+func goPluginTest(hooks []string) string {
+	var b strings.Builder
+	b.WriteString(`package main
+
+import "testing"
+
+func TestHooks(t *testing.T) {
+	for _, args := range [][]string{
+		{"Handshake"},
+`)
+	for _, h := range hooks {
+		if strings.HasSuffix(h, "Node") {
+			fmt.Fprintf(&b, "\t\t{%q, \"example-task\"},\n", h)
+		} else {
+			fmt.Fprintf(&b, "\t\t{%q},\n", h)
+		}
+	}
+	b.WriteString(`	} {
+		if err := run(args); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+	}
+	if err := run([]string{"Unknown"}); err == nil {
+		t.Fatalf("expected unsupported hook to fail")
+	}
+}
+`)
+	return b.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
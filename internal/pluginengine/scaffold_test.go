@@ -0,0 +1,89 @@
+package pluginengine
+
+import (
+	"context"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScaffold_ExecPluginIsDiscoverableAndRunnable(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "hello")
+	files, err := Scaffold(dir, ScaffoldOptions{PluginID: "hello", Hooks: []string{"BeforeRun", "AfterNode"}})
+	if err != nil {
+		t.Fatalf("Scaffold: %v", err)
+	}
+	if want := []string{"manifest.json", "plugin", "plugin_test.sh"}; !reflect.DeepEqual(files, want) {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+
+	reg, errs := DiscoverAndRegister(root, nil)
+	if len(errs) != 0 || len(reg.Manifests) != 1 {
+		t.Fatalf("discover: %v %#v", errs, reg)
+	}
+	if got := reg.Manifests[0].Hooks; !reflect.DeepEqual(got, []string{"AfterNode", "BeforeRun"}) {
+		t.Fatalf("hooks = %v", got)
+	}
+	plugins, err := Instantiate(reg)
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	eng, _ := NewHookEngine(plugins, nil)
+	eng.BeforeRun(context.Background())
+	eng.AfterNode(context.Background(), "t1")
+	if errs := eng.Errors(); len(errs) != 0 {
+		t.Fatalf("hook errors: %v", errs)
+	}
+
+	if out, err := exec.Command(filepath.Join(dir, "plugin_test.sh")).CombinedOutput(); err != nil {
+		t.Fatalf("plugin_test.sh: %v\n%s", err, out)
+	}
+}
+
+func TestScaffold_GoPluginSourcesAreFormatted(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gop")
+	files, err := Scaffold(dir, ScaffoldOptions{PluginID: "gop", Protocol: ProtocolGo})
+	if err != nil {
+		t.Fatalf("Scaffold: %v", err)
+	}
+	if want := []string{"go.mod", "main.go", "main_test.go", "manifest.json"}; !reflect.DeepEqual(files, want) {
+		t.Fatalf("files = %v, want %v", files, want)
+	}
+	for _, name := range []string{"main.go", "main_test.go"} {
+		src, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		formatted, err := format.Source(src)
+		if err != nil {
+			t.Fatalf("%s does not parse: %v", name, err)
+		}
+		if string(formatted) != string(src) {
+			t.Fatalf("%s is not gofmt-clean:\n%s", name, src)
+		}
+	}
+}
+
+func TestScaffold_RejectsNonEmptyDirAndBadInput(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := Scaffold(dir, ScaffoldOptions{PluginID: "p"}); err == nil {
+		t.Fatalf("expected non-empty dir to be rejected")
+	}
+	empty := filepath.Join(t.TempDir(), "p")
+	if _, err := Scaffold(empty, ScaffoldOptions{PluginID: "../p"}); err == nil {
+		t.Fatalf("expected invalid id to be rejected")
+	}
+	if _, err := Scaffold(empty, ScaffoldOptions{PluginID: "p", Hooks: []string{"OnWhatever"}}); err == nil {
+		t.Fatalf("expected unsupported hook to be rejected")
+	}
+	if _, err := Scaffold(empty, ScaffoldOptions{PluginID: "p", Protocol: "grpc"}); err == nil {
+		t.Fatalf("expected unknown protocol to be rejected")
+	}
+}
@@ -0,0 +1,136 @@
+package pluginengine
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SignatureFileName is the optional file next to manifest.json that holds
+// the base64-encoded Ed25519 signature of the plugin directory.
+const SignatureFileName = "signature"
+
+// signatureHeader starts the signed payload and versions its format.
+const signatureHeader = "scriptweaver-plugin-signature-v1\n"
+
+// SignaturePayload returns the bytes a plugin signature covers: a header line
+// followed by one "<sha256>  <path>" line per file in dir (recursively, in
+// lexical path order, excluding the signature file itself). Any change to the
+// manifest, executable or other plugin files invalidates the signature.
+func SignaturePayload(dir string) ([]byte, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == SignatureFileName {
+			return nil
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var b bytes.Buffer
+	b.WriteString(signatureHeader)
+	for _, rel := range paths {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		fmt.Fprintf(&b, "%s  %s\n", hex.EncodeToString(sum[:]), rel)
+	}
+	return b.Bytes(), nil
+}
+
+// SignPlugin writes the signature file for the plugin in dir.
+func SignPlugin(dir string, key ed25519.PrivateKey) error {
+	payload, err := SignaturePayload(dir)
+	if err != nil {
+		return err
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+	return os.WriteFile(filepath.Join(dir, SignatureFileName), []byte(sig+"\n"), 0o644)
+}
+
+// VerifyPlugin checks the plugin in dir against the trusted keys. It returns
+// an error wrapping ErrUnsignedPlugin when the signature file is missing and
+// ErrInvalidSignature when no key verifies it.
+func VerifyPlugin(dir string, keys []ed25519.PublicKey) error {
+	raw, err := os.ReadFile(filepath.Join(dir, SignatureFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ErrUnsignedPlugin
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed signature file", ErrInvalidSignature)
+	}
+	payload, err := SignaturePayload(dir)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	for _, k := range keys {
+		if ed25519.Verify(k, payload, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no trusted key matches", ErrInvalidSignature)
+}
+
+// Verify checks every discovered plugin's signature against the trusted keys
+// and returns the registry of plugins that may be loaded. Plugins that are
+// unsigned or whose signature does not verify are skipped, with the reason
+// recorded in Rejected, unless allowUnsigned is set, in which case they are
+// kept and a warning is logged.
+//
+// Verification is enabled by trusting at least one key; with no keys Verify
+// returns r unchanged.
+func (r Registry) Verify(keys []ed25519.PublicKey, allowUnsigned bool, log Logger) Registry {
+	if len(keys) == 0 {
+		return r
+	}
+	log = loggerOrNop(log)
+	out := Registry{ByID: make(map[string]PluginManifest), Dirs: make(map[string]string), Rejected: make(map[string]error)}
+	for id, err := range r.Rejected {
+		out.Rejected[id] = err
+	}
+	for _, m := range r.Manifests {
+		id := m.PluginID
+		if err := VerifyPlugin(r.Dirs[id], keys); err != nil {
+			err = fmt.Errorf("plugin %s: %w", id, err)
+			if !allowUnsigned {
+				log.Printf("pluginengine: skipping %v", err)
+				out.Rejected[id] = err
+				continue
+			}
+			log.Printf("pluginengine: loading despite %v", err)
+		}
+		out.ByID[id] = m
+		out.Dirs[id] = r.Dirs[id]
+		out.Manifests = append(out.Manifests, m)
+	}
+	return out
+}
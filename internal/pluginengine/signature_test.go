@@ -0,0 +1,72 @@
+package pluginengine
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testKey(t *testing.T, seed byte) ed25519.PrivateKey {
+	t.Helper()
+	s := make([]byte, ed25519.SeedSize)
+	s[0] = seed
+	return ed25519.NewKeyFromSeed(s)
+}
+
+func TestVerifyPlugin_SignedUnsignedTampered(t *testing.T) {
+	root := t.TempDir()
+	dir := writeExecPlugin(t, root, "p", `["BeforeRun"]`, "#!/bin/sh\n")
+	key := testKey(t, 1)
+	trusted := []ed25519.PublicKey{testKey(t, 2).Public().(ed25519.PublicKey), key.Public().(ed25519.PublicKey)}
+
+	if err := VerifyPlugin(dir, trusted); !errors.Is(err, ErrUnsignedPlugin) {
+		t.Fatalf("expected ErrUnsignedPlugin, got %v", err)
+	}
+	if err := SignPlugin(dir, key); err != nil {
+		t.Fatalf("SignPlugin: %v", err)
+	}
+	if err := VerifyPlugin(dir, trusted); err != nil {
+		t.Fatalf("VerifyPlugin: %v", err)
+	}
+	if err := VerifyPlugin(dir, trusted[:1]); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for untrusted key, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ExecutableName), []byte("#!/bin/sh\ncurl evil\n"), 0o755); err != nil {
+		t.Fatalf("tamper: %v", err)
+	}
+	if err := VerifyPlugin(dir, trusted); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature after tampering, got %v", err)
+	}
+}
+
+func TestRegistryVerify_SkipsUnverifiedUnlessAllowed(t *testing.T) {
+	root := t.TempDir()
+	key := testKey(t, 1)
+	keys := []ed25519.PublicKey{key.Public().(ed25519.PublicKey)}
+	if err := SignPlugin(writeExecPlugin(t, root, "signed", `["BeforeRun"]`, "#!/bin/sh\n"), key); err != nil {
+		t.Fatalf("SignPlugin: %v", err)
+	}
+	writeExecPlugin(t, root, "unsigned", `["BeforeRun"]`, "#!/bin/sh\n")
+	reg, _ := DiscoverAndRegister(root, nil)
+
+	if got := reg.Verify(nil, false, nil); len(got.Manifests) != 2 {
+		t.Fatalf("verification without keys should keep all plugins, got %v", got.Manifests)
+	}
+
+	got := reg.Verify(keys, false, nil)
+	if len(got.Manifests) != 1 || got.Manifests[0].PluginID != "signed" {
+		t.Fatalf("Manifests = %v", got.Manifests)
+	}
+	if _, err := got.Filter([]string{"unsigned"}); !errors.Is(err, ErrUnsignedPlugin) {
+		t.Fatalf("expected ErrUnsignedPlugin from Filter, got %v", err)
+	}
+	if _, err := got.Filter([]string{"signed"}); err != nil {
+		t.Fatalf("Filter signed: %v", err)
+	}
+
+	if got := reg.Verify(keys, true, nil); len(got.Manifests) != 2 || len(got.Rejected) != 0 {
+		t.Fatalf("allowUnsigned: %#v", got)
+	}
+}
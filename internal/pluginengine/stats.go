@@ -0,0 +1,110 @@
+package pluginengine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StatsFileName is the run file that holds the plugin hook statistics of a run.
+const StatsFileName = "plugin-stats.json"
+
+// HookStats summarizes the invocations of one hook of one plugin.
+type HookStats struct {
+	Invocations int           `json:"invocations"`
+	Duration    time.Duration `json:"duration_ns"`
+	Errors      int           `json:"errors"`
+}
+
+// PluginStats summarizes a plugin's hook activity during a run. The totals
+// are the sums over Hooks.
+type PluginStats struct {
+	PluginID    string               `json:"plugin_id"`
+	Invocations int                  `json:"invocations"`
+	Duration    time.Duration        `json:"duration_ns"`
+	Errors      int                  `json:"errors"`
+	Hooks       map[string]HookStats `json:"hooks"`
+}
+
+// hookStats accumulates per-plugin, per-hook counters.
+type hookStats struct {
+	mu   sync.Mutex
+	byID map[string]map[string]HookStats
+}
+
+// record adds one hook outcome. invoked is false when the hook could not be
+// called at all, which counts as an error without an invocation.
+func (s *hookStats) record(pluginID, hook string, d time.Duration, invoked, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byID == nil {
+		s.byID = make(map[string]map[string]HookStats)
+	}
+	hooks := s.byID[pluginID]
+	if hooks == nil {
+		hooks = make(map[string]HookStats)
+		s.byID[pluginID] = hooks
+	}
+	h := hooks[hook]
+	if invoked {
+		h.Invocations++
+		h.Duration += d
+	}
+	if failed {
+		h.Errors++
+	}
+	hooks[hook] = h
+}
+
+// Stats returns a snapshot of hook statistics for every plugin in the engine,
+// in plugin_id order. Plugins whose hooks never ran are included with zero
+// counts.
+func (e *HookEngine) Stats() []PluginStats {
+	if e == nil {
+		return nil
+	}
+	e.stats.mu.Lock()
+	defer e.stats.mu.Unlock()
+	out := make([]PluginStats, 0, len(e.plug))
+	for _, ent := range e.plug {
+		ps := PluginStats{PluginID: ent.id, Hooks: make(map[string]HookStats)}
+		for hook, h := range e.stats.byID[ent.id] {
+			ps.Hooks[hook] = h
+			ps.Invocations += h.Invocations
+			ps.Duration += h.Duration
+			ps.Errors += h.Errors
+		}
+		out = append(out, ps)
+	}
+	return out
+}
+
+// MarshalStats encodes stats for StatsFileName.
+func MarshalStats(stats []PluginStats) ([]byte, error) {
+	sorted := make([]PluginStats, len(stats))
+	copy(sorted, stats)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PluginID < sorted[j].PluginID })
+	b, err := json.MarshalIndent(sorted, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// ParseStats decodes the contents of StatsFileName.
+func ParseStats(data []byte) ([]PluginStats, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var stats []PluginStats
+	if err := dec.Decode(&stats); err != nil {
+		return nil, fmt.Errorf("parse plugin stats: %w", err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return nil, fmt.Errorf("parse plugin stats: trailing data")
+	}
+	return stats, nil
+}
@@ -0,0 +1,49 @@
+package pluginengine
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHookEngine_StatsCountInvocationsAndErrors(t *testing.T) {
+	var calls []string
+	ok := &recordingPlugin{manifest: PluginManifest{PluginID: "a", Version: "1", Hooks: []string{"BeforeRun", "AfterNode"}}, calls: &calls}
+	bad := &recordingPlugin{manifest: PluginManifest{PluginID: "b", Version: "1", Hooks: []string{"AfterNode"}}, calls: &calls, errAfterNode: errors.New("nope")}
+	idle := &recordingPlugin{manifest: PluginManifest{PluginID: "c", Version: "1", Hooks: []string{"AfterRun"}}, calls: &calls}
+
+	eng, err := NewHookEngine([]RuntimePlugin{idle, bad, ok}, nil)
+	if err != nil {
+		t.Fatalf("NewHookEngine: %v", err)
+	}
+	ctx := context.Background()
+	eng.BeforeRun(ctx)
+	eng.AfterNode(ctx, "t1")
+	eng.AfterNode(ctx, "t2")
+
+	stats := eng.Stats()
+	if len(stats) != 3 || stats[0].PluginID != "a" || stats[1].PluginID != "b" || stats[2].PluginID != "c" {
+		t.Fatalf("unexpected stats order: %#v", stats)
+	}
+	if stats[0].Invocations != 3 || stats[0].Errors != 0 || stats[0].Hooks["AfterNode"].Invocations != 2 {
+		t.Fatalf("plugin a stats = %#v", stats[0])
+	}
+	if stats[1].Invocations != 2 || stats[1].Errors != 2 {
+		t.Fatalf("plugin b stats = %#v", stats[1])
+	}
+	if stats[2].Invocations != 0 || len(stats[2].Hooks) != 0 {
+		t.Fatalf("plugin c stats = %#v", stats[2])
+	}
+
+	data, err := MarshalStats(stats)
+	if err != nil {
+		t.Fatalf("MarshalStats: %v", err)
+	}
+	back, err := ParseStats(data)
+	if err != nil {
+		t.Fatalf("ParseStats: %v", err)
+	}
+	if len(back) != 3 || back[1].Errors != 2 || back[0].Hooks["BeforeRun"].Invocations != 1 {
+		t.Fatalf("round trip = %#v", back)
+	}
+}
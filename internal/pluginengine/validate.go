@@ -0,0 +1,150 @@
+package pluginengine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+// ValidateHook is the manifest hook name declared by validator plugins.
+const ValidateHook = "Validate"
+
+// ruleCodePattern constrains rule codes so they stay stable, greppable
+// identifiers (for example "NO_NETWORK" or "naming.kebab-case").
+var ruleCodePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*$`)
+
+// Finding is one semantic validation problem reported by a validator plugin.
+type Finding struct {
+	// PluginID is set by the engine to the reporting plugin.
+	PluginID string `json:"plugin_id"`
+	// Code is the plugin's stable rule code.
+	Code string `json:"code"`
+	// Node names the offending task; empty for graph-level findings.
+	Node    string `json:"node,omitempty"`
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Node == "" {
+		return fmt.Sprintf("%s/%s: %s", f.PluginID, f.Code, f.Message)
+	}
+	return fmt.Sprintf("%s/%s %s: %s", f.PluginID, f.Code, f.Node, f.Message)
+}
+
+// Validator is implemented by plugins that declare the Validate hook.
+// Validate is invoked once per graph, after structural validation and before
+// execution, and returns the plugin's findings. A returned error means the
+// validator itself failed; it is logged and recorded like other hook errors
+// and does not count as a finding.
+type Validator interface {
+	Validate(ctx context.Context, g *dag.TaskGraph) ([]Finding, error)
+}
+
+// Validate runs every validator plugin against g in plugin_id order and
+// returns their findings sorted by plugin, code, node and message. Findings
+// with an invalid rule code are rejected as hook errors.
+func (e *HookEngine) Validate(ctx context.Context, g *dag.TaskGraph) []Finding {
+	if e == nil {
+		return nil
+	}
+	var out []Finding
+	for _, ent := range e.plug {
+		if _, ok := ent.hooks[ValidateHook]; !ok {
+			continue
+		}
+		v, ok := ent.plugin.(Validator)
+		if !ok {
+			err := fmt.Errorf("plugin %s declares Validate but does not implement it", ent.id)
+			e.log.Printf("pluginengine: %v", err)
+			e.recordError(err)
+			e.stats.record(ent.id, ValidateHook, 0, false, true)
+			continue
+		}
+		e.recordInvocation(ent.id, ValidateHook)
+		start := time.Now()
+		failed := false
+		func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					failed = true
+					err := fmt.Errorf("plugin %s hook Validate panic: %v", ent.id, rec)
+					e.log.Printf("pluginengine: %v", err)
+					e.recordError(err)
+				}
+			}()
+			findings, err := v.Validate(ctx, g)
+			if err != nil {
+				failed = true
+				err2 := fmt.Errorf("plugin %s hook Validate error: %w", ent.id, err)
+				e.log.Printf("pluginengine: %v", err2)
+				e.recordError(err2)
+				return
+			}
+			for _, f := range findings {
+				if !ruleCodePattern.MatchString(f.Code) {
+					failed = true
+					err := fmt.Errorf("plugin %s hook Validate: invalid rule code %q", ent.id, f.Code)
+					e.log.Printf("pluginengine: %v", err)
+					e.recordError(err)
+					continue
+				}
+				f.PluginID = ent.id
+				out = append(out, f)
+			}
+		}()
+		e.stats.record(ent.id, ValidateHook, time.Since(start), true, failed)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.PluginID != b.PluginID {
+			return a.PluginID < b.PluginID
+		}
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		if a.Node != b.Node {
+			return a.Node < b.Node
+		}
+		return a.Message < b.Message
+	})
+	return out
+}
+
+// graphDocument is the JSON a plugin executable reads from stdin for the
+// Validate hook. It has the shape of a graph definition file.
+type graphDocument struct {
+	Tasks []core.Task `json:"tasks"`
+	Edges []dag.Edge  `json:"edges"`
+}
+
+func marshalGraph(g *dag.TaskGraph) ([]byte, error) {
+	doc := graphDocument{Tasks: []core.Task{}, Edges: []dag.Edge{}}
+	for _, n := range g.Nodes() {
+		doc.Tasks = append(doc.Tasks, n.Task)
+	}
+	doc.Edges = append(doc.Edges, g.Edges()...)
+	return json.Marshal(doc)
+}
+
+// parseFindings decodes the findings a plugin executable prints for the
+// Validate hook: a JSON array of {"code", "node", "message"} objects. Empty
+// output means no findings.
+func parseFindings(out []byte) ([]Finding, error) {
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.DisallowUnknownFields()
+	var findings []Finding
+	if err := dec.Decode(&findings); err != nil {
+		return nil, fmt.Errorf("parse findings: %w", err)
+	}
+	return findings, nil
+}
@@ -0,0 +1,102 @@
+package pluginengine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+)
+
+type validatingPlugin struct {
+	id       string
+	findings []Finding
+	err      error
+}
+
+func (p *validatingPlugin) Manifest() PluginManifest {
+	return PluginManifest{PluginID: p.id, Version: "1", Hooks: []string{ValidateHook}}
+}
+
+func (p *validatingPlugin) Validate(context.Context, *dag.TaskGraph) ([]Finding, error) {
+	return p.findings, p.err
+}
+
+func validateGraph(t *testing.T) *dag.TaskGraph {
+	t.Helper()
+	g, err := dag.NewTaskGraph([]core.Task{{Name: "a", Run: "curl example.com"}, {Name: "b", Run: "true"}}, []dag.Edge{{From: "a", To: "b"}})
+	if err != nil {
+		t.Fatalf("NewTaskGraph: %v", err)
+	}
+	return g
+}
+
+func TestHookEngine_ValidateCollectsSortedFindings(t *testing.T) {
+	eng, err := NewHookEngine([]RuntimePlugin{
+		&validatingPlugin{id: "zeta", findings: []Finding{{Code: "R2", Node: "b", Message: "m"}, {PluginID: "spoofed", Code: "R1", Message: "graph-level"}}},
+		&validatingPlugin{id: "alpha", findings: []Finding{{Code: "NO_NETWORK", Node: "a", Message: "uses curl"}}},
+		&validatingPlugin{id: "broken", err: errors.New("boom")},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewHookEngine: %v", err)
+	}
+	got := eng.Validate(context.Background(), validateGraph(t))
+	want := []string{
+		"alpha/NO_NETWORK a: uses curl",
+		"zeta/R1: graph-level",
+		"zeta/R2 b: m",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findings = %v", got)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Fatalf("finding %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if errs := eng.Errors(); len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken") {
+		t.Fatalf("errors = %v", errs)
+	}
+	for _, s := range eng.Stats() {
+		if s.Hooks[ValidateHook].Invocations != 1 {
+			t.Fatalf("stats %s = %#v", s.PluginID, s)
+		}
+	}
+}
+
+func TestHookEngine_ValidateRejectsInvalidRuleCode(t *testing.T) {
+	eng, _ := NewHookEngine([]RuntimePlugin{
+		&validatingPlugin{id: "p", findings: []Finding{{Code: "", Message: "no code"}, {Code: "has space", Message: "x"}, {Code: "OK", Message: "kept"}}},
+	}, nil)
+	got := eng.Validate(context.Background(), validateGraph(t))
+	if len(got) != 1 || got[0].Code != "OK" {
+		t.Fatalf("findings = %v", got)
+	}
+	if errs := eng.Errors(); len(errs) != 2 {
+		t.Fatalf("errors = %v", errs)
+	}
+}
+
+func TestProcessPlugin_ValidateParsesFindingsFromStdout(t *testing.T) {
+	root := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"if grep -q curl; then\n" +
+		"  echo '[{\"code\":\"NO_NETWORK\",\"node\":\"a\",\"message\":\"network access\"}]'\n" +
+		"fi\n"
+	writeExecPlugin(t, root, "net", `["Validate"]`, script)
+	reg, _ := DiscoverAndRegister(root, nil)
+	plugins, err := Instantiate(reg)
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	eng, _ := NewHookEngine(plugins, nil)
+	got := eng.Validate(context.Background(), validateGraph(t))
+	if errs := eng.Errors(); len(errs) != 0 {
+		t.Fatalf("hook errors: %v", errs)
+	}
+	if len(got) != 1 || got[0].String() != "net/NO_NETWORK a: network access" {
+		t.Fatalf("findings = %v", got)
+	}
+}
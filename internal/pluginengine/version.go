@@ -0,0 +1,120 @@
+package pluginengine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// APIVersion is the version of the plugin protocol this engine implements.
+// Manifests may constrain it with "requires".
+const APIVersion = "1.0.0"
+
+type semver [3]int
+
+func parseSemver(s string) (semver, error) {
+	var v semver
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("version %q is not MAJOR.MINOR.PATCH", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || p != strconv.Itoa(n) {
+			return v, fmt.Errorf("version %q is not MAJOR.MINOR.PATCH", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+func (v semver) compare(o semver) int {
+	for i := range v {
+		if v[i] != o[i] {
+			if v[i] < o[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionTerm is one comparison of a version constraint, such as ">=1.2.0".
+type versionTerm struct {
+	op string
+	v  semver
+}
+
+// parseConstraint parses a comma-separated list of comparisons with the
+// operators =, >, >=, < and <=, for example ">=1.0.0, <2.0.0".
+func parseConstraint(s string) ([]versionTerm, error) {
+	var terms []versionTerm
+	for _, raw := range strings.Split(s, ",") {
+		raw = strings.TrimSpace(raw)
+		op := ""
+		for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(raw, candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("constraint %q: term %q has no operator (=, >, >=, <, <=)", s, raw)
+		}
+		v, err := parseSemver(strings.TrimPrefix(raw, op))
+		if err != nil {
+			return nil, fmt.Errorf("constraint %q: %w", s, err)
+		}
+		terms = append(terms, versionTerm{op: op, v: v})
+	}
+	return terms, nil
+}
+
+// satisfies reports whether version satisfies every term of constraint.
+func satisfies(version, constraint string) (bool, error) {
+	v, err := parseSemver(version)
+	if err != nil {
+		return false, err
+	}
+	terms, err := parseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range terms {
+		c := v.compare(t.v)
+		ok := false
+		switch t.op {
+		case "=":
+			ok = c == 0
+		case ">":
+			ok = c > 0
+		case ">=":
+			ok = c >= 0
+		case "<":
+			ok = c < 0
+		case "<=":
+			ok = c <= 0
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// checkCompatible reports an ErrIncompatiblePlugin when the manifest's
+// requires constraint excludes APIVersion.
+func checkCompatible(m PluginManifest) error {
+	if m.Requires == "" {
+		return nil
+	}
+	ok, err := satisfies(APIVersion, m.Requires)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrManifestInvalid, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s requires plugin API %s, engine provides %s", ErrIncompatiblePlugin, m.PluginID, m.Requires, APIVersion)
+	}
+	return nil
+}
@@ -1,23 +1,211 @@
 package config
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+
+	"scriptweaver/internal/cron"
 )
 
 // Config is the integration-specific configuration loaded from
 // <projectRoot>/.scriptweaver/config.json.
 //
-// Strictness: Only graph_path is permitted. Any other field causes an error.
+// Strictness: Only graph_path, publish, plugin_keys, cache, cache_encryption, hash_algorithm, label_limits, retention, notify, schedules and run_queue are permitted. Any other field causes an error.
 //
 // Determinism: No environment variables and no global config locations are used.
 // The only config location is .scriptweaver/config.json under the project root.
 type Config struct {
 	GraphPath string
+	// Publish is nil unless a publish step is configured.
+	Publish *PublishConfig
+	// PluginKeys are the Ed25519 public keys trusted to sign plugins. When
+	// any are configured, plugins must carry a valid signature to load.
+	PluginKeys []ed25519.PublicKey
+	// Cache is nil unless shared cache tiers are configured.
+	Cache *CacheConfig
+	// CacheEncryption is nil unless the local cache is encrypted.
+	CacheEncryption *CacheEncryptionConfig
+	// HashAlgorithm computes task hashes: HashSHA256 (the default when
+	// empty) or HashBLAKE3.
+	HashAlgorithm string
+	// LabelLimits caps the number of tasks carrying a label that run at
+	// once; nil when no limits are configured.
+	LabelLimits map[string]int
+	// Retention is nil unless old runs are purged after each run.
+	Retention *RetentionConfig
+	// Notify is nil unless run outcomes are posted to a chat webhook.
+	Notify *NotifyConfig
+	// Schedules are the graphs `sw daemon` runs on cron schedules.
+	Schedules []ScheduleConfig
+	// RunQueue is nil unless runs requested while another is active are
+	// handled other than by queueing them without bound.
+	RunQueue *RunQueueConfig
+}
+
+// RunQueueConfig decides what happens to a run requested of `sw daemon` while
+// another run of the project is active.
+type RunQueueConfig struct {
+	// Policy is QueueWait (the default), QueueCoalesce or QueueReject.
+	Policy string
+	// MaxQueued caps the runs waiting; a run requested when the queue is
+	// full is rejected. Zero means no limit.
+	MaxQueued int
+}
+
+// Run queue policies. QueueWait runs requests in turn; QueueCoalesce runs a
+// request identical to one already waiting only once, answering both with
+// its result; QueueReject refuses requests while a run is active.
+const (
+	QueueWait     = "queue"
+	QueueCoalesce = "coalesce"
+	QueueReject   = "reject"
+)
+
+// ScheduleConfig runs a graph on a cron schedule while `sw daemon` serves the
+// project.
+type ScheduleConfig struct {
+	// Name identifies the schedule in run records and listings.
+	Name string
+	// Cron is the cron expression (see package cron), in the daemon's local
+	// time.
+	Cron string
+	// Graph is the graph file, relative to the project root unless absolute;
+	// empty selects the config's graph_path.
+	Graph string
+	// Mode is the execution mode: ScheduleIncremental (the default) or
+	// ScheduleClean.
+	Mode string
+	// OutputDir is the output directory, inside the project root; empty
+	// selects .sw/schedules/<name>.
+	OutputDir string
+	// Set gives values to graph parameters, like --set.
+	Set map[string]string
+}
+
+// Execution modes of scheduled runs.
+const (
+	ScheduleIncremental = "incremental"
+	ScheduleClean       = "clean"
+)
+
+// scheduleNamePattern restricts schedule names to a single path segment, so
+// a name can select a default output directory.
+var scheduleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// NotifyConfig posts run outcomes to a Slack or Microsoft Teams incoming
+// webhook; exactly one of WebhookURL and WebhookURLFile is set.
+type NotifyConfig struct {
+	// Format is the payload format: NotifySlack or NotifyTeams.
+	Format string
+	// WebhookURL is the webhook to post to. WebhookURLFile instead names a
+	// file holding it, relative to the project root unless absolute, so the
+	// secret URL need not be committed with the config.
+	WebhookURL     string
+	WebhookURLFile string
+	// On is the notify-on policy: NotifyAlways, NotifyFailure (the default)
+	// or NotifyRecovery.
+	On string
+	// Template is the message, with {{name}} placeholders for NotifyFields;
+	// empty selects a default message.
+	Template string
+	// Link, when set, is the URL of a run's report, with {{run_id}}
+	// replaced; it is appended to the message as a link.
+	Link string
+}
+
+// Notification formats.
+const (
+	NotifySlack = "slack"
+	NotifyTeams = "teams"
+)
+
+// Notify-on policies. NotifyRecovery notifies failed runs and the first
+// successful run after a failed one.
+const (
+	NotifyAlways   = "always"
+	NotifyFailure  = "failure"
+	NotifyRecovery = "recovery"
+)
+
+// NotifyFields are the placeholders a notification template may use.
+var NotifyFields = []string{"run_id", "status", "exit_code", "graph", "failed_nodes", "duration", "link"}
+
+// RetentionConfig bounds the run records kept under .scriptweaver/runs. Runs
+// are purged oldest first; zero leaves a bound unset.
+type RetentionConfig struct {
+	// KeepRuns is the number of most recent runs kept.
+	KeepRuns int
+	// MaxBytes caps the total size of the kept runs.
+	MaxBytes int64
+}
+
+// Hash algorithms.
+const (
+	HashSHA256 = "sha256"
+	HashBLAKE3 = "blake3"
+)
+
+// CacheConfig adds shared tiers behind the local artifact cache.
+type CacheConfig struct {
+	// Tiers are cache locations read, in order, after the local cache:
+	// directories, typically on a shared network filesystem, relative to the
+	// project root unless absolute, or s3://bucket/prefix and
+	// gs://bucket/prefix object store URLs.
+	Tiers []string
+	// Write is the write policy: "write-local" (the default) writes results
+	// to the local cache only, "write-all" to every tier.
+	Write string
+	// Region and Endpoint locate the object store tiers; both are optional.
+	Region   string
+	Endpoint string
+	// Namespace "graph" stores entries of object store tiers under a prefix
+	// per graph hash; empty stores all entries under the tier's prefix.
+	Namespace string
+	// Concurrency bounds the requests in flight to each object store tier;
+	// UploadBytesPerSec and DownloadBytesPerSec cap its bandwidth. Zero
+	// means no limit.
+	Concurrency         int
+	UploadBytesPerSec   int64
+	DownloadBytesPerSec int64
+}
+
+// CacheEncryptionConfig locates the keys the local cache is encrypted with;
+// exactly one of KeyFile and Keychain is set. Keys are never read from the
+// environment.
+type CacheEncryptionConfig struct {
+	// KeyFile is a file of base64-encoded keys, one per line with the
+	// current key first, relative to the project root unless absolute.
+	KeyFile string
+	// Keychain is the service name of the OS keychain item holding the keys
+	// in the same format.
+	Keychain string
+}
+
+// Cache write policies.
+const (
+	CacheWriteLocal = "write-local"
+	CacheWriteAll   = "write-all"
+)
+
+// CacheNamespaceGraph partitions object store tiers by graph hash.
+const CacheNamespaceGraph = "graph"
+
+// PublishConfig selects outputs to publish after every successful run.
+type PublishConfig struct {
+	// Destination is a directory, relative to the project root unless absolute.
+	Destination string
+	// Outputs are declared task outputs to publish, relative to the project root.
+	Outputs []string
 }
 
 var (
@@ -28,6 +216,16 @@ var (
 //
 // Allowed fields:
 // - graph_path (string, non-empty)
+// - publish (object: destination string, outputs non-empty string array)
+// - plugin_keys (non-empty array of base64-encoded Ed25519 public keys)
+// - cache (object: tiers non-empty string array; write, region, endpoint, namespace, concurrency, upload_bytes_per_sec, download_bytes_per_sec)
+// - cache_encryption (object: key_file or keychain, non-empty string)
+// - hash_algorithm (string: sha256 or blake3)
+// - label_limits (object: label to positive integer)
+// - retention (object: keep_runs and max_bytes, non-negative integers, not both zero)
+// - notify (object: format slack or teams, webhook_url or webhook_url_file, on always, failure or recovery, template, link)
+// - schedules (array of objects: unique name, cron expression, graph, mode incremental or clean, output_dir, set)
+// - run_queue (object: policy queue, coalesce or reject; max_queued non-negative integer)
 //
 // Rejected fields (explicit):
 // - workspace_path
@@ -53,6 +251,71 @@ func Parse(data []byte) (Config, error) {
 				return Config{}, fmt.Errorf("%w: graph_path must be non-empty", ErrInvalidConfig)
 			}
 			cfg.GraphPath = s
+		case "publish":
+			p, err := parsePublish(value)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.Publish = p
+		case "plugin_keys":
+			keys, err := parsePluginKeys(value)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.PluginKeys = keys
+		case "cache":
+			c, err := parseCache(value)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.Cache = c
+		case "cache_encryption":
+			ce, err := parseCacheEncryption(value)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.CacheEncryption = ce
+		case "hash_algorithm":
+			var s string
+			if err := json.Unmarshal(value, &s); err != nil {
+				return Config{}, fmt.Errorf("%w: hash_algorithm must be a string", ErrInvalidConfig)
+			}
+			switch s = strings.TrimSpace(s); s {
+			case HashSHA256, HashBLAKE3:
+				cfg.HashAlgorithm = s
+			default:
+				return Config{}, fmt.Errorf("%w: hash_algorithm must be %q or %q", ErrInvalidConfig, HashSHA256, HashBLAKE3)
+			}
+		case "label_limits":
+			limits, err := parseLabelLimits(value)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.LabelLimits = limits
+		case "retention":
+			r, err := parseRetention(value)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.Retention = r
+		case "notify":
+			n, err := parseNotify(value)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.Notify = n
+		case "schedules":
+			sc, err := parseSchedules(value)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.Schedules = sc
+		case "run_queue":
+			q, err := parseRunQueue(value)
+			if err != nil {
+				return Config{}, err
+			}
+			cfg.RunQueue = q
 		case "workspace_path":
 			return Config{}, fmt.Errorf("%w: workspace_path is not permitted", ErrInvalidConfig)
 		case "semantic_overrides":
@@ -61,10 +324,301 @@ func Parse(data []byte) (Config, error) {
 			return Config{}, fmt.Errorf("%w: unknown field %q", ErrInvalidConfig, key)
 		}
 	}
+	for _, sc := range cfg.Schedules {
+		if sc.Graph == "" && cfg.GraphPath == "" {
+			return Config{}, fmt.Errorf("%w: schedules[%q] sets no graph and there is no graph_path", ErrInvalidConfig, sc.Name)
+		}
+	}
 
 	return cfg, nil
 }
 
+func parsePublish(data json.RawMessage) (*PublishConfig, error) {
+	var raw struct {
+		Destination *string  `json:"destination"`
+		Outputs     []string `json:"outputs"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: publish: %v", ErrInvalidConfig, err)
+	}
+	if raw.Destination == nil || strings.TrimSpace(*raw.Destination) == "" {
+		return nil, fmt.Errorf("%w: publish.destination must be non-empty", ErrInvalidConfig)
+	}
+	if len(raw.Outputs) == 0 {
+		return nil, fmt.Errorf("%w: publish.outputs must be a non-empty array", ErrInvalidConfig)
+	}
+	outputs := make([]string, 0, len(raw.Outputs))
+	for i, o := range raw.Outputs {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			return nil, fmt.Errorf("%w: publish.outputs[%d] must be non-empty", ErrInvalidConfig, i)
+		}
+		outputs = append(outputs, o)
+	}
+	return &PublishConfig{Destination: strings.TrimSpace(*raw.Destination), Outputs: outputs}, nil
+}
+
+func parseCache(data json.RawMessage) (*CacheConfig, error) {
+	var raw struct {
+		Tiers     []string `json:"tiers"`
+		Write     *string  `json:"write"`
+		Region    string   `json:"region"`
+		Endpoint  string   `json:"endpoint"`
+		Namespace string   `json:"namespace"`
+
+		Concurrency         int   `json:"concurrency"`
+		UploadBytesPerSec   int64 `json:"upload_bytes_per_sec"`
+		DownloadBytesPerSec int64 `json:"download_bytes_per_sec"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: cache: %v", ErrInvalidConfig, err)
+	}
+	if raw.Concurrency < 0 || raw.UploadBytesPerSec < 0 || raw.DownloadBytesPerSec < 0 {
+		return nil, fmt.Errorf("%w: cache.concurrency, cache.upload_bytes_per_sec and cache.download_bytes_per_sec must not be negative", ErrInvalidConfig)
+	}
+	if len(raw.Tiers) == 0 {
+		return nil, fmt.Errorf("%w: cache.tiers must be a non-empty array", ErrInvalidConfig)
+	}
+	tiers := make([]string, 0, len(raw.Tiers))
+	for i, t := range raw.Tiers {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return nil, fmt.Errorf("%w: cache.tiers[%d] must be non-empty", ErrInvalidConfig, i)
+		}
+		if strings.Contains(t, "://") && !strings.HasPrefix(t, "s3://") && !strings.HasPrefix(t, "gs://") {
+			return nil, fmt.Errorf("%w: cache.tiers[%d] must be a directory or an s3:// or gs:// URL", ErrInvalidConfig, i)
+		}
+		tiers = append(tiers, t)
+	}
+	c := &CacheConfig{
+		Tiers:               tiers,
+		Write:               CacheWriteLocal,
+		Region:              strings.TrimSpace(raw.Region),
+		Endpoint:            strings.TrimSpace(raw.Endpoint),
+		Concurrency:         raw.Concurrency,
+		UploadBytesPerSec:   raw.UploadBytesPerSec,
+		DownloadBytesPerSec: raw.DownloadBytesPerSec,
+	}
+	switch ns := strings.TrimSpace(raw.Namespace); ns {
+	case "", CacheNamespaceGraph:
+		c.Namespace = ns
+	default:
+		return nil, fmt.Errorf("%w: cache.namespace must be %q or empty", ErrInvalidConfig, CacheNamespaceGraph)
+	}
+	if raw.Write != nil {
+		switch w := strings.TrimSpace(*raw.Write); w {
+		case CacheWriteLocal, CacheWriteAll:
+			c.Write = w
+		default:
+			return nil, fmt.Errorf("%w: cache.write must be %q or %q", ErrInvalidConfig, CacheWriteLocal, CacheWriteAll)
+		}
+	}
+	return c, nil
+}
+
+func parseCacheEncryption(data json.RawMessage) (*CacheEncryptionConfig, error) {
+	var raw struct {
+		KeyFile  string `json:"key_file"`
+		Keychain string `json:"keychain"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: cache_encryption: %v", ErrInvalidConfig, err)
+	}
+	ce := &CacheEncryptionConfig{KeyFile: strings.TrimSpace(raw.KeyFile), Keychain: strings.TrimSpace(raw.Keychain)}
+	if (ce.KeyFile == "") == (ce.Keychain == "") {
+		return nil, fmt.Errorf("%w: cache_encryption must set exactly one of key_file and keychain", ErrInvalidConfig)
+	}
+	return ce, nil
+}
+
+func parseRetention(data json.RawMessage) (*RetentionConfig, error) {
+	var raw struct {
+		KeepRuns int   `json:"keep_runs"`
+		MaxBytes int64 `json:"max_bytes"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: retention: %v", ErrInvalidConfig, err)
+	}
+	if raw.KeepRuns < 0 || raw.MaxBytes < 0 {
+		return nil, fmt.Errorf("%w: retention.keep_runs and retention.max_bytes must not be negative", ErrInvalidConfig)
+	}
+	if raw.KeepRuns == 0 && raw.MaxBytes == 0 {
+		return nil, fmt.Errorf("%w: retention must set keep_runs or max_bytes", ErrInvalidConfig)
+	}
+	return &RetentionConfig{KeepRuns: raw.KeepRuns, MaxBytes: raw.MaxBytes}, nil
+}
+
+func parseRunQueue(data json.RawMessage) (*RunQueueConfig, error) {
+	var raw struct {
+		Policy    string `json:"policy"`
+		MaxQueued int    `json:"max_queued"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: run_queue: %v", ErrInvalidConfig, err)
+	}
+	q := &RunQueueConfig{Policy: strings.TrimSpace(raw.Policy), MaxQueued: raw.MaxQueued}
+	switch q.Policy {
+	case "":
+		q.Policy = QueueWait
+	case QueueWait, QueueCoalesce, QueueReject:
+	default:
+		return nil, fmt.Errorf("%w: run_queue.policy must be %q, %q or %q", ErrInvalidConfig, QueueWait, QueueCoalesce, QueueReject)
+	}
+	if q.MaxQueued < 0 {
+		return nil, fmt.Errorf("%w: run_queue.max_queued must not be negative", ErrInvalidConfig)
+	}
+	return q, nil
+}
+
+func parseSchedules(data json.RawMessage) ([]ScheduleConfig, error) {
+	var raw []struct {
+		Name      string            `json:"name"`
+		Cron      string            `json:"cron"`
+		Graph     string            `json:"graph"`
+		Mode      string            `json:"mode"`
+		OutputDir string            `json:"output_dir"`
+		Set       map[string]string `json:"set"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: schedules: %v", ErrInvalidConfig, err)
+	}
+	out := make([]ScheduleConfig, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for i, r := range raw {
+		sc := ScheduleConfig{
+			Name:      strings.TrimSpace(r.Name),
+			Cron:      strings.TrimSpace(r.Cron),
+			Graph:     strings.TrimSpace(r.Graph),
+			Mode:      strings.TrimSpace(r.Mode),
+			OutputDir: strings.TrimSpace(r.OutputDir),
+			Set:       r.Set,
+		}
+		if !scheduleNamePattern.MatchString(sc.Name) {
+			return nil, fmt.Errorf("%w: schedules[%d]: invalid name %q (letters, digits, _, . and -)", ErrInvalidConfig, i, sc.Name)
+		}
+		if seen[sc.Name] {
+			return nil, fmt.Errorf("%w: schedules: duplicate name %q", ErrInvalidConfig, sc.Name)
+		}
+		seen[sc.Name] = true
+		if _, err := cron.Parse(sc.Cron); err != nil {
+			return nil, fmt.Errorf("%w: schedules[%q]: %v", ErrInvalidConfig, sc.Name, err)
+		}
+		switch sc.Mode {
+		case "":
+			sc.Mode = ScheduleIncremental
+		case ScheduleIncremental, ScheduleClean:
+		default:
+			return nil, fmt.Errorf("%w: schedules[%q].mode must be %q or %q", ErrInvalidConfig, sc.Name, ScheduleIncremental, ScheduleClean)
+		}
+		out = append(out, sc)
+	}
+	return out, nil
+}
+
+func parseNotify(data json.RawMessage) (*NotifyConfig, error) {
+	var raw struct {
+		Format         string `json:"format"`
+		WebhookURL     string `json:"webhook_url"`
+		WebhookURLFile string `json:"webhook_url_file"`
+		On             string `json:"on"`
+		Template       string `json:"template"`
+		Link           string `json:"link"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: notify: %v", ErrInvalidConfig, err)
+	}
+	n := &NotifyConfig{
+		Format:         strings.TrimSpace(raw.Format),
+		WebhookURL:     strings.TrimSpace(raw.WebhookURL),
+		WebhookURLFile: strings.TrimSpace(raw.WebhookURLFile),
+		On:             strings.TrimSpace(raw.On),
+		Template:       raw.Template,
+		Link:           strings.TrimSpace(raw.Link),
+	}
+	switch n.Format {
+	case NotifySlack, NotifyTeams:
+	default:
+		return nil, fmt.Errorf("%w: notify.format must be %q or %q", ErrInvalidConfig, NotifySlack, NotifyTeams)
+	}
+	if (n.WebhookURL == "") == (n.WebhookURLFile == "") {
+		return nil, fmt.Errorf("%w: notify must set exactly one of webhook_url and webhook_url_file", ErrInvalidConfig)
+	}
+	if n.WebhookURL != "" {
+		if err := ValidateWebhookURL(n.WebhookURL); err != nil {
+			return nil, fmt.Errorf("%w: notify.webhook_url %v", ErrInvalidConfig, err)
+		}
+	}
+	switch n.On {
+	case "":
+		n.On = NotifyFailure
+	case NotifyAlways, NotifyFailure, NotifyRecovery:
+	default:
+		return nil, fmt.Errorf("%w: notify.on must be %q, %q or %q", ErrInvalidConfig, NotifyAlways, NotifyFailure, NotifyRecovery)
+	}
+	for _, m := range notifyPlaceholder.FindAllStringSubmatch(n.Template, -1) {
+		if !slices.Contains(NotifyFields, m[1]) {
+			return nil, fmt.Errorf("%w: notify.template: unknown placeholder {{%s}} (expected one of %s)", ErrInvalidConfig, m[1], strings.Join(NotifyFields, ", "))
+		}
+	}
+	for _, m := range notifyPlaceholder.FindAllStringSubmatch(n.Link, -1) {
+		if m[1] != "run_id" {
+			return nil, fmt.Errorf("%w: notify.link: unknown placeholder {{%s}} (expected run_id)", ErrInvalidConfig, m[1])
+		}
+	}
+	if n.Link != "" {
+		if err := ValidateWebhookURL(notifyPlaceholder.ReplaceAllString(n.Link, "id")); err != nil {
+			return nil, fmt.Errorf("%w: notify.link %v", ErrInvalidConfig, err)
+		}
+	}
+	return n, nil
+}
+
+// notifyPlaceholder matches the {{name}} placeholders of notification
+// templates.
+var notifyPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_]+)\s*\}\}`)
+
+// ValidateWebhookURL reports whether u is an absolute http or https URL.
+func ValidateWebhookURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return errors.New("must be an http or https URL")
+	}
+	return nil
+}
+
+func parsePluginKeys(data json.RawMessage) ([]ed25519.PublicKey, error) {
+	var raw []string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: plugin_keys must be an array of strings", ErrInvalidConfig)
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: plugin_keys must be a non-empty array", ErrInvalidConfig)
+	}
+	keys := make([]ed25519.PublicKey, 0, len(raw))
+	for i, s := range raw {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil || len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: plugin_keys[%d] must be a base64-encoded Ed25519 public key", ErrInvalidConfig, i)
+		}
+		keys = append(keys, ed25519.PublicKey(b))
+	}
+	return keys, nil
+}
+
 // LoadOptional loads .scriptweaver/config.json from the given project root.
 //
 // If the config file is missing, it returns (Config{}, false, nil).
@@ -89,3 +643,19 @@ func LoadOptional(projectRoot string) (Config, bool, error) {
 	}
 	return cfg, true, nil
 }
+
+func parseLabelLimits(value json.RawMessage) (map[string]int, error) {
+	var limits map[string]int
+	if err := json.Unmarshal(value, &limits); err != nil {
+		return nil, fmt.Errorf("%w: label_limits must be an object of integers", ErrInvalidConfig)
+	}
+	for label, limit := range limits {
+		if strings.TrimSpace(label) == "" {
+			return nil, fmt.Errorf("%w: label_limits labels must be non-empty", ErrInvalidConfig)
+		}
+		if limit <= 0 {
+			return nil, fmt.Errorf("%w: label_limits.%s must be positive", ErrInvalidConfig, label)
+		}
+	}
+	return limits, nil
+}
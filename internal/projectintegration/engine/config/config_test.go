@@ -1,6 +1,9 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -111,3 +114,214 @@ func mustErrText(t *testing.T, fn func() error) string {
 	}
 	return err.Error()
 }
+
+func TestParse_Publish(t *testing.T) {
+	cfg, err := Parse([]byte(`{"publish":{"destination":"dist","outputs":["a.txt"," out/ "]}}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Publish == nil || cfg.Publish.Destination != "dist" || len(cfg.Publish.Outputs) != 2 || cfg.Publish.Outputs[1] != "out/" {
+		t.Fatalf("Publish = %+v", cfg.Publish)
+	}
+
+	for _, bad := range []string{
+		`{"publish":{"outputs":["a"]}}`,
+		`{"publish":{"destination":"dist","outputs":[]}}`,
+		`{"publish":{"destination":"dist","outputs":["a"],"remote":true}}`,
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestParse_PluginKeys(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize))
+	cfg, err := Parse([]byte(`{"plugin_keys":["` + key + `"]}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(cfg.PluginKeys) != 1 || len(cfg.PluginKeys[0]) != ed25519.PublicKeySize {
+		t.Fatalf("PluginKeys = %v", cfg.PluginKeys)
+	}
+
+	for _, bad := range []string{
+		`{"plugin_keys":[]}`,
+		`{"plugin_keys":"` + key + `"}`,
+		`{"plugin_keys":["not base64!"]}`,
+		`{"plugin_keys":["` + base64.StdEncoding.EncodeToString([]byte("short")) + `"]}`,
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestParse_Cache(t *testing.T) {
+	cfg, err := Parse([]byte(`{"cache":{"tiers":["/mnt/team/sw-cache"]}}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Cache == nil || len(cfg.Cache.Tiers) != 1 || cfg.Cache.Write != CacheWriteLocal {
+		t.Fatalf("Cache = %+v", cfg.Cache)
+	}
+	cfg, err = Parse([]byte(`{"cache":{"tiers":["a","b"],"write":"write-all"}}`))
+	if err != nil || cfg.Cache.Write != CacheWriteAll {
+		t.Fatalf("Cache = %+v, %v", cfg.Cache, err)
+	}
+	cfg, err = Parse([]byte(`{"cache":{"tiers":["s3://team/sw"],"region":"eu-west-1","endpoint":"http://minio:9000","namespace":"graph"}}`))
+	if err != nil || cfg.Cache.Region != "eu-west-1" || cfg.Cache.Endpoint != "http://minio:9000" || cfg.Cache.Namespace != CacheNamespaceGraph {
+		t.Fatalf("Cache = %+v, %v", cfg.Cache, err)
+	}
+	cfg, err = Parse([]byte(`{"cache":{"tiers":["s3://team/sw"],"concurrency":4,"upload_bytes_per_sec":1000000,"download_bytes_per_sec":5000000}}`))
+	if err != nil || cfg.Cache.Concurrency != 4 || cfg.Cache.UploadBytesPerSec != 1000000 || cfg.Cache.DownloadBytesPerSec != 5000000 {
+		t.Fatalf("Cache = %+v, %v", cfg.Cache, err)
+	}
+
+	for _, bad := range []string{
+		`{"cache":{"tiers":[]}}`,
+		`{"cache":{"tiers":["a"],"concurrency":-1}}`,
+		`{"cache":{"tiers":["a"],"upload_bytes_per_sec":-5}}`,
+		`{"cache":{"tiers":[" "]}}`,
+		`{"cache":{"tiers":["a"],"write":"write-some"}}`,
+		`{"cache":{"tiers":["a"],"url":"s3://bucket"}}`,
+		`{"cache":{"tiers":["https://cache.example.com"]}}`,
+		`{"cache":{"tiers":["s3://team"],"namespace":"run"}}`,
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestParse_CacheEncryption(t *testing.T) {
+	cfg, err := Parse([]byte(`{"cache_encryption":{"key_file":"../keys/sw-cache.keys"}}`))
+	if err != nil || cfg.CacheEncryption == nil || cfg.CacheEncryption.KeyFile != "../keys/sw-cache.keys" {
+		t.Fatalf("CacheEncryption = %+v, %v", cfg.CacheEncryption, err)
+	}
+	cfg, err = Parse([]byte(`{"cache_encryption":{"keychain":"scriptweaver-cache"}}`))
+	if err != nil || cfg.CacheEncryption.Keychain != "scriptweaver-cache" {
+		t.Fatalf("CacheEncryption = %+v, %v", cfg.CacheEncryption, err)
+	}
+	for _, bad := range []string{
+		`{"cache_encryption":{}}`,
+		`{"cache_encryption":{"key_file":"k","keychain":"s"}}`,
+		`{"cache_encryption":{"key_env":"SW_KEY"}}`,
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestParse_HashAlgorithm(t *testing.T) {
+	cfg, err := Parse([]byte(`{"hash_algorithm":"blake3"}`))
+	if err != nil || cfg.HashAlgorithm != HashBLAKE3 {
+		t.Fatalf("HashAlgorithm = %q, %v", cfg.HashAlgorithm, err)
+	}
+	for _, bad := range []string{`{"hash_algorithm":"md5"}`, `{"hash_algorithm":1}`} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestParse_LabelLimits(t *testing.T) {
+	cfg, err := Parse([]byte(`{"label_limits":{"network":2}}`))
+	if err != nil || cfg.LabelLimits["network"] != 2 {
+		t.Fatalf("LabelLimits = %v, %v", cfg.LabelLimits, err)
+	}
+	for _, bad := range []string{`{"label_limits":{"network":0}}`, `{"label_limits":{"":1}}`, `{"label_limits":["network"]}`} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestParse_Retention(t *testing.T) {
+	cfg, err := Parse([]byte(`{"retention":{"keep_runs":10,"max_bytes":1048576}}`))
+	if err != nil || cfg.Retention == nil || cfg.Retention.KeepRuns != 10 || cfg.Retention.MaxBytes != 1<<20 {
+		t.Fatalf("Retention = %+v, %v", cfg.Retention, err)
+	}
+	for _, bad := range []string{`{"retention":{}}`, `{"retention":{"keep_runs":-1}}`, `{"retention":{"keep_runs":1,"max_age":"1h"}}`, `{"retention":5}`} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Fatalf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestParse_Notify(t *testing.T) {
+	cfg, err := Parse([]byte(`{"notify":{"format":"slack","webhook_url":"https://hooks.example.com/T1","link":"http://127.0.0.1:7070/#{{run_id}}"}}`))
+	if err != nil || cfg.Notify == nil || cfg.Notify.Format != NotifySlack || cfg.Notify.On != NotifyFailure || cfg.Notify.Link != "http://127.0.0.1:7070/#{{run_id}}" {
+		t.Fatalf("Notify = %+v, %v", cfg.Notify, err)
+	}
+	cfg, err = Parse([]byte(`{"notify":{"format":"teams","webhook_url_file":".scriptweaver/webhook","on":"recovery","template":"{{ status }}: {{failed_nodes}}"}}`))
+	if err != nil || cfg.Notify.WebhookURLFile != ".scriptweaver/webhook" || cfg.Notify.On != NotifyRecovery {
+		t.Fatalf("Notify = %+v, %v", cfg.Notify, err)
+	}
+	for _, bad := range []string{
+		`{"notify":{"webhook_url":"https://hooks.example.com"}}`,
+		`{"notify":{"format":"irc","webhook_url":"https://hooks.example.com"}}`,
+		`{"notify":{"format":"slack"}}`,
+		`{"notify":{"format":"slack","webhook_url":"https://hooks.example.com","webhook_url_file":"f"}}`,
+		`{"notify":{"format":"slack","webhook_url":"hooks.example.com"}}`,
+		`{"notify":{"format":"slack","webhook_url":"https://hooks.example.com","on":"never"}}`,
+		`{"notify":{"format":"slack","webhook_url":"https://hooks.example.com","template":"{{owner}}"}}`,
+		`{"notify":{"format":"slack","webhook_url":"https://hooks.example.com","link":"http://h/{{status}}"}}`,
+		`{"notify":{"format":"slack","webhook_url":"https://hooks.example.com","channel":"#ci"}}`,
+	} {
+		if _, err := Parse([]byte(bad)); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("expected ErrInvalidConfig for %s, got %v", bad, err)
+		}
+	}
+}
+
+func TestParse_Schedules(t *testing.T) {
+	cfg, err := Parse([]byte(`{"graph_path":"graph.json","schedules":[
+		{"name":"nightly","cron":"0 2 * * *"},
+		{"name":"hourly-clean","cron":"@hourly","graph":"other.json","mode":"clean","output_dir":"out/h","set":{"env":"ci"}}
+	]}`))
+	if err != nil || len(cfg.Schedules) != 2 {
+		t.Fatalf("Schedules = %+v, %v", cfg.Schedules, err)
+	}
+	if sc := cfg.Schedules[0]; sc.Name != "nightly" || sc.Mode != ScheduleIncremental || sc.Graph != "" {
+		t.Fatalf("nightly = %+v", sc)
+	}
+	if sc := cfg.Schedules[1]; sc.Mode != ScheduleClean || sc.Graph != "other.json" || sc.OutputDir != "out/h" || sc.Set["env"] != "ci" {
+		t.Fatalf("hourly-clean = %+v", sc)
+	}
+	for _, bad := range []string{
+		`{"schedules":[{"name":"nightly","cron":"0 2 * * *"}]}`,
+		`{"schedules":[{"name":"n","cron":"0 25 * * *","graph":"g.json"}]}`,
+		`{"schedules":[{"name":"n","cron":"@often","graph":"g.json"}]}`,
+		`{"schedules":[{"name":"a b","cron":"@daily","graph":"g.json"}]}`,
+		`{"schedules":[{"cron":"@daily","graph":"g.json"}]}`,
+		`{"schedules":[{"name":"n","cron":"@daily","graph":"g.json"},{"name":"n","cron":"@hourly","graph":"g.json"}]}`,
+		`{"schedules":[{"name":"n","cron":"@daily","graph":"g.json","mode":"fast"}]}`,
+		`{"schedules":[{"name":"n","cron":"@daily","graph":"g.json","timezone":"UTC"}]}`,
+	} {
+		if _, err := Parse([]byte(bad)); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("expected ErrInvalidConfig for %s, got %v", bad, err)
+		}
+	}
+}
+
+func TestParse_RunQueue(t *testing.T) {
+	cfg, err := Parse([]byte(`{"run_queue":{"max_queued":3}}`))
+	if err != nil || cfg.RunQueue == nil || cfg.RunQueue.Policy != QueueWait || cfg.RunQueue.MaxQueued != 3 {
+		t.Fatalf("RunQueue = %+v, %v", cfg.RunQueue, err)
+	}
+	cfg, err = Parse([]byte(`{"run_queue":{"policy":"coalesce"}}`))
+	if err != nil || cfg.RunQueue.Policy != QueueCoalesce {
+		t.Fatalf("RunQueue = %+v, %v", cfg.RunQueue, err)
+	}
+	for _, bad := range []string{
+		`{"run_queue":{"policy":"drop"}}`,
+		`{"run_queue":{"max_queued":-1}}`,
+		`{"run_queue":{"policy":"queue","timeout":"1m"}}`,
+	} {
+		if _, err := Parse([]byte(bad)); !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("expected ErrInvalidConfig for %s, got %v", bad, err)
+		}
+	}
+}
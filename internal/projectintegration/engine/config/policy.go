@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Policy is the organizational rules every graph run in a workspace must
+// follow, loaded from <projectRoot>/.scriptweaver/policy.json. Unlike the
+// config, which tunes how graphs run, the policy rejects graphs: it is
+// enforced by sw validate and again before a run executes anything.
+type Policy struct {
+	// AllowedRunners are the runners tasks may select; LocalRunner names the
+	// built-in local executor. Nil allows every runner.
+	AllowedRunners []string
+	// ForbiddenCommands are patterns no task command may match.
+	ForbiddenCommands []*regexp.Regexp
+	// RequireTimeout requires every command task to declare timeout_seconds.
+	RequireTimeout bool
+	// RequireOwner requires every task to declare metadata.owner.
+	RequireOwner bool
+}
+
+// LocalRunner is the name under which Policy.AllowedRunners allows tasks
+// that select no runner.
+const LocalRunner = "local"
+
+var (
+	ErrInvalidPolicy = errors.New("invalid workspace policy")
+)
+
+// ParsePolicy parses and validates workspace policy JSON.
+//
+// Allowed fields:
+// - allowed_runners (non-empty array of non-empty strings)
+// - forbidden_commands (non-empty array of regular expressions)
+// - require_timeout (bool)
+// - require_owner (bool)
+//
+// Any unknown field is rejected.
+func ParsePolicy(data []byte) (*Policy, error) {
+	var raw struct {
+		AllowedRunners    []string `json:"allowed_runners"`
+		ForbiddenCommands []string `json:"forbidden_commands"`
+		RequireTimeout    bool     `json:"require_timeout"`
+		RequireOwner      bool     `json:"require_owner"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPolicy, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidPolicy)
+	}
+
+	p := &Policy{RequireTimeout: raw.RequireTimeout, RequireOwner: raw.RequireOwner}
+	if raw.AllowedRunners != nil {
+		if len(raw.AllowedRunners) == 0 {
+			return nil, fmt.Errorf("%w: allowed_runners must be a non-empty array", ErrInvalidPolicy)
+		}
+		for i, r := range raw.AllowedRunners {
+			r = strings.TrimSpace(r)
+			if r == "" {
+				return nil, fmt.Errorf("%w: allowed_runners[%d] must be non-empty", ErrInvalidPolicy, i)
+			}
+			p.AllowedRunners = append(p.AllowedRunners, r)
+		}
+	}
+	if raw.ForbiddenCommands != nil {
+		if len(raw.ForbiddenCommands) == 0 {
+			return nil, fmt.Errorf("%w: forbidden_commands must be a non-empty array", ErrInvalidPolicy)
+		}
+		for i, pattern := range raw.ForbiddenCommands {
+			if strings.TrimSpace(pattern) == "" {
+				return nil, fmt.Errorf("%w: forbidden_commands[%d] must be non-empty", ErrInvalidPolicy, i)
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: forbidden_commands[%d]: %v", ErrInvalidPolicy, i, err)
+			}
+			p.ForbiddenCommands = append(p.ForbiddenCommands, re)
+		}
+	}
+	return p, nil
+}
+
+// LoadPolicy loads .scriptweaver/policy.json from the given project root.
+//
+// If the policy file is missing, it returns (nil, nil).
+func LoadPolicy(projectRoot string) (*Policy, error) {
+	if strings.TrimSpace(projectRoot) == "" {
+		return nil, fmt.Errorf("%w: project root is required", ErrInvalidPolicy)
+	}
+
+	path := filepath.Join(projectRoot, ".scriptweaver", "policy.json")
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read policy: %w", err)
+	}
+	return ParsePolicy(b)
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy([]byte(`{"allowed_runners":["local"," kubernetes "],"forbidden_commands":["curl .*\\| *sh"],"require_timeout":true,"require_owner":true}`))
+	if err != nil {
+		t.Fatalf("ParsePolicy: %v", err)
+	}
+	if len(p.AllowedRunners) != 2 || p.AllowedRunners[1] != "kubernetes" || !p.RequireTimeout || !p.RequireOwner {
+		t.Fatalf("policy = %+v", p)
+	}
+	if len(p.ForbiddenCommands) != 1 || !p.ForbiddenCommands[0].MatchString("curl https://x | sh") {
+		t.Fatalf("forbidden_commands = %v", p.ForbiddenCommands)
+	}
+
+	for _, bad := range []string{
+		`{"owners":true}`,
+		`{"allowed_runners":[]}`,
+		`{"allowed_runners":[""]}`,
+		`{"forbidden_commands":["("]}`,
+		`{"forbidden_commands":[" "]}`,
+		`{"require_timeout":"yes"}`,
+		`{} {}`,
+	} {
+		if _, err := ParsePolicy([]byte(bad)); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("ParsePolicy(%s) = %v, want ErrInvalidPolicy", bad, err)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	root := t.TempDir()
+	if p, err := LoadPolicy(root); err != nil || p != nil {
+		t.Fatalf("missing policy: %+v, %v", p, err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, ".scriptweaver"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".scriptweaver", "policy.json"), []byte(`{"require_owner":true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := LoadPolicy(root); err != nil || p == nil || !p.RequireOwner {
+		t.Fatalf("LoadPolicy = %+v, %v", p, err)
+	}
+}
@@ -29,49 +29,59 @@ var (
 //
 // The returned path is absolute.
 func Discover(projectRoot, explicitCLIPath string) (string, error) {
+	p, _, err := DiscoverWithWarnings(projectRoot, explicitCLIPath)
+	return p, err
+}
+
+// DiscoverWithWarnings is Discover, also returning a warning for every use of
+// a deprecated field in the discovered graph (see graph.Deprecation).
+func DiscoverWithWarnings(projectRoot, explicitCLIPath string) (string, []graph.DeprecationWarning, error) {
 	root := strings.TrimSpace(projectRoot)
 	if root == "" {
-		return "", fmt.Errorf("%w: project root is required", ErrInvalidGraphPath)
+		return "", nil, fmt.Errorf("%w: project root is required", ErrInvalidGraphPath)
 	}
 
 	rootAbs, err := filepath.Abs(root)
 	if err != nil {
-		return "", fmt.Errorf("resolve project root: %w", err)
+		return "", nil, fmt.Errorf("resolve project root: %w", err)
 	}
 
 	// 1) Explicit path
 	if strings.TrimSpace(explicitCLIPath) != "" {
 		p, err := resolveUnderRoot(rootAbs, explicitCLIPath)
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
-		if err := validateGraphFile(p); err != nil {
-			return "", err
+		warnings, err := validateGraphFile(p)
+		if err != nil {
+			return "", nil, err
 		}
-		return p, nil
+		return p, warnings, nil
 	}
 
 	// 2) graphs/ at project root
 	if p, ok, err := discoverSingleCandidate(filepath.Join(rootAbs, "graphs")); err != nil {
-		return "", err
+		return "", nil, err
 	} else if ok {
-		if err := validateGraphFile(p); err != nil {
-			return "", err
+		warnings, err := validateGraphFile(p)
+		if err != nil {
+			return "", nil, err
 		}
-		return p, nil
+		return p, warnings, nil
 	}
 
 	// 3) .scriptweaver/graphs/
 	if p, ok, err := discoverSingleCandidate(filepath.Join(rootAbs, ".scriptweaver", "graphs")); err != nil {
-		return "", err
+		return "", nil, err
 	} else if ok {
-		if err := validateGraphFile(p); err != nil {
-			return "", err
+		warnings, err := validateGraphFile(p)
+		if err != nil {
+			return "", nil, err
 		}
-		return p, nil
+		return p, warnings, nil
 	}
 
-	return "", ErrNoGraphFound
+	return "", nil, ErrNoGraphFound
 }
 
 func resolveUnderRoot(rootAbs, provided string) (string, error) {
@@ -152,16 +162,22 @@ func discoverSingleCandidate(dir string) (string, bool, error) {
 	return candidates[0], true, nil
 }
 
-func validateGraphFile(path string) error {
+func validateGraphFile(path string) ([]graph.DeprecationWarning, error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("%w: open %s: %v", ErrInvalidGraph, path, err)
+		return nil, fmt.Errorf("%w: open %s: %v", ErrInvalidGraph, path, err)
 	}
 	defer func() { _ = f.Close() }()
 
-	// graph.Parse enforces Sprint-06 schema (schema_version and unknown fields).
-	if _, err := graph.Parse(io.Reader(f)); err != nil {
-		return fmt.Errorf("%w: %s: %v", ErrInvalidGraph, path, err)
+	// graph.Parse enforces Sprint-06 schema (schema_version and unknown fields);
+	// graph.ParseLines applies it to documents written as JSON Lines.
+	parse := graph.ParseWithWarnings
+	if filepath.Ext(path) == graph.LinesExt {
+		parse = graph.ParseLinesWithWarnings
+	}
+	_, warnings, err := parse(io.Reader(f))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidGraph, path, err)
 	}
-	return nil
+	return warnings, nil
 }
@@ -86,6 +86,42 @@ func TestDiscover_InvalidGraphFails(t *testing.T) {
 	}
 }
 
+func TestDiscover_JSONLinesGraph(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "graphs", "big.jsonl"), `{"schema_version": "2.0.0"}
+{"node": {"id": "a", "type": "exec", "inputs": {"cmd": "true"}, "outputs": []}}
+`)
+	if _, err := Discover(root, ""); err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+
+	mustWrite(t, filepath.Join(root, "bad.jsonl"), validMinimalGraphJSON)
+	if _, err := Discover(root, "bad.jsonl"); err == nil {
+		t.Fatalf("expected error for a JSON document with the JSON Lines extension, got nil")
+	}
+}
+
+func TestDiscoverWithWarnings_ReportsDeprecatedFields(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "graphs", "g.json"), `{"schema_version": "2.0.0", "graph": {"nodes": [
+		{"id": "a", "type": "exec", "inputs": {"cmd": "true", "env": {"CI": "1"}}, "outputs": []}
+	], "edges": []}}`)
+	_, warnings, err := DiscoverWithWarnings(root, "")
+	if err != nil {
+		t.Fatalf("DiscoverWithWarnings: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Path != "graph.nodes[0].inputs.env" {
+		t.Fatalf("warnings = %v", warnings)
+	}
+
+	mustWrite(t, filepath.Join(root, "g.jsonl"), `{"schema_version": "2.0.0"}
+{"node": {"id": "a", "type": "exec", "inputs": {"cmd": "true", "env": {}}, "outputs": []}}
+`)
+	if _, warnings, err = DiscoverWithWarnings(root, "g.jsonl"); err != nil || len(warnings) != 1 {
+		t.Fatalf("JSON Lines: warnings = %v, err = %v", warnings, err)
+	}
+}
+
 func mustWrite(t *testing.T, path, content string) {
 	t.Helper()
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
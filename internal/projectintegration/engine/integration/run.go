@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"scriptweaver/internal/graph"
 	"scriptweaver/internal/projectintegration/engine/config"
 	"scriptweaver/internal/projectintegration/engine/discovery"
 	"scriptweaver/internal/projectintegration/engine/workspace"
@@ -16,6 +17,8 @@ type Result struct {
 	Workspace   workspace.Workspace
 	Config      config.Config
 	GraphPath   string
+	// Warnings report the uses of deprecated fields in the graph.
+	Warnings []graph.DeprecationWarning
 }
 
 // Run orchestrates the deterministic integration flow:
@@ -61,7 +64,7 @@ func Run(projectRoot, cliGraphPath string, sandboxGuard bool) (Result, error) {
 		explicit = cfg.GraphPath
 	}
 
-	graphPath, err := discovery.Discover(root, explicit)
+	graphPath, warnings, err := discovery.DiscoverWithWarnings(root, explicit)
 	if err != nil {
 		switch {
 		case errors.Is(err, discovery.ErrAmbiguousGraphs):
@@ -83,5 +86,5 @@ func Run(projectRoot, cliGraphPath string, sandboxGuard bool) (Result, error) {
 		}
 	}
 
-	return Result{ProjectRoot: root, Workspace: ws, Config: cfg, GraphPath: graphPath}, nil
+	return Result{ProjectRoot: root, Workspace: ws, Config: cfg, GraphPath: graphPath, Warnings: warnings}, nil
 }
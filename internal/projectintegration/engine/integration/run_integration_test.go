@@ -33,6 +33,21 @@ func TestRun_ZeroConfigCleanRepo(t *testing.T) {
 	mustBeDir(t, filepath.Join(root, ".scriptweaver", "logs"))
 }
 
+func TestRun_ReportsDeprecatedFields(t *testing.T) {
+	root := t.TempDir()
+	mustWrite(t, filepath.Join(root, "graphs", "only.json"), `{"schema_version": "2.0.0", "graph": {"nodes": [
+		{"id": "a", "type": "exec", "inputs": {"cmd": "true", "env": {"CI": "1"}}, "outputs": []}
+	], "edges": []}}`)
+
+	res, err := Run(root, "", false)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Use != "graph.nodes[].env" {
+		t.Fatalf("Warnings = %v", res.Warnings)
+	}
+}
+
 func TestRun_Isolation_UserFilesUntouched(t *testing.T) {
 	root := t.TempDir()
 
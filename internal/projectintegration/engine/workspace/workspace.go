@@ -17,7 +17,24 @@ type Workspace struct {
 	CacheDir    string
 	RunsDir     string
 	LogsDir     string
+	KeysDir     string // optional; created on first use
 	ConfigPath  string
+	SocketPath  string // present only while `sw daemon` is running
+}
+
+// SocketName is the daemon socket inside the workspace directory.
+const SocketName = "daemon.sock"
+
+// OutputsName is the record of the output files each node has produced,
+// kept across runs to detect stale outputs.
+const OutputsName = "outputs.json"
+
+// PolicyName is the optional workspace policy file (see config.Policy).
+const PolicyName = "policy.json"
+
+// SocketPath returns the daemon socket path for a project root.
+func SocketPath(projectRoot string) string {
+	return filepath.Join(projectRoot, ".scriptweaver", SocketName)
 }
 
 var (
@@ -51,7 +68,8 @@ func DetectProjectRoot() (string, error) {
 // not exist, they are created.
 //
 // Rejection behavior: if the workspace contains any unauthorized files or
-// directories (other than optional config.json), initialization fails.
+// directories (other than optional config.json, policy.json and the daemon
+// socket), initialization fails.
 func EnsureWorkspace(projectRoot string) (Workspace, error) {
 	root := projectRoot
 	if root == "" {
@@ -66,6 +84,7 @@ func EnsureWorkspace(projectRoot string) (Workspace, error) {
 	cacheDir := filepath.Join(workspaceDir, "cache")
 	runsDir := filepath.Join(workspaceDir, "runs")
 	logsDir := filepath.Join(workspaceDir, "logs")
+	keysDir := filepath.Join(workspaceDir, "keys")
 	configPath := filepath.Join(workspaceDir, "config.json")
 
 	ws := Workspace{
@@ -74,7 +93,9 @@ func EnsureWorkspace(projectRoot string) (Workspace, error) {
 		CacheDir:    cacheDir,
 		RunsDir:     runsDir,
 		LogsDir:     logsDir,
+		KeysDir:     keysDir,
 		ConfigPath:  configPath,
+		SocketPath:  filepath.Join(workspaceDir, SocketName),
 	}
 
 	info, err := os.Stat(workspaceDir)
@@ -133,14 +154,18 @@ func validateWorkspaceTopLevel(workspaceDir string) error {
 	for _, entry := range entries {
 		name := entry.Name()
 		switch name {
-		case "cache", "runs", "logs", "graphs":
+		case "cache", "runs", "logs", "graphs", "keys", "plugins":
 			if !entry.IsDir() {
 				return fmt.Errorf("%w: %s must be a directory", ErrInvalidWorkspace, filepath.Join(workspaceDir, name))
 			}
-		case "config.json":
+		case "config.json", OutputsName, PolicyName:
 			if entry.IsDir() {
 				return fmt.Errorf("%w: %s must be a file", ErrInvalidWorkspace, filepath.Join(workspaceDir, name))
 			}
+		case SocketName:
+			if entry.Type()&os.ModeSocket == 0 {
+				return fmt.Errorf("%w: %s must be a socket", ErrInvalidWorkspace, filepath.Join(workspaceDir, name))
+			}
 		default:
 			return fmt.Errorf("%w: %s", ErrUnauthorizedWorkspace, filepath.Join(workspaceDir, name))
 		}
@@ -1,6 +1,7 @@
 package workspace
 
 import (
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
@@ -66,6 +67,21 @@ func TestEnsureWorkspace_AllowsOptionalConfigJSON(t *testing.T) {
 	}
 }
 
+func TestEnsureWorkspace_AllowsOutputsRecord(t *testing.T) {
+	root := t.TempDir()
+	workspaceDir := filepath.Join(root, ".scriptweaver")
+	if err := os.MkdirAll(workspaceDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workspaceDir, OutputsName), []byte(`{"nodes":{}}`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if _, err := EnsureWorkspace(root); err != nil {
+		t.Fatalf("EnsureWorkspace: %v", err)
+	}
+}
+
 func TestEnsureWorkspace_AllowsOptionalGraphsDir(t *testing.T) {
 	root := t.TempDir()
 	if err := os.MkdirAll(filepath.Join(root, ".scriptweaver", "graphs"), 0o755); err != nil {
@@ -77,6 +93,50 @@ func TestEnsureWorkspace_AllowsOptionalGraphsDir(t *testing.T) {
 	}
 }
 
+func TestEnsureWorkspace_AllowsOptionalKeysDir(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".scriptweaver", "keys"), 0o700); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	if _, err := EnsureWorkspace(root); err != nil {
+		t.Fatalf("EnsureWorkspace: %v", err)
+	}
+}
+
+func TestEnsureWorkspace_AllowsOptionalPluginsDir(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".scriptweaver", "plugins"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+
+	if _, err := EnsureWorkspace(root); err != nil {
+		t.Fatalf("EnsureWorkspace: %v", err)
+	}
+}
+
+func TestEnsureWorkspace_AllowsDaemonSocketOnly(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, ".scriptweaver"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	ln, err := net.Listen("unix", SocketPath(root))
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	if _, err := EnsureWorkspace(root); err != nil {
+		t.Fatalf("EnsureWorkspace: %v", err)
+	}
+	ln.Close()
+
+	if err := os.WriteFile(SocketPath(root), []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if _, err := EnsureWorkspace(root); err == nil {
+		t.Fatalf("expected regular file named %s to be rejected", SocketName)
+	}
+}
+
 func TestEnsureWorkspace_RejectsUnauthorizedEntries(t *testing.T) {
 	root := t.TempDir()
 	workspaceDir := filepath.Join(root, ".scriptweaver")
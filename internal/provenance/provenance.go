@@ -0,0 +1,232 @@
+// Package provenance produces signed, per-run provenance documents.
+//
+// A provenance document records how a run's outputs were produced: the graph
+// hash, every node's task hash and terminal state, the SHA-256 of each
+// declared output, the toolchain that executed the run and when it happened.
+// Documents are signed with an Ed25519 workspace key so downstream consumers
+// can check they were not altered after the run.
+package provenance
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FileName is the provenance file written into each run directory.
+const FileName = "provenance.json"
+
+// KeyFileName is the signing key file inside <projectRoot>/.scriptweaver/keys.
+const KeyFileName = "provenance.ed25519"
+
+// SignatureAlgorithm identifies the signature scheme.
+const SignatureAlgorithm = "ed25519"
+
+// Document is the provenance statement for a single run.
+type Document struct {
+	RunID      string    `json:"run_id"`
+	GraphHash  string    `json:"graph_hash"`
+	Mode       string    `json:"mode"`
+	StartTime  time.Time `json:"start_time"`
+	FinishTime time.Time `json:"finish_time"`
+	Toolchain  Toolchain `json:"toolchain"`
+	CacheHits  int       `json:"cache_hits"`
+	Nodes      []Node    `json:"nodes"`
+
+	Signature *Signature `json:"signature,omitempty"`
+}
+
+// Toolchain describes the engine that executed the run.
+type Toolchain struct {
+	Engine        string `json:"engine"`
+	EngineVersion string `json:"engine_version"`
+	GoVersion     string `json:"go_version"`
+	OS            string `json:"os"`
+	Arch          string `json:"arch"`
+}
+
+// Node is the provenance of a single graph node. Nodes are sorted by name.
+type Node struct {
+	Name      string   `json:"name"`
+	TaskHash  string   `json:"task_hash"`
+	State     string   `json:"state"`
+	FromCache bool     `json:"from_cache"`
+	Outputs   []Output `json:"outputs"`
+}
+
+// Output is a declared output file and its content hash. Outputs are sorted by path.
+type Output struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// Signature carries the detached signature over the unsigned document.
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"`
+	Value     string `json:"value"`
+}
+
+// CurrentToolchain returns the toolchain of the running binary.
+func CurrentToolchain() Toolchain {
+	version := "(devel)"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	return Toolchain{
+		Engine:        "scriptweaver",
+		EngineVersion: version,
+		GoVersion:     runtime.Version(),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+	}
+}
+
+// normalized returns a copy with nodes and outputs sorted and UTC timestamps.
+func (d Document) normalized() Document {
+	nodes := make([]Node, len(d.Nodes))
+	copy(nodes, d.Nodes)
+	for i := range nodes {
+		outs := make([]Output, len(nodes[i].Outputs))
+		copy(outs, nodes[i].Outputs)
+		sort.Slice(outs, func(a, b int) bool { return outs[a].Path < outs[b].Path })
+		nodes[i].Outputs = outs
+	}
+	sort.Slice(nodes, func(a, b int) bool { return nodes[a].Name < nodes[b].Name })
+	d.Nodes = nodes
+	d.StartTime = d.StartTime.UTC()
+	d.FinishTime = d.FinishTime.UTC()
+	return d
+}
+
+// signingPayload returns the canonical bytes covered by the signature: the
+// normalized document JSON with the signature omitted.
+func (d Document) signingPayload() ([]byte, error) {
+	n := d.normalized()
+	n.Signature = nil
+	return json.Marshal(n)
+}
+
+// Sign signs the document in place with key.
+func (d *Document) Sign(key ed25519.PrivateKey) error {
+	if d == nil {
+		return errors.New("nil document")
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return errors.New("invalid ed25519 private key")
+	}
+	payload, err := d.signingPayload()
+	if err != nil {
+		return fmt.Errorf("encode provenance: %w", err)
+	}
+	pub := key.Public().(ed25519.PublicKey)
+	d.Signature = &Signature{
+		Algorithm: SignatureAlgorithm,
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+		Value:     base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	}
+	return nil
+}
+
+// Verify checks the document signature against its embedded public key.
+//
+// Callers that need to establish trust must also compare Signature.PublicKey
+// with a key they already trust.
+func (d *Document) Verify() error {
+	if d == nil || d.Signature == nil {
+		return errors.New("provenance is not signed")
+	}
+	if d.Signature.Algorithm != SignatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", d.Signature.Algorithm)
+	}
+	pub, err := base64.StdEncoding.DecodeString(d.Signature.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid signature public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(d.Signature.Value)
+	if err != nil {
+		return errors.New("invalid signature encoding")
+	}
+	payload, err := d.signingPayload()
+	if err != nil {
+		return fmt.Errorf("encode provenance: %w", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pub), payload, sig) {
+		return errors.New("provenance signature mismatch")
+	}
+	return nil
+}
+
+// Marshal encodes the document as indented JSON with nodes and outputs sorted.
+func (d Document) Marshal() ([]byte, error) {
+	return json.MarshalIndent(d.normalized(), "", "  ")
+}
+
+// Parse decodes a provenance document, rejecting unknown fields.
+func Parse(data []byte) (*Document, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var d Document
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("parse provenance: %w", err)
+	}
+	return &d, nil
+}
+
+// KeyPath returns the signing key path for a project root.
+func KeyPath(projectRoot string) string {
+	return filepath.Join(projectRoot, ".scriptweaver", "keys", KeyFileName)
+}
+
+// LoadOrCreateKey loads the workspace signing key, generating one on first use.
+// The key file holds the hex-encoded Ed25519 seed and is readable only by its owner.
+func LoadOrCreateKey(projectRoot string) (ed25519.PrivateKey, error) {
+	p := KeyPath(projectRoot)
+	data, err := os.ReadFile(p)
+	if err == nil {
+		seed, derr := hex.DecodeString(strings.TrimSpace(string(data)))
+		if derr != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid provenance key %s", p)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read provenance key: %w", err)
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("generate provenance key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return nil, fmt.Errorf("create key dir: %w", err)
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if os.IsExist(err) {
+			// Lost a race with a concurrent run; use its key.
+			return LoadOrCreateKey(projectRoot)
+		}
+		return nil, fmt.Errorf("write provenance key: %w", err)
+	}
+	if _, err := f.WriteString(hex.EncodeToString(seed) + "\n"); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("write provenance key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("write provenance key: %w", err)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
@@ -0,0 +1,85 @@
+package provenance
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func sampleDocument() *Document {
+	return &Document{
+		RunID:      "run-1",
+		GraphHash:  "abc",
+		Mode:       "incremental",
+		StartTime:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		FinishTime: time.Date(2024, 1, 1, 0, 0, 5, 0, time.UTC),
+		Toolchain:  CurrentToolchain(),
+		CacheHits:  1,
+		Nodes: []Node{
+			{Name: "b", TaskHash: "h2", State: "CACHED", FromCache: true, Outputs: []Output{{Path: "z", SHA256: "1"}, {Path: "a", SHA256: "2"}}},
+			{Name: "a", TaskHash: "h1", State: "COMPLETED", Outputs: []Output{}},
+		},
+	}
+}
+
+func TestDocument_SignVerifyRoundTrip(t *testing.T) {
+	key, err := LoadOrCreateKey(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadOrCreateKey: %v", err)
+	}
+	doc := sampleDocument()
+	if err := doc.Sign(key); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	data, err := doc.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := parsed.Verify(); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if parsed.Nodes[0].Name != "a" || parsed.Nodes[1].Outputs[0].Path != "a" {
+		t.Fatalf("expected sorted nodes and outputs, got %+v", parsed.Nodes)
+	}
+
+	tampered, err := Parse(bytes.Replace(data, []byte(`"h1"`), []byte(`"h9"`), 1))
+	if err != nil {
+		t.Fatalf("Parse tampered: %v", err)
+	}
+	if err := tampered.Verify(); err == nil {
+		t.Fatalf("expected signature mismatch for tampered document")
+	}
+}
+
+func TestDocument_VerifyRejectsUnsigned(t *testing.T) {
+	if err := sampleDocument().Verify(); err == nil {
+		t.Fatalf("expected unsigned document to fail verification")
+	}
+}
+
+func TestLoadOrCreateKey_PersistsKey(t *testing.T) {
+	root := t.TempDir()
+	k1, err := LoadOrCreateKey(root)
+	if err != nil {
+		t.Fatalf("first LoadOrCreateKey: %v", err)
+	}
+	k2, err := LoadOrCreateKey(root)
+	if err != nil {
+		t.Fatalf("second LoadOrCreateKey: %v", err)
+	}
+	if !bytes.Equal(k1, k2) {
+		t.Fatalf("expected the same key on reload")
+	}
+	info, err := os.Stat(KeyPath(root))
+	if err != nil {
+		t.Fatalf("stat key: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+}
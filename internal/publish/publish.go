@@ -0,0 +1,122 @@
+// Package publish copies selected run outputs to a destination together with a
+// checksum manifest and the run provenance.
+//
+// A published bundle contains every selected output at its workspace-relative
+// path, a SHA256SUMS manifest in the format accepted by `sha256sum -c`, and the
+// signed provenance.json of the run that produced the outputs.
+package publish
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"scriptweaver/internal/core"
+)
+
+// ManifestName is the checksum manifest written alongside published outputs.
+const ManifestName = "SHA256SUMS"
+
+// ProvenanceName is the provenance file written alongside published outputs.
+const ProvenanceName = "provenance.json"
+
+// Sink receives published files. Names are slash-separated relative paths.
+//
+// DirSink is the built-in sink; plugins provide remote destinations by
+// implementing Sink.
+type Sink interface {
+	Put(name string, data []byte) error
+}
+
+// DirSink publishes into a local directory.
+type DirSink struct {
+	Dir string
+}
+
+// Put writes data to Dir/name, creating parent directories as needed.
+func (s DirSink) Put(name string, data []byte) error {
+	if strings.TrimSpace(s.Dir) == "" {
+		return errors.New("publish destination is required")
+	}
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid publish name %q", name)
+	}
+	dst := filepath.Join(s.Dir, clean)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("create publish dir: %w", err)
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+// File is a single published output.
+type File struct {
+	Path   string
+	SHA256 string
+}
+
+// Result describes a completed publish.
+type Result struct {
+	Files []File
+}
+
+// Publish copies outputs (workspace-relative files or directories) from workDir
+// to sink, followed by the checksum manifest and, when non-empty, provenance.
+//
+// The manifest is written after every output and provenance last, so a
+// consumer that sees provenance.json can rely on the bundle being complete.
+func Publish(workDir string, outputs []string, provenance []byte, sink Sink) (*Result, error) {
+	if sink == nil {
+		return nil, errors.New("publish sink is required")
+	}
+	set, err := core.NewHarvester(workDir).Harvest(outputs)
+	if err != nil {
+		return nil, fmt.Errorf("collect outputs: %w", err)
+	}
+
+	files := make([]File, 0, len(set.Artifacts))
+	for _, a := range set.Artifacts {
+		if a.Path == ManifestName || a.Path == ProvenanceName {
+			return nil, fmt.Errorf("output %q collides with publish metadata", a.Path)
+		}
+		if err := sink.Put(a.Path, a.Content); err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(a.Content)
+		files = append(files, File{Path: a.Path, SHA256: hex.EncodeToString(sum[:])})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	if err := sink.Put(ManifestName, Manifest(files)); err != nil {
+		return nil, err
+	}
+	if len(provenance) > 0 {
+		if err := sink.Put(ProvenanceName, provenance); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Files: files}, nil
+}
+
+// Manifest renders files as SHA256SUMS lines ("<hex>  <path>"), sorted by path.
+func Manifest(files []File) []byte {
+	sorted := make([]File, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	var b strings.Builder
+	for _, f := range sorted {
+		b.WriteString(f.SHA256)
+		b.WriteString("  ")
+		b.WriteString(f.Path)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
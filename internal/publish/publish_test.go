@@ -0,0 +1,61 @@
+package publish
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPublish_WritesOutputsManifestAndProvenance(t *testing.T) {
+	workDir := t.TempDir()
+	dest := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workDir, "out"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "out", "b.txt"), []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Publish(workDir, []string{"out", "a.txt"}, []byte(`{"run_id":"r"}`), DirSink{Dir: dest})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if len(res.Files) != 2 || res.Files[0].Path != "a.txt" || res.Files[1].Path != "out/b.txt" {
+		t.Fatalf("unexpected files: %+v", res.Files)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dest, "out", "b.txt"))
+	if err != nil || string(got) != "b" {
+		t.Fatalf("published output = %q, %v", got, err)
+	}
+	sums, err := os.ReadFile(filepath.Join(dest, ManifestName))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	want := "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb  a.txt\n" +
+		"3e23e8160039594a33894f6564e1b1348bbd7a0088d42c4acb73eeaed59c009d  out/b.txt\n"
+	if string(sums) != want {
+		t.Fatalf("manifest:\n%s\nwant:\n%s", sums, want)
+	}
+	if _, err := os.Stat(filepath.Join(dest, ProvenanceName)); err != nil {
+		t.Fatalf("provenance not published: %v", err)
+	}
+}
+
+func TestPublish_MissingOutputFails(t *testing.T) {
+	_, err := Publish(t.TempDir(), []string{"missing.txt"}, nil, DirSink{Dir: t.TempDir()})
+	if err == nil {
+		t.Fatalf("expected error for missing output")
+	}
+}
+
+func TestDirSink_RejectsEscapingNames(t *testing.T) {
+	err := DirSink{Dir: t.TempDir()}.Put("../x", []byte("x"))
+	if err == nil || !strings.Contains(err.Error(), "invalid publish name") {
+		t.Fatalf("expected invalid name error, got %v", err)
+	}
+}
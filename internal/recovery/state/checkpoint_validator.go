@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"sort"
 	"strings"
 	"time"
 
@@ -39,6 +40,9 @@ type CheckpointInput struct {
 	ExitCode        int
 	FromCache       bool
 	TraceEvents     []trace.TraceEvent
+
+	// Upstream lists the node's direct dependencies; it is stored sorted.
+	Upstream []string
 }
 
 // CreateAndSave validates the provided evidence and, if valid, writes a checkpoint.
@@ -120,6 +124,10 @@ func (v *CheckpointValidator) CreateAndSave(in CheckpointInput) (Checkpoint, err
 		OutputHash: outputHash,
 		Valid:      true,
 	}
+	if in.Upstream != nil {
+		cp.Upstream = append([]string{}, in.Upstream...)
+		sort.Strings(cp.Upstream)
+	}
 	if err := v.Store.SaveCheckpoint(in.RunID, cp); err != nil {
 		return Checkpoint{}, err
 	}
@@ -140,6 +148,9 @@ func validateTraceForCheckpoint(events []trace.TraceEvent, nodeID string, fromCa
 			seenFailed = true
 		case trace.EventTaskExecuted:
 			seenExecuted = true
+		case trace.EventTaskDeduplicated:
+			// A deduplicated node completed by sharing an executed node's result.
+			seenExecuted = true
 		case trace.EventTaskArtifactsRestored:
 			seenArtifactsRestored = true
 		}
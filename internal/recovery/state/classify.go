@@ -0,0 +1,118 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// FailureKind refines a failure's class with its likely cause. It decides
+// whether the failure is transient, i.e. may not recur when the run is retried.
+type FailureKind string
+
+const (
+	// FailureKindTimeout is a task or runner that exceeded a deadline.
+	FailureKindTimeout FailureKind = "timeout"
+	// FailureKindOOM is a task killed by SIGKILL, as the kernel OOM killer does.
+	FailureKindOOM FailureKind = "oom"
+	// FailureKindNetwork is a task or runner that could not reach a remote
+	// service.
+	FailureKindNetwork FailureKind = "network"
+	// FailureKindMissingTool is a task whose command was not found or is not
+	// executable.
+	FailureKindMissingTool FailureKind = "missing_tool"
+	// FailureKindExit is an ordinary nonzero exit status.
+	FailureKindExit FailureKind = "exit"
+	// FailureKindSkipPolicy is a node that never ran: it was failed, under
+	// the fail skip policy, because a node it depends on was skipped on
+	// request.
+	FailureKindSkipPolicy FailureKind = "skip_policy"
+)
+
+// Transient reports whether failures of kind k may not recur on retry.
+func (k FailureKind) Transient() bool {
+	switch k {
+	case FailureKindTimeout, FailureKindOOM, FailureKindNetwork:
+		return true
+	}
+	return false
+}
+
+func (k FailureKind) valid() bool {
+	switch k {
+	case "", FailureKindTimeout, FailureKindOOM, FailureKindNetwork, FailureKindMissingTool, FailureKindExit, FailureKindSkipPolicy:
+		return true
+	}
+	return false
+}
+
+// Exit statuses with a conventional meaning.
+const (
+	exitTimeout     = 124 // timeout(1)
+	exitNotRunnable = 126 // sh: command found but not executable
+	exitNotFound    = 127 // sh: command not found
+	exitKilled      = 128 + int(syscall.SIGKILL)
+	exitTempFail    = 75 // sysexits EX_TEMPFAIL
+)
+
+// networkMarkers are stderr fragments printed by common tools (curl, wget,
+// git, package managers) when a remote service cannot be reached.
+var networkMarkers = [][]byte{
+	[]byte("could not resolve host"),
+	[]byte("temporary failure in name resolution"),
+	[]byte("name or service not known"),
+	[]byte("connection refused"),
+	[]byte("connection reset"),
+	[]byte("connection timed out"),
+	[]byte("network is unreachable"),
+	[]byte("no route to host"),
+	[]byte("tls handshake timeout"),
+	[]byte("503 service unavailable"),
+}
+
+// ClassifyExit returns the kind of failure of a task that exited with exitCode
+// after writing stderr. A negative exitCode means the task was killed by a
+// signal that its shell did not report as a status, which is treated like
+// SIGKILL.
+func ClassifyExit(exitCode int, stderr []byte) FailureKind {
+	lower := bytes.ToLower(stderr)
+	switch {
+	case exitCode == exitNotFound || exitCode == exitNotRunnable || bytes.Contains(lower, []byte("command not found")):
+		return FailureKindMissingTool
+	case exitCode == exitKilled || exitCode < 0:
+		return FailureKindOOM
+	case exitCode == exitTimeout:
+		return FailureKindTimeout
+	case exitCode == exitTempFail:
+		return FailureKindNetwork
+	}
+	for _, m := range networkMarkers {
+		if bytes.Contains(lower, m) {
+			return FailureKindNetwork
+		}
+	}
+	return FailureKindExit
+}
+
+// ClassifyError returns the kind of failure of an error that aborted a run,
+// or "" when err carries no recognizable cause.
+func ClassifyError(err error) FailureKind {
+	if err == nil {
+		return ""
+	}
+	var timeout interface{ Timeout() bool }
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return FailureKindTimeout
+	case errors.As(err, &timeout) && timeout.Timeout():
+		return FailureKindTimeout
+	case errors.Is(err, exec.ErrNotFound):
+		return FailureKindMissingTool
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH):
+		return FailureKindNetwork
+	}
+	return ""
+}
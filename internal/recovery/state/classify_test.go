@@ -0,0 +1,73 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"testing"
+)
+
+func TestClassifyExit(t *testing.T) {
+	cases := []struct {
+		code   int
+		stderr string
+		want   FailureKind
+	}{
+		{1, "", FailureKindExit},
+		{2, "assertion failed", FailureKindExit},
+		{127, "sh: 1: protoc: not found", FailureKindMissingTool},
+		{126, "", FailureKindMissingTool},
+		{1, "sh: protoc: command not found", FailureKindMissingTool},
+		{137, "", FailureKindOOM},
+		{-1, "", FailureKindOOM},
+		{124, "", FailureKindTimeout},
+		{75, "", FailureKindNetwork},
+		{6, "curl: (6) Could not resolve host: example.com", FailureKindNetwork},
+		{128, "fatal: unable to access 'https://example.com/': Connection reset by peer", FailureKindNetwork},
+	}
+	for _, c := range cases {
+		if got := ClassifyExit(c.code, []byte(c.stderr)); got != c.want {
+			t.Fatalf("ClassifyExit(%d, %q) = %q, want %q", c.code, c.stderr, got, c.want)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want FailureKind
+	}{
+		{nil, ""},
+		{errors.New("boom"), ""},
+		{fmt.Errorf("run: %w", context.DeadlineExceeded), FailureKindTimeout},
+		{&exec.Error{Name: "sh", Err: exec.ErrNotFound}, FailureKindMissingTool},
+		{fmt.Errorf("dial: %w", syscall.ECONNREFUSED), FailureKindNetwork},
+	}
+	for _, c := range cases {
+		if got := ClassifyError(c.err); got != c.want {
+			t.Fatalf("ClassifyError(%v) = %q, want %q", c.err, got, c.want)
+		}
+	}
+}
+
+func TestFailureFromError_RecordsKindAndTransient(t *testing.T) {
+	f, err := failureFromError(&ExecutionFailureError{NodeID: "A", Code: "NodeFailed", Message: "killed", Kind: FailureKindOOM})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Kind != FailureKindOOM || !f.Transient {
+		t.Fatalf("unexpected failure: %#v", f)
+	}
+	f, err = failureFromError(&SystemFailureError{Code: "EngineError", Message: "spawn", Cause: &exec.Error{Name: "sh", Err: exec.ErrNotFound}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Kind != FailureKindMissingTool || f.Transient {
+		t.Fatalf("unexpected failure: %#v", f)
+	}
+	if err := (Failure{FailureClass: FailureClassSystem, ErrorCode: "X", ErrorMessage: "x", Kind: "flaky"}).Validate(); err == nil {
+		t.Fatalf("expected unknown kind to be rejected")
+	}
+}
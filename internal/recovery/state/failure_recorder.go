@@ -2,10 +2,10 @@ package state
 
 import (
 	"crypto/rand"
-	"encoding/hex"
 	"errors"
 	"fmt"
-	"time"
+
+	"scriptweaver/internal/platform"
 )
 
 // FailureRecorder writes failure.json artifacts for runs.
@@ -15,29 +15,29 @@ import (
 // the Failure record using Store (atomic + durable).
 type FailureRecorder struct {
 	Store *Store
+	// Clock stamps run IDs and start times; nil means the system clock.
+	Clock platform.Clock
 }
 
 func (r *FailureRecorder) NewRunID() (string, error) {
 	// Run IDs are operational identifiers. The frozen sprint-08 spec does not define
-	// a deterministic format, so we use a random 128-bit hex string.
-	var b [16]byte
-	if _, err := rand.Read(b[:]); err != nil {
-		return "", err
-	}
-	return hex.EncodeToString(b[:]), nil
+	// a deterministic format, so we use time-ordered ULIDs (see newRunID).
+	return newRunID(platform.ClockOr(r.Clock).Now(), rand.Reader)
 }
 
+// StartRun records the metadata of a new run. It fails with ErrRunExists
+// rather than overwrite a run recorded under the same ID.
 func (r *FailureRecorder) StartRun(run Run) error {
 	if r == nil || r.Store == nil {
 		return errors.New("Store is required")
 	}
 	if run.StartTime.IsZero() {
-		run.StartTime = time.Now().UTC()
+		run.StartTime = platform.ClockOr(r.Clock).Now().UTC()
 	}
 	if err := run.Validate(); err != nil {
 		return fmt.Errorf("invalid run: %w", err)
 	}
-	return r.Store.SaveRun(run)
+	return r.Store.CreateRun(run)
 }
 
 func (r *FailureRecorder) RecordFailure(runID string, err error) error {
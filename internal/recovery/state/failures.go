@@ -51,7 +51,13 @@ type ExecutionFailureError struct {
 	NodeID  string
 	Code    string
 	Message string
-	Cause   error
+	// Kind is the likely cause, typically from ClassifyExit.
+	Kind FailureKind
+	// FailedNodes and SkippedNodes enumerate every failed and skipped node
+	// when NodeID is a representative of several.
+	FailedNodes  []string
+	SkippedNodes []SkippedNode
+	Cause        error
 }
 
 func (e *ExecutionFailureError) Error() string {
@@ -74,7 +80,9 @@ func (e *ExecutionFailureError) Unwrap() error { return e.Cause }
 type SystemFailureError struct {
 	Code    string
 	Message string
-	Cause   error
+	// Kind is the likely cause; empty means ClassifyError(Cause).
+	Kind  FailureKind
+	Cause error
 }
 
 func (e *SystemFailureError) Error() string {
@@ -129,31 +137,59 @@ func failureFromError(err error) (Failure, error) {
 			ErrorCode:    nonEmptyOr(ef.Code, "ExecutionFailure"),
 			ErrorMessage: nonEmptyOr(ef.Message, ef.Error()),
 			// Conditionally resumable; the caller decides based on checkpoint presence.
-			Resumable: true,
+			Resumable:    true,
+			Kind:         ef.Kind,
+			Transient:    ef.Kind.Transient(),
+			FailedNodes:  ef.FailedNodes,
+			SkippedNodes: ef.SkippedNodes,
 		}, nil
 	}
 
 	var sf *SystemFailureError
 	if errors.As(err, &sf) && sf != nil {
+		kind := sf.Kind
+		if kind == "" {
+			kind = ClassifyError(sf.Cause)
+		}
+		code := nonEmptyOr(sf.Code, "SystemFailure")
 		return Failure{
 			FailureClass: FailureClassSystem,
 			NodeID:       nil,
-			ErrorCode:    nonEmptyOr(sf.Code, "SystemFailure"),
+			ErrorCode:    code,
 			ErrorMessage: nonEmptyOr(sf.Message, sf.Error()),
 			Resumable:    true,
+			Kind:         kind,
+			Transient:    systemFailureTransient(code, kind),
 		}, nil
 	}
 
 	// Unknown error: classify as system failure (most conservative within the 4-class taxonomy).
+	kind := ClassifyError(err)
 	return Failure{
 		FailureClass: FailureClassSystem,
 		NodeID:       nil,
 		ErrorCode:    "UnknownError",
 		ErrorMessage: err.Error(),
 		Resumable:    true,
+		Kind:         kind,
+		Transient:    systemFailureTransient("UnknownError", kind),
 	}, nil
 }
 
+// systemFailureTransient decides whether a system failure is worth retrying.
+// Without a recognized cause it is presumed to be infrastructure trouble,
+// except for cancellations, panics and plugin load errors, which would recur.
+func systemFailureTransient(code string, kind FailureKind) bool {
+	if kind != "" {
+		return kind.Transient()
+	}
+	switch code {
+	case "Cancelled", "Panic", "PluginLoad":
+		return false
+	}
+	return true
+}
+
 func nonEmptyOr(v, fallback string) string {
 	if v != "" {
 		return v
@@ -1,6 +1,9 @@
 package state
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestFailureFromError_ClassifiesGraphFailure(t *testing.T) {
 	f, err := failureFromError(&GraphFailureError{Code: "SchemaViolation", Message: "bad"})
@@ -41,3 +44,28 @@ func TestFailureFromError_ClassifiesSystemFailure(t *testing.T) {
 		t.Fatalf("unexpected failure: %#v", f)
 	}
 }
+
+func TestFailure_Transient(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&SystemFailureError{Code: "EngineError", Message: "spawn"}, true},
+		{errors.New("unknown"), true},
+		{&SystemFailureError{Code: "Cancelled", Message: "interrupted"}, false},
+		{&SystemFailureError{Code: "Panic", Message: "boom"}, false},
+		{&ExecutionFailureError{NodeID: "A", Code: "NodeFailed", Message: "exit 1", Kind: FailureKindExit}, false},
+		{&ExecutionFailureError{NodeID: "A", Code: "NodeFailed", Message: "exit 124", Kind: FailureKindTimeout}, true},
+		{&GraphFailureError{Code: "SchemaViolation", Message: "bad"}, false},
+		{&WorkspaceFailureError{Code: "WorkspaceInvalid", Message: "bad"}, false},
+	}
+	for _, c := range cases {
+		f, err := failureFromError(c.err)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := f.Transient; got != c.want {
+			t.Fatalf("Transient for %v = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
@@ -70,6 +70,10 @@ type Checkpoint struct {
 	CacheKeys  []string  `json:"cache_keys"`
 	OutputHash string    `json:"output_hash"`
 	Valid      bool      `json:"valid"`
+	// Upstream lists the node's direct dependencies when the checkpoint was
+	// taken, sorted. It is nil for checkpoints written before it was recorded,
+	// and empty (not nil) for nodes without dependencies.
+	Upstream []string `json:"upstream"`
 }
 
 func (c Checkpoint) Validate() error {
@@ -116,6 +120,25 @@ type Failure struct {
 	ErrorCode    string       `json:"error_code"`
 	ErrorMessage string       `json:"error_message"`
 	Resumable    bool         `json:"resumable"`
+	// Kind is the likely cause of the failure; empty when unknown.
+	Kind FailureKind `json:"kind,omitempty"`
+	// Transient is set for infrastructure and system failures that may not
+	// recur, so that retrying the run is worthwhile. Graph, workspace and
+	// ordinary task failures are deterministic.
+	Transient bool `json:"transient"`
+	// FailedNodes lists every failed node, in lexical order, and SkippedNodes
+	// every node skipped because of them; NodeID is a representative of
+	// FailedNodes.
+	FailedNodes  []string      `json:"failed_nodes,omitempty"`
+	SkippedNodes []SkippedNode `json:"skipped_nodes,omitempty"`
+}
+
+// SkippedNode is a node that was not executed because an upstream node
+// failed.
+type SkippedNode struct {
+	NodeID string `json:"node_id"`
+	// Cause is the failed node whose failure skipped NodeID.
+	Cause string `json:"cause"`
 }
 
 func (f Failure) Validate() error {
@@ -129,6 +152,9 @@ func (f Failure) Validate() error {
 	if f.NodeID != nil && strings.TrimSpace(*f.NodeID) == "" {
 		errs = append(errs, errors.New("node_id must not be empty when provided"))
 	}
+	if !f.Kind.valid() {
+		errs = append(errs, fmt.Errorf("invalid kind %q", f.Kind))
+	}
 	if strings.TrimSpace(f.ErrorCode) == "" {
 		errs = append(errs, errors.New("error_code is required"))
 	}
@@ -140,3 +166,83 @@ func (f Failure) Validate() error {
 	}
 	return errors.Join(errs...)
 }
+
+// NodeFailure is the post-mortem context of a failed node, recorded for every
+// failed node so that a failure can be investigated without rerunning it.
+type NodeFailure struct {
+	NodeID  string `json:"node_id"`
+	Command string `json:"command"`
+	// EnvDigest is the sha256 hex of the node's resolved environment, so that
+	// runs can be compared without storing secrets.
+	EnvDigest string `json:"env_digest"`
+	// ExitCode is nil for a node that never ran (FailureKindSkipPolicy).
+	ExitCode *int        `json:"exit_code,omitempty"`
+	Kind     FailureKind `json:"kind"`
+	// Cause is, for FailureKindSkipPolicy, the node skipped on request that
+	// the node depends on.
+	Cause string `json:"cause,omitempty"`
+	// StderrTail holds at most StderrTailBytes of the end of stderr;
+	// StderrTruncated is set when earlier output was dropped.
+	StderrTail      string        `json:"stderr_tail"`
+	StderrTruncated bool          `json:"stderr_truncated"`
+	Duration        time.Duration `json:"duration_ns"`
+	// RetryCount is the retry count of the run the node failed in.
+	RetryCount int `json:"retry_count"`
+	// Owner, Description and DocsURL are the node's metadata annotations,
+	// empty when the graph does not declare them.
+	Owner       string `json:"owner,omitempty"`
+	Description string `json:"description,omitempty"`
+	DocsURL     string `json:"docs_url,omitempty"`
+}
+
+// NodeAttempt is one attempt at a node: its execution, or its restoration
+// from the cache or a checkpoint, in one run.
+type NodeAttempt struct {
+	// Attempt numbers the attempts at the node from 1, across a run and the
+	// runs it retries or resumes.
+	Attempt   int           `json:"attempt"`
+	RunID     string        `json:"run_id"`
+	ExitCode  int           `json:"exit_code"`
+	Duration  time.Duration `json:"duration_ns"`
+	FromCache bool          `json:"from_cache"`
+}
+
+func (a NodeAttempt) Validate() error {
+	var errs []error
+	if a.Attempt < 1 {
+		errs = append(errs, errors.New("attempt must be >= 1"))
+	}
+	if strings.TrimSpace(a.RunID) == "" {
+		errs = append(errs, errors.New("run_id is required"))
+	}
+	if a.Duration < 0 {
+		errs = append(errs, errors.New("duration_ns must be >= 0"))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(errs...)
+}
+
+// StderrTailBytes bounds NodeFailure.StderrTail.
+const StderrTailBytes = 4096
+
+func (f NodeFailure) Validate() error {
+	var errs []error
+	if strings.TrimSpace(f.NodeID) == "" {
+		errs = append(errs, errors.New("node_id is required"))
+	}
+	if !f.Kind.valid() {
+		errs = append(errs, fmt.Errorf("invalid kind %q", f.Kind))
+	}
+	if len(f.StderrTail) > StderrTailBytes {
+		errs = append(errs, fmt.Errorf("stderr_tail exceeds %d bytes", StderrTailBytes))
+	}
+	if f.RetryCount < 0 {
+		errs = append(errs, errors.New("retry_count must be >= 0"))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+)
+
+// OutputOwnership records, per node, every output file the node has produced
+// in the workspace, with paths relative to the workspace root in slash form.
+// Unlike run records it spans runs, so that files left behind by nodes that
+// were removed from the graph, or that stopped declaring an output, can be
+// found.
+type OutputOwnership struct {
+	Nodes map[string][]string `json:"nodes"`
+}
+
+// Add records files as produced by node, keeping earlier records.
+func (o *OutputOwnership) Add(node string, files []string) {
+	if o.Nodes == nil {
+		o.Nodes = make(map[string][]string)
+	}
+	o.Nodes[node] = mergeSorted(o.Nodes[node], files)
+}
+
+// Remove forgets file for node, and node once it owns no files.
+func (o *OutputOwnership) Remove(node, file string) {
+	files := o.Nodes[node]
+	for i, f := range files {
+		if f == file {
+			files = append(files[:i:i], files[i+1:]...)
+			break
+		}
+	}
+	if len(files) == 0 {
+		delete(o.Nodes, node)
+		return
+	}
+	o.Nodes[node] = files
+}
+
+func mergeSorted(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	out := make([]string, 0, len(a)+len(b))
+	for _, list := range [][]string{a, b} {
+		for _, f := range list {
+			if !seen[f] {
+				seen[f] = true
+				out = append(out, f)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// outputOwnershipPath matches workspace.OutputsName.
+func (s *Store) outputOwnershipPath() string {
+	return filepath.Join(s.baseDir, ".scriptweaver", "outputs.json")
+}
+
+// LoadOutputOwnership reads the workspace's output ownership record. A
+// workspace without one has no recorded outputs.
+func (s *Store) LoadOutputOwnership() (OutputOwnership, error) {
+	if s == nil {
+		return OutputOwnership{}, errors.New("nil Store")
+	}
+	var o OutputOwnership
+	if err := s.readJSONStrict(s.outputOwnershipPath(), &o); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return OutputOwnership{Nodes: map[string][]string{}}, nil
+		}
+		return OutputOwnership{}, fmt.Errorf("read output ownership: %w", err)
+	}
+	if o.Nodes == nil {
+		o.Nodes = map[string][]string{}
+	}
+	return o, nil
+}
+
+// SaveOutputOwnership atomically replaces the workspace's output ownership
+// record.
+func (s *Store) SaveOutputOwnership(o OutputOwnership) error {
+	if s == nil {
+		return errors.New("nil Store")
+	}
+	if o.Nodes == nil {
+		o.Nodes = map[string][]string{}
+	}
+	data, err := jsonMarshalStable(o)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureDirDurable(filepath.Dir(s.outputOwnershipPath()), 0o755); err != nil {
+		return fmt.Errorf("ensure workspace dir: %w", err)
+	}
+	path := s.outputOwnershipPath()
+	if err := s.writeFileAtomicDurable(path, data, 0o644); err != nil {
+		return fmt.Errorf("write output ownership: %w", err)
+	}
+	s.recordWrite(path)
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 
+	"scriptweaver/internal/core"
 	"scriptweaver/internal/incremental"
 	"scriptweaver/internal/projectintegration/engine/workspace"
 )
@@ -14,7 +15,8 @@ import (
 // ResumeEligibilityChecker determines whether a new run may resume from a previous run.
 //
 // Enforces frozen sprint-08 Resume Eligibility Rules:
-//   - Graph hash unchanged
+//   - Graph hash unchanged, unless the request is node-level (see
+//     ResumeEligibilityRequest.NodeLevel)
 //   - Workspace intact and validated
 //   - previous_run_id linked and exists
 //   - No upstream invalidation markers exist
@@ -38,6 +40,12 @@ type ResumeEligibilityRequest struct {
 	// used to verify that no upstream invalidation exists.
 	Graph        *incremental.GraphSnapshot
 	Invalidation incremental.InvalidationMap
+
+	// NodeLevel permits resuming although the graph hash changed. The caller
+	// must then mark every node whose task hash or dependencies differ from
+	// the previous run as invalidated, so that the upstream invalidation rule
+	// only lets unchanged ancestries be reused.
+	NodeLevel bool
 }
 
 func (c *ResumeEligibilityChecker) Check(req ResumeEligibilityRequest) error {
@@ -77,8 +85,8 @@ func (c *ResumeEligibilityChecker) Check(req ResumeEligibilityRequest) error {
 		return fmt.Errorf("previous run does not exist: %w", err)
 	}
 
-	// Graph hash must be unchanged.
-	if prevRun.GraphHash != req.NewRun.GraphHash {
+	// Graph hash must be unchanged unless compatibility is decided per node.
+	if !core.SameHash(prevRun.GraphHash, req.NewRun.GraphHash) && !req.NodeLevel {
 		return fmt.Errorf("graph hash mismatch (prev=%s new=%s)", prevRun.GraphHash, req.NewRun.GraphHash)
 	}
 
@@ -144,3 +144,31 @@ func TestResumeEligibilityChecker_Rejects_WhenWorkspaceUnauthorizedEntry(t *test
 		t.Fatalf("expected error")
 	}
 }
+
+func TestResumeEligibilityChecker_NodeLevel_AllowsGraphHashChange(t *testing.T) {
+	root := t.TempDir()
+	store, _ := NewStore(root)
+
+	prev := Run{RunID: "prev", GraphHash: "gh1", StartTime: time.Unix(1, 0).UTC(), Mode: ExecutionModeIncremental, RetryCount: 0, Status: "failed"}
+	_ = store.SaveRun(prev)
+	_ = store.SaveFailure("prev", Failure{FailureClass: FailureClassExecution, ErrorCode: "E", ErrorMessage: "err", Resumable: true})
+
+	prevID := "prev"
+	newRun := Run{RunID: "new", GraphHash: "gh2", StartTime: time.Unix(2, 0).UTC(), Mode: ExecutionModeIncremental, RetryCount: 1, Status: "running", PreviousRunID: &prevID}
+	g := &incremental.GraphSnapshot{Nodes: map[string]incremental.NodeSnapshot{
+		"A": {Name: "A", Upstream: []string{}},
+		"B": {Name: "B", Upstream: []string{"A"}},
+	}}
+
+	checker := &ResumeEligibilityChecker{Store: store, ProjectRoot: root}
+	req := ResumeEligibilityRequest{NewRun: newRun, ResumeFromNodeID: "B", Graph: g, Invalidation: incremental.InvalidationMap{"A": {}, "B": {}}, NodeLevel: true}
+	if err := checker.Check(req); err != nil {
+		t.Fatalf("expected node-level resume to be eligible: %v", err)
+	}
+
+	// A changed node still blocks resuming from its descendants.
+	req.Invalidation = incremental.InvalidationMap{"A": {Invalidated: true}, "B": {}}
+	if err := checker.Check(req); err == nil {
+		t.Fatalf("expected upstream invalidation to block resume")
+	}
+}
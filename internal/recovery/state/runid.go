@@ -0,0 +1,68 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Run IDs are ULIDs: a 48-bit millisecond timestamp followed by 80 random
+// bits, encoded as 26 characters of Crockford base32. They sort by start time
+// and stay unique across concurrent invocations on one or many machines.
+const runIDLen = 26
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// machineSeed identifies this process on this machine. It is mixed into the
+// random bits of every run ID so that processes whose random sources repeat,
+// such as cloned VMs or containers restored from a snapshot, still generate
+// distinct IDs.
+var machineSeed = func() []byte {
+	host, _ := os.Hostname()
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(os.Getpid()))
+	binary.BigEndian.PutUint64(b[8:], uint64(time.Now().UnixNano()))
+	sum := sha256.Sum256(append([]byte(host), b[:]...))
+	return sum[:]
+}()
+
+// runIDSeq distinguishes the run IDs generated by this process.
+var runIDSeq atomic.Uint64
+
+// newRunID returns the ULID for a run started at now, drawing its random bits
+// from random.
+func newRunID(now time.Time, random io.Reader) (string, error) {
+	var id [16]byte
+	ms := uint64(now.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(ms)
+		ms >>= 8
+	}
+	if _, err := io.ReadFull(random, id[6:]); err != nil {
+		return "", err
+	}
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], runIDSeq.Add(1))
+	mix := sha256.Sum256(append(append([]byte{}, machineSeed...), seq[:]...))
+	for i := 6; i < len(id); i++ {
+		id[i] ^= mix[i]
+	}
+	return encodeRunID(id), nil
+}
+
+// encodeRunID encodes the 128 bits of id as 26 base32 digits, most
+// significant first; the first digit holds the top 3 bits.
+func encodeRunID(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [runIDLen]byte
+	for i := runIDLen - 1; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
@@ -0,0 +1,83 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEncodeRunID_CrockfordBase32(t *testing.T) {
+	var zero, ones [16]byte
+	for i := range ones {
+		ones[i] = 0xff
+	}
+	if got := encodeRunID(zero); got != "00000000000000000000000000" {
+		t.Fatalf("zero id = %q", got)
+	}
+	if got := encodeRunID(ones); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Fatalf("max id = %q", got)
+	}
+}
+
+func TestNewRunID_SortsByTimeAndIsUniqueAcrossGoroutines(t *testing.T) {
+	format := regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`)
+	earlier, err := newRunID(time.UnixMilli(1000), bytes.NewReader(make([]byte, 10)))
+	if err != nil {
+		t.Fatalf("newRunID: %v", err)
+	}
+	later, err := newRunID(time.UnixMilli(1001), bytes.NewReader(make([]byte, 10)))
+	if err != nil {
+		t.Fatalf("newRunID: %v", err)
+	}
+	if !format.MatchString(earlier) || earlier >= later {
+		t.Fatalf("run ids %q, %q are not time-ordered ULIDs", earlier, later)
+	}
+
+	// Even an entropy source that repeats itself yields distinct IDs.
+	const n = 64
+	ids := make([]string, n)
+	var wg sync.WaitGroup
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], _ = newRunID(now, bytes.NewReader(make([]byte, 10)))
+		}(i)
+	}
+	wg.Wait()
+	sort.Strings(ids)
+	for i := 1; i < n; i++ {
+		if ids[i] == ids[i-1] {
+			t.Fatalf("duplicate run id %q", ids[i])
+		}
+	}
+}
+
+func TestStartRun_FailsOnRunIDCollision(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	rec := &FailureRecorder{Store: store}
+	run := Run{RunID: "r1", GraphHash: "g1", StartTime: time.Unix(1, 0).UTC(), Mode: ExecutionModeClean, Status: "running"}
+	if err := rec.StartRun(run); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	other := run
+	other.GraphHash = "g2"
+	if err := rec.StartRun(other); !errors.Is(err, ErrRunExists) {
+		t.Fatalf("expected ErrRunExists, got %v", err)
+	}
+	loaded, err := store.LoadRun("r1")
+	if err != nil {
+		t.Fatalf("LoadRun: %v", err)
+	}
+	if loaded.GraphHash != "g1" {
+		t.Fatalf("first run was overwritten: %+v", loaded)
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"scriptweaver/internal/audit"
+	"scriptweaver/internal/platform"
 )
 
 // Store provides persistent storage for execution state under:
@@ -18,6 +22,8 @@ import (
 // All state writes are atomic and durable (file sync + atomic rename + dir sync).
 type Store struct {
 	baseDir string
+	audit   *audit.Log
+	fsys    platform.FS
 }
 
 func NewStore(baseDir string) (*Store, error) {
@@ -27,6 +33,29 @@ func NewStore(baseDir string) (*Store, error) {
 	return &Store{baseDir: baseDir}, nil
 }
 
+// SetAuditLog makes every successful state write append an audit entry.
+// A nil log disables auditing.
+func (s *Store) SetAuditLog(l *audit.Log) {
+	s.audit = l
+}
+
+// SetFS makes the store read and write through fsys instead of the host
+// filesystem. A nil fsys restores the host filesystem.
+func (s *Store) SetFS(fsys platform.FS) {
+	s.fsys = fsys
+}
+
+func (s *Store) fs() platform.FS { return platform.FSOr(s.fsys) }
+
+// recordWrite audits a state file write, best-effort.
+func (s *Store) recordWrite(path string) {
+	rel, err := filepath.Rel(s.baseDir, path)
+	if err != nil {
+		rel = path
+	}
+	_ = s.audit.Record(audit.KindStateWrite, filepath.ToSlash(rel))
+}
+
 func (s *Store) runsRootDir() string {
 	return filepath.Join(s.baseDir, ".scriptweaver", "runs")
 }
@@ -39,7 +68,7 @@ func (s *Store) ListRunIDs() ([]string, error) {
 		return nil, errors.New("nil Store")
 	}
 	root := s.runsRootDir()
-	entries, err := os.ReadDir(root)
+	entries, err := s.fs().ReadDir(root)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -73,10 +102,97 @@ func (s *Store) failurePath(runID string) string {
 	return filepath.Join(s.runDir(runID), "failure.json")
 }
 
+func (s *Store) attemptsPath(runID string) string {
+	return filepath.Join(s.runDir(runID), "attempts.json")
+}
+
 func (s *Store) checkpointsDir(runID string) string {
 	return filepath.Join(s.runDir(runID), "checkpoints")
 }
 
+// RunCacheDir returns the run-scoped artifact cache directory of runID. Runs
+// without a shared cache (clean mode) keep the artifacts of their checkpointed
+// nodes there so that a later run can resume from them.
+func (s *Store) RunCacheDir(runID string) string {
+	return filepath.Join(s.runDir(runID), "cache")
+}
+
+// RunDir returns the directory holding everything recorded for runID.
+func (s *Store) RunDir(runID string) (string, error) {
+	if strings.TrimSpace(runID) == "" || runID != filepath.Base(runID) {
+		return "", fmt.Errorf("invalid runID %q", runID)
+	}
+	return s.runDir(runID), nil
+}
+
+// RunFilePath returns the path of the run file name of runID, for files that
+// are written incrementally rather than with SaveRunFile. The run directory
+// exists once the run has started.
+func (s *Store) RunFilePath(runID, name string) (string, error) {
+	if strings.TrimSpace(runID) == "" {
+		return "", errors.New("runID is required")
+	}
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid run file name %q", name)
+	}
+	return filepath.Join(s.runDir(runID), name), nil
+}
+
+// RunSize returns the total size in bytes of the files recorded for runID.
+func (s *Store) RunSize(runID string) (int64, error) {
+	dir, err := s.RunDir(runID)
+	if err != nil {
+		return 0, err
+	}
+	return s.dirSize(dir)
+}
+
+func (s *Store) dirSize(dir string) (int64, error) {
+	entries, err := s.fs().ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() {
+			n, err := s.dirSize(filepath.Join(dir, e.Name()))
+			if err != nil {
+				return 0, err
+			}
+			total += n
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return 0, err
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// RemoveRun deletes runID and everything recorded for it: its metadata,
+// checkpoints, failures, run files and run-scoped cache.
+func (s *Store) RemoveRun(runID string) error {
+	dir, err := s.RunDir(runID)
+	if err != nil {
+		return err
+	}
+	if err := s.fs().RemoveAll(dir); err != nil {
+		return err
+	}
+	_ = s.audit.Record(audit.KindRunPurge, runID)
+	return nil
+}
+
+func (s *Store) nodeFailuresDir(runID string) string {
+	return filepath.Join(s.runDir(runID), "failures")
+}
+
+func (s *Store) nodeFailurePath(runID, nodeID string) string {
+	return filepath.Join(s.nodeFailuresDir(runID), nodeID+".json")
+}
+
 func (s *Store) checkpointPath(runID, nodeID string) string {
 	// Assumption (documented in notes): node_id is a stable identifier safe to use as a filename.
 	return filepath.Join(s.checkpointsDir(runID), nodeID+".json")
@@ -93,14 +209,14 @@ func (s *Store) LoadAllCheckpoints(runID string) (map[string]Checkpoint, error)
 		return nil, errors.New("runID is required")
 	}
 	dir := s.checkpointsDir(runID)
-	entries, err := os.ReadDir(dir)
+	entries, err := s.fs().ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return map[string]Checkpoint{}, nil
 		}
 		return nil, err
 	}
-	// os.ReadDir returns entries sorted by filename.
+	// ReadDir returns entries sorted by filename.
 	out := make(map[string]Checkpoint, 0)
 	for _, e := range entries {
 		if e.IsDir() {
@@ -127,16 +243,45 @@ func (s *Store) SaveRun(run Run) error {
 	if err := run.Validate(); err != nil {
 		return fmt.Errorf("invalid run: %w", err)
 	}
-	if err := ensureDirDurable(s.runDir(run.RunID), 0o755); err != nil {
+	if err := s.ensureDirDurable(s.runDir(run.RunID), 0o755); err != nil {
 		return fmt.Errorf("ensure run dir: %w", err)
 	}
 	data, err := jsonMarshalStable(run)
 	if err != nil {
 		return fmt.Errorf("marshal run: %w", err)
 	}
-	if err := writeFileAtomicDurable(s.runPath(run.RunID), data, 0o644); err != nil {
+	if err := s.writeFileAtomicDurable(s.runPath(run.RunID), data, 0o644); err != nil {
 		return fmt.Errorf("write run: %w", err)
 	}
+	s.recordWrite(s.runPath(run.RunID))
+	return nil
+}
+
+// ErrRunExists is returned by CreateRun when a run is already recorded under
+// the same ID.
+var ErrRunExists = errors.New("run already exists")
+
+// CreateRun records a new run. Unlike SaveRun it never replaces an existing
+// run.json: the record is linked into place, which fails atomically when the
+// ID is taken, even by a concurrent process.
+func (s *Store) CreateRun(run Run) error {
+	if err := run.Validate(); err != nil {
+		return fmt.Errorf("invalid run: %w", err)
+	}
+	if err := s.ensureDirDurable(s.runDir(run.RunID), 0o755); err != nil {
+		return fmt.Errorf("ensure run dir: %w", err)
+	}
+	data, err := jsonMarshalStable(run)
+	if err != nil {
+		return fmt.Errorf("marshal run: %w", err)
+	}
+	if err := s.writeFileDurable(s.runPath(run.RunID), data, 0o644, s.linkNew); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("run %s: %w", run.RunID, ErrRunExists)
+		}
+		return fmt.Errorf("write run: %w", err)
+	}
+	s.recordWrite(s.runPath(run.RunID))
 	return nil
 }
 
@@ -145,7 +290,7 @@ func (s *Store) LoadRun(runID string) (Run, error) {
 	if strings.TrimSpace(runID) == "" {
 		return Run{}, errors.New("runID is required")
 	}
-	if err := readJSONStrict(s.runPath(runID), &run); err != nil {
+	if err := s.readJSONStrict(s.runPath(runID), &run); err != nil {
 		return Run{}, err
 	}
 	if err := run.Validate(); err != nil {
@@ -166,16 +311,17 @@ func (s *Store) SaveCheckpoint(runID string, checkpoint Checkpoint) error {
 		checkpoint.CacheKeys = []string{}
 	}
 
-	if err := ensureDirDurable(s.checkpointsDir(runID), 0o755); err != nil {
+	if err := s.ensureDirDurable(s.checkpointsDir(runID), 0o755); err != nil {
 		return fmt.Errorf("ensure checkpoints dir: %w", err)
 	}
 	data, err := jsonMarshalStable(checkpoint)
 	if err != nil {
 		return fmt.Errorf("marshal checkpoint: %w", err)
 	}
-	if err := writeFileAtomicDurable(s.checkpointPath(runID, checkpoint.NodeID), data, 0o644); err != nil {
+	if err := s.writeFileAtomicDurable(s.checkpointPath(runID, checkpoint.NodeID), data, 0o644); err != nil {
 		return fmt.Errorf("write checkpoint: %w", err)
 	}
+	s.recordWrite(s.checkpointPath(runID, checkpoint.NodeID))
 	return nil
 }
 
@@ -187,7 +333,7 @@ func (s *Store) LoadCheckpoint(runID, nodeID string) (Checkpoint, error) {
 	if strings.TrimSpace(nodeID) == "" {
 		return Checkpoint{}, errors.New("nodeID is required")
 	}
-	if err := readJSONStrict(s.checkpointPath(runID, nodeID), &checkpoint); err != nil {
+	if err := s.readJSONStrict(s.checkpointPath(runID, nodeID), &checkpoint); err != nil {
 		return Checkpoint{}, err
 	}
 	if checkpoint.CacheKeys == nil {
@@ -206,25 +352,163 @@ func (s *Store) SaveFailure(runID string, failure Failure) error {
 	if err := failure.Validate(); err != nil {
 		return fmt.Errorf("invalid failure: %w", err)
 	}
-	if err := ensureDirDurable(s.runDir(runID), 0o755); err != nil {
+	if err := s.ensureDirDurable(s.runDir(runID), 0o755); err != nil {
 		return fmt.Errorf("ensure run dir: %w", err)
 	}
 	data, err := jsonMarshalStable(failure)
 	if err != nil {
 		return fmt.Errorf("marshal failure: %w", err)
 	}
-	if err := writeFileAtomicDurable(s.failurePath(runID), data, 0o644); err != nil {
+	if err := s.writeFileAtomicDurable(s.failurePath(runID), data, 0o644); err != nil {
 		return fmt.Errorf("write failure: %w", err)
 	}
+	s.recordWrite(s.failurePath(runID))
+	return nil
+}
+
+// SaveNodeFailure records the post-mortem context of a failed node of runID.
+func (s *Store) SaveNodeFailure(runID string, failure NodeFailure) error {
+	if strings.TrimSpace(runID) == "" {
+		return errors.New("runID is required")
+	}
+	if err := failure.Validate(); err != nil {
+		return fmt.Errorf("invalid node failure: %w", err)
+	}
+	if err := s.ensureDirDurable(s.nodeFailuresDir(runID), 0o755); err != nil {
+		return fmt.Errorf("ensure failures dir: %w", err)
+	}
+	data, err := jsonMarshalStable(failure)
+	if err != nil {
+		return fmt.Errorf("marshal node failure: %w", err)
+	}
+	path := s.nodeFailurePath(runID, failure.NodeID)
+	if err := s.writeFileAtomicDurable(path, data, 0o644); err != nil {
+		return fmt.Errorf("write node failure: %w", err)
+	}
+	s.recordWrite(path)
 	return nil
 }
 
+// LoadNodeFailures loads the node failures recorded for runID, ordered by
+// node ID. A run without failed nodes has none.
+func (s *Store) LoadNodeFailures(runID string) ([]NodeFailure, error) {
+	if strings.TrimSpace(runID) == "" {
+		return nil, errors.New("runID is required")
+	}
+	entries, err := s.fs().ReadDir(s.nodeFailuresDir(runID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []NodeFailure{}, nil
+		}
+		return nil, err
+	}
+	out := make([]NodeFailure, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		var f NodeFailure
+		if err := s.readJSONStrict(filepath.Join(s.nodeFailuresDir(runID), e.Name()), &f); err != nil {
+			return nil, err
+		}
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid node failure on disk: %w", err)
+		}
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].NodeID < out[j].NodeID })
+	return out, nil
+}
+
+// SaveAttempts records the attempts at each node of runID, by node ID. Unlike
+// a checkpoint, which keeps only a node's latest successful result, the
+// attempts of a node include its failures and those of the runs runID
+// retries or resumes.
+func (s *Store) SaveAttempts(runID string, attempts map[string][]NodeAttempt) error {
+	if strings.TrimSpace(runID) == "" {
+		return errors.New("runID is required")
+	}
+	for node, list := range attempts {
+		for _, a := range list {
+			if err := a.Validate(); err != nil {
+				return fmt.Errorf("invalid attempt of node %s: %w", node, err)
+			}
+		}
+	}
+	if err := s.ensureDirDurable(s.runDir(runID), 0o755); err != nil {
+		return fmt.Errorf("ensure run dir: %w", err)
+	}
+	data, err := jsonMarshalStable(attempts)
+	if err != nil {
+		return fmt.Errorf("marshal attempts: %w", err)
+	}
+	if err := s.writeFileAtomicDurable(s.attemptsPath(runID), data, 0o644); err != nil {
+		return fmt.Errorf("write attempts: %w", err)
+	}
+	s.recordWrite(s.attemptsPath(runID))
+	return nil
+}
+
+// LoadAttempts loads the attempts recorded for runID by SaveAttempts. A run
+// that recorded none, such as one that crashed, has none.
+func (s *Store) LoadAttempts(runID string) (map[string][]NodeAttempt, error) {
+	if strings.TrimSpace(runID) == "" {
+		return nil, errors.New("runID is required")
+	}
+	attempts := map[string][]NodeAttempt{}
+	if err := s.readJSONStrict(s.attemptsPath(runID), &attempts); err != nil {
+		if os.IsNotExist(err) {
+			return map[string][]NodeAttempt{}, nil
+		}
+		return nil, err
+	}
+	for node, list := range attempts {
+		for _, a := range list {
+			if err := a.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid attempt of node %s on disk: %w", node, err)
+			}
+		}
+	}
+	return attempts, nil
+}
+
+// SaveRunFile atomically writes an auxiliary file (e.g. provenance.json) into
+// the run directory. name must be a plain file name.
+func (s *Store) SaveRunFile(runID, name string, data []byte) error {
+	if strings.TrimSpace(runID) == "" {
+		return errors.New("runID is required")
+	}
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return fmt.Errorf("invalid run file name %q", name)
+	}
+	if err := s.ensureDirDurable(s.runDir(runID), 0o755); err != nil {
+		return fmt.Errorf("ensure run dir: %w", err)
+	}
+	path := filepath.Join(s.runDir(runID), name)
+	if err := s.writeFileAtomicDurable(path, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	s.recordWrite(path)
+	return nil
+}
+
+// LoadRunFile reads an auxiliary file written by SaveRunFile.
+func (s *Store) LoadRunFile(runID, name string) ([]byte, error) {
+	if strings.TrimSpace(runID) == "" {
+		return nil, errors.New("runID is required")
+	}
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return nil, fmt.Errorf("invalid run file name %q", name)
+	}
+	return s.fs().ReadFile(filepath.Join(s.runDir(runID), name))
+}
+
 func (s *Store) LoadFailure(runID string) (Failure, error) {
 	var failure Failure
 	if strings.TrimSpace(runID) == "" {
 		return Failure{}, errors.New("runID is required")
 	}
-	if err := readJSONStrict(s.failurePath(runID), &failure); err != nil {
+	if err := s.readJSONStrict(s.failurePath(runID), &failure); err != nil {
 		return Failure{}, err
 	}
 	if err := failure.Validate(); err != nil {
@@ -241,8 +525,8 @@ func jsonMarshalStable(v any) ([]byte, error) {
 	return append(b, '\n'), nil
 }
 
-func readJSONStrict(path string, dst any) error {
-	f, err := os.Open(path)
+func (s *Store) readJSONStrict(path string, dst any) error {
+	f, err := s.fs().Open(path)
 	if err != nil {
 		return err
 	}
@@ -260,31 +544,45 @@ func readJSONStrict(path string, dst any) error {
 	return nil
 }
 
-func ensureDirDurable(dir string, perm os.FileMode) error {
-	if err := os.MkdirAll(dir, perm); err != nil {
+func (s *Store) ensureDirDurable(dir string, perm os.FileMode) error {
+	if err := s.fs().MkdirAll(dir, perm); err != nil {
 		return err
 	}
 	// Best-effort durability: sync the directory and its parent.
-	if err := fsyncDir(dir); err != nil {
+	if err := s.fsyncDir(dir); err != nil {
 		return err
 	}
 	parent := filepath.Dir(dir)
 	if parent != dir {
-		if err := fsyncDir(parent); err != nil {
+		if err := s.fsyncDir(parent); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func writeFileAtomicDurable(path string, data []byte, perm os.FileMode) error {
+func (s *Store) writeFileAtomicDurable(path string, data []byte, perm os.FileMode) error {
+	return s.writeFileDurable(path, data, perm, s.fs().Rename)
+}
+
+// linkNew moves tmp to path, failing with fs.ErrExist if path exists.
+func (s *Store) linkNew(tmp, path string) error {
+	if err := s.fs().Link(tmp, path); err != nil {
+		return err
+	}
+	return s.fs().Remove(tmp)
+}
+
+// writeFileDurable writes data to a temporary file next to path, syncs it and
+// moves it into place with commit.
+func (s *Store) writeFileDurable(path string, data []byte, perm os.FileMode, commit func(tmp, path string) error) error {
 	dir := filepath.Dir(path)
 	base := filepath.Base(path)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
+	if err := s.fs().MkdirAll(dir, 0o755); err != nil {
 		return err
 	}
 
-	tmp, err := os.CreateTemp(dir, base+".tmp.*")
+	tmp, err := s.fs().CreateTemp(dir, base+".tmp.*")
 	if err != nil {
 		return err
 	}
@@ -293,7 +591,7 @@ func writeFileAtomicDurable(path string, data []byte, perm os.FileMode) error {
 	defer func() {
 		_ = tmp.Close()
 		if !committed {
-			_ = os.Remove(tmpName)
+			_ = s.fs().Remove(tmpName)
 		}
 	}()
 
@@ -310,15 +608,15 @@ func writeFileAtomicDurable(path string, data []byte, perm os.FileMode) error {
 	if err := tmp.Close(); err != nil {
 		return err
 	}
-	if err := os.Rename(tmpName, path); err != nil {
+	if err := commit(tmpName, path); err != nil {
 		return err
 	}
 	committed = true
-	return fsyncDir(dir)
+	return s.fsyncDir(dir)
 }
 
-func fsyncDir(dir string) error {
-	f, err := os.Open(dir)
+func (s *Store) fsyncDir(dir string) error {
+	f, err := s.fs().Open(dir)
 	if err != nil {
 		return err
 	}
@@ -1,11 +1,16 @@
 package state
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
+
+	"scriptweaver/internal/platform"
 )
 
 func TestStore_SaveAndLoadRun_IncludesNullablePreviousRunID(t *testing.T) {
@@ -109,3 +114,129 @@ func TestStore_SaveAndLoadFailure_NodeIDOptional(t *testing.T) {
 		t.Fatalf("loaded failure mismatch: %+v", loaded)
 	}
 }
+
+func TestStore_SaveAndLoadNodeFailures_OrderedByNode(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if got, err := store.LoadNodeFailures("run-1"); err != nil || len(got) != 0 {
+		t.Fatalf("expected no node failures, got %v (err=%v)", got, err)
+	}
+	for _, id := range []string{"b", "a.b", "a"} {
+		if err := store.SaveNodeFailure("run-1", NodeFailure{NodeID: id, Command: "false", ExitCode: new(int), Kind: FailureKindExit}); err != nil {
+			t.Fatalf("SaveNodeFailure(%s): %v", id, err)
+		}
+	}
+	got, err := store.LoadNodeFailures("run-1")
+	if err != nil {
+		t.Fatalf("LoadNodeFailures: %v", err)
+	}
+	var ids []string
+	for _, f := range got {
+		ids = append(ids, f.NodeID)
+	}
+	if strings.Join(ids, ",") != "a,a.b,b" {
+		t.Fatalf("unexpected order: %v", ids)
+	}
+	if err := store.SaveNodeFailure("run-1", NodeFailure{NodeID: "c", StderrTail: strings.Repeat("x", StderrTailBytes+1)}); err == nil {
+		t.Fatalf("expected oversized stderr tail to be rejected")
+	}
+}
+
+func TestStore_SaveAndLoadAttempts(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if got, err := store.LoadAttempts("run-2"); err != nil || len(got) != 0 {
+		t.Fatalf("expected no attempts, got %v (err=%v)", got, err)
+	}
+	want := map[string][]NodeAttempt{"a": {
+		{Attempt: 1, RunID: "run-1", ExitCode: 7, Duration: time.Second},
+		{Attempt: 2, RunID: "run-2", Duration: 2 * time.Second},
+	}}
+	if err := store.SaveAttempts("run-2", want); err != nil {
+		t.Fatalf("SaveAttempts: %v", err)
+	}
+	got, err := store.LoadAttempts("run-2")
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("LoadAttempts = %v (err=%v), want %v", got, err, want)
+	}
+	if err := store.SaveAttempts("run-2", map[string][]NodeAttempt{"a": {{RunID: "run-2"}}}); err == nil {
+		t.Fatalf("expected attempt 0 to be rejected")
+	}
+}
+
+func TestStore_SaveAndLoadOutputOwnership_MergesAndForgets(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	o, err := store.LoadOutputOwnership()
+	if err != nil || len(o.Nodes) != 0 {
+		t.Fatalf("empty workspace: %#v, %v", o, err)
+	}
+
+	o.Add("a", []string{"b.txt", "a.txt"})
+	o.Add("a", []string{"a.txt", "c.txt"})
+	o.Add("b", []string{"x.txt"})
+	o.Remove("b", "x.txt")
+	if err := store.SaveOutputOwnership(o); err != nil {
+		t.Fatalf("SaveOutputOwnership: %v", err)
+	}
+	got, err := store.LoadOutputOwnership()
+	if err != nil {
+		t.Fatalf("LoadOutputOwnership: %v", err)
+	}
+	if len(got.Nodes) != 1 || strings.Join(got.Nodes["a"], ",") != "a.txt,b.txt,c.txt" {
+		t.Fatalf("unexpected ownership: %#v", got)
+	}
+}
+
+func TestStore_FailedWriteLeavesNoPartialState(t *testing.T) {
+	base := t.TempDir()
+	store, err := NewStore(base)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	store.SetFS(platform.FaultFS{Fault: func(op, name string) error {
+		if op == "Sync" {
+			return errors.New("disk full")
+		}
+		return nil
+	}})
+
+	run := Run{RunID: "run-1", GraphHash: "gh", StartTime: time.Unix(1, 0).UTC(), Mode: ExecutionModeIncremental, Status: "running"}
+	if err := store.SaveRun(run); err == nil {
+		t.Fatalf("SaveRun succeeded on a failing disk")
+	}
+	store.SetFS(nil)
+	if _, err := store.LoadRun("run-1"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("LoadRun after failed SaveRun: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(base, ".scriptweaver", "runs", "run-1"))
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("run dir holds %v (%v), want nothing", entries, err)
+	}
+}
+
+func TestFailureRecorder_StampsRunsWithItsClock(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	rec := &FailureRecorder{Store: store, Clock: platform.NewFakeClock(now)}
+	id, err := rec.NewRunID()
+	if err != nil {
+		t.Fatalf("NewRunID: %v", err)
+	}
+	if err := rec.StartRun(Run{RunID: id, GraphHash: "gh", Mode: ExecutionModeClean, Status: "running"}); err != nil {
+		t.Fatalf("StartRun: %v", err)
+	}
+	run, err := store.LoadRun(id)
+	if err != nil || !run.StartTime.Equal(now) {
+		t.Fatalf("start time = %v (%v), want %v", run.StartTime, err, now)
+	}
+}
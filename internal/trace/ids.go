@@ -0,0 +1,73 @@
+package trace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Event IDs link trace events into a tree, run → node → event, so that trace
+// diffs, exporters and replay tools can correlate events without matching on
+// their contents:
+//
+//   - The trace itself is the run.
+//   - Every task has a node ID, NodeID(taskID), which is the ParentID of all
+//     of the task's events. Events without a task, such as StageCompleted,
+//     have no ParentID: they belong to the run.
+//   - An event's ID is derived from its task, its kind and how many earlier
+//     events of that kind the task has. A logical event therefore keeps its ID
+//     across runs, even when other events are added or removed around it.
+//   - CauseID is the ID of the terminal (last) event of CauseTaskID: the
+//     failure that skipped a task, or the result a deduplicated task shared.
+//
+// IDs are 16 hex characters, the size of an OpenTelemetry span ID. They are
+// independent of the graph hash and run ID, so runs of different graph
+// versions can be compared node by node.
+
+// NodeID returns the ID of the node of task taskID.
+func NodeID(taskID string) string {
+	return shortID("node", taskID)
+}
+
+func eventID(taskID string, kind TraceEventKind, n int) string {
+	return shortID("event", taskID, string(kind), strconv.Itoa(n))
+}
+
+func shortID(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// assignIDs sets the ID, ParentID and CauseID of the canonically ordered
+// events, replacing any previous values.
+func (t *ExecutionTrace) assignIDs() {
+	type taskKind struct {
+		task string
+		kind TraceEventKind
+	}
+	seen := make(map[taskKind]int)
+	terminal := make(map[string]string)
+	for i := range t.Events {
+		e := &t.Events[i]
+		k := taskKind{e.TaskID, e.Kind}
+		e.ID = eventID(e.TaskID, e.Kind, seen[k])
+		seen[k]++
+		e.ParentID = ""
+		if e.TaskID == "" {
+			continue
+		}
+		e.ParentID = NodeID(e.TaskID)
+		terminal[e.TaskID] = e.ID
+	}
+	for i := range t.Events {
+		e := &t.Events[i]
+		e.CauseID = terminal[e.CauseTaskID]
+		if e.CauseTaskID == "" {
+			e.CauseID = ""
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package trace
+
+import "testing"
+
+func TestCanonicalize_AssignsIDsAndCausalityLinks(t *testing.T) {
+	tr := ExecutionTrace{GraphHash: "g", Events: []TraceEvent{
+		{Kind: EventTaskSkipped, TaskID: "c", Reason: "UpstreamFailed", CauseTaskID: "b"},
+		{Kind: EventTaskFailed, TaskID: "b"},
+		{Kind: EventTaskExecuted, TaskID: "a"},
+	}}
+	tr.Canonicalize()
+	byTask := make(map[string]TraceEvent)
+	ids := make(map[string]bool)
+	for _, e := range tr.Events {
+		byTask[e.TaskID] = e
+		if len(e.ID) != 16 || ids[e.ID] {
+			t.Fatalf("event %s: bad or duplicate id %q", e.TaskID, e.ID)
+		}
+		ids[e.ID] = true
+		if e.ParentID != NodeID(e.TaskID) {
+			t.Fatalf("event %s: parent %q, want node %q", e.TaskID, e.ParentID, NodeID(e.TaskID))
+		}
+	}
+	if got := byTask["c"].CauseID; got != byTask["b"].ID {
+		t.Fatalf("skip cause = %q, want failed event %q", got, byTask["b"].ID)
+	}
+	if byTask["a"].CauseID != "" {
+		t.Fatalf("unexpected cause on %#v", byTask["a"])
+	}
+}
+
+func TestCanonicalize_EventIDStableWhenOtherEventsChange(t *testing.T) {
+	full := ExecutionTrace{GraphHash: "g1", Events: []TraceEvent{
+		{Kind: EventTaskInvalidated, TaskID: "a", Reason: "InputChanged"},
+		{Kind: EventTaskExecuted, TaskID: "a"},
+	}}
+	partial := ExecutionTrace{GraphHash: "g2", Events: []TraceEvent{
+		{Kind: EventTaskExecuted, TaskID: "a"},
+	}}
+	full.Canonicalize()
+	partial.Canonicalize()
+	if full.Events[1].ID != partial.Events[0].ID {
+		t.Fatalf("executed event id changed: %q vs %q", full.Events[1].ID, partial.Events[0].ID)
+	}
+}
@@ -0,0 +1,92 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Journal is a Sink that appends every event to a file, one JSON line per
+// event, as soon as it is recorded. Each event is written with a single
+// write and without buffering, so the events of a run survive a crash of
+// the process recording them; ReadJournal reads them back.
+//
+// Like every Sink, Record is inert: after the first write error the journal
+// stops recording and Close reports the error.
+type Journal struct {
+	mu  sync.Mutex
+	f   *os.File
+	err error
+}
+
+// CreateJournal creates, or truncates, the journal file at path.
+func CreateJournal(path string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{f: f}, nil
+}
+
+func (j *Journal) Record(event TraceEvent) {
+	if j == nil {
+		return
+	}
+	line, err := event.MarshalJSON()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.err != nil || j.f == nil {
+		return
+	}
+	if err == nil {
+		_, err = j.f.Write(append(line, '\n'))
+	}
+	j.err = err
+}
+
+// Close closes the journal file and returns the first error of a write or
+// of closing it.
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return j.err
+	}
+	if err := j.f.Close(); j.err == nil {
+		j.err = err
+	}
+	j.f = nil
+	return j.err
+}
+
+// ReadJournal returns the events of the journal file at path, in the order
+// they were recorded. A final line that a crash left incomplete is ignored.
+func ReadJournal(path string) ([]TraceEvent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var events []TraceEvent
+	r := bufio.NewReader(bytes.NewReader(data))
+	for n := 1; ; n++ {
+		line, rerr := r.ReadBytes('\n')
+		if errors.Is(rerr, io.EOF) {
+			// Only complete lines are terminated: the write of this one was
+			// cut short.
+			return events, nil
+		}
+		var ev TraceEvent
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return nil, fmt.Errorf("journal line %d: %w", n, err)
+		}
+		events = append(events, ev)
+	}
+}
@@ -0,0 +1,59 @@
+package trace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJournal_RecordsEventsAsTheyHappen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.journal")
+	j, err := CreateJournal(path)
+	if err != nil {
+		t.Fatalf("CreateJournal: %v", err)
+	}
+	rec := NewRecorder()
+	rec.Forward = j
+	for _, ev := range goldenTrace.Events {
+		rec.Record(ev)
+	}
+
+	// The events are on disk before the journal is closed, as after a crash.
+	got, err := ReadJournal(path)
+	if err != nil {
+		t.Fatalf("ReadJournal: %v", err)
+	}
+	var kinds, want []TraceEventKind
+	for _, ev := range got {
+		kinds = append(kinds, ev.Kind)
+	}
+	for _, ev := range goldenTrace.Events {
+		want = append(want, ev.Kind)
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("journal kinds = %v, want %v", kinds, want)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// A write the crash cut short is ignored.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := f.WriteString(`{"kind":"TaskExec`); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	f.Close()
+	if got, err = ReadJournal(path); err != nil || len(got) != len(goldenTrace.Events) {
+		t.Fatalf("ReadJournal = %d events, %v", len(got), err)
+	}
+	tr := ExecutionTrace{GraphHash: goldenTrace.GraphHash, Events: got}
+	a, _ := tr.CanonicalJSON()
+	b, _ := goldenTrace.CanonicalJSON()
+	if string(a) != string(b) {
+		t.Fatalf("journaled trace differs:\n%s\n%s", a, b)
+	}
+}
@@ -0,0 +1,67 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedVersion is returned when reading a trace written in a format
+// newer than FormatVersion.
+var ErrUnsupportedVersion = errors.New("unsupported trace version")
+
+// Parse decodes and validates a trace in any format version up to
+// FormatVersion. Unknown fields, such as event fields added by a later
+// producer, are ignored.
+func Parse(data []byte) (ExecutionTrace, error) {
+	var t ExecutionTrace
+	if err := json.Unmarshal(data, &t); err != nil {
+		return ExecutionTrace{}, fmt.Errorf("parse trace: %w", err)
+	}
+	if err := t.Validate(); err != nil {
+		return ExecutionTrace{}, fmt.Errorf("parse trace: %w", err)
+	}
+	return t, nil
+}
+
+// UnmarshalJSON reads every supported format version. A trace without a
+// version field is version 1.
+func (t *ExecutionTrace) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Version   *int         `json:"version"`
+		GraphHash string       `json:"graphHash"`
+		Events    []TraceEvent `json:"events"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	version := 1
+	if raw.Version != nil {
+		version = *raw.Version
+	}
+	if version < 1 || version > FormatVersion {
+		return fmt.Errorf("%w %d (supported: 1-%d)", ErrUnsupportedVersion, version, FormatVersion)
+	}
+	*t = ExecutionTrace{Version: version, GraphHash: raw.GraphHash, Events: raw.Events}
+	return nil
+}
+
+// UnmarshalJSON reads an event encoded by TraceEvent.MarshalJSON.
+func (e *TraceEvent) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Kind        TraceEventKind `json:"kind"`
+		TaskID      string         `json:"taskId"`
+		Reason      string         `json:"reason"`
+		CauseTaskID string         `json:"causeTaskId"`
+		Artifacts   []string       `json:"artifacts"`
+		Stage       *StageSummary  `json:"stage"`
+		ID          string         `json:"id"`
+		ParentID    string         `json:"parentId"`
+		CauseID     string         `json:"causeId"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = TraceEvent(raw)
+	return nil
+}
@@ -0,0 +1,95 @@
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// goldenTrace exercises every event kind and optional field.
+var goldenTrace = ExecutionTrace{
+	GraphHash: "88a5d242823933b1912b3e3b8b9e4aeed160c8acad14a5020bffe4c90711cd39",
+	Events: []TraceEvent{
+		{Kind: EventTaskInvalidated, TaskID: "build", Reason: "InputChanged"},
+		{Kind: EventTaskExecuted, TaskID: "build", Reason: "FreshWork"},
+		{Kind: EventTaskArtifactsRestored, TaskID: "deps", Artifacts: []string{"vendor/b", "vendor/a"}},
+		{Kind: EventTaskCached, TaskID: "deps", Reason: "CacheHit"},
+		{Kind: EventTaskDeduplicated, TaskID: "lint-copy", CauseTaskID: "lint"},
+		{Kind: EventTaskFailed, TaskID: "lint"},
+		{Kind: EventTaskSkipped, TaskID: "package", Reason: "UpstreamFailed", CauseTaskID: "lint"},
+	},
+}
+
+// TestGolden_CanonicalBytes locks the canonical encoding of the current
+// format version. Set SCRIPTWEAVER_UPDATE_GOLDEN=1 to rewrite the fixture
+// after a deliberate format change, and bump FormatVersion with it.
+func TestGolden_CanonicalBytes(t *testing.T) {
+	got, err := goldenTrace.CanonicalJSON()
+	if err != nil {
+		t.Fatalf("canonical json: %v", err)
+	}
+	path := filepath.Join("testdata", "trace_v2.json")
+	if os.Getenv("SCRIPTWEAVER_UPDATE_GOLDEN") == "1" {
+		if err := os.WriteFile(path, append(got, '\n'), 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if !bytes.Equal(got, bytes.TrimSuffix(want, []byte("\n"))) {
+		t.Fatalf("canonical bytes changed\n got=%s\nwant=%s", got, want)
+	}
+}
+
+func TestParse_ReadsEveryFormatVersion(t *testing.T) {
+	want, err := goldenTrace.CanonicalJSON()
+	if err != nil {
+		t.Fatalf("canonical json: %v", err)
+	}
+	for file, version := range map[string]int{"trace_v1.json": 1, "trace_v2.json": 2} {
+		data, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		tr, err := Parse(data)
+		if err != nil {
+			t.Fatalf("parse %s: %v", file, err)
+		}
+		if tr.Version != version {
+			t.Fatalf("%s: version = %d, want %d", file, tr.Version, version)
+		}
+		// Re-encoding upgrades to the current format.
+		got, err := tr.CanonicalJSON()
+		if err != nil {
+			t.Fatalf("%s: canonical json: %v", file, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s: re-encoded bytes differ\n got=%s\nwant=%s", file, got, want)
+		}
+	}
+}
+
+func TestParse_IgnoresUnknownFields(t *testing.T) {
+	data := []byte(`{"version":2,"graphHash":"g","producer":"future","events":[{"kind":"TaskExecuted","taskId":"a","durationClass":"slow"}]}`)
+	tr, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []TraceEvent{{Kind: EventTaskExecuted, TaskID: "a"}}
+	if !reflect.DeepEqual(tr.Events, want) {
+		t.Fatalf("events = %#v", tr.Events)
+	}
+}
+
+func TestParse_RejectsNewerVersion(t *testing.T) {
+	_, err := Parse([]byte(`{"version":3,"graphHash":"g","events":[]}`))
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+}
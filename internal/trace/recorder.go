@@ -41,6 +41,10 @@ func SafeRecord(s Sink, event TraceEvent) {
 type Recorder struct {
 	mu     sync.Mutex
 	events []TraceEvent
+
+	// Forward, if set, also receives every event as it is recorded, such as
+	// a Journal that keeps the events of a run that may crash.
+	Forward Sink
 }
 
 func NewRecorder() *Recorder { return &Recorder{} }
@@ -56,6 +60,7 @@ func (r *Recorder) Record(event TraceEvent) {
 	r.mu.Lock()
 	r.events = append(r.events, event)
 	r.mu.Unlock()
+	SafeRecord(r.Forward, event)
 }
 
 // Snapshot returns a point-in-time copy of all recorded events.
@@ -32,10 +32,25 @@ import (
 //
 // See docs/sprints/sprint-03/in-process/trace-engine/spec.md for rationale and constraints.
 type ExecutionTrace struct {
+	// Version is the trace format version the trace was read from (see Parse).
+	// Encoding always produces FormatVersion, so it does not affect the
+	// canonical bytes of a trace built in memory.
+	Version   int
 	GraphHash string
 	Events    []TraceEvent
 }
 
+// FormatVersion is the version of the canonical trace encoding.
+//
+// Version history:
+//   - 1: {"graphHash","events"} without a version field.
+//   - 2: adds the leading "version" field.
+//
+// Adding optional event fields does not change the version: readers ignore
+// fields they do not know. Renaming or removing fields, or changing the
+// canonical ordering, does.
+const FormatVersion = 2
+
 // TraceEventKind is the stable, canonical discriminator for TraceEvent.
 //
 // These kinds represent logical decisions/transitions, not runtime occurrences.
@@ -49,8 +64,27 @@ const (
 	EventTaskExecuted         TraceEventKind = "TaskExecuted"
 	EventTaskFailed           TraceEventKind = "TaskFailed"
 	EventTaskSkipped          TraceEventKind = "TaskSkipped"
+
+	// EventTaskDeduplicated records that a task shared the terminal result of a
+	// byte-identical task (CauseTaskID) instead of executing.
+	EventTaskDeduplicated TraceEventKind = "TaskDeduplicated"
+
+	// EventStageCompleted summarizes the outcome of the nodes of one
+	// topological depth (Stage), so that consumers of very wide graphs can
+	// render progress without tracking every node. It has no TaskID.
+	EventStageCompleted TraceEventKind = "StageCompleted"
 )
 
+// StageSummary counts the nodes of one topological depth by final state.
+type StageSummary struct {
+	Depth     int `json:"depth"`
+	Nodes     int `json:"nodes"`
+	Completed int `json:"completed"`
+	Cached    int `json:"cached"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+}
+
 // TraceEvent is a single logical transition/decision.
 //
 // Determinism constraints:
@@ -76,6 +110,15 @@ type TraceEvent struct {
 
 	// Artifacts is a list of restored artifact identifiers. The producer must ensure identifiers are stable.
 	Artifacts []string
+
+	// Stage is the summary of a StageCompleted event.
+	Stage *StageSummary
+
+	// ID, ParentID and CauseID are assigned by Canonicalize; producers leave
+	// them empty. See assignIDs.
+	ID       string
+	ParentID string
+	CauseID  string
 }
 
 // Validate checks basic invariants and returns a descriptive error.
@@ -107,8 +150,10 @@ func (t *ExecutionTrace) Validate() error {
 
 func isTaskEvent(kind TraceEventKind) bool {
 	switch kind {
-	case EventTaskInvalidated, EventTaskArtifactsRestored, EventTaskCached, EventTaskExecuted, EventTaskFailed, EventTaskSkipped:
+	case EventTaskInvalidated, EventTaskArtifactsRestored, EventTaskCached, EventTaskExecuted, EventTaskDeduplicated, EventTaskFailed, EventTaskSkipped:
 		return true
+	case EventStageCompleted:
+		return false
 	default:
 		return true
 	}
@@ -122,7 +167,8 @@ func isTaskEvent(kind TraceEventKind) bool {
 // Canonicalization rules:
 //   - Artifacts are copied and sorted.
 //   - Empty Artifacts slices are normalized to nil.
-//   - Events are stably sorted by (taskId, kindOrder, reason, causeTaskId, artifactsLex).
+//   - Events are stably sorted by (taskId, kindOrder, stage depth, reason, causeTaskId, artifactsLex).
+//   - Event IDs and causality links are derived from the sorted events.
 func (t *ExecutionTrace) Canonicalize() {
 	if t == nil {
 		return
@@ -148,6 +194,9 @@ func (t *ExecutionTrace) Canonicalize() {
 		if kindOrder(a.Kind) != kindOrder(b.Kind) {
 			return kindOrder(a.Kind) < kindOrder(b.Kind)
 		}
+		if a.Stage != nil && b.Stage != nil && a.Stage.Depth != b.Stage.Depth {
+			return a.Stage.Depth < b.Stage.Depth
+		}
 		if a.Reason != b.Reason {
 			return a.Reason < b.Reason
 		}
@@ -156,10 +205,13 @@ func (t *ExecutionTrace) Canonicalize() {
 		}
 		return compareStringSlices(a.Artifacts, b.Artifacts)
 	})
+	t.assignIDs()
 }
 
 func kindOrder(k TraceEventKind) int {
 	switch k {
+	case EventStageCompleted:
+		return 5
 	case EventTaskInvalidated:
 		return 10
 	case EventTaskArtifactsRestored:
@@ -168,6 +220,8 @@ func kindOrder(k TraceEventKind) int {
 		return 30
 	case EventTaskExecuted:
 		return 40
+	case EventTaskDeduplicated:
+		return 45
 	case EventTaskFailed:
 		return 50
 	case EventTaskSkipped:
@@ -226,6 +280,9 @@ func (t ExecutionTrace) MarshalJSON() ([]byte, error) {
 	var buf bytes.Buffer
 	buf.WriteByte('{')
 
+	// version (always first)
+	fmt.Fprintf(&buf, "\"version\":%d,", FormatVersion)
+
 	// graphHash
 	buf.WriteString("\"graphHash\":")
 	gh, _ := json.Marshal(t.GraphHash)
@@ -271,6 +328,20 @@ func (e TraceEvent) MarshalJSON() ([]byte, error) {
 	kb, _ := json.Marshal(string(e.Kind))
 	buf.Write(kb)
 
+	// id
+	if e.ID != "" {
+		buf.WriteString(",\"id\":")
+		ib, _ := json.Marshal(e.ID)
+		buf.Write(ib)
+	}
+
+	// parentId
+	if e.ParentID != "" {
+		buf.WriteString(",\"parentId\":")
+		pb, _ := json.Marshal(e.ParentID)
+		buf.Write(pb)
+	}
+
 	// taskId
 	if e.TaskID != "" {
 		buf.WriteByte(',')
@@ -295,6 +366,13 @@ func (e TraceEvent) MarshalJSON() ([]byte, error) {
 		buf.Write(cb)
 	}
 
+	// causeId
+	if e.CauseID != "" {
+		buf.WriteString(",\"causeId\":")
+		cb, _ := json.Marshal(e.CauseID)
+		buf.Write(cb)
+	}
+
 	// artifacts
 	if len(artifacts) > 0 {
 		buf.WriteByte(',')
@@ -309,6 +387,13 @@ func (e TraceEvent) MarshalJSON() ([]byte, error) {
 		buf.WriteByte(']')
 	}
 
+	// stage
+	if e.Stage != nil {
+		buf.WriteString(",\"stage\":")
+		sb, _ := json.Marshal(e.Stage)
+		buf.Write(sb)
+	}
+
 	buf.WriteByte('}')
 	return buf.Bytes(), nil
 }
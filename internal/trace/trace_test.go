@@ -51,7 +51,7 @@ func TestCanonicalOrdering_SortsByTaskID(t *testing.T) {
 		t.Fatalf("canonical json: %v", err)
 	}
 	// Expect task a before b.
-	expected := `{"graphHash":"graph-abc","events":[{"kind":"TaskExecuted","taskId":"a"},{"kind":"TaskExecuted","taskId":"b"}]}`
+	expected := `{"version":2,"graphHash":"graph-abc","events":[{"kind":"TaskExecuted","id":"f5d0e79a2ac3fab4","parentId":"70be8b75dda6ee6b","taskId":"a"},{"kind":"TaskExecuted","id":"0bb50828b46e3c84","parentId":"84469de86f9710a2","taskId":"b"}]}`
 	if string(b) != expected {
 		t.Fatalf("unexpected canonical bytes\nexpected=%s\nactual  =%s", expected, string(b))
 	}
@@ -116,7 +116,7 @@ func TestEventArtifacts_CanonicalizedAndOmittedWhenEmpty(t *testing.T) {
 	if err != nil {
 		t.Fatalf("canonical json: %v", err)
 	}
-	expected := `{"graphHash":"g","events":[{"kind":"TaskArtifactsRestored","taskId":"a","artifacts":["a","z"]}]}`
+	expected := `{"version":2,"graphHash":"g","events":[{"kind":"TaskArtifactsRestored","id":"b5bf1b9d8cc61018","parentId":"70be8b75dda6ee6b","taskId":"a","artifacts":["a","z"]}]}`
 	if string(b) != expected {
 		t.Fatalf("unexpected canonical bytes\nexpected=%s\nactual  =%s", expected, string(b))
 	}
@@ -126,7 +126,7 @@ func TestEventArtifacts_CanonicalizedAndOmittedWhenEmpty(t *testing.T) {
 	if err != nil {
 		t.Fatalf("canonical json: %v", err)
 	}
-	expected2 := `{"graphHash":"g","events":[{"kind":"TaskCached","taskId":"a"}]}`
+	expected2 := `{"version":2,"graphHash":"g","events":[{"kind":"TaskCached","id":"9f04f611646bb500","parentId":"70be8b75dda6ee6b","taskId":"a"}]}`
 	if string(b2) != expected2 {
 		t.Fatalf("unexpected canonical bytes\nexpected=%s\nactual  =%s", expected2, string(b2))
 	}
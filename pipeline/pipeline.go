@@ -0,0 +1,83 @@
+// Package pipeline is the public API for the stages of a ScriptWeaver run,
+// so that programs embedding the engine add cross-cutting behaviour, such as
+// metrics or policy checks, by inserting a Stage into DefaultPipeline
+// instead of forking the CLI.
+//
+// It re-exports the pipeline types and functions of the engine: the
+// Pipeline returned here is the one sw run executes.
+package pipeline
+
+import (
+	"context"
+
+	"scriptweaver/internal/cli"
+)
+
+// Names of the stages of DefaultPipeline, in order.
+const (
+	StageRecovery  = cli.StageRecovery
+	StageWorkspace = cli.StageWorkspace
+	StageRetention = cli.StageRetention
+	StageNotify    = cli.StageNotify
+	StagePlugins   = cli.StagePlugins
+	StageGraph     = cli.StageGraph
+	StageSelect    = cli.StageSelect
+	StageTrace     = cli.StageTrace
+	StageOutputDir = cli.StageOutputDir
+	StageCache     = cli.StageCache
+	StagePlan      = cli.StagePlan
+	StageOffline   = cli.StageOffline
+	StageStart     = cli.StageStart
+	StageExecute   = cli.StageExecute
+	StageOutputs   = cli.StageOutputs
+)
+
+// Next runs the remaining stages of a pipeline.
+type Next = cli.Next
+
+// Stage is one step of a run. Its Run prepares its part of the RunContext
+// and calls next to continue the run, then may act on the outcome once next
+// returns, like an HTTP middleware. A stage that fails sets
+// RunContext.Result.ExitCode and returns an error without calling next.
+type Stage = cli.Stage
+
+// Pipeline is the sequence of stages that executes a run; the first stage
+// runs outermost. InsertBefore adds a stage, and Execute runs an Invocation
+// through the pipeline.
+type Pipeline = cli.Pipeline
+
+// RunContext is the state of a run that the stages of a Pipeline build up.
+// Each field is set by the stage named in its documentation and is the zero
+// value before it.
+type RunContext = cli.RunContext
+
+// Invocation describes a run: the graph, workspace and options sw run takes.
+type Invocation = cli.CLIInvocation
+
+// Result is the outcome of a run; ExitCode is one of the codes of package
+// scriptweaver/exitcode.
+type Result = cli.CLIResult
+
+// ExecutionMode selects how a run uses the results of earlier runs.
+type ExecutionMode = cli.ExecutionMode
+
+// Execution modes of an Invocation.
+const (
+	ExecutionModeClean       = cli.ExecutionModeClean
+	ExecutionModeIncremental = cli.ExecutionModeIncremental
+	ExecutionModeResumeOnly  = cli.ExecutionModeResumeOnly
+)
+
+// TraceConfig configures the trace of a run (Invocation.Trace).
+type TraceConfig = cli.TraceConfig
+
+// DefaultPipeline returns the stages sw run executes.
+func DefaultPipeline() Pipeline {
+	return cli.DefaultPipeline()
+}
+
+// Execute runs inv through DefaultPipeline, as sw run does, including
+// automatic retries.
+func Execute(ctx context.Context, inv Invocation) (Result, error) {
+	return cli.Execute(ctx, inv)
+}
@@ -0,0 +1,54 @@
+package pipeline_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"scriptweaver/pipeline"
+)
+
+// A stage inserted before StageExecute runs once the graph is loaded and the
+// executor prepared, and sees the outcome of the execution when next
+// returns.
+func ExamplePipeline_InsertBefore() {
+	workDir, err := os.MkdirTemp("", "pipeline-example")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(workDir)
+	graphPath := filepath.Join(workDir, "graph.json")
+	graphJSON := `{"tasks": [{"name": "hello", "inputs": [], "run": "echo hello > hello.txt", "outputs": ["hello.txt"]}], "edges": []}`
+	if err := os.WriteFile(graphPath, []byte(graphJSON), 0o644); err != nil {
+		log.Fatal(err)
+	}
+
+	p, err := pipeline.DefaultPipeline().InsertBefore(pipeline.StageExecute, pipeline.Stage{
+		Name: "metrics",
+		Run: func(ctx context.Context, rc *pipeline.RunContext, next pipeline.Next) error {
+			fmt.Println("nodes:", len(rc.Graph.Nodes()))
+			err := next(ctx)
+			fmt.Println("exit code:", rc.Result.ExitCode)
+			return err
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	res, err := p.Execute(context.Background(), pipeline.Invocation{
+		GraphPath:     graphPath,
+		WorkDir:       workDir,
+		OutputDir:     filepath.Join(workDir, "out"),
+		ExecutionMode: pipeline.ExecutionModeClean,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("succeeded:", res.GraphResult.FinalState["hello"])
+	// Output:
+	// nodes: 1
+	// exit code: 0
+	// succeeded: COMPLETED
+}
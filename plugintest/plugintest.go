@@ -0,0 +1,351 @@
+// Package plugintest runs plugins against the real scriptweaver DAG executor
+// so plugin authors can verify that their hooks behave deterministically and
+// respect engine semantics.
+//
+// Tasks are scripted: a fake runner decides each task's exit code and cache
+// state without executing anything, so tests are fast and hermetic. Every hook
+// call is recorded, and the recorded calls together with the engine's
+// canonical execution trace can be compared against a golden file.
+package plugintest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"scriptweaver/internal/core"
+	"scriptweaver/internal/dag"
+	"scriptweaver/internal/pluginengine"
+)
+
+// Plugin is the runtime plugin interface. Implementations provide Manifest
+// and any of BeforeRun(ctx), AfterRun(ctx), BeforeNode(ctx, taskID),
+// AfterNode(ctx, taskID) and Report(ctx, Report), each returning an error, and
+// Validate(ctx, *dag.TaskGraph) returning findings and an error.
+type Plugin = pluginengine.RuntimePlugin
+
+// Manifest is a plugin manifest.
+type Manifest = pluginengine.PluginManifest
+
+// Report is what reporter plugins receive after a run.
+type Report = pluginengine.Report
+
+// Finding is a validation finding reported by a validator plugin.
+type Finding = pluginengine.Finding
+
+// UpdateEnv is the environment variable that makes Golden rewrite golden
+// files instead of comparing against them.
+const UpdateEnv = "SCRIPTWEAVER_UPDATE_GOLDEN"
+
+// Task is a scripted graph node.
+type Task struct {
+	Name string
+	// Deps lists the tasks that must finish before this one.
+	Deps []string
+	// ExitCode is the exit code the fake runner reports.
+	ExitCode int
+	// Cached makes the task a cache hit, so it is replayed instead of run.
+	Cached bool
+}
+
+// Graph is a scripted graph.
+type Graph struct {
+	Tasks []Task
+}
+
+// Chain returns a graph of tasks that each depend on the previous one.
+func Chain(names ...string) Graph {
+	var g Graph
+	for i, name := range names {
+		t := Task{Name: name}
+		if i > 0 {
+			t.Deps = []string{names[i-1]}
+		}
+		g.Tasks = append(g.Tasks, t)
+	}
+	return g
+}
+
+// Call is one hook invocation.
+type Call struct {
+	PluginID string
+	Hook     string
+	// TaskID is empty for run-level hooks.
+	TaskID string
+}
+
+func (c Call) String() string {
+	if c.TaskID == "" {
+		return c.PluginID + " " + c.Hook
+	}
+	return c.PluginID + " " + c.Hook + " " + c.TaskID
+}
+
+// Recorder records the hook calls made to the plugins it wraps.
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+// Wrap returns a plugin that records each hook call and forwards it to p.
+func (r *Recorder) Wrap(p Plugin) Plugin {
+	return &recordedPlugin{inner: p, rec: r}
+}
+
+// Calls returns the calls recorded so far, in invocation order.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Call, len(r.calls))
+	copy(out, r.calls)
+	return out
+}
+
+func (r *Recorder) add(c Call) {
+	r.mu.Lock()
+	r.calls = append(r.calls, c)
+	r.mu.Unlock()
+}
+
+type recordedPlugin struct {
+	inner Plugin
+	rec   *Recorder
+}
+
+func (p *recordedPlugin) Manifest() Manifest { return p.inner.Manifest() }
+
+func (p *recordedPlugin) notImplemented(hook string) error {
+	return fmt.Errorf("plugin %s declares %s but does not implement it", p.inner.Manifest().PluginID, hook)
+}
+
+func (p *recordedPlugin) BeforeRun(ctx context.Context) error {
+	h, ok := p.inner.(interface{ BeforeRun(context.Context) error })
+	if !ok {
+		return p.notImplemented("BeforeRun")
+	}
+	p.rec.add(Call{PluginID: p.inner.Manifest().PluginID, Hook: "BeforeRun"})
+	return h.BeforeRun(ctx)
+}
+
+func (p *recordedPlugin) AfterRun(ctx context.Context) error {
+	h, ok := p.inner.(interface{ AfterRun(context.Context) error })
+	if !ok {
+		return p.notImplemented("AfterRun")
+	}
+	p.rec.add(Call{PluginID: p.inner.Manifest().PluginID, Hook: "AfterRun"})
+	return h.AfterRun(ctx)
+}
+
+func (p *recordedPlugin) BeforeNode(ctx context.Context, taskID string) error {
+	h, ok := p.inner.(interface {
+		BeforeNode(context.Context, string) error
+	})
+	if !ok {
+		return p.notImplemented("BeforeNode")
+	}
+	p.rec.add(Call{PluginID: p.inner.Manifest().PluginID, Hook: "BeforeNode", TaskID: taskID})
+	return h.BeforeNode(ctx, taskID)
+}
+
+func (p *recordedPlugin) AfterNode(ctx context.Context, taskID string) error {
+	h, ok := p.inner.(interface {
+		AfterNode(context.Context, string) error
+	})
+	if !ok {
+		return p.notImplemented("AfterNode")
+	}
+	p.rec.add(Call{PluginID: p.inner.Manifest().PluginID, Hook: "AfterNode", TaskID: taskID})
+	return h.AfterNode(ctx, taskID)
+}
+
+func (p *recordedPlugin) Report(ctx context.Context, r Report) error {
+	h, ok := p.inner.(pluginengine.Reporter)
+	if !ok {
+		return p.notImplemented(pluginengine.ReportHook)
+	}
+	p.rec.add(Call{PluginID: p.inner.Manifest().PluginID, Hook: pluginengine.ReportHook})
+	return h.Report(ctx, r)
+}
+
+func (p *recordedPlugin) Validate(ctx context.Context, g *dag.TaskGraph) ([]Finding, error) {
+	h, ok := p.inner.(pluginengine.Validator)
+	if !ok {
+		return nil, p.notImplemented(pluginengine.ValidateHook)
+	}
+	p.rec.add(Call{PluginID: p.inner.Manifest().PluginID, Hook: pluginengine.ValidateHook})
+	return h.Validate(ctx, g)
+}
+
+// Result is the outcome of Run.
+type Result struct {
+	// Calls lists every hook call, in invocation order.
+	Calls []Call
+	// Findings lists the validator plugins' findings. As in the engine, any
+	// finding stops the run before execution.
+	Findings []Finding
+	// HookErrors lists hook errors and recovered panics, as the engine logs
+	// them; they never fail the run.
+	HookErrors []error
+	// ExitCodes holds the exit code of every task that ran or was replayed.
+	ExitCodes map[string]int
+	// ExecutionOrder lists the tasks that were started, in order.
+	ExecutionOrder []string
+	// Trace is the engine's canonical execution trace (JSON).
+	Trace []byte
+}
+
+// Run validates and executes g serially with the given plugins active, exactly
+// as the engine would, using a fake runner for the tasks.
+func Run(ctx context.Context, g Graph, plugins ...Plugin) (*Result, error) {
+	tasks := make([]core.Task, 0, len(g.Tasks))
+	var edges []dag.Edge
+	script := make(map[string]Task, len(g.Tasks))
+	for _, t := range g.Tasks {
+		tasks = append(tasks, core.Task{Name: t.Name, Run: "scripted " + t.Name})
+		for _, d := range t.Deps {
+			edges = append(edges, dag.Edge{From: d, To: t.Name})
+		}
+		script[t.Name] = t
+	}
+	tg, err := dag.NewTaskGraph(tasks, edges)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Recorder{}
+	wrapped := make([]pluginengine.RuntimePlugin, 0, len(plugins))
+	for _, p := range plugins {
+		wrapped = append(wrapped, rec.Wrap(p))
+	}
+	hooks, err := pluginengine.NewHookEngine(wrapped, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{ExitCodes: make(map[string]int)}
+	if res.Findings = hooks.Validate(ctx, tg); len(res.Findings) > 0 {
+		res.Calls = rec.Calls()
+		res.HookErrors = hooks.Errors()
+		return res, nil
+	}
+
+	exec, err := dag.NewExecutor(tg, scriptedRunner{script: script})
+	if err != nil {
+		return nil, err
+	}
+	exec.Hooks = hooks
+	gr, err := exec.RunSerial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Calls = rec.Calls()
+	res.HookErrors = hooks.Errors()
+	res.ExecutionOrder = gr.ExecutionOrder
+	res.Trace = gr.TraceBytes
+	for _, t := range g.Tasks {
+		if code, ok := gr.ExitCodeOf(t.Name); ok {
+			res.ExitCodes[t.Name] = code
+		}
+	}
+	return res, nil
+}
+
+// CheckDeterministic runs g n times with fresh plugins from newPlugins and
+// reports an error if the hook calls or the execution trace differ between
+// runs.
+func CheckDeterministic(ctx context.Context, g Graph, n int, newPlugins func() []Plugin) error {
+	var first *Result
+	for i := 0; i < n; i++ {
+		res, err := Run(ctx, g, newPlugins()...)
+		if err != nil {
+			return err
+		}
+		if first == nil {
+			first = res
+			continue
+		}
+		if !reflect.DeepEqual(first.Calls, res.Calls) {
+			return fmt.Errorf("run %d: hook calls differ from run 0:\n%s\nvs\n%s", i, formatCalls(first.Calls), formatCalls(res.Calls))
+		}
+		if !bytes.Equal(first.Trace, res.Trace) {
+			return fmt.Errorf("run %d: execution trace differs from run 0", i)
+		}
+	}
+	return nil
+}
+
+// Golden compares the recorded hook calls and execution trace of res with the
+// golden file at path. When UpdateEnv is set to 1 the file is written instead.
+func Golden(t testing.TB, path string, res *Result) {
+	t.Helper()
+	got := formatGolden(res)
+	if os.Getenv(UpdateEnv) == "1" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden (set %s=1 to create it): %v", UpdateEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("golden mismatch for %s (set %s=1 to update)\n--- got ---\n%s\n--- want ---\n%s", path, UpdateEnv, got, want)
+	}
+}
+
+func formatCalls(calls []Call) string {
+	lines := make([]string, len(calls))
+	for i, c := range calls {
+		lines[i] = c.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatGolden(res *Result) []byte {
+	var b bytes.Buffer
+	b.WriteString("# hook calls\n")
+	for _, c := range res.Calls {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	b.WriteString("# trace\n")
+	b.Write(res.Trace)
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+// scriptedRunner is the fake task runner: it reports the scripted outcome of
+// each task without executing anything.
+type scriptedRunner struct {
+	script map[string]Task
+}
+
+func (r scriptedRunner) result(task core.Task) *dag.NodeResult {
+	sum := sha256.Sum256([]byte(task.Name))
+	t := r.script[task.Name]
+	return &dag.NodeResult{Hash: core.TaskHash(hex.EncodeToString(sum[:])), ExitCode: t.ExitCode, FromCache: t.Cached}
+}
+
+func (r scriptedRunner) Probe(_ context.Context, task core.Task) (*dag.NodeResult, bool, error) {
+	if !r.script[task.Name].Cached {
+		return nil, false, nil
+	}
+	return r.result(task), true, nil
+}
+
+func (r scriptedRunner) Run(_ context.Context, task core.Task) (*dag.NodeResult, error) {
+	return r.result(task), nil
+}
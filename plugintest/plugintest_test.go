@@ -0,0 +1,95 @@
+package plugintest
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type countingPlugin struct {
+	id    string
+	nodes int
+}
+
+func (p *countingPlugin) Manifest() Manifest {
+	return Manifest{PluginID: p.id, Version: "1.0.0", Hooks: []string{"BeforeRun", "AfterNode"}}
+}
+
+func (p *countingPlugin) BeforeRun(context.Context) error { return nil }
+
+func (p *countingPlugin) AfterNode(_ context.Context, taskID string) error {
+	p.nodes++
+	if taskID == "boom" {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func TestRun_RecordsHooksInEngineOrder(t *testing.T) {
+	p := &countingPlugin{id: "counter"}
+	res, err := Run(context.Background(), Chain("a", "b", "c"), p)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []Call{
+		{PluginID: "counter", Hook: "BeforeRun"},
+		{PluginID: "counter", Hook: "AfterNode", TaskID: "a"},
+		{PluginID: "counter", Hook: "AfterNode", TaskID: "b"},
+		{PluginID: "counter", Hook: "AfterNode", TaskID: "c"},
+	}
+	if !reflect.DeepEqual(res.Calls, want) {
+		t.Fatalf("calls = %v, want %v", res.Calls, want)
+	}
+	if p.nodes != 3 || len(res.HookErrors) != 0 {
+		t.Fatalf("nodes=%d errors=%v", p.nodes, res.HookErrors)
+	}
+}
+
+func TestRun_FailedTaskSkipsDownstreamAndReportsHookErrors(t *testing.T) {
+	g := Graph{Tasks: []Task{
+		{Name: "boom", ExitCode: 1},
+		{Name: "after", Deps: []string{"boom"}},
+		{Name: "cached", Cached: true},
+	}}
+	res, err := Run(context.Background(), g, &countingPlugin{id: "counter"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, ran := res.ExitCodes["after"]; ran {
+		t.Fatalf("downstream of a failed task must not run: %v", res.ExitCodes)
+	}
+	if res.ExitCodes["boom"] != 1 || len(res.HookErrors) != 1 {
+		t.Fatalf("exit codes=%v hook errors=%v", res.ExitCodes, res.HookErrors)
+	}
+}
+
+func TestCheckDeterministic(t *testing.T) {
+	g := Graph{Tasks: []Task{{Name: "a"}, {Name: "b"}}}
+	if err := CheckDeterministic(context.Background(), g, 3, func() []Plugin {
+		return []Plugin{&countingPlugin{id: "counter"}}
+	}); err != nil {
+		t.Fatalf("stable plugin reported nondeterministic: %v", err)
+	}
+
+	runs := 0
+	err := CheckDeterministic(context.Background(), g, 2, func() []Plugin {
+		runs++
+		if runs > 1 {
+			return []Plugin{&countingPlugin{id: "counter"}, &countingPlugin{id: "extra"}}
+		}
+		return []Plugin{&countingPlugin{id: "counter"}}
+	})
+	if err == nil {
+		t.Fatalf("expected differing hook calls to be reported")
+	}
+}
+
+func TestGolden_MatchesRecordedRun(t *testing.T) {
+	res, err := Run(context.Background(), Chain("a", "b"), &countingPlugin{id: "counter"})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	Golden(t, filepath.Join("testdata", "chain.golden"), res)
+}